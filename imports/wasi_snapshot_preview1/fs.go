@@ -404,21 +404,18 @@ func fdFilestatGetFunc(mod api.Module, fd int32, resultBuf uint32) syscall.Errno
 }
 
 func getWasiFiletype(fm fs.FileMode) uint8 {
-	switch {
-	case fm.IsRegular():
+	switch platform.FileTypeOf(fm) {
+	case platform.FileTypeRegular:
 		return wasip1.FILETYPE_REGULAR_FILE
-	case fm.IsDir():
+	case platform.FileTypeDirectory:
 		return wasip1.FILETYPE_DIRECTORY
-	case fm&fs.ModeSymlink != 0:
+	case platform.FileTypeSymlink:
 		return wasip1.FILETYPE_SYMBOLIC_LINK
-	case fm&fs.ModeDevice != 0:
-		// Unlike ModeDevice and ModeCharDevice, FILETYPE_CHARACTER_DEVICE and
-		// FILETYPE_BLOCK_DEVICE are set mutually exclusively.
-		if fm&fs.ModeCharDevice != 0 {
-			return wasip1.FILETYPE_CHARACTER_DEVICE
-		}
+	case platform.FileTypeCharDevice:
+		return wasip1.FILETYPE_CHARACTER_DEVICE
+	case platform.FileTypeBlockDevice:
 		return wasip1.FILETYPE_BLOCK_DEVICE
-	default: // unknown
+	default: // unknown, or no WASI equivalent, such as a named pipe.
 		return wasip1.FILETYPE_UNKNOWN
 	}
 }
@@ -839,8 +836,9 @@ func fdReaddirFn(_ context.Context, mod api.Module, params []uint64) syscall.Err
 		// This happens when the program calls rewinddir, for example:
 		// https://github.com/WebAssembly/wasi-libc/blob/659ff414560721b1660a19685110e484a081c3d4/libc-bottom-half/cloudlibc/src/libc/dirent/rewinddir.c#L10-L12
 		//
-		// Since we cannot unwind fs.ReadDirFile results, we re-open while keeping the same file descriptor.
-		f, errno := fsc.ReOpenDir(fd)
+		// This rewinds the directory, or re-opens it if that's unsupported,
+		// while keeping the same file descriptor.
+		f, errno := fsc.RewindDir(fd)
 		if errno != 0 {
 			return errno
 		}
@@ -1186,6 +1184,8 @@ func fdSeekFn(_ context.Context, mod api.Module, params []uint64) syscall.Errno
 
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return syscall.EBADF
+	} else if whence > io.SeekEnd {
+		return syscall.EINVAL // WASI doesn't define SeekHole or SeekData.
 	} else if newOffset, errno := f.File.Seek(int64(offset), int(whence)); errno != 0 {
 		return errno
 	} else if !mod.Memory().WriteUint64Le(resultNewoffset, uint64(newOffset)) {
@@ -1323,6 +1323,7 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) syscall.Err
 
 	var resultNwritten uint32
 	var writer func(p []byte) (n int, errno syscall.Errno)
+	var writev func(bufs [][]byte) (n int, errno syscall.Errno)
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return syscall.EBADF
 	} else if f.File.AccessMode() == syscall.O_RDONLY {
@@ -1333,6 +1334,7 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) syscall.Err
 		resultNwritten = uint32(params[4])
 	} else {
 		writer = f.File.Write
+		writev = f.File.Writev
 		resultNwritten = uint32(params[3])
 	}
 
@@ -1343,6 +1345,7 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) syscall.Err
 		return syscall.EFAULT
 	}
 
+	bufs := make([][]byte, 0, iovsCount)
 	for iovsPos := uint32(0); iovsPos < iovsStop; iovsPos += 8 {
 		offset := le.Uint32(iovsBuf[iovsPos:])
 		l := le.Uint32(iovsBuf[iovsPos+4:])
@@ -1351,11 +1354,27 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) syscall.Err
 		if !ok {
 			return syscall.EFAULT
 		}
-		n, errno := writer(b)
-		nwritten += uint32(n)
-		if errno != 0 {
-			return errno
+		bufs = append(bufs, b)
+	}
+
+	// Write all buffers at once when possible, so that they aren't interleaved
+	// with writes from another module sharing the same file, e.g. stdout.
+	errno := syscall.ENOSYS
+	if writev != nil {
+		var n int
+		n, errno = writev(bufs)
+		nwritten = uint32(n)
+	}
+	if errno == syscall.ENOSYS {
+		for _, b := range bufs {
+			n, errno := writer(b)
+			nwritten += uint32(n)
+			if errno != 0 {
+				return errno
+			}
 		}
+	} else if errno != 0 {
+		return errno
 	}
 
 	if !mod.Memory().WriteUint32Le(resultNwritten, nwritten) {
@@ -1452,14 +1471,14 @@ func pathFilestatGetFn(_ context.Context, mod api.Module, params []uint64) sysca
 	path := uint32(params[2])
 	pathLen := uint32(params[3])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	dir, pathName, errno := atDir(fsc, mod.Memory(), fd, path, pathLen)
 	if errno != 0 {
 		return errno
 	}
 
 	// Stat the file without allocating a file descriptor.
 	//
-	// Note: `preopen` is a `sysfs.FS` interface, so passing the address of `st`
+	// Note: `dir.FS` is a `sysfs.FS` interface, so passing the address of `st`
 	// causes the value to escape to the heap because the compiler doesn't know
 	// whether the pointer will be retained by the method.
 	//
@@ -1468,9 +1487,9 @@ func pathFilestatGetFn(_ context.Context, mod api.Module, params []uint64) sysca
 	var st platform.Stat_t
 
 	if (flags & wasip1.LOOKUP_SYMLINK_FOLLOW) == 0 {
-		st, errno = preopen.Lstat(pathName)
+		st, errno = dir.FS.Lstat(dir.AtPath(pathName))
 	} else {
-		st, errno = preopen.Stat(pathName)
+		st, errno = dir.StatAt(pathName)
 	}
 	if errno != 0 {
 		return errno
@@ -1542,7 +1561,7 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) syscall.Errn
 	oldPath := uint32(params[2])
 	oldPathLen := uint32(params[3])
 
-	oldFS, oldName, errno := atPath(fsc, mem, oldFD, oldPath, oldPathLen)
+	oldDir, oldName, errno := atDir(fsc, mem, oldFD, oldPath, oldPathLen)
 	if errno != 0 {
 		return errno
 	}
@@ -1551,16 +1570,16 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) syscall.Errn
 	newPath := uint32(params[5])
 	newPathLen := uint32(params[6])
 
-	newFS, newName, errno := atPath(fsc, mem, newFD, newPath, newPathLen)
+	newDir, newName, errno := atDir(fsc, mem, newFD, newPath, newPathLen)
 	if errno != 0 {
 		return errno
 	}
 
-	if oldFS != newFS { // TODO: handle link across filesystems
+	if oldDir.FS != newDir.FS { // TODO: handle link across filesystems
 		return syscall.ENOSYS
 	}
 
-	return oldFS.Link(oldName, newName)
+	return oldDir.LinkAt(oldName, newDir, newName)
 }
 
 // pathOpen is the WASI function named PathOpenName which opens a file or
@@ -1643,7 +1662,7 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) syscall.Errn
 	fdflags := uint16(params[7])
 	resultOpenedFD := uint32(params[8])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), preopenFD, path, pathLen)
+	dir, pathName, errno := atDir(fsc, mod.Memory(), preopenFD, path, pathLen)
 	if errno != 0 {
 		return errno
 	}
@@ -1655,7 +1674,9 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) syscall.Errn
 		return syscall.EINVAL // use pathCreateDirectory!
 	}
 
-	newFD, errno := fsc.OpenFile(preopen, pathName, fileOpenFlags, 0o600)
+	// Resolve relative to the open directory, if possible, instead of again
+	// from the root.
+	newFD, errno := fsc.OpenFileAt(dir, pathName, fileOpenFlags, 0o600)
 	if errno != 0 {
 		return errno
 	}
@@ -1697,6 +1718,16 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) syscall.Errn
 // See https://github.com/WebAssembly/wasi-libc/blob/659ff414560721b1660a19685110e484a081c3d4/libc-bottom-half/sources/at_fdcwd.c
 // See https://linux.die.net/man/2/openat
 func atPath(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen uint32) (sysfs.FS, string, syscall.Errno) {
+	if dir, pathName, errno := atDir(fsc, mem, fd, p, pathLen); errno != 0 {
+		return nil, "", errno
+	} else {
+		return dir.FS, dir.AtPath(pathName), 0
+	}
+}
+
+// atDir is like atPath, except it returns the directory `fd` and the path
+// relative to it, for use with sys.FSContext OpenFileAt.
+func atDir(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen uint32) (*sys.FileEntry, string, syscall.Errno) {
 	b, ok := mem.Read(p, pathLen)
 	if !ok {
 		return nil, "", syscall.EFAULT
@@ -1728,11 +1759,8 @@ func atPath(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen uint32) (sy
 		return nil, "", errno
 	} else if !isDir {
 		return nil, "", syscall.ENOTDIR
-	} else if f.IsPreopen { // don't append the pre-open name
-		return f.FS, pathName, 0
 	} else {
-		// Join via concat to avoid name conflict on path.Join
-		return f.FS, f.Name + "/" + pathName, 0
+		return f, pathName, 0
 	}
 }
 
@@ -1780,6 +1808,9 @@ func openFlags(dirflags, oflags, fdflags uint16, rights uint32) (openFlags int)
 		openFlags |= syscall.O_APPEND
 		defaultMode = syscall.O_RDWR
 	}
+	if fdflags&wasip1.FD_NONBLOCK != 0 {
+		openFlags |= platform.O_NONBLOCK
+	}
 	// Since rights were discontinued in wasi, we only interpret RIGHT_FD_WRITE
 	// because it is the only way to know that we need to set write permissions
 	// on a file if the application did not pass any of O_CREATE, O_APPEND, nor
@@ -1830,16 +1861,18 @@ func pathReadlinkFn(_ context.Context, mod api.Module, params []uint64) syscall.
 		return errno
 	}
 
-	dst, errno := preopen.Readlink(p)
-	if errno != 0 {
-		return errno
+	dst, ok := mem.Read(buf, bufLen)
+	if !ok {
+		return syscall.EFAULT
 	}
 
-	if ok := mem.WriteString(buf, dst); !ok {
-		return syscall.EFAULT
+	// Like readlink in POSIX, a destination longer than buf is truncated.
+	n, errno := preopen.ReadlinkInto(p, dst)
+	if errno != 0 {
+		return errno
 	}
 
-	if !mem.WriteUint32Le(resultBufused, uint32(len(dst))) {
+	if !mem.WriteUint32Le(resultBufused, uint32(n)) {
 		return syscall.EFAULT
 	}
 	return 0
@@ -1991,11 +2024,11 @@ func pathSymlinkFn(_ context.Context, mod api.Module, params []uint64) syscall.E
 		return syscall.EFAULT
 	}
 
-	return dir.FS.Symlink(
+	return dir.SymlinkAt(
 		// Do not join old path since it's only resolved when dereference the link created here.
 		// And the dereference result depends on the opening directory's file descriptor at that point.
 		bufToStr(oldPathBuf),
-		path.Join(dir.Name, bufToStr(newPathBuf)),
+		bufToStr(newPathBuf),
 	)
 }
 
@@ -2039,10 +2072,10 @@ func pathUnlinkFileFn(_ context.Context, mod api.Module, params []uint64) syscal
 	path := uint32(params[1])
 	pathLen := uint32(params[2])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	dir, pathName, errno := atDir(fsc, mod.Memory(), fd, path, pathLen)
 	if errno != 0 {
 		return errno
 	}
 
-	return preopen.Unlink(pathName)
+	return dir.UnlinkAt(pathName)
 }
@@ -2647,6 +2647,21 @@ func Test_fdSeek(t *testing.T) {
 			expectedLog: `
 ==> wasi_snapshot_preview1.fd_seek(fd=4,offset=4,whence=0)
 <== (newoffset=4,errno=ESUCCESS)
+`,
+		},
+		{
+			name:           "SeekStart past 4GiB",
+			offset:         1 << 33, // doesn't fit in 32 bits
+			whence:         io.SeekStart,
+			expectedOffset: 1 << 33, // = offset
+			expectedMemory: []byte{
+				'?',                    // resultNewoffset is after this
+				0, 0, 0, 0, 2, 0, 0, 0, // = expectedOffset
+				'?',
+			},
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_seek(fd=4,offset=8589934592,whence=0)
+<== (newoffset=8589934592,errno=ESUCCESS)
 `,
 		},
 		{
@@ -4369,6 +4384,22 @@ func Test_pathReadlink(t *testing.T) {
 		}
 	})
 
+	t.Run("truncated", func(t *testing.T) {
+		const buf, bufLen, resultBufused = 0x100, 4, 0x200
+		require.True(t, mem.WriteByte(buf+bufLen, '?'))
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathReadlinkName,
+			uint64(dirFD), uint64(destinationPath), uint64(len(destinationPathName)),
+			buf, bufLen, resultBufused)
+
+		size, ok := mem.ReadUint32Le(resultBufused)
+		require.True(t, ok)
+		require.Equal(t, uint32(bufLen), size)
+		// Only the buffer is written.
+		actual, ok := mem.Read(buf, bufLen+1)
+		require.True(t, ok)
+		require.Equal(t, originalRelativePath[:bufLen]+"?", string(actual))
+	})
+
 	t.Run("errors", func(t *testing.T) {
 		for _, tc := range []struct {
 			name                                      string
@@ -319,6 +319,11 @@ func Test_openFlags(t *testing.T) {
 			fdflags:           wasip1.FD_APPEND,
 			expectedOpenFlags: platform.O_NOFOLLOW | syscall.O_RDWR | syscall.O_APPEND,
 		},
+		{
+			name:              "fdflags=FD_NONBLOCK",
+			fdflags:           wasip1.FD_NONBLOCK,
+			expectedOpenFlags: platform.O_NOFOLLOW | syscall.O_RDONLY | platform.O_NONBLOCK,
+		},
 		{
 			name:              "oflags=O_TRUNC|O_CREAT",
 			oflags:            wasip1.O_TRUNC | wasip1.O_CREAT,
@@ -1,6 +1,10 @@
 package custom
 
-import "io/fs"
+import (
+	"io/fs"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
 
 const (
 	NameFs          = "fs"
@@ -174,24 +178,20 @@ const (
 // ToJsMode is required because the mode property read in `GOOS=js` is
 // incompatible with normal go. Particularly the directory flag isn't the same.
 func ToJsMode(fm fs.FileMode) (jsMode uint32) {
-	switch {
-	case fm.IsRegular():
+	switch platform.FileTypeOf(fm) {
+	case platform.FileTypeRegular:
 		jsMode = S_IFREG
-	case fm.IsDir():
+	case platform.FileTypeDirectory:
 		jsMode = S_IFDIR
-	case fm&fs.ModeSymlink != 0:
+	case platform.FileTypeSymlink:
 		jsMode = S_IFLNK
-	case fm&fs.ModeDevice != 0:
-		// Unlike ModeDevice and ModeCharDevice, S_IFCHR and S_IFBLK are set
-		// mutually exclusively.
-		if fm&fs.ModeCharDevice != 0 {
-			jsMode = S_IFCHR
-		} else {
-			jsMode = S_IFBLK
-		}
-	case fm&fs.ModeNamedPipe != 0:
+	case platform.FileTypeCharDevice:
+		jsMode = S_IFCHR
+	case platform.FileTypeBlockDevice:
+		jsMode = S_IFBLK
+	case platform.FileTypeNamedPipe:
 		jsMode = S_IFIFO
-	case fm&fs.ModeSocket != 0:
+	case platform.FileTypeSocket:
 		jsMode = S_IFSOCK
 	default: // unknown
 		jsMode = 0
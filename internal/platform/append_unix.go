@@ -0,0 +1,17 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import "syscall"
+
+// setAppend ensures O_APPEND is set on the file descriptor.
+func setAppend(fd uintptr) syscall.Errno {
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+	if errno != 0 {
+		return errno
+	} else if flags&syscall.O_APPEND != 0 {
+		return 0 // already set, e.g. by OpenFile.
+	}
+	_, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETFL, flags|syscall.O_APPEND)
+	return errno
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || linux || freebsd)
+
+package platform
+
+import "syscall"
+
+// setAppend returns syscall.ENOSYS as the file descriptor flags can't be read,
+// so O_APPEND is emulated.
+func setAppend(uintptr) syscall.Errno {
+	return syscall.ENOSYS
+}
@@ -0,0 +1,55 @@
+package platform
+
+import "syscall"
+
+// IOOp is an operation for BatchIO: File.Pread of Buf at Offset, or
+// File.Pwrite when Write is true.
+type IOOp struct {
+	File   File
+	Write  bool
+	Buf    []byte
+	Offset int64
+}
+
+// IOResult is the result of an IOOp, the same as its File.Pread or
+// File.Pwrite would return.
+type IOResult struct {
+	N     int
+	Errno syscall.Errno
+}
+
+// BatchIO performs `ops`, returning their results in the same order.
+//
+// This is for hosts which read or write many files at once, where system
+// call overhead dominates. On Linux, operations on files opened from the
+// host are submitted together with io_uring, so a batch costs a few system
+// calls, instead of one per operation. Otherwise, such as when io_uring is
+// unavailable or the file is wrapped, each operation calls File.Pread or
+// File.Pwrite.
+//
+// # Notes
+//
+//   - Operations may be performed concurrently and in any order, so a batch
+//     shouldn't write a range another reads or writes.
+//   - Like File.Pwrite, a write is only short when it fails.
+func BatchIO(ops []IOOp) []IOResult {
+	results := make([]IOResult, len(ops))
+	batchIO(ops, results)
+	return results
+}
+
+// sequentialIO performs each of `ops` with its File.
+func sequentialIO(ops []IOOp, results []IOResult) {
+	for i := range ops {
+		results[i] = fileIO(&ops[i])
+	}
+}
+
+func fileIO(op *IOOp) (r IOResult) {
+	if op.Write {
+		r.N, r.Errno = op.File.Pwrite(op.Buf, op.Offset)
+	} else {
+		r.N, r.Errno = op.File.Pread(op.Buf, op.Offset)
+	}
+	return
+}
@@ -0,0 +1,320 @@
+package platform
+
+import (
+	"runtime"
+	gosync "sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	_SYS_IO_URING_SETUP = 425
+	_SYS_IO_URING_ENTER = 426
+
+	_IORING_OFF_SQ_RING = 0
+	_IORING_OFF_CQ_RING = 0x8000000
+	_IORING_OFF_SQES    = 0x10000000
+
+	_IORING_FEAT_SINGLE_MMAP = 1 << 0
+	_IORING_ENTER_GETEVENTS  = 1 << 0
+
+	// _IORING_OP_READV and _IORING_OP_WRITEV are used instead of READ and
+	// WRITE, as they are supported by all kernels with io_uring (5.1+).
+	_IORING_OP_READV  = 1
+	_IORING_OP_WRITEV = 2
+
+	// uringEntries is the size of the submission queue, which limits the
+	// operations submitted at once.
+	uringEntries = 64
+	uringSQESize = 64
+	uringCQESize = 16
+)
+
+// uringParams is struct io_uring_params.
+type uringParams struct {
+	sqEntries, cqEntries, flags, sqThreadCPU, sqThreadIdle, features, wqFd uint32
+	resv                                                                   [3]uint32
+	sqOff                                                                  uringSQOffsets
+	cqOff                                                                  uringCQOffsets
+}
+
+// uringSQOffsets is struct io_sqring_offsets.
+type uringSQOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+// uringCQOffsets is struct io_cqring_offsets.
+type uringCQOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+var (
+	sharedUringOnce gosync.Once
+	// sharedUring is used by all calls to BatchIO, or nil if io_uring is
+	// unavailable, e.g. disabled by seccomp. It is never closed.
+	sharedUring *uring
+)
+
+// uringEnters counts calls to io_uring_enter, for benchmarks.
+var uringEnters uint64
+
+func batchIO(ops []IOOp, results []IOResult) {
+	sharedUringOnce.Do(func() {
+		sharedUring, _ = newUring()
+	})
+	if sharedUring == nil {
+		sequentialIO(ops, results)
+		return
+	}
+
+	// Submit operations on host files to io_uring, and perform the rest.
+	var indexes []int
+	var fds []int32
+	for i := range ops {
+		if fd, ok := uringFd(&ops[i]); ok {
+			indexes = append(indexes, i)
+			fds = append(fds, fd)
+		} else {
+			results[i] = fileIO(&ops[i])
+		}
+	}
+	for len(indexes) > 0 {
+		n := len(indexes)
+		if n > uringEntries {
+			n = uringEntries
+		}
+		completed, errno := sharedUring.run(ops, results, indexes[:n], fds[:n])
+		for j, i := range indexes[:n] {
+			if completed[j] {
+				finishUringIO(&ops[i], &results[i])
+			} else {
+				results[i] = fileIO(&ops[i]) // the ring never performed it.
+			}
+		}
+		indexes, fds = indexes[n:], fds[n:]
+		if errno != 0 {
+			for _, i := range indexes {
+				results[i] = fileIO(&ops[i])
+			}
+			return
+		}
+	}
+}
+
+// uringFd returns the file descriptor to use for `op`, or false if it must
+// be performed by its File, to keep the same semantics.
+func uringFd(op *IOOp) (int32, bool) {
+	f, ok := op.File.(*fsFile)
+	if !ok || len(op.Buf) == 0 || f.append != nil || f.direct {
+		return 0, false
+	}
+	fd, ok := f.file.(fdFile)
+	if !ok {
+		return 0, false
+	}
+	if op.Write {
+		f.stHint = nil
+	}
+	return int32(fd.Fd()), true
+}
+
+// finishUringIO retries an operation io_uring couldn't complete, and
+// completes a short write, like File.Pwrite.
+func finishUringIO(op *IOOp, r *IOResult) {
+	switch {
+	case r.Errno == syscall.EINTR || r.Errno == syscall.EAGAIN:
+		*r = fileIO(op)
+	case r.Errno == 0 && op.Write && r.N < len(op.Buf):
+		n, errno := op.File.Pwrite(op.Buf[r.N:], op.Offset+int64(r.N))
+		r.N, r.Errno = r.N+n, errno
+	}
+}
+
+// uring is an io_uring instance, used by one caller at a time.
+type uring struct {
+	mux gosync.Mutex
+	fd  int
+
+	// mmaps are the mappings of the rings, released by close.
+	mmaps [][]byte
+	// broken is true after io_uring_enter failed unexpectedly, when the
+	// rings may be inconsistent.
+	broken bool
+
+	sqes []byte
+
+	sqTail  *uint32
+	sqMask  uint32
+	sqArray []uint32
+
+	cqHead, cqTail *uint32
+	cqMask         uint32
+	cqes           []byte
+}
+
+func newUring() (*uring, syscall.Errno) {
+	var p uringParams
+	fd, _, e := syscall.Syscall(_SYS_IO_URING_SETUP, uringEntries, uintptr(unsafe.Pointer(&p)), 0)
+	if e != 0 {
+		return nil, e
+	}
+	r := &uring{fd: int(fd)}
+
+	sqSize := int(p.sqOff.array + p.sqEntries*4)
+	cqSize := int(p.cqOff.cqes + p.cqEntries*uringCQESize)
+	singleMmap := p.features&_IORING_FEAT_SINGLE_MMAP != 0
+	if singleMmap && cqSize > sqSize {
+		sqSize = cqSize
+	}
+	sqRing, errno := r.mmap(_IORING_OFF_SQ_RING, sqSize)
+	if errno != 0 {
+		r.close()
+		return nil, errno
+	}
+	cqRing := sqRing
+	if !singleMmap {
+		if cqRing, errno = r.mmap(_IORING_OFF_CQ_RING, cqSize); errno != 0 {
+			r.close()
+			return nil, errno
+		}
+	}
+	if r.sqes, errno = r.mmap(_IORING_OFF_SQES, int(p.sqEntries*uringSQESize)); errno != 0 {
+		r.close()
+		return nil, errno
+	}
+
+	r.sqTail = uint32At(sqRing, p.sqOff.tail)
+	r.sqMask = *uint32At(sqRing, p.sqOff.ringMask)
+	r.sqArray = unsafe.Slice(uint32At(sqRing, p.sqOff.array), p.sqEntries)
+	r.cqHead = uint32At(cqRing, p.cqOff.head)
+	r.cqTail = uint32At(cqRing, p.cqOff.tail)
+	r.cqMask = *uint32At(cqRing, p.cqOff.ringMask)
+	r.cqes = cqRing[p.cqOff.cqes:]
+	return r, 0
+}
+
+func (r *uring) mmap(offset int64, size int) ([]byte, syscall.Errno) {
+	m, err := syscall.Mmap(r.fd, offset, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err != nil {
+		return nil, UnwrapOSError(err)
+	}
+	r.mmaps = append(r.mmaps, m)
+	return m, 0
+}
+
+func uint32At(b []byte, off uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&b[off]))
+}
+
+// close releases the mappings and file descriptor of a partially created
+// uring.
+func (r *uring) close() {
+	for _, m := range r.mmaps {
+		_ = syscall.Munmap(m)
+	}
+	_ = syscall.Close(r.fd)
+}
+
+// run submits the operations at `indexes` of `ops`, which has at most
+// uringEntries, and waits for them to complete. The result of each is
+// recorded in `results`, and `completed` is true at the same position in
+// `indexes`.
+//
+// When io_uring_enter fails unexpectedly, this returns its error, and the
+// ring isn't used again. Operations which weren't completed by then weren't
+// and won't be performed, so the caller must perform them instead.
+//
+// Note: The buffers of `ops` escape to the heap, as they are also passed to
+// File methods, so they don't move while the kernel uses them.
+func (r *uring) run(ops []IOOp, results []IOResult, indexes []int, fds []int32) (completed []bool, errno syscall.Errno) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	completed = make([]bool, len(indexes))
+	if r.broken {
+		return completed, syscall.ENOSYS
+	}
+	iovecs := make([]syscall.Iovec, len(indexes))
+	tail := atomic.LoadUint32(r.sqTail)
+	for j, i := range indexes {
+		op := &ops[i]
+		iovecs[j].Base = &op.Buf[0]
+		iovecs[j].SetLen(len(op.Buf))
+
+		idx := tail & r.sqMask
+		sqe := r.sqes[idx*uringSQESize : (idx+1)*uringSQESize]
+		for k := range sqe {
+			sqe[k] = 0
+		}
+		if op.Write {
+			sqe[0] = _IORING_OP_WRITEV
+		} else {
+			sqe[0] = _IORING_OP_READV
+		}
+		*(*int32)(unsafe.Pointer(&sqe[4])) = fds[j]
+		*(*uint64)(unsafe.Pointer(&sqe[8])) = uint64(op.Offset)
+		*(*uint64)(unsafe.Pointer(&sqe[16])) = uint64(uintptr(unsafe.Pointer(&iovecs[j])))
+		*(*uint32)(unsafe.Pointer(&sqe[24])) = 1 // count of iovecs
+		*(*uint64)(unsafe.Pointer(&sqe[32])) = uint64(j)
+		r.sqArray[idx] = idx
+		tail++
+	}
+	atomic.StoreUint32(r.sqTail, tail)
+
+	// Keep the buffers and iovecs alive until the kernel is done with them,
+	// including on error.
+	defer runtime.KeepAlive(ops)
+	defer runtime.KeepAlive(iovecs)
+
+	toSubmit, pending := len(indexes), len(indexes)
+	for pending > 0 {
+		atomic.AddUint64(&uringEnters, 1)
+		n, _, e := syscall.Syscall6(_SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(toSubmit),
+			uintptr(pending), _IORING_ENTER_GETEVENTS, 0, 0)
+		switch e {
+		case 0:
+			toSubmit -= int(n)
+		case syscall.EINTR, syscall.EAGAIN, syscall.EBUSY:
+		default:
+			r.broken = true
+			// Entries not yet submitted stay in the ring, which is never
+			// entered again. Those submitted may still be in flight, so wait
+			// for the kernel to complete them, without entering.
+			inFlight := pending - toSubmit
+			for inFlight > 0 {
+				if reaped := r.reap(results, indexes, completed); reaped > 0 {
+					inFlight -= reaped
+				} else {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			return completed, e
+		}
+		pending -= r.reap(results, indexes, completed)
+	}
+	return completed, 0
+}
+
+// reap records the results of completed operations, returning their count.
+func (r *uring) reap(results []IOResult, indexes []int, completed []bool) (n int) {
+	head := atomic.LoadUint32(r.cqHead)
+	tail := atomic.LoadUint32(r.cqTail)
+	for ; head != tail; head++ {
+		cqe := r.cqes[(head&r.cqMask)*uringCQESize:]
+		j := *(*uint64)(unsafe.Pointer(&cqe[0]))
+		i := indexes[j]
+		if res := *(*int32)(unsafe.Pointer(&cqe[8])); res < 0 {
+			results[i] = IOResult{Errno: syscall.Errno(-res)}
+		} else {
+			results[i] = IOResult{N: int(res)}
+		}
+		completed[j] = true
+		n++
+	}
+	atomic.StoreUint32(r.cqHead, head)
+	return
+}
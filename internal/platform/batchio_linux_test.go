@@ -0,0 +1,83 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestUring_runFails(t *testing.T) {
+	r, errno := newUring()
+	if errno != 0 {
+		t.Skip("io_uring is unavailable")
+	}
+	defer r.close()
+
+	p := path.Join(t.TempDir(), wazeroFile)
+	require.NoError(t, os.WriteFile(p, []byte("wazero"), 0o600))
+	f := openFsFile(t, p, os.O_RDONLY, 0)
+	defer f.Close()
+	ops := []IOOp{{File: f, Buf: make([]byte, 4)}, {File: f, Buf: make([]byte, 2), Offset: 4}}
+	fd, ok := uringFd(&ops[0])
+	require.True(t, ok)
+
+	// Make io_uring_enter fail, by entering a file that isn't a ring.
+	ringFd := r.fd
+	r.fd = int(fd)
+	results := make([]IOResult, len(ops))
+	completed, errno := r.run(ops, results, []int{0, 1}, []int32{fd, fd})
+	r.fd = ringFd
+	require.NotEqual(t, syscall.Errno(0), errno)
+	require.True(t, r.broken)
+
+	// Nothing was submitted, so the caller must perform all operations.
+	require.Equal(t, []bool{false, false}, completed)
+
+	// The ring isn't used again.
+	completed, errno = r.run(ops, results, []int{0}, []int32{fd})
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+	require.Equal(t, []bool{false}, completed)
+}
+
+func BenchmarkBatchIO(b *testing.B) {
+	tmpDir := b.TempDir()
+	ops := make([]IOOp, 32)
+	for i := range ops {
+		p := path.Join(tmpDir, fmt.Sprint(i))
+		if err := os.WriteFile(p, make([]byte, 4096), 0o600); err != nil {
+			b.Fatal(err)
+		}
+		f, errno := OpenFile(p, os.O_RDONLY, 0)
+		if errno != 0 {
+			b.Fatal(errno)
+		}
+		defer f.Close()
+		ops[i] = IOOp{File: NewFsFile(p, os.O_RDONLY, f), Buf: make([]byte, 4096)}
+	}
+
+	b.Run("Pread", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sequentialIO(ops, make([]IOResult, len(ops)))
+		}
+		b.ReportMetric(float64(len(ops)), "syscalls/op")
+	})
+
+	b.Run("BatchIO", func(b *testing.B) {
+		BatchIO(ops) // initialize io_uring
+		if sharedUring == nil {
+			b.Skip("io_uring is unavailable")
+		}
+		enters := atomic.LoadUint64(&uringEnters)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			BatchIO(ops)
+		}
+		enters = atomic.LoadUint64(&uringEnters) - enters
+		b.ReportMetric(float64(enters)/float64(b.N), "syscalls/op")
+	})
+}
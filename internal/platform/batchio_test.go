@@ -0,0 +1,63 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	gofstest "testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestBatchIO(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := make([]File, 3)
+	for i := range files {
+		p := path.Join(tmpDir, fmt.Sprint(i))
+		require.NoError(t, os.WriteFile(p, []byte("wazero"), 0o600))
+		files[i] = openFsFile(t, p, os.O_RDWR, 0)
+		defer files[i].Close()
+	}
+	readOnly := openFsFile(t, path.Join(tmpDir, "0"), os.O_RDONLY, 0)
+	defer readOnly.Close()
+	// A file without a file descriptor is read with its Pread.
+	mapFile, err := gofstest.MapFS{"mem": {Data: []byte("wazero")}}.Open("mem")
+	require.NoError(t, err)
+	mf := NewFsFile("mem", os.O_RDONLY, mapFile)
+
+	bufs := [][]byte{make([]byte, 4), make([]byte, 4), []byte("WAZ"), make([]byte, 10), make([]byte, 2), {}}
+	results := BatchIO([]IOOp{
+		{File: files[0], Buf: bufs[0]},
+		{File: files[1], Buf: bufs[1], Offset: 2},
+		{File: files[2], Write: true, Buf: bufs[2]},
+		{File: mf, Buf: bufs[3], Offset: 4},
+		{File: readOnly, Write: true, Buf: bufs[4]},
+		{File: files[0], Buf: bufs[5]},
+	})
+	require.Equal(t, []IOResult{
+		{N: 4},
+		{N: 4},
+		{N: 3},
+		{N: 2},
+		{Errno: syscall.EBADF},
+		{},
+	}, results)
+	require.Equal(t, "waze", string(bufs[0]))
+	require.Equal(t, "zero", string(bufs[1]))
+	require.Equal(t, "ro", string(bufs[3][:2]))
+
+	buf := make([]byte, 6)
+	requirePread(t, files[2], buf, 0)
+	require.Equal(t, "WAZero", string(buf))
+
+	// More operations than fit in a submission queue still complete.
+	ops := make([]IOOp, 200)
+	for i := range ops {
+		ops[i] = IOOp{File: files[i%len(files)], Buf: make([]byte, 1), Offset: int64(i % 6)}
+	}
+	for i, r := range BatchIO(ops) {
+		require.Equal(t, IOResult{N: 1}, r, i)
+	}
+}
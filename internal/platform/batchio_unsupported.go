@@ -0,0 +1,7 @@
+//go:build !linux
+
+package platform
+
+func batchIO(ops []IOOp, results []IOResult) {
+	sequentialIO(ops, results)
+}
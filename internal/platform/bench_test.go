@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"strconv"
 	"syscall"
 	"testing"
 )
@@ -94,3 +95,26 @@ func BenchmarkFsFileRead(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkFsFileReaddir(b *testing.B) {
+	tmpDir := b.TempDir()
+	for i := 0; i < 10000; i++ {
+		if err := os.WriteFile(path.Join(tmpDir, strconv.Itoa(i)), nil, 0o600); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f, errno := OpenFile(tmpDir, syscall.O_RDONLY, 0)
+		if errno != 0 {
+			b.Fatal(errno)
+		}
+		dir := NewFsFile(tmpDir, syscall.O_RDONLY, f)
+		if _, errno = dir.Readdir(-1); errno != 0 {
+			b.Fatal(errno)
+		}
+		dir.Close()
+	}
+}
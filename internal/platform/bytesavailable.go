@@ -0,0 +1,64 @@
+package platform
+
+import (
+	"io"
+	"io/fs"
+	"syscall"
+)
+
+// BytesAvailable implements File.BytesAvailable
+func (f *fsFile) BytesAvailable() (int64, syscall.Errno) {
+	if f.isClosed() {
+		return 0, syscall.EBADF
+	} else if errno := f.checkPathOnly(); errno != 0 {
+		return 0, errno
+	} else if f.accessMode == syscall.O_WRONLY {
+		return 0, syscall.EBADF
+	}
+
+	ft, errno := f.cachedStat()
+	if errno != 0 {
+		return 0, errno
+	}
+	switch ft {
+	case fs.ModeDir:
+		return 0, syscall.EISDIR
+	case 0: // regular file
+		return f.remainingSize()
+	}
+
+	// Prefer the raw connection, as os.File.Fd makes the file blocking.
+	if sc, ok := f.file.(syscall.Conn); ok {
+		rc, err := sc.SyscallConn()
+		if err != nil {
+			return 0, UnwrapOSError(err)
+		}
+		var n int64
+		if err = rc.Control(func(fd uintptr) { n, errno = fionread(fd) }); err != nil {
+			return 0, UnwrapOSError(err)
+		}
+		return n, errno
+	} else if fd, ok := f.file.(fdFile); ok {
+		return fionread(fd.Fd())
+	}
+	return 0, syscall.ENOSYS
+}
+
+// remainingSize returns the size of a regular file after its offset.
+func (f *fsFile) remainingSize() (int64, syscall.Errno) {
+	s, ok := f.file.(io.Seeker)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	off, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, UnwrapOSError(err)
+	}
+	st, errno := statFile(f.file)
+	if errno != 0 {
+		return 0, errno
+	} else if st.Size <= off {
+		return 0, 0
+	}
+	return st.Size - off, 0
+}
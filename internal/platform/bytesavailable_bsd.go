@@ -0,0 +1,7 @@
+//go:build darwin || freebsd
+
+package platform
+
+// ioctlFionread is the ioctl request of `FIONREAD`, which isn't defined in
+// the syscall package.
+const ioctlFionread = 0x4004667f
@@ -0,0 +1,7 @@
+package platform
+
+import "syscall"
+
+// ioctlFionread is the ioctl request of `FIONREAD`, which Linux also names
+// `TIOCINQ`.
+const ioctlFionread = syscall.TIOCINQ
@@ -0,0 +1,87 @@
+package platform
+
+import (
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFsFile_BytesAvailable(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := path.Join(tmpDir, wazeroFile)
+	require.NoError(t, os.WriteFile(filePath, []byte("wazero"), 0o600))
+
+	t.Run("file", func(t *testing.T) {
+		f := openFsFile(t, filePath, syscall.O_RDONLY, 0)
+		defer f.Close()
+
+		n, errno := f.BytesAvailable()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(6), n)
+
+		_, errno = f.Read(make([]byte, 2))
+		require.EqualErrno(t, 0, errno)
+		n, errno = f.BytesAvailable()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(4), n)
+
+		_, errno = f.Seek(10, io.SeekEnd)
+		require.EqualErrno(t, 0, errno)
+		n, errno = f.BytesAvailable()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(0), n)
+
+		require.EqualErrno(t, 0, f.Close())
+		_, errno = f.BytesAvailable()
+		require.EqualErrno(t, syscall.EBADF, errno)
+	})
+
+	t.Run("write-only", func(t *testing.T) {
+		f := openFsFile(t, filePath, syscall.O_WRONLY, 0)
+		defer f.Close()
+
+		_, errno := f.BytesAvailable()
+		require.EqualErrno(t, syscall.EBADF, errno)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		d := openFsFile(t, tmpDir, syscall.O_RDONLY, 0)
+		defer d.Close()
+
+		_, errno := d.BytesAvailable()
+		require.EqualErrno(t, syscall.EISDIR, errno)
+	})
+
+	t.Run("pipe", func(t *testing.T) {
+		if runtime.GOOS == "js" {
+			t.Skip("pipes aren't supported on " + runtime.GOOS)
+		}
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer w.Close()
+
+		f := NewFsFile("pipe", syscall.O_RDONLY, r)
+		defer f.Close()
+
+		n, errno := f.BytesAvailable()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(0), n)
+
+		_, err = w.Write([]byte("wazero"))
+		require.NoError(t, err)
+		n, errno = f.BytesAvailable()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(6), n)
+
+		// The count is exact, so a single read of that size doesn't block.
+		buf := make([]byte, n)
+		read, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, read)
+	})
+}
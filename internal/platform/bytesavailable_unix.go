@@ -0,0 +1,23 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// fionread returns the count of bytes which can be read from `fd` without
+// blocking, or syscall.ENOSYS if it isn't a pipe, socket or terminal.
+func fionread(fd uintptr) (int64, syscall.Errno) {
+	var n int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlFionread, uintptr(unsafe.Pointer(&n)))
+	switch errno {
+	case 0:
+		return int64(n), 0
+	case syscall.ENOTTY, syscall.EINVAL: // e.g. a device other than a terminal
+		return 0, syscall.ENOSYS
+	default:
+		return 0, errno
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || linux || freebsd || windows)
+
+package platform
+
+import "syscall"
+
+// fionread returns syscall.ENOSYS, as it isn't supported on this platform.
+func fionread(uintptr) (int64, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
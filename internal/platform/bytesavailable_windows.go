@@ -0,0 +1,26 @@
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// fionread returns the count of bytes which can be read from the pipe
+// `handle` without blocking, or syscall.ENOSYS if it isn't a pipe.
+func fionread(handle uintptr) (int64, syscall.Errno) {
+	var totalBytesAvail uint32
+	totalBytesPtr := unsafe.Pointer(&totalBytesAvail)
+	r, _, err := procPeekNamedPipe.Call(
+		handle,                 // [in]            HANDLE  hNamedPipe,
+		0,                      // [out, optional] LPVOID  lpBuffer,
+		0,                      // [in]            DWORD   nBufferSize,
+		0,                      // [out, optional] LPDWORD lpBytesRead
+		uintptr(totalBytesPtr), // [out, optional] LPDWORD lpTotalBytesAvail,
+		0)                      // [out, optional] LPDWORD lpBytesLeftThisMessage
+	if r != 0 {
+		return int64(totalBytesAvail), 0
+	} else if err == syscall.ERROR_BROKEN_PIPE {
+		return 0, 0 // the writer closed, so the next read is EOF.
+	}
+	return 0, syscall.ENOSYS
+}
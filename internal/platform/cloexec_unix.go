@@ -0,0 +1,20 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import "syscall"
+
+// setCloexec sets or clears FD_CLOEXEC on the file descriptor.
+func setCloexec(fd uintptr, enable bool) syscall.Errno {
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFD, 0)
+	if errno != 0 {
+		return errno
+	}
+	if enable {
+		flags |= syscall.FD_CLOEXEC
+	} else {
+		flags &^= syscall.FD_CLOEXEC
+	}
+	_, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETFD, flags)
+	return errno
+}
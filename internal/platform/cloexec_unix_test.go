@@ -0,0 +1,39 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFsFileSetCloexec(t *testing.T) {
+	p := path.Join(t.TempDir(), wazeroFile)
+	of, errno := OpenFile(p, os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	f := NewFsFile(p, os.O_RDWR, of)
+	defer f.Close()
+	fd := of.(*os.File).Fd()
+
+	// Files are opened close-on-exec by default.
+	require.True(t, isCloexec(t, fd))
+
+	require.EqualErrno(t, 0, f.SetCloexec(false))
+	require.False(t, isCloexec(t, fd))
+
+	require.EqualErrno(t, 0, f.SetCloexec(true))
+	require.True(t, isCloexec(t, fd))
+
+	// Files without a descriptor don't support it.
+	require.EqualErrno(t, syscall.ENOSYS, NewFsFile(wazeroFile, os.O_RDONLY, embedFile(t)).SetCloexec(true))
+}
+
+func isCloexec(t *testing.T, fd uintptr) bool {
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFD, 0)
+	require.EqualErrno(t, 0, errno)
+	return flags&syscall.FD_CLOEXEC != 0
+}
@@ -0,0 +1,9 @@
+//go:build !(darwin || linux || freebsd || windows)
+
+package platform
+
+import "syscall"
+
+func setCloexec(uintptr, bool) syscall.Errno {
+	return syscall.ENOSYS
+}
@@ -0,0 +1,14 @@
+package platform
+
+import "syscall"
+
+// setCloexec clears or sets HANDLE_FLAG_INHERIT on the handle, as Windows
+// controls inheritance instead of close-on-exec.
+func setCloexec(fd uintptr, enable bool) syscall.Errno {
+	var flags uint32
+	if !enable {
+		flags = syscall.HANDLE_FLAG_INHERIT
+	}
+	err := syscall.SetHandleInformation(syscall.Handle(fd), syscall.HANDLE_FLAG_INHERIT, flags)
+	return UnwrapOSError(err)
+}
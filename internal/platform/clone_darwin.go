@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Clone creates `dst` as a copy-on-write clone of `src`, using `clonefile`.
+// `dst` must not exist.
+//
+// This returns syscall.ENOTSUP when the filesystem can't clone, e.g. HFS+.
+func Clone(src, dst string) syscall.Errno {
+	srcPtr, err := syscall.BytePtrFromString(src)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	dstPtr, err := syscall.BytePtrFromString(dst)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	_, _, errno := syscall_syscall6(libc_clonefile_trampoline_addr,
+		uintptr(unsafe.Pointer(srcPtr)), uintptr(unsafe.Pointer(dstPtr)), 0, 0, 0, 0)
+	return errno
+}
+
+// libc_clonefile_trampoline_addr is the address of the
+// `libc_clonefile_trampoline` symbol, defined in `clone_darwin.s`.
+//
+// We use this to invoke the syscall through syscall_syscall6.
+var libc_clonefile_trampoline_addr uintptr
+
+// Imports the clonefile symbol from libc as `libc_clonefile`.
+//
+// Note: CGO mechanisms are used in darwin regardless of the CGO_ENABLED value
+// or the "cgo" build flag. See /RATIONALE.md for why.
+//go:cgo_import_dynamic libc_clonefile clonefile "/usr/lib/libSystem.B.dylib"
@@ -0,0 +1,45 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"syscall"
+)
+
+// _FICLONE is the `ioctl` request which shares the extents of a file with
+// another. This isn't defined in the syscall package.
+const _FICLONE = 0x40049409
+
+// Clone creates `dst` as a copy-on-write clone of the regular file `src`,
+// using the FICLONE ioctl. `dst` must not exist.
+//
+// This returns syscall.ENOTSUP when the filesystem can't reflink, e.g. ext4.
+func Clone(src, dst string) syscall.Errno {
+	srcFd, err := syscall.Open(src, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return UnwrapOSError(err)
+	}
+	defer syscall.Close(srcFd)
+
+	var st syscall.Stat_t
+	if err = syscall.Fstat(srcFd, &st); err != nil {
+		return UnwrapOSError(err)
+	}
+	dstFd, err := syscall.Open(dst, syscall.O_WRONLY|syscall.O_CREAT|syscall.O_EXCL|syscall.O_CLOEXEC, st.Mode&0o777)
+	if err != nil {
+		return UnwrapOSError(err)
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(dstFd), _FICLONE, uintptr(srcFd))
+	syscall.Close(dstFd)
+	if errno == 0 {
+		return 0
+	}
+	_ = syscall.Unlink(dst)
+	switch errno {
+	case syscall.EOPNOTSUPP, syscall.EINVAL, syscall.ENOTTY:
+		// Filesystems which can't reflink return one of these.
+		return syscall.ENOTSUP
+	}
+	return errno
+}
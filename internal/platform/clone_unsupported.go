@@ -0,0 +1,10 @@
+//go:build !((amd64 || arm64 || riscv64) && linux) && !darwin
+
+package platform
+
+import "syscall"
+
+// Clone returns syscall.ENOTSUP as reflinks aren't supported.
+func Clone(src, dst string) syscall.Errno {
+	return syscall.ENOTSUP
+}
@@ -0,0 +1,69 @@
+package platform
+
+import "syscall"
+
+// cloneRangeFile is implemented by files which can share a range of their
+// storage with another file, such as fsFile.
+type cloneRangeFile interface {
+	CloneRange(dst File, srcOff, dstOff, length int64) syscall.Errno
+}
+
+// CloneRange makes `length` bytes of `dst` at `dstOff` a copy-on-write clone
+// of those in `src` at `srcOff`, sharing the storage instead of copying it.
+// `dst` grows if the range ends past its size.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.ENOTSUP: the files, platform or filesystem can't share
+//     storage, or the files are on different filesystems. Callers should
+//     fall back to copying the range.
+//   - syscall.EBADF: either file is closed, `src` isn't readable or `dst`
+//     isn't writeable.
+//   - syscall.EINVAL: an offset is negative, `length` isn't positive, or
+//     the range isn't aligned as described below.
+//   - syscall.EISDIR: either file is a directory.
+//
+// # Notes
+//
+//   - This is like the FICLONERANGE `ioctl` on Linux, supported by
+//     filesystems such as Btrfs and XFS. Other platforms return
+//     syscall.ENOTSUP.
+//   - Both offsets and `length` must be multiples of the block size of the
+//     filesystem, usually 4KiB, except the range may end at the end of
+//     `src` when that isn't aligned.
+//   - Files which don't implement CloneRange, such as wrappers which change
+//     the contents, return syscall.ENOTSUP.
+func CloneRange(src, dst File, srcOff, dstOff, length int64) syscall.Errno {
+	if f, ok := src.(cloneRangeFile); ok {
+		return f.CloneRange(dst, srcOff, dstOff, length)
+	}
+	return syscall.ENOTSUP
+}
+
+// CloneRange implements cloneRangeFile.CloneRange
+func (f *fsFile) CloneRange(dst File, srcOff, dstOff, length int64) syscall.Errno {
+	d, ok := dst.(*fsFile)
+	if !ok {
+		return syscall.ENOTSUP
+	} else if errno := f.isDirErrno(); errno != 0 {
+		return errno
+	} else if errno = d.isDirErrno(); errno != 0 {
+		return errno
+	} else if f.accessMode == syscall.O_WRONLY || d.accessMode == syscall.O_RDONLY {
+		return syscall.EBADF
+	} else if srcOff < 0 || dstOff < 0 || length <= 0 {
+		return syscall.EINVAL
+	}
+
+	srcFd, ok := f.file.(fdFile)
+	if !ok {
+		return syscall.ENOTSUP
+	}
+	dstFd, ok := d.file.(fdFile)
+	if !ok {
+		return syscall.ENOTSUP
+	}
+	d.stHint = nil
+	return cloneRange(srcFd.Fd(), dstFd.Fd(), srcOff, dstOff, length)
+}
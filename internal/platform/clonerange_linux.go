@@ -0,0 +1,45 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// _FICLONERANGE is the `ioctl` request which shares a range of the extents
+// of a file with another. This isn't defined in the syscall package.
+const _FICLONERANGE = 0x4020940d
+
+// fileCloneRange is the argument of _FICLONERANGE, `struct file_clone_range`.
+type fileCloneRange struct {
+	srcFd     int64
+	srcOffset uint64
+	srcLength uint64
+	dstOffset uint64
+}
+
+// cloneRange shares the range with the FICLONERANGE ioctl on `dstFd`.
+func cloneRange(srcFd, dstFd uintptr, srcOff, dstOff, length int64) syscall.Errno {
+	arg := fileCloneRange{
+		srcFd:     int64(srcFd),
+		srcOffset: uint64(srcOff),
+		srcLength: uint64(length),
+		dstOffset: uint64(dstOff),
+	}
+	for {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dstFd, _FICLONERANGE, uintptr(unsafe.Pointer(&arg)))
+		switch errno {
+		case 0:
+			return 0
+		case syscall.EINTR:
+			continue
+		case syscall.EOPNOTSUPP, syscall.ENOTTY, syscall.EXDEV:
+			// Filesystems which can't reflink return EOPNOTSUPP or ENOTTY,
+			// and EXDEV is returned across filesystems. Unlike Clone, EINVAL
+			// isn't converted, as it is returned for unaligned ranges.
+			return syscall.ENOTSUP
+		}
+		return errno
+	}
+}
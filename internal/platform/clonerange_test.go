@@ -0,0 +1,66 @@
+package platform
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestCloneRange(t *testing.T) {
+	const blockSize = 4096
+	tmpDir := t.TempDir()
+	content := append(bytes.Repeat([]byte{'a'}, blockSize), bytes.Repeat([]byte{'b'}, blockSize)...)
+	srcPath, dstPath := path.Join(tmpDir, "src"), path.Join(tmpDir, "dst")
+	require.NoError(t, os.WriteFile(srcPath, content, 0o600))
+	require.NoError(t, os.WriteFile(dstPath, nil, 0o600))
+
+	open := func(path string, flag int) File {
+		f, errno := OpenFile(path, flag, 0)
+		require.EqualErrno(t, 0, errno)
+		return NewFsFile(path, flag, f)
+	}
+	src, dst := open(srcPath, syscall.O_RDONLY), open(dstPath, syscall.O_RDWR)
+	defer src.Close()
+	defer dst.Close()
+
+	t.Run("EINVAL", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EINVAL, CloneRange(src, dst, -1, 0, blockSize))
+		require.EqualErrno(t, syscall.EINVAL, CloneRange(src, dst, 0, -1, blockSize))
+		require.EqualErrno(t, syscall.EINVAL, CloneRange(src, dst, 0, 0, 0))
+	})
+
+	t.Run("EBADF", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EBADF, CloneRange(dst, src, 0, 0, blockSize))
+	})
+
+	t.Run("EISDIR", func(t *testing.T) {
+		dir := open(tmpDir, syscall.O_RDONLY)
+		defer dir.Close()
+		require.EqualErrno(t, syscall.EISDIR, CloneRange(dir, dst, 0, 0, blockSize))
+	})
+
+	t.Run("ENOTSUP without a descriptor", func(t *testing.T) {
+		mem := NewMemoryFile(content, syscall.O_RDWR)
+		require.EqualErrno(t, syscall.ENOTSUP, CloneRange(src, mem, 0, 0, blockSize))
+		require.EqualErrno(t, syscall.ENOTSUP, CloneRange(mem, dst, 0, 0, blockSize))
+	})
+
+	t.Run("clones range", func(t *testing.T) {
+		errno := CloneRange(src, dst, blockSize, 0, blockSize)
+		if errno == syscall.ENOTSUP {
+			t.Skip("reflinks aren't supported by this platform or filesystem")
+		}
+		require.EqualErrno(t, 0, errno)
+
+		b, err := os.ReadFile(dstPath)
+		require.NoError(t, err)
+		require.Equal(t, content[blockSize:], b)
+
+		// Unaligned offsets fail, except at the end of src.
+		require.EqualErrno(t, syscall.EINVAL, CloneRange(src, dst, 1, 0, blockSize))
+	})
+}
@@ -0,0 +1,11 @@
+//go:build !((amd64 || arm64 || riscv64) && linux)
+
+package platform
+
+import "syscall"
+
+// cloneRange returns syscall.ENOTSUP as sharing a range of storage isn't
+// supported on this platform.
+func cloneRange(uintptr, uintptr, int64, int64, int64) syscall.Errno {
+	return syscall.ENOTSUP
+}
@@ -25,6 +25,13 @@ type Dirent struct {
 	// Type is fs.FileMode masked on fs.ModeType. For example, zero is a
 	// regular file, fs.ModeDir is a directory and fs.ModeIrregular is unknown.
 	Type fs.FileMode
+
+	// Cookie is an opaque position after this entry, which ReaddirFrom
+	// resumes at. It is zero unless the entry was read by ReaddirFrom.
+	//
+	// Note: A cookie is only valid for the same open file. For example, on
+	// Linux it is `d_off`, which may be a hash of the name, not an index.
+	Cookie uint64
 }
 
 func (d *Dirent) String() string {
@@ -36,7 +43,126 @@ func (d *Dirent) IsDir() bool {
 	return d.Type == fs.ModeDir
 }
 
-func readdir(f fs.File, n int) (dirents []Dirent, errno syscall.Errno) {
+// ReaddirNoIno is like File.Readdir, except Dirent.Ino may be zero when
+// reading it has a cost. Use this when the caller doesn't need inodes, such
+// as to list names or to check if a directory is empty. FillIno can populate
+// Dirent.Ino later, for entries which need it.
+//
+// The per-entry cost of Dirent.Ino in File.Readdir varies by platform:
+//   - linux: none, as `getdents64` includes `d_ino`.
+//   - darwin and freebsd: none, as os.File Readdir already `lstat`s each entry.
+//   - windows: an extra `lstat`, as `FindNextFile` doesn't include it.
+//   - fs.FS: none, as Dirent.Ino is always zero.
+//
+// Note: WASI `fd_readdir` needs Dirent.Ino, so uses File.Readdir.
+func ReaddirNoIno(f File, n int) ([]Dirent, syscall.Errno) {
+	if f, ok := f.(lazyInoFile); ok {
+		return f.readdirNoIno(n)
+	}
+	return f.Readdir(n)
+}
+
+// FillIno populates Dirent.Ino on `dirents` read from `dir` by ReaddirNoIno.
+// Entries are unchanged when their Dirent.Ino isn't available.
+func FillIno(dir File, dirents []Dirent) syscall.Errno {
+	if f, ok := dir.(lazyInoFile); ok {
+		return f.fillIno(dirents)
+	}
+	return 0 // Readdir doesn't skip Dirent.Ino
+}
+
+// ReaddirFrom is like File.Readdir, except it reads entries after `cookie`,
+// and sets Dirent.Cookie on each. A zero `cookie` reads from the beginning.
+//
+// This allows resuming iteration, such as for WASI `fd_readdir` cookies,
+// without buffering the directory. A cookie is only valid for the same open
+// file, and only as long as it is open.
+//
+// The cookie is a position specific to the implementation:
+//   - linux: `d_off` from `getdents64`, positioned with `lseek` (`seekdir`).
+//   - other platforms and fs.FS: the count of entries before the position.
+//     Resuming at a position other than the last read rewinds the directory
+//     and skips entries, so is slower.
+//
+// Files which don't implement ReaddirFrom themselves are rewound and read
+// from the beginning each call.
+func ReaddirFrom(f File, cookie uint64, n int) ([]Dirent, syscall.Errno) {
+	if f, ok := f.(readdirFromFile); ok {
+		return f.ReaddirFrom(cookie, n)
+	}
+	if errno := f.RewindDir(); errno != 0 {
+		return nil, errno
+	}
+	if errno := skipDirents(f.Readdir, cookie); errno != 0 {
+		return nil, errno
+	}
+	dirents, errno := f.Readdir(n)
+	for i := range dirents {
+		dirents[i].Cookie = cookie + uint64(i) + 1
+	}
+	return dirents, errno
+}
+
+// skipDirents reads and discards `count` entries using `readdir`, stopping
+// early at the end of the directory.
+func skipDirents(readdir func(n int) ([]Dirent, syscall.Errno), count uint64) syscall.Errno {
+	for count > 0 {
+		n := direntSkipBatch
+		if count < uint64(n) {
+			n = int(count)
+		}
+		dirents, errno := readdir(n)
+		if errno != 0 {
+			return errno
+		} else if len(dirents) == 0 {
+			return 0
+		}
+		count -= uint64(len(dirents))
+	}
+	return 0
+}
+
+// direntSkipBatch is the count of entries read at a time by skipDirents.
+const direntSkipBatch = 128
+
+// MinReaddirBufSize is the smallest size accepted by SetReaddirBufSize, as
+// the buffer must fit the longest entry.
+const MinReaddirBufSize = 512
+
+// readdirBufSizeFile is implemented by files which read directory entries
+// from the host into a buffer, such as fsFile.
+type readdirBufSizeFile interface{ setReaddirBufSize(size int) }
+
+// SetReaddirBufSize sets the size in bytes of the buffer `f` reads directory
+// entries into from the host. A larger buffer means fewer calls to the host
+// for a large directory, while File.Readdir still returns the count of
+// entries requested. Sizes less than MinReaddirBufSize are raised to it.
+//
+// This has no effect unless `f` is a directory opened by OpenFile on Linux,
+// where this sizes the buffer of `getdents64`, which is 8KiB by default.
+// Other platforms read with os.File.Readdir, which sizes its own buffer.
+func SetReaddirBufSize(f File, size int) {
+	if f, ok := f.(readdirBufSizeFile); ok {
+		if size < MinReaddirBufSize {
+			size = MinReaddirBufSize
+		}
+		f.setReaddirBufSize(size)
+	}
+}
+
+// readdirFromFile is implemented by files which can resume Readdir at a
+// cookie, without rewinding.
+type readdirFromFile interface {
+	ReaddirFrom(cookie uint64, n int) ([]Dirent, syscall.Errno)
+}
+
+// lazyInoFile is implemented by files which skip Dirent.Ino in ReaddirNoIno.
+type lazyInoFile interface {
+	readdirNoIno(n int) ([]Dirent, syscall.Errno)
+	fillIno(dirents []Dirent) syscall.Errno
+}
+
+func readdir(f fs.File, n int, withIno bool) (dirents []Dirent, errno syscall.Errno) {
 	// ^^ case format is to match POSIX and similar to os.File.Readdir
 
 	switch f := f.(type) {
@@ -50,7 +176,9 @@ func readdir(f fs.File, n int) (dirents []Dirent, errno syscall.Errno) {
 		// linux/darwin won't have to fan out to lstat, but windows will.
 		var ino uint64
 		for _, t := range fis {
-			if ino, errno = inoFromFileInfo(f, t); errno != 0 {
+			if !withIno {
+				// ino stays zero
+			} else if ino, errno = inoFromFileInfo(f, t); errno != 0 {
 				return
 			}
 			dirents = append(dirents, Dirent{Name: t.Name(), Ino: ino, Type: t.Mode().Type()})
@@ -105,6 +233,21 @@ func (DirFile) SetNonblock(bool) syscall.Errno {
 	return syscall.EISDIR
 }
 
+// SetCloexec implements File.SetCloexec
+func (DirFile) SetCloexec(bool) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+// SetTerminalRaw implements File.SetTerminalRaw
+func (DirFile) SetTerminalRaw(bool) syscall.Errno {
+	return syscall.ENOTTY
+}
+
+// RewindDir implements File.RewindDir
+func (DirFile) RewindDir() syscall.Errno {
+	return syscall.ENOSYS
+}
+
 // IsDir implements File.IsDir
 func (DirFile) IsDir() (bool, syscall.Errno) {
 	return true, 0
@@ -130,11 +273,21 @@ func (DirFile) PollRead(*time.Duration) (ready bool, errno syscall.Errno) {
 	return false, syscall.ENOSYS
 }
 
+// BytesAvailable implements File.BytesAvailable
+func (DirFile) BytesAvailable() (int64, syscall.Errno) {
+	return 0, syscall.EISDIR
+}
+
 // Write implements File.Write
 func (DirFile) Write([]byte) (int, syscall.Errno) {
 	return 0, syscall.EISDIR
 }
 
+// Writev implements File.Writev
+func (DirFile) Writev([][]byte) (int, syscall.Errno) {
+	return 0, syscall.EISDIR
+}
+
 // Pwrite implements File.Pwrite
 func (DirFile) Pwrite([]byte, int64) (int, syscall.Errno) {
 	return 0, syscall.EISDIR
@@ -144,3 +297,13 @@ func (DirFile) Pwrite([]byte, int64) (int, syscall.Errno) {
 func (DirFile) Truncate(int64) syscall.Errno {
 	return syscall.EISDIR
 }
+
+// PunchHole implements File.PunchHole
+func (DirFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.EISDIR
+}
+
+// Dup implements File.Dup
+func (DirFile) Dup() (File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
@@ -175,3 +175,168 @@ func requireIno(t *testing.T, dirents []platform.Dirent, expectIno bool) {
 		}
 	}
 }
+
+func TestReaddirNoIno(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	f, errno := platform.OpenFile(tmpDir, os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	dir := platform.NewFsFile(".", os.O_RDONLY, f)
+	defer dir.Close()
+
+	dirents, errno := platform.ReaddirNoIno(dir, -1)
+	require.EqualErrno(t, 0, errno)
+	sort.Slice(dirents, func(i, j int) bool { return dirents[i].Name < dirents[j].Name })
+	requireIno(t, dirents, runtime.GOOS != "windows")
+
+	require.EqualErrno(t, 0, platform.FillIno(dir, dirents))
+	requireIno(t, dirents, true)
+
+	var names []string
+	for _, d := range dirents {
+		names = append(names, d.Name)
+	}
+	require.Equal(t, []string{"animals.txt", "dir", "empty.txt", "emptydir", "sub"}, names)
+
+	// Files which don't implement it fall back to Readdir.
+	mf, err := fstest.FS.Open(".")
+	require.NoError(t, err)
+	mapDir := platform.NewFsFile(".", os.O_RDONLY, mf)
+	defer mapDir.Close()
+	dirents, errno = platform.ReaddirNoIno(struct{ platform.File }{mapDir}, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 5, len(dirents))
+}
+
+func TestReaddirFrom(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	requireResumable := func(t *testing.T, dir platform.File) {
+		all, errno := platform.ReaddirFrom(dir, 0, -1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 5, len(all))
+
+		// Resume after each entry, in reverse, so that each call seeks.
+		for i := len(all) - 1; i >= 0; i-- {
+			rest, errno := platform.ReaddirFrom(dir, all[i].Cookie, -1)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, len(all)-i-1, len(rest))
+			if len(rest) > 0 {
+				require.Equal(t, all[i+1:], rest)
+			}
+		}
+
+		// A zero cookie reads from the beginning again.
+		first, errno := platform.ReaddirFrom(dir, 0, 2)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, all[:2], first)
+		next, errno := platform.ReaddirFrom(dir, first[1].Cookie, 1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, all[2:3], next)
+	}
+
+	openDir := func(t *testing.T) platform.File {
+		f, errno := platform.OpenFile(tmpDir, os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		dir := platform.NewFsFile(".", os.O_RDONLY, f)
+		t.Cleanup(func() { dir.Close() })
+		return dir
+	}
+
+	t.Run("os.File", func(t *testing.T) {
+		requireResumable(t, openDir(t))
+	})
+
+	t.Run("fs.FS", func(t *testing.T) {
+		f, err := fstest.FS.Open(".")
+		require.NoError(t, err)
+		dir := platform.NewFsFile(".", os.O_RDONLY, f)
+		defer dir.Close()
+
+		// The cookie is an index, so resuming at the current position works
+		// without seeking.
+		first, errno := platform.ReaddirFrom(dir, 0, 2)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []uint64{1, 2}, []uint64{first[0].Cookie, first[1].Cookie})
+		rest, errno := platform.ReaddirFrom(dir, 2, -1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 3, len(rest))
+		require.Equal(t, uint64(5), rest[2].Cookie)
+
+		// Otherwise, it needs to rewind, which isn't supported.
+		_, errno = platform.ReaddirFrom(dir, 1, -1)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		// Files which don't implement it rewind and skip entries.
+		requireResumable(t, struct{ platform.File }{openDir(t)})
+	})
+
+	t.Run("Readdir has no cookie", func(t *testing.T) {
+		dirents, errno := openDir(t).Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		for _, d := range dirents {
+			require.Zero(t, d.Cookie)
+		}
+	})
+
+	t.Run("ENOTDIR", func(t *testing.T) {
+		f, errno := platform.OpenFile(path.Join(tmpDir, "animals.txt"), os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		file := platform.NewFsFile("animals.txt", os.O_RDONLY, f)
+		defer file.Close()
+
+		_, errno = platform.ReaddirFrom(file, 0, -1)
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+	})
+}
+
+func TestRewindDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	t.Run("os.File", func(t *testing.T) {
+		dF, errno := platform.OpenFile(path.Join(tmpDir, "dir"), os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		dirF := platform.NewFsFile("dir", os.O_RDONLY, dF)
+		defer dirF.Close()
+
+		// Partially read, so that the next read would otherwise continue.
+		dirents, errno := dirF.Readdir(1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, len(dirents))
+
+		require.EqualErrno(t, 0, dirF.RewindDir())
+		dirents, errno = dirF.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 3, len(dirents))
+
+		// Rewinding sees entries added since the directory was opened.
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "dir", "new"), nil, 0o600))
+		require.EqualErrno(t, 0, dirF.RewindDir())
+		dirents, errno = dirF.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 4, len(dirents))
+	})
+
+	t.Run("ENOSYS without seek", func(t *testing.T) {
+		dF, err := fstest.FS.Open("dir")
+		require.NoError(t, err)
+		dirF := platform.NewFsFile("dir", os.O_RDONLY, dF)
+		defer dirF.Close()
+
+		require.EqualErrno(t, syscall.ENOSYS, dirF.RewindDir())
+	})
+
+	t.Run("ENOTDIR", func(t *testing.T) {
+		fF, errno := platform.OpenFile(path.Join(tmpDir, "animals.txt"), os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		fileF := platform.NewFsFile("animals.txt", os.O_RDONLY, fF)
+		defer fileF.Close()
+
+		require.EqualErrno(t, syscall.ENOTDIR, fileF.RewindDir())
+	})
+}
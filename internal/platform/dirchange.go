@@ -0,0 +1,8 @@
+//go:build !windows
+
+package platform
+
+// DirChanged notifies open directories at the host path `dir` that entries
+// were added or removed, for platforms where an open directory doesn't see
+// them. This is a no-op, except on Windows.
+func DirChanged(string) {}
@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"path/filepath"
+	gosync "sync"
+)
+
+// openDirs are the directories read via windowsWrappedFile, by cleaned path.
+// The value is true when entries changed since the directory was opened.
+var openDirs = struct {
+	mux  gosync.Mutex
+	dirs map[string]map[*windowsWrappedFile]bool
+}{dirs: map[string]map[*windowsWrappedFile]bool{}}
+
+// DirChanged notifies open directories at the host path `dir` that entries
+// were added or removed, for platforms where an open directory doesn't see
+// them.
+//
+// On Windows, a directory handle lists the entries as of when it was opened,
+// so this makes the next Readdir re-open it.
+func DirChanged(dir string) {
+	dir = filepath.Clean(dir)
+	openDirs.mux.Lock()
+	defer openDirs.mux.Unlock()
+	for w := range openDirs.dirs[dir] {
+		openDirs.dirs[dir][w] = true
+	}
+}
+
+// addOpenDir registers `w` to be notified by DirChanged.
+func addOpenDir(w *windowsWrappedFile) {
+	dir := filepath.Clean(w.path)
+	openDirs.mux.Lock()
+	defer openDirs.mux.Unlock()
+	files, ok := openDirs.dirs[dir]
+	if !ok {
+		files = map[*windowsWrappedFile]bool{}
+		openDirs.dirs[dir] = files
+	}
+	files[w] = false
+}
+
+// removeOpenDir unregisters `w`, which is closed.
+func removeOpenDir(w *windowsWrappedFile) {
+	dir := filepath.Clean(w.path)
+	openDirs.mux.Lock()
+	defer openDirs.mux.Unlock()
+	if files, ok := openDirs.dirs[dir]; ok {
+		delete(files, w)
+		if len(files) == 0 {
+			delete(openDirs.dirs, dir)
+		}
+	}
+}
+
+// takeOpenDirChanged returns true if DirChanged was called for the directory
+// of `w` since the last call, resetting it.
+func takeOpenDirChanged(w *windowsWrappedFile) bool {
+	dir := filepath.Clean(w.path)
+	openDirs.mux.Lock()
+	defer openDirs.mux.Unlock()
+	files := openDirs.dirs[dir]
+	changed := files[w]
+	if changed {
+		files[w] = false
+	}
+	return changed
+}
@@ -0,0 +1,24 @@
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// checkDirectAligned returns syscall.EINVAL unless the address and length of
+// `p`, and `off`, are multiples of directAlignment. This is only enforced on
+// files opened with O_DIRECT on hosts which require alignment.
+//
+// Note: This only checks the minimum alignment. The host returns
+// syscall.EINVAL itself when its block device requires more, such as 4096
+// bytes on drives with 4K sectors.
+func checkDirectAligned(p []byte, off int64) syscall.Errno {
+	if len(p) == 0 {
+		return 0
+	}
+	addr := uintptr(unsafe.Pointer(&p[0]))
+	if addr%directAlignment != 0 || len(p)%directAlignment != 0 || off%directAlignment != 0 {
+		return syscall.EINVAL
+	}
+	return 0
+}
@@ -0,0 +1,25 @@
+package platform
+
+import (
+	"os"
+	"syscall"
+)
+
+// O_DIRECT is a placeholder, as darwin uses `F_NOCACHE` instead. See the
+// comments on the same constant in direct_unix.go.
+const O_DIRECT = 1 << 28
+
+// directAlignment is one, as `F_NOCACHE` has no alignment requirement.
+const directAlignment = 1
+
+// directFlag returns `flag` without the O_DIRECT placeholder, which setDirect
+// implements.
+func directFlag(flag int) (int, syscall.Errno) {
+	return flag &^ O_DIRECT, 0
+}
+
+// setDirect disables the page cache of `f` with `F_NOCACHE`.
+func setDirect(f *os.File) syscall.Errno {
+	_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), syscall.F_NOCACHE, 1)
+	return errno
+}
@@ -0,0 +1,88 @@
+package platform
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenFile_Direct(t *testing.T) {
+	p := path.Join(t.TempDir(), "direct")
+	f, errno := OpenFile(p, os.O_RDWR|os.O_CREATE|O_DIRECT, 0o600)
+	switch runtime.GOOS {
+	case "linux", "freebsd", "darwin":
+	default:
+		require.EqualErrno(t, syscall.ENOTSUP, errno)
+		return
+	}
+	if errno == syscall.EINVAL {
+		t.Skip("filesystem doesn't support O_DIRECT")
+	}
+	require.EqualErrno(t, 0, errno)
+
+	file := NewFsFile(p, os.O_RDWR|O_DIRECT, f)
+	defer file.Close()
+
+	buf := alignedBuf(4096)
+	copy(buf, "wazero")
+	requirePwrite(t, file, buf, 0)
+
+	read := alignedBuf(4096)
+	requirePread(t, file, read, 0)
+	require.Equal(t, "wazero", string(read[:6]))
+
+	if directAlignment > 1 {
+		_, errno = file.Pread(read, 1)
+		require.EqualErrno(t, syscall.EINVAL, errno)
+		_, errno = file.Pwrite(buf[1:513], 0)
+		require.EqualErrno(t, syscall.EINVAL, errno)
+	}
+}
+
+func TestFsFile_DirectAlignment(t *testing.T) {
+	if directAlignment == 1 {
+		t.Skip("O_DIRECT has no alignment requirement on " + runtime.GOOS)
+	}
+
+	// memFile has no alignment requirement, so any error is from fsFile.
+	f := NewFsFile(wazeroFile, os.O_RDWR|O_DIRECT, &memFile{})
+	buf := alignedBuf(1024)
+
+	tests := []struct {
+		name string
+		op   func() (int, syscall.Errno)
+	}{
+		{name: "Write misaligned address", op: func() (int, syscall.Errno) { return f.Write(buf[1:513]) }},
+		{name: "Write misaligned length", op: func() (int, syscall.Errno) { return f.Write(buf[:100]) }},
+		{name: "Writev misaligned", op: func() (int, syscall.Errno) { return f.Writev([][]byte{buf[:512], buf[:1]}) }},
+		{name: "Pwrite misaligned offset", op: func() (int, syscall.Errno) { return f.Pwrite(buf[:512], 1) }},
+		{name: "Read misaligned length", op: func() (int, syscall.Errno) { return f.Read(buf[:513]) }},
+		{name: "Pread misaligned offset", op: func() (int, syscall.Errno) { return f.Pread(buf, 100) }},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			n, errno := tc.op()
+			require.EqualErrno(t, syscall.EINVAL, errno)
+			require.Zero(t, n)
+		})
+	}
+
+	// Aligned I/O works.
+	requireWrite(t, f, buf[:512])
+}
+
+// alignedBuf returns a buffer whose address is aligned to 4096 bytes, which
+// satisfies O_DIRECT on common block devices.
+func alignedBuf(size int) []byte {
+	const align = 4096
+	b := make([]byte, size+align)
+	off := (align - int(uintptr(unsafe.Pointer(&b[0]))%align)) % align
+	return b[off : off+size]
+}
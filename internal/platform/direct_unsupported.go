@@ -0,0 +1,28 @@
+//go:build !(linux || freebsd || darwin)
+
+package platform
+
+import (
+	"os"
+	"syscall"
+)
+
+// O_DIRECT is a placeholder, which is not supported on this platform. See
+// the comments on the same constant in direct_unix.go.
+const O_DIRECT = 1 << 28
+
+// directAlignment is one, as files can't be opened with O_DIRECT.
+const directAlignment = 1
+
+// directFlag returns syscall.ENOTSUP if `flag` includes O_DIRECT.
+func directFlag(flag int) (int, syscall.Errno) {
+	if flag&O_DIRECT != 0 {
+		return 0, syscall.ENOTSUP
+	}
+	return flag, 0
+}
+
+// setDirect is never called, as directFlag fails first.
+func setDirect(*os.File) syscall.Errno {
+	return syscall.ENOTSUP
+}
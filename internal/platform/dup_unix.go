@@ -0,0 +1,29 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// dupFile duplicates the file descriptor of `f`, which must be an *os.File.
+func dupFile(f fs.File) (fs.File, syscall.Errno) {
+	osf, ok := f.(*os.File)
+	if !ok {
+		return nil, syscall.ENOSYS
+	}
+	// Hold the fork lock, so that a concurrent exec doesn't inherit the new
+	// file descriptor before it is marked close-on-exec.
+	syscall.ForkLock.RLock()
+	fd, err := syscall.Dup(int(osf.Fd()))
+	if err == nil {
+		syscall.CloseOnExec(fd)
+	}
+	syscall.ForkLock.RUnlock()
+	if err != nil {
+		return nil, UnwrapOSError(err)
+	}
+	return os.NewFile(uintptr(fd), osf.Name()), 0
+}
@@ -0,0 +1,12 @@
+//go:build !(darwin || linux || freebsd || windows)
+
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+func dupFile(fs.File) (fs.File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
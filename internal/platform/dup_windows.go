@@ -0,0 +1,47 @@
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// dupFile duplicates the handle of `f`, which must be a windowsWrappedFile.
+func dupFile(f fs.File) (fs.File, syscall.Errno) {
+	w, ok := f.(*windowsWrappedFile)
+	if !ok {
+		return nil, syscall.ENOSYS
+	} else if w.closed {
+		return nil, syscall.EBADF
+	}
+	osf, ok := w.osFile.(*os.File)
+	if !ok {
+		return nil, syscall.ENOSYS
+	}
+
+	p, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return nil, UnwrapOSError(err)
+	}
+	var h syscall.Handle
+	err = syscall.DuplicateHandle(p, syscall.Handle(osf.Fd()), p, &h, 0, false, syscall.DUPLICATE_SAME_ACCESS)
+	if err != nil {
+		return nil, UnwrapOSError(err)
+	}
+	dup := &windowsWrappedFile{
+		osFile:         os.NewFile(uintptr(h), osf.Name()),
+		path:           w.path,
+		flag:           w.flag,
+		perm:           w.perm,
+		dirInitialized: w.dirInitialized,
+		fileType:       w.fileType,
+	}
+	if w.dirSeen != nil {
+		dup.dirSeen = make(map[string]struct{}, len(w.dirSeen))
+		for name := range w.dirSeen {
+			dup.dirSeen[name] = struct{}{}
+		}
+		addOpenDir(dup)
+	}
+	return dup, 0
+}
@@ -0,0 +1,47 @@
+package platform
+
+import (
+	"errors"
+	"syscall"
+)
+
+// maxEINTRRetries bounds RetryOnEINTR, so that a call interrupted by a steady
+// stream of signals eventually returns.
+const maxEINTRRetries = 16
+
+// RetryOnEINTR calls fn until it returns an error other than syscall.EINTR,
+// or maxEINTRRetries times, returning the last error.
+//
+// On unix, a slow syscall can fail with syscall.EINTR when a signal arrives,
+// which guests rarely handle. Retrying is safe when `fn` has no effect when it
+// fails this way, such as open or a read which read nothing.
+//
+// # Notes
+//
+//   - Don't use this for operations which wait until a timeout or deadline,
+//     such as PollRead, as retrying would restart the wait. EINTR may also
+//     be how a blocked call is interrupted on purpose, e.g. when the context
+//     of a module is canceled, so it must propagate.
+//   - When the retries are exhausted, syscall.EINTR propagates to the caller.
+func RetryOnEINTR(fn func() error) (err error) {
+	for i := 0; i < maxEINTRRetries; i++ {
+		if err = fn(); !errors.Is(err, syscall.EINTR) {
+			return
+		}
+	}
+	return
+}
+
+// retryIOOnEINTR is like RetryOnEINTR, except for a read or write which
+// returns a count. syscall.EINTR after some bytes were transferred isn't
+// retried, as that would lose the count. Instead, it succeeds with a short
+// count.
+func retryIOOnEINTR(fn func() (int, error)) (n int, err error) {
+	err = RetryOnEINTR(func() (err error) {
+		if n, err = fn(); n > 0 && errors.Is(err, syscall.EINTR) {
+			err = nil
+		}
+		return
+	})
+	return
+}
@@ -0,0 +1,153 @@
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestRetryOnEINTR(t *testing.T) {
+	t.Run("retries until success", func(t *testing.T) {
+		calls := 0
+		err := RetryOnEINTR(func() error {
+			if calls++; calls < 3 {
+				return &fs.PathError{Op: "open", Path: "file", Err: syscall.EINTR}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("other errors aren't retried", func(t *testing.T) {
+		calls := 0
+		err := RetryOnEINTR(func() error {
+			calls++
+			return syscall.ENOENT
+		})
+		require.Equal(t, syscall.ENOENT, err)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		calls := 0
+		err := RetryOnEINTR(func() error {
+			calls++
+			return syscall.EINTR
+		})
+		require.Equal(t, syscall.EINTR, err)
+		require.Equal(t, maxEINTRRetries, calls)
+	})
+}
+
+func TestFsFile_EINTR(t *testing.T) {
+	newFile := func() (File, *eintrFile) {
+		ef := &eintrFile{memFile: memFile{data: []byte("wazero")}, eintrs: 2}
+		return NewFsFile(wazeroFile, os.O_RDWR, ef), ef
+	}
+
+	t.Run("Read", func(t *testing.T) {
+		f, ef := newFile()
+		buf := make([]byte, 6)
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "wazero", string(buf[:n]))
+		require.Zero(t, ef.eintrs)
+	})
+
+	t.Run("Pread", func(t *testing.T) {
+		f, ef := newFile()
+		buf := make([]byte, 4)
+		n, errno := f.Pread(buf, 2)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "zero", string(buf[:n]))
+		require.Zero(t, ef.eintrs)
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		f, ef := newFile()
+		n, errno := f.Write([]byte("WA"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 2, n)
+		require.Equal(t, "WAzero", string(ef.data))
+		require.Zero(t, ef.eintrs)
+	})
+
+	t.Run("Pwrite", func(t *testing.T) {
+		f, ef := newFile()
+		n, errno := f.Pwrite([]byte("RO"), 4)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 2, n)
+		require.Equal(t, "wazeRO", string(ef.data))
+		require.Zero(t, ef.eintrs)
+	})
+
+	t.Run("Sync", func(t *testing.T) {
+		f, ef := newFile()
+		require.EqualErrno(t, 0, f.Sync())
+		require.Zero(t, ef.eintrs)
+	})
+
+	t.Run("EINTR after progress isn't retried", func(t *testing.T) {
+		n, err := retryIOOnEINTR(func() (int, error) {
+			return 2, syscall.EINTR
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+	})
+}
+
+// eintrFile is a memFile whose operations fail with syscall.EINTR until
+// eintrs reaches zero.
+type eintrFile struct {
+	memFile
+	eintrs int
+}
+
+func (f *eintrFile) interrupted() bool {
+	if f.eintrs > 0 {
+		f.eintrs--
+		return true
+	}
+	return false
+}
+
+func (f *eintrFile) Read(p []byte) (int, error) {
+	if f.interrupted() {
+		return 0, syscall.EINTR
+	}
+	n := copy(p, f.data[f.off:])
+	f.off += int64(n)
+	return n, nil
+}
+
+func (f *eintrFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.interrupted() {
+		return 0, syscall.EINTR
+	}
+	return copy(p, f.data[off:]), nil
+}
+
+func (f *eintrFile) Write(p []byte) (int, error) {
+	if f.interrupted() {
+		return 0, syscall.EINTR
+	}
+	return f.memFile.Write(p)
+}
+
+func (f *eintrFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.interrupted() {
+		return 0, &fs.PathError{Op: "write", Path: wazeroFile, Err: syscall.EINTR}
+	}
+	return copy(f.data[off:], p), nil
+}
+
+func (f *eintrFile) Sync() error {
+	if f.interrupted() {
+		return &fs.PathError{Op: "sync", Path: wazeroFile, Err: syscall.EINTR}
+	}
+	return nil
+}
@@ -1,9 +1,14 @@
-//go:build !windows
+//go:build !windows && !wasip1
 
 package platform
 
 import "syscall"
 
 func adjustErrno(err syscall.Errno) syscall.Errno {
+	// POSIX allows EWOULDBLOCK to differ from EAGAIN. Normalize it, so that a
+	// guest of a non-blocking file sees one error to retry on.
+	if err == syscall.EWOULDBLOCK {
+		return syscall.EAGAIN
+	}
 	return err
 }
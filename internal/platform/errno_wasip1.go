@@ -0,0 +1,8 @@
+package platform
+
+import "syscall"
+
+// adjustErrno is a no-op, as wasip1 has no syscall.EWOULDBLOCK to normalize.
+func adjustErrno(err syscall.Errno) syscall.Errno {
+	return err
+}
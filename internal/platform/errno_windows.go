@@ -12,10 +12,31 @@ const (
 	// instead of syscall.EBADF
 	ERROR_INVALID_HANDLE = syscall.Errno(6)
 
+	// ERROR_SHARING_VIOLATION is a Windows error returned when another
+	// process has the file open without sharing, instead of syscall.EBUSY
+	ERROR_SHARING_VIOLATION = syscall.Errno(0x20)
+
+	// ERROR_LOCK_VIOLATION is a Windows error returned by syscall.Read and
+	// syscall.Write when another process locked the range, instead of
+	// syscall.EBUSY
+	ERROR_LOCK_VIOLATION = syscall.Errno(0x21)
+
+	// ERROR_HANDLE_DISK_FULL is a Windows error returned by syscall.Write
+	// instead of syscall.ENOSPC
+	ERROR_HANDLE_DISK_FULL = syscall.Errno(0x27)
+
+	// ERROR_NOT_SUPPORTED is a Windows error returned for operations the
+	// file system doesn't support, instead of syscall.ENOTSUP
+	ERROR_NOT_SUPPORTED = syscall.Errno(0x32)
+
 	// ERROR_FILE_EXISTS is a Windows error returned by os.OpenFile
 	// instead of syscall.EEXIST
 	ERROR_FILE_EXISTS = syscall.Errno(0x50)
 
+	// ERROR_DISK_FULL is a Windows error returned by syscall.Write and
+	// os.OpenFile instead of syscall.ENOSPC
+	ERROR_DISK_FULL = syscall.Errno(0x70)
+
 	// ERROR_INVALID_NAME is a Windows error returned by open when a file
 	// path has a trailing slash
 	ERROR_INVALID_NAME = syscall.Errno(0x7B)
@@ -32,11 +53,23 @@ const (
 	// instead of syscall.EEXIST
 	ERROR_ALREADY_EXISTS = syscall.Errno(0xB7)
 
+	// ERROR_NO_DATA is a Windows error returned by syscall.Read and
+	// syscall.Write on a non-blocking pipe with no data or space, instead of
+	// syscall.EAGAIN
+	ERROR_NO_DATA = syscall.Errno(0xE8)
+
 	// ERROR_DIRECTORY is a Windows error returned by syscall.Rmdir
 	// instead of syscall.ENOTDIR
 	ERROR_DIRECTORY = syscall.Errno(0x10B)
 )
 
+// See https://learn.microsoft.com/en-us/windows/win32/winsock/windows-sockets-error-codes-2
+const (
+	// WSAEWOULDBLOCK is a Windows error returned by a non-blocking socket
+	// which isn't ready, instead of syscall.EAGAIN
+	WSAEWOULDBLOCK = syscall.Errno(10035)
+)
+
 // See https://learn.microsoft.com/en-us/windows/win32/debug/system-error-codes--1300-1699-
 const (
 	// ERROR_PRIVILEGE_NOT_HELD is a Windows error returned by os.Symlink
@@ -65,6 +98,14 @@ func adjustErrno(err syscall.Errno) syscall.Errno {
 		return syscall.EPERM
 	case ERROR_NEGATIVE_SEEK, ERROR_INVALID_NAME:
 		return syscall.EINVAL
+	case ERROR_SHARING_VIOLATION, ERROR_LOCK_VIOLATION:
+		return syscall.EBUSY
+	case ERROR_DISK_FULL, ERROR_HANDLE_DISK_FULL:
+		return syscall.ENOSPC
+	case ERROR_NOT_SUPPORTED:
+		return syscall.ENOTSUP
+	case ERROR_NO_DATA, WSAEWOULDBLOCK, syscall.EWOULDBLOCK:
+		return syscall.EAGAIN
 	}
 	return err
 }
@@ -0,0 +1,46 @@
+package platform
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestUnwrapOSError_windows(t *testing.T) {
+	tests := []struct {
+		input    syscall.Errno
+		expected syscall.Errno
+	}{
+		{input: ERROR_ACCESS_DENIED, expected: syscall.EACCES},
+		{input: ERROR_INVALID_HANDLE, expected: syscall.EBADF},
+		{input: ERROR_SHARING_VIOLATION, expected: syscall.EBUSY},
+		{input: ERROR_LOCK_VIOLATION, expected: syscall.EBUSY},
+		{input: ERROR_HANDLE_DISK_FULL, expected: syscall.ENOSPC},
+		{input: ERROR_DISK_FULL, expected: syscall.ENOSPC},
+		{input: ERROR_NOT_SUPPORTED, expected: syscall.ENOTSUP},
+		{input: ERROR_FILE_EXISTS, expected: syscall.EEXIST},
+		{input: ERROR_ALREADY_EXISTS, expected: syscall.EEXIST},
+		{input: ERROR_INVALID_NAME, expected: syscall.EINVAL},
+		{input: ERROR_NEGATIVE_SEEK, expected: syscall.EINVAL},
+		{input: ERROR_DIR_NOT_EMPTY, expected: syscall.ENOTEMPTY},
+		{input: ERROR_DIRECTORY, expected: syscall.ENOTDIR},
+		{input: ERROR_PRIVILEGE_NOT_HELD, expected: syscall.EPERM},
+		{input: ERROR_NO_DATA, expected: syscall.EAGAIN},
+		{input: WSAEWOULDBLOCK, expected: syscall.EAGAIN},
+		{input: syscall.EWOULDBLOCK, expected: syscall.EAGAIN},
+		{input: syscall.ERROR_FILE_NOT_FOUND, expected: syscall.ENOENT},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.input.Error(), func(t *testing.T) {
+			// The mapping applies whether the error is bare or wrapped, as
+			// returned by os.File Read, Write, ReadDir and os.OpenFile.
+			require.EqualErrno(t, tc.expected, UnwrapOSError(tc.input))
+			require.EqualErrno(t, tc.expected, UnwrapOSError(&os.PathError{Op: "write", Path: "file", Err: tc.input}))
+			require.EqualErrno(t, tc.expected, UnwrapOSError(&os.SyscallError{Syscall: "ReadFile", Err: tc.input}))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"errors"
 	"io"
 	"io/fs"
 	"os"
@@ -8,6 +9,11 @@ import (
 )
 
 // UnwrapOSError returns a syscall.Errno or zero if the input is nil.
+//
+// Errors which wrap a syscall.Errno, or one of the fs.ErrXXX sentinels, are
+// matched with errors.As and errors.Is. This allows custom fs.FS to return
+// errors such as `fmt.Errorf("open %s: %w", name, fs.ErrPermission)`.
+// Otherwise, the result is syscall.EIO.
 func UnwrapOSError(err error) syscall.Errno {
 	if err == nil {
 		return 0
@@ -16,26 +22,47 @@ func UnwrapOSError(err error) syscall.Errno {
 	if se, ok := err.(syscall.Errno); ok {
 		return adjustErrno(se)
 	}
+	var se syscall.Errno
+	if errors.As(err, &se) {
+		return adjustErrno(se)
+	}
 	// Below are all the fs.ErrXXX in fs.go.
 	//
 	// Note: Once we have our own file type, we should never see these.
-	switch err {
-	case nil, io.EOF:
+	switch {
+	case errors.Is(err, io.EOF):
 		return 0 // EOF is not a syscall.Errno
-	case fs.ErrInvalid:
+	case errors.Is(err, fs.ErrInvalid):
 		return syscall.EINVAL
-	case fs.ErrPermission:
-		return syscall.EPERM
-	case fs.ErrExist:
+	case errors.Is(err, fs.ErrPermission):
+		return syscall.EACCES // like os.ErrPermission from open(2)
+	case errors.Is(err, fs.ErrExist):
 		return syscall.EEXIST
-	case fs.ErrNotExist:
+	case errors.Is(err, fs.ErrNotExist):
 		return syscall.ENOENT
-	case fs.ErrClosed:
+	case errors.Is(err, fs.ErrClosed):
 		return syscall.EBADF
 	}
 	return syscall.EIO
 }
 
+// UnwrapReadError is like UnwrapOSError, except for the result of an
+// io.Reader or io.ReaderAt call which read `n` bytes and returned `err`.
+//
+// File.Read doesn't return io.EOF: the end of the file is a read of zero
+// bytes. Readers may return io.EOF together with the last data, or only in a
+// following call, so both are normalized to a zero errno here.
+//
+// Like io.Reader, an error that accompanies data is not returned, so that the
+// caller doesn't discard what was read. Readers return the error again on the
+// next call.
+func UnwrapReadError(n int, err error) syscall.Errno {
+	if n > 0 {
+		return 0
+	}
+	return UnwrapOSError(err)
+}
+
 // underlyingError returns the underlying error if a well-known OS error type.
 //
 // This impl is basically the same as os.underlyingError in os/error.go
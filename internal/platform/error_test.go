@@ -41,7 +41,37 @@ func TestUnwrapOSError(t *testing.T) {
 		{
 			name:     "PathError ErrPermission",
 			input:    &os.PathError{Err: os.ErrPermission},
-			expected: syscall.EPERM,
+			expected: syscall.EACCES,
+		},
+		{
+			name:     "wrapped ErrPermission",
+			input:    fmt.Errorf("custom: %w", fs.ErrPermission),
+			expected: syscall.EACCES,
+		},
+		{
+			name:     "wrapped ErrExist",
+			input:    fmt.Errorf("custom: %w", fs.ErrExist),
+			expected: syscall.EEXIST,
+		},
+		{
+			name:     "wrapped ErrNotExist",
+			input:    fmt.Errorf("custom: %w", fs.ErrNotExist),
+			expected: syscall.ENOENT,
+		},
+		{
+			name:     "wrapped ErrClosed",
+			input:    fmt.Errorf("custom: %w", fs.ErrClosed),
+			expected: syscall.EBADF,
+		},
+		{
+			name:     "wrapped io.EOF is not an error",
+			input:    fmt.Errorf("custom: %w", io.EOF),
+			expected: 0,
+		},
+		{
+			name:     "wrapped PathError",
+			input:    fmt.Errorf("custom: %w", &os.PathError{Err: syscall.ENOTDIR}),
+			expected: syscall.ENOTDIR,
 		},
 		{
 			name:     "PathError ErrExist",
@@ -87,3 +117,11 @@ func TestUnwrapOSError(t *testing.T) {
 		require.Zero(t, UnwrapOSError(nil))
 	})
 }
+
+func TestUnwrapReadError(t *testing.T) {
+	require.EqualErrno(t, 0, UnwrapReadError(3, io.EOF))
+	require.EqualErrno(t, 0, UnwrapReadError(0, io.EOF))
+	require.EqualErrno(t, 0, UnwrapReadError(3, io.ErrUnexpectedEOF))
+	require.EqualErrno(t, syscall.EIO, UnwrapReadError(0, io.ErrUnexpectedEOF))
+	require.EqualErrno(t, syscall.EBADF, UnwrapReadError(0, fs.ErrClosed))
+}
@@ -0,0 +1,39 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// _RENAME_EXCHANGE is a flag of renameat2 which atomically exchanges the
+// paths. This isn't defined in the syscall package.
+const _RENAME_EXCHANGE = 1 << 1
+
+// atFdcwd is AT_FDCWD, which resolves paths relative to the current
+// directory. This is a variable, as a negative constant can't convert to
+// uintptr.
+var atFdcwd = -0x64
+
+// ExchangeDir atomically exchanges the paths `a` and `b`, which must both
+// exist. This returns syscall.ENOSYS when the host doesn't support it.
+func ExchangeDir(a, b string) syscall.Errno {
+	aPtr, err := syscall.BytePtrFromString(a)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	bPtr, err := syscall.BytePtrFromString(b)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	_, _, errno := syscall.Syscall6(_SYS_RENAMEAT2,
+		uintptr(atFdcwd), uintptr(unsafe.Pointer(aPtr)),
+		uintptr(atFdcwd), uintptr(unsafe.Pointer(bPtr)),
+		_RENAME_EXCHANGE, 0)
+	if errno == syscall.EINVAL {
+		// Filesystems which don't support RENAME_EXCHANGE return EINVAL.
+		return syscall.ENOSYS
+	}
+	return errno
+}
@@ -0,0 +1,7 @@
+//go:build (arm64 || riscv64) && linux
+
+package platform
+
+import "syscall"
+
+const _SYS_RENAMEAT2 = syscall.SYS_RENAMEAT2
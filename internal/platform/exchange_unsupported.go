@@ -0,0 +1,10 @@
+//go:build !((amd64 || arm64 || riscv64) && linux)
+
+package platform
+
+import "syscall"
+
+// ExchangeDir returns syscall.ENOSYS as renameat2 isn't supported.
+func ExchangeDir(a, b string) syscall.Errno {
+	return syscall.ENOSYS
+}
@@ -1,5 +1,9 @@
 package platform
 
+// fdSetSize is the number of file descriptors a FdSet can hold, known as
+// FD_SETSIZE in POSIX. Larger file descriptors cannot be passed to _select.
+const fdSetSize = len(FdSet{}.Bits) * nfdbits
+
 // Set adds the given fd to the set.
 func (f *FdSet) Set(fd int) {
 	f.Bits[fd/nfdbits] |= (1 << (uintptr(fd) % nfdbits))
@@ -3,6 +3,11 @@ package platform
 import (
 	"io"
 	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	gosync "sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -42,13 +47,8 @@ type File interface {
 	//   - syscall.O_RDWR: read-write, e.g. os.CreateTemp
 	AccessMode() int
 
-	// IsNonblock returns true if SetNonblock was successfully enabled on this
-	// file.
-	//
-	// # Notes
-	//
-	//   - This may not match the underlying state of the file descriptor if it
-	//     was opened (OpenFile) in non-blocking mode.
+	// IsNonblock returns true if the file was opened with O_NONBLOCK,
+	// or SetNonblock was successfully enabled on this file.
 	IsNonblock() bool
 	// ^-- TODO: We should be able to cache the open flag and remove this note.
 
@@ -66,6 +66,46 @@ type File interface {
 	//     POSIX. See https://pubs.opengroup.org/onlinepubs/9699919799/functions/fcntl.html
 	SetNonblock(enable bool) syscall.Errno
 
+	// SetCloexec toggles whether this file is closed in processes started by
+	// the host, such as via os/exec. This prevents files opened by the guest
+	// from leaking into subprocesses.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation does not support this function.
+	//   - syscall.EBADF: the file or directory was closed.
+	//
+	// # Notes
+	//
+	//   - This is like `fcntl` with `F_SETFD` and `FD_CLOEXEC` in POSIX, or
+	//     SetHandleInformation with HANDLE_FLAG_INHERIT on Windows.
+	//     See https://pubs.opengroup.org/onlinepubs/9699919799/functions/fcntl.html
+	//   - OpenFile enables this by default, as os.OpenFile does.
+	SetCloexec(enable bool) syscall.Errno
+
+	// SetTerminalRaw toggles raw mode of a terminal, such as stdin of an
+	// interactive shell. Raw mode disables line editing (canonical mode) and
+	// echo, so that each key is read as typed, as needed by REPLs and
+	// editors. Disabling it restores the state before it was first enabled.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation does not support this function.
+	//   - syscall.EBADF: the file was closed.
+	//   - syscall.ENOTTY: the file isn't a terminal. See IsTerminal.
+	//
+	// # Notes
+	//
+	//   - This is like `tcsetattr` clearing ICANON and ECHO in POSIX, or
+	//     SetConsoleMode clearing ENABLE_LINE_INPUT and ENABLE_ECHO_INPUT on
+	//     Windows. See https://pubs.opengroup.org/onlinepubs/9699919799/functions/tcsetattr.html
+	//   - Close restores the state before the first change, even for stdio
+	//     files which are otherwise left open. As the files of a module are
+	//     closed with it, this includes when its start function traps.
+	SetTerminalRaw(enable bool) syscall.Errno
+
 	// Stat is similar to syscall.Fstat.
 	//
 	// # Errors
@@ -124,6 +164,7 @@ type File interface {
 	//   - syscall.EBADF: the file or directory was closed or not readable.
 	//   - syscall.EINVAL: the offset was negative.
 	//   - syscall.EISDIR: the file was a directory.
+	//   - syscall.ESPIPE: the file was a pipe or socket, which has no offset.
 	//
 	// # Notes
 	//
@@ -131,6 +172,8 @@ type File interface {
 	//     of io.ReaderAt. See https://pubs.opengroup.org/onlinepubs/9699919799/functions/pread.html
 	//   - Unlike io.ReaderAt, there is no io.EOF returned on end-of-file. To
 	//     read the file completely, the caller must repeat until `n` is zero.
+	//   - `off` is 64-bit on all hosts, and may be past 4GiB. On 32-bit hosts,
+	//     this is passed to `pread64`, not truncated.
 	Pread(p []byte, off int64) (n int, errno syscall.Errno)
 
 	// Seek attempts to set the next offset for Read or Write and returns the
@@ -145,6 +188,8 @@ type File interface {
 	//     the next Read or Write 16 bytes past the prior.
 	//   - io.SeekEnd: relative to the end of the file, e.g. offset=-1 sets the
 	//     next Read or Write to the last byte in the file.
+	//   - SeekHole or SeekData: the next hole or data in a sparse file at or
+	//     after the offset. See SeekHole for details.
 	//
 	// # Errors
 	//
@@ -153,11 +198,16 @@ type File interface {
 	//   - syscall.EBADF: the file or directory was closed or not readable.
 	//   - syscall.EINVAL: the offset was negative.
 	//   - syscall.EISDIR: the file was a directory.
+	//   - syscall.ENXIO: there is no hole or data at or after the offset.
+	//   - syscall.ESPIPE: the file was a pipe, socket or terminal, which has
+	//     no offset.
 	//
 	// # Notes
 	//
 	//   - This is like io.Seeker and `fseek` in POSIX, preferring semantics
 	//     of io.Seeker. See https://pubs.opengroup.org/onlinepubs/9699919799/functions/fseek.html
+	//   - Offsets are 64-bit on all hosts. On 32-bit Linux, this requires
+	//     `_llseek`, which the syscall package uses for `lseek`.
 	Seek(offset int64, whence int) (newOffset int64, errno syscall.Errno)
 
 	// PollRead returns if the file has data ready to be read or an error.
@@ -180,6 +230,27 @@ type File interface {
 	//     available).
 	PollRead(timeout *time.Duration) (ready bool, errno syscall.Errno)
 
+	// BytesAvailable returns the count of bytes which can be read without
+	// blocking, for example, to size the buffer of a single Read.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation or platform can't determine it.
+	//   - syscall.EBADF: the file was closed or not readable.
+	//   - syscall.EISDIR: the file was a directory.
+	//
+	// # Notes
+	//
+	//   - This is like `ioctl` with `FIONREAD` in POSIX, which is defined for
+	//     pipes, sockets and terminals. Windows uses `PeekNamedPipe`, which
+	//     only supports pipes.
+	//   - For a regular file, this is the size after the current offset, or
+	//     zero at or past the end.
+	//   - The result may be stale by the time of the next Read, if another
+	//     process reads or writes the file concurrently.
+	BytesAvailable() (int64, syscall.Errno)
+
 	// Readdir reads the contents of the directory associated with file and
 	// returns a slice of up to n Dirent values in an arbitrary order. This is
 	// a stateful function, so subsequent calls return any next values.
@@ -201,6 +272,23 @@ type File interface {
 	//     directory, when the file is closed or removed while open.
 	//     See https://github.com/ziglang/zig/blob/0.10.1/lib/std/fs.zig#L635-L637
 	Readdir(n int) (dirents []Dirent, errno syscall.Errno)
+
+	// RewindDir resets Readdir to return entries from the beginning of the
+	// directory again.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation does not support this function.
+	//     Callers can re-open the directory instead.
+	//   - syscall.EBADF: the file or directory was closed.
+	//   - syscall.ENOTDIR: the file was not a directory
+	//
+	// # Notes
+	//
+	//   - This is like `rewinddir` in POSIX.
+	//     See https://pubs.opengroup.org/onlinepubs/9699919799/functions/rewinddir.html
+	RewindDir() syscall.Errno
 	// ^-- TODO: consider being more like POSIX, for example, returning a
 	// closeable Dirent object that can iterate on demand. This would
 	// centralize sizing logic needed by wasi, particularly extra dirents
@@ -216,6 +304,8 @@ type File interface {
 	//   - syscall.ENOSYS: the implementation does not support this function.
 	//   - syscall.EBADF: the file or directory was closed or not writeable.
 	//   - syscall.EISDIR: the file was a directory.
+	//   - syscall.ENOSPC: there was no space left, such as a full disk. This
+	//     is also returned when writes stop making progress without error.
 	//
 	// # Notes
 	//
@@ -223,6 +313,21 @@ type File interface {
 	//     io.Writer. See https://pubs.opengroup.org/onlinepubs/9699919799/functions/write.html
 	Write(p []byte) (n int, errno syscall.Errno)
 
+	// Writev is like Write, except it writes each of `bufs` in order, as one
+	// operation. This returns the total count written even on error.
+	//
+	// # Errors
+	//
+	// The same as Write.
+	//
+	// # Notes
+	//
+	//   - This is like `writev` in POSIX. See
+	//     https://pubs.opengroup.org/onlinepubs/9699919799/functions/writev.html
+	//   - Concurrent writes to the same file don't interleave with the
+	//     buffers, though writes by other processes may.
+	Writev(bufs [][]byte) (n int, errno syscall.Errno)
+
 	// Pwrite attempts to write all bytes in `p` to the file at the given
 	// offset `off`, and returns the count written even on error.
 	//
@@ -233,11 +338,14 @@ type File interface {
 	//   - syscall.EBADF: the file or directory was closed or not writeable.
 	//   - syscall.EINVAL: the offset was negative.
 	//   - syscall.EISDIR: the file was a directory.
+	//   - syscall.ENOSPC: there was no space left. See Write for details.
+	//   - syscall.ESPIPE: the file was a pipe or socket, which has no offset.
 	//
 	// # Notes
 	//
 	//   - This is like io.WriterAt and `pwrite` in POSIX, preferring semantics
 	//     of io.WriterAt. See https://pubs.opengroup.org/onlinepubs/9699919799/functions/pwrite.html
+	//   - Like Pread, `off` is 64-bit on all hosts, and may be past 4GiB.
 	Pwrite(p []byte, off int64) (n int, errno syscall.Errno)
 
 	// Truncate truncates a file to a specified length.
@@ -254,9 +362,34 @@ type File interface {
 	//
 	//   - This is like syscall.Ftruncate and `ftruncate` in POSIX. See
 	//     https://pubs.opengroup.org/onlinepubs/9699919799/functions/ftruncate.html
+	//   - Shrinking a file releases the storage after `size`, so extending it
+	//     again reads zeros without using space, on filesystems which support
+	//     sparse files.
 	//   - Windows does not error when calling Truncate on a closed file.
 	Truncate(size int64) syscall.Errno
 
+	// PunchHole deallocates `length` bytes of storage at `offset`, which then
+	// read as zeros. The size of the file is unchanged.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation, platform or filesystem does not
+	//     support this function.
+	//   - syscall.EBADF: the file or directory was closed or not writeable.
+	//   - syscall.EINVAL: `offset` is negative or `length` isn't positive.
+	//   - syscall.EISDIR: the file was a directory.
+	//
+	// # Notes
+	//
+	//   - This is like `fallocate` with FALLOC_FL_PUNCH_HOLE on Linux, and
+	//     `fcntl` with F_PUNCHHOLE on Darwin, which requires both values to
+	//     be multiples of the block size. Other platforms return
+	//     syscall.ENOSYS.
+	//   - This is best effort: callers implementing sparse storage can fall
+	//     back to writing zeros on syscall.ENOSYS.
+	PunchHole(offset, length int64) syscall.Errno
+
 	// Sync synchronizes changes to the file.
 	//
 	// # Errors
@@ -342,9 +475,35 @@ type File interface {
 	//   - This is like syscall.UtimesNano and `futimens` in POSIX. See
 	//     https://pubs.opengroup.org/onlinepubs/9699919799/functions/futimens.html
 	//   - Windows requires files to be open with syscall.O_RDWR, which means you
-	//     cannot use this to update timestamps on a directory (syscall.EPERM).
+	//     cannot use this to update timestamps on a directory. This returns
+	//     syscall.ENOSYS in that case, so callers can fall back to the
+	//     path-based Utimens.
 	Utimens(times *[2]syscall.Timespec) syscall.Errno
 
+	// Dup returns a new File, which refers to the same open file. Closing
+	// either doesn't close the other.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation does not support this function.
+	//   - syscall.EBADF: the file or directory was closed.
+	//   - syscall.EMFILE: the process has too many open files.
+	//
+	// # Notes
+	//
+	//   - This is like syscall.Dup and `dup` in POSIX. See
+	//     https://pubs.opengroup.org/onlinepubs/9699919799/functions/dup.html
+	//   - When backed by a file descriptor, the result shares the file offset
+	//     and status flags, such as syscall.O_APPEND, as both refer to the
+	//     same open file description. In contrast, opening the same path
+	//     again results in a separate file offset.
+	//   - Wrappers, such as the read-only files of sysfs.NewReadFS, wrap the
+	//     result the same way, so it has the same restrictions.
+	//   - Files not backed by a file descriptor may share the same underlying
+	//     file, which is closed when the last File referring to it is.
+	Dup() (File, syscall.Errno)
+
 	// Close closes the underlying file.
 	//
 	// A zero syscall.Errno is success. The below are expected otherwise:
@@ -371,6 +530,16 @@ func (UnimplementedFile) SetNonblock(bool) syscall.Errno {
 	return syscall.ENOSYS
 }
 
+// SetCloexec implements File.SetCloexec
+func (UnimplementedFile) SetCloexec(bool) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+// SetTerminalRaw implements File.SetTerminalRaw
+func (UnimplementedFile) SetTerminalRaw(bool) syscall.Errno {
+	return syscall.ENOSYS
+}
+
 // Stat implements File.Stat
 func (UnimplementedFile) Stat() (Stat_t, syscall.Errno) {
 	return Stat_t{}, syscall.ENOSYS
@@ -401,16 +570,31 @@ func (UnimplementedFile) Readdir(int) (dirents []Dirent, errno syscall.Errno) {
 	return nil, syscall.ENOSYS
 }
 
+// RewindDir implements File.RewindDir
+func (UnimplementedFile) RewindDir() syscall.Errno {
+	return syscall.ENOSYS
+}
+
 // PollRead implements File.PollRead
 func (UnimplementedFile) PollRead(*time.Duration) (ready bool, errno syscall.Errno) {
 	return false, syscall.ENOSYS
 }
 
+// BytesAvailable implements File.BytesAvailable
+func (UnimplementedFile) BytesAvailable() (int64, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
+
 // Write implements File.Write
 func (UnimplementedFile) Write([]byte) (int, syscall.Errno) {
 	return 0, syscall.ENOSYS
 }
 
+// Writev implements File.Writev
+func (UnimplementedFile) Writev([][]byte) (int, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
+
 // Pwrite implements File.Pwrite
 func (UnimplementedFile) Pwrite([]byte, int64) (int, syscall.Errno) {
 	return 0, syscall.ENOSYS
@@ -421,6 +605,11 @@ func (UnimplementedFile) Truncate(int64) syscall.Errno {
 	return syscall.ENOSYS
 }
 
+// PunchHole implements File.PunchHole
+func (UnimplementedFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.ENOSYS
+}
+
 // Sync implements File.Sync
 func (UnimplementedFile) Sync() syscall.Errno {
 	return 0 // not syscall.ENOSYS
@@ -446,6 +635,11 @@ func (UnimplementedFile) Utimens(*[2]syscall.Timespec) syscall.Errno {
 	return syscall.ENOSYS
 }
 
+// Dup implements File.Dup
+func (UnimplementedFile) Dup() (File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
+
 func NewStdioFile(stdin bool, f fs.File) (File, error) {
 	// Return constant stat, which has fake times, but keep the underlying
 	// file mode. Fake times are needed to pass wasi-testsuite.
@@ -454,7 +648,7 @@ func NewStdioFile(stdin bool, f fs.File) (File, error) {
 	if st, err := f.Stat(); err != nil {
 		return nil, err
 	} else {
-		mode = st.Mode()
+		mode = terminalMode(f, st.Mode())
 	}
 	var accessMode int
 	if stdin {
@@ -463,17 +657,44 @@ func NewStdioFile(stdin bool, f fs.File) (File, error) {
 		accessMode = syscall.O_WRONLY
 	}
 	return &stdioFile{
-		fsFile: fsFile{accessMode: accessMode, file: f},
+		// Cache the file type, as stdio is often written concurrently.
+		fsFile: fsFile{accessMode: accessMode, file: f, ref: &fileRef{count: 1}, cachedSt: &cachedStat{fileType: mode.Type()}},
 		st:     Stat_t{Mode: mode, Nlink: 1},
 	}, nil
 }
 
 func NewFsFile(openPath string, openFlag int, f fs.File) File {
-	return &fsFile{
+	return newFsFile(openPath, openFlag, f)
+}
+
+// NewFsFileWithInfo is like NewFsFile, except it uses `info` already read
+// from the same file, e.g. from a prior Readdir. This avoids a syscall on the
+// first File.IsDir or File.Stat.
+//
+// Note: `info` is only returned by Stat until the file is changed, e.g. by
+// File.Write. Later calls read the current status of the file.
+func NewFsFileWithInfo(openPath string, openFlag int, f fs.File, info fs.FileInfo) File {
+	ret := newFsFile(openPath, openFlag, f)
+	st := statFromFileInfo(info)
+	ret.cachedSt = &cachedStat{fileType: st.Mode & fs.ModeType}
+	ret.stHint = &st
+	return ret
+}
+
+func newFsFile(openPath string, openFlag int, f fs.File) *fsFile {
+	ret := &fsFile{
 		path:       openPath,
 		accessMode: openFlag & (syscall.O_RDONLY | syscall.O_WRONLY | syscall.O_RDWR),
 		file:       f,
+		ref:        &fileRef{count: 1},
+	}
+	if openFlag&syscall.O_APPEND != 0 {
+		ret.append = &appendState{}
 	}
+	ret.direct = openFlag&O_DIRECT != 0 && directAlignment > 1
+	ret.nonblock = openFlag&O_NONBLOCK != 0
+	ret.pathOnly = openFlag&O_PATH != 0
+	return ret
 }
 
 type stdioFile struct {
@@ -491,8 +712,31 @@ func (f *stdioFile) Stat() (Stat_t, syscall.Errno) {
 	return f.st, 0
 }
 
+// Writev implements File.Writev
+//
+// Stdio is never a directory, nor opened with O_DIRECT or O_APPEND, so this
+// skips the checks of fsFile.Writev. When the file has a descriptor, such as
+// os.Stdout, one fd_write is one writev, so it doesn't interleave with other
+// writers of the same descriptor, even outside this process.
+func (f *stdioFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	if f.accessMode == syscall.O_RDONLY {
+		return 0, syscall.EBADF
+	}
+
+	f.writeMux.Lock()
+	defer f.writeMux.Unlock()
+
+	if sc, ok := f.file.(syscall.Conn); ok {
+		if n, errno = f.rawWritev(sc, bufs); errno != syscall.ENOSYS {
+			return
+		}
+	}
+	return f.writeEach(bufs)
+}
+
 // Close implements File.Close
 func (f *stdioFile) Close() syscall.Errno {
+	f.restoreTerminal()
 	return 0
 }
 
@@ -501,10 +745,73 @@ type fsFile struct {
 	accessMode int
 	file       fs.File
 
+	// ref counts the handles sharing file, which is closed when the last one
+	// is. It is shared by Dup when the host can't duplicate the file.
+	ref *fileRef
+
+	// closed is non-zero once Close was called on this handle. It is
+	// accessed atomically, so that concurrent calls close at most once.
+	closed int32
+
 	nonblock bool
 
 	// cachedStat includes fields that won't change while a file is open.
 	cachedSt *cachedStat
+
+	// stHint is from NewFsFileWithInfo, and returned by the next Stat unless
+	// the file was changed first.
+	stHint *Stat_t
+
+	// rawDir is the state of readdirRaw, if the file is a directory.
+	rawDir *rawDir
+
+	// savedTerm is the terminal state before the first SetTerminalRaw, which
+	// Close restores.
+	savedTerm *termState
+	// readdirBufSize is the size of the buffer of readdirRaw, set by
+	// SetReaddirBufSize, or zero for the default.
+	readdirBufSize int
+
+	// dirPos is the count of entries read since the directory was opened or
+	// rewound, when not using readdirRaw. It is the cookie of ReaddirFrom.
+	dirPos uint64
+
+	// append is non-nil when the file was opened with syscall.O_APPEND.
+	append *appendState
+
+	// direct is true when the file was opened with O_DIRECT, and the host
+	// requires aligned I/O.
+	direct bool
+
+	// pathOnly is true when the file was opened with O_PATH, so it can't be
+	// read or written.
+	pathOnly bool
+
+	// writeMux serializes writes, so that Writev doesn't interleave.
+	writeMux gosync.Mutex
+
+	// spliceHeld are bytes Splice read from a file which can't rewind, but
+	// couldn't yet write to this one. It is guarded by writeMux.
+	spliceHeld map[*fsFile][]byte
+
+	// mapped is the latest mapping from MmapRead, used by Pread. mapMux
+	// guards it, so that the mapping isn't unmapped during a copy.
+	mapped []byte
+	mapMux gosync.RWMutex
+}
+
+// fileRef is the count of fsFile handles which share the same fs.File.
+type fileRef struct {
+	count int32
+}
+
+// appendState tracks how syscall.O_APPEND is enforced on Write.
+type appendState struct {
+	// once guards checking the file descriptor for syscall.O_APPEND.
+	once gosync.Once
+	// emulated is true when the file isn't backed by a file descriptor with
+	// syscall.O_APPEND, so each Write seeks to the end first.
+	emulated bool
 }
 
 type cachedStat struct {
@@ -540,6 +847,9 @@ func (f *fsFile) IsNonblock() bool {
 
 // SetNonblock implements File.SetNonblock
 func (f *fsFile) SetNonblock(enable bool) syscall.Errno {
+	if f.isClosed() {
+		return syscall.EBADF
+	}
 	if fd, ok := f.file.(fdFile); ok {
 		if err := setNonblock(fd.Fd(), enable); err != nil {
 			return UnwrapOSError(err)
@@ -550,6 +860,17 @@ func (f *fsFile) SetNonblock(enable bool) syscall.Errno {
 	return syscall.ENOSYS
 }
 
+// SetCloexec implements File.SetCloexec
+func (f *fsFile) SetCloexec(enable bool) syscall.Errno {
+	if f.isClosed() {
+		return syscall.EBADF
+	}
+	if fd, ok := f.file.(fdFile); ok {
+		return setCloexec(fd.Fd(), enable)
+	}
+	return syscall.ENOSYS
+}
+
 // IsDir implements File.IsDir
 func (f *fsFile) IsDir() (bool, syscall.Errno) {
 	if ft, errno := f.cachedStat(); errno != 0 {
@@ -562,6 +883,12 @@ func (f *fsFile) IsDir() (bool, syscall.Errno) {
 
 // Stat implements File.Stat
 func (f *fsFile) Stat() (Stat_t, syscall.Errno) {
+	if f.isClosed() {
+		return Stat_t{}, syscall.EBADF
+	} else if st := f.stHint; st != nil {
+		f.stHint = nil
+		return *st, 0
+	}
 	st, errno := statFile(f.file)
 	switch errno {
 	case 0:
@@ -578,35 +905,92 @@ func (f *fsFile) Read(p []byte) (n int, errno syscall.Errno) {
 		return 0, 0 // less overhead on zero-length reads.
 	}
 
-	if errno = f.isDirErrno(); errno != 0 {
+	if errno = f.checkPathOnly(); errno != 0 {
+		return
+	} else if errno = f.isDirErrno(); errno != 0 {
 		return
 	} else if f.accessMode == syscall.O_WRONLY {
 		return 0, syscall.EBADF
+	} else if errno = f.checkAligned(p, 0); errno != 0 {
+		return // the host checks the file offset.
 	}
 
-	if w, ok := f.file.(io.Reader); ok {
-		n, err := w.Read(p)
-		return n, UnwrapOSError(err)
+	if f.nonblock {
+		if n, errno, ok := f.rawNonblockIO(p, false); ok {
+			return n, errno
+		}
+	}
+	if r, ok := f.file.(io.Reader); ok {
+		n, err := retryIOOnEINTR(func() (int, error) { return r.Read(p) })
+		return n, UnwrapReadError(n, err)
 	}
 	return 0, syscall.EBADF
 }
 
+// rawNonblockIO reads or writes `p` with the file descriptor of a
+// non-blocking file, and returns syscall.EAGAIN when it isn't ready. This
+// returns false if the file has no file descriptor.
+//
+// This is needed as os.File waits for a pipe or socket to be ready, even
+// after its file descriptor is made non-blocking, which would hang a guest
+// which expects to retry.
+func (f *fsFile) rawNonblockIO(p []byte, write bool) (n int, errno syscall.Errno, ok bool) {
+	sc, ok := f.file.(syscall.Conn)
+	if !ok {
+		return
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return 0, 0, false
+	}
+	call := func(fd uintptr) bool {
+		var err error
+		if write {
+			n, err = retryIOOnEINTR(func() (int, error) { return writeFd(fd, p) })
+		} else {
+			n, err = retryIOOnEINTR(func() (int, error) { return readFd(fd, p) })
+		}
+		if n < 0 {
+			n = 0 // read(2) and write(2) return -1 on error
+		}
+		errno = UnwrapOSError(err)
+		return true // don't wait, even on syscall.EAGAIN
+	}
+	if write {
+		err = rc.Write(call)
+	} else {
+		err = rc.Read(call)
+	}
+	if err != nil {
+		return 0, UnwrapOSError(err), true
+	}
+	return n, errno, true
+}
+
 // Pread implements File.Pread
 func (f *fsFile) Pread(p []byte, off int64) (n int, errno syscall.Errno) {
 	if len(p) == 0 {
 		return 0, 0 // less overhead on zero-length reads.
 	}
 
-	if errno = f.isDirErrno(); errno != 0 {
+	if errno = f.checkPathOnly(); errno != 0 {
+		return
+	} else if errno = f.checkSeekable(); errno != 0 {
 		return
 	} else if f.accessMode == syscall.O_WRONLY {
 		return 0, syscall.EBADF
+	} else if errno = f.checkAligned(p, off); errno != 0 {
+		return
+	}
+
+	if n, ok := f.preadMapped(p, off); ok {
+		return n, 0
 	}
 
 	// Simple case, handle with io.ReaderAt.
-	if w, ok := f.file.(io.ReaderAt); ok {
-		n, err := w.ReadAt(p, off)
-		return n, UnwrapOSError(err)
+	if r, ok := f.file.(io.ReaderAt); ok {
+		n, err := retryIOOnEINTR(func() (int, error) { return r.ReadAt(p, off) })
+		return n, UnwrapReadError(n, err)
 	}
 
 	// See /RATIONALE.md "fd_pread: io.Seeker fallback when io.ReaderAt is not supported"
@@ -627,8 +1011,8 @@ func (f *fsFile) Pread(p []byte, off int64) (n int, errno syscall.Errno) {
 			}
 		}
 
-		n, err := rs.Read(p)
-		return n, UnwrapOSError(err)
+		n, err := retryIOOnEINTR(func() (int, error) { return rs.Read(p) })
+		return n, UnwrapReadError(n, err)
 	}
 
 	return 0, syscall.ENOSYS // unsupported
@@ -636,8 +1020,12 @@ func (f *fsFile) Pread(p []byte, off int64) (n int, errno syscall.Errno) {
 
 // Seek implements File.Seek
 func (f *fsFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
-	if errno := f.isDirErrno(); errno != 0 {
+	if errno := f.checkPathOnly(); errno != 0 {
 		return 0, errno
+	} else if errno := f.checkSeekable(); errno != 0 {
+		return 0, errno
+	} else if whence == SeekHole || whence == SeekData {
+		return f.seekSparse(offset, whence)
 	} else if uint(whence) > io.SeekEnd {
 		return 0, syscall.EINVAL // negative or exceeds the largest valid whence
 	}
@@ -645,15 +1033,47 @@ func (f *fsFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
 	if seeker, ok := f.file.(io.Seeker); ok {
 		newOffset, err := seeker.Seek(offset, whence)
 		return newOffset, UnwrapOSError(err)
+	} else if ft, _ := f.cachedStat(); ft&fs.ModeCharDevice != 0 {
+		return 0, syscall.ESPIPE // e.g. a terminal
 	}
 	return 0, syscall.ENOSYS
 }
 
+// seekSparse implements Seek for SeekHole and SeekData.
+func (f *fsFile) seekSparse(offset int64, whence int) (int64, syscall.Errno) {
+	if offset < 0 {
+		return 0, syscall.EINVAL // like other whence, instead of ENXIO.
+	}
+	if fd, ok := f.file.(fdFile); ok {
+		if newOffset, errno := seekSparse(fd.Fd(), offset, whence); errno != syscall.ENOSYS {
+			return newOffset, errno
+		}
+	}
+
+	// Otherwise, treat the whole file as data, followed by the implicit hole
+	// at the end of the file.
+	st, errno := f.Stat()
+	if errno != 0 {
+		return 0, errno
+	} else if offset >= st.Size {
+		return 0, syscall.ENXIO
+	} else if whence == SeekHole {
+		offset = st.Size
+	}
+	return f.Seek(offset, io.SeekStart)
+}
+
 // PollRead implements File.PollRead
 func (f *fsFile) PollRead(timeout *time.Duration) (ready bool, errno syscall.Errno) {
+	if f.isClosed() {
+		return false, syscall.EBADF
+	}
 	if f, ok := f.file.(fdFile); ok {
 		fdSet := FdSet{}
 		fd := int(f.Fd())
+		if fd >= fdSetSize {
+			return false, syscall.EBADF // select can't watch this fd.
+		}
 		fdSet.Set(fd)
 		nfds := fd + 1 // See https://man7.org/linux/man-pages/man2/select.2.html#:~:text=condition%20has%20occurred.-,nfds,-This%20argument%20should
 		count, err := _select(nfds, &fdSet, nil, nil, timeout)
@@ -662,37 +1082,366 @@ func (f *fsFile) PollRead(timeout *time.Duration) (ready bool, errno syscall.Err
 	return false, syscall.ENOSYS
 }
 
+// selectFd implements selectFile
+func (f *fsFile) selectFd() (int, bool) {
+	if f, ok := f.file.(fdFile); ok {
+		return int(f.Fd()), true
+	}
+	return -1, false
+}
+
 // Readdir implements File.Readdir
 func (f *fsFile) Readdir(n int) ([]Dirent, syscall.Errno) {
+	if f.isClosed() {
+		return nil, 0 // like a directory closed while reading, see adjustReaddirErr
+	} else if errno := f.checkPathOnly(); errno != 0 {
+		return nil, errno
+	}
+	if isDir, errno := f.IsDir(); errno != 0 {
+		return nil, errno
+	} else if !isDir {
+		return nil, syscall.ENOTDIR
+	}
+	if dirents, ok, errno := f.readdirRaw(n, false); ok {
+		return dirents, errno
+	}
+	dirents, errno := readdir(f.file, n, true)
+	f.dirPos += uint64(len(dirents))
+	return dirents, errno
+}
+
+// ReaddirFrom implements the same method as documented on ReaddirFrom
+func (f *fsFile) ReaddirFrom(cookie uint64, n int) ([]Dirent, syscall.Errno) {
+	if f.isClosed() {
+		return nil, 0 // like a directory closed while reading, see adjustReaddirErr
+	} else if errno := f.checkPathOnly(); errno != 0 {
+		return nil, errno
+	}
+	if isDir, errno := f.IsDir(); errno != 0 {
+		return nil, errno
+	} else if !isDir {
+		return nil, syscall.ENOTDIR
+	}
+	if ok, errno := f.seekDirRaw(cookie); ok {
+		if errno != 0 {
+			return nil, errno
+		}
+		dirents, _, errno := f.readdirRaw(n, true)
+		return dirents, errno
+	}
+
+	// Otherwise, the cookie is an index, which is cheap to resume at when it
+	// is the current position.
+	if cookie != f.dirPos {
+		if errno := f.RewindDir(); errno != 0 {
+			return nil, errno
+		}
+		readdirNoIno := func(n int) ([]Dirent, syscall.Errno) { return readdir(f.file, n, false) }
+		if errno := skipDirents(readdirNoIno, cookie); errno != 0 {
+			return nil, errno
+		}
+		f.dirPos = cookie
+	}
+	dirents, errno := readdir(f.file, n, true)
+	for i := range dirents {
+		f.dirPos++
+		dirents[i].Cookie = f.dirPos
+	}
+	return dirents, errno
+}
+
+// readdirNoIno implements lazyInoFile
+func (f *fsFile) readdirNoIno(n int) ([]Dirent, syscall.Errno) {
 	if isDir, errno := f.IsDir(); errno != 0 {
 		return nil, errno
 	} else if !isDir {
 		return nil, syscall.ENOTDIR
 	}
-	return readdir(f.file, n)
+	// getdents64 includes the inode, so there's no cost to skip.
+	if dirents, ok, errno := f.readdirRaw(n, false); ok {
+		return dirents, errno
+	}
+	// Only windows has to fan out to lstat for the inode.
+	dirents, errno := readdir(f.file, n, runtime.GOOS != "windows")
+	f.dirPos += uint64(len(dirents))
+	return dirents, errno
+}
+
+// fillIno implements lazyInoFile
+func (f *fsFile) fillIno(dirents []Dirent) syscall.Errno {
+	pf, ok := f.file.(PathFile)
+	if !ok {
+		return 0 // readdirNoIno didn't skip Dirent.Ino
+	}
+	for i := range dirents {
+		d := &dirents[i]
+		if d.Ino != 0 {
+			continue
+		}
+		if st, errno := Lstat(path.Join(pf.Path(), d.Name)); errno == 0 {
+			d.Ino = st.Ino
+		} else if errno != syscall.ENOENT { // removed since read
+			return errno
+		}
+	}
+	return 0
+}
+
+// RewindDir implements File.RewindDir
+func (f *fsFile) RewindDir() syscall.Errno {
+	if errno := f.checkPathOnly(); errno != 0 {
+		return errno
+	}
+	if isDir, errno := f.IsDir(); errno != 0 {
+		return errno
+	} else if !isDir {
+		return syscall.ENOTDIR
+	}
+
+	if rf, ok := f.file.(rewindDirFile); ok {
+		if errno := rf.rewindDir(); errno != 0 {
+			return errno
+		}
+	} else if s, ok := f.file.(io.Seeker); ok {
+		// os.File discards any buffered entries when seeking a directory.
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return UnwrapOSError(err)
+		}
+	} else {
+		return syscall.ENOSYS // e.g. embed.FS
+	}
+	f.rawDir = nil // discard entries buffered by readdirRaw.
+	f.dirPos = 0
+	return 0
 }
 
 // Write implements File.Write
 func (f *fsFile) Write(p []byte) (n int, errno syscall.Errno) {
-	if errno = f.isDirErrno(); errno != 0 {
+	if errno = f.checkWrite(); errno != 0 {
+		return
+	} else if len(p) == 0 {
+		return 0, 0 // less overhead on zero-length writes.
+	} else if errno = f.checkAligned(p, 0); errno != 0 {
+		return // the host checks the file offset.
+	}
+
+	f.writeMux.Lock()
+	defer f.writeMux.Unlock()
+	return f.write(p)
+}
+
+// checkAligned returns syscall.EINVAL if the file was opened with O_DIRECT,
+// and `p` or `off` aren't aligned as the host requires.
+func (f *fsFile) checkAligned(p []byte, off int64) syscall.Errno {
+	if !f.direct {
+		return 0
+	}
+	return checkDirectAligned(p, off)
+}
+
+// checkWrite returns syscall.EISDIR or syscall.EBADF if the file can't be
+// written.
+func (f *fsFile) checkWrite() (errno syscall.Errno) {
+	if errno = f.checkPathOnly(); errno != 0 {
+		return
+	} else if errno = f.isDirErrno(); errno != 0 {
 		return
 	} else if f.accessMode == syscall.O_RDONLY {
-		return 0, syscall.EBADF
+		return syscall.EBADF
 	}
+	return
+}
 
-	if len(p) == 0 {
-		return 0, 0 // less overhead on zero-length writes.
+// write is Write, without checks or locking.
+//
+// Short writes are common on pipes, so this writes until all of `p` is
+// written or there's an error, such as syscall.EAGAIN on a non-blocking file.
+// In either case, the count is the total bytes written. See writeAll.
+func (f *fsFile) write(p []byte) (int, syscall.Errno) {
+	f.stHint = nil
+	w, ok := f.file.(io.Writer)
+	if !ok {
+		return 0, syscall.ENOSYS // unsupported
 	}
-	if w, ok := f.file.(io.Writer); ok {
-		n, err := w.Write(p)
+	return writeAll(p, func(p []byte) (int, syscall.Errno) {
+		if f.nonblock && !f.appendEmulated() {
+			if n, errno, ok := f.rawNonblockIO(p, true); ok {
+				return n, errno
+			}
+		}
+		if f.append != nil {
+			return f.appendWrite(w, p)
+		}
+		n, err := retryIOOnEINTR(func() (int, error) { return w.Write(p) })
 		return n, UnwrapOSError(err)
+	})
+}
+
+// writeAll calls `write` with the remainder of `p` until it is all written or
+// there's an error, and returns the total count written.
+//
+// A write which makes no progress, but has no error, is retried once. If it
+// still makes no progress, this returns syscall.ENOSPC, as a full disk or
+// quota is the usual cause. Otherwise, a guest could misinterpret the short
+// count as success, or loop forever.
+func writeAll(p []byte, write func([]byte) (int, syscall.Errno)) (n int, errno syscall.Errno) {
+	retried := false
+	for n < len(p) {
+		var written int
+		written, errno = write(p[n:])
+		n += written
+		if errno != 0 {
+			return
+		} else if written > 0 {
+			retried = false
+		} else if retried {
+			return n, syscall.ENOSPC
+		} else {
+			retried = true
+		}
 	}
-	return 0, syscall.ENOSYS // unsupported
+	return
+}
+
+// Writev implements File.Writev
+func (f *fsFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	if errno = f.checkWrite(); errno != 0 {
+		return
+	}
+	for _, buf := range bufs {
+		if errno = f.checkAligned(buf, 0); errno != 0 {
+			return
+		}
+	}
+
+	f.writeMux.Lock()
+	defer f.writeMux.Unlock()
+	f.stHint = nil
+
+	// Prefer writev, which writes all buffers in one syscall. This isn't used
+	// when O_APPEND is emulated, as that must seek before each write.
+	if sc, ok := f.file.(syscall.Conn); ok && !f.appendEmulated() {
+		if n, errno = f.rawWritev(sc, bufs); errno != syscall.ENOSYS {
+			return
+		}
+	}
+
+	return f.writeEach(bufs)
+}
+
+// writeEach writes each buffer in order. The caller holds writeMux.
+func (f *fsFile) writeEach(bufs [][]byte) (n int, errno syscall.Errno) {
+	for _, buf := range bufs {
+		if len(buf) == 0 {
+			continue
+		}
+		written, errno := f.write(buf)
+		n += written
+		if errno != 0 || written < len(buf) {
+			return n, errno
+		}
+	}
+	return n, 0
+}
+
+// rawWritev calls writev with the file descriptor of `sc`, which is typically
+// an os.File. This returns syscall.ENOSYS when writev isn't supported.
+//
+// Like write, this continues with the remaining buffers after a short write,
+// and retries syscall.EINTR, until all are written or there's an error. See
+// writeAll for how a write which makes no progress is handled.
+func (f *fsFile) rawWritev(sc syscall.Conn, bufs [][]byte) (n int, errno syscall.Errno) {
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return 0, syscall.ENOSYS
+	}
+	retried := false
+	for {
+		var written int
+		// Like os.File.Write, this waits until a blocking file is writable.
+		err = rc.Write(func(fd uintptr) bool {
+			var ioErr error
+			written, ioErr = retryIOOnEINTR(func() (int, error) {
+				n, errno := writev(fd, bufs)
+				if errno != 0 {
+					return n, errno
+				}
+				return n, nil
+			})
+			errno = UnwrapOSError(ioErr)
+			return errno != syscall.EAGAIN || f.nonblock
+		})
+		if err != nil {
+			return n, UnwrapOSError(err)
+		}
+		n += written
+		if errno != 0 {
+			return // syscall.ENOSYS is only possible before any write.
+		} else if bufs = skipWritten(bufs, written); len(bufs) == 0 {
+			return n, 0
+		} else if written > 0 {
+			retried = false
+		} else if retried {
+			return n, syscall.ENOSPC
+		} else {
+			retried = true
+		}
+	}
+}
+
+// skipWritten returns the part of `bufs` after the first `n` bytes, without
+// modifying `bufs`. Empty buffers at the start are skipped.
+func skipWritten(bufs [][]byte, n int) [][]byte {
+	for len(bufs) > 0 && n >= len(bufs[0]) {
+		n -= len(bufs[0])
+		bufs = bufs[1:]
+	}
+	if n > 0 {
+		bufs = append([][]byte{bufs[0][n:]}, bufs[1:]...)
+	}
+	return bufs
+}
+
+// appendEmulated returns true if the file was opened with syscall.O_APPEND,
+// but it isn't backed by a file descriptor with that flag.
+func (f *fsFile) appendEmulated() bool {
+	a := f.append
+	if a == nil {
+		return false
+	}
+	a.once.Do(func() {
+		a.emulated = true
+		if fd, ok := f.file.(fdFile); ok {
+			a.emulated = setAppend(fd.Fd()) != 0
+		}
+	})
+	return a.emulated
+}
+
+// appendWrite writes to the end of a file opened with syscall.O_APPEND.
+//
+// The host enforces this when the file descriptor has syscall.O_APPEND. Other
+// files, such as those from an fs.FS, seek to the end before each write. The
+// caller holds writeMux, so concurrent writes to this file don't interleave,
+// but writes to other files opened on the same path may.
+func (f *fsFile) appendWrite(w io.Writer, p []byte) (int, syscall.Errno) {
+	if f.appendEmulated() {
+		// Files which can't seek, such as pipes, can only write at the end.
+		if s, ok := f.file.(io.Seeker); ok {
+			if _, err := s.Seek(0, io.SeekEnd); err != nil {
+				return 0, UnwrapOSError(err)
+			}
+		}
+	}
+	n, err := retryIOOnEINTR(func() (int, error) { return w.Write(p) })
+	return n, UnwrapOSError(err)
 }
 
 // Pwrite implements File.Pwrite
 func (f *fsFile) Pwrite(p []byte, off int64) (n int, errno syscall.Errno) {
-	if errno = f.isDirErrno(); errno != 0 {
+	if errno = f.checkPathOnly(); errno != 0 {
+		return
+	} else if errno = f.checkSeekable(); errno != 0 {
 		return
 	} else if f.accessMode == syscall.O_RDONLY {
 		return 0, syscall.EBADF
@@ -700,33 +1449,64 @@ func (f *fsFile) Pwrite(p []byte, off int64) (n int, errno syscall.Errno) {
 
 	if len(p) == 0 {
 		return 0, 0 // less overhead on zero-length writes.
+	} else if errno = f.checkAligned(p, off); errno != 0 {
+		return
 	}
 
 	if w, ok := f.file.(io.WriterAt); ok {
-		n, err := w.WriteAt(p, off)
-		return n, UnwrapOSError(err)
+		f.stHint = nil
+		return writeAll(p, func(remaining []byte) (int, syscall.Errno) {
+			at := off + int64(len(p)-len(remaining))
+			n, err := retryIOOnEINTR(func() (int, error) { return w.WriteAt(remaining, at) })
+			return n, UnwrapOSError(err)
+		})
 	}
 	return 0, syscall.ENOSYS // unsupported
 }
 
 // Truncate implements File.Truncate
 func (f *fsFile) Truncate(size int64) syscall.Errno {
-	if errno := f.isDirErrno(); errno != 0 {
+	if errno := f.checkPathOnly(); errno != 0 {
+		return errno
+	} else if errno := f.isDirErrno(); errno != 0 {
 		return errno
 	} else if f.accessMode == syscall.O_RDONLY {
 		return syscall.EBADF
 	}
 
 	if tf, ok := f.file.(truncateFile); ok {
+		f.stHint = nil
+		f.invalidateMapping(size)
 		return UnwrapOSError(tf.Truncate(size))
 	}
 	return syscall.ENOSYS
 }
 
+// PunchHole implements File.PunchHole
+func (f *fsFile) PunchHole(offset, length int64) syscall.Errno {
+	if errno := f.checkPathOnly(); errno != 0 {
+		return errno
+	} else if errno := f.isDirErrno(); errno != 0 {
+		return errno
+	} else if f.accessMode == syscall.O_RDONLY {
+		return syscall.EBADF
+	} else if offset < 0 || length <= 0 {
+		return syscall.EINVAL
+	}
+
+	if fd, ok := f.file.(fdFile); ok {
+		f.stHint = nil
+		return punchHole(fd.Fd(), offset, length)
+	}
+	return syscall.ENOSYS
+}
+
 // isDirErrno returns syscall.EISDIR, if the file is a directory, or any error
-// calling IsDir.
+// calling IsDir. It returns syscall.EBADF if this handle was closed.
 func (f *fsFile) isDirErrno() syscall.Errno {
-	if isDir, errno := f.IsDir(); errno != 0 {
+	if f.isClosed() {
+		return syscall.EBADF
+	} else if isDir, errno := f.IsDir(); errno != 0 {
 		return errno
 	} else if isDir {
 		return syscall.EISDIR
@@ -734,19 +1514,50 @@ func (f *fsFile) isDirErrno() syscall.Errno {
 	return 0
 }
 
+// checkSeekable is like isDirErrno, except it also returns syscall.ESPIPE
+// for a pipe or socket, which has no file offset.
+func (f *fsFile) checkSeekable() syscall.Errno {
+	if f.isClosed() {
+		return syscall.EBADF
+	} else if ft, errno := f.cachedStat(); errno != 0 {
+		return errno
+	} else if ft == fs.ModeDir {
+		return syscall.EISDIR
+	} else if ft&(fs.ModeNamedPipe|fs.ModeSocket) != 0 {
+		return syscall.ESPIPE
+	}
+	return 0
+}
+
 // Sync implements File.Sync
 func (f *fsFile) Sync() syscall.Errno {
+	if f.isClosed() {
+		return syscall.EBADF
+	} else if errno := f.checkPathOnly(); errno != 0 {
+		return errno
+	}
 	return sync(f.file)
 }
 
 // Datasync implements File.Datasync
 func (f *fsFile) Datasync() syscall.Errno {
+	if f.isClosed() {
+		return syscall.EBADF
+	} else if errno := f.checkPathOnly(); errno != 0 {
+		return errno
+	}
 	return datasync(f.file)
 }
 
 // Chmod implements File.Chmod
 func (f *fsFile) Chmod(mode fs.FileMode) syscall.Errno {
-	if f, ok := f.file.(chmodFile); ok {
+	if f.isClosed() {
+		return syscall.EBADF
+	}
+	f.stHint = nil
+	if name, ok := f.pathOnlyName(); ok {
+		return UnwrapOSError(os.Chmod(name, mode))
+	} else if f, ok := f.file.(chmodFile); ok {
 		return UnwrapOSError(f.Chmod(mode))
 	}
 	return syscall.ENOSYS
@@ -754,7 +1565,13 @@ func (f *fsFile) Chmod(mode fs.FileMode) syscall.Errno {
 
 // Chown implements File.Chown
 func (f *fsFile) Chown(uid, gid int) syscall.Errno {
-	if f, ok := f.file.(fdFile); ok {
+	if f.isClosed() {
+		return syscall.EBADF
+	}
+	f.stHint = nil
+	if name, ok := f.pathOnlyName(); ok {
+		return Chown(name, uid, gid)
+	} else if f, ok := f.file.(fdFile); ok {
 		return fchown(f.Fd(), uid, gid)
 	}
 	return syscall.ENOSYS
@@ -762,16 +1579,101 @@ func (f *fsFile) Chown(uid, gid int) syscall.Errno {
 
 // Utimens implements File.Utimens
 func (f *fsFile) Utimens(times *[2]syscall.Timespec) syscall.Errno {
-	if f, ok := f.file.(fdFile); ok {
+	if f.isClosed() {
+		return syscall.EBADF
+	}
+	f.stHint = nil
+	if name, ok := f.pathOnlyName(); ok {
+		return Utimens(name, times, true)
+	} else if f, ok := f.file.(fdFile); ok {
 		err := futimens(f.Fd(), times)
 		return UnwrapOSError(err)
 	}
 	return syscall.ENOSYS
 }
 
+// setReaddirBufSize implements readdirBufSizeFile.setReaddirBufSize
+func (f *fsFile) setReaddirBufSize(size int) {
+	f.readdirBufSize = size
+}
+
+// Dup implements File.Dup
+//
+// When the host can't duplicate the file, such as an fs.File from an fs.FS,
+// the result shares it, and it is closed when the last handle is.
+func (f *fsFile) Dup() (File, syscall.Errno) {
+	if f.isClosed() {
+		return nil, syscall.EBADF
+	}
+	file, ref := f.file, f.ref
+	if dup, errno := dupFile(f.file); errno == 0 {
+		file, ref = dup, &fileRef{count: 1}
+	} else if errno != syscall.ENOSYS {
+		return nil, errno
+	} else if !f.ref.acquire() {
+		return nil, syscall.EBADF // closed concurrently
+	}
+	dup := &fsFile{
+		path:           f.path,
+		accessMode:     f.accessMode,
+		file:           file,
+		ref:            ref,
+		nonblock:       f.nonblock,
+		cachedSt:       f.cachedSt,
+		direct:         f.direct,
+		pathOnly:       f.pathOnly,
+		readdirBufSize: f.readdirBufSize,
+	}
+	if f.append != nil {
+		dup.append = &appendState{}
+	}
+	return dup, 0
+}
+
+// Dup implements File.Dup
+//
+// The result is closed by Close, unlike stdioFile, which leaves the
+// standard stream open.
+func (f *stdioFile) Dup() (File, syscall.Errno) {
+	return f.fsFile.Dup()
+}
+
 // Close implements File.Close
+//
+// Closing the same handle again returns syscall.EBADF, instead of closing
+// the file a second time, which could close a descriptor reused by an
+// unrelated open.
 func (f *fsFile) Close() syscall.Errno {
-	return UnwrapOSError(f.file.Close())
+	if !atomic.CompareAndSwapInt32(&f.closed, 0, 1) {
+		return syscall.EBADF
+	}
+	f.restoreTerminal()
+	if f.ref.release() {
+		return UnwrapOSError(f.file.Close())
+	}
+	return 0
+}
+
+// isClosed returns true if Close was called on this handle.
+func (f *fsFile) isClosed() bool {
+	return atomic.LoadInt32(&f.closed) != 0
+}
+
+// acquire adds a handle, unless the last one was already released.
+func (r *fileRef) acquire() bool {
+	for {
+		count := atomic.LoadInt32(&r.count)
+		if count == 0 {
+			return false
+		} else if atomic.CompareAndSwapInt32(&r.count, count, count+1) {
+			return true
+		}
+	}
+}
+
+// release removes a handle, and returns true if it was the last one.
+func (r *fileRef) release() bool {
+	return atomic.AddInt32(&r.count, -1) == 0
 }
 
 // The following interfaces are used until we finalize our own FD-scoped file.
@@ -782,6 +1684,9 @@ type (
 	}
 	// fdFile is implemented by os.File in file_unix.go and file_windows.go
 	fdFile interface{ Fd() (fd uintptr) }
+	// rewindDirFile is implemented by files which can't rewind a directory
+	// with io.Seeker, such as windowsWrappedFile.
+	rewindDirFile interface{ rewindDir() syscall.Errno }
 	// readdirFile is implemented by os.File in dir.go
 	readdirFile interface {
 		Readdir(n int) ([]fs.FileInfo, error)
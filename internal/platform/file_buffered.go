@@ -0,0 +1,176 @@
+package platform
+
+import (
+	"io"
+	"syscall"
+	"time"
+)
+
+// defaultBufferSize is used by NewBufferedFile when the size is not positive.
+const defaultBufferSize = 4096
+
+// NewBufferedFile returns a File which buffers forward reads of `f`, so that
+// many small Read calls result in fewer reads of the underlying file.
+//
+// # Notes
+//
+//   - This changes the count and size of reads made to `f`, so tracing of
+//     the underlying file won't match the guest's calls one-to-one.
+//   - The buffer is discarded on Seek, Write, Writev, Pwrite, Truncate and
+//     PunchHole, rewinding the underlying offset to where the caller last
+//     read. A file which can't rewind, such as a pipe or socket, keeps the
+//     buffer instead, so that data read ahead is still returned.
+//   - Pread bypasses the buffer, as it doesn't affect the file offset.
+//   - PollRead returns true immediately while buffered data remains.
+func NewBufferedFile(f File, size int) File {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	return &bufferedFile{File: f, buf: make([]byte, 0, size)}
+}
+
+type bufferedFile struct {
+	File
+
+	// buf holds data read from File not yet returned by Read.
+	buf []byte
+	// pos is the position in buf of the next byte to Read.
+	pos int
+}
+
+// buffered returns the count of bytes read from the file, but not the caller.
+func (f *bufferedFile) buffered() int {
+	return len(f.buf) - f.pos
+}
+
+// Read implements File.Read
+func (f *bufferedFile) Read(p []byte) (int, syscall.Errno) {
+	if len(p) == 0 {
+		return 0, 0
+	}
+
+	if f.buffered() == 0 {
+		// Avoid copying when the read is at least as large as the buffer.
+		if len(p) >= cap(f.buf) {
+			return f.File.Read(p)
+		}
+		n, errno := f.File.Read(f.buf[:cap(f.buf)])
+		f.buf, f.pos = f.buf[:n], 0
+		if n == 0 {
+			return 0, errno
+		}
+	}
+
+	n := copy(p, f.buf[f.pos:])
+	f.pos += n
+	return n, 0
+}
+
+// discard drops any buffered data, rewinding the file offset to account for
+// data the caller didn't read yet. Data of a file which can't rewind, such as
+// a pipe, is kept, as it can't be read again.
+func (f *bufferedFile) discard() syscall.Errno {
+	if remaining := f.buffered(); remaining > 0 {
+		switch _, errno := f.File.Seek(int64(-remaining), io.SeekCurrent); errno {
+		case 0:
+		case syscall.ESPIPE, syscall.ENOSYS:
+			return 0
+		default:
+			return errno
+		}
+	}
+	f.buf, f.pos = f.buf[:0], 0
+	return 0
+}
+
+// Seek implements File.Seek
+func (f *bufferedFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if whence == io.SeekCurrent {
+		// Seek relative to what the caller read, not the underlying offset.
+		offset -= int64(f.buffered())
+	}
+	newOffset, errno := f.File.Seek(offset, whence)
+	if errno == 0 {
+		f.buf, f.pos = f.buf[:0], 0
+	}
+	return newOffset, errno
+}
+
+// Write implements File.Write
+func (f *bufferedFile) Write(p []byte) (int, syscall.Errno) {
+	if errno := f.discard(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Write(p)
+}
+
+// Writev implements File.Writev
+func (f *bufferedFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	if errno := f.discard(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Writev(bufs)
+}
+
+// Pwrite implements File.Pwrite
+func (f *bufferedFile) Pwrite(p []byte, off int64) (int, syscall.Errno) {
+	if errno := f.discard(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Pwrite(p, off)
+}
+
+// Truncate implements File.Truncate
+func (f *bufferedFile) Truncate(size int64) syscall.Errno {
+	if errno := f.discard(); errno != 0 {
+		return errno
+	}
+	return f.File.Truncate(size)
+}
+
+// PunchHole implements File.PunchHole
+func (f *bufferedFile) PunchHole(offset, length int64) syscall.Errno {
+	if errno := f.discard(); errno != 0 {
+		return errno
+	}
+	return f.File.PunchHole(offset, length)
+}
+
+// Dup implements File.Dup
+//
+// Buffered data is rewound first, so that the result reads it, too. Data
+// buffered from a file which can't rewind, such as a pipe, remains readable
+// only from this file.
+func (f *bufferedFile) Dup() (File, syscall.Errno) {
+	if errno := f.discard(); errno != 0 {
+		return nil, errno
+	}
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return NewBufferedFile(dup, cap(f.buf)), 0
+}
+
+// PollRead implements File.PollRead
+func (f *bufferedFile) PollRead(timeout *time.Duration) (ready bool, errno syscall.Errno) {
+	if f.buffered() > 0 {
+		return true, 0
+	}
+	return f.File.PollRead(timeout)
+}
+
+// BytesAvailable implements File.BytesAvailable
+//
+// This includes buffered data. When the underlying file can't report its
+// count, this returns only the buffered count, if any.
+func (f *bufferedFile) BytesAvailable() (int64, syscall.Errno) {
+	buffered := int64(f.buffered())
+	n, errno := f.File.BytesAvailable()
+	if errno == syscall.ENOSYS && buffered > 0 {
+		return buffered, 0
+	} else if errno != 0 {
+		return 0, errno
+	}
+	return buffered + n, 0
+}
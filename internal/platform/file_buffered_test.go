@@ -0,0 +1,156 @@
+package platform
+
+import (
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewBufferedFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	p := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(p, []byte("wazero is a runtime"), 0o600))
+
+	of, err := os.OpenFile(p, os.O_RDWR, 0)
+	require.NoError(t, err)
+	counter := &readCountFile{File: NewFsFile(p, os.O_RDWR, of)}
+	f := NewBufferedFile(counter, 8)
+	defer f.Close()
+
+	// Byte-at-a-time reads are served from the buffer.
+	buf := make([]byte, 1)
+	for _, c := range []byte("wazero") {
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, n)
+		require.Equal(t, c, buf[0])
+	}
+	require.Equal(t, 1, counter.reads)
+
+	// PollRead is ready while buffered data remains.
+	timeout := time.Duration(0)
+	ready, errno := f.PollRead(&timeout)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	// Seek is relative to what was read, not the underlying offset.
+	off, errno := f.Seek(0, io.SeekCurrent)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), off)
+
+	// Pread bypasses the buffer.
+	pbuf := make([]byte, 7)
+	n, errno := f.Pread(pbuf, 12)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "runtime", string(pbuf[:n]))
+
+	// Write discards the buffer, writing at the caller's offset.
+	n, errno = f.Write([]byte(" IS"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 3, n)
+
+	rest := make([]byte, 20)
+	n, errno = f.Read(rest)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, " a runtime", string(rest[:n]))
+
+	b, err := os.ReadFile(p)
+	require.NoError(t, err)
+	require.Equal(t, "wazero IS a runtime", string(b))
+
+	// Truncate discards the buffer.
+	_, errno = f.Seek(0, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Truncate(3))
+	n, errno = f.Read(rest)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "az", string(rest[:n]))
+
+	n, errno = f.Read(rest)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+}
+
+func TestNewBufferedFile_Errors(t *testing.T) {
+	// Errors from the underlying file are returned as-is.
+	f := NewBufferedFile(NoopFile{}, 0)
+	_, errno := f.Read(make([]byte, 1))
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+
+	// Writes to a read-only file fail after discarding the buffer.
+	f = NewBufferedFile(NewFsFile(wazeroFile, syscall.O_RDONLY, embedFile(t)), 0)
+	defer f.Close()
+	_, errno = f.Read(make([]byte, 1))
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("a"))
+	require.EqualErrno(t, syscall.EBADF, errno)
+}
+
+func TestNewBufferedFile_unseekable(t *testing.T) {
+	p := path.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(p, []byte("wazero"), 0o600))
+
+	of, err := os.OpenFile(p, os.O_RDWR, 0)
+	require.NoError(t, err)
+	f := NewBufferedFile(&unseekableFile{File: NewFsFile(p, os.O_RDWR, of)}, 8)
+	defer f.Close()
+
+	buf := make([]byte, 2)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wa", string(buf[:n]))
+
+	// Like a duplex pipe, data read ahead is kept across writes, as it can't
+	// be read again.
+	_, errno = f.Write([]byte("!"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Truncate(7))
+	_, errno = f.Pwrite([]byte("?"), 7)
+	require.EqualErrno(t, 0, errno)
+
+	rest := make([]byte, 8)
+	n, errno = f.Read(rest)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "zero", string(rest[:n]))
+}
+
+// unseekableFile fails Seek like a pipe.
+type unseekableFile struct {
+	File
+}
+
+func (f *unseekableFile) Seek(int64, int) (int64, syscall.Errno) {
+	return 0, syscall.ESPIPE
+}
+
+// readCountFile counts calls to Read.
+type readCountFile struct {
+	File
+	reads int
+}
+
+func (f *readCountFile) Read(buf []byte) (int, syscall.Errno) {
+	f.reads++
+	return f.File.Read(buf)
+}
+
+func TestBufferedFile_BytesAvailable(t *testing.T) {
+	filePath := path.Join(t.TempDir(), wazeroFile)
+	require.NoError(t, os.WriteFile(filePath, []byte("wazero"), 0o600))
+
+	f := NewBufferedFile(openFsFile(t, filePath, syscall.O_RDONLY, 0), 4)
+	defer f.Close()
+
+	_, errno := f.Read(make([]byte, 1))
+	require.EqualErrno(t, 0, errno)
+	n, errno := f.BytesAvailable()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(5), n) // 3 buffered and 2 not yet read
+}
@@ -0,0 +1,228 @@
+package platform
+
+import (
+	"context"
+	"os"
+	gosync "sync"
+	"syscall"
+	"time"
+)
+
+// NewContextFile returns a File whose blocking Read, Pread, Write, Writev,
+// Pwrite and PollRead return syscall.ECANCELED once the context is done.
+//
+// # Notes
+//
+//   - Cancellation wakes up a blocked call via the read-end of a pipe added
+//     to the select set, as described on _select. The pipe and the goroutine
+//     watching the context are only created on the first call that would
+//     block. The goroutine exits once the context is done, and Close
+//     releases the pipe.
+//   - When the file has no file descriptor, the descriptor is too large for
+//     select (FD_SETSIZE), or select isn't supported on the platform, the
+//     context is only checked before each call.
+//   - A context which can never be done, such as context.Background, returns
+//     the file as-is.
+func NewContextFile(ctx context.Context, f File) File {
+	if ctx.Done() == nil {
+		return f // nothing to cancel
+	}
+	cf := &contextFile{File: f, ctx: ctx, fd: -1, closed: make(chan struct{})}
+	if fd, ok := f.(selectFile); ok {
+		cf.fd, _ = fd.selectFd()
+	}
+	if cf.fd >= fdSetSize {
+		cf.fd = -1
+	}
+	return cf
+}
+
+// selectFile is implemented by files which can be passed to _select.
+type selectFile interface {
+	// selectFd returns the file descriptor or -1 if not applicable.
+	selectFd() (fd int, ok bool)
+}
+
+type contextFile struct {
+	File
+
+	ctx context.Context
+	// fd is the file descriptor of File or -1 if it cannot be selected.
+	fd int
+
+	// mux guards the fields below, which are lazily initialized by wakeFd.
+	mux          gosync.Mutex
+	wakeR, wakeW *os.File
+	closed       chan struct{}
+	isClosed     bool
+	// noWake is set when the pipe can't be used, so isn't retried.
+	noWake bool
+}
+
+// Read implements File.Read
+func (f *contextFile) Read(buf []byte) (int, syscall.Errno) {
+	if errno := f.wait(false); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Read(buf)
+}
+
+// Pread implements File.Pread
+func (f *contextFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.wait(false); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Pread(buf, off)
+}
+
+// Write implements File.Write
+func (f *contextFile) Write(buf []byte) (int, syscall.Errno) {
+	if errno := f.wait(true); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Write(buf)
+}
+
+// Writev implements File.Writev
+func (f *contextFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	if errno := f.wait(true); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Writev(bufs)
+}
+
+// Pwrite implements File.Pwrite
+func (f *contextFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.wait(true); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Pwrite(buf, off)
+}
+
+// PollRead implements File.PollRead
+func (f *contextFile) PollRead(timeout *time.Duration) (ready bool, errno syscall.Errno) {
+	if f.ctx.Err() != nil {
+		return false, syscall.ECANCELED
+	} else if f.fd == -1 {
+		return f.File.PollRead(timeout)
+	}
+
+	wakeFd := f.wakeFd()
+	if wakeFd == -1 {
+		return f.File.PollRead(timeout)
+	}
+	var r FdSet
+	r.Set(f.fd)
+	r.Set(wakeFd)
+	count, err := _select(f.nfds(wakeFd), &r, nil, nil, timeout)
+	if errno = UnwrapOSError(err); errno == syscall.ENOSYS {
+		return f.File.PollRead(timeout)
+	} else if errno != 0 {
+		return false, errno
+	} else if r.IsSet(wakeFd) {
+		return false, syscall.ECANCELED
+	}
+	return count > 0, 0
+}
+
+// wait blocks until the file is ready to read or write, returning
+// syscall.ECANCELED if the context is done first.
+func (f *contextFile) wait(write bool) syscall.Errno {
+	if f.ctx.Err() != nil {
+		return syscall.ECANCELED
+	} else if f.fd == -1 || f.File.IsNonblock() {
+		return 0 // either we can't wait, or the operation won't block.
+	}
+
+	wakeFd := f.wakeFd()
+	if wakeFd == -1 {
+		return 0 // we can't wait, so only the context check above applies.
+	}
+	for {
+		var r, w FdSet
+		r.Set(wakeFd)
+		if write {
+			w.Set(f.fd)
+		} else {
+			r.Set(f.fd)
+		}
+		_, err := _select(f.nfds(wakeFd), &r, &w, nil, nil)
+		switch UnwrapOSError(err) {
+		case 0:
+			if r.IsSet(wakeFd) {
+				return syscall.ECANCELED
+			}
+			return 0
+		case syscall.EINTR:
+			continue
+		default:
+			// e.g. syscall.ENOSYS: let the operation proceed, blocking.
+			return 0
+		}
+	}
+}
+
+// wakeFd returns the read-end of the pipe which becomes readable once the
+// context is done, creating it on first use. This returns -1 when the pipe
+// can't be created or can't be passed to select.
+func (f *contextFile) wakeFd() int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.isClosed || f.noWake {
+		return -1
+	} else if f.wakeR != nil {
+		return int(f.wakeR.Fd())
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		f.noWake = true // e.g. pipes aren't supported on GOOS=js
+		return -1
+	} else if int(r.Fd()) >= fdSetSize {
+		_, _ = r.Close(), w.Close()
+		f.noWake = true
+		return -1
+	}
+	f.wakeR, f.wakeW = r, w
+	go func(ctx context.Context, closed <-chan struct{}) {
+		select {
+		case <-ctx.Done():
+			// Closing the write-end makes the read-end readable (EOF).
+			_ = w.Close()
+		case <-closed:
+		}
+	}(f.ctx, f.closed)
+	return int(r.Fd())
+}
+
+func (f *contextFile) nfds(wakeFd int) int {
+	if f.fd > wakeFd {
+		return f.fd + 1
+	}
+	return wakeFd + 1
+}
+
+// Dup implements File.Dup
+func (f *contextFile) Dup() (File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return NewContextFile(f.ctx, dup), 0
+}
+
+// Close implements File.Close
+func (f *contextFile) Close() syscall.Errno {
+	f.mux.Lock()
+	if !f.isClosed {
+		f.isClosed = true
+		close(f.closed)
+		if f.wakeR != nil {
+			_ = f.wakeR.Close()
+			_ = f.wakeW.Close()
+		}
+	}
+	f.mux.Unlock()
+	return f.File.Close()
+}
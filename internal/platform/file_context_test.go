@@ -0,0 +1,152 @@
+package platform
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewContextFile(t *testing.T) {
+	// Test using os.Pipe as reads on it block until data is available.
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rF := NewContextFile(ctx, NewFsFile(wazeroFile, syscall.O_RDONLY, r))
+	defer rF.Close()
+
+	// Data already available is read as usual.
+	expected := []byte("wazero")
+	_, err = w.Write(expected)
+	require.NoError(t, err)
+
+	buf := make([]byte, 10)
+	n, errno := rF.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, expected, buf[:n])
+
+	timeout := time.Duration(0)
+	ready, errno := rF.PollRead(&timeout)
+	if runtime.GOOS == "windows" {
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+		t.Skip("TODO: windows File.PollRead")
+	}
+	require.EqualErrno(t, 0, errno)
+	require.False(t, ready)
+
+	// A blocked read is interrupted by cancellation.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, errno = rF.Read(buf)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+
+	// Subsequent calls fail fast.
+	_, errno = rF.PollRead(nil)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+	_, errno = rF.Write(buf)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+}
+
+func TestNewContextFile_PollRead(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TODO: windows File.PollRead")
+	}
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rF := NewContextFile(ctx, NewFsFile(wazeroFile, syscall.O_RDONLY, r))
+	defer rF.Close()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, errno := rF.PollRead(nil) // would otherwise block forever
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+}
+
+func TestNewContextFile_noFd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	f := NewContextFile(ctx, NewFsFile(wazeroFile, syscall.O_RDONLY, embedFile(t)))
+	defer f.Close()
+
+	buf := make([]byte, 1)
+	_, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+
+	cancel()
+	_, errno = f.Read(buf)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+}
+
+func embedFile(t *testing.T) fs.File {
+	f, err := testdata.Open("testdata/" + wazeroFile)
+	require.NoError(t, err)
+	return f
+}
+
+func TestNewContextFile_Background(t *testing.T) {
+	f := NewFsFile(wazeroFile, syscall.O_RDONLY, embedFile(t))
+	defer f.Close()
+
+	// A context which can't be canceled doesn't need to be watched.
+	require.Equal(t, f, NewContextFile(context.Background(), f))
+}
+
+func TestNewContextFile_Pread(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	f := NewContextFile(ctx, NewFsFile(wazeroFile, syscall.O_RDONLY, embedFile(t)))
+	defer f.Close()
+
+	buf := make([]byte, 1)
+	_, errno := f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+
+	cancel()
+	_, errno = f.Pread(buf, 0)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+	_, errno = f.Pwrite(buf, 0)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+}
+
+// largeFdFile has a file descriptor which can't be passed to select.
+type largeFdFile struct{ NoopFile }
+
+func (largeFdFile) selectFd() (int, bool)                         { return fdSetSize, true }
+func (largeFdFile) Read([]byte) (int, syscall.Errno)              { return 0, 0 }
+func (largeFdFile) PollRead(*time.Duration) (bool, syscall.Errno) { return true, 0 }
+
+func TestNewContextFile_largeFd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	f := NewContextFile(ctx, largeFdFile{})
+
+	// The context is only checked before each call, instead of panicking.
+	_, errno := f.Read(nil)
+	require.EqualErrno(t, 0, errno)
+	ready, errno := f.PollRead(nil)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	cancel()
+	_, errno = f.Read(nil)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+}
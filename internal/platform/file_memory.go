@@ -0,0 +1,269 @@
+package platform
+
+import (
+	"io"
+	"math"
+	gosync "sync"
+	"syscall"
+	"time"
+)
+
+// NewMemoryFile returns a regular File whose contents are a copy of
+// `initial`, held in memory. This is for tests, such as of host functions,
+// which need a single file without a file system.
+//
+// `accessMode` is syscall.O_RDONLY, syscall.O_WRONLY or syscall.O_RDWR, and
+// is enforced like the host: for example, Write of a file opened with
+// syscall.O_RDONLY returns syscall.EBADF.
+//
+// # Notes
+//
+//   - Write, Writev, Pwrite and Truncate grow the file as needed, filling
+//     any gap with zeros. Growing past MaxMemoryFileSize returns
+//     syscall.EFBIG.
+//   - Stat returns a synthetic Stat_t, with the current size, the mode 0o600
+//     and times from the last change.
+//   - Functions without meaning for memory, such as Chown, return
+//     syscall.ENOSYS. See sysfs.NewMemFS for a file system of such files.
+func NewMemoryFile(initial []byte, accessMode int) File {
+	now := time.Now().UnixNano()
+	return &memoryFile{
+		accessMode: accessMode & (syscall.O_RDONLY | syscall.O_WRONLY | syscall.O_RDWR),
+		data:       append([]byte(nil), initial...),
+		mtim:       now,
+	}
+}
+
+// MaxMemoryFileSize is the largest size of a file held in memory, such as by
+// NewMemoryFile or sysfs.NewMemFS. Growing a file past it, for example with a
+// Pwrite at a huge offset, fails with syscall.EFBIG instead of exhausting
+// memory.
+const MaxMemoryFileSize = math.MaxInt32
+
+type memoryFile struct {
+	UnimplementedFile
+	accessMode int
+
+	// mux guards the below fields, so that the file is safe for concurrent
+	// use, like a host file.
+	mux    gosync.Mutex
+	data   []byte
+	offset int64
+	mtim   int64
+	closed bool
+}
+
+// Path implements File.Path
+func (f *memoryFile) Path() string {
+	return ""
+}
+
+// AccessMode implements File.AccessMode
+func (f *memoryFile) AccessMode() int {
+	return f.accessMode
+}
+
+// IsDir implements File.IsDir
+func (f *memoryFile) IsDir() (bool, syscall.Errno) {
+	return false, 0
+}
+
+// Stat implements File.Stat
+func (f *memoryFile) Stat() (Stat_t, syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.closed {
+		return Stat_t{}, syscall.EBADF
+	}
+	return Stat_t{
+		Mode:  0o600,
+		Nlink: 1,
+		Size:  int64(len(f.data)),
+		Atim:  f.mtim,
+		Mtim:  f.mtim,
+		Ctim:  f.mtim,
+	}, 0
+}
+
+// checkRead returns syscall.EBADF if the file is closed or not readable.
+func (f *memoryFile) checkRead() syscall.Errno {
+	if f.closed || f.accessMode == syscall.O_WRONLY {
+		return syscall.EBADF
+	}
+	return 0
+}
+
+// checkWrite returns syscall.EBADF if the file is closed or not writable.
+func (f *memoryFile) checkWrite() syscall.Errno {
+	if f.closed || f.accessMode == syscall.O_RDONLY {
+		return syscall.EBADF
+	}
+	return 0
+}
+
+// Read implements File.Read
+func (f *memoryFile) Read(p []byte) (int, syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	n, errno := f.pread(p, f.offset)
+	f.offset += int64(n)
+	return n, errno
+}
+
+// Pread implements File.Pread
+func (f *memoryFile) Pread(p []byte, off int64) (int, syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.pread(p, off)
+}
+
+func (f *memoryFile) pread(p []byte, off int64) (int, syscall.Errno) {
+	if errno := f.checkRead(); errno != 0 {
+		return 0, errno
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if off >= int64(len(f.data)) {
+		return 0, 0 // EOF
+	}
+	return copy(p, f.data[off:]), 0
+}
+
+// Seek implements File.Seek
+func (f *memoryFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// PollRead implements File.PollRead
+func (f *memoryFile) PollRead(*time.Duration) (bool, syscall.Errno) {
+	return true, 0 // memory is always readable
+}
+
+// Write implements File.Write
+func (f *memoryFile) Write(p []byte) (int, syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	n, errno := f.pwrite(p, f.offset)
+	f.offset += int64(n)
+	return n, errno
+}
+
+// Writev implements File.Writev
+func (f *memoryFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for _, buf := range bufs {
+		var written int
+		written, errno = f.pwrite(buf, f.offset)
+		f.offset += int64(written)
+		n += written
+		if errno != 0 {
+			return
+		}
+	}
+	return
+}
+
+// Pwrite implements File.Pwrite
+func (f *memoryFile) Pwrite(p []byte, off int64) (int, syscall.Errno) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.pwrite(p, off)
+}
+
+func (f *memoryFile) pwrite(p []byte, off int64) (int, syscall.Errno) {
+	if errno := f.checkWrite(); errno != 0 {
+		return 0, errno
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(p) == 0 {
+		return 0, 0
+	} else if off > MaxMemoryFileSize-int64(len(p)) {
+		return 0, syscall.EFBIG
+	}
+	if end := off + int64(len(p)); end > int64(len(f.data)) {
+		f.resize(end)
+	}
+	f.mtim = time.Now().UnixNano()
+	return copy(f.data[off:], p), 0
+}
+
+// Truncate implements File.Truncate
+func (f *memoryFile) Truncate(size int64) syscall.Errno {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if errno := f.checkWrite(); errno != 0 {
+		return errno
+	} else if size < 0 {
+		return syscall.EINVAL
+	} else if size > MaxMemoryFileSize {
+		return syscall.EFBIG
+	}
+	f.resize(size)
+	f.mtim = time.Now().UnixNano()
+	return 0
+}
+
+// resize changes the size of the data, zeroing any growth. The caller checks
+// `size` isn't over MaxMemoryFileSize.
+func (f *memoryFile) resize(size int64) {
+	if size <= int64(len(f.data)) {
+		f.data = f.data[:size]
+	} else if size <= int64(cap(f.data)) {
+		// Zero what a previous shrink left behind.
+		old := len(f.data)
+		f.data = f.data[:size]
+		for i := old; i < len(f.data); i++ {
+			f.data[i] = 0
+		}
+	} else {
+		f.data = append(f.data, make([]byte, size-int64(len(f.data)))...)
+	}
+}
+
+// Sync implements File.Sync
+func (f *memoryFile) Sync() syscall.Errno {
+	return 0 // memory has no storage to sync
+}
+
+// Datasync implements File.Datasync
+func (f *memoryFile) Datasync() syscall.Errno {
+	return 0 // memory has no storage to sync
+}
+
+// Close implements File.Close
+func (f *memoryFile) Close() syscall.Errno {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.closed {
+		return syscall.EBADF
+	}
+	f.closed = true
+	return 0
+}
@@ -0,0 +1,105 @@
+package platform
+
+import (
+	"io"
+	"math"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewMemoryFile(t *testing.T) {
+	initial := []byte("wazero")
+	f := NewMemoryFile(initial, syscall.O_RDWR)
+	require.Equal(t, syscall.O_RDWR, f.AccessMode())
+
+	// The initial contents are copied.
+	initial[0] = 'W'
+	buf := make([]byte, 4)
+	requireRead(t, f, buf)
+	require.Equal(t, "waze", string(buf))
+
+	// Writes at the offset overwrite, then grow the file.
+	n, errno := f.Write([]byte("RO!"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 3, n)
+	n, errno = f.Writev([][]byte{[]byte("a"), []byte("b")})
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 2, n)
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(9), st.Size)
+	require.Equal(t, uint64(1), st.Nlink)
+	require.True(t, st.Mode.IsRegular())
+
+	// Pwrite past the end leaves a hole of zeros.
+	_, errno = f.Pwrite([]byte("z"), 10)
+	require.EqualErrno(t, 0, errno)
+	buf = make([]byte, 20)
+	n, errno = f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazeRO!ab\x00z", string(buf[:n]))
+
+	// Shrinking then growing doesn't reveal old data.
+	require.EqualErrno(t, 0, f.Truncate(2))
+	require.EqualErrno(t, 0, f.Truncate(4))
+	n, errno = f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wa\x00\x00", string(buf[:n]))
+
+	off, errno := f.Seek(-1, io.SeekEnd)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(3), off)
+	_, errno = f.Seek(-1, io.SeekStart)
+	require.EqualErrno(t, syscall.EINVAL, errno)
+
+	// Reading at the end is EOF.
+	_, errno = f.Seek(0, io.SeekEnd)
+	require.EqualErrno(t, 0, errno)
+	n, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, syscall.EBADF, f.Close())
+	_, errno = f.Read(buf)
+	require.EqualErrno(t, syscall.EBADF, errno)
+	_, errno = f.Stat()
+	require.EqualErrno(t, syscall.EBADF, errno)
+}
+
+func TestNewMemoryFile_accessMode(t *testing.T) {
+	ro := NewMemoryFile([]byte("wazero"), syscall.O_RDONLY)
+	_, errno := ro.Write([]byte("a"))
+	require.EqualErrno(t, syscall.EBADF, errno)
+	_, errno = ro.Pwrite([]byte("a"), 0)
+	require.EqualErrno(t, syscall.EBADF, errno)
+	require.EqualErrno(t, syscall.EBADF, ro.Truncate(0))
+
+	wo := NewMemoryFile(nil, syscall.O_WRONLY)
+	_, errno = wo.Read(make([]byte, 1))
+	require.EqualErrno(t, syscall.EBADF, errno)
+	_, errno = wo.Pread(make([]byte, 1), 0)
+	require.EqualErrno(t, syscall.EBADF, errno)
+}
+
+func TestNewMemoryFile_hugeFile(t *testing.T) {
+	f := NewMemoryFile([]byte("wazero"), syscall.O_RDWR)
+
+	require.EqualErrno(t, syscall.EFBIG, f.Truncate(MaxMemoryFileSize+1))
+	_, errno := f.Pwrite([]byte{1}, MaxMemoryFileSize)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+	// The end would overflow int64.
+	_, errno = f.Pwrite([]byte{1}, math.MaxInt64)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+	_, errno = f.Seek(MaxMemoryFileSize, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte{1})
+	require.EqualErrno(t, syscall.EFBIG, errno)
+
+	// The file didn't grow.
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+}
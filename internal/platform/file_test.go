@@ -1,15 +1,19 @@
 package platform
 
 import (
+	"bytes"
 	"embed"
 	"io"
 	"io/fs"
 	"os"
 	"path"
 	"runtime"
+	gosync "sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	gofstest "testing/fstest"
+	"testing/iotest"
 	"time"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -65,6 +69,71 @@ func TestFsFileSetNonblock(t *testing.T) {
 	require.False(t, rF.IsNonblock())
 }
 
+func TestFsFileNonblock_EAGAIN(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TODO: windows File.SetNonblock")
+	}
+
+	// Test using os.Pipe as it is known to support non-blocking reads.
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	rF := NewFsFile(wazeroFile, syscall.O_RDONLY, r)
+	require.EqualErrno(t, 0, rF.SetNonblock(true))
+	wF := NewFsFile(wazeroFile, syscall.O_WRONLY, w)
+	require.EqualErrno(t, 0, wF.SetNonblock(true))
+
+	t.Run("Read drains then returns EAGAIN", func(t *testing.T) {
+		n, errno := wF.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+
+		buf := make([]byte, 4)
+		n, errno = rF.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "waze", string(buf[:n]))
+		n, errno = rF.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "ro", string(buf[:n]))
+
+		// The pipe is empty, so the guest must retry.
+		n, errno = rF.Read(buf)
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+		require.Zero(t, n)
+
+		// Retrying succeeds once there's data.
+		_, errno = wF.Write([]byte("!"))
+		require.EqualErrno(t, 0, errno)
+		n, errno = rF.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "!", string(buf[:n]))
+	})
+
+	t.Run("Write fills then returns EAGAIN", func(t *testing.T) {
+		// Write more than a pipe buffers, which is 64KiB on Linux.
+		buf := make([]byte, 1<<20)
+		n, errno := wF.Write(buf)
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+		require.True(t, n < len(buf))
+
+		// The pipe is full, so nothing more can be written.
+		written, errno := wF.Write(buf)
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+		require.Zero(t, written)
+
+		// Drain what was written, then the pipe is empty again.
+		for read := 0; read < n; {
+			r, errno := rF.Read(buf)
+			require.EqualErrno(t, 0, errno)
+			read += r
+		}
+		_, errno = rF.Read(buf)
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+	})
+}
+
 func TestFsFileIsDir(t *testing.T) {
 	dirFS, embedFS, mapFS := dirEmbedMapFS(t, t.TempDir())
 
@@ -110,6 +179,51 @@ func TestFsFileIsDir(t *testing.T) {
 	}
 }
 
+func TestNewFsFileWithInfo(t *testing.T) {
+	p := path.Join(t.TempDir(), wazeroFile)
+	require.NoError(t, os.WriteFile(p, []byte("wazero"), 0o600))
+	info, err := os.Stat(p)
+	require.NoError(t, err)
+
+	of, errno := OpenFile(p, os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	sf := &statCountFile{File: of}
+	f := NewFsFileWithInfo(p, os.O_RDWR, sf, info)
+	defer f.Close()
+
+	// Neither IsDir nor the first Stat needs to stat the file.
+	isDir, errno := f.IsDir()
+	require.EqualErrno(t, 0, errno)
+	require.False(t, isDir)
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+	require.Zero(t, sf.count)
+
+	// A write invalidates the info, so Stat sees the new size.
+	f = NewFsFileWithInfo(p, os.O_RDWR, sf, info)
+	requirePwrite(t, f, []byte("wazero"), 6)
+	st, errno = f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(12), st.Size)
+	require.Equal(t, 1, sf.count)
+}
+
+// statCountFile counts calls to fs.File.Stat.
+type statCountFile struct {
+	fs.File
+	count int
+}
+
+func (f *statCountFile) Stat() (fs.FileInfo, error) {
+	f.count++
+	return f.File.Stat()
+}
+
+func (f *statCountFile) WriteAt(p []byte, off int64) (int, error) {
+	return f.File.(io.WriterAt).WriteAt(p, off)
+}
+
 func TestFsFileReadAndPread(t *testing.T) {
 	dirFS, embedFS, mapFS := dirEmbedMapFS(t, t.TempDir())
 
@@ -245,6 +359,91 @@ func TestFsFileRead_empty(t *testing.T) {
 	}
 }
 
+func TestFsFileRead_EOF(t *testing.T) {
+	_, embedFS, mapFS := dirEmbedMapFS(t, t.TempDir())
+
+	tests := []struct {
+		name string
+		r    func(io.Reader) io.Reader
+	}{
+		{name: "EOF after data", r: func(r io.Reader) io.Reader { return r }},
+		{name: "EOF with data", r: iotest.DataErrReader},
+		{name: "one byte", r: iotest.OneByteReader},
+		{name: "half", r: iotest.HalfReader},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := mapFS.Open(wazeroFile)
+			require.NoError(t, err)
+			defer f.Close()
+
+			fs := NewFsFile(wazeroFile, syscall.O_RDONLY, &readerFile{File: f, r: tc.r(f)})
+			require.Equal(t, "wazero\n", string(readAll(t, fs)))
+
+			// Subsequent reads consistently return zero.
+			n, errno := fs.Read(make([]byte, 3))
+			require.EqualErrno(t, 0, errno)
+			require.Zero(t, n)
+		})
+	}
+
+	t.Run("error after data", func(t *testing.T) {
+		f, err := mapFS.Open(wazeroFile)
+		require.NoError(t, err)
+		defer f.Close()
+
+		fs := NewFsFile(wazeroFile, syscall.O_RDONLY, &readerFile{File: f, r: iotest.TimeoutReader(f)})
+		buf := make([]byte, 3)
+		requireRead(t, fs, buf)
+		require.Equal(t, "waz", string(buf))
+		_, errno := fs.Read(buf)
+		require.EqualErrno(t, syscall.EIO, errno)
+	})
+
+	t.Run("Pread past EOF", func(t *testing.T) {
+		f, err := embedFS.Open(wazeroFile)
+		require.NoError(t, err)
+		defer f.Close()
+
+		// embed.FS returns io.EOF with the data when the read is short.
+		fs := NewFsFile(wazeroFile, syscall.O_RDONLY, f)
+		buf := make([]byte, 10)
+		n, errno := fs.Pread(buf, 4)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "ro\n", string(buf[:n]))
+
+		n, errno = fs.Pread(buf, 7)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+	})
+}
+
+// readerFile overrides the Read method of an fs.File.
+type readerFile struct {
+	fs.File
+	r io.Reader
+}
+
+func (f *readerFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+func readAll(t *testing.T, f File) []byte {
+	var b []byte
+	buf := make([]byte, 4)
+	for {
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		if n == 0 {
+			return b
+		}
+		b = append(b, buf[:n]...)
+	}
+}
+
 func TestFsFilePread_Unsupported(t *testing.T) {
 	embedFS, err := fs.Sub(testdata, "testdata")
 	require.NoError(t, err)
@@ -329,7 +528,7 @@ func TestFsFileSeek(t *testing.T) {
 			fs := NewFsFile(wazeroFile, syscall.O_RDONLY, f)
 
 			// Shouldn't be able to use an invalid whence
-			_, errno := fs.Seek(0, io.SeekEnd+1)
+			_, errno := fs.Seek(0, SeekData+1)
 			require.EqualErrno(t, syscall.EINVAL, errno)
 			_, errno = fs.Seek(0, -1)
 			require.EqualErrno(t, syscall.EINVAL, errno)
@@ -436,6 +635,40 @@ func TestFsFileSeek_Unsupported(t *testing.T) {
 	require.EqualErrno(t, syscall.ENOSYS, errno)
 }
 
+// TestFsFile_LargeOffset ensures offsets past 4GiB aren't truncated, notably
+// on 32-bit hosts.
+func TestFsFile_LargeOffset(t *testing.T) {
+	const off = int64(5 << 30) // past the 32-bit limit
+
+	f := openFsFile(t, path.Join(t.TempDir(), "large"), syscall.O_RDWR|os.O_CREATE, 0o600)
+	defer f.Close()
+
+	// Only continue where the file can be sparse, to avoid writing 5GiB.
+	if errno := f.Truncate(off); errno != 0 {
+		t.Skipf("cannot truncate to %d: %v", off, errno)
+	} else if hole, errno := f.Seek(0, SeekHole); errno != 0 || hole == off {
+		t.Skip("filesystem doesn't support sparse files")
+	}
+
+	requirePwrite(t, f, []byte("wazero"), off)
+
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, off+6, st.Size)
+
+	buf := make([]byte, 6)
+	requirePread(t, f, buf, off)
+	require.Equal(t, "wazero", string(buf))
+
+	require.Equal(t, off+2, requireSeek(t, f, off+2, io.SeekStart))
+	requireWrite(t, f, []byte("ZE"))
+	require.Equal(t, off+4, requireSeek(t, f, 0, io.SeekCurrent))
+
+	require.Equal(t, off, requireSeek(t, f, -6, io.SeekEnd))
+	requireRead(t, f, buf)
+	require.Equal(t, "waZEro", string(buf))
+}
+
 func TestFsFileWriteAndPwrite(t *testing.T) {
 	// fs.FS doesn't support writes, and there is no other built-in
 	// implementation except os.File.
@@ -563,6 +796,316 @@ func TestFsFileWrite_Unsupported(t *testing.T) {
 	}
 }
 
+func TestFsFileWrite_append(t *testing.T) {
+	const goroutines, writes = 8, 100
+	record := []byte("0123456789\n")
+
+	t.Run("os.File without O_APPEND", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(p, []byte("wazero\n"), 0o600))
+
+		// The descriptor lacks O_APPEND, but the file was opened with it.
+		of, err := os.OpenFile(p, os.O_RDWR, 0)
+		require.NoError(t, err)
+		f := NewFsFile(p, os.O_RDWR|os.O_APPEND, of)
+		defer f.Close()
+
+		_, errno := f.Write(record)
+		require.EqualErrno(t, 0, errno)
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+		require.Equal(t, "wazero\n"+string(record), string(b))
+	})
+
+	t.Run("os.File concurrent", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(p, nil, 0o600))
+
+		// Each goroutine has its own file, as the host enforces O_APPEND.
+		requireConcurrentWrites(t, goroutines, writes, record, func() File {
+			of, errno := OpenFile(p, os.O_WRONLY|os.O_APPEND, 0)
+			require.EqualErrno(t, 0, errno)
+			return NewFsFile(p, os.O_WRONLY|os.O_APPEND, of)
+		})
+
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+		requireRecords(t, b, record, goroutines*writes)
+	})
+
+	t.Run("fs.File concurrent", func(t *testing.T) {
+		mf := &memFile{data: []byte("wazero\n")}
+		f := NewFsFile(wazeroFile, os.O_WRONLY|os.O_APPEND, mf)
+		requireConcurrentWrites(t, goroutines, writes, record, func() File { return f })
+
+		require.Equal(t, "wazero\n", string(mf.data[:7]))
+		requireRecords(t, mf.data[7:], record, goroutines*writes)
+	})
+}
+
+func TestFsFileWritev(t *testing.T) {
+	const goroutines, writes = 8, 100
+	bufs := [][]byte{[]byte("01234"), nil, []byte("56789"), []byte("\n")}
+	record := []byte("0123456789\n")
+
+	t.Run("os.File", func(t *testing.T) {
+		p := path.Join(t.TempDir(), wazeroFile)
+		f := openFsFile(t, p, syscall.O_RDWR|os.O_CREATE, 0o600)
+		defer f.Close()
+
+		n, errno := f.Writev(bufs)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, len(record), n)
+
+		// The file offset advanced, so the next write is after the first.
+		requireWrite(t, f, []byte("wazero"))
+
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+		require.Equal(t, string(record)+"wazero", string(b))
+	})
+
+	t.Run("os.File empty", func(t *testing.T) {
+		p := path.Join(t.TempDir(), emptyFile)
+		f := openFsFile(t, p, syscall.O_RDWR|os.O_CREATE, 0o600)
+		defer f.Close()
+
+		n, errno := f.Writev(nil)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+	})
+
+	t.Run("pipe concurrent", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer r.Close()
+
+		f, err := NewStdioFile(false, w)
+		require.NoError(t, err)
+		read := make(chan []byte)
+		go func() {
+			b, _ := io.ReadAll(r)
+			read <- b
+		}()
+		requireConcurrentWritevs(t, goroutines, writes, bufs, func() File { return f })
+		require.NoError(t, w.Close()) // stdio files don't close the host file.
+
+		requireRecords(t, <-read, record, goroutines*writes)
+	})
+
+	t.Run("fs.File concurrent", func(t *testing.T) {
+		// Without a file descriptor, each buffer is written under a lock.
+		mf := &memFile{}
+		f := NewFsFile(wazeroFile, os.O_WRONLY, mf)
+		requireConcurrentWritevs(t, goroutines, writes, bufs, func() File { return f })
+
+		requireRecords(t, mf.data, record, goroutines*writes)
+	})
+}
+
+func TestFsFileWritev_large(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+
+	// Larger than a pipe buffer, so writev may return a short count.
+	bufs := [][]byte{make([]byte, 200<<10), make([]byte, 300<<10), []byte("wazero")}
+	f, err := NewStdioFile(false, w)
+	require.NoError(t, err)
+	read := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		read <- b
+	}()
+
+	n, errno := f.Writev(bufs)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 500<<10+6, n)
+	require.NoError(t, w.Close())
+
+	b := <-read
+	require.Equal(t, 500<<10+6, len(b))
+	require.Equal(t, "wazero", string(b[500<<10:]))
+}
+
+func TestSkipWritten(t *testing.T) {
+	bufs := [][]byte{nil, []byte("abc"), []byte("de"), nil, []byte("f")}
+
+	require.Equal(t, [][]byte{[]byte("abc"), []byte("de"), nil, []byte("f")}, skipWritten(bufs, 0))
+	require.Equal(t, [][]byte{[]byte("c"), []byte("de"), nil, []byte("f")}, skipWritten(bufs, 2))
+	require.Equal(t, [][]byte{[]byte("f")}, skipWritten(bufs, 5))
+	require.Equal(t, 0, len(skipWritten(bufs, 6)))
+
+	// The input isn't modified.
+	require.Equal(t, "abc", string(bufs[1]))
+}
+
+// requireConcurrentWritevs is like requireConcurrentWrites, except each write
+// is a File.Writev of `bufs`.
+func requireConcurrentWritevs(t *testing.T, goroutines, writes int, bufs [][]byte, open func() File) {
+	var wg gosync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		f := open()
+		// Cache the file type before concurrent use, as that isn't locked.
+		_, errno := f.IsDir()
+		require.EqualErrno(t, 0, errno)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				if _, errno := f.Writev(bufs); errno != 0 {
+					t.Error(errno)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func requireConcurrentWrites(t *testing.T, goroutines, writes int, record []byte, open func() File) {
+	var wg gosync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		f := open()
+		// Cache the file type before concurrent use, as that isn't locked.
+		_, errno := f.IsDir()
+		require.EqualErrno(t, 0, errno)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				if _, errno := f.Write(record); errno != 0 {
+					t.Error(errno)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func requireRecords(t *testing.T, b, record []byte, count int) {
+	require.Equal(t, count*len(record), len(b))
+	for i := 0; i < count; i++ {
+		require.Equal(t, record, b[i*len(record):(i+1)*len(record)])
+	}
+}
+
+// memFile is a writable fs.File which isn't safe for concurrent use.
+type memFile struct {
+	fs.File
+	data []byte
+	off  int64
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return gofstest.MapFS{"f": {Data: f.data}}.Stat("f")
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if end := f.off + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	n := copy(f.data[f.off:], p)
+	f.off += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+func TestFsFileWrite_ShortWrites(t *testing.T) {
+	t.Run("writes all", func(t *testing.T) {
+		sw := &shortWriteFile{max: 2}
+		f := NewFsFile(wazeroFile, os.O_WRONLY, sw)
+
+		n, errno := f.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+		require.Equal(t, "wazero", string(sw.data))
+		require.Equal(t, 3, sw.calls)
+	})
+
+	t.Run("O_APPEND writes all", func(t *testing.T) {
+		sw := &shortWriteFile{max: 4, memFile: memFile{data: []byte("hello ")}}
+		f := NewFsFile(wazeroFile, os.O_WRONLY|os.O_APPEND, sw)
+
+		n, errno := f.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+		require.Equal(t, "hello wazero", string(sw.data))
+	})
+
+	t.Run("EAGAIN returns partial count", func(t *testing.T) {
+		sw := &shortWriteFile{max: 4, capacity: 4}
+		f := NewFsFile(wazeroFile, os.O_WRONLY, sw)
+
+		n, errno := f.Write([]byte("wazero"))
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+		require.Equal(t, 4, n)
+		require.Equal(t, "waze", string(sw.data))
+	})
+
+	t.Run("no progress", func(t *testing.T) {
+		sw := &shortWriteFile{max: 0}
+		f := NewFsFile(wazeroFile, os.O_WRONLY, sw)
+
+		n, errno := f.Write([]byte("wazero"))
+		require.EqualErrno(t, syscall.ENOSPC, errno)
+		require.Zero(t, n)
+		require.Equal(t, 2, sw.calls) // retried once
+	})
+
+	t.Run("ENOSPC mid-buffer", func(t *testing.T) {
+		sw := &shortWriteFile{max: 4, quota: 5}
+		f := NewFsFile(wazeroFile, os.O_WRONLY, sw)
+
+		n, errno := f.Write([]byte("wazero"))
+		require.EqualErrno(t, syscall.ENOSPC, errno)
+		require.Equal(t, 5, n)
+		require.Equal(t, "wazer", string(sw.data))
+	})
+}
+
+// shortWriteFile is a memFile which writes at most max bytes per call, like a
+// pipe. When capacity is non-zero, writes beyond it fail with EAGAIN, like a
+// full non-blocking pipe. When quota is non-zero, writes beyond it are short
+// without an error, like a full disk.
+type shortWriteFile struct {
+	memFile
+	max, capacity, quota, calls int
+}
+
+func (f *shortWriteFile) Write(p []byte) (int, error) {
+	f.calls++
+	if f.quota != 0 && len(p) > f.quota-len(f.data) {
+		p = p[:f.quota-len(f.data)]
+	}
+	if f.capacity != 0 {
+		if avail := f.capacity - len(f.data); avail <= 0 {
+			return 0, syscall.EAGAIN
+		} else if len(p) > avail {
+			p = p[:avail]
+		}
+	}
+	if len(p) > f.max {
+		p = p[:f.max]
+	}
+	return f.memFile.Write(p)
+}
+
 func TestFsFileWrite_Errors(t *testing.T) {
 	// Create the file
 	path := path.Join(t.TempDir(), emptyFile)
@@ -588,6 +1131,10 @@ func TestFsFileWrite_Errors(t *testing.T) {
 			_, errno := f.Pwrite(buf, 0)
 			return errno
 		}},
+		{name: "Writev", fn: func(f File) syscall.Errno {
+			_, errno := f.Writev([][]byte{buf})
+			return errno
+		}},
 	}
 
 	for _, tc := range tests {
@@ -775,6 +1322,57 @@ func TestFsFileTruncate(t *testing.T) {
 	})
 }
 
+func TestFsFilePunchHole(t *testing.T) {
+	const blockSize = 64 * 1024 // larger than the block size of common filesystems
+	content := bytes.Repeat([]byte{'a'}, 3*blockSize)
+
+	t.Run("zeros the range", func(t *testing.T) {
+		f := openForWrite(t, path.Join(t.TempDir(), "punch"), content)
+		defer f.Close()
+
+		errno := f.PunchHole(blockSize, blockSize)
+		if errno == syscall.ENOSYS {
+			t.Skip("unsupported by the platform or filesystem")
+		}
+		require.EqualErrno(t, 0, errno)
+
+		actual, err := os.ReadFile(f.Path())
+		require.NoError(t, err)
+		expected := append([]byte(nil), content...)
+		copy(expected[blockSize:], make([]byte, blockSize))
+		require.Equal(t, expected, actual)
+	})
+
+	punchHole := func(f File) syscall.Errno {
+		return f.PunchHole(0, 1)
+	}
+
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		testEBADFIfFileClosed(t, punchHole)
+	}
+
+	testEISDIR(t, punchHole)
+
+	t.Run("read-only", func(t *testing.T) {
+		tmpPath := path.Join(t.TempDir(), "ro")
+		require.NoError(t, os.WriteFile(tmpPath, content, 0o600))
+		f, errno := OpenFile(tmpPath, syscall.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		file := NewFsFile(tmpPath, syscall.O_RDONLY, f)
+		defer file.Close()
+
+		require.EqualErrno(t, syscall.EBADF, file.PunchHole(0, 1))
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		f := openForWrite(t, path.Join(t.TempDir(), "punch"), content)
+		defer f.Close()
+
+		require.EqualErrno(t, syscall.EINVAL, f.PunchHole(-1, 1))
+		require.EqualErrno(t, syscall.EINVAL, f.PunchHole(0, 0))
+	})
+}
+
 func TestFsFileUtimens(t *testing.T) {
 	switch runtime.GOOS {
 	case "linux", "darwin": // supported
@@ -797,6 +1395,190 @@ func TestFsFileUtimens(t *testing.T) {
 	})
 }
 
+func TestFsFileDup(t *testing.T) {
+	path := path.Join(t.TempDir(), "dup")
+	require.NoError(t, os.WriteFile(path, []byte("wazero"), 0o600))
+	f := openFsFile(t, path, os.O_RDWR, 0)
+
+	dup, errno := f.Dup()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, f.Path(), dup.Path())
+	require.Equal(t, f.AccessMode(), dup.AccessMode())
+
+	// The file offset is shared.
+	requireRead(t, f, make([]byte, 2))
+	buf := make([]byte, 2)
+	requireRead(t, dup, buf)
+	require.Equal(t, "ze", string(buf))
+
+	// Closing one doesn't close the other.
+	require.EqualErrno(t, 0, f.Close())
+	requireRead(t, dup, buf)
+	require.Equal(t, "ro", string(buf))
+	require.EqualErrno(t, 0, dup.Close())
+
+	testEBADFIfFileClosed(t, func(f File) syscall.Errno {
+		_, errno := f.Dup()
+		return errno
+	})
+}
+
+// closeCountingFile counts calls to Close, and isn't an *os.File, so Dup
+// shares it instead of duplicating it on the host.
+type closeCountingFile struct {
+	fs.File
+	closes int32
+}
+
+func (f *closeCountingFile) Close() error {
+	atomic.AddInt32(&f.closes, 1)
+	return f.File.Close()
+}
+
+func TestFsFile_Close(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+	open := func(t *testing.T) (File, *closeCountingFile) {
+		osf, err := os.Open(path.Join(tmpDir, "file"))
+		require.NoError(t, err)
+		cf := &closeCountingFile{File: osf}
+		return NewFsFile("file", syscall.O_RDONLY, cf), cf
+	}
+
+	t.Run("close twice", func(t *testing.T) {
+		f, cf := open(t)
+		require.EqualErrno(t, 0, f.Close())
+		require.EqualErrno(t, syscall.EBADF, f.Close())
+		require.Equal(t, int32(1), cf.closes)
+	})
+
+	t.Run("shared by dup", func(t *testing.T) {
+		f, cf := open(t)
+		dup, errno := f.Dup()
+		require.EqualErrno(t, 0, errno)
+
+		// Closing one leaves the other usable.
+		require.EqualErrno(t, 0, f.Close())
+		require.Zero(t, cf.closes)
+		_, errno = f.Read(make([]byte, 1))
+		require.EqualErrno(t, syscall.EBADF, errno)
+		requireRead(t, dup, make([]byte, 1))
+
+		// Closing a closed handle doesn't release the other's reference.
+		require.EqualErrno(t, syscall.EBADF, f.Close())
+		require.Zero(t, cf.closes)
+		require.EqualErrno(t, 0, dup.Close())
+		require.Equal(t, int32(1), cf.closes)
+
+		_, errno = dup.Dup()
+		require.EqualErrno(t, syscall.EBADF, errno)
+	})
+
+	t.Run("concurrent close of dups", func(t *testing.T) {
+		f, cf := open(t)
+		files := []File{f}
+		for i := 0; i < 100; i++ {
+			dup, errno := f.Dup()
+			require.EqualErrno(t, 0, errno)
+			files = append(files, dup)
+		}
+
+		errnos := make([]syscall.Errno, len(files))
+		var wg gosync.WaitGroup
+		for i := range files {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errnos[i] = files[i].Close()
+			}(i)
+		}
+		wg.Wait()
+
+		for _, errno := range errnos {
+			require.EqualErrno(t, 0, errno)
+		}
+		require.Equal(t, int32(1), cf.closes)
+	})
+
+	t.Run("concurrent close of the same file", func(t *testing.T) {
+		f, cf := open(t)
+		var closed, ebadf int32
+		var wg gosync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				switch f.Close() {
+				case 0:
+					atomic.AddInt32(&closed, 1)
+				case syscall.EBADF:
+					atomic.AddInt32(&ebadf, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		require.Equal(t, int32(1), closed)
+		require.Equal(t, int32(99), ebadf)
+		require.Equal(t, int32(1), cf.closes)
+	})
+}
+
+func TestFsFile_ESPIPE(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	rf := NewFsFile("pipe", syscall.O_RDONLY, r)
+	defer rf.Close()
+	wf := NewFsFile("pipe", syscall.O_WRONLY, w)
+	defer wf.Close()
+
+	// A pipe has no offset, so these fail the same way on all platforms.
+	_, errno := rf.Seek(0, io.SeekStart)
+	require.EqualErrno(t, syscall.ESPIPE, errno)
+	_, errno = rf.Pread(make([]byte, 1), 0)
+	require.EqualErrno(t, syscall.ESPIPE, errno)
+	_, errno = wf.Pwrite([]byte("wazero"), 0)
+	require.EqualErrno(t, syscall.ESPIPE, errno)
+
+	// Sequential I/O still works.
+	_, errno = wf.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	buf := make([]byte, 6)
+	requireRead(t, rf, buf)
+	require.Equal(t, "wazero", string(buf))
+}
+
+func TestFsFile_independentOpens(t *testing.T) {
+	path := path.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("wazero"), 0o600))
+	f1 := openFsFile(t, path, os.O_RDWR, 0)
+	defer f1.Close()
+	f2 := openFsFile(t, path, os.O_RDWR, 0)
+	defer f2.Close()
+
+	// Each open has its own offset, so a seek doesn't affect the other.
+	_, errno := f1.Seek(4, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	buf := make([]byte, 2)
+	requireRead(t, f2, buf)
+	require.Equal(t, "wa", string(buf))
+
+	// They refer to the same file, so a write through one is visible to
+	// reads through the other.
+	n, errno := f1.Write([]byte("ZZ"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 2, n)
+	requireRead(t, f2, make([]byte, 2))
+	requireRead(t, f2, buf)
+	require.Equal(t, "ZZ", string(buf))
+
+	st1, errno := f1.Stat()
+	require.EqualErrno(t, 0, errno)
+	st2, errno := f2.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st1.Ino, st2.Ino)
+}
+
 func TestNewStdioFile(t *testing.T) {
 	// simulate regular file attached to stdin
 	f, err := os.CreateTemp(t.TempDir(), "somefile")
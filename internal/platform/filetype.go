@@ -0,0 +1,82 @@
+package platform
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// FileType is the type of a file, classified from the type bits of an
+// fs.FileMode, such as Dirent.Type or Stat_t.Mode.
+type FileType uint8
+
+const (
+	// FileTypeUnknown is a file of a type not listed below, such as one with
+	// fs.ModeIrregular or an invalid combination of type bits.
+	FileTypeUnknown FileType = iota
+	// FileTypeRegular is a regular file: one without any type bits.
+	FileTypeRegular
+	// FileTypeDirectory is a file with fs.ModeDir.
+	FileTypeDirectory
+	// FileTypeSymlink is a file with fs.ModeSymlink.
+	FileTypeSymlink
+	// FileTypeBlockDevice is a file with fs.ModeDevice, but not
+	// fs.ModeCharDevice.
+	FileTypeBlockDevice
+	// FileTypeCharDevice is a file with fs.ModeCharDevice, which is
+	// typically combined with fs.ModeDevice.
+	FileTypeCharDevice
+	// FileTypeSocket is a file with fs.ModeSocket.
+	FileTypeSocket
+	// FileTypeNamedPipe is a file with fs.ModeNamedPipe, also known as a FIFO.
+	FileTypeNamedPipe
+)
+
+// String implements fmt.Stringer
+func (t FileType) String() string {
+	switch t {
+	case FileTypeUnknown:
+		return "unknown"
+	case FileTypeRegular:
+		return "regular"
+	case FileTypeDirectory:
+		return "directory"
+	case FileTypeSymlink:
+		return "symlink"
+	case FileTypeBlockDevice:
+		return "block device"
+	case FileTypeCharDevice:
+		return "char device"
+	case FileTypeSocket:
+		return "socket"
+	case FileTypeNamedPipe:
+		return "named pipe"
+	}
+	return fmt.Sprintf("FileType(%d)", t)
+}
+
+// FileTypeOf returns the type of a file with the given mode. Permission bits
+// are ignored.
+//
+// Note: Unlike the type bits in fs.FileMode, a character device is not also a
+// block device, as the FileType constants are mutually exclusive.
+func FileTypeOf(mode fs.FileMode) FileType {
+	// Match the type bits exactly, as checking one bit at a time is prone to
+	// misclassifying a character device as a block device.
+	switch mode.Type() {
+	case 0:
+		return FileTypeRegular
+	case fs.ModeDir:
+		return FileTypeDirectory
+	case fs.ModeSymlink:
+		return FileTypeSymlink
+	case fs.ModeDevice:
+		return FileTypeBlockDevice
+	case fs.ModeDevice | fs.ModeCharDevice, fs.ModeCharDevice:
+		return FileTypeCharDevice
+	case fs.ModeSocket:
+		return FileTypeSocket
+	case fs.ModeNamedPipe:
+		return FileTypeNamedPipe
+	}
+	return FileTypeUnknown
+}
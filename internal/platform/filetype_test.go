@@ -0,0 +1,48 @@
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFileTypeOf(t *testing.T) {
+	tests := []struct {
+		mode     fs.FileMode
+		expected FileType
+	}{
+		{mode: 0o644, expected: FileTypeRegular},
+		{mode: fs.ModeDir | 0o755, expected: FileTypeDirectory},
+		{mode: fs.ModeSymlink | 0o777, expected: FileTypeSymlink},
+		{mode: fs.ModeDevice | 0o660, expected: FileTypeBlockDevice},
+		{mode: fs.ModeDevice | fs.ModeCharDevice | 0o666, expected: FileTypeCharDevice},
+		{mode: fs.ModeCharDevice, expected: FileTypeCharDevice},
+		{mode: fs.ModeSocket | 0o755, expected: FileTypeSocket},
+		{mode: fs.ModeNamedPipe | 0o600, expected: FileTypeNamedPipe},
+		{mode: fs.ModeIrregular, expected: FileTypeUnknown},
+		{mode: fs.ModeDir | fs.ModeSymlink, expected: FileTypeUnknown},
+		// Non-type bits don't affect the type.
+		{mode: fs.ModeSetuid | fs.ModeSticky | 0o755, expected: FileTypeRegular},
+		{mode: fs.ModeDir | fs.ModeSetgid | fs.ModeSticky, expected: FileTypeDirectory},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			require.Equal(t, tc.expected, FileTypeOf(tc.mode))
+		})
+	}
+}
+
+func TestFileTypeOf_DevNull(t *testing.T) {
+	st, err := os.Stat(os.DevNull)
+	require.NoError(t, err)
+	require.Equal(t, FileTypeCharDevice, FileTypeOf(st.Mode()))
+}
+
+func TestFileType_String(t *testing.T) {
+	require.Equal(t, "char device", FileTypeCharDevice.String())
+	require.Equal(t, "FileType(100)", FileType(100).String())
+}
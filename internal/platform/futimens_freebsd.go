@@ -0,0 +1,42 @@
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_AT_FDCWD               = -0x64
+	_AT_SYMLINK_NOFOLLOW    = 0x200
+	_UTIME_NOW              = -1
+	_UTIME_OMIT             = -2
+	SupportsSymlinkNoFollow = true
+)
+
+func utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) error {
+	var flags int
+	if !symlinkFollow {
+		flags = _AT_SYMLINK_NOFOLLOW
+	}
+
+	_p0, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return utimensat(_AT_FDCWD, _p0, times, flags)
+}
+
+func futimens(fd uintptr, times *[2]syscall.Timespec) error {
+	// FreeBSD's utimensat doesn't accept a NUL path, as Linux does, and the
+	// syscall package doesn't define futimens on all architectures.
+	return syscall.ENOSYS
+}
+
+func utimensat(dirfd int, path *byte, times *[2]syscall.Timespec, flags int) error {
+	_, _, e1 := syscall.Syscall6(syscall.SYS_UTIMENSAT, uintptr(dirfd), uintptr(unsafe.Pointer(path)),
+		uintptr(unsafe.Pointer(times)), uintptr(flags), 0, 0)
+	if e1 != 0 {
+		return e1
+	}
+	return nil
+}
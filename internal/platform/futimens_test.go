@@ -26,6 +26,80 @@ func TestUtimens(t *testing.T) {
 	testUtimens(t, false)
 }
 
+// TestUtimens_omit ensures UTIME_OMIT retains the exact prior value when the
+// other timestamp changes, including on directories.
+func TestUtimens_omit(t *testing.T) {
+	if !CompilerSupported() {
+		t.Skip("atim isn't readable")
+	}
+
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(file, []byte{}, 0o600))
+	dir := path.Join(tmpDir, "dir")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+
+	initial := &[2]syscall.Timespec{{Sec: 123, Nsec: 4 * 1e3}, {Sec: 223, Nsec: 5 * 1e3}}
+	for _, path := range []string{file, dir} {
+		require.EqualErrno(t, 0, Utimens(path, initial, true))
+
+		errno := Utimens(path, &[2]syscall.Timespec{{Nsec: UTIME_OMIT}, {Sec: 323}}, true)
+		require.EqualErrno(t, 0, errno)
+
+		st, errno := Stat(path)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, initial[0].Nano(), st.Atim)
+		require.Equal(t, int64(323*1e9), st.Mtim)
+
+		errno = Utimens(path, &[2]syscall.Timespec{{Sec: 423}, {Nsec: UTIME_OMIT}}, true)
+		require.EqualErrno(t, 0, errno)
+
+		st, errno = Stat(path)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(423*1e9), st.Atim)
+		require.Equal(t, int64(323*1e9), st.Mtim)
+	}
+}
+
+// TestUtimens_symlinkNoFollow ensures times are set on the symbolic link
+// itself, not its target, unless following.
+func TestUtimens_symlinkNoFollow(t *testing.T) {
+	if !SupportsSymlinkNoFollow {
+		t.Skip("symlinkFollow=false isn't supported")
+	}
+
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(file, []byte{}, 0o600))
+	link := path.Join(tmpDir, "link")
+	if err := os.Symlink(file, link); err != nil {
+		t.Skip("symbolic links aren't supported", err)
+	}
+
+	initial := &[2]syscall.Timespec{{Sec: 123}, {Sec: 223}}
+	require.EqualErrno(t, 0, Utimens(file, initial, true))
+
+	linkTimes := &[2]syscall.Timespec{{Sec: 323}, {Sec: 423}}
+	require.EqualErrno(t, 0, Utimens(link, linkTimes, false))
+
+	st, errno := Lstat(link)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(423*1e9), st.Mtim)
+	st, errno = Stat(file)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(223*1e9), st.Mtim)
+
+	// Following the link changes the target instead.
+	require.EqualErrno(t, 0, Utimens(link, &[2]syscall.Timespec{{Sec: 523}, {Sec: 623}}, true))
+
+	st, errno = Lstat(link)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(423*1e9), st.Mtim)
+	st, errno = Stat(file)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(623*1e9), st.Mtim)
+}
+
 func testUtimens(t *testing.T, futimes bool) {
 	// Note: This sets microsecond granularity because Windows doesn't support
 	// nanosecond.
@@ -161,16 +235,18 @@ func testUtimens(t *testing.T, futimes bool) {
 					flag := syscall.O_RDWR
 					if path == dir {
 						flag = syscall.O_RDONLY
-						if runtime.GOOS == "windows" {
-							// windows requires O_RDWR, which is invalid for directories
-							t.Skip("windows cannot update timestamps on a dir")
-						}
 					}
 
 					f := openFsFile(t, path, flag, 0)
 
 					errno = f.Utimens(tc.times)
 					require.EqualErrno(t, 0, f.Close())
+					if path == dir && runtime.GOOS == "windows" {
+						// windows requires O_RDWR, which is invalid for
+						// directories, so fall back to the path.
+						require.EqualErrno(t, syscall.ENOSYS, errno)
+						errno = Utimens(path, tc.times, true)
+					}
 					require.EqualErrno(t, 0, errno)
 				}
 
@@ -9,11 +9,40 @@ import (
 const (
 	_UTIME_NOW              = -1
 	_UTIME_OMIT             = -2
-	SupportsSymlinkNoFollow = false
+	SupportsSymlinkNoFollow = true
 )
 
 func utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) error {
-	return utimensPortable(path, times, symlinkFollow)
+	// Unlike utimensPortable, there's no need to stat to implement UTIME_OMIT,
+	// as SetFileTime leaves nil timestamps unchanged.
+	a, w := timespecToFiletime(times)
+
+	if a == nil && w == nil {
+		return nil // both omitted, so nothing to change
+	}
+
+	pathp, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open a directory. Opening
+	// only for FILE_WRITE_ATTRIBUTES allows this on directories, where
+	// syscall.O_RDWR is invalid.
+	flags := uint32(syscall.FILE_FLAG_BACKUP_SEMANTICS)
+	if !symlinkFollow {
+		// Like lutimes, open the symbolic link itself instead of its target.
+		flags |= syscall.FILE_FLAG_OPEN_REPARSE_POINT
+	}
+	h, err := syscall.CreateFile(pathp, syscall.FILE_WRITE_ATTRIBUTES,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, flags, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+
+	return syscall.SetFileTime(h, nil, a, w)
 }
 
 func futimens(fd uintptr, times *[2]syscall.Timespec) error {
@@ -35,7 +64,17 @@ func futimens(fd uintptr, times *[2]syscall.Timespec) error {
 	// Attempt to get the stat by handle, which works for normal files
 	h := syscall.Handle(fd)
 
-	// Note: This returns ERROR_ACCESS_DENIED when the input is a directory.
+	// Directories can't be opened with syscall.O_RDWR, so SetFileTime would
+	// return ERROR_ACCESS_DENIED. Kick out so that callers can use the
+	// path-based operation, which opens the directory with write access to
+	// its attributes.
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(h, &info); err != nil {
+		return err
+	} else if info.FileAttributes&syscall.FILE_ATTRIBUTE_DIRECTORY != 0 {
+		return syscall.ENOSYS
+	}
+
 	return syscall.SetFileTime(h, nil, a, w)
 }
 
@@ -0,0 +1,115 @@
+package platform
+
+import (
+	"io/fs"
+	gosync "sync"
+	"syscall"
+)
+
+// MmapRead maps the contents of a regular file into memory for reading, and
+// returns the mapping with a function to unmap it. Until unmapped, File.Pread
+// on `f` copies from the mapping, instead of making a syscall per read.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.ENOSYS: `f` has no file descriptor, e.g. it is from an fs.FS,
+//     or the platform doesn't support mapping files.
+//   - syscall.EBADF: `f` was closed or isn't open for reading.
+//   - syscall.EISDIR: `f` is a directory.
+//   - syscall.ENODEV: `f` isn't a regular file, e.g. it is a pipe.
+//
+// # Notes
+//
+//   - This is like `mmap` with `PROT_READ` and `MAP_SHARED` in POSIX, or
+//     MapViewOfFile on Windows.
+//     See https://pubs.opengroup.org/onlinepubs/9699919799/functions/mmap.html
+//   - The mapping has the size of the file when called. An empty file results
+//     in an empty mapping.
+//   - File.Truncate to a smaller size stops Pread using the mapping. Reading
+//     the mapping past the end of a truncated file crashes the process, so
+//     don't map files which can be truncated by others.
+func MmapRead(f File) ([]byte, func() error, syscall.Errno) {
+	if ff, ok := f.(*fsFile); ok {
+		return ff.mmapRead()
+	}
+	return nil, nil, syscall.ENOSYS
+}
+
+func (f *fsFile) mmapRead() ([]byte, func() error, syscall.Errno) {
+	fd, ok := f.file.(fdFile)
+	if !ok {
+		return nil, nil, syscall.ENOSYS
+	} else if f.accessMode == syscall.O_WRONLY {
+		return nil, nil, syscall.EBADF
+	}
+
+	st, errno := f.Stat()
+	if errno != 0 {
+		return nil, nil, errno
+	}
+	switch st.Mode.Type() {
+	case 0:
+	case fs.ModeDir:
+		return nil, nil, syscall.EISDIR
+	default:
+		return nil, nil, syscall.ENODEV
+	}
+
+	if st.Size == 0 {
+		return []byte{}, func() error { return nil }, 0
+	} else if int64(int(st.Size)) != st.Size {
+		return nil, nil, syscall.ENOMEM // too large for the address space
+	}
+
+	buf, err := mmapFile(fd.Fd(), int(st.Size))
+	if err != nil {
+		return nil, nil, UnwrapOSError(err)
+	}
+
+	f.mapMux.Lock()
+	f.mapped = buf
+	f.mapMux.Unlock()
+
+	var once gosync.Once
+	unmap := func() (err error) {
+		once.Do(func() {
+			// Wait for any Pread copying from the mapping.
+			f.mapMux.Lock()
+			if sameMapping(f.mapped, buf) {
+				f.mapped = nil
+			}
+			f.mapMux.Unlock()
+			err = munmapFile(buf)
+		})
+		return
+	}
+	return buf, unmap, 0
+}
+
+// preadMapped copies from the mapping of MmapRead, if there is one which
+// includes all bytes to read. This returns false otherwise, e.g. if the file
+// grew after it was mapped.
+func (f *fsFile) preadMapped(p []byte, off int64) (int, bool) {
+	f.mapMux.RLock()
+	defer f.mapMux.RUnlock()
+
+	if m := f.mapped; m != nil && off >= 0 && off+int64(len(p)) <= int64(len(m)) {
+		return copy(p, m[off:]), true
+	}
+	return 0, false
+}
+
+// invalidateMapping stops Pread using the mapping of MmapRead if the file is
+// being truncated to less than its size.
+func (f *fsFile) invalidateMapping(size int64) {
+	f.mapMux.Lock()
+	if size < int64(len(f.mapped)) {
+		f.mapped = nil
+	}
+	f.mapMux.Unlock()
+}
+
+func sameMapping(a, b []byte) bool {
+	return len(a) > 0 && len(b) > 0 && &a[0] == &b[0]
+}
@@ -0,0 +1,125 @@
+package platform
+
+import (
+	"errors"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMmapRead(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" && runtime.GOOS != "windows" {
+		t.Skip("mmap unsupported")
+	}
+
+	tmpDir := t.TempDir()
+	p := path.Join(tmpDir, wazeroFile)
+	require.NoError(t, os.WriteFile(p, []byte("wazero\n"), 0o600))
+
+	t.Run("Pread uses the mapping", func(t *testing.T) {
+		of, err := os.OpenFile(p, os.O_RDWR, 0)
+		require.NoError(t, err)
+		// Reads without the mapping fail, so success means it was used.
+		f := NewFsFile(p, os.O_RDWR, &noReadAtFile{of})
+		defer f.Close()
+
+		b, unmap, errno := MmapRead(f)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "wazero\n", string(b))
+
+		buf := make([]byte, 3)
+		requirePread(t, f, buf, 2)
+		require.Equal(t, "zer", string(buf))
+
+		// Reads past the mapping, e.g. if the file grew, don't use it.
+		_, errno = f.Pread(buf, 6)
+		require.EqualErrno(t, syscall.EIO, errno)
+
+		require.NoError(t, unmap())
+		require.NoError(t, unmap()) // idempotent
+		_, errno = f.Pread(buf, 2)
+		require.EqualErrno(t, syscall.EIO, errno)
+	})
+
+	t.Run("Truncate invalidates", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("windows can't truncate a mapped file")
+		}
+		p := path.Join(tmpDir, "truncate")
+		require.NoError(t, os.WriteFile(p, []byte("wazero\n"), 0o600))
+		of, err := os.OpenFile(p, os.O_RDWR, 0)
+		require.NoError(t, err)
+		f := NewFsFile(p, os.O_RDWR, &noReadAtFile{of})
+		defer f.Close()
+
+		_, unmap, errno := MmapRead(f)
+		require.EqualErrno(t, 0, errno)
+		defer unmap()
+
+		require.EqualErrno(t, 0, f.Truncate(2))
+		_, errno = f.Pread(make([]byte, 1), 0)
+		require.EqualErrno(t, syscall.EIO, errno)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		p := path.Join(tmpDir, emptyFile)
+		f := openFsFile(t, p, os.O_RDWR|os.O_CREATE, 0o600)
+		defer f.Close()
+
+		b, unmap, errno := MmapRead(f)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, len(b))
+		require.NoError(t, unmap())
+	})
+
+	t.Run("ENOSYS without a file descriptor", func(t *testing.T) {
+		f := NewFsFile(wazeroFile, syscall.O_RDONLY, embedFile(t))
+		defer f.Close()
+
+		_, _, errno := MmapRead(f)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+	})
+
+	t.Run("EBADF if not open for reading", func(t *testing.T) {
+		f := openFsFile(t, p, os.O_WRONLY, 0)
+		defer f.Close()
+
+		_, _, errno := MmapRead(f)
+		require.EqualErrno(t, syscall.EBADF, errno)
+	})
+
+	t.Run("EISDIR", func(t *testing.T) {
+		f := openFsFile(t, tmpDir, os.O_RDONLY, 0)
+		defer f.Close()
+
+		_, _, errno := MmapRead(f)
+		require.EqualErrno(t, syscall.EISDIR, errno)
+	})
+
+	t.Run("ENODEV if not a regular file", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("windows pipes aren't stat as named pipes")
+		}
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer w.Close()
+		f := NewFsFile("pipe", syscall.O_RDONLY, r)
+		defer f.Close()
+
+		_, _, errno := MmapRead(f)
+		require.EqualErrno(t, syscall.ENODEV, errno)
+	})
+}
+
+// noReadAtFile fails io.ReaderAt, to show when Pread doesn't use a mapping.
+type noReadAtFile struct {
+	*os.File
+}
+
+func (f *noReadAtFile) ReadAt([]byte, int64) (int, error) {
+	return 0, errors.New("ReadAt")
+}
@@ -0,0 +1,13 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import "syscall"
+
+func mmapFile(fd uintptr, size int) ([]byte, error) {
+	return syscall.Mmap(int(fd), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmapFile(b []byte) error {
+	return syscall.Munmap(b)
+}
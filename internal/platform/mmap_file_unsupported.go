@@ -0,0 +1,13 @@
+//go:build !(darwin || linux || freebsd || windows)
+
+package platform
+
+import "syscall"
+
+func mmapFile(uintptr, int) ([]byte, error) {
+	return nil, syscall.ENOSYS
+}
+
+func munmapFile([]byte) error {
+	return syscall.ENOSYS
+}
@@ -0,0 +1,32 @@
+package platform
+
+import (
+	"reflect"
+	"syscall"
+	"unsafe"
+)
+
+func mmapFile(fd uintptr, size int) ([]byte, error) {
+	h, err := syscall.CreateFileMapping(syscall.Handle(fd), nil, syscall.PAGE_READONLY, 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The view keeps the mapping open, so the handle isn't needed after.
+	defer syscall.CloseHandle(h)
+
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	sh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	sh.Data = addr
+	sh.Len = size
+	sh.Cap = size
+	return b, nil
+}
+
+func munmapFile(b []byte) error {
+	return syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(&b[0])))
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// OpenFileNoatime is like OpenFile, except reads from the result don't
+// update the access time of the file, where the host allows it.
+//
+// On Linux, this opens with `O_NOATIME`, which is only allowed for the owner
+// of the file. When it is not allowed, this opens the file without it, so
+// the access time is updated as the mount of the file decides.
+func OpenFileNoatime(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	f, errno := OpenFile(path, flag|syscall.O_NOATIME, perm)
+	if errno == syscall.EPERM {
+		return OpenFile(path, flag, perm)
+	}
+	return f, errno
+}
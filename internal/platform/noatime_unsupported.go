@@ -0,0 +1,14 @@
+//go:build !linux
+
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// OpenFileNoatime is like OpenFile, as this platform can't open a file
+// without updating its access time. See the Linux implementation.
+func OpenFileNoatime(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	return OpenFile(path, flag, perm)
+}
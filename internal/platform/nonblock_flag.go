@@ -0,0 +1,9 @@
+//go:build !windows && !js && !illumos && !solaris && !wasip1
+
+package platform
+
+import "syscall"
+
+// O_NONBLOCK is an alias of syscall.O_NONBLOCK. See nonblock_flag_wasip1.go
+// for platforms which do not have it.
+const O_NONBLOCK = syscall.O_NONBLOCK
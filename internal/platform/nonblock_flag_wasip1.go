@@ -0,0 +1,6 @@
+package platform
+
+// O_NONBLOCK is a placeholder, as wasip1 has no such open flag in the syscall
+// package. syscall.Open ignores it, and openNonblock applies it afterward via
+// syscall.SetNonblock.
+const O_NONBLOCK = 1 << 27
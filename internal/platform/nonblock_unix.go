@@ -7,3 +7,13 @@ import "syscall"
 func setNonblock(fd uintptr, enable bool) error {
 	return syscall.SetNonblock(int(fd), enable)
 }
+
+// readFd calls read(2) on `fd`, without waiting for it to be readable.
+func readFd(fd uintptr, p []byte) (int, error) {
+	return syscall.Read(int(fd), p)
+}
+
+// writeFd calls write(2) on `fd`, without waiting for it to be writable.
+func writeFd(fd uintptr, p []byte) (int, error) {
+	return syscall.Write(int(fd), p)
+}
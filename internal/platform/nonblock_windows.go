@@ -7,3 +7,13 @@ import "syscall"
 func setNonblock(fd uintptr, enable bool) error {
 	return syscall.SetNonblock(syscall.Handle(fd), enable)
 }
+
+// readFd calls ReadFile on `fd`, without waiting for it to be readable.
+func readFd(fd uintptr, p []byte) (int, error) {
+	return syscall.Read(syscall.Handle(fd), p)
+}
+
+// writeFd calls WriteFile on `fd`, without waiting for it to be writable.
+func writeFd(fd uintptr, p []byte) (int, error) {
+	return syscall.Write(syscall.Handle(fd), p)
+}
@@ -17,10 +17,64 @@ const (
 
 // OpenFile is like os.OpenFile except it returns syscall.Errno. A zero
 // syscall.Errno is success.
+//
+// When `flag` includes O_NONBLOCK, the result is non-blocking, like
+// after File.SetNonblock. This follows POSIX for FIFOs: opening for read
+// succeeds even without a writer, and opening for write returns
+// syscall.ENXIO if there is no reader.
+//
+// When `flag` includes O_PATH, only the metadata of the result can be read
+// or changed. See O_PATH for details.
 func OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
-	f, err := os.OpenFile(path, flag, perm)
+	if flag&O_PATH != 0 {
+		flag = pathFlag(flag)
+	}
+	direct := flag&O_DIRECT != 0
+	flag, errno := directFlag(flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	var f *os.File
+	err := RetryOnEINTR(func() (err error) {
+		if flag&O_NONBLOCK != 0 {
+			f, err = openNonblock(path, flag, perm)
+		} else {
+			f, err = os.OpenFile(path, flag, perm)
+		}
+		return
+	})
+	if errno = UnwrapOSError(err); errno != 0 {
+		return nil, errno
+	} else if direct {
+		if errno = setDirect(f); errno != 0 {
+			_ = f.Close()
+			return nil, errno
+		}
+	}
 	// Note: This does not return a platform.File because sysfs.FS that returns
 	// one may want to hide the real OS path. For example, this is needed for
 	// pre-opens.
-	return f, UnwrapOSError(err)
+	return f, 0
+}
+
+// openNonblock opens a file with O_NONBLOCK, like File.SetNonblock
+// after open. This doesn't use os.OpenFile, as its result waits for the file
+// to be ready, instead of returning syscall.EAGAIN.
+func openNonblock(path string, flag int, perm fs.FileMode) (*os.File, error) {
+	fd, err := syscall.Open(path, flag|syscall.O_CLOEXEC, uint32(perm.Perm()))
+	if err != nil {
+		return nil, err
+	}
+	// Clear the flag while creating the os.File, so that it isn't added to
+	// the poller, then restore it.
+	if err = syscall.SetNonblock(fd, false); err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), path)
+	if err = syscall.SetNonblock(fd, true); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
 }
@@ -10,10 +10,17 @@ import (
 const (
 	O_DIRECTORY = 1 << 29
 	O_NOFOLLOW  = 1 << 30
+	O_NONBLOCK  = 1 << 27
 )
 
 func OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
-	flag &= ^(O_DIRECTORY | O_NOFOLLOW) // erase placeholders
+	if flag&O_PATH != 0 {
+		flag = pathFlag(flag)
+	}
+	flag &= ^(O_DIRECTORY | O_NOFOLLOW | O_NONBLOCK) // erase placeholders
+	if flag&O_DIRECT != 0 {
+		return nil, syscall.ENOTSUP
+	}
 	f, err := os.OpenFile(path, flag, perm)
 	return f, UnwrapOSError(err)
 }
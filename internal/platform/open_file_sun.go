@@ -12,9 +12,20 @@ const (
 	// See https://github.com/illumos/illumos-gate/blob/edd580643f2cf1434e252cd7779e83182ea84945/usr/src/uts/common/sys/fcntl.h#L90
 	O_DIRECTORY = 0x1000000
 	O_NOFOLLOW  = syscall.O_NOFOLLOW
+	O_NONBLOCK  = syscall.O_NONBLOCK
 )
 
 func OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
-	f, err := os.OpenFile(path, flag, perm)
+	if flag&O_PATH != 0 {
+		flag = pathFlag(flag)
+	}
+	if flag&O_DIRECT != 0 {
+		return nil, syscall.ENOTSUP
+	}
+	var f *os.File
+	err := RetryOnEINTR(func() (err error) {
+		f, err = os.OpenFile(path, flag, perm)
+		return
+	})
 	return f, UnwrapOSError(err)
 }
@@ -0,0 +1,45 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenFile_fifoNonblock(t *testing.T) {
+	fifo := path.Join(t.TempDir(), "fifo")
+	require.NoError(t, syscall.Mkfifo(fifo, 0o600))
+
+	// Opening for write fails without a reader.
+	_, errno := OpenFile(fifo, os.O_WRONLY|O_NONBLOCK, 0)
+	require.EqualErrno(t, syscall.ENXIO, errno)
+
+	// Opening for read succeeds without a writer.
+	rf, errno := OpenFile(fifo, os.O_RDONLY|O_NONBLOCK, 0)
+	require.EqualErrno(t, 0, errno)
+	r := NewFsFile(fifo, os.O_RDONLY|O_NONBLOCK, rf)
+	defer r.Close()
+	require.True(t, r.IsNonblock())
+
+	wf, errno := OpenFile(fifo, os.O_WRONLY|O_NONBLOCK, 0)
+	require.EqualErrno(t, 0, errno)
+	w := NewFsFile(fifo, os.O_WRONLY|O_NONBLOCK, wf)
+	defer w.Close()
+	require.True(t, w.IsNonblock())
+
+	// Reading without data doesn't block.
+	buf := make([]byte, 10)
+	_, errno = r.Read(buf)
+	require.EqualErrno(t, syscall.EAGAIN, errno)
+
+	_, errno = w.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	n, errno := r.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazero", string(buf[:n]))
+}
@@ -14,12 +14,12 @@ import (
 // works as expected.
 //
 // Since those placeholder are not interpreted by the open function, the unix
-// features they represent are also not implemented on windows:
+// features they represent are emulated or not implemented on windows:
 //
 //   - O_DIRECTORY allows programs to ensure that the opened file is a directory.
-//     This could be emulated by doing a stat call on the file after opening it
-//     to verify that it is in fact a directory, then closing it and returning an
-//     error if it is not.
+//     This is emulated by doing a stat call on the file after opening it to
+//     verify that it is in fact a directory, then closing it and returning
+//     syscall.ENOTDIR if it is not.
 //
 //   - O_NOFOLLOW allows programs to ensure that if the opened file is a symbolic
 //     link, the link itself is opened instead of its target.
@@ -28,8 +28,19 @@ const (
 	O_NOFOLLOW  = 1 << 30
 )
 
+// O_NONBLOCK is ignored by open, like syscall.SetNonblock on windows.
+const O_NONBLOCK = syscall.O_NONBLOCK
+
 func OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
-	if f, errno := openFile(path, flag, perm); errno != 0 {
+	if flag&O_PATH != 0 {
+		flag = pathFlag(flag)
+	}
+	if flag&O_DIRECT != 0 {
+		return nil, syscall.ENOTSUP // FILE_FLAG_NO_BUFFERING isn't implemented.
+	} else if f, errno := openFile(path, flag, perm); errno != 0 {
+		return nil, errno
+	} else if errno = checkDirectory(f, flag); errno != 0 {
+		_ = f.Close()
 		return nil, errno
 	} else { // TODO: revisit windowsWrappedFile once fsFile is complete
 		f := &windowsWrappedFile{osFile: f, path: path, flag: flag, perm: perm}
@@ -37,6 +48,19 @@ func OpenFile(path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno)
 	}
 }
 
+// checkDirectory returns syscall.ENOTDIR if `flag` includes O_DIRECTORY, but
+// `f` isn't a directory.
+func checkDirectory(f *os.File, flag int) syscall.Errno {
+	if flag&O_DIRECTORY == 0 {
+		return 0
+	} else if st, err := f.Stat(); err != nil {
+		return UnwrapOSError(err)
+	} else if !st.IsDir() {
+		return syscall.ENOTDIR
+	}
+	return 0
+}
+
 func openFile(path string, flag int, perm fs.FileMode) (*os.File, syscall.Errno) {
 	isDir := flag&O_DIRECTORY > 0
 	flag &= ^(O_DIRECTORY | O_NOFOLLOW) // erase placeholders
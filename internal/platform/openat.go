@@ -0,0 +1,138 @@
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// dirFdFile is implemented by files which can be used as the directory in
+// the *at family of functions, such as Openat.
+type dirFdFile interface {
+	// dirFd returns the file descriptor and host path of the directory, or
+	// false if the file isn't backed by one.
+	dirFd() (fd int, name string, ok bool)
+}
+
+// dirFd implements dirFdFile
+func (f *fsFile) dirFd() (int, string, bool) {
+	if f.isClosed() {
+		return -1, "", false
+	} else if osf, ok := f.file.(*os.File); ok {
+		return int(osf.Fd()), osf.Name(), true
+	}
+	return -1, "", false
+}
+
+// Openat is like OpenFile, except a relative `path` is resolved against the
+// directory `dir`, instead of the current working directory.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.ENOSYS: `dir` isn't backed by a file descriptor, or the
+//     platform doesn't support this function.
+//
+// Otherwise, errors are the same as OpenFile.
+//
+// # Notes
+//
+//   - The *at functions in this package are only implemented on Linux
+//     (amd64, arm64 and riscv64), as the syscall package doesn't export
+//     them for other platforms. Elsewhere, including darwin and FreeBSD,
+//     they return syscall.ENOSYS.
+//   - This is like `openat` in POSIX. See
+//     https://pubs.opengroup.org/onlinepubs/9699919799/functions/open.html
+//   - Unlike path joining, this is not affected by renames of `dir` or its
+//     parents while it is open.
+func Openat(dir File, path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	if fd, name, ok := dirFdOf(dir); ok {
+		return openat(fd, name, path, flag, perm)
+	}
+	return nil, syscall.ENOSYS
+}
+
+// Statat is like Stat, except a relative `path` is resolved against the
+// directory `dir`. When `symlinkFollow` is false, this is like Lstat.
+//
+// See Openat for notes on syscall.ENOSYS.
+//
+// Note: This is like `fstatat` in POSIX. See
+// https://pubs.opengroup.org/onlinepubs/9699919799/functions/fstatat.html
+func Statat(dir File, path string, symlinkFollow bool) (Stat_t, syscall.Errno) {
+	if fd, name, ok := dirFdOf(dir); ok {
+		return statat(fd, name, path, symlinkFollow)
+	}
+	return Stat_t{}, syscall.ENOSYS
+}
+
+// Mkdirat is like os.Mkdir, except a relative `path` is resolved against the
+// directory `dir`.
+//
+// See Openat for notes on syscall.ENOSYS.
+//
+// Note: This is like `mkdirat` in POSIX. See
+// https://pubs.opengroup.org/onlinepubs/9699919799/functions/mkdirat.html
+func Mkdirat(dir File, path string, perm fs.FileMode) syscall.Errno {
+	if fd, _, ok := dirFdOf(dir); ok {
+		return mkdirat(fd, path, perm)
+	}
+	return syscall.ENOSYS
+}
+
+// Unlinkat is like Unlink, except a relative `path` is resolved against the
+// directory `dir`.
+//
+// See Openat for notes on syscall.ENOSYS.
+//
+// Note: This is like `unlinkat` in POSIX. See
+// https://pubs.opengroup.org/onlinepubs/9699919799/functions/unlinkat.html
+func Unlinkat(dir File, path string) syscall.Errno {
+	if fd, _, ok := dirFdOf(dir); ok {
+		return unlinkat(fd, path)
+	}
+	return syscall.ENOSYS
+}
+
+// Symlinkat is like Symlink, except a relative `link` is resolved against the
+// directory `dir`. `target` isn't resolved, as it is only resolved when the
+// link is followed.
+//
+// See Openat for notes on syscall.ENOSYS.
+//
+// Note: This is like `symlinkat` in POSIX. See
+// https://pubs.opengroup.org/onlinepubs/9699919799/functions/symlinkat.html
+func Symlinkat(target string, dir File, link string) syscall.Errno {
+	if fd, _, ok := dirFdOf(dir); ok {
+		return symlinkat(target, fd, link)
+	}
+	return syscall.ENOSYS
+}
+
+// Linkat is like os.Link, except relative `oldPath` and `newPath` are
+// resolved against the directories `oldDir` and `newDir`. Symbolic links
+// aren't followed.
+//
+// See Openat for notes on syscall.ENOSYS, which is returned if either
+// directory isn't backed by a file descriptor.
+//
+// Note: This is like `linkat` in POSIX. See
+// https://pubs.opengroup.org/onlinepubs/9699919799/functions/linkat.html
+func Linkat(oldDir File, oldPath string, newDir File, newPath string) syscall.Errno {
+	oldFd, _, ok := dirFdOf(oldDir)
+	if !ok {
+		return syscall.ENOSYS
+	}
+	newFd, _, ok := dirFdOf(newDir)
+	if !ok {
+		return syscall.ENOSYS
+	}
+	return linkat(oldFd, oldPath, newFd, newPath)
+}
+
+func dirFdOf(dir File) (int, string, bool) {
+	if d, ok := dir.(dirFdFile); ok {
+		return d.dirFd()
+	}
+	return -1, "", false
+}
@@ -0,0 +1,78 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func openat(dirfd int, dirName, path string, flag int, perm fs.FileMode) (fs.File, syscall.Errno) {
+	fd, err := syscall.Openat(dirfd, path, flag|syscall.O_CLOEXEC, uint32(perm.Perm()))
+	if err != nil {
+		return nil, UnwrapOSError(err)
+	}
+	return os.NewFile(uintptr(fd), dirName+"/"+path), 0
+}
+
+func statat(dirfd int, dirName, path string, symlinkFollow bool) (Stat_t, syscall.Errno) {
+	flag := O_PATH | syscall.O_CLOEXEC
+	if !symlinkFollow {
+		flag |= syscall.O_NOFOLLOW
+	}
+	fd, err := syscall.Openat(dirfd, path, flag, 0)
+	if err != nil {
+		return Stat_t{}, UnwrapOSError(err)
+	}
+	f := os.NewFile(uintptr(fd), dirName+"/"+path)
+	defer f.Close()
+	return statFile(f)
+}
+
+func mkdirat(dirfd int, path string, perm fs.FileMode) syscall.Errno {
+	err := syscall.Mkdirat(dirfd, path, uint32(perm.Perm()))
+	return UnwrapOSError(err)
+}
+
+func unlinkat(dirfd int, path string) syscall.Errno {
+	err := syscall.Unlinkat(dirfd, path)
+	if errno := UnwrapOSError(err); errno == syscall.EPERM {
+		return syscall.EISDIR // same as Unlink
+	}
+	return UnwrapOSError(err)
+}
+
+// symlinkat calls the syscall directly, as the syscall package doesn't export
+// it.
+func symlinkat(target string, dirfd int, link string) syscall.Errno {
+	targetPtr, err := syscall.BytePtrFromString(target)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	linkPtr, err := syscall.BytePtrFromString(link)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_SYMLINKAT,
+		uintptr(unsafe.Pointer(targetPtr)), uintptr(dirfd), uintptr(unsafe.Pointer(linkPtr)))
+	return errno
+}
+
+// linkat calls the syscall directly, as the syscall package doesn't export
+// it.
+func linkat(oldDirfd int, oldPath string, newDirfd int, newPath string) syscall.Errno {
+	oldPtr, err := syscall.BytePtrFromString(oldPath)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	newPtr, err := syscall.BytePtrFromString(newPath)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_LINKAT,
+		uintptr(oldDirfd), uintptr(unsafe.Pointer(oldPtr)),
+		uintptr(newDirfd), uintptr(unsafe.Pointer(newPtr)), 0, 0)
+	return errno
+}
@@ -0,0 +1,77 @@
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenat(t *testing.T) {
+	tmpDir := t.TempDir()
+	dirPath := path.Join(tmpDir, "dir")
+	require.NoError(t, os.Mkdir(dirPath, 0o700))
+	require.NoError(t, os.WriteFile(path.Join(dirPath, wazeroFile), []byte("wazero"), 0o600))
+
+	dir := openFsFile(t, dirPath, syscall.O_RDONLY, 0)
+	defer dir.Close()
+
+	if _, errno := Openat(dir, wazeroFile, syscall.O_RDONLY, 0); errno == syscall.ENOSYS {
+		t.Skip("unsupported on " + runtime.GOOS + "/" + runtime.GOARCH)
+	}
+
+	// Renaming the directory doesn't affect operations relative to it.
+	renamed := path.Join(tmpDir, "renamed")
+	require.NoError(t, os.Rename(dirPath, renamed))
+
+	f, errno := Openat(dir, wazeroFile, syscall.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	b := make([]byte, 6)
+	_, err := f.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+	require.NoError(t, f.Close())
+
+	st, errno := Statat(dir, wazeroFile, true)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+
+	require.EqualErrno(t, 0, Mkdirat(dir, "sub", 0o700))
+	st, errno = Statat(dir, "sub", false)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeDir, st.Mode.Type())
+
+	require.EqualErrno(t, 0, Symlinkat(wazeroFile, dir, "link"))
+	target, err := os.Readlink(path.Join(renamed, "link"))
+	require.NoError(t, err)
+	require.Equal(t, wazeroFile, target)
+	require.EqualErrno(t, syscall.EEXIST, Symlinkat(wazeroFile, dir, "link"))
+
+	require.EqualErrno(t, 0, Linkat(dir, wazeroFile, dir, "sub/hard"))
+	st, errno = Statat(dir, "sub/hard", false)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, uint64(2), st.Nlink)
+
+	require.EqualErrno(t, syscall.EISDIR, Unlinkat(dir, "sub"))
+	require.EqualErrno(t, 0, Unlinkat(dir, wazeroFile))
+	_, err = os.Stat(path.Join(renamed, wazeroFile))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, errno = Statat(dir, wazeroFile, true)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+}
+
+func TestOpenat_ENOSYS(t *testing.T) {
+	_, errno := Openat(NoopFile{}, wazeroFile, syscall.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+	_, errno = Statat(NoopFile{}, wazeroFile, true)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+	require.EqualErrno(t, syscall.ENOSYS, Mkdirat(NoopFile{}, "sub", 0o700))
+	require.EqualErrno(t, syscall.ENOSYS, Unlinkat(NoopFile{}, wazeroFile))
+	require.EqualErrno(t, syscall.ENOSYS, Symlinkat(wazeroFile, NoopFile{}, "link"))
+	require.EqualErrno(t, syscall.ENOSYS, Linkat(NoopFile{}, wazeroFile, NoopFile{}, "hard"))
+}
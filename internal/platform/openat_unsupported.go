@@ -0,0 +1,32 @@
+//go:build !((amd64 || arm64 || riscv64) && linux)
+
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+func openat(int, string, string, int, fs.FileMode) (fs.File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
+
+func statat(int, string, string, bool) (Stat_t, syscall.Errno) {
+	return Stat_t{}, syscall.ENOSYS
+}
+
+func mkdirat(int, string, fs.FileMode) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+func unlinkat(int, string) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+func symlinkat(string, int, string) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+func linkat(int, string, int, string) syscall.Errno {
+	return syscall.ENOSYS
+}
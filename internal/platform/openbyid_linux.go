@@ -0,0 +1,73 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// _FILEID_INO32_GEN is the file handle type of ext2, ext3 and ext4, which is
+// a 32-bit inode number and generation. This isn't defined in the syscall
+// package.
+const _FILEID_INO32_GEN = 1
+
+// fileHandle is struct file_handle, with the fields of _FILEID_INO32_GEN.
+type fileHandle struct {
+	handleBytes uint32
+	handleType  int32
+	ino, gen    uint32
+}
+
+// OpenByID opens the file with the inode number `ino`, on the filesystem
+// which contains the directory `mountDir`. The name of the returned file is
+// its host path.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.EPERM: the process lacks the CAP_DAC_READ_SEARCH capability.
+//   - syscall.ENOTSUP: the filesystem doesn't support file handles, or
+//     `ino` doesn't fit in one.
+//   - syscall.ENOENT: there's no file with the inode `ino`, or the
+//     filesystem requires its generation, which isn't known.
+//
+// Otherwise, errors are the same as OpenFile.
+//
+// # Notes
+//
+//   - This is like `open_by_handle_at` in Linux, with a handle built from
+//     `ino` and a generation of zero, which ext4 accepts for any file. See
+//     https://man7.org/linux/man-pages/man2/open_by_handle_at.2.html
+//   - Other platforms return syscall.ENOSYS.
+func OpenByID(mountDir string, ino uint64, flag int) (fs.File, syscall.Errno) {
+	if ino > math.MaxUint32 {
+		return nil, syscall.ENOTSUP
+	}
+	mountFd, err := syscall.Open(mountDir, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, UnwrapOSError(err)
+	}
+	defer syscall.Close(mountFd)
+
+	handle := fileHandle{handleBytes: 8, handleType: _FILEID_INO32_GEN, ino: uint32(ino)}
+	fd, _, errno := syscall.Syscall(_SYS_OPEN_BY_HANDLE_AT,
+		uintptr(mountFd), uintptr(unsafe.Pointer(&handle)), uintptr(flag|syscall.O_CLOEXEC))
+	if errno == syscall.ESTALE {
+		return nil, syscall.ENOENT
+	} else if errno != 0 {
+		return nil, errno
+	}
+
+	// The kernel knows the current path of the file, even if renamed.
+	name, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(fd)))
+	if err != nil {
+		_ = syscall.Close(int(fd))
+		return nil, UnwrapOSError(err)
+	}
+	return os.NewFile(fd, name), 0
+}
@@ -0,0 +1,4 @@
+package platform
+
+// _SYS_OPEN_BY_HANDLE_AT isn't defined in the syscall package on amd64.
+const _SYS_OPEN_BY_HANDLE_AT = 304
@@ -0,0 +1,7 @@
+//go:build (arm64 || riscv64) && linux
+
+package platform
+
+import "syscall"
+
+const _SYS_OPEN_BY_HANDLE_AT = syscall.SYS_OPEN_BY_HANDLE_AT
@@ -0,0 +1,59 @@
+package platform
+
+import (
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenByID(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(filePath, []byte("wazero"), 0o600))
+
+	st, errno := Stat(filePath)
+	require.EqualErrno(t, 0, errno)
+
+	f, errno := OpenByID(tmpDir, st.Ino, syscall.O_RDONLY)
+	switch errno {
+	case syscall.ENOSYS, syscall.EPERM, syscall.ENOTSUP:
+		t.Skip("open_by_handle_at isn't supported by the platform, process or filesystem")
+	}
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	t.Run("reads the file", func(t *testing.T) {
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("name is the current path", func(t *testing.T) {
+		renamed := path.Join(tmpDir, "renamed")
+		require.NoError(t, os.Rename(filePath, renamed))
+
+		f, errno := OpenByID(tmpDir, st.Ino, syscall.O_RDONLY)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		fst, err := f.Stat()
+		require.NoError(t, err)
+		require.Equal(t, renamed, f.(*os.File).Name())
+		require.Equal(t, "renamed", fst.Name())
+	})
+
+	t.Run("ENOENT after removal", func(t *testing.T) {
+		dirPath := path.Join(tmpDir, "dir")
+		require.NoError(t, os.Mkdir(dirPath, 0o700))
+		dirSt, errno := Stat(dirPath)
+		require.EqualErrno(t, 0, errno)
+		require.NoError(t, os.Remove(dirPath))
+
+		_, errno = OpenByID(tmpDir, dirSt.Ino, syscall.O_RDONLY)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+}
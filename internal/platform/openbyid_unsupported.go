@@ -0,0 +1,13 @@
+//go:build !((amd64 || arm64 || riscv64) && linux)
+
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// OpenByID returns syscall.ENOSYS as open_by_handle_at isn't supported.
+func OpenByID(string, uint64, int) (fs.File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
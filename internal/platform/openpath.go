@@ -0,0 +1,16 @@
+package platform
+
+import "syscall"
+
+// pathOnlyFlags are the only flags which have an effect with O_PATH. Others,
+// such as the access mode and syscall.O_CREAT, are ignored, like on Linux.
+const pathOnlyFlags = O_PATH | O_DIRECTORY | O_NOFOLLOW
+
+// checkPathOnly returns syscall.EBADF if the file was opened with O_PATH,
+// for operations which need to read or write it.
+func (f *fsFile) checkPathOnly() syscall.Errno {
+	if f.pathOnly {
+		return syscall.EBADF
+	}
+	return 0
+}
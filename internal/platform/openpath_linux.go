@@ -0,0 +1,46 @@
+package platform
+
+import (
+	"os"
+	"strconv"
+)
+
+// O_PATH is an OpenFile flag which opens a handle to the file, without
+// opening the file itself. This is for guests which only need metadata, or
+// which use a directory as the anchor of the *at family of functions, such
+// as Openat and Statat.
+//
+// # Behavior
+//
+// Only Stat, IsDir, Chmod, Chown, Utimens, Dup, Close and use as the
+// directory of *at functions work on the result. Other operations, such as
+// Read, Write, Seek and Readdir, return syscall.EBADF. Flags other than
+// O_DIRECTORY and O_NOFOLLOW are ignored.
+//
+// # Platform support
+//
+//   - Linux: This is the host's `O_PATH`. It doesn't need read permission on
+//     the file, and with O_NOFOLLOW, opens a symbolic link itself.
+//   - Otherwise: This is emulated by opening with syscall.O_RDONLY, so it
+//     needs read permission on the file, and O_NOFOLLOW fails on a symbolic
+//     link as usual.
+const O_PATH = 0x200000 // missing from the syscall package
+
+// pathFlag returns `flag` with O_PATH converted for the host.
+func pathFlag(flag int) int {
+	return flag & pathOnlyFlags // native
+}
+
+// pathOnlyName returns a name which resolves to the file, when it was opened
+// with O_PATH and the host can't use its file descriptor to change it.
+//
+// Note: `fchmod` and `futimens` return syscall.EBADF on a file descriptor
+// opened with `O_PATH`, but the link in /proc/self/fd can be followed.
+func (f *fsFile) pathOnlyName() (string, bool) {
+	if !f.pathOnly {
+		return "", false
+	} else if osf, ok := f.file.(*os.File); ok {
+		return "/proc/self/fd/" + strconv.Itoa(int(osf.Fd())), true
+	}
+	return "", false
+}
@@ -0,0 +1,85 @@
+package platform
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenFile_O_PATH(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := path.Join(tmpDir, wazeroFile)
+	require.NoError(t, os.WriteFile(filePath, []byte("wazero"), 0o600))
+
+	t.Run("file", func(t *testing.T) {
+		// The access mode and other flags are ignored.
+		f := openFsFile(t, filePath, O_PATH|syscall.O_RDWR|syscall.O_TRUNC, 0)
+		defer f.Close()
+
+		st, errno := f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(6), st.Size)
+
+		_, errno = f.Read(make([]byte, 6))
+		require.EqualErrno(t, syscall.EBADF, errno)
+		_, errno = f.Pread(make([]byte, 6), 0)
+		require.EqualErrno(t, syscall.EBADF, errno)
+		_, errno = f.Write([]byte("wa"))
+		require.EqualErrno(t, syscall.EBADF, errno)
+		_, errno = f.Seek(0, 0)
+		require.EqualErrno(t, syscall.EBADF, errno)
+		require.EqualErrno(t, syscall.EBADF, f.Truncate(0))
+
+		dup, errno := f.Dup()
+		require.EqualErrno(t, 0, errno)
+		_, errno = dup.Read(make([]byte, 6))
+		require.EqualErrno(t, syscall.EBADF, errno)
+		require.EqualErrno(t, 0, dup.Close())
+
+		if runtime.GOOS != "windows" {
+			require.EqualErrno(t, 0, f.Chmod(0o640))
+			info, err := os.Stat(filePath)
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		}
+
+		times := &[2]syscall.Timespec{{Sec: 1, Nsec: 0}, {Sec: 2, Nsec: 0}}
+		require.EqualErrno(t, 0, f.Utimens(times))
+		st, errno = f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(2e9), st.Mtim)
+
+		// The file wasn't truncated or written.
+		b, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir := openFsFile(t, tmpDir, O_PATH|O_DIRECTORY, 0)
+		defer dir.Close()
+
+		isDir, errno := dir.IsDir()
+		require.EqualErrno(t, 0, errno)
+		require.True(t, isDir)
+
+		_, errno = dir.Readdir(-1)
+		require.EqualErrno(t, syscall.EBADF, errno)
+
+		// It can be the directory of *at functions.
+		st, errno := Statat(dir, wazeroFile, true)
+		if errno == syscall.ENOSYS {
+			return // unsupported on this platform
+		}
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(6), st.Size)
+
+		f, errno := Openat(dir, wazeroFile, syscall.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		require.NoError(t, f.Close())
+	})
+}
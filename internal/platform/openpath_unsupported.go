@@ -0,0 +1,20 @@
+//go:build !linux
+
+package platform
+
+import "syscall"
+
+// O_PATH is a placeholder, which is emulated on this platform. See the
+// comments on the same constant in openpath_linux.go.
+const O_PATH = 1 << 26
+
+// pathFlag returns `flag` with O_PATH replaced by syscall.O_RDONLY.
+func pathFlag(flag int) int {
+	return flag&(pathOnlyFlags&^O_PATH) | syscall.O_RDONLY
+}
+
+// pathOnlyName returns false, as the file descriptor of the emulated O_PATH
+// can be changed directly.
+func (f *fsFile) pathOnlyName() (string, bool) {
+	return "", false
+}
@@ -0,0 +1,35 @@
+package platform
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// openPty returns the terminal side of a new pseudo-terminal, skipping the
+// test when the host doesn't allow them.
+func openPty(t *testing.T) File {
+	ptmx, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skip("pseudo-terminals aren't available:", err)
+	}
+	t.Cleanup(func() { ptmx.Close() })
+
+	var unlock int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, ptmx.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock)))
+	require.EqualErrno(t, 0, errno)
+	var n uint32
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, ptmx.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n)))
+	require.EqualErrno(t, 0, errno)
+
+	ptsPath := "/dev/pts/" + strconv.Itoa(int(n))
+	pts, err := os.OpenFile(ptsPath, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip("pseudo-terminals aren't available:", err)
+	}
+	return NewFsFile(ptsPath, syscall.O_RDWR, pts)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package platform
+
+import "testing"
+
+// openPty skips the test, as pseudo-terminals are only opened on Linux.
+func openPty(t *testing.T) File {
+	t.Skip("pseudo-terminals are only tested on Linux")
+	return nil
+}
@@ -0,0 +1,30 @@
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// _F_PUNCHHOLE is the `fcntl` command to deallocate a range of a file.
+const _F_PUNCHHOLE = 99
+
+// fpunchhole is `struct fpunchhole` in <sys/fcntl.h>.
+type fpunchhole struct {
+	flags    uint32 // unused
+	reserved uint32
+	offset   int64
+	length   int64
+}
+
+// punchHole deallocates the range with `fcntl(F_PUNCHHOLE)`.
+//
+// Note: APFS requires `offset` and `length` to be multiples of the block
+// size, returning syscall.EINVAL otherwise.
+func punchHole(fd uintptr, offset, length int64) syscall.Errno {
+	args := fpunchhole{offset: offset, length: length}
+	_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, _F_PUNCHHOLE, uintptr(unsafe.Pointer(&args)))
+	if errno == syscall.ENOTSUP { // e.g. HFS+
+		return syscall.ENOSYS
+	}
+	return errno
+}
@@ -0,0 +1,27 @@
+package platform
+
+import "syscall"
+
+const (
+	// _FALLOC_FL_KEEP_SIZE keeps the file size when punching a hole at its
+	// end.
+	_FALLOC_FL_KEEP_SIZE = 0x1
+	// _FALLOC_FL_PUNCH_HOLE deallocates the range, which then reads as zeros.
+	_FALLOC_FL_PUNCH_HOLE = 0x2
+)
+
+// punchHole deallocates the range with `fallocate`.
+func punchHole(fd uintptr, offset, length int64) syscall.Errno {
+	for {
+		err := syscall.Fallocate(int(fd), _FALLOC_FL_PUNCH_HOLE|_FALLOC_FL_KEEP_SIZE, offset, length)
+		switch err {
+		case nil:
+			return 0
+		case syscall.EINTR:
+			continue
+		case syscall.EOPNOTSUPP: // e.g. tmpfs before Linux 3.5
+			return syscall.ENOSYS
+		}
+		return UnwrapOSError(err)
+	}
+}
@@ -0,0 +1,50 @@
+package platform
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestPunchHole_releasesBlocks(t *testing.T) {
+	const size = 1024 * 1024
+	content := bytes.Repeat([]byte{'a'}, size)
+	tmpPath := path.Join(t.TempDir(), "sparse")
+	require.NoError(t, os.WriteFile(tmpPath, content, 0o600))
+
+	f, errno := OpenFile(tmpPath, syscall.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	file := NewFsFile(tmpPath, syscall.O_RDWR, f)
+	defer file.Close()
+	require.EqualErrno(t, 0, file.Sync())
+	before := blocks(t, tmpPath)
+
+	if errno = file.PunchHole(0, size); errno == syscall.ENOSYS {
+		t.Skip("unsupported by the filesystem")
+	}
+	require.EqualErrno(t, 0, errno)
+	require.True(t, blocks(t, tmpPath) < before)
+
+	st, errno := file.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(size), st.Size)
+
+	// Truncate to shrink then extend also releases blocks.
+	_, errno = file.Pwrite(content, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, file.Sync())
+	require.EqualErrno(t, 0, file.Truncate(0))
+	require.EqualErrno(t, 0, file.Truncate(size))
+	require.True(t, blocks(t, tmpPath) < before)
+}
+
+// blocks returns the count of 512-byte blocks allocated to the file.
+func blocks(t *testing.T, path string) int64 {
+	var st syscall.Stat_t
+	require.NoError(t, syscall.Stat(path, &st))
+	return st.Blocks
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || linux)
+
+package platform
+
+import "syscall"
+
+// punchHole returns syscall.ENOSYS as there's no portable way to deallocate
+// a range on this platform.
+func punchHole(uintptr, int64, int64) syscall.Errno {
+	return syscall.ENOSYS
+}
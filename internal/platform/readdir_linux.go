@@ -0,0 +1,172 @@
+package platform
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// direntBufSize is the default size of the buffer passed to getdents64. This
+// is the same as the block size used by os.File.Readdir.
+const direntBufSize = 8192
+
+// Offsets of fields in struct linux_dirent64.
+// See https://man7.org/linux/man-pages/man2/getdents.2.html
+const (
+	direntInoOffset    = 0
+	direntOffOffset    = 8
+	direntReclenOffset = 16
+	direntTypeOffset   = 18
+	direntNameOffset   = 19
+)
+
+// Values of d_type in struct linux_dirent64.
+const (
+	_DT_UNKNOWN = 0
+	_DT_FIFO    = 1
+	_DT_CHR     = 2
+	_DT_DIR     = 4
+	_DT_BLK     = 6
+	_DT_REG     = 8
+	_DT_LNK     = 10
+	_DT_SOCK    = 12
+)
+
+// rawDir is the state of reading a directory with getdents64, which can
+// return more entries than requested.
+type rawDir struct {
+	buf        []byte
+	bufp, nbuf int // the read offset and length of valid data in buf
+	eof        bool
+}
+
+// readdirRaw reads directory entries with getdents64, avoiding the fs.FileInfo
+// os.File.Readdir creates per entry. This returns false if the file isn't an
+// os.File. When withCookie is true, Dirent.Cookie is set to `d_off`.
+func (f *fsFile) readdirRaw(n int, withCookie bool) (dirents []Dirent, ok bool, errno syscall.Errno) {
+	osf, ok := f.file.(*os.File)
+	if !ok {
+		return nil, false, 0
+	}
+
+	d := f.rawDir
+	if d == nil {
+		size := direntBufSize
+		if f.readdirBufSize > 0 {
+			size = f.readdirBufSize
+		}
+		d = &rawDir{buf: make([]byte, size)}
+		f.rawDir = d
+	}
+
+	fd := int(osf.Fd())
+	for !d.eof && (n <= 0 || len(dirents) < n) {
+		if d.bufp >= d.nbuf {
+			d.bufp = 0
+			var err error
+			d.nbuf, err = readDirent(fd, d.buf)
+			if errno = adjustReaddirErr(err); errno != 0 {
+				return
+			} else if d.nbuf <= 0 {
+				d.eof = true
+				break
+			}
+		}
+		var consumed int
+		consumed, dirents = parseDirents(osf.Name(), d.buf[d.bufp:d.nbuf], n, withCookie, dirents)
+		d.bufp += consumed
+	}
+	return
+}
+
+// seekDirRaw positions readdirRaw at `cookie`, a `d_off` from a prior entry,
+// like `seekdir`. This returns false if the file isn't an os.File.
+func (f *fsFile) seekDirRaw(cookie uint64) (ok bool, errno syscall.Errno) {
+	osf, ok := f.file.(*os.File)
+	if !ok {
+		return false, 0
+	}
+	if _, err := syscall.Seek(int(osf.Fd()), int64(cookie), io.SeekStart); err != nil {
+		return true, UnwrapOSError(err)
+	}
+	f.rawDir = nil // discard entries buffered at the prior position.
+	return true, 0
+}
+
+// readDirent calls getdents64, retrying on syscall.EINTR.
+func readDirent(fd int, buf []byte) (n int, err error) {
+	for {
+		if n, err = syscall.ReadDirent(fd, buf); err != syscall.EINTR {
+			return
+		}
+	}
+}
+
+// parseDirents appends entries in buf to dirents until there are n of them,
+// returning the count of bytes consumed. When n <= 0, buf is read fully.
+// When withCookie is true, Dirent.Cookie is set to `d_off`.
+//
+// When the filesystem doesn't report the file type (DT_UNKNOWN), it is read
+// with Lstat, relative to the directory path.
+func parseDirents(dir string, buf []byte, n int, withCookie bool, dirents []Dirent) (consumed int, _ []Dirent) {
+	for consumed+direntNameOffset <= len(buf) {
+		if n > 0 && len(dirents) >= n {
+			break
+		}
+		rec := buf[consumed:]
+		reclen := int(*(*uint16)(unsafe.Pointer(&rec[direntReclenOffset])))
+		if reclen == 0 || reclen > len(rec) {
+			consumed = len(buf) // corrupt: skip the remaining buffer.
+			break
+		}
+		consumed += reclen
+
+		ino := *(*uint64)(unsafe.Pointer(&rec[direntInoOffset]))
+		if ino == 0 {
+			continue // deleted entry
+		}
+
+		name := rec[direntNameOffset:reclen]
+		for i, c := range name {
+			if c == 0 {
+				name = name[:i]
+				break
+			}
+		}
+		if string(name) == "." || string(name) == ".." {
+			continue // same as os.File.Readdir
+		}
+
+		d := Dirent{Name: string(name), Ino: ino}
+		if withCookie {
+			d.Cookie = *(*uint64)(unsafe.Pointer(&rec[direntOffOffset]))
+		}
+		switch rec[direntTypeOffset] {
+		case _DT_REG:
+		case _DT_DIR:
+			d.Type = fs.ModeDir
+		case _DT_LNK:
+			d.Type = fs.ModeSymlink
+		case _DT_FIFO:
+			d.Type = fs.ModeNamedPipe
+		case _DT_SOCK:
+			d.Type = fs.ModeSocket
+		case _DT_CHR:
+			d.Type = fs.ModeDevice | fs.ModeCharDevice
+		case _DT_BLK:
+			d.Type = fs.ModeDevice
+		default: // _DT_UNKNOWN
+			if st, errno := lstat(dir + "/" + d.Name); errno == syscall.ENOENT {
+				continue // removed since read
+			} else if errno != 0 {
+				d.Type = fs.ModeIrregular
+			} else {
+				d.Type = st.Mode.Type()
+			}
+		}
+		dirents = append(dirents, d)
+	}
+	return consumed, dirents
+}
@@ -0,0 +1,93 @@
+package platform
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestParseDirents(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "unknown"), 0o700))
+
+	var buf []byte
+	buf = appendDirent(buf, 1, _DT_DIR, ".")
+	buf = appendDirent(buf, 2, _DT_DIR, "..")
+	buf = appendDirent(buf, 3, _DT_REG, "file")
+	buf = appendDirent(buf, 0, _DT_REG, "deleted")
+	buf = appendDirent(buf, 4, _DT_LNK, "link")
+	buf = appendDirent(buf, 5, _DT_UNKNOWN, "unknown")
+	buf = appendDirent(buf, 6, _DT_UNKNOWN, "removed")
+
+	consumed, dirents := parseDirents(tmpDir, buf, -1, false, nil)
+	require.Equal(t, len(buf), consumed)
+	require.Equal(t, []Dirent{
+		{Name: "file", Ino: 3},
+		{Name: "link", Ino: 4, Type: fs.ModeSymlink},
+		{Name: "unknown", Ino: 5, Type: fs.ModeDir}, // via lstat
+	}, dirents)
+
+	// Only consume up to the requested count.
+	consumed, dirents = parseDirents(tmpDir, buf, 1, false, nil)
+	require.Equal(t, []Dirent{{Name: "file", Ino: 3}}, dirents)
+	consumed2, dirents := parseDirents(tmpDir, buf[consumed:], 1, false, dirents[:0])
+	require.Equal(t, []Dirent{{Name: "link", Ino: 4, Type: fs.ModeSymlink}}, dirents)
+	require.True(t, consumed+consumed2 < len(buf))
+
+	// The cookie is d_off, when requested.
+	_, dirents = parseDirents(tmpDir, buf, 1, true, nil)
+	require.Equal(t, []Dirent{{Name: "file", Ino: 3, Cookie: 300}}, dirents)
+}
+
+// appendDirent encodes a struct linux_dirent64, padded to 8 bytes.
+func appendDirent(buf []byte, ino uint64, typ byte, name string) []byte {
+	reclen := (direntNameOffset + len(name) + 1 + 7) &^ 7
+	rec := make([]byte, reclen)
+	binary.LittleEndian.PutUint64(rec[direntInoOffset:], ino)
+	binary.LittleEndian.PutUint64(rec[direntOffOffset:], ino*100)
+	binary.LittleEndian.PutUint16(rec[direntReclenOffset:], uint16(reclen))
+	rec[direntTypeOffset] = typ
+	copy(rec[direntNameOffset:], name)
+	return append(buf, rec...)
+}
+
+func TestFsFileReaddir_raw(t *testing.T) {
+	tmpDir := t.TempDir()
+	const count = 1000 // enough to exceed direntBufSize
+	for i := 0; i < count; i++ {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, strconv.Itoa(i)), nil, 0o600))
+	}
+
+	f := openFsFile(t, tmpDir, syscall.O_RDONLY, 0)
+	defer f.Close()
+
+	var dirents []Dirent
+	for {
+		page, errno := f.Readdir(7)
+		require.EqualErrno(t, 0, errno)
+		if len(page) == 0 {
+			break
+		}
+		require.True(t, len(page) <= 7)
+		dirents = append(dirents, page...)
+	}
+	require.Equal(t, count, len(dirents))
+
+	names := make([]string, 0, count)
+	for _, d := range dirents {
+		require.NotEqual(t, uint64(0), d.Ino)
+		require.Zero(t, d.Type)
+		names = append(names, d.Name)
+	}
+	sort.Strings(names)
+	for i := 1; i < len(names); i++ {
+		require.NotEqual(t, names[i-1], names[i])
+	}
+}
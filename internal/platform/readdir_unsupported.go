@@ -0,0 +1,18 @@
+//go:build !linux
+
+package platform
+
+import "syscall"
+
+// rawDir is unused on this platform.
+type rawDir struct{}
+
+// readdirRaw returns false as there's no fast path on this platform.
+func (f *fsFile) readdirRaw(int, bool) ([]Dirent, bool, syscall.Errno) {
+	return nil, false, 0
+}
+
+// seekDirRaw returns false as there's no fast path on this platform.
+func (f *fsFile) seekDirRaw(uint64) (bool, syscall.Errno) {
+	return false, 0
+}
@@ -0,0 +1,64 @@
+package platform
+
+import (
+	"io"
+	"io/fs"
+	"syscall"
+)
+
+// ReadWriterAt is the combination of io.ReaderAt and io.WriterAt.
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// AsReadWriterAt adapts File to io.ReaderAt and io.WriterAt, for host code
+// that expects idiomatic Go errors instead of syscall.Errno.
+//
+// # Notes
+//
+//   - Non-zero syscall.Errno values are returned wrapped in fs.PathError,
+//     which UnwrapOSError converts back.
+//   - ReadAt returns io.EOF when the end of the file is reached before `p`
+//     is filled, per io.ReaderAt.
+//   - WriteAt returns io.ErrShortWrite if the file accepts zero bytes
+//     without an error.
+func AsReadWriterAt(f File) ReadWriterAt {
+	return &readWriterAt{f: f}
+}
+
+type readWriterAt struct {
+	f File
+}
+
+// ReadAt implements io.ReaderAt
+func (r *readWriterAt) ReadAt(p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		m, errno := r.f.Pread(p[n:], off+int64(n))
+		n += m
+		if errno != 0 {
+			return n, r.pathError("read", errno)
+		} else if m == 0 {
+			return n, io.EOF
+		}
+	}
+	return
+}
+
+// WriteAt implements io.WriterAt
+func (r *readWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		m, errno := r.f.Pwrite(p[n:], off+int64(n))
+		n += m
+		if errno != 0 {
+			return n, r.pathError("write", errno)
+		} else if m == 0 {
+			return n, io.ErrShortWrite
+		}
+	}
+	return
+}
+
+func (r *readWriterAt) pathError(op string, errno syscall.Errno) error {
+	return &fs.PathError{Op: op, Path: r.f.Path(), Err: errno}
+}
@@ -0,0 +1,55 @@
+package platform
+
+import (
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestAsReadWriterAt(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, wazeroFile)
+
+	f := openForWrite(t, file, []byte("wazero"))
+	defer f.Close()
+
+	rw := AsReadWriterAt(f)
+
+	buf := make([]byte, 4)
+	n, err := rw.ReadAt(buf, 1)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, "azer", string(buf))
+
+	// Reading past the end returns the partial count and io.EOF.
+	n, err = rw.ReadAt(buf, 4)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, "ro", string(buf[:n]))
+
+	n, err = rw.WriteAt([]byte("ZERO"), 2)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "waZERO", string(b))
+}
+
+func TestAsReadWriterAt_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, wazeroFile)
+	require.NoError(t, os.WriteFile(file, []byte("wazero"), 0o600))
+
+	f := openFsFile(t, file, syscall.O_RDONLY, 0)
+	defer f.Close()
+
+	rw := AsReadWriterAt(f)
+
+	_, err := rw.WriteAt([]byte("ZERO"), 0)
+	require.EqualErrno(t, syscall.EBADF, UnwrapOSError(err))
+}
@@ -0,0 +1,26 @@
+package platform
+
+import "syscall"
+
+// Rmdir removes the empty directory `path`.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.ENOENT: `path` doesn't exist.
+//   - syscall.ENOTDIR: `path` isn't a directory.
+//   - syscall.ENOTEMPTY: the directory isn't empty.
+//
+// # Notes
+//
+//   - This is like syscall.Rmdir and `rmdir` in POSIX, which allows a
+//     non-empty directory to fail with syscall.EEXIST instead. This
+//     normalizes it to syscall.ENOTEMPTY, as do the mappings of Windows
+//     errors. See https://pubs.opengroup.org/onlinepubs/9699919799/functions/rmdir.html
+func Rmdir(path string) syscall.Errno {
+	err := syscall.Rmdir(path)
+	if errno := UnwrapOSError(err); errno != syscall.EEXIST {
+		return errno
+	}
+	return syscall.ENOTEMPTY
+}
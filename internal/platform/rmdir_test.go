@@ -0,0 +1,37 @@
+package platform
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestRmdir(t *testing.T) {
+	tmpDir := t.TempDir()
+	dir := path.Join(tmpDir, "dir")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	file := path.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	t.Run("doesn't exist", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOENT, Rmdir(path.Join(tmpDir, "missing")))
+	})
+
+	t.Run("not empty", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOTEMPTY, Rmdir(dir))
+	})
+
+	t.Run("not directory", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOTDIR, Rmdir(file))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		require.NoError(t, os.Remove(file))
+		require.EqualErrno(t, 0, Rmdir(dir))
+		_, err := os.Stat(dir)
+		require.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,19 @@
+package platform
+
+// Whence values of File.Seek, in addition to those in the io package, which
+// find data and holes in sparse files. These have the same values as Darwin
+// and Solaris.
+//
+// The `offset` is relative to the start of the file, and the new offset is:
+//   - SeekHole: the start of the next hole at or after `offset`. The end of
+//     the file is an implicit hole, so this is never past the end.
+//   - SeekData: the start of the next data at or after `offset`.
+//
+// syscall.ENXIO is returned when `offset` is negative or beyond the end of
+// the file, or for SeekData when there is only a hole after `offset`.
+//
+// When the host doesn't support sparse files, the entire file is data.
+const (
+	SeekHole = 3
+	SeekData = 4
+)
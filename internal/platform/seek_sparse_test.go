@@ -0,0 +1,77 @@
+package platform
+
+import (
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFsFileSeek_Sparse(t *testing.T) {
+	const dataSize, size = 4096, 1 << 20
+
+	// Write data at the start and end of the file, leaving a hole between.
+	f := openFsFile(t, path.Join(t.TempDir(), "sparse"), syscall.O_RDWR|os.O_CREATE, 0o600)
+	defer f.Close()
+	data := make([]byte, dataSize)
+	_, errno := f.Pwrite(data, 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Pwrite(data, size-dataSize)
+	require.EqualErrno(t, 0, errno)
+
+	newOffset, errno := f.Seek(0, SeekData)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, newOffset)
+
+	// Filesystems without sparse file support report the whole file as data.
+	hole, errno := f.Seek(0, SeekHole)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, hole == size || (hole >= dataSize && hole <= size-dataSize), "hole=%d", hole)
+	if hole < size {
+		newOffset, errno = f.Seek(hole, SeekData)
+		require.EqualErrno(t, 0, errno)
+		require.True(t, newOffset > hole && newOffset <= size-dataSize, "data=%d", newOffset)
+	}
+
+	// The offset is updated.
+	newOffset, errno = f.Seek(0, io.SeekCurrent)
+	require.EqualErrno(t, 0, errno)
+	require.NotEqual(t, int64(0), newOffset)
+
+	_, errno = f.Seek(size, SeekData)
+	require.EqualErrno(t, syscall.ENXIO, errno)
+	_, errno = f.Seek(size, SeekHole)
+	require.EqualErrno(t, syscall.ENXIO, errno)
+	_, errno = f.Seek(-1, SeekData)
+	require.EqualErrno(t, syscall.EINVAL, errno)
+}
+
+func TestFsFileSeek_SparseEmulated(t *testing.T) {
+	// memFile has no file descriptor, so the whole file is data.
+	f := NewFsFile(wazeroFile, syscall.O_RDONLY, &memFile{data: []byte("wazero")})
+
+	newOffset, errno := f.Seek(2, SeekData)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(2), newOffset)
+
+	newOffset, errno = f.Seek(2, SeekHole)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), newOffset)
+
+	newOffset, errno = f.Seek(0, io.SeekCurrent)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), newOffset)
+
+	for _, whence := range []int{SeekData, SeekHole} {
+		_, errno = f.Seek(6, whence)
+		require.EqualErrno(t, syscall.ENXIO, errno)
+		_, errno = f.Seek(-1, whence)
+		require.EqualErrno(t, syscall.EINVAL, errno)
+	}
+
+	_, errno = f.Seek(0, SeekData+1)
+	require.EqualErrno(t, syscall.EINVAL, errno)
+}
@@ -0,0 +1,18 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// seekSparse calls lseek with the host value of SeekHole or SeekData.
+func seekSparse(fd uintptr, offset int64, whence int) (int64, syscall.Errno) {
+	if runtime.GOOS != "darwin" {
+		// Linux and FreeBSD define SEEK_DATA as 3 and SEEK_HOLE as 4.
+		whence = SeekHole + SeekData - whence
+	}
+	newOffset, err := syscall.Seek(int(fd), offset, whence)
+	return newOffset, UnwrapOSError(err)
+}
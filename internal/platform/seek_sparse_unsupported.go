@@ -0,0 +1,10 @@
+//go:build !(darwin || linux || freebsd)
+
+package platform
+
+import "syscall"
+
+// seekSparse returns syscall.ENOSYS, so the whole file is treated as data.
+func seekSparse(fd uintptr, offset int64, whence int) (int64, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
@@ -15,6 +15,10 @@ import "time"
 //
 //	This implementation *will not* update the pointed time.Duration value accordingly.
 //
+//	The precision of the timeout depends on the platform. Linux uses pselect6, which has
+//	nanosecond precision. Darwin rounds up to the microsecond precision of select(2), so
+//	that a positive timeout never becomes zero.
+//
 //	See also: https://github.com/golang/sys/blob/master/unix/syscall_unix_test.go#L606-L617
 //
 // # Notes on the Syscall
@@ -22,7 +26,7 @@ import "time"
 //	Because this is a blocking syscall, it will also block the carrier thread of the goroutine,
 //	preventing any means to support context cancellation directly.
 //
-//	There are ways to obviate this issue. We outline here one idea, that is however not currently implemented.
+//	There are ways to obviate this issue. We outline here one idea, which is implemented by NewContextFile.
 //	A common approach to support context cancellation is to add a signal file descriptor to the set,
 //	e.g. the read-end of a pipe or an eventfd on Linux.
 //	When the context is canceled, we may unblock a Select call by writing to the fd, causing it to return immediately.
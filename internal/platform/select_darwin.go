@@ -9,10 +9,13 @@ import (
 // syscall_select invokes select on Darwin, with the given timeout Duration.
 // We implement our own version instead of relying on syscall.Select because the latter
 // only returns the error and discards the result.
+//
+// Note: Darwin has no pselect syscall, so the timeout has microsecond
+// precision. See durationToTimeval for how it is rounded.
 func syscall_select(n int, r, w, e *FdSet, timeout *time.Duration) (int, error) {
 	var t *syscall.Timeval
 	if timeout != nil {
-		tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+		tv := durationToTimeval(*timeout)
 		t = &tv
 	}
 	result, _, errno := syscall_syscall6(
@@ -41,3 +44,14 @@ var libc_select_trampoline_addr uintptr
 // Note: CGO mechanisms are used in darwin regardless of the CGO_ENABLED value
 // or the "cgo" build flag. See /RATIONALE.md for why.
 //go:cgo_import_dynamic libc_select select "/usr/lib/libSystem.B.dylib"
+
+// durationToTimeval converts the timeout to the microsecond precision of
+// select, rounding up. Rounding down would convert a positive timeout under a
+// microsecond to zero, which returns immediately and causes callers to busy
+// loop.
+func durationToTimeval(d time.Duration) syscall.Timeval {
+	if rem := d % time.Microsecond; rem > 0 {
+		d += time.Microsecond - rem
+	}
+	return syscall.NsecToTimeval(d.Nanoseconds())
+}
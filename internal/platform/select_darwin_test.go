@@ -0,0 +1,26 @@
+package platform
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func Test_durationToTimeval(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected syscall.Timeval
+	}{
+		{d: 0, expected: syscall.Timeval{}},
+		{d: time.Nanosecond, expected: syscall.Timeval{Usec: 1}},
+		{d: 500 * time.Microsecond, expected: syscall.Timeval{Usec: 500}},
+		{d: 500*time.Microsecond + 1, expected: syscall.Timeval{Usec: 501}},
+		{d: time.Second, expected: syscall.Timeval{Sec: 1}},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.expected, durationToTimeval(tc.d), tc.d.String())
+	}
+}
@@ -3,14 +3,32 @@ package platform
 import (
 	"syscall"
 	"time"
+	"unsafe"
 )
 
-// syscall_select invokes select on Unix (unless Darwin), with the given timeout Duration.
+// syscall_select invokes pselect6 on Linux, with the given timeout Duration.
+//
+// This uses pselect6 instead of select, as its timespec has nanosecond
+// precision, where the timeval of select has microsecond precision. Notably,
+// select rounds a timeout under a microsecond down to zero, which returns
+// immediately and causes callers to busy loop.
 func syscall_select(n int, r, w, e *FdSet, timeout *time.Duration) (int, error) {
-	var t *syscall.Timeval
+	var t *syscall.Timespec
 	if timeout != nil {
-		tv := syscall.NsecToTimeval(timeout.Nanoseconds())
-		t = &tv
+		ts := syscall.NsecToTimespec(timeout.Nanoseconds())
+		t = &ts
 	}
-	return syscall.Select(n, (*syscall.FdSet)(r), (*syscall.FdSet)(w), (*syscall.FdSet)(e), t)
+	// The last parameter is the signal mask, which is nil to leave it as is.
+	result, _, errno := syscall.Syscall6(
+		syscall.SYS_PSELECT6,
+		uintptr(n),
+		uintptr(unsafe.Pointer(r)),
+		uintptr(unsafe.Pointer(w)),
+		uintptr(unsafe.Pointer(e)),
+		uintptr(unsafe.Pointer(t)),
+		0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(result), nil
 }
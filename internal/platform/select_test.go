@@ -59,6 +59,31 @@ func TestSelect(t *testing.T) {
 		}
 	})
 
+	t.Run("should wait for a sub-millisecond duration", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("only Linux has nanosecond precision")
+		}
+
+		// Scheduling can delay any one call, so take the fastest of several.
+		dur := 500 * time.Microsecond
+		fastest := time.Hour
+		for i := 0; i < 10; i++ {
+			d := dur
+			start := time.Now()
+			_, err := _select(0, nil, nil, nil, &d)
+			took := time.Since(start)
+			if err == syscall.EINTR {
+				continue
+			}
+			require.NoError(t, err)
+			require.True(t, took >= dur, "returned early after %v", took)
+			if took < fastest {
+				fastest = took
+			}
+		}
+		require.True(t, fastest < time.Millisecond, "fastest wait was %v", fastest)
+	})
+
 	t.Run("should return 1 if a given FD has data", func(t *testing.T) {
 		rr, ww, err := os.Pipe()
 		require.NoError(t, err)
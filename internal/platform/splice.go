@@ -0,0 +1,172 @@
+package platform
+
+import (
+	"io"
+	"syscall"
+)
+
+// spliceBufSize is the size of the buffer Splice copies through, when the
+// host can't move the data itself.
+const spliceBufSize = 32 * 1024
+
+// spliceFile is implemented by files which can be written from another file
+// without copying through a buffer of the caller, such as fsFile.
+type spliceFile interface {
+	Splice(src File, count int64) (int64, syscall.Errno)
+}
+
+// fsFileBacked is implemented by files which are or embed fsFile, such as
+// stdioFile.
+type fsFileBacked interface {
+	asFsFile() *fsFile
+}
+
+// asFsFile implements fsFileBacked.asFsFile
+func (f *fsFile) asFsFile() *fsFile {
+	return f
+}
+
+// Splice moves up to `count` bytes from the current offset of `src` to the
+// current offset of `dst`, advancing both, and returns the count moved. This
+// is for pipelines which move data between a pipe and a file, without
+// copying it through memory of the caller.
+//
+// # Errors
+//
+// A zero syscall.Errno is success, even if fewer than `count` bytes were
+// moved, such as at the end of `src`, or when a non-blocking pipe wasn't
+// ready. The below are expected otherwise:
+//   - syscall.ENOSYS: either file isn't backed by a file descriptor.
+//   - syscall.EBADF: either file is closed, `src` isn't readable or `dst`
+//     isn't writeable.
+//   - syscall.EINVAL: `count` is negative.
+//   - syscall.EISDIR: either file is a directory.
+//   - syscall.EAGAIN: nothing was moved, as a non-blocking file wasn't
+//     ready.
+//
+// # Notes
+//
+//   - This is like `splice` on Linux, which requires one file to be a pipe.
+//     See https://man7.org/linux/man-pages/man2/splice.2.html
+//   - Otherwise, such as between two regular files, or on other platforms,
+//     this copies through a buffer. If only part of what was read could be
+//     written, `src` is rewound, or if it can't be, such as a pipe, the rest
+//     is held and written first by the next Splice from `src` to `dst`.
+func Splice(dst, src File, count int64) (int64, syscall.Errno) {
+	if f, ok := dst.(spliceFile); ok {
+		return f.Splice(src, count)
+	}
+	return 0, syscall.ENOSYS
+}
+
+// Splice implements spliceFile.Splice
+func (f *fsFile) Splice(src File, count int64) (int64, syscall.Errno) {
+	b, ok := src.(fsFileBacked)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	s := b.asFsFile()
+	if errno := f.isDirErrno(); errno != 0 {
+		return 0, errno
+	} else if errno = s.isDirErrno(); errno != 0 {
+		return 0, errno
+	} else if s.accessMode == syscall.O_WRONLY || f.accessMode == syscall.O_RDONLY {
+		return 0, syscall.EBADF
+	} else if count < 0 {
+		return 0, syscall.EINVAL
+	} else if count == 0 {
+		return 0, 0
+	}
+
+	f.writeMux.Lock()
+	defer f.writeMux.Unlock()
+	f.stHint = nil
+
+	if n, errno := f.writeSpliceHeld(s, count); n > 0 || errno != 0 {
+		return n, errno
+	}
+
+	srcFd, ok := s.file.(fdFile)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+	dstFd, ok := f.file.(fdFile)
+	if !ok {
+		return 0, syscall.ENOSYS
+	}
+
+	// An emulated O_APPEND must seek before each write, so it is copied.
+	if !f.appendEmulated() {
+		n, errno := splice(srcFd.Fd(), dstFd.Fd(), count, s.nonblock || f.nonblock)
+		if errno != syscall.ENOSYS {
+			return n, errno
+		}
+	}
+	return f.spliceCopy(s, count)
+}
+
+// spliceCopy is the fallback of Splice, which copies through a buffer. The
+// caller holds writeMux.
+//
+// Like splice, this stops after a short read, so that it doesn't block on a
+// pipe after moving what was available.
+func (f *fsFile) spliceCopy(src *fsFile, count int64) (n int64, errno syscall.Errno) {
+	buf := make([]byte, spliceBufSize)
+	for n < count {
+		if remaining := count - n; remaining < int64(len(buf)) {
+			buf = buf[:remaining]
+		}
+		var read, written int
+		if read, errno = src.Read(buf); errno != 0 || read == 0 {
+			break
+		}
+		written, errno = f.write(buf[:read])
+		n += int64(written)
+		if written < read {
+			// The rest was already read from src, so must not be lost.
+			f.holdSplice(src, buf[written:read])
+			break
+		} else if errno != 0 || read < len(buf) {
+			break
+		}
+	}
+	if n > 0 {
+		return n, 0 // the error is returned by the next call
+	}
+	return 0, errno
+}
+
+// holdSplice rewinds `src` by the length of `unwritten`, or if it can't, such
+// as a pipe, holds a copy to write on the next Splice from `src`. The caller
+// holds writeMux.
+func (f *fsFile) holdSplice(src *fsFile, unwritten []byte) {
+	if _, errno := src.Seek(-int64(len(unwritten)), io.SeekCurrent); errno == 0 {
+		return
+	}
+	if f.spliceHeld == nil {
+		f.spliceHeld = map[*fsFile][]byte{}
+	}
+	f.spliceHeld[src] = append(f.spliceHeld[src], unwritten...)
+}
+
+// writeSpliceHeld writes up to `count` bytes held by holdSplice for `src`, and
+// returns the count written. Like Splice, an error after writing some bytes is
+// returned by the next call. The caller holds writeMux.
+func (f *fsFile) writeSpliceHeld(src *fsFile, count int64) (int64, syscall.Errno) {
+	held := f.spliceHeld[src]
+	if len(held) == 0 {
+		return 0, 0
+	} else if int64(len(held)) > count {
+		held = held[:count]
+	}
+	n, errno := f.write(held)
+	if rest := f.spliceHeld[src][n:]; len(rest) > 0 {
+		f.spliceHeld[src] = rest
+	} else {
+		delete(f.spliceHeld, src)
+	}
+	if n > 0 {
+		return int64(n), 0
+	}
+	return 0, errno
+}
@@ -0,0 +1,41 @@
+package platform
+
+import "syscall"
+
+// Flags of splice, which aren't defined in the syscall package.
+const (
+	_SPLICE_F_MOVE     = 0x1
+	_SPLICE_F_NONBLOCK = 0x2
+)
+
+// maxSplice limits the count of one splice, so that it fits in an int on
+// 32-bit platforms. This is more than a pipe holds.
+const maxSplice = 1 << 30
+
+// splice moves up to `count` bytes from `srcFd` to `dstFd` with the splice
+// syscall. This returns syscall.ENOSYS when neither is a pipe, as splice
+// returns syscall.EINVAL.
+//
+// Like read, this returns after one call, so that it doesn't block on a pipe
+// after moving what was available.
+func splice(srcFd, dstFd uintptr, count int64, nonblock bool) (int64, syscall.Errno) {
+	flags := _SPLICE_F_MOVE
+	if nonblock {
+		flags |= _SPLICE_F_NONBLOCK
+	}
+	if count > maxSplice {
+		count = maxSplice
+	}
+	var n int64
+	err := RetryOnEINTR(func() error {
+		moved, err := syscall.Splice(int(srcFd), nil, int(dstFd), nil, int(count), flags)
+		n = int64(moved)
+		return err
+	})
+	if errno := UnwrapOSError(err); errno == syscall.EINVAL {
+		return 0, syscall.ENOSYS // neither file is a pipe
+	} else if errno != 0 {
+		return 0, errno
+	}
+	return n, 0
+}
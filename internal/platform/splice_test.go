@@ -0,0 +1,185 @@
+package platform
+
+import (
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestSplice(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	open := func(name string, flag int) File {
+		p := path.Join(tmpDir, name)
+		f, errno := OpenFile(p, flag, 0o600)
+		require.EqualErrno(t, 0, errno)
+		return NewFsFile(p, flag, f)
+	}
+	pipe := func() (File, File) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		return NewFsFile("r", syscall.O_RDONLY, r), NewFsFile("w", syscall.O_WRONLY, w)
+	}
+	readAll := func(name string) string {
+		b, err := os.ReadFile(path.Join(tmpDir, name))
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	t.Run("pipe to file", func(t *testing.T) {
+		r, w := pipe()
+		defer r.Close()
+		defer w.Close()
+		dst := open("from_pipe", syscall.O_WRONLY|syscall.O_CREAT)
+		defer dst.Close()
+
+		_, errno := w.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+
+		// Only what is in the pipe is moved, without waiting for more.
+		n, errno := Splice(dst, r, 100)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(6), n)
+		require.Equal(t, "wazero", readAll("from_pipe"))
+	})
+
+	t.Run("file to pipe", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "to_pipe"), []byte("wazero"), 0o600))
+		r, w := pipe()
+		defer r.Close()
+		defer w.Close()
+		src := open("to_pipe", syscall.O_RDONLY)
+		defer src.Close()
+
+		n, errno := Splice(w, src, 4)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(4), n)
+		n, errno = Splice(w, src, 4)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(2), n)
+
+		// At the end of the file, nothing is moved.
+		n, errno = Splice(w, src, 4)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+
+		buf := make([]byte, 10)
+		read, errno := r.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "wazero", string(buf[:read]))
+	})
+
+	t.Run("file to file copies", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "src"), []byte("wazero"), 0o600))
+		src := open("src", syscall.O_RDONLY)
+		defer src.Close()
+		dst := open("dst", syscall.O_WRONLY|syscall.O_CREAT|syscall.O_APPEND)
+		defer dst.Close()
+
+		n, errno := Splice(dst, src, 100)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(6), n)
+		require.Equal(t, "wazero", readAll("dst"))
+	})
+
+	t.Run("stdin to file", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer w.Close()
+		stdin, err := NewStdioFile(true, r)
+		require.NoError(t, err)
+		defer r.Close()
+		dst := open("from_stdin", syscall.O_WRONLY|syscall.O_CREAT)
+		defer dst.Close()
+
+		_, err = w.Write([]byte("wazero"))
+		require.NoError(t, err)
+
+		n, errno := Splice(dst, stdin, 100)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(6), n)
+		require.Equal(t, "wazero", readAll("from_stdin"))
+	})
+
+	t.Run("short write rewinds a file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "rewind"), []byte("wazero"), 0o600))
+		src := open("rewind", syscall.O_RDONLY)
+		defer src.Close()
+		sw := &shortWriteFile{max: 100, capacity: 2}
+		dst := newFsFile("dst", syscall.O_WRONLY, sw)
+
+		n, errno := dst.spliceCopy(src.(*fsFile), 100)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(2), n)
+
+		off, errno := src.Seek(0, io.SeekCurrent)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(2), off)
+	})
+
+	t.Run("short write holds what a pipe can't rewind", func(t *testing.T) {
+		r, w := pipe()
+		defer r.Close()
+		defer w.Close()
+		sw := &shortWriteFile{max: 100, capacity: 2}
+		dst := newFsFile("dst", syscall.O_WRONLY, sw)
+
+		_, errno := w.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+
+		n, errno := dst.spliceCopy(r.(*fsFile), 100)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(2), n)
+
+		// The rest is written first by the next Splice, up to its count.
+		sw.capacity = 0
+		n, errno = Splice(dst, r, 3)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(3), n)
+		n, errno = Splice(dst, r, 100)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(1), n)
+		require.Equal(t, "wazero", string(sw.data))
+	})
+
+	t.Run("EAGAIN when a non-blocking pipe is empty", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("TODO: windows File.SetNonblock")
+		}
+		r, w := pipe()
+		defer r.Close()
+		defer w.Close()
+		require.EqualErrno(t, 0, r.SetNonblock(true))
+		dst := open("nonblock", syscall.O_WRONLY|syscall.O_CREAT)
+		defer dst.Close()
+
+		_, errno := Splice(dst, r, 100)
+		require.EqualErrno(t, syscall.EAGAIN, errno)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		src := open("src", syscall.O_RDONLY)
+		defer src.Close()
+		dst := open("dst", syscall.O_WRONLY)
+		defer dst.Close()
+		dir := open(".", syscall.O_RDONLY)
+		defer dir.Close()
+		mem := NewMemoryFile(nil, syscall.O_RDWR)
+
+		_, errno := Splice(mem, src, 1)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+		_, errno = Splice(dst, mem, 1)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+		_, errno = Splice(src, dst, 1)
+		require.EqualErrno(t, syscall.EBADF, errno)
+		_, errno = Splice(dst, dir, 1)
+		require.EqualErrno(t, syscall.EISDIR, errno)
+		_, errno = Splice(dst, src, -1)
+		require.EqualErrno(t, syscall.EINVAL, errno)
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package platform
+
+import "syscall"
+
+// splice returns syscall.ENOSYS as the splice syscall isn't supported.
+func splice(uintptr, uintptr, int64, bool) (int64, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
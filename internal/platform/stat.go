@@ -48,6 +48,24 @@ type Stat_t struct {
 
 	// Ctim is the last file status change timestamp in epoch nanoseconds.
 	Ctim int64
+
+	// Btime is the birth (creation) timestamp in epoch nanoseconds, or zero
+	// if unavailable.
+	//
+	// This is from statx on Linux, st_birthtimespec on Darwin and FreeBSD,
+	// and CreationTime on Windows. It is zero when the filesystem doesn't
+	// record it, and when the stat is from an fs.FileInfo without one, such
+	// as files in an fs.FS.
+	Btime int64
+}
+
+// SameFile returns true if both are the status of the same file, such as hard
+// links or a symbolic link and its target. Like os.SameFile, this compares
+// the device and inode.
+//
+// Note: This returns false when either inode is zero, as it is unknown.
+func SameFile(a, b Stat_t) bool {
+	return a.Ino != 0 && a.Ino == b.Ino && a.Dev == b.Dev
 }
 
 // Lstat is like syscall.Lstat. This returns syscall.ENOENT if the path doesn't
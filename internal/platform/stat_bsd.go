@@ -51,6 +51,8 @@ func statFromFileInfo(t fs.FileInfo) Stat_t {
 		st.Mtim = mtime.Sec*1e9 + mtime.Nsec
 		ctime := d.Ctimespec
 		st.Ctim = ctime.Sec*1e9 + ctime.Nsec
+		btime := d.Birthtimespec
+		st.Btime = btime.Sec*1e9 + btime.Nsec
 		return st
 	}
 	return statFromDefaultFileInfo(t)
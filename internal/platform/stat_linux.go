@@ -12,6 +12,9 @@ import (
 )
 
 func lstat(path string) (Stat_t, syscall.Errno) {
+	if st, errno := statx(_AT_FDCWD, path, _AT_SYMLINK_NOFOLLOW); errno != syscall.ENOSYS {
+		return st, errno
+	}
 	if t, err := os.Lstat(path); err != nil {
 		return Stat_t{}, UnwrapOSError(err)
 	} else {
@@ -20,6 +23,9 @@ func lstat(path string) (Stat_t, syscall.Errno) {
 }
 
 func stat(path string) (Stat_t, syscall.Errno) {
+	if st, errno := statx(_AT_FDCWD, path, 0); errno != syscall.ENOSYS {
+		return st, errno
+	}
 	if t, err := os.Stat(path); err != nil {
 		return Stat_t{}, UnwrapOSError(err)
 	} else {
@@ -28,6 +34,9 @@ func stat(path string) (Stat_t, syscall.Errno) {
 }
 
 func statFile(f fs.File) (Stat_t, syscall.Errno) {
+	if st, errno := fstatx(f); errno != syscall.ENOSYS {
+		return st, errno
+	}
 	return defaultStatFile(f)
 }
 
@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"syscall"
 	"testing"
+	gofstest "testing/fstest"
 	"time"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -271,6 +272,45 @@ func Test_StatFile_times(t *testing.T) {
 	}
 }
 
+func TestStat_btime(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(file, []byte{}, 0o600))
+
+	st, errno := Stat(file)
+	require.EqualErrno(t, 0, errno)
+	switch runtime.GOOS {
+	case "linux":
+		if st.Btime == 0 {
+			t.Skip("filesystem doesn't record birth time")
+		}
+	case "darwin", "freebsd", "windows":
+	default:
+		require.Zero(t, st.Btime)
+		return
+	}
+	require.NotEqual(t, int64(0), st.Btime)
+
+	// Changing times doesn't change the birth time.
+	require.NoError(t, os.Chtimes(file, time.Unix(123, 0), time.Unix(567, 0)))
+	lst, errno := Lstat(file)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st.Btime, lst.Btime)
+
+	f := openFsFile(t, file, syscall.O_RDONLY, 0)
+	defer f.Close()
+	fst, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st.Btime, fst.Btime)
+
+	// Stats from an fs.FS don't have a birth time.
+	mf, err := gofstest.MapFS{"file": {}}.Open("file")
+	require.NoError(t, err)
+	mst, errno := NewFsFile("file", syscall.O_RDONLY, mf).Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, mst.Btime)
+}
+
 func TestStatFile_dev_inode(t *testing.T) {
 	tmpDir := t.TempDir()
 	d := openFsFile(t, tmpDir, os.O_RDONLY, 0)
@@ -333,6 +373,39 @@ func TestStatFile_dev_inode(t *testing.T) {
 	require.Equal(t, st1.Ino, st1Again.Ino)
 }
 
+func TestSameFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	file, other := path.Join(tmpDir, "file"), path.Join(tmpDir, "other")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	require.NoError(t, os.WriteFile(other, nil, 0o600))
+
+	hardlink, symlink := path.Join(tmpDir, "hardlink"), path.Join(tmpDir, "symlink")
+	require.NoError(t, os.Link(file, hardlink))
+	require.NoError(t, os.Symlink(file, symlink))
+
+	stat := func(path string, follow bool) Stat_t {
+		var st Stat_t
+		var errno syscall.Errno
+		if follow {
+			st, errno = Stat(path)
+		} else {
+			st, errno = Lstat(path)
+		}
+		require.EqualErrno(t, 0, errno)
+		return st
+	}
+
+	st := stat(file, true)
+	require.True(t, SameFile(st, st))
+	require.True(t, SameFile(st, stat(hardlink, true)))
+	require.True(t, SameFile(st, stat(symlink, true)))
+	require.False(t, SameFile(st, stat(symlink, false)))
+	require.False(t, SameFile(st, stat(other, true)))
+
+	// Zero inodes are unknown, so never the same.
+	require.False(t, SameFile(Stat_t{}, Stat_t{}))
+}
+
 func requireDirectoryDevIno(t *testing.T, st Stat_t) {
 	// windows before go 1.20 has trouble reading the inode information on
 	// directories.
@@ -86,6 +86,7 @@ func statFromFileInfo(t fs.FileInfo) Stat_t {
 		st.Atim = d.LastAccessTime.Nanoseconds()
 		st.Mtim = d.LastWriteTime.Nanoseconds()
 		st.Ctim = d.CreationTime.Nanoseconds()
+		st.Btime = d.CreationTime.Nanoseconds()
 		return st
 	} else {
 		return statFromDefaultFileInfo(t)
@@ -111,7 +112,7 @@ func statHandle(h syscall.Handle) (Stat_t, syscall.Errno) {
 	}
 
 	switch { // check whether this is a symlink first
-	case fi.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0:
+	case fi.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0 && isLinkReparsePoint(h):
 		m |= fs.ModeSymlink
 	case winFt == syscall.FILE_TYPE_PIPE:
 		m |= fs.ModeNamedPipe
@@ -132,5 +133,6 @@ func statHandle(h syscall.Handle) (Stat_t, syscall.Errno) {
 	st.Atim = fi.LastAccessTime.Nanoseconds()
 	st.Mtim = fi.LastWriteTime.Nanoseconds()
 	st.Ctim = fi.CreationTime.Nanoseconds()
+	st.Btime = fi.CreationTime.Nanoseconds()
 	return st, 0
 }
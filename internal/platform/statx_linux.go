@@ -0,0 +1,140 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"io/fs"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_AT_EMPTY_PATH = 0x1000
+
+	// _STATX_BASIC_STATS are the fields also in struct stat.
+	_STATX_BASIC_STATS = 0x7ff
+	_STATX_BTIME       = 0x800
+
+	// sizeofStatx is sizeof(struct statx).
+	sizeofStatx = 256
+)
+
+// statxUnsupported is set when the kernel doesn't support statx (before
+// Linux 4.11), to avoid retrying it.
+var statxUnsupported uint32
+
+// statx returns the status of the file at `path` relative to `dirfd`, from
+// one statx call, so that all fields are from the same version of the file.
+// This returns syscall.ENOSYS when the kernel doesn't support statx, so the
+// caller should fall back to stat.
+func statx(dirfd int, path string, flags int) (Stat_t, syscall.Errno) {
+	if atomic.LoadUint32(&statxUnsupported) != 0 {
+		return Stat_t{}, syscall.ENOSYS
+	}
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return Stat_t{}, syscall.EINVAL
+	}
+	var buf [sizeofStatx]byte
+	err = RetryOnEINTR(func() error {
+		_, _, e := syscall.Syscall6(_SYS_STATX, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags),
+			_STATX_BASIC_STATS|_STATX_BTIME, uintptr(unsafe.Pointer(&buf[0])), 0)
+		if e != 0 {
+			return e
+		}
+		return nil
+	})
+	if errno := UnwrapOSError(err); errno == syscall.ENOSYS {
+		atomic.StoreUint32(&statxUnsupported, 1)
+		return Stat_t{}, errno
+	} else if errno != 0 {
+		return Stat_t{}, errno
+	}
+	return statFromStatx(&buf), 0
+}
+
+// statFromStatx converts struct statx to Stat_t, the same as statFromFileInfo
+// would convert the equivalent struct stat.
+func statFromStatx(buf *[sizeofStatx]byte) Stat_t {
+	u32 := func(off int) uint32 { return *(*uint32)(unsafe.Pointer(&buf[off])) }
+	u64 := func(off int) uint64 { return *(*uint64)(unsafe.Pointer(&buf[off])) }
+	// ts converts a struct statx_timestamp to epoch nanoseconds.
+	ts := func(off int) int64 { return int64(u64(off))*1e9 + int64(u32(off+8)) }
+
+	st := Stat_t{
+		Dev:   mkdev(u32(136), u32(140)),
+		Ino:   u64(32),
+		Uid:   u32(20),
+		Gid:   u32(24),
+		Mode:  fileModeFromUnix(uint32(*(*uint16)(unsafe.Pointer(&buf[28])))),
+		Nlink: uint64(u32(16)),
+		Size:  int64(u64(40)),
+		Atim:  ts(64),
+		Ctim:  ts(96),
+		Mtim:  ts(112),
+	}
+	if u32(0)&_STATX_BTIME != 0 { // stx_mask, as not all filesystems have it.
+		st.Btime = ts(80)
+	}
+	return st
+}
+
+// mkdev encodes a device ID like syscall.Stat_t Dev, which is makedev in
+// glibc.
+func mkdev(major, minor uint32) uint64 {
+	dev := (uint64(major) & 0x00000fff) << 8
+	dev |= (uint64(major) & 0xfffff000) << 32
+	dev |= (uint64(minor) & 0x000000ff) << 0
+	dev |= (uint64(minor) & 0xffffff00) << 12
+	return dev
+}
+
+// fileModeFromUnix converts st_mode to fs.FileMode, the same as os.Stat.
+func fileModeFromUnix(mode uint32) fs.FileMode {
+	m := fs.FileMode(mode & 0o777)
+	switch mode & syscall.S_IFMT {
+	case syscall.S_IFBLK:
+		m |= fs.ModeDevice
+	case syscall.S_IFCHR:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	case syscall.S_IFDIR:
+		m |= fs.ModeDir
+	case syscall.S_IFIFO:
+		m |= fs.ModeNamedPipe
+	case syscall.S_IFLNK:
+		m |= fs.ModeSymlink
+	case syscall.S_IFSOCK:
+		m |= fs.ModeSocket
+	}
+	if mode&syscall.S_ISGID != 0 {
+		m |= fs.ModeSetgid
+	}
+	if mode&syscall.S_ISUID != 0 {
+		m |= fs.ModeSetuid
+	}
+	if mode&syscall.S_ISVTX != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
+
+// fstatx is statx of the open file `f`, or syscall.ENOSYS if `f` has no file
+// descriptor.
+func fstatx(f fs.File) (st Stat_t, errno syscall.Errno) {
+	// Use syscall.Conn, as os.File Fd would put the file in blocking mode.
+	c, ok := f.(syscall.Conn)
+	if !ok {
+		return Stat_t{}, syscall.ENOSYS
+	}
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return Stat_t{}, syscall.ENOSYS
+	}
+	if err = rc.Control(func(fd uintptr) {
+		st, errno = statx(int(fd), "", _AT_EMPTY_PATH)
+	}); err != nil {
+		return Stat_t{}, UnwrapOSError(err) // e.g. the file was closed.
+	}
+	return
+}
@@ -0,0 +1,4 @@
+package platform
+
+// _SYS_STATX isn't defined in the syscall package on amd64.
+const _SYS_STATX = 332
@@ -0,0 +1,6 @@
+//go:build (arm64 || riscv64) && linux
+
+package platform
+
+// _SYS_STATX isn't defined in the syscall package on arm64 or riscv64.
+const _SYS_STATX = 291
@@ -0,0 +1,66 @@
+//go:build (amd64 || arm64 || riscv64) && linux
+
+package platform
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// TestStatx_matchesStat ensures fields built from statx are the same as
+// those from os.Stat, which uses fstatat.
+func TestStatx_matchesStat(t *testing.T) {
+	if _, errno := Stat("."); errno == 0 && statxUnsupported != 0 {
+		t.Skip("statx unsupported")
+	}
+	tmpDir := t.TempDir()
+	file := path.Join(tmpDir, "file")
+	require.NoError(t, os.WriteFile(file, []byte("wazero"), 0o640))
+	link := path.Join(tmpDir, "link")
+	require.NoError(t, os.Symlink(file, link))
+	require.NoError(t, os.Chmod(tmpDir, 0o700|os.ModeSticky))
+
+	for _, p := range []string{tmpDir, file, link, "/dev/null"} {
+		p := p
+		t.Run(p, func(t *testing.T) {
+			t.Run("Stat", func(t *testing.T) {
+				st, errno := Stat(p)
+				require.EqualErrno(t, 0, errno)
+				fi, err := os.Stat(p)
+				require.NoError(t, err)
+				requireStatEqual(t, statFromFileInfo(fi), st)
+			})
+			t.Run("Lstat", func(t *testing.T) {
+				st, errno := Lstat(p)
+				require.EqualErrno(t, 0, errno)
+				fi, err := os.Lstat(p)
+				require.NoError(t, err)
+				requireStatEqual(t, statFromFileInfo(fi), st)
+			})
+			t.Run("StatFile", func(t *testing.T) {
+				f := openFsFile(t, p, syscall.O_RDONLY, 0)
+				defer f.Close()
+				st, errno := f.Stat()
+				require.EqualErrno(t, 0, errno)
+				fi, err := os.Stat(p)
+				require.NoError(t, err)
+				requireStatEqual(t, statFromFileInfo(fi), st)
+			})
+		})
+	}
+
+	_, errno := Stat(path.Join(tmpDir, "missing"))
+	require.EqualErrno(t, syscall.ENOENT, errno)
+}
+
+// requireStatEqual compares all fields except the access time, which reads
+// can change, and the birth time, which os.Stat doesn't have.
+func requireStatEqual(t *testing.T, expected, actual Stat_t) {
+	expected.Atim, actual.Atim = 0, 0
+	actual.Btime = 0
+	require.Equal(t, expected, actual)
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package platform
+
+import (
+	"os"
+	"syscall"
+)
+
+// Symlink creates a symbolic link at `link`, whose contents are `target`.
+//
+// The `target` isn't resolved, so it can be relative to the directory of
+// `link` or not exist.
+func Symlink(target, link string) syscall.Errno {
+	return UnwrapOSError(os.Symlink(target, link))
+}
+
+// Readlink returns the contents of the symbolic link at `path`.
+func Readlink(path string) (string, syscall.Errno) {
+	// os.Readlink retries with a larger buffer when the link is long.
+	dst, err := os.Readlink(path)
+	if err != nil {
+		return "", UnwrapOSError(err)
+	}
+	return dst, 0
+}
+
+// ReadlinkInto writes the contents of the symbolic link at `path` into `buf`,
+// truncating them if longer, and returns the count of bytes written.
+func ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	n, err := syscall.Readlink(path, buf)
+	if err != nil {
+		return 0, UnwrapOSError(err)
+	}
+	return n, 0
+}
@@ -0,0 +1,53 @@
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "dir", "file"), []byte("wazero"), 0o600))
+
+	tests := []struct{ name, target string }{
+		{name: "file", target: "dir/file"},
+		{name: "dir", target: "dir"},
+		{name: "missing", target: "missing"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			link := path.Join(tmpDir, tc.name+"-link")
+			if errno := Symlink(tc.target, link); errno == syscall.EPERM {
+				t.Skip("no privilege to create symbolic links")
+			} else {
+				require.EqualErrno(t, 0, errno)
+			}
+			require.EqualErrno(t, syscall.EEXIST, Symlink(tc.target, link))
+
+			st, errno := Lstat(link)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, fs.ModeSymlink, st.Mode.Type())
+
+			dst, errno := Readlink(link)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, tc.target, ToPosixPath(dst))
+
+			require.EqualErrno(t, 0, Unlink(link))
+		})
+	}
+
+	t.Run("not a link", func(t *testing.T) {
+		_, errno := Readlink(path.Join(tmpDir, "dir", "file"))
+		require.EqualErrno(t, syscall.EINVAL, errno)
+		_, errno = Readlink(path.Join(tmpDir, "dir"))
+		require.EqualErrno(t, syscall.EINVAL, errno)
+	})
+}
@@ -0,0 +1,270 @@
+package platform
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+)
+
+const (
+	// ERROR_INVALID_PARAMETER is a Windows error returned by
+	// CreateSymbolicLink before Windows 10 1703, which doesn't support
+	// _SYMBOLIC_LINK_FLAG_ALLOW_UNPRIVILEGED_CREATE.
+	ERROR_INVALID_PARAMETER = syscall.Errno(0x57)
+
+	// _SYMBOLIC_LINK_FLAG_ALLOW_UNPRIVILEGED_CREATE allows creating symbolic
+	// links without SeCreateSymbolicLinkPrivilege, in developer mode.
+	_SYMBOLIC_LINK_FLAG_ALLOW_UNPRIVILEGED_CREATE = 0x2
+
+	// _IO_REPARSE_TAG_MOUNT_POINT is the reparse tag of a junction.
+	_IO_REPARSE_TAG_MOUNT_POINT = 0xA0000003
+
+	// _FSCTL_SET_REPARSE_POINT is the DeviceIoControl code to write a reparse
+	// point, used to create junctions.
+	_FSCTL_SET_REPARSE_POINT = 0x900A4
+
+	// _SYMLINK_FLAG_RELATIVE is set in a symbolic link reparse point when its
+	// target is relative to the directory of the link.
+	_SYMLINK_FLAG_RELATIVE = 0x1
+)
+
+// Symlink creates a symbolic link at `link`, whose contents are `target`.
+//
+// The `target` isn't resolved, so it can be relative to the directory of
+// `link` or not exist.
+//
+// # Notes
+//
+//   - This uses CreateSymbolicLink, marking the link as a directory when
+//     `target` is one, as Windows requires.
+//   - Without SeCreateSymbolicLinkPrivilege or developer mode, a link to an
+//     existing directory falls back to a junction, which needs no privilege.
+//     Readlink of a junction returns the absolute path of `target`.
+//   - Otherwise, lacking privilege returns syscall.EPERM.
+func Symlink(target, link string) syscall.Errno {
+	winTarget := filepath.FromSlash(target)
+	isDir := isDirTarget(winTarget, link)
+
+	var flags uint32
+	if isDir {
+		flags = syscall.SYMBOLIC_LINK_FLAG_DIRECTORY
+	}
+	linkp, err := syscall.UTF16PtrFromString(link)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	targetp, err := syscall.UTF16PtrFromString(winTarget)
+	if err != nil {
+		return syscall.EINVAL
+	}
+
+	err = syscall.CreateSymbolicLink(linkp, targetp, flags|_SYMBOLIC_LINK_FLAG_ALLOW_UNPRIVILEGED_CREATE)
+	if err == ERROR_INVALID_PARAMETER { // older Windows doesn't support the flag.
+		err = syscall.CreateSymbolicLink(linkp, targetp, flags)
+	}
+	if err == ERROR_PRIVILEGE_NOT_HELD && isDir {
+		return createJunction(winTarget, link)
+	}
+	return UnwrapOSError(err)
+}
+
+// isDirTarget returns true if `target` ends with a separator or is an
+// existing directory, resolving it relative to the directory of `link`.
+func isDirTarget(target, link string) bool {
+	if strings.HasSuffix(target, `\`) {
+		return true
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	st, err := os.Stat(target)
+	return err == nil && st.IsDir()
+}
+
+// createJunction creates a junction at `link` to the directory `target`.
+func createJunction(target, link string) syscall.Errno {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return UnwrapOSError(err)
+	}
+
+	if err = os.Mkdir(link, 0o777); err != nil {
+		return UnwrapOSError(err)
+	}
+	h, errno := openReparsePoint(link, syscall.GENERIC_WRITE)
+	if errno == 0 {
+		buf := junctionReparseData(target)
+		var n uint32
+		err = syscall.DeviceIoControl(h, _FSCTL_SET_REPARSE_POINT, &buf[0], uint32(len(buf)), nil, 0, &n, nil)
+		syscall.CloseHandle(h)
+		errno = UnwrapOSError(err)
+	}
+	if errno != 0 {
+		_ = os.Remove(link)
+	}
+	return errno
+}
+
+// junctionReparseData returns the REPARSE_DATA_BUFFER of a junction to the
+// absolute path `target`.
+func junctionReparseData(target string) []byte {
+	substitute := utf16.Encode([]rune(`\??\` + target))
+	printName := utf16.Encode([]rune(target))
+	subLen, printLen := len(substitute)*2, len(printName)*2
+
+	// The path buffer has both names, each terminated by NUL.
+	pathBuf := make([]byte, subLen+2+printLen+2)
+	for i, c := range substitute {
+		binary.LittleEndian.PutUint16(pathBuf[i*2:], c)
+	}
+	for i, c := range printName {
+		binary.LittleEndian.PutUint16(pathBuf[subLen+2+i*2:], c)
+	}
+
+	buf := make([]byte, 16, 16+len(pathBuf))
+	binary.LittleEndian.PutUint32(buf[0:], _IO_REPARSE_TAG_MOUNT_POINT)
+	binary.LittleEndian.PutUint16(buf[4:], uint16(8+len(pathBuf))) // ReparseDataLength
+	binary.LittleEndian.PutUint16(buf[8:], 0)                      // SubstituteNameOffset
+	binary.LittleEndian.PutUint16(buf[10:], uint16(subLen))        // SubstituteNameLength
+	binary.LittleEndian.PutUint16(buf[12:], uint16(subLen+2))      // PrintNameOffset
+	binary.LittleEndian.PutUint16(buf[14:], uint16(printLen))      // PrintNameLength
+	return append(buf, pathBuf...)
+}
+
+// Readlink returns the contents of the symbolic link or junction at `path`.
+//
+// This reads the reparse point with DeviceIoControl(FSCTL_GET_REPARSE_POINT),
+// returning syscall.EINVAL if `path` isn't a symbolic link or junction.
+func Readlink(path string) (string, syscall.Errno) {
+	h, errno := openReparsePoint(path, 0)
+	if errno != 0 {
+		return "", errno
+	}
+	defer syscall.CloseHandle(h)
+
+	buf, errno := readReparsePoint(h)
+	if errno != 0 {
+		return "", errno
+	}
+	return parseReparseTarget(buf)
+}
+
+// ReadlinkInto writes the contents of the symbolic link or junction at `path`
+// into `buf`, truncating them if longer, and returns the count of bytes
+// written. Unlike Readlink, the path separator is normalized to "/".
+func ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	dst, errno := Readlink(path)
+	if errno != 0 {
+		return 0, errno
+	}
+	return copy(buf, ToPosixPath(dst)), 0
+}
+
+// openReparsePoint opens `path` without following any reparse point, so that
+// it can be read or written with DeviceIoControl.
+func openReparsePoint(path string, access uint32) (syscall.Handle, syscall.Errno) {
+	pathp, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, syscall.EINVAL
+	}
+	attrs := uint32(syscall.FILE_FLAG_BACKUP_SEMANTICS | syscall.FILE_FLAG_OPEN_REPARSE_POINT)
+	h, err := syscall.CreateFile(pathp, access, 0, nil, syscall.OPEN_EXISTING, attrs, 0)
+	if err != nil {
+		return 0, UnwrapOSError(err)
+	}
+	return h, 0
+}
+
+// readReparsePoint returns the REPARSE_DATA_BUFFER of the handle, or
+// syscall.EINVAL if it isn't a reparse point.
+func readReparsePoint(h syscall.Handle) ([]byte, syscall.Errno) {
+	buf := make([]byte, syscall.MAXIMUM_REPARSE_DATA_BUFFER_SIZE)
+	var n uint32
+	err := syscall.DeviceIoControl(h, syscall.FSCTL_GET_REPARSE_POINT, nil, 0, &buf[0], uint32(len(buf)), &n, nil)
+	if err != nil {
+		// ERROR_NOT_A_REPARSE_POINT is the typical error here.
+		return nil, syscall.EINVAL
+	} else if n < 8 {
+		return nil, syscall.EIO
+	}
+	return buf[:n], 0
+}
+
+// isLinkReparsePoint returns true if the handle is a symbolic link or
+// junction, as opposed to another reparse point, such as a deduplicated or
+// cloud file, which behaves like a regular file or directory.
+func isLinkReparsePoint(h syscall.Handle) bool {
+	buf, errno := readReparsePoint(h)
+	if errno != 0 {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(buf) {
+	case syscall.IO_REPARSE_TAG_SYMLINK, _IO_REPARSE_TAG_MOUNT_POINT:
+		return true
+	}
+	return false
+}
+
+// parseReparseTarget returns the target of a symbolic link or junction,
+// given its REPARSE_DATA_BUFFER.
+func parseReparseTarget(buf []byte) (string, syscall.Errno) {
+	if len(buf) < 16 {
+		return "", syscall.EIO
+	}
+	tag := binary.LittleEndian.Uint32(buf)
+	subOff := int(binary.LittleEndian.Uint16(buf[8:]))
+	subLen := int(binary.LittleEndian.Uint16(buf[10:]))
+	printOff := int(binary.LittleEndian.Uint16(buf[12:]))
+	printLen := int(binary.LittleEndian.Uint16(buf[14:]))
+
+	var pathBuf []byte
+	var relative bool
+	switch tag {
+	case syscall.IO_REPARSE_TAG_SYMLINK:
+		if len(buf) < 20 {
+			return "", syscall.EIO
+		}
+		relative = binary.LittleEndian.Uint32(buf[16:])&_SYMLINK_FLAG_RELATIVE != 0
+		pathBuf = buf[20:]
+	case _IO_REPARSE_TAG_MOUNT_POINT:
+		pathBuf = buf[16:]
+	default:
+		return "", syscall.EINVAL // not a link
+	}
+
+	// Prefer the print name, as the substitute name is an NT path, such as
+	// `\??\C:\dir`, which isn't usable by the guest.
+	if printLen > 0 {
+		if s, ok := decodeUTF16(pathBuf, printOff, printLen); ok {
+			return s, 0
+		}
+		return "", syscall.EIO
+	}
+	s, ok := decodeUTF16(pathBuf, subOff, subLen)
+	if !ok {
+		return "", syscall.EIO
+	}
+	if !relative {
+		s = strings.TrimPrefix(s, `\??\`)
+	}
+	return s, 0
+}
+
+// decodeUTF16 decodes `length` bytes at `offset` of the little-endian UTF-16
+// `buf`, or returns false if they are out of range.
+func decodeUTF16(buf []byte, offset, length int) (string, bool) {
+	if offset+length > len(buf) || length%2 != 0 {
+		return "", false
+	}
+	u := make([]uint16, length/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(buf[offset+i*2:])
+	}
+	return string(utf16.Decode(u)), true
+}
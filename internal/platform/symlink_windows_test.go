@@ -0,0 +1,60 @@
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestCreateJunction(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := path.Join(tmpDir, "dir")
+	require.NoError(t, os.Mkdir(target, 0o700))
+	require.NoError(t, os.WriteFile(path.Join(target, "file"), []byte("wazero"), 0o600))
+
+	link := path.Join(tmpDir, "junction")
+	require.EqualErrno(t, 0, createJunction(target, link))
+
+	// A junction is reported as a symbolic link, like unix.
+	st, errno := Lstat(link)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeSymlink, st.Mode.Type())
+
+	// It is followed like one, too.
+	st, errno = Stat(link)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, st.Mode.IsDir())
+	b, err := os.ReadFile(path.Join(link, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+
+	dst, errno := Readlink(link)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, ToPosixPath(target), ToPosixPath(dst))
+
+	// Unlinking removes the junction, not the target.
+	require.EqualErrno(t, 0, Unlink(link))
+	_, err = os.Stat(path.Join(target, "file"))
+	require.NoError(t, err)
+}
+
+func TestParseReparseTarget(t *testing.T) {
+	buf := junctionReparseData(`C:\dir`)
+	dst, errno := parseReparseTarget(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, `C:\dir`, dst)
+
+	// Without a print name, the NT prefix is removed from the substitute name.
+	buf[14], buf[15] = 0, 0 // PrintNameLength
+	dst, errno = parseReparseTarget(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, `C:\dir`, dst)
+
+	// Truncated buffers are an I/O error, not a panic.
+	_, errno = parseReparseTarget(buf[:20])
+	require.EqualErrno(t, syscall.EIO, errno)
+}
@@ -9,7 +9,7 @@ import (
 
 func sync(f fs.File) syscall.Errno {
 	if s, ok := f.(syncFile); ok {
-		return UnwrapOSError(s.Sync())
+		return UnwrapOSError(RetryOnEINTR(s.Sync))
 	}
 	return 0
 }
@@ -7,7 +7,7 @@ import (
 
 func sync(f fs.File) syscall.Errno {
 	if s, ok := f.(syncFile); ok {
-		errno := UnwrapOSError(s.Sync())
+		errno := UnwrapOSError(RetryOnEINTR(s.Sync))
 		// Coerce error performing stat on a directory to 0, as it won't work
 		// on Windows.
 		switch errno {
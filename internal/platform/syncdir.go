@@ -0,0 +1,16 @@
+package platform
+
+import "syscall"
+
+// SyncDir flushes the entries of the directory `path` to storage, like
+// `fsync` on a directory opened for read.
+//
+// Note: Windows can't sync a directory, so this only checks it exists.
+func SyncDir(path string) syscall.Errno {
+	f, errno := OpenFile(path, syscall.O_RDONLY|O_DIRECTORY, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer f.Close()
+	return sync(f)
+}
@@ -0,0 +1,86 @@
+package platform
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// terminalFile is implemented by files which can be a terminal, such as
+// fsFile.
+type terminalFile interface {
+	isTerminal() (bool, syscall.Errno)
+}
+
+// IsTerminal returns true if `f` is a terminal, such as stdin of an
+// interactive shell. Regular files, directories and pipes are not.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.EBADF: the file was closed.
+//
+// # Notes
+//
+//   - This is like `isatty` in POSIX, implemented with `tcgetattr` on unix
+//     and `GetConsoleMode` on Windows. See
+//     https://pubs.opengroup.org/onlinepubs/9699919799/functions/isatty.html
+//   - Files which don't implement this, such as fs.FS files, return false.
+func IsTerminal(f File) (bool, syscall.Errno) {
+	if f, ok := f.(terminalFile); ok {
+		return f.isTerminal()
+	}
+	return false, 0
+}
+
+// isTerminal implements terminalFile.isTerminal
+func (f *fsFile) isTerminal() (bool, syscall.Errno) {
+	if f.isClosed() {
+		return false, syscall.EBADF
+	} else if fd, ok := f.file.(fdFile); ok {
+		return isTerminal(fd.Fd()), 0
+	}
+	return false, 0
+}
+
+// terminalMode returns `mode` as a character device when `f` is a terminal,
+// as some hosts report a console as another type, e.g. Windows.
+func terminalMode(f fs.File, mode fs.FileMode) fs.FileMode {
+	if fd, ok := f.(fdFile); ok && isTerminal(fd.Fd()) {
+		return fs.ModeDevice | fs.ModeCharDevice | mode.Perm()
+	}
+	return mode
+}
+
+// SetTerminalRaw implements File.SetTerminalRaw
+func (f *fsFile) SetTerminalRaw(enable bool) syscall.Errno {
+	if f.isClosed() {
+		return syscall.EBADF
+	}
+	fd, ok := f.file.(fdFile)
+	if !ok {
+		return syscall.ENOTTY
+	}
+	st, errno := getTermState(fd.Fd())
+	if errno != 0 {
+		return syscall.ENOTTY
+	}
+	if f.savedTerm == nil {
+		f.savedTerm = &st
+	}
+	if enable {
+		return setTermState(fd.Fd(), rawTermState(*f.savedTerm))
+	}
+	return setTermState(fd.Fd(), *f.savedTerm)
+}
+
+// restoreTerminal restores the terminal state from before the first
+// SetTerminalRaw, if called.
+func (f *fsFile) restoreTerminal() {
+	if f.savedTerm == nil {
+		return
+	}
+	if fd, ok := f.file.(fdFile); ok {
+		_ = setTermState(fd.Fd(), *f.savedTerm)
+	}
+	f.savedTerm = nil
+}
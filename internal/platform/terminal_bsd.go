@@ -0,0 +1,11 @@
+//go:build darwin || freebsd
+
+package platform
+
+import "syscall"
+
+// ioctl requests of `tcgetattr` and `tcsetattr`.
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,9 @@
+package platform
+
+import "syscall"
+
+// ioctl requests of `tcgetattr` and `tcsetattr`.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
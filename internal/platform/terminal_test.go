@@ -0,0 +1,116 @@
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestIsTerminal(t *testing.T) {
+	tmpPath := path.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(tmpPath, nil, 0o600))
+
+	t.Run("regular file", func(t *testing.T) {
+		f, errno := OpenFile(tmpPath, syscall.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		file := NewFsFile(tmpPath, syscall.O_RDONLY, f)
+
+		isTerminal, errno := IsTerminal(file)
+		require.EqualErrno(t, 0, errno)
+		require.False(t, isTerminal)
+
+		require.EqualErrno(t, 0, file.Close())
+		_, errno = IsTerminal(file)
+		require.EqualErrno(t, syscall.EBADF, errno)
+	})
+
+	t.Run("pipe", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer w.Close()
+		file := NewFsFile("", syscall.O_RDONLY, r)
+		defer file.Close()
+
+		isTerminal, errno := IsTerminal(file)
+		require.EqualErrno(t, 0, errno)
+		require.False(t, isTerminal)
+	})
+
+	t.Run("memory file", func(t *testing.T) {
+		isTerminal, errno := IsTerminal(NewMemoryFile(nil, syscall.O_RDWR))
+		require.EqualErrno(t, 0, errno)
+		require.False(t, isTerminal)
+	})
+
+	t.Run("pty", func(t *testing.T) {
+		pty := openPty(t)
+		defer pty.Close()
+
+		isTerminal, errno := IsTerminal(pty)
+		require.EqualErrno(t, 0, errno)
+		require.True(t, isTerminal)
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		stdin, err := NewStdioFile(true, os.Stdin)
+		require.NoError(t, err)
+		isTerminal, errno := IsTerminal(stdin)
+		require.EqualErrno(t, 0, errno)
+		if !isTerminal {
+			t.Skip("stdin isn't a terminal")
+		}
+
+		// A terminal is a character device, e.g. for WASI fd_filestat_get.
+		st, errno := stdin.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.ModeDevice|fs.ModeCharDevice, st.Mode.Type())
+	})
+}
+
+func TestFsFile_SetTerminalRaw(t *testing.T) {
+	tmpPath := path.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(tmpPath, nil, 0o600))
+
+	t.Run("ENOTTY", func(t *testing.T) {
+		f, errno := OpenFile(tmpPath, syscall.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		file := NewFsFile(tmpPath, syscall.O_RDONLY, f)
+		require.EqualErrno(t, syscall.ENOTTY, file.SetTerminalRaw(true))
+
+		require.EqualErrno(t, 0, file.Close())
+		require.EqualErrno(t, syscall.EBADF, file.SetTerminalRaw(true))
+	})
+
+	t.Run("pty", func(t *testing.T) {
+		pty := openPty(t)
+		fd := pty.(*fsFile).file.(fdFile).Fd()
+		cooked, errno := getTermState(fd)
+		require.EqualErrno(t, 0, errno)
+
+		require.EqualErrno(t, 0, pty.SetTerminalRaw(true))
+		st, errno := getTermState(fd)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, rawTermState(cooked), st)
+
+		// Disabling restores the original state.
+		require.EqualErrno(t, 0, pty.SetTerminalRaw(false))
+		st, errno = getTermState(fd)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, cooked, st)
+
+		// Close restores it, even though stdio files remain open.
+		stdio, err := NewStdioFile(true, pty.(*fsFile).file)
+		require.NoError(t, err)
+		require.EqualErrno(t, 0, stdio.SetTerminalRaw(true))
+		require.EqualErrno(t, 0, stdio.Close())
+		st, errno = getTermState(fd)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, cooked, st)
+
+		require.EqualErrno(t, 0, pty.Close())
+	})
+}
@@ -0,0 +1,38 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// termState is the state of a terminal changed by File.SetTerminalRaw.
+type termState = syscall.Termios
+
+// isTerminal returns true if `tcgetattr` succeeds on `fd`.
+func isTerminal(fd uintptr) bool {
+	_, errno := getTermState(fd)
+	return errno == 0
+}
+
+// getTermState reads the state of the terminal `fd` with `tcgetattr`.
+func getTermState(fd uintptr) (st termState, errno syscall.Errno) {
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&st)))
+	return
+}
+
+// setTermState changes the state of the terminal `fd` with `tcsetattr`.
+func setTermState(fd uintptr, st termState) syscall.Errno {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(&st)))
+	return errno
+}
+
+// rawTermState returns `st` without canonical mode and echo, so that reads
+// return each byte as typed.
+func rawTermState(st termState) termState {
+	st.Lflag &^= syscall.ICANON | syscall.ECHO
+	st.Cc[syscall.VMIN] = 1
+	st.Cc[syscall.VTIME] = 0
+	return st
+}
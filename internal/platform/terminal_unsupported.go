@@ -0,0 +1,30 @@
+//go:build !(darwin || linux || freebsd || windows)
+
+package platform
+
+import "syscall"
+
+// termState is empty as terminals aren't supported on this platform.
+type termState struct{}
+
+// isTerminal returns false as terminals can't be detected on this platform.
+func isTerminal(uintptr) bool {
+	return false
+}
+
+// getTermState returns syscall.ENOTTY as terminals aren't supported on this
+// platform.
+func getTermState(uintptr) (termState, syscall.Errno) {
+	return termState{}, syscall.ENOTTY
+}
+
+// setTermState returns syscall.ENOTTY as terminals aren't supported on this
+// platform.
+func setTermState(uintptr, termState) syscall.Errno {
+	return syscall.ENOTTY
+}
+
+// rawTermState returns `st` as terminals aren't supported on this platform.
+func rawTermState(st termState) termState {
+	return st
+}
@@ -0,0 +1,46 @@
+package platform
+
+import "syscall"
+
+// Console modes cleared by rawTermState.
+const (
+	_ENABLE_LINE_INPUT = 0x2
+	_ENABLE_ECHO_INPUT = 0x4
+)
+
+// procSetConsoleMode is the syscall.LazyProc in kernel32 for SetConsoleMode,
+// which isn't in the syscall package.
+var procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+
+// termState is the console mode changed by File.SetTerminalRaw.
+type termState = uint32
+
+// isTerminal returns true if `fd` is a console handle, which is the case
+// when GetConsoleMode succeeds.
+func isTerminal(fd uintptr) bool {
+	_, errno := getTermState(fd)
+	return errno == 0
+}
+
+// getTermState reads the mode of the console `fd`.
+func getTermState(fd uintptr) (termState, syscall.Errno) {
+	var mode uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(fd), &mode); err != nil {
+		return 0, syscall.ENOTTY
+	}
+	return mode, 0
+}
+
+// setTermState changes the mode of the console `fd`.
+func setTermState(fd uintptr, st termState) syscall.Errno {
+	if r, _, err := procSetConsoleMode.Call(fd, uintptr(st)); r == 0 {
+		return UnwrapOSError(err)
+	}
+	return 0
+}
+
+// rawTermState returns `st` without line input and echo, so that reads
+// return each key as typed.
+func rawTermState(st termState) termState {
+	return st &^ (_ENABLE_LINE_INPUT | _ENABLE_ECHO_INPUT)
+}
@@ -14,8 +14,9 @@ func Unlink(name string) syscall.Errno {
 	}
 	errno := UnwrapOSError(err)
 	if errno == syscall.EACCES {
-		lstat, errLstat := os.Lstat(name)
-		if errLstat == nil && lstat.Mode()&os.ModeSymlink != 0 {
+		// Use Lstat, as os.Lstat doesn't report junctions as symbolic links
+		// in all versions of Go.
+		if st, errLstat := Lstat(name); errLstat == 0 && st.Mode&os.ModeSymlink != 0 {
 			errno = UnwrapOSError(os.Remove(name))
 		} else {
 			errno = syscall.EISDIR
@@ -38,6 +38,9 @@ type windowsWrappedFile struct {
 	flag           int
 	perm           fs.FileMode
 	dirInitialized bool
+	// dirSeen are the names read since the directory was initialized, so
+	// that re-opening it after DirChanged doesn't repeat them.
+	dirSeen map[string]struct{}
 
 	fileType *fs.FileMode
 
@@ -59,7 +62,19 @@ func (w *windowsWrappedFile) Readdir(n int) (fis []fs.FileInfo, err error) {
 		return
 	}
 
-	return w.osFile.Readdir(n)
+	for {
+		var read []fs.FileInfo
+		read, err = w.osFile.Readdir(n)
+		for _, fi := range read {
+			if w.markSeen(fi.Name()) {
+				fis = append(fis, fi)
+			}
+		}
+		// Read again if all entries were seen before a re-open.
+		if n <= 0 || len(fis) > 0 || len(read) == 0 || err != nil {
+			return
+		}
+	}
 }
 
 // ReadDir implements fs.ReadDirFile.
@@ -70,7 +85,29 @@ func (w *windowsWrappedFile) ReadDir(n int) (dirents []fs.DirEntry, err error) {
 		return
 	}
 
-	return w.osFile.ReadDir(n)
+	for {
+		var read []fs.DirEntry
+		read, err = w.osFile.ReadDir(n)
+		for _, d := range read {
+			if w.markSeen(d.Name()) {
+				dirents = append(dirents, d)
+			}
+		}
+		// Read again if all entries were seen before a re-open.
+		if n <= 0 || len(dirents) > 0 || len(read) == 0 || err != nil {
+			return
+		}
+	}
+}
+
+// markSeen returns true if `name` wasn't yet read since the directory was
+// initialized, recording it.
+func (w *windowsWrappedFile) markSeen(name string) bool {
+	if _, ok := w.dirSeen[name]; ok {
+		return false
+	}
+	w.dirSeen[name] = struct{}{}
+	return true
 }
 
 // Write implements io.Writer
@@ -91,24 +128,28 @@ func (w *windowsWrappedFile) Write(p []byte) (n int, err error) {
 // Close implements io.Closer
 func (w *windowsWrappedFile) Close() (err error) {
 	if w.closed {
-		return
+		return syscall.EBADF
 	}
 
-	if err = w.osFile.Close(); err != nil {
+	if err = w.osFile.Close(); err == nil {
 		w.closed = true
+		if w.dirSeen != nil {
+			removeOpenDir(w)
+		}
 	}
 	return
 }
 
 func (w *windowsWrappedFile) maybeInitDir() error {
-	if w.dirInitialized {
+	if w.dirInitialized && !takeOpenDirChanged(w) {
 		return nil
 	}
 
 	// On Windows, once the directory is opened, changes to the directory are
 	// not visible on ReadDir on that already-opened file handle.
 	//
-	// To provide consistent behavior with other platforms, we re-open it.
+	// To provide consistent behavior with other platforms, we re-open it,
+	// and again after DirChanged, skipping entries already read.
 	if err := w.osFile.Close(); err != nil {
 		return err
 	}
@@ -117,10 +158,26 @@ func (w *windowsWrappedFile) maybeInitDir() error {
 		return &fs.PathError{Op: "OpenFile", Path: w.path, Err: errno}
 	}
 	w.osFile = newW
+	if !w.dirInitialized {
+		if w.dirSeen == nil {
+			addOpenDir(w)
+		}
+		w.dirSeen = map[string]struct{}{}
+	}
 	w.dirInitialized = true
 	return nil
 }
 
+// rewindDir makes the next Readdir re-open the directory, as seeking doesn't
+// reset the directory position of a handle on Windows.
+func (w *windowsWrappedFile) rewindDir() syscall.Errno {
+	if w.closed {
+		return syscall.EBADF
+	}
+	w.dirInitialized = false
+	return 0
+}
+
 // requireFile is used to making syscalls which will fail.
 func (w *windowsWrappedFile) requireFile(op string, readOnly, isDir bool) error {
 	var ft fs.FileMode
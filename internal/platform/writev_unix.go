@@ -0,0 +1,36 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// iovMax is the minimum IOV_MAX of supported platforms.
+const iovMax = 1024
+
+// writev writes the buffers to the file descriptor in one syscall, or returns
+// syscall.ENOSYS if there are too many buffers to do so.
+func writev(fd uintptr, bufs [][]byte) (int, syscall.Errno) {
+	iovs := make([]syscall.Iovec, 0, len(bufs))
+	for _, buf := range bufs {
+		if len(buf) == 0 {
+			continue
+		}
+		iov := syscall.Iovec{Base: &buf[0]}
+		iov.SetLen(len(buf))
+		iovs = append(iovs, iov)
+	}
+	if len(iovs) == 0 {
+		return 0, 0
+	} else if len(iovs) > iovMax {
+		return 0, syscall.ENOSYS
+	}
+
+	n, _, errno := syscall.Syscall(syscall.SYS_WRITEV, fd, uintptr(unsafe.Pointer(&iovs[0])), uintptr(len(iovs)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), 0
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || linux || freebsd)
+
+package platform
+
+import "syscall"
+
+// writev returns syscall.ENOSYS as it isn't supported on this platform.
+func writev(uintptr, [][]byte) (int, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
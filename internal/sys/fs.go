@@ -1,6 +1,7 @@
 package sys
 
 import (
+	"bytes"
 	"io"
 	"io/fs"
 	"os"
@@ -58,6 +59,13 @@ func (f *writerFile) Write(buf []byte) (int, syscall.Errno) {
 	return n, platform.UnwrapOSError(err)
 }
 
+// Writev implements the same method as documented on platform.File
+func (f *writerFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	// Join the buffers, so that they are written with one call.
+	n, err := f.w.Write(bytes.Join(bufs, nil))
+	return n, platform.UnwrapOSError(err)
+}
+
 // noopStdinFile is a fs.ModeDevice file for use implementing FdStdin. This is
 // safer than reading from os.DevNull as it can never overrun operating system
 // file descriptors.
@@ -96,6 +104,14 @@ func (noopStdoutFile) Write(p []byte) (int, syscall.Errno) {
 	return len(p), 0 // same as io.Discard
 }
 
+// Writev implements the same method as documented on platform.File
+func (noopStdoutFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	for _, buf := range bufs {
+		n += len(buf) // same as io.Discard
+	}
+	return
+}
+
 type noopStdioFile struct {
 	platform.UnimplementedFile
 }
@@ -126,6 +142,10 @@ type lazyDir struct {
 
 	fs sysfs.FS
 	f  platform.File
+
+	// isRoot is true when fs is also the FileEntry.FS, so the directory can
+	// be used with sysfs.AtFS.
+	isRoot bool
 }
 
 // Path implements the same method as documented on platform.File
@@ -250,6 +270,71 @@ type FileEntry struct {
 	openPerm fs.FileMode
 }
 
+// AtPath returns `path`, which is relative to this directory, as a path
+// relative to the root of FS.
+func (f *FileEntry) AtPath(path string) string {
+	if f.IsPreopen { // don't append the pre-open name
+		return path
+	}
+	// Join via concat to avoid name conflict on path.Join
+	return f.Name + "/" + path
+}
+
+// StatAt is like FS.Stat, except `path` is relative to this directory. See
+// FSContext.OpenFileAt for when this avoids resolving the directory again.
+func (f *FileEntry) StatAt(path string) (platform.Stat_t, syscall.Errno) {
+	if d, ok := f.atDir(); ok {
+		return sysfs.StatAt(f.FS, d, path)
+	}
+	return f.FS.Stat(f.AtPath(path))
+}
+
+// UnlinkAt is like FS.Unlink, except `path` is relative to this directory.
+// See FSContext.OpenFileAt for when this avoids resolving the directory again.
+func (f *FileEntry) UnlinkAt(path string) syscall.Errno {
+	if d, ok := f.atDir(); ok {
+		return sysfs.UnlinkAt(f.FS, d, path)
+	}
+	return f.FS.Unlink(f.AtPath(path))
+}
+
+// SymlinkAt is like FS.Symlink, except `link` is relative to this directory.
+// `target` is stored as is, as it is resolved when the link is followed. See
+// FSContext.OpenFileAt for when this avoids resolving the directory again.
+func (f *FileEntry) SymlinkAt(target, link string) syscall.Errno {
+	if d, ok := f.atDir(); ok {
+		return sysfs.SymlinkAt(f.FS, target, d, link)
+	}
+	return f.FS.Symlink(target, f.AtPath(link))
+}
+
+// LinkAt is like FS.Link, except `oldPath` is relative to this directory and
+// `newPath` is relative to `newDir`, which must have the same FS. See
+// FSContext.OpenFileAt for when this avoids resolving the directories again.
+func (f *FileEntry) LinkAt(oldPath string, newDir *FileEntry, newPath string) syscall.Errno {
+	if oldD, ok := f.atDir(); ok {
+		if newD, ok := newDir.atDir(); ok {
+			return sysfs.LinkAt(f.FS, oldD, oldPath, newD, newPath)
+		}
+	}
+	return f.FS.Link(f.AtPath(oldPath), newDir.AtPath(newPath))
+}
+
+// atDir returns the open directory to resolve paths against with sysfs.AtFS,
+// or false if they must be resolved from the root of FS instead.
+func (f *FileEntry) atDir() (platform.File, bool) {
+	if _, ok := f.FS.(sysfs.AtFS); !ok {
+		return nil, false
+	}
+	if d, ok := f.File.(*lazyDir); ok {
+		if !d.isRoot {
+			return nil, false // opened from a different FS, e.g. CompositeFS
+		}
+		return d.file()
+	}
+	return f.File, true
+}
+
 type cachedStat struct {
 	// Ino is the file serial number, or zero if not available.
 	Ino uint64
@@ -355,7 +440,7 @@ func (c *Context) NewFSContext(stdin io.Reader, stdout, stderr io.Writer, rootFS
 			FS:        rootFS,
 			Name:      "/",
 			IsPreopen: true,
-			File:      &lazyDir{fs: rootFS},
+			File:      &lazyDir{fs: rootFS, isRoot: true},
 		})
 	}
 
@@ -402,17 +487,64 @@ func (c *FSContext) OpenFile(fs sysfs.FS, path string, flag int, perm fs.FileMod
 	if f, errno := fs.OpenFile(path, flag, perm); errno != 0 {
 		return 0, errno
 	} else {
-		fe := &FileEntry{FS: fs, File: f, openFlag: flag, openPerm: perm}
-		if path == "/" || path == "." {
-			fe.Name = ""
-		} else {
-			fe.Name = path
-		}
-		if newFD, ok := c.openedFiles.Insert(fe); !ok {
-			return 0, syscall.EBADF
-		} else {
-			return newFD, 0
+		return c.insertFile(fs, path, f, flag, perm)
+	}
+}
+
+// OpenFileAt is like OpenFile, except `path` is relative to the directory
+// `dir`, which must be open in this context.
+//
+// When the file system of `dir` implements sysfs.AtFS, this resolves `path`
+// against the open directory, instead of resolving the directory again from
+// the root. This saves syscalls, and avoids races with renames of the
+// directory or its parents. Otherwise, this is the same as OpenFile with the
+// path joined by FileEntry.AtPath.
+func (c *FSContext) OpenFileAt(dir *FileEntry, path string, flag int, perm fs.FileMode) (int32, syscall.Errno) {
+	atPath := dir.AtPath(path)
+	if d, ok := dir.atDir(); !ok {
+		return c.OpenFile(dir.FS, atPath, flag, perm)
+	} else if f, errno := sysfs.OpenFileAt(dir.FS, d, path, flag, perm); errno != 0 {
+		return 0, errno
+	} else {
+		return c.insertFile(dir.FS, atPath, f, flag, perm)
+	}
+}
+
+// insertFile assigns a file descriptor to `f`, opened at `path` in `fs`.
+func (c *FSContext) insertFile(fs sysfs.FS, path string, f platform.File, flag int, perm fs.FileMode) (int32, syscall.Errno) {
+	fe := &FileEntry{FS: fs, File: f, openFlag: flag, openPerm: perm}
+	if path == "/" || path == "." {
+		fe.Name = ""
+	} else {
+		fe.Name = path
+	}
+	if newFD, ok := c.openedFiles.Insert(fe); !ok {
+		_ = f.Close()
+		return 0, syscall.EBADF
+	} else {
+		return newFD, 0
+	}
+}
+
+// RewindDir resets the directory to read entries from the beginning, while
+// keeping the same file descriptor. This re-opens the directory if its file
+// doesn't support platform.File RewindDir.
+func (c *FSContext) RewindDir(fd int32) (*FileEntry, syscall.Errno) {
+	f, ok := c.openedFiles.Lookup(fd)
+	if !ok {
+		return nil, syscall.EBADF
+	}
+
+	switch errno := f.File.RewindDir(); errno {
+	case 0:
+		if f.ReadDir != nil {
+			f.ReadDir.CountRead, f.ReadDir.Dirents = 0, nil
 		}
+		return f, 0
+	case syscall.ENOSYS:
+		return c.ReOpenDir(fd)
+	default:
+		return nil, errno
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"runtime"
 	"syscall"
 	"testing"
 	"testing/fstest"
@@ -274,6 +275,122 @@ func TestFSContext_ReOpenDir(t *testing.T) {
 	})
 }
 
+func TestFSContext_OpenFileAt(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(tmpDir, "dir", "sub"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "dir", "sub", "file"), []byte("wazero"), 0o600))
+
+	for _, tc := range []struct {
+		name   string
+		testFS sysfs.FS
+	}{
+		{name: "dirFS", testFS: sysfs.NewDirFS(tmpDir)},
+		{name: "adapter", testFS: sysfs.Adapt(os.DirFS(tmpDir))},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := Context{}
+			require.NoError(t, c.NewFSContext(nil, nil, nil, tc.testFS))
+			fsc := c.fsc
+			defer fsc.Close()
+
+			preopen, ok := fsc.LookupFile(FdPreopen)
+			require.True(t, ok)
+			dirFD, errno := fsc.OpenFileAt(preopen, "dir", os.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			dir, ok := fsc.LookupFile(dirFD)
+			require.True(t, ok)
+			require.Equal(t, "dir", dir.Name)
+
+			st, errno := dir.StatAt("sub/file")
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, int64(6), st.Size)
+
+			fd, errno := fsc.OpenFileAt(dir, "sub/file", os.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			f, ok := fsc.LookupFile(fd)
+			require.True(t, ok)
+			require.Equal(t, "dir/sub/file", f.Name)
+			require.EqualErrno(t, 0, fsc.CloseFile(fd))
+
+			_, errno = fsc.OpenFileAt(dir, "missing", os.O_RDONLY, 0)
+			require.EqualErrno(t, syscall.ENOENT, errno)
+
+			// When openat is supported, the open directory is reused, so
+			// renaming it doesn't affect resolution.
+			if _, ok := tc.testFS.(sysfs.AtFS); ok && runtime.GOOS == "linux" {
+				moved := path.Join(tmpDir, "moved")
+				require.NoError(t, os.Rename(path.Join(tmpDir, "dir"), moved))
+				defer os.Rename(moved, path.Join(tmpDir, "dir")) //nolint
+
+				fd, errno = fsc.OpenFileAt(dir, "sub/file", os.O_RDONLY, 0)
+				require.EqualErrno(t, 0, errno)
+				require.EqualErrno(t, 0, fsc.CloseFile(fd))
+
+				// So do links and unlinks.
+				require.EqualErrno(t, 0, dir.SymlinkAt("sub/file", "link"))
+				require.EqualErrno(t, 0, dir.LinkAt("sub/file", dir, "hard"))
+				for _, name := range []string{"link", "hard"} {
+					_, err := os.Stat(path.Join(moved, name))
+					require.NoError(t, err)
+					require.EqualErrno(t, 0, dir.UnlinkAt(name))
+				}
+			}
+		})
+	}
+}
+
+func TestFSContext_RewindDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	dirFs := sysfs.NewDirFS(tmpDir)
+	errno := dirFs.Mkdir("dir", 0o700)
+	require.EqualErrno(t, 0, errno)
+
+	c := Context{}
+	err := c.NewFSContext(nil, nil, nil, dirFs)
+	require.NoError(t, err)
+	fsc := c.fsc
+	defer fsc.Close()
+
+	tests := []struct {
+		name string
+		fs   sysfs.FS
+	}{
+		{name: "rewinds", fs: dirFs},
+		{name: "re-opens", fs: sysfs.Adapt(fstest.MapFS{"dir": {Mode: fs.ModeDir}})},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dirFd, errno := fsc.OpenFile(tc.fs, "dir", os.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			defer fsc.CloseFile(dirFd)
+
+			ent, ok := fsc.LookupFile(dirFd)
+			require.True(t, ok)
+			file := ent.File
+
+			// Set arbitrary state.
+			ent.ReadDir = &ReadDir{Dirents: make([]platform.Dirent, 10), CountRead: 12345}
+
+			ent, errno = fsc.RewindDir(dirFd)
+			require.EqualErrno(t, 0, errno)
+
+			// Verify the read dir state has been reset.
+			require.Equal(t, &ReadDir{}, ent.ReadDir)
+
+			// Only re-open when the file doesn't support rewinding.
+			require.Equal(t, tc.fs == dirFs, ent.File == file)
+		})
+	}
+
+	t.Run("non existing", func(t *testing.T) {
+		_, errno = fsc.RewindDir(12345)
+		require.EqualErrno(t, syscall.EBADF, errno)
+	})
+}
+
 func TestFSContext_Renumber(t *testing.T) {
 	tmpDir := t.TempDir()
 	dirFs := sysfs.NewDirFS(tmpDir)
@@ -64,7 +64,7 @@ func TestDefaultSysContext(t *testing.T) {
 		IsPreopen: true,
 		Name:      "/",
 		FS:        testFS,
-		File:      &lazyDir{fs: testFS},
+		File:      &lazyDir{fs: testFS, isRoot: true},
 	})
 	require.Equal(t, expected, sysCtx.FS().openedFiles)
 }
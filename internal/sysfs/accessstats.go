@@ -0,0 +1,317 @@
+package sysfs
+
+import (
+	"container/heap"
+	"io/fs"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// accessStatsMaxPaths bounds the memory of AccessStatsFS. When more paths are
+// accessed, the least accessed path is forgotten.
+const accessStatsMaxPaths = 4096
+
+// PathStat are the counts of operations on a path, returned by
+// AccessStatsFS.TopPaths.
+type PathStat struct {
+	// Path is the cleaned path, relative to the root of the FS.
+	Path string
+
+	// Opens is the count of FS.OpenFile calls which succeeded.
+	Opens uint64
+
+	// Reads is the count of File.Read, File.Pread and File.Readdir calls.
+	Reads uint64
+
+	// Writes is the count of File.Write, File.Writev and File.Pwrite calls.
+	Writes uint64
+}
+
+func (s *PathStat) total() uint64 {
+	return s.Opens + s.Reads + s.Writes
+}
+
+// NewAccessStatsFS returns an AccessStatsFS, which counts opens, reads and
+// writes of each path in `fs`. This helps profile which files a module
+// accesses most.
+//
+// Unlike NewMetricsFS, which reports each operation, this aggregates counts
+// in memory, for TopPaths.
+func NewAccessStatsFS(fs FS) *AccessStatsFS {
+	return &AccessStatsFS{fs: fs, stats: map[string]*accessStat{}}
+}
+
+// AccessStatsFS is an FS which delegates to another, and counts accesses of
+// each path. See NewAccessStatsFS for details.
+type AccessStatsFS struct {
+	UnimplementedFS
+	fs FS
+
+	// mux guards stats and least.
+	mux sync.Mutex
+	// stats are the counts by cleaned path. When there are
+	// accessStatsMaxPaths, the least accessed is removed to add another, so
+	// counts are approximate when many paths are accessed.
+	stats map[string]*accessStat
+	// least orders the values of stats, least accessed first, so that
+	// evicting doesn't scan all paths.
+	least accessStatsHeap
+}
+
+// accessStat is a PathStat and its position in AccessStatsFS.least.
+type accessStat struct {
+	PathStat
+	index int
+}
+
+// accessStatsHeap is a container/heap.Interface of the least accessed path.
+type accessStatsHeap []*accessStat
+
+func (h accessStatsHeap) Len() int { return len(h) }
+
+func (h accessStatsHeap) Less(i, j int) bool { return h[i].total() < h[j].total() }
+
+func (h accessStatsHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *accessStatsHeap) Push(x interface{}) {
+	s := x.(*accessStat)
+	s.index = len(*h)
+	*h = append(*h, s)
+}
+
+func (h *accessStatsHeap) Pop() interface{} {
+	old := *h
+	s := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return s
+}
+
+// TopPaths returns the stats of the `n` most accessed paths, most first. The
+// count of accesses is the sum of opens, reads and writes. A negative `n`
+// returns no stats.
+func (a *AccessStatsFS) TopPaths(n int) []PathStat {
+	if n < 0 {
+		n = 0
+	}
+	a.mux.Lock()
+	result := make([]PathStat, 0, len(a.stats))
+	for _, s := range a.stats {
+		result = append(result, s.PathStat)
+	}
+	a.mux.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if ti, tj := result[i].total(), result[j].total(); ti != tj {
+			return ti > tj
+		}
+		return result[i].Path < result[j].Path
+	})
+	if n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
+
+// record increments the counts of `path` with `inc`.
+func (a *AccessStatsFS) record(path string, inc func(*PathStat)) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	s, ok := a.stats[path]
+	if !ok {
+		if len(a.stats) >= accessStatsMaxPaths {
+			least := heap.Pop(&a.least).(*accessStat)
+			delete(a.stats, least.Path)
+		}
+		s = &accessStat{PathStat: PathStat{Path: path}}
+		a.stats[path] = s
+		heap.Push(&a.least, s)
+	}
+	inc(&s.PathStat)
+	heap.Fix(&a.least, s.index)
+}
+
+// String implements fmt.Stringer
+func (a *AccessStatsFS) String() string {
+	return a.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (a *AccessStatsFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	f, errno := a.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	path = cleanPath(path)
+	a.record(path, func(s *PathStat) { s.Opens++ })
+	return &accessStatsFile{File: f, fs: a, path: path}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (a *AccessStatsFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	f, errno := a.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	path := cleanPath(f.Path())
+	a.record(path, func(s *PathStat) { s.Opens++ })
+	return &accessStatsFile{File: f, fs: a, path: path}, 0
+}
+
+// Lstat implements FS.Lstat
+func (a *AccessStatsFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return a.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (a *AccessStatsFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return a.fs.Stat(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (a *AccessStatsFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return a.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (a *AccessStatsFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return a.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (a *AccessStatsFS) Chown(path string, uid, gid int) syscall.Errno {
+	return a.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (a *AccessStatsFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return a.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (a *AccessStatsFS) Rename(from, to string) syscall.Errno {
+	return a.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (a *AccessStatsFS) ExchangeDir(x, y string) syscall.Errno {
+	return a.fs.ExchangeDir(x, y)
+}
+
+// Clone implements FS.Clone
+func (a *AccessStatsFS) Clone(src, dst string) syscall.Errno {
+	return a.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (a *AccessStatsFS) Rmdir(path string) syscall.Errno {
+	return a.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (a *AccessStatsFS) Unlink(path string) syscall.Errno {
+	return a.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (a *AccessStatsFS) Link(oldPath, newPath string) syscall.Errno {
+	return a.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (a *AccessStatsFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return a.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (a *AccessStatsFS) Readlink(path string) (string, syscall.Errno) {
+	return a.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (a *AccessStatsFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return a.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (a *AccessStatsFS) Truncate(path string, size int64) syscall.Errno {
+	return a.fs.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (a *AccessStatsFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return a.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (a *AccessStatsFS) SyncDir(path string) syscall.Errno {
+	return a.fs.SyncDir(path)
+}
+
+// accessStatsFile counts reads and writes of a file opened by AccessStatsFS.
+type accessStatsFile struct {
+	platform.File
+	fs   *AccessStatsFS
+	path string
+}
+
+func (f *accessStatsFile) read() {
+	f.fs.record(f.path, func(s *PathStat) { s.Reads++ })
+}
+
+func (f *accessStatsFile) write() {
+	f.fs.record(f.path, func(s *PathStat) { s.Writes++ })
+}
+
+// Read implements the same method as documented on platform.File
+func (f *accessStatsFile) Read(buf []byte) (int, syscall.Errno) {
+	f.read()
+	return f.File.Read(buf)
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *accessStatsFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	f.read()
+	return f.File.Pread(buf, off)
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *accessStatsFile) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	f.read()
+	return f.File.Readdir(n)
+}
+
+// Write implements the same method as documented on platform.File
+func (f *accessStatsFile) Write(buf []byte) (int, syscall.Errno) {
+	f.write()
+	return f.File.Write(buf)
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *accessStatsFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	f.write()
+	return f.File.Writev(bufs)
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *accessStatsFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	f.write()
+	return f.File.Pwrite(buf, off)
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *accessStatsFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &accessStatsFile{File: dup, fs: f.fs, path: f.path}, 0
+}
@@ -0,0 +1,81 @@
+package sysfs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewAccessStatsFS(t *testing.T) {
+	testFS := NewAccessStatsFS(NewMemFS())
+	require.Equal(t, "mem:/", testFS.String())
+	require.Zero(t, len(testFS.TopPaths(10)))
+
+	f, errno := testFS.OpenFile("hot", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	for i := 0; i < 3; i++ {
+		_, errno = f.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+	}
+	_, errno = f.Pread(make([]byte, 6), 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Opens are counted by the cleaned path.
+	f, errno = testFS.OpenFile("./cold", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Failed opens aren't counted.
+	_, errno = testFS.OpenFile("missing", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	require.Equal(t, []PathStat{
+		{Path: "hot", Opens: 1, Reads: 1, Writes: 3},
+		{Path: "cold", Opens: 1},
+	}, testFS.TopPaths(10))
+	require.Equal(t, []PathStat{{Path: "hot", Opens: 1, Reads: 1, Writes: 3}}, testFS.TopPaths(1))
+}
+
+func TestAccessStatsFS_bounded(t *testing.T) {
+	testFS := NewAccessStatsFS(NewMemFS())
+	f, errno := testFS.OpenFile("hot", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	testFS.record("hot", func(s *PathStat) { s.Reads += 10 })
+
+	for i := 0; i < accessStatsMaxPaths+10; i++ {
+		testFS.record(fmt.Sprint(i), func(s *PathStat) { s.Opens++ })
+	}
+	top := testFS.TopPaths(accessStatsMaxPaths + 10)
+	require.Equal(t, accessStatsMaxPaths, len(top))
+	// The most accessed path is kept.
+	require.Equal(t, "hot", top[0].Path)
+}
+
+func TestAccessStatsFS_TopPaths_negative(t *testing.T) {
+	testFS := NewAccessStatsFS(NewMemFS())
+	testFS.record("a", func(s *PathStat) { s.Opens++ })
+	require.Zero(t, len(testFS.TopPaths(-1)))
+	require.Zero(t, len(testFS.TopPaths(0)))
+}
+
+func TestAccessStatsFS_evictsLeast(t *testing.T) {
+	testFS := NewAccessStatsFS(NewMemFS())
+	for i := 0; i < accessStatsMaxPaths; i++ {
+		path := fmt.Sprint(i)
+		testFS.record(path, func(s *PathStat) { s.Reads += uint64(i + 2) })
+	}
+	// "cold" evicts "0", the least accessed, then "warm" evicts "cold".
+	testFS.record("cold", func(s *PathStat) { s.Opens++ })
+	testFS.record("warm", func(s *PathStat) { s.Opens++ })
+	_, ok := testFS.stats["0"]
+	require.False(t, ok)
+	_, ok = testFS.stats["cold"]
+	require.False(t, ok)
+	require.Equal(t, accessStatsMaxPaths, len(testFS.stats))
+	require.Equal(t, accessStatsMaxPaths, testFS.least.Len())
+}
@@ -2,6 +2,7 @@ package sysfs
 
 import (
 	"fmt"
+	"io"
 	"io/fs"
 	"path"
 	"syscall"
@@ -12,20 +13,40 @@ import (
 // Adapt adapts the input to FS unless it is already one. Use NewDirFS instead
 // of os.DirFS as it handles interop issues such as windows support.
 //
-// Note: This performs no flag verification on FS.OpenFile. fs.FS cannot read
-// flags as there is no parameter to pass them through with. Moreover, fs.FS
-// documentation does not require the file to be present. In summary, we can't
-// enforce flag behavior.
+// Note: fs.FS cannot read flags as there is no parameter to pass them through
+// with. Moreover, fs.FS documentation does not require the file to be present.
+// In summary, we can't enforce most flag behavior. The exceptions are below:
+//
+//   - When the input implements OpenFileFS, opens for writing or creating use
+//     it, so that it can honor flags such as os.O_CREATE and os.O_TRUNC.
+//   - Otherwise, opening for writing fails with syscall.ENOSYS if the file
+//     can't be written, such as from fstest.MapFS, rather than failing on
+//     the first write.
+//   - When the input implements fs.ReadDirFS, but its directories don't
+//     implement fs.ReadDirFile, directories are listed with fs.ReadDir.
 func Adapt(fs fs.FS) FS {
 	if fs == nil {
 		return UnimplementedFS{}
 	}
 	if sys, ok := fs.(FS); ok {
 		return sys
+	} else if f, ok := fs.(*fsFS); ok {
+		return f.fs // from AsFsFS
 	}
 	return &adapter{fs: fs}
 }
 
+// OpenFileFS is an fs.FS which can also open files with flags, like
+// os.OpenFile. Adapt uses this to honor opens for writing or creating, which
+// fs.FS Open can't express.
+type OpenFileFS interface {
+	fs.FS
+
+	// OpenFile is like os.OpenFile, where `name` is valid per fs.ValidPath.
+	// The result is expected to honor `flag` and `perm`.
+	OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error)
+}
+
 type adapter struct {
 	UnimplementedFS
 	fs fs.FS
@@ -38,20 +59,172 @@ func (a *adapter) String() string {
 
 // OpenFile implements FS.OpenFile
 func (a *adapter) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if errno := validatePath(path, DefaultMaxPathLen); errno != 0 {
+		return nil, errno
+	}
 	path = cleanPath(path)
+	writing := flag&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+	if ofs, ok := a.fs.(OpenFileFS); ok && (writing || flag&syscall.O_CREAT != 0) {
+		f, err := ofs.OpenFile(path, flag, perm)
+		if err != nil {
+			return nil, platform.UnwrapOSError(err)
+		} else if errno := checkOpenType(f, flag); errno != 0 {
+			_ = f.Close()
+			return nil, errno
+		}
+		return platform.NewFsFile(path, flag, f), 0
+	}
+
 	f, err := a.fs.Open(path)
-	return platform.NewFsFile(path, flag, f), platform.UnwrapOSError(err)
+	if err != nil {
+		return nil, platform.UnwrapOSError(err)
+	} else if errno := checkOpenType(f, flag); errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	if _, ok := f.(io.Writer); writing && !ok {
+		// Fail now, instead of on the first write. See readFS.OpenFile.
+		_ = f.Close()
+		return nil, syscall.ENOSYS
+	}
+	f, errno := a.readDirFallback(path, f)
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	file := platform.NewFsFile(path, flag, f)
+	if flag&syscall.O_TRUNC != 0 {
+		if errno := truncateOnOpen(file, flag); errno != 0 {
+			_ = file.Close()
+			return nil, errno
+		}
+	}
+	return file, 0
+}
+
+// checkOpenType returns syscall.EISDIR if `f` is a directory opened for
+// writing, or syscall.ENOTDIR if it isn't a directory, but `flag` includes
+// platform.O_DIRECTORY. An fs.FS can't enforce these, so without this check,
+// the error would be deferred to the first write or read. See readFS.OpenFile
+// for why failing early is better.
+func checkOpenType(f fs.File, flag int) syscall.Errno {
+	writing := flag&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+	if !writing && flag&platform.O_DIRECTORY == 0 {
+		return 0 // no need to stat
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return platform.UnwrapOSError(err)
+	} else if info.IsDir() {
+		if writing {
+			return syscall.EISDIR
+		}
+	} else if flag&platform.O_DIRECTORY != 0 {
+		return syscall.ENOTDIR
+	}
+	return 0
+}
+
+// readDirFallback returns `f` as a readDirFallbackFile if it is a directory
+// which can't list its entries, but the underlying fs.FS can.
+//
+// Note: fs.ReadDir opens the directory again unless the fs.FS implements
+// fs.ReadDirFS, so it can only succeed in that case.
+func (a *adapter) readDirFallback(path string, f fs.File) (fs.File, syscall.Errno) {
+	if _, ok := a.fs.(fs.ReadDirFS); !ok {
+		return f, 0
+	}
+	switch f.(type) {
+	case fs.ReadDirFile, interface {
+		Readdir(n int) ([]fs.FileInfo, error)
+	}:
+		return f, 0
+	}
+	if info, err := f.Stat(); err != nil {
+		return f, platform.UnwrapOSError(err)
+	} else if !info.IsDir() {
+		return f, 0
+	}
+	if path == "" {
+		path = "."
+	}
+	return &readDirFallbackFile{File: f, fs: a.fs, name: path}, 0
+}
+
+// readDirFallbackFile implements fs.ReadDirFile for a directory of an
+// fs.ReadDirFS, by listing it with fs.ReadDir on the first read.
+type readDirFallbackFile struct {
+	fs.File
+	fs   fs.FS
+	name string
+
+	// entries are those not yet read, or nil if not yet listed.
+	entries []fs.DirEntry
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *readDirFallbackFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		entries, err := fs.ReadDir(d.fs, d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = append(make([]fs.DirEntry, 0, len(entries)), entries...)
+	}
+	if n <= 0 || n > len(d.entries) {
+		if n > 0 && len(d.entries) == 0 {
+			return nil, io.EOF
+		}
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// Seek implements io.Seeker, only to rewind the directory, which lists it
+// again on the next read.
+func (d *readDirFallbackFile) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, syscall.EINVAL
+	}
+	d.entries = nil
+	return 0, nil
+}
+
+// truncateOnOpen implements syscall.O_TRUNC for a file system which doesn't
+// handle it natively. This returns syscall.EISDIR if the file is a directory,
+// or syscall.ENOSYS if it is open for writing, but doesn't support truncate.
+//
+// Note: POSIX leaves syscall.O_TRUNC with syscall.O_RDONLY unspecified. This
+// ignores it, as the file can't be written anyway.
+func truncateOnOpen(f platform.File, flag int) syscall.Errno {
+	if st, errno := f.Stat(); errno != 0 {
+		return errno
+	} else if st.Mode.IsDir() {
+		return syscall.EISDIR
+	} else if flag&(syscall.O_WRONLY|syscall.O_RDWR) == 0 || st.Size == 0 {
+		return 0 // nothing to truncate
+	}
+	return f.Truncate(0)
 }
 
 // Stat implements FS.Stat
 func (a *adapter) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := validatePath(path, DefaultMaxPathLen); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
 	name := cleanPath(path)
 	f, err := a.fs.Open(name)
 	if err != nil {
 		return platform.Stat_t{}, platform.UnwrapOSError(err)
 	}
 	defer f.Close()
-	return platform.NewFsFile(path, syscall.O_RDONLY, f).Stat()
+	info, err := f.Stat()
+	if err != nil {
+		return platform.Stat_t{}, platform.UnwrapOSError(err)
+	}
+	return platform.NewFsFileWithInfo(path, syscall.O_RDONLY, f, info).Stat()
 }
 
 // Lstat implements FS.Lstat
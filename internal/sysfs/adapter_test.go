@@ -2,14 +2,17 @@ package sysfs
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
 	"syscall"
 	"testing"
+	gofstest "testing/fstest"
 
 	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -92,6 +95,10 @@ func TestAdapt_UtimesNano(t *testing.T) {
 
 	err := testFS.Utimens(path, nil, true)
 	require.EqualErrno(t, syscall.ENOSYS, err)
+
+	// fs.FS can't distinguish a symbolic link from its target.
+	err = testFS.Utimens(path, nil, false)
+	require.EqualErrno(t, syscall.ENOSYS, err)
 }
 
 func TestAdapt_Open_Read(t *testing.T) {
@@ -167,3 +174,212 @@ func TestAdapt_HackedWrites(t *testing.T) {
 
 	testOpen_O_RDWR(t, tmpDir, testFS)
 }
+
+func TestAdapt_OpenFile_dirOrFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "dir"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "file"), nil, 0o600))
+
+	// hackFS opens directories read-only, so only Adapt can return EISDIR.
+	t.Run("hackFS", func(t *testing.T) {
+		testOpen_dirOrFile(t, Adapt(hackFS(tmpDir)))
+	})
+	t.Run("OpenFileFS", func(t *testing.T) {
+		testOpen_dirOrFile(t, Adapt(openFileFS(tmpDir)))
+	})
+}
+
+func TestAdapt_OpenFile_O_TRUNC(t *testing.T) {
+	t.Run("writable", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+		testFS := Adapt(hackFS(tmpDir))
+
+		f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_TRUNC, 0)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		b, err := os.ReadFile(filepath.Join(tmpDir, "file"))
+		require.NoError(t, err)
+		require.Zero(t, len(b))
+	})
+
+	testFS := Adapt(fstest.FS)
+
+	tests := []struct {
+		name          string
+		path          string
+		flag          int
+		expectedErrno syscall.Errno
+	}{
+		{name: "read-only ignored", path: "animals.txt", flag: os.O_RDONLY | os.O_TRUNC},
+		{name: "not writable", path: "empty.txt", flag: os.O_WRONLY | os.O_TRUNC, expectedErrno: syscall.ENOSYS},
+		{name: "unsupported", path: "animals.txt", flag: os.O_WRONLY | os.O_TRUNC, expectedErrno: syscall.ENOSYS},
+		{name: "directory", path: "sub", flag: os.O_RDONLY | os.O_TRUNC, expectedErrno: syscall.EISDIR},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			f, errno := testFS.OpenFile(tc.path, tc.flag, 0)
+			require.EqualErrno(t, tc.expectedErrno, errno)
+			if errno == 0 {
+				require.EqualErrno(t, 0, f.Close())
+			}
+		})
+	}
+
+	// The file is unchanged when truncation was ignored or failed.
+	st, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.NotEqual(t, int64(0), st.Size)
+}
+
+// openFileFS is a directory which implements OpenFileFS.
+type openFileFS string
+
+// Open implements fs.FS
+func (dir openFileFS) Open(name string) (fs.File, error) {
+	return os.Open(filepath.Join(string(dir), name))
+}
+
+// OpenFile implements OpenFileFS.OpenFile
+func (dir openFileFS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	return os.OpenFile(filepath.Join(string(dir), name), flag, perm)
+}
+
+func TestAdapt_OpenFileFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	realPath := filepath.Join(tmpDir, "file")
+	testFS := Adapt(openFileFS(tmpDir))
+
+	// Creating honors the flag.
+	f, errno := testFS.OpenFile("file", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	_, errno = testFS.OpenFile("file", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	require.EqualErrno(t, syscall.EEXIST, errno)
+
+	// Truncating with create keeps the file, but not its contents.
+	f, errno = testFS.OpenFile("file", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("wa"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	b, err := os.ReadFile(realPath)
+	require.NoError(t, err)
+	require.Equal(t, "wa", string(b))
+
+	// Reads don't need OpenFile.
+	f, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("wa"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+}
+
+func TestAdapt_OpenFile_notWritable(t *testing.T) {
+	testFS := Adapt(fstest.FS)
+
+	// Opening for write fails up front, as fstest.MapFS can't write.
+	for _, flag := range []int{os.O_WRONLY, os.O_RDWR, os.O_WRONLY | os.O_CREATE | os.O_TRUNC} {
+		_, errno := testFS.OpenFile("empty.txt", flag, 0o600)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+	}
+
+	// Without OpenFileFS, creating a missing file can't work.
+	_, errno := testFS.OpenFile("missing.txt", os.O_WRONLY|os.O_CREATE, 0o600)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+}
+
+// errFS is an fs.FS whose Open fails with err.
+type errFS struct{ err error }
+
+// Open implements fs.FS
+func (e errFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: e.err}
+}
+
+func TestAdapt_OpenFile_errors(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected syscall.Errno
+	}{
+		{err: fs.ErrNotExist, expected: syscall.ENOENT},
+		{err: fs.ErrPermission, expected: syscall.EACCES},
+		{err: fs.ErrExist, expected: syscall.EEXIST},
+		{err: fs.ErrClosed, expected: syscall.EBADF},
+		{err: fs.ErrInvalid, expected: syscall.EINVAL},
+		{err: errors.New("ice cream"), expected: syscall.EIO},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			// Custom fs.FS implementations often wrap the sentinel errors.
+			for _, err := range []error{tc.err, fmt.Errorf("custom: %w", tc.err)} {
+				testFS := Adapt(errFS{err: err})
+
+				_, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+				require.EqualErrno(t, tc.expected, errno)
+				_, errno = testFS.Stat("file")
+				require.EqualErrno(t, tc.expected, errno)
+			}
+		})
+	}
+}
+
+// plainFileFS is an fs.ReadDirFS whose files only implement fs.File.
+type plainFileFS struct{ fs fs.FS }
+
+// Open implements fs.FS
+func (p plainFileFS) Open(name string) (fs.File, error) {
+	f, err := p.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.File }{f}, nil
+}
+
+// ReadDir implements fs.ReadDirFS
+func (p plainFileFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(p.fs, name)
+}
+
+func TestAdapt_Readdir_fallback(t *testing.T) {
+	testFS := Adapt(plainFileFS{fs: gofstest.MapFS{
+		"dir/file": {Data: []byte("wazero")},
+		"dir/sub":  {Mode: fs.ModeDir},
+	}})
+
+	d, errno := testFS.OpenFile("dir", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer d.Close()
+
+	dirents, errno := d.Readdir(1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []platform.Dirent{{Name: "file", Type: 0}}, dirents)
+	dirents, errno = d.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []platform.Dirent{{Name: "sub", Type: fs.ModeDir}}, dirents)
+	dirents, errno = d.Readdir(1)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, len(dirents))
+
+	// Rewinding lists the directory again.
+	require.EqualErrno(t, 0, d.RewindDir())
+	dirents, errno = d.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 2, len(dirents))
+
+	// Regular files aren't affected.
+	f, errno := testFS.OpenFile("dir/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	require.Equal(t, []byte("wazero"), readAll(t, f))
+	_, errno = f.Readdir(-1)
+	require.EqualErrno(t, syscall.ENOTDIR, errno)
+}
@@ -0,0 +1,710 @@
+package sysfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// ArchiveFormat is the encoding of an archive passed to NewArchiveFS.
+type ArchiveFormat uint8
+
+const (
+	// ArchiveFormatZip is a zip archive, indexed by its central directory.
+	ArchiveFormatZip ArchiveFormat = iota
+	// ArchiveFormatTar is an uncompressed tar archive.
+	ArchiveFormatTar
+	// ArchiveFormatTarGzip is a gzip compressed tar archive. As gzip isn't
+	// seekable, this is decompressed into memory when the FS is created.
+	ArchiveFormatTarGzip
+)
+
+// String implements fmt.Stringer
+func (f ArchiveFormat) String() string {
+	switch f {
+	case ArchiveFormatZip:
+		return "zip"
+	case ArchiveFormatTar:
+		return "tar"
+	case ArchiveFormatTarGzip:
+		return "tar.gz"
+	}
+	return fmt.Sprintf("ArchiveFormat(%d)", f)
+}
+
+// maxSymlinkHops is the limit of symbolic links followed before returning
+// syscall.ELOOP. This is the same as MAXSYMLINKS on Linux.
+const maxSymlinkHops = 40
+
+// NewArchiveFS returns a read-only FS which serves the contents of an archive
+// without extracting it.
+//
+// The archive is indexed once, on creation. Reads use this index to go
+// directly to the entry's data in `r`, so Pread doesn't rescan the archive.
+//
+// # Notes
+//
+//   - ArchiveFormatZip requires the size of `r`, so it must implement a
+//     `Size() int64` method, like bytes.Reader, or `Stat`, like os.File.
+//   - Compressed zip entries can't be read randomly. Preads on them
+//     decompress forward from the last position, restarting on a backwards
+//     read.
+//   - Operations that would modify the archive return syscall.EROFS.
+func NewArchiveFS(r io.ReaderAt, format ArchiveFormat) (FS, error) {
+	a := &archiveFS{format: format, r: r, entries: map[string]*archiveEntry{}, dev: syntheticDev()}
+	a.add(".", &archiveEntry{mode: fs.ModeDir | 0o555})
+
+	var err error
+	switch format {
+	case ArchiveFormatZip:
+		err = a.indexZip()
+	case ArchiveFormatTar:
+		err = a.indexTar()
+	case ArchiveFormatTarGzip:
+		err = a.indexTarGzip()
+	default:
+		err = fmt.Errorf("unsupported archive format: %v", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range a.entries {
+		sort.Strings(e.children)
+	}
+	return a, nil
+}
+
+type archiveFS struct {
+	readOnlyFS
+	format  ArchiveFormat
+	r       io.ReaderAt
+	entries map[string]*archiveEntry
+	// dev is the synthetic device ID of all files in the archive.
+	dev uint64
+}
+
+// archiveEntry is an indexed file in the archive.
+type archiveEntry struct {
+	ino      uint64
+	mode     fs.FileMode
+	size     int64
+	mtim     int64
+	linkname string
+
+	// offset is the position of the entry's data in archiveFS.r, or -1 if the
+	// data is compressed and must be read via zf.
+	offset int64
+	zf     *zip.File
+
+	// children are the sorted base names of entries in this directory.
+	children []string
+}
+
+func (e *archiveEntry) stat(dev uint64) platform.Stat_t {
+	return platform.Stat_t{
+		Dev:   dev,
+		Ino:   e.ino,
+		Mode:  e.mode,
+		Nlink: 1,
+		Size:  e.size,
+		Atim:  e.mtim,
+		Mtim:  e.mtim,
+		Ctim:  e.mtim,
+	}
+}
+
+// sizer is implemented by types like bytes.Reader and io.SectionReader.
+type sizer interface{ Size() int64 }
+
+// readerAtSize returns the size of the input or false if it isn't known.
+func readerAtSize(r io.ReaderAt) (int64, bool) {
+	switch r := r.(type) {
+	case sizer:
+		return r.Size(), true
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if st, err := r.Stat(); err == nil {
+			return st.Size(), true
+		}
+	}
+	return 0, false
+}
+
+func (a *archiveFS) indexZip() error {
+	size, ok := readerAtSize(a.r)
+	if !ok {
+		return errors.New("zip archive requires a reader with a known size")
+	}
+	zr, err := zip.NewReader(a.r, size)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		name := cleanArchivePath(zf.Name)
+		if name == "" {
+			continue
+		}
+		e := &archiveEntry{
+			mode:   zf.Mode(),
+			size:   int64(zf.UncompressedSize64),
+			mtim:   zf.Modified.UnixNano(),
+			offset: -1,
+			zf:     zf,
+		}
+		if zf.Method == zip.Store {
+			if e.offset, err = zf.DataOffset(); err != nil {
+				return err
+			}
+		}
+		if e.mode&fs.ModeSymlink != 0 {
+			if e.linkname, err = readZipLink(zf); err != nil {
+				return err
+			}
+		}
+		a.add(name, e)
+	}
+	return nil
+}
+
+// readZipLink reads the target of a symbolic link, which zip stores as the
+// entry's contents.
+func readZipLink(zf *zip.File) (string, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	return string(b), err
+}
+
+func (a *archiveFS) indexTar() error {
+	size, ok := readerAtSize(a.r)
+	if !ok {
+		size = math.MaxInt64
+	}
+	// tar.Reader reads headers in whole blocks, and skips data via Seek. This
+	// means the current position of the section reader after Next is the
+	// offset of the entry's data.
+	sr := io.NewSectionReader(a.r, 0, size)
+	tr := tar.NewReader(sr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		name := cleanArchivePath(hdr.Name)
+		if name == "" {
+			continue
+		}
+		offset, err := sr.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		e := &archiveEntry{
+			mode:     hdr.FileInfo().Mode(),
+			mtim:     hdr.ModTime.UnixNano(),
+			linkname: hdr.Linkname,
+			offset:   offset,
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			e.size = hdr.Size
+		case tar.TypeLink:
+			// A hard link shares the data of an earlier entry.
+			target, ok := a.entries[cleanArchivePath(hdr.Linkname)]
+			if !ok || !target.mode.IsRegular() {
+				return fmt.Errorf("invalid hard link %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			e.mode, e.size, e.offset, e.linkname = target.mode, target.size, target.offset, ""
+		case tar.TypeSymlink:
+			e.size = int64(len(hdr.Linkname))
+		}
+		a.add(name, e)
+		if hdr.Typeflag == tar.TypeLink {
+			e.ino = a.entries[cleanArchivePath(hdr.Linkname)].ino // same file
+		}
+	}
+}
+
+func (a *archiveFS) indexTarGzip() error {
+	size, ok := readerAtSize(a.r)
+	if !ok {
+		size = math.MaxInt64
+	}
+	zr, err := gzip.NewReader(io.NewSectionReader(a.r, 0, size))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+	a.r = bytes.NewReader(b)
+	return a.indexTar()
+}
+
+// cleanArchivePath returns the archive name as a relative path, or empty if
+// it is the root or escapes it.
+func cleanArchivePath(name string) string {
+	name = cleanPath(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return ""
+	}
+	return name
+}
+
+// add indexes the entry, creating any parent directories not present in the
+// archive. When the name is a duplicate, the later entry wins.
+func (a *archiveFS) add(name string, e *archiveEntry) {
+	if existing, ok := a.entries[name]; ok {
+		e.ino = existing.ino
+		if e.mode.IsDir() {
+			e.children = existing.children
+		}
+		a.entries[name] = e
+		return
+	}
+	e.ino = uint64(len(a.entries) + 1)
+	a.entries[name] = e
+	if name == "." {
+		return
+	}
+
+	dir, base := path.Split(name)
+	dir = cleanPath(dir)
+	if dir == "" {
+		dir = "."
+	}
+	parent, ok := a.entries[dir]
+	if !ok {
+		parent = &archiveEntry{mode: fs.ModeDir | 0o555, mtim: e.mtim}
+		a.add(dir, parent)
+	}
+	parent.children = append(parent.children, base)
+}
+
+// lookup returns the entry at the path, following symbolic links in parent
+// directories, and the last element when `followLast` is true.
+func (a *archiveFS) lookup(name string, followLast bool) (string, *archiveEntry, syscall.Errno) {
+	name = cleanPath(name)
+	if name == "" || name == "." {
+		return ".", a.entries["."], 0
+	} else if name == ".." || strings.HasPrefix(name, "../") {
+		return "", nil, syscall.ENOENT
+	}
+
+	parts := strings.Split(name, "/")
+	cur, hops := ".", 0
+	for i := 0; i < len(parts); i++ {
+		next := path.Join(cur, parts[i])
+		e, ok := a.entries[next]
+		if !ok {
+			return "", nil, syscall.ENOENT
+		}
+
+		last := i == len(parts)-1
+		if e.mode&fs.ModeSymlink != 0 && (!last || followLast) {
+			if hops++; hops > maxSymlinkHops {
+				return "", nil, syscall.ELOOP
+			}
+			target := e.linkname
+			if !path.IsAbs(target) {
+				target = path.Join(cur, target)
+			}
+			if target = cleanPath(target); target == ".." || strings.HasPrefix(target, "../") {
+				return "", nil, syscall.ENOENT // escapes the archive
+			}
+			rest := parts[i+1:]
+			if target == "." {
+				parts = rest
+			} else {
+				parts = append(strings.Split(target, "/"), rest...)
+			}
+			cur, i = ".", -1
+			continue
+		}
+
+		if !last && !e.mode.IsDir() {
+			return "", nil, syscall.ENOTDIR
+		}
+		cur = next
+	}
+	return cur, a.entries[cur], 0
+}
+
+// String implements fmt.Stringer
+func (a *archiveFS) String() string {
+	return a.format.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (a *archiveFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, syscall.EROFS
+	}
+
+	name, e, errno := a.lookup(path, flag&platform.O_NOFOLLOW == 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	switch {
+	case e.mode.IsDir():
+		return &archiveDir{path: path, name: name, fs: a, e: e}, 0
+	case flag&platform.O_DIRECTORY != 0:
+		return nil, syscall.ENOTDIR
+	case e.mode&fs.ModeSymlink != 0:
+		return nil, syscall.ELOOP // O_NOFOLLOW
+	}
+	return &archiveFile{path: path, fs: a, e: e}, 0
+}
+
+// Lstat implements FS.Lstat
+func (a *archiveFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	_, e, errno := a.lookup(path, false)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return e.stat(a.dev), 0
+}
+
+// Stat implements FS.Stat
+func (a *archiveFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	_, e, errno := a.lookup(path, true)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return e.stat(a.dev), 0
+}
+
+// Readlink implements FS.Readlink
+func (a *archiveFS) Readlink(path string) (string, syscall.Errno) {
+	_, e, errno := a.lookup(path, false)
+	if errno != 0 {
+		return "", errno
+	} else if e.mode&fs.ModeSymlink == 0 {
+		return "", syscall.EINVAL
+	}
+	return e.linkname, 0
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (a *archiveFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	_, e, errno := a.lookup(path, false)
+	if errno != 0 {
+		return 0, errno
+	} else if e.mode&fs.ModeSymlink == 0 {
+		return 0, syscall.EINVAL
+	}
+	return copy(buf, e.linkname), 0
+}
+
+// compile-time check to ensure archiveFile implements platform.File.
+var _ platform.File = (*archiveFile)(nil)
+
+// archiveFile is a regular file in an archive, opened for reading.
+type archiveFile struct {
+	platform.UnimplementedFile
+
+	path   string
+	fs     *archiveFS
+	e      *archiveEntry
+	offset int64
+	closed bool
+
+	// zr is the decompressing reader of a compressed zip entry, positioned
+	// at zrPos.
+	zr    io.ReadCloser
+	zrPos int64
+}
+
+// Path implements the same method as documented on platform.File
+func (f *archiveFile) Path() string {
+	return f.path
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *archiveFile) AccessMode() int {
+	return syscall.O_RDONLY
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *archiveFile) Stat() (platform.Stat_t, syscall.Errno) {
+	if f.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return f.e.stat(f.fs.dev), 0
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *archiveFile) IsDir() (bool, syscall.Errno) {
+	return false, 0
+}
+
+// Read implements the same method as documented on platform.File
+func (f *archiveFile) Read(buf []byte) (n int, errno syscall.Errno) {
+	if n, errno = f.Pread(buf, f.offset); errno == 0 {
+		f.offset += int64(n)
+	}
+	return
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *archiveFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if off >= f.e.size || len(buf) == 0 {
+		return 0, 0
+	}
+
+	if remaining := f.e.size - off; int64(len(buf)) > remaining {
+		buf = buf[:remaining]
+	}
+	if f.e.offset >= 0 {
+		n, err := f.fs.r.ReadAt(buf, f.e.offset+off)
+		return n, platform.UnwrapReadError(n, err)
+	}
+	return f.preadCompressed(buf, off)
+}
+
+// preadCompressed reads from a compressed zip entry, which can only be read
+// forward. Backwards reads restart decompression from the beginning.
+func (f *archiveFile) preadCompressed(buf []byte, off int64) (int, syscall.Errno) {
+	if f.zr == nil || off < f.zrPos {
+		if f.zr != nil {
+			_ = f.zr.Close()
+		}
+		zr, err := f.e.zf.Open()
+		if err != nil {
+			f.zr = nil
+			return 0, platform.UnwrapOSError(err)
+		}
+		f.zr, f.zrPos = zr, 0
+	}
+	if skip := off - f.zrPos; skip > 0 {
+		n, err := io.CopyN(io.Discard, f.zr, skip)
+		f.zrPos += n
+		if err != nil {
+			return 0, platform.UnwrapOSError(err)
+		}
+	}
+	n, err := io.ReadFull(f.zr, buf)
+	f.zrPos += int64(n)
+	return n, platform.UnwrapReadError(n, err)
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *archiveFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.e.size
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *archiveFile) PollRead(*time.Duration) (ready bool, errno syscall.Errno) {
+	return true, 0 // archive data is always available.
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *archiveFile) Readdir(int) ([]platform.Dirent, syscall.Errno) {
+	return nil, syscall.ENOTDIR
+}
+
+// Write implements the same method as documented on platform.File
+func (f *archiveFile) Write([]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *archiveFile) Writev([][]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *archiveFile) Pwrite([]byte, int64) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *archiveFile) Truncate(int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *archiveFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *archiveFile) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *archiveFile) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *archiveFile) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Close implements the same method as documented on platform.File
+func (f *archiveFile) Close() syscall.Errno {
+	if f.closed {
+		return 0
+	}
+	f.closed = true
+	if f.zr != nil {
+		return platform.UnwrapOSError(f.zr.Close())
+	}
+	return 0
+}
+
+// compile-time check to ensure archiveDir implements platform.File.
+var _ platform.File = (*archiveDir)(nil)
+
+// archiveDir is a directory in an archive, opened for reading.
+type archiveDir struct {
+	platform.DirFile
+
+	path      string
+	name      string // the resolved name of e in archiveFS.entries
+	fs        *archiveFS
+	e         *archiveEntry
+	childrenI int // the read offset, an index into e.children
+	closed    bool
+}
+
+// Path implements the same method as documented on platform.File
+func (d *archiveDir) Path() string {
+	return d.path
+}
+
+// Stat implements the same method as documented on platform.File
+func (d *archiveDir) Stat() (platform.Stat_t, syscall.Errno) {
+	if d.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return d.e.stat(d.fs.dev), 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (d *archiveDir) Readdir(count int) (dirents []platform.Dirent, errno syscall.Errno) {
+	if d.closed {
+		return // See platform.File Readdir notes on closed directories.
+	}
+
+	n := len(d.e.children) - d.childrenI
+	if n == 0 {
+		return
+	}
+	if count > 0 && n > count {
+		n = count
+	}
+	prefix := ""
+	if d.name != "." {
+		prefix = d.name + "/"
+	}
+	dirents = make([]platform.Dirent, n)
+	for i := range dirents {
+		name := d.e.children[d.childrenI+i]
+		e := d.fs.entries[prefix+name]
+		dirents[i] = platform.Dirent{Name: name, Ino: e.ino, Type: e.mode.Type()}
+	}
+	d.childrenI += n
+	return
+}
+
+// ReaddirFrom implements the same method as documented on
+// platform.ReaddirFrom. The cookie is an index.
+func (d *archiveDir) ReaddirFrom(cookie uint64, n int) ([]platform.Dirent, syscall.Errno) {
+	if d.closed {
+		return nil, 0 // See platform.File Readdir notes on closed directories.
+	}
+	if cookie > uint64(len(d.e.children)) {
+		cookie = uint64(len(d.e.children))
+	}
+	d.childrenI = int(cookie)
+	dirents, errno := d.Readdir(n)
+	for i := range dirents {
+		cookie++
+		dirents[i].Cookie = cookie
+	}
+	return dirents, errno
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (d *archiveDir) RewindDir() syscall.Errno {
+	if d.closed {
+		return syscall.EBADF
+	}
+	d.childrenI = 0
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (d *archiveDir) Sync() syscall.Errno {
+	return 0
+}
+
+// Datasync implements the same method as documented on platform.File
+func (d *archiveDir) Datasync() syscall.Errno {
+	return 0
+}
+
+// Chmod implements the same method as documented on platform.File
+func (d *archiveDir) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (d *archiveDir) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (d *archiveDir) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Close implements the same method as documented on platform.File
+func (d *archiveDir) Close() syscall.Errno {
+	d.closed = true
+	return 0
+}
@@ -0,0 +1,249 @@
+package sysfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewArchiveFS(t *testing.T) {
+	for _, tc := range []struct {
+		format ArchiveFormat
+		data   []byte
+	}{
+		{format: ArchiveFormatZip, data: zipTestFiles(t, zip.Store)},
+		{format: ArchiveFormatZip, data: zipTestFiles(t, zip.Deflate)},
+		{format: ArchiveFormatTar, data: tarTestFiles(t)},
+		{format: ArchiveFormatTarGzip, data: gzipBytes(t, tarTestFiles(t))},
+	} {
+		tc := tc
+		t.Run(tc.format.String(), func(t *testing.T) {
+			testFS, err := NewArchiveFS(bytes.NewReader(tc.data), tc.format)
+			require.NoError(t, err)
+			require.Equal(t, tc.format.String(), testFS.String())
+
+			testOpen_Read(t, testFS, true)
+
+			t.Run("Pread backwards", func(t *testing.T) {
+				f, errno := testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+				require.EqualErrno(t, 0, errno)
+				defer f.Close()
+
+				buf := make([]byte, 4)
+				requirePread(t, f, buf, 10)
+				require.Equal(t, "hark", string(buf))
+				requirePread(t, f, buf, 0)
+				require.Equal(t, "bear", string(buf))
+			})
+
+			t.Run("Stat", func(t *testing.T) {
+				st, errno := testFS.Stat("sub/test.txt")
+				require.EqualErrno(t, 0, errno)
+				require.Equal(t, fs.FileMode(0o444), st.Mode)
+				require.Equal(t, int64(14), st.Size)
+				require.Equal(t, time.Unix(1672531200, 0).UnixNano(), st.Mtim)
+
+				st, errno = testFS.Stat("sub")
+				require.EqualErrno(t, 0, errno)
+				require.True(t, st.Mode.IsDir())
+
+				_, errno = testFS.Stat("sub/test.txt/nope")
+				require.EqualErrno(t, syscall.ENOTDIR, errno)
+				_, errno = testFS.Stat("../animals.txt")
+				require.EqualErrno(t, syscall.ENOENT, errno)
+			})
+
+			t.Run("EROFS", func(t *testing.T) {
+				_, errno := testFS.OpenFile("animals.txt", os.O_RDWR, 0)
+				require.EqualErrno(t, syscall.EROFS, errno)
+				_, errno = testFS.OpenFile("new.txt", os.O_RDONLY|os.O_CREATE, 0o600)
+				require.EqualErrno(t, syscall.EROFS, errno)
+				require.EqualErrno(t, syscall.EROFS, testFS.Mkdir("dir2", 0o700))
+				require.EqualErrno(t, syscall.EROFS, testFS.Unlink("animals.txt"))
+				require.EqualErrno(t, syscall.EROFS, testFS.Truncate("animals.txt", 0))
+			})
+		})
+	}
+}
+
+func TestNewArchiveFS_Symlink(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	requireTarFile(t, tw, &tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755}, nil)
+	requireTarFile(t, tw, &tar.Header{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0o644}, []byte("hello"))
+	requireTarFile(t, tw, &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "dir/file"}, nil)
+	requireTarFile(t, tw, &tar.Header{Name: "dirlink", Typeflag: tar.TypeSymlink, Linkname: "dir"}, nil)
+	requireTarFile(t, tw, &tar.Header{Name: "escape", Typeflag: tar.TypeSymlink, Linkname: "../etc/passwd"}, nil)
+	requireTarFile(t, tw, &tar.Header{Name: "loop", Typeflag: tar.TypeSymlink, Linkname: "loop"}, nil)
+	requireTarFile(t, tw, &tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "dir/file"}, nil)
+	require.NoError(t, tw.Close())
+
+	testFS, err := NewArchiveFS(bytes.NewReader(buf.Bytes()), ArchiveFormatTar)
+	require.NoError(t, err)
+
+	dst, errno := testFS.Readlink("link")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "dir/file", dst)
+
+	_, errno = testFS.Readlink("dir/file")
+	require.EqualErrno(t, syscall.EINVAL, errno)
+
+	st, errno := testFS.Lstat("link")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeSymlink, st.Mode.Type())
+
+	st, errno = testFS.Stat("link")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(5), st.Size)
+
+	for _, name := range []string{"link", "dirlink/file", "hard"} {
+		f, errno := testFS.OpenFile(name, os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []byte("hello"), readAll(t, f))
+		require.EqualErrno(t, 0, f.Close())
+	}
+
+	_, errno = testFS.OpenFile("link", os.O_RDONLY|platform.O_NOFOLLOW, 0)
+	require.EqualErrno(t, syscall.ELOOP, errno)
+
+	// Links are the same file as their target.
+	fileSt, errno := testFS.Stat("dir/file")
+	require.EqualErrno(t, 0, errno)
+	for _, name := range []string{"link", "hard"} {
+		st, errno = testFS.Stat(name)
+		require.EqualErrno(t, 0, errno)
+		require.True(t, platform.SameFile(fileSt, st))
+	}
+	st, errno = testFS.Lstat("link")
+	require.EqualErrno(t, 0, errno)
+	require.False(t, platform.SameFile(fileSt, st))
+
+	// The same entry in another archive is a different file.
+	otherFS, err := NewArchiveFS(bytes.NewReader(buf.Bytes()), ArchiveFormatTar)
+	require.NoError(t, err)
+	st, errno = otherFS.Stat("dir/file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fileSt.Ino, st.Ino)
+	require.False(t, platform.SameFile(fileSt, st))
+
+	_, errno = testFS.Stat("escape")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	_, errno = testFS.Stat("loop")
+	require.EqualErrno(t, syscall.ELOOP, errno)
+}
+
+func TestNewArchiveFS_Errors(t *testing.T) {
+	_, err := NewArchiveFS(bytes.NewReader([]byte("not a zip")), ArchiveFormatZip)
+	require.Error(t, err)
+
+	_, err = NewArchiveFS(onlyReaderAt{bytes.NewReader(zipTestFiles(t, zip.Store))}, ArchiveFormatZip)
+	require.EqualError(t, err, "zip archive requires a reader with a known size")
+
+	_, err = NewArchiveFS(bytes.NewReader(nil), ArchiveFormat(10))
+	require.EqualError(t, err, "unsupported archive format: ArchiveFormat(10)")
+}
+
+// onlyReaderAt hides any Size method of the underlying reader.
+type onlyReaderAt struct{ r io.ReaderAt }
+
+func (o onlyReaderAt) ReadAt(p []byte, off int64) (int, error) { return o.r.ReadAt(p, off) }
+
+func zipTestFiles(t *testing.T, method uint16) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	err := fs.WalkDir(fstest.FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name, hdr.Method = path, method
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(fstest.FS, path)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func tarTestFiles(t *testing.T) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := fs.WalkDir(fstest.FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = path
+		if err = tw.WriteHeader(hdr); err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(fstest.FS, path)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(b)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func requireTarFile(t *testing.T, tw *tar.Writer, hdr *tar.Header, data []byte) {
+	hdr.Size = int64(len(data))
+	require.NoError(t, tw.WriteHeader(hdr))
+	_, err := tw.Write(data)
+	require.NoError(t, err)
+}
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func requirePread(t *testing.T, f platform.File, buf []byte, off int64) {
+	n, errno := f.Pread(buf, off)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, len(buf), n)
+}
@@ -0,0 +1,201 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// AsFsFS returns a read-only fs.FS view of the input, for use with standard
+// library functions such as fs.WalkDir. This is the inverse of Adapt.
+//
+// The result also implements fs.ReadDirFS and fs.StatFS. Non-zero
+// syscall.Errno values are returned wrapped in fs.PathError, so errors.Is
+// works with sentinels such as fs.ErrNotExist.
+func AsFsFS(fs FS) fs.FS {
+	if a, ok := fs.(*adapter); ok {
+		return a.fs
+	}
+	return &fsFS{fs: fs}
+}
+
+type fsFS struct {
+	fs FS
+}
+
+// String implements fmt.Stringer
+func (f *fsFS) String() string {
+	return f.fs.String()
+}
+
+// Open implements fs.FS
+func (f *fsFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	file, errno := f.fs.OpenFile(name, syscall.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errno}
+	}
+	return &fsFSFile{fs: f.fs, f: file, name: name}, nil
+}
+
+// Stat implements fs.StatFS
+func (f *fsFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	st, errno := f.fs.Stat(name)
+	if errno != 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: errno}
+	}
+	return &statInfo{name: path.Base(name), st: st}, nil
+}
+
+// ReadDir implements fs.ReadDirFS
+func (f *fsFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := file.(*fsFSFile).ReadDir(-1)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, err
+}
+
+// fsFSFile adapts platform.File to fs.File, fs.ReadDirFile and io.Seeker.
+type fsFSFile struct {
+	fs   FS
+	f    platform.File
+	name string
+}
+
+// Stat implements fs.File
+func (f *fsFSFile) Stat() (fs.FileInfo, error) {
+	st, errno := f.f.Stat()
+	if errno != 0 {
+		return nil, f.pathError("stat", errno)
+	}
+	return &statInfo{name: path.Base(f.name), st: st}, nil
+}
+
+// Read implements fs.File
+func (f *fsFSFile) Read(p []byte) (int, error) {
+	n, errno := f.f.Read(p)
+	if errno != 0 {
+		return n, f.pathError("read", errno)
+	} else if n == 0 && len(p) > 0 {
+		return 0, io.EOF // platform.File doesn't return io.EOF.
+	}
+	return n, nil
+}
+
+// Seek implements io.Seeker
+func (f *fsFSFile) Seek(offset int64, whence int) (int64, error) {
+	newOffset, errno := f.f.Seek(offset, whence)
+	if errno != 0 {
+		return newOffset, f.pathError("seek", errno)
+	}
+	return newOffset, nil
+}
+
+// ReadDir implements fs.ReadDirFile
+func (f *fsFSFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	dirents, errno := f.f.Readdir(n)
+	if errno != 0 {
+		return nil, f.pathError("readdir", errno)
+	} else if n > 0 && len(dirents) == 0 {
+		return nil, io.EOF
+	}
+	entries := make([]fs.DirEntry, 0, len(dirents))
+	for _, d := range dirents {
+		entries = append(entries, &dirEntry{dir: f, d: d})
+	}
+	return entries, nil
+}
+
+// Close implements fs.File
+func (f *fsFSFile) Close() error {
+	if errno := f.f.Close(); errno != 0 {
+		return f.pathError("close", errno)
+	}
+	return nil
+}
+
+func (f *fsFSFile) pathError(op string, errno syscall.Errno) error {
+	return &fs.PathError{Op: op, Path: f.name, Err: errno}
+}
+
+// dirEntry adapts platform.Dirent to fs.DirEntry.
+type dirEntry struct {
+	dir *fsFSFile
+	d   platform.Dirent
+}
+
+// Name implements fs.DirEntry
+func (e *dirEntry) Name() string {
+	return e.d.Name
+}
+
+// IsDir implements fs.DirEntry
+func (e *dirEntry) IsDir() bool {
+	return e.d.IsDir()
+}
+
+// Type implements fs.DirEntry
+func (e *dirEntry) Type() fs.FileMode {
+	return e.d.Type
+}
+
+// Info implements fs.DirEntry
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	name := path.Join(e.dir.name, e.d.Name)
+	st, errno := e.dir.fs.Lstat(name)
+	if errno != 0 {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: errno}
+	}
+	return &statInfo{name: e.d.Name, st: st}, nil
+}
+
+// statInfo adapts platform.Stat_t to fs.FileInfo.
+type statInfo struct {
+	name string
+	st   platform.Stat_t
+}
+
+// Name implements fs.FileInfo
+func (i *statInfo) Name() string {
+	return i.name
+}
+
+// Size implements fs.FileInfo
+func (i *statInfo) Size() int64 {
+	return i.st.Size
+}
+
+// Mode implements fs.FileInfo
+func (i *statInfo) Mode() fs.FileMode {
+	return i.st.Mode
+}
+
+// ModTime implements fs.FileInfo
+func (i *statInfo) ModTime() time.Time {
+	return time.Unix(0, i.st.Mtim)
+}
+
+// IsDir implements fs.FileInfo
+func (i *statInfo) IsDir() bool {
+	return i.st.Mode.IsDir()
+}
+
+// Sys implements fs.FileInfo, returning the platform.Stat_t.
+func (i *statInfo) Sys() interface{} {
+	return &i.st
+}
@@ -0,0 +1,60 @@
+package sysfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	gofstest "testing/fstest"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestAsFsFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	testFS := AsFsFS(NewDirFS(tmpDir))
+
+	// Use the standard library to verify the fs.FS contract.
+	require.NoError(t, gofstest.TestFS(testFS, "animals.txt", "dir", "empty.txt", "emptydir", "sub/test.txt"))
+
+	t.Run("WalkDir", func(t *testing.T) {
+		var paths []string
+		err := fs.WalkDir(testFS, "sub", func(path string, _ fs.DirEntry, err error) error {
+			paths = append(paths, path)
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"sub", "sub/test.txt"}, paths)
+	})
+
+	t.Run("ReadFile returns io.EOF at the end", func(t *testing.T) {
+		f, err := testFS.Open("empty.txt")
+		require.NoError(t, err)
+		defer f.Close()
+		_, err = f.Read(make([]byte, 1))
+		require.Equal(t, io.EOF, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := testFS.Open("missing")
+		require.True(t, errors.Is(err, fs.ErrNotExist))
+		var pe *fs.PathError
+		require.True(t, errors.As(err, &pe))
+		require.EqualErrno(t, syscall.ENOENT, pe.Err.(syscall.Errno))
+
+		_, err = fs.Stat(testFS, "/animals.txt")
+		require.True(t, errors.Is(err, fs.ErrInvalid))
+	})
+}
+
+func TestAsFsFS_Adapt(t *testing.T) {
+	dirFS := os.DirFS(t.TempDir())
+	require.Equal(t, dirFS, AsFsFS(Adapt(dirFS)))
+
+	testFS := NewDirFS(t.TempDir())
+	require.Equal(t, testFS, Adapt(AsFsFS(testFS)))
+}
@@ -0,0 +1,256 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// AtFS is an optional interface implemented by an FS which can resolve paths
+// relative to a directory it opened, like the *at family of POSIX functions.
+//
+// WASI path operations are relative to a directory file descriptor. Resolving
+// them against the directory, instead of joining its path to the FS root,
+// avoids races with renames of the directory or its parents.
+//
+// Use OpenFileAt and similar, which fall back to path joining when the FS
+// doesn't implement this interface.
+//
+// # Notes
+//
+//   - Parameters and errors are the same as the corresponding FS method,
+//     except relative paths resolve against `dir`, which must have been
+//     opened by the same FS.
+//   - An absolute path resolves against the root of the FS, not the host.
+//   - The FS returned by NewDirFS only resolves relative to `dir` on Linux,
+//     where platform.Openat and similar are implemented. On other platforms,
+//     such as darwin and FreeBSD, it falls back to path joining, so doesn't
+//     protect against renames of `dir` or its parents during the call.
+type AtFS interface {
+	// OpenFileAt is like FS.OpenFile, except relative to `dir`.
+	OpenFileAt(dir platform.File, path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno)
+
+	// StatAt is like FS.Stat, except relative to `dir`.
+	StatAt(dir platform.File, path string) (platform.Stat_t, syscall.Errno)
+
+	// MkdirAt is like FS.Mkdir, except relative to `dir`.
+	MkdirAt(dir platform.File, path string, perm fs.FileMode) syscall.Errno
+
+	// UnlinkAt is like FS.Unlink, except relative to `dir`.
+	UnlinkAt(dir platform.File, path string) syscall.Errno
+
+	// SymlinkAt is like FS.Symlink, except `link` is relative to `dir`.
+	SymlinkAt(target string, dir platform.File, link string) syscall.Errno
+
+	// LinkAt is like FS.Link, except `oldPath` is relative to `oldDir` and
+	// `newPath` is relative to `newDir`.
+	LinkAt(oldDir platform.File, oldPath string, newDir platform.File, newPath string) syscall.Errno
+}
+
+// OpenFileAt calls AtFS.OpenFileAt if implemented by `fs`, or FS.OpenFile
+// with `path` joined to the path of `dir` otherwise.
+func OpenFileAt(fs FS, dir platform.File, path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if atFS, ok := fs.(AtFS); ok {
+		return atFS.OpenFileAt(dir, path, flag, perm)
+	}
+	return fs.OpenFile(joinAt(dir, path), flag, perm)
+}
+
+// StatAt calls AtFS.StatAt if implemented by `fs`, or FS.Stat with `path`
+// joined to the path of `dir` otherwise.
+func StatAt(fs FS, dir platform.File, path string) (platform.Stat_t, syscall.Errno) {
+	if atFS, ok := fs.(AtFS); ok {
+		return atFS.StatAt(dir, path)
+	}
+	return fs.Stat(joinAt(dir, path))
+}
+
+// MkdirAt calls AtFS.MkdirAt if implemented by `fs`, or FS.Mkdir with `path`
+// joined to the path of `dir` otherwise.
+func MkdirAt(fs FS, dir platform.File, path string, perm fs.FileMode) syscall.Errno {
+	if atFS, ok := fs.(AtFS); ok {
+		return atFS.MkdirAt(dir, path, perm)
+	}
+	return fs.Mkdir(joinAt(dir, path), perm)
+}
+
+// UnlinkAt calls AtFS.UnlinkAt if implemented by `fs`, or FS.Unlink with
+// `path` joined to the path of `dir` otherwise.
+func UnlinkAt(fs FS, dir platform.File, path string) syscall.Errno {
+	if atFS, ok := fs.(AtFS); ok {
+		return atFS.UnlinkAt(dir, path)
+	}
+	return fs.Unlink(joinAt(dir, path))
+}
+
+// SymlinkAt calls AtFS.SymlinkAt if implemented by `fs`, or FS.Symlink with
+// `link` joined to the path of `dir` otherwise.
+func SymlinkAt(fs FS, target string, dir platform.File, link string) syscall.Errno {
+	if atFS, ok := fs.(AtFS); ok {
+		return atFS.SymlinkAt(target, dir, link)
+	}
+	return fs.Symlink(target, joinAt(dir, link))
+}
+
+// LinkAt calls AtFS.LinkAt if implemented by `fs`, or FS.Link with each path
+// joined to the path of its directory otherwise.
+func LinkAt(fs FS, oldDir platform.File, oldPath string, newDir platform.File, newPath string) syscall.Errno {
+	if atFS, ok := fs.(AtFS); ok {
+		return atFS.LinkAt(oldDir, oldPath, newDir, newPath)
+	}
+	return fs.Link(joinAt(oldDir, oldPath), joinAt(newDir, newPath))
+}
+
+// joinAt returns the path relative to the FS that opened `dir`. An absolute
+// `p` is relative to the root of that FS instead.
+func joinAt(dir platform.File, p string) string {
+	if path.IsAbs(p) {
+		return cleanPath(p)
+	}
+	return path.Join(dir.Path(), p)
+}
+
+// compile-time check to ensure dirFS implements AtFS.
+var _ AtFS = (*dirFS)(nil)
+
+// OpenFileAt implements AtFS.OpenFileAt
+func (d *dirFS) OpenFileAt(dir platform.File, path string, flag int, perm fs.FileMode) (f platform.File, errno syscall.Errno) {
+	if errno = d.validatePaths(path); errno != 0 {
+		return
+	}
+	if d.createOwner && flag&os.O_CREATE != 0 {
+		f, errno = d.createOwned(flag, func(flag int) (platform.File, syscall.Errno) {
+			return d.openFileAt(dir, path, flag, perm)
+		}, func() syscall.Errno {
+			return d.UnlinkAt(dir, path)
+		})
+	} else {
+		f, errno = d.openFileAt(dir, path, flag, perm)
+	}
+	if errno == 0 {
+		d.firstOpen.opened(joinAt(dir, path))
+	}
+	return
+}
+
+func (d *dirFS) openFileAt(dir platform.File, path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if isAbsOrParent(path) || d.atime == NoAtime {
+		// Openat can't add O_NOATIME, so NoAtime opens by path instead.
+		return d.OpenFile(joinAt(dir, path), flag, perm)
+	}
+	if errno := d.openLimit.acquire(); errno != 0 {
+		return nil, errno
+	}
+	f, errno := platform.Openat(dir, path, flag, perm&^d.umask)
+	if errno == 0 {
+		return d.wrap(platform.NewFsFile(joinAt(dir, path), flag, f), flag), 0
+	}
+	d.openLimit.release()
+	if errno == syscall.ENOSYS {
+		return d.OpenFile(joinAt(dir, path), flag, perm)
+	}
+	return nil, errno
+}
+
+// StatAt implements AtFS.StatAt
+func (d *dirFS) StatAt(dir platform.File, path string) (platform.Stat_t, syscall.Errno) {
+	if errno := d.validatePaths(path); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	if isAbsOrParent(path) {
+		return d.Stat(joinAt(dir, path))
+	}
+	st, errno := platform.Statat(dir, path, true)
+	if errno == syscall.ENOSYS {
+		return d.Stat(joinAt(dir, path))
+	}
+	return st, errno
+}
+
+// MkdirAt implements AtFS.MkdirAt
+func (d *dirFS) MkdirAt(dir platform.File, path string, perm fs.FileMode) syscall.Errno {
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	}
+	if isAbsOrParent(path) {
+		return d.Mkdir(joinAt(dir, path), perm)
+	}
+	switch errno := platform.Mkdirat(dir, path, perm&^d.umask); errno {
+	case 0:
+		if !d.createOwner {
+			return 0
+		}
+		return d.chownCreatedDir(func() (platform.File, syscall.Errno) {
+			return d.openFileAt(dir, path, createdDirFlag, 0)
+		}, func() syscall.Errno {
+			return d.Rmdir(joinAt(dir, path))
+		})
+	case syscall.ENOSYS:
+		return d.Mkdir(joinAt(dir, path), perm)
+	case syscall.ENOTDIR:
+		return syscall.ENOENT // same as Mkdir
+	default:
+		return errno
+	}
+}
+
+// UnlinkAt implements AtFS.UnlinkAt
+func (d *dirFS) UnlinkAt(dir platform.File, path string) syscall.Errno {
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	}
+	if isAbsOrParent(path) {
+		return d.Unlink(joinAt(dir, path))
+	}
+	errno := platform.Unlinkat(dir, path)
+	if errno == syscall.ENOSYS {
+		return d.Unlink(joinAt(dir, path))
+	}
+	return errno
+}
+
+// SymlinkAt implements AtFS.SymlinkAt
+func (d *dirFS) SymlinkAt(target string, dir platform.File, link string) syscall.Errno {
+	if errno := d.validatePaths(target, link); errno != 0 {
+		return errno
+	}
+	if isAbsOrParent(link) {
+		return d.Symlink(target, joinAt(dir, link))
+	}
+	// Like Symlink, `target` isn't resolved, as it is resolved when the link
+	// is followed.
+	errno := platform.Symlinkat(target, dir, link)
+	if errno == syscall.ENOSYS {
+		return d.Symlink(target, joinAt(dir, link))
+	}
+	return errno
+}
+
+// LinkAt implements AtFS.LinkAt
+func (d *dirFS) LinkAt(oldDir platform.File, oldPath string, newDir platform.File, newPath string) syscall.Errno {
+	if errno := d.validatePaths(oldPath, newPath); errno != 0 {
+		return errno
+	}
+	if isAbsOrParent(oldPath) || isAbsOrParent(newPath) {
+		return d.Link(joinAt(oldDir, oldPath), joinAt(newDir, newPath))
+	}
+	errno := platform.Linkat(oldDir, oldPath, newDir, newPath)
+	if errno == syscall.ENOSYS {
+		return d.Link(joinAt(oldDir, oldPath), joinAt(newDir, newPath))
+	}
+	return errno
+}
+
+// isAbsOrParent returns true if the path could resolve outside the directory
+// it is relative to, in which case the *at functions can't be used as the
+// host would resolve it outside this FS.
+func isAbsOrParent(p string) bool {
+	if path.IsAbs(p) {
+		return true
+	}
+	cleaned := path.Clean(p)
+	return cleaned == ".." || len(cleaned) > 2 && cleaned[:3] == "../"
+}
@@ -0,0 +1,94 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestDirFS_At(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("TODO: *at functions on " + runtime.GOOS)
+	}
+
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	testFS := NewDirFS(tmpDir)
+
+	dir, errno := testFS.OpenFile("sub", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer dir.Close()
+
+	// Rename the directory while open: operations relative to the directory
+	// handle should still resolve against it.
+	require.NoError(t, os.Rename(path.Join(tmpDir, "sub"), path.Join(tmpDir, "moved")))
+
+	f, errno := OpenFileAt(testFS, dir, "test.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("greet sub dir\n"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	st, errno := StatAt(testFS, dir, "test.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(14), st.Size)
+
+	require.EqualErrno(t, 0, MkdirAt(testFS, dir, "newdir", 0o700))
+	st, errno = testFS.Stat("moved/newdir")
+	require.EqualErrno(t, 0, errno)
+	require.True(t, st.Mode.IsDir())
+	require.EqualErrno(t, syscall.EEXIST, MkdirAt(testFS, dir, "newdir", 0o700))
+
+	require.EqualErrno(t, 0, SymlinkAt(testFS, "test.txt", dir, "link"))
+	target, errno := testFS.Readlink("moved/link")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "test.txt", target)
+
+	require.EqualErrno(t, 0, LinkAt(testFS, dir, "test.txt", dir, "newdir/hard"))
+	st, errno = testFS.Stat("moved/newdir/hard")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(14), st.Size)
+
+	require.EqualErrno(t, 0, UnlinkAt(testFS, dir, "test.txt"))
+	_, errno = testFS.Stat("moved/test.txt")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	// Absolute paths resolve against the root of the FS, not the directory.
+	st, errno = StatAt(testFS, dir, "/animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.False(t, st.Mode.IsDir())
+}
+
+func TestAdapt_At(t *testing.T) {
+	testFS := Adapt(fstest.FS)
+
+	dir, errno := testFS.OpenFile("sub", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer dir.Close()
+
+	// The adapter doesn't implement AtFS, so paths are joined instead.
+	_, ok := testFS.(AtFS)
+	require.False(t, ok)
+
+	f, errno := OpenFileAt(testFS, dir, "test.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("greet sub dir\n"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	st, errno := StatAt(testFS, dir, "test.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(14), st.Size)
+
+	_, errno = StatAt(testFS, dir, "missing")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	require.EqualErrno(t, syscall.ENOSYS, MkdirAt(testFS, dir, "newdir", 0o700))
+	require.EqualErrno(t, syscall.ENOSYS, UnlinkAt(testFS, dir, "test.txt"))
+	require.EqualErrno(t, syscall.ENOSYS, SymlinkAt(testFS, "test.txt", dir, "link"))
+	require.EqualErrno(t, syscall.ENOSYS, LinkAt(testFS, dir, "test.txt", dir, "hard"))
+}
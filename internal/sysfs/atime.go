@@ -0,0 +1,68 @@
+package sysfs
+
+import "time"
+
+// AtimePolicy decides when reading a file updates its access time, like the
+// `strictatime`, `relatime` and `noatime` options of a mount in Linux.
+type AtimePolicy int
+
+const (
+	// StrictAtime updates the access time on every read. This is the
+	// default.
+	StrictAtime AtimePolicy = iota
+
+	// RelAtime updates the access time on read, only if it is earlier than
+	// the modification or change time, or more than a day old. This keeps
+	// the access time useful to tools that check if a file was read since it
+	// was written, with far fewer updates than StrictAtime.
+	RelAtime
+
+	// NoAtime never updates the access time on read.
+	NoAtime
+)
+
+// relAtimeInterval is how old the access time must be for RelAtime to update
+// it, regardless of the modification time. This is the same as Linux.
+const relAtimeInterval = int64(24 * time.Hour)
+
+// shouldUpdateAtime returns true if a read at `now` should update the access
+// time `atim`, given the modification and change times.
+func (p AtimePolicy) shouldUpdateAtime(atim, mtim, ctim, now int64) bool {
+	switch p {
+	case NoAtime:
+		return false
+	case RelAtime:
+		return atim <= mtim || atim <= ctim || now-atim >= relAtimeInterval
+	}
+	return true
+}
+
+// WithAtime sets the AtimePolicy of an FS returned by NewDirFS.
+//
+// # Notes
+//
+//   - The host applies the policy of its mount first. For example, a read
+//     doesn't update the access time on a host mount with `noatime`,
+//     regardless of this option.
+//   - StrictAtime and RelAtime leave reads to the host, as it can't be told
+//     to update the access time more often. Most Linux hosts default to
+//     `relatime`.
+//   - NoAtime opens files with `O_NOATIME` on Linux, which the host only
+//     allows for files owned by the user of this process. Other files, and
+//     other platforms, update the access time as the host mount decides.
+//   - An fs.FS wrapped with Adapt can't control this, as it has no way to
+//     pass open flags to the host.
+func WithAtime(policy AtimePolicy) DirFSOption {
+	return func(d *dirFS) {
+		d.atime = policy
+	}
+}
+
+// WithMemAtime sets the AtimePolicy of an FS returned by NewMemFS. Unlike
+// WithAtime, this is implemented entirely in software, so all policies
+// behave as documented.
+func WithMemAtime(policy AtimePolicy) MemFSOption {
+	return func(m *memFS) {
+		m.atime = policy
+	}
+}
@@ -0,0 +1,104 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestAtimePolicy_shouldUpdateAtime(t *testing.T) {
+	now := int64(100 * time.Hour)
+	recent, old := now-int64(time.Hour), now-int64(25*time.Hour)
+
+	tests := []struct {
+		name             string
+		atim, mtim, ctim int64
+		expected         [3]bool // StrictAtime, RelAtime, NoAtime
+	}{
+		{name: "read since written", atim: recent, mtim: old, ctim: old, expected: [3]bool{true, false, false}},
+		{name: "written since read", atim: old, mtim: recent, ctim: recent, expected: [3]bool{true, true, false}},
+		{name: "changed since read", atim: recent - 1, mtim: old, ctim: recent, expected: [3]bool{true, true, false}},
+		{name: "read over a day ago", atim: old, mtim: old - 1, ctim: old - 1, expected: [3]bool{true, true, false}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			for i, p := range []AtimePolicy{StrictAtime, RelAtime, NoAtime} {
+				require.Equal(t, tc.expected[i], p.shouldUpdateAtime(tc.atim, tc.mtim, tc.ctim, now), i)
+			}
+		})
+	}
+}
+
+func TestMemFS_WithMemAtime(t *testing.T) {
+	// readAtime sets the access time after the modification time, reads the
+	// file and returns the new access time.
+	readAtime := func(t *testing.T, testFS FS, atim time.Time) int64 {
+		times := &[2]syscall.Timespec{
+			syscall.NsecToTimespec(atim.UnixNano()),
+			{Sec: 0, Nsec: platform.UTIME_OMIT},
+		}
+		require.EqualErrno(t, 0, testFS.Utimens("file", times, true))
+		f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		readAll(t, f)
+		require.EqualErrno(t, 0, f.Close())
+		st, errno := testFS.Stat("file")
+		require.EqualErrno(t, 0, errno)
+		return st.Atim
+	}
+
+	future := time.Now().Add(time.Hour)
+	for _, tc := range []struct {
+		policy                 AtimePolicy
+		updatesRecent, updates bool
+	}{
+		{policy: StrictAtime, updatesRecent: true, updates: true},
+		{policy: RelAtime, updatesRecent: false, updates: true},
+		{policy: NoAtime, updatesRecent: false, updates: false},
+	} {
+		testFS := NewMemFS(WithMemAtime(tc.policy))
+		require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", []byte("wazero"), 0o600))
+
+		// An access time after the last change is only updated by StrictAtime.
+		atim := readAtime(t, testFS, future)
+		require.Equal(t, tc.updatesRecent, atim != future.UnixNano(), tc.policy)
+
+		// An access time before the last change is updated unless NoAtime.
+		past := time.Unix(0, 0)
+		atim = readAtime(t, testFS, past)
+		require.Equal(t, tc.updates, atim != past.UnixNano(), tc.policy)
+	}
+}
+
+func TestDirFS_WithAtime(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("O_NOATIME is only supported on linux")
+	}
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+	testFS := NewDirFS(tmpDir, WithAtime(NoAtime))
+
+	// Set an access time before the modification time, which even a host
+	// mount with relatime would update on read.
+	past := time.Unix(0, 0)
+	times := &[2]syscall.Timespec{syscall.NsecToTimespec(past.UnixNano()), {Sec: 0, Nsec: platform.UTIME_OMIT}}
+	require.EqualErrno(t, 0, testFS.Utimens("file", times, true))
+
+	f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("wazero"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	st, errno := testFS.Stat("file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, past.UnixNano(), st.Atim)
+}
@@ -0,0 +1,344 @@
+package sysfs
+
+import (
+	"hash"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// Hash returns a new hash.Hash for NewChecksumFS, such as sha256.New.
+//
+// Checksums whose constructor returns a narrower type need a wrapper, e.g.
+// `func() hash.Hash { return crc32.NewIEEE() }`. BLAKE3 isn't in the standard
+// library, but any implementation of hash.Hash works.
+type Hash func() hash.Hash
+
+// NewChecksumFS returns a ChecksumFS which hashes files in `fs` with `algo`
+// as they are read. This allows the host to verify the integrity of assets
+// read by the guest, without reading them again.
+func NewChecksumFS(fs FS, algo Hash) *ChecksumFS {
+	return &ChecksumFS{fs: fs, algo: algo, sums: map[string][]byte{}}
+}
+
+// ChecksumFS is an FS which delegates to another, and records a checksum of
+// each file the guest reads completely. See Checksum for details.
+type ChecksumFS struct {
+	UnimplementedFS
+	fs   FS
+	algo Hash
+
+	// match is set by HashOnly.
+	match func(path string) bool
+
+	// mux guards sums.
+	mux sync.Mutex
+	// sums are checksums of files completely read, by cleaned path.
+	sums map[string][]byte
+}
+
+// HashOnly limits hashing on read to files whose path `match` returns true
+// for, to avoid overhead on files which are never checked. Checksum can
+// still compute the checksum of other files on demand.
+//
+// Note: This must be called before any file is opened.
+func (c *ChecksumFS) HashOnly(match func(path string) bool) *ChecksumFS {
+	c.match = match
+	return c
+}
+
+// Checksum returns the checksum of the file at `path`.
+//
+// When the guest read the file sequentially to its end, this returns the
+// checksum computed meanwhile. Otherwise, such as when the guest used Seek or
+// never opened the file, this reads the file to compute it.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. Otherwise, errors are the same as FS.OpenFile
+// and platform.File Read.
+func (c *ChecksumFS) Checksum(path string) ([]byte, syscall.Errno) {
+	path = cleanPath(path)
+	c.mux.Lock()
+	sum, ok := c.sums[path]
+	c.mux.Unlock()
+	if ok {
+		return sum, 0
+	}
+
+	f, errno := c.fs.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	defer f.Close()
+
+	h := c.algo()
+	buf := make([]byte, 32*1024)
+	for {
+		n, errno := f.Read(buf)
+		if errno != 0 {
+			return nil, errno
+		} else if n == 0 {
+			break
+		}
+		h.Write(buf[:n])
+	}
+	sum = h.Sum(nil)
+	c.setSum(path, sum)
+	return sum, 0
+}
+
+func (c *ChecksumFS) setSum(path string, sum []byte) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.sums[path] = sum
+}
+
+// invalidate discards any checksum of files at the given paths, as they may
+// have changed.
+func (c *ChecksumFS) invalidate(paths ...string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, p := range paths {
+		delete(c.sums, cleanPath(p))
+	}
+}
+
+// invalidateTree is like invalidate, except it also discards the checksum
+// of any file under the given paths, as a renamed directory moves them.
+func (c *ChecksumFS) invalidateTree(paths ...string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, p := range paths {
+		p = cleanPath(p)
+		if p == "" || p == "." {
+			c.sums = map[string][]byte{} // the root contains everything.
+			return
+		}
+		for key := range c.sums {
+			if key == p || strings.HasPrefix(key, p+"/") {
+				delete(c.sums, key)
+			}
+		}
+	}
+}
+
+// String implements fmt.Stringer
+func (c *ChecksumFS) String() string {
+	return c.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (c *ChecksumFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	readOnly := flag&(os.O_WRONLY|os.O_RDWR) == 0 && flag&os.O_TRUNC == 0
+	if !readOnly {
+		c.invalidate(path)
+	}
+	f, errno := c.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	} else if !readOnly || (c.match != nil && !c.match(path)) {
+		return f, 0
+	}
+	return &checksumFile{File: f, fs: c, path: cleanPath(path), h: c.algo()}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (c *ChecksumFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	f, errno := c.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	// The path is only known once open, so invalidate after.
+	path := cleanPath(f.Path())
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 || flag&os.O_TRUNC != 0 {
+		c.invalidate(path)
+		return f, 0
+	} else if c.match != nil && !c.match(path) {
+		return f, 0
+	}
+	return &checksumFile{File: f, fs: c, path: path, h: c.algo()}, 0
+}
+
+// Lstat implements FS.Lstat
+func (c *ChecksumFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return c.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (c *ChecksumFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return c.fs.Stat(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (c *ChecksumFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return c.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (c *ChecksumFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return c.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (c *ChecksumFS) Chown(path string, uid, gid int) syscall.Errno {
+	return c.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (c *ChecksumFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return c.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (c *ChecksumFS) Rename(from, to string) syscall.Errno {
+	c.invalidateTree(from, to)
+	return c.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (c *ChecksumFS) ExchangeDir(a, b string) syscall.Errno {
+	c.invalidateTree(a, b)
+	return c.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (c *ChecksumFS) Clone(src, dst string) syscall.Errno {
+	c.invalidate(dst)
+	return c.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (c *ChecksumFS) Rmdir(path string) syscall.Errno {
+	return c.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (c *ChecksumFS) Unlink(path string) syscall.Errno {
+	c.invalidate(path)
+	return c.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (c *ChecksumFS) Link(oldPath, newPath string) syscall.Errno {
+	c.invalidate(newPath)
+	return c.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (c *ChecksumFS) Symlink(oldPath, linkName string) syscall.Errno {
+	c.invalidate(linkName)
+	return c.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (c *ChecksumFS) Readlink(path string) (string, syscall.Errno) {
+	return c.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (c *ChecksumFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return c.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (c *ChecksumFS) Truncate(path string, size int64) syscall.Errno {
+	c.invalidate(path)
+	return c.fs.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (c *ChecksumFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return c.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (c *ChecksumFS) SyncDir(path string) syscall.Errno {
+	return c.fs.SyncDir(path)
+}
+
+// checksumFile hashes a file opened read-only by ChecksumFS, while it is read
+// sequentially from the beginning.
+type checksumFile struct {
+	platform.File
+	fs   *ChecksumFS
+	path string
+
+	// h is the running hash, or nil after a read out of sequence.
+	h hash.Hash
+	// pos is the count of bytes hashed.
+	pos int64
+	// offset is the file offset used by Read.
+	offset int64
+}
+
+// Read implements the same method as documented on platform.File
+func (f *checksumFile) Read(buf []byte) (n int, errno syscall.Errno) {
+	n, errno = f.File.Read(buf)
+	f.offset += int64(n)
+	f.update(buf[:n], f.offset-int64(n), len(buf) == 0, errno)
+	return
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *checksumFile) Pread(buf []byte, off int64) (n int, errno syscall.Errno) {
+	n, errno = f.File.Pread(buf, off)
+	f.update(buf[:n], off, len(buf) == 0, errno)
+	return
+}
+
+// update hashes `read` if it was read at the end of the bytes hashed so far.
+// Otherwise, hashing stops, and Checksum will read the file instead.
+func (f *checksumFile) update(read []byte, off int64, empty bool, errno syscall.Errno) {
+	if f.h == nil || errno != 0 || empty {
+		return
+	} else if off != f.pos {
+		f.h = nil
+	} else if len(read) > 0 {
+		f.h.Write(read)
+		f.pos += int64(len(read))
+	} else {
+		f.done() // zero bytes read is the end of the file.
+	}
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *checksumFile) Seek(offset int64, whence int) (newOffset int64, errno syscall.Errno) {
+	if newOffset, errno = f.File.Seek(offset, whence); errno == 0 {
+		f.offset = newOffset // Read checks this continues the sequence.
+	}
+	return
+}
+
+// Dup implements the same method as documented on platform.File
+//
+// Reads of the result move the shared offset, so hashing stops, and the
+// result isn't hashed either.
+func (f *checksumFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno == 0 {
+		f.h = nil
+	}
+	return dup, errno
+}
+
+// Close implements the same method as documented on platform.File
+func (f *checksumFile) Close() syscall.Errno {
+	// Guests often stop reading at the size, instead of reading zero bytes.
+	if f.h != nil {
+		if st, errno := f.File.Stat(); errno == 0 && st.Mode.IsRegular() && st.Size == f.pos {
+			f.done()
+		}
+	}
+	return f.File.Close()
+}
+
+// done records the checksum, as the file was read to its end.
+func (f *checksumFile) done() {
+	f.fs.setSum(f.path, f.h.Sum(nil))
+	f.h = nil
+}
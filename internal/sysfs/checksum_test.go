@@ -0,0 +1,133 @@
+package sysfs
+
+import (
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestChecksumFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+	expected := sha256.Sum256([]byte("wazero"))
+
+	t.Run("sequential read", func(t *testing.T) {
+		testFS := NewChecksumFS(NewDirFS(tmpDir), sha256.New)
+		f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		buf := make([]byte, 4)
+		for n := 1; n > 0; {
+			n, errno = f.Read(buf)
+			require.EqualErrno(t, 0, errno)
+		}
+		require.EqualErrno(t, 0, f.Close())
+
+		// Corrupt the file, to prove the checksum is from the read.
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("WAZERO"), 0o600))
+		defer os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600) //nolint
+
+		sum, errno := testFS.Checksum("/file")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, expected[:], sum)
+	})
+
+	t.Run("closed at size", func(t *testing.T) {
+		testFS := NewChecksumFS(NewDirFS(tmpDir), sha256.New)
+		f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Pread(make([]byte, 6), 0)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		testFS.mux.Lock()
+		require.Equal(t, expected[:], testFS.sums["file"])
+		testFS.mux.Unlock()
+	})
+
+	t.Run("seek computes on demand", func(t *testing.T) {
+		testFS := NewChecksumFS(NewDirFS(tmpDir), sha256.New)
+		f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Seek(2, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Read(make([]byte, 6))
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		testFS.mux.Lock()
+		require.Zero(t, len(testFS.sums))
+		testFS.mux.Unlock()
+
+		sum, errno := testFS.Checksum("file")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, expected[:], sum)
+	})
+
+	t.Run("HashOnly", func(t *testing.T) {
+		testFS := NewChecksumFS(NewDirFS(tmpDir), sha256.New).HashOnly(func(string) bool { return false })
+		f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		_, ok := f.(*checksumFile)
+		require.False(t, ok)
+		require.EqualErrno(t, 0, f.Close())
+	})
+
+	t.Run("write invalidates", func(t *testing.T) {
+		testFS := NewChecksumFS(NewDirFS(tmpDir), func() hash.Hash { return crc32.NewIEEE() })
+		sum, errno := testFS.Checksum("file")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 4, len(sum))
+
+		f, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("WA"))
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+		defer os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600) //nolint
+
+		updated, errno := testFS.Checksum("file")
+		require.EqualErrno(t, 0, errno)
+		require.NotEqual(t, sum, updated)
+	})
+
+	t.Run("rename invalidates files in directories", func(t *testing.T) {
+		testFS := NewChecksumFS(NewMemFS(), sha256.New)
+		for _, dir := range []string{"a", "b", "ab"} {
+			require.EqualErrno(t, 0, testFS.Mkdir(dir, 0o700))
+			f, errno := testFS.OpenFile(dir+"/file", os.O_WRONLY|os.O_CREATE, 0o600)
+			require.EqualErrno(t, 0, errno)
+			_, errno = f.Write([]byte(dir))
+			require.EqualErrno(t, 0, errno)
+			require.EqualErrno(t, 0, f.Close())
+			_, errno = testFS.Checksum(dir + "/file")
+			require.EqualErrno(t, 0, errno)
+		}
+
+		require.EqualErrno(t, 0, testFS.ExchangeDir("a", "b"))
+		testFS.mux.Lock()
+		require.Equal(t, 1, len(testFS.sums)) // only "ab/file" is unaffected.
+		testFS.mux.Unlock()
+		sum, errno := testFS.Checksum("a/file")
+		require.EqualErrno(t, 0, errno)
+		expected := sha256.Sum256([]byte("b"))
+		require.Equal(t, expected[:], sum)
+
+		require.EqualErrno(t, 0, testFS.Rename("a", "c"))
+		testFS.mux.Lock()
+		_, ok := testFS.sums["a/file"]
+		testFS.mux.Unlock()
+		require.False(t, ok)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, errno := NewChecksumFS(NewDirFS(tmpDir), sha256.New).Checksum("missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+}
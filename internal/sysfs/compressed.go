@@ -0,0 +1,781 @@
+package sysfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// Compression is an algorithm used by NewCompressedFS.
+type Compression uint8
+
+const (
+	// CompressionGzip compresses with gzip, using the default level.
+	CompressionGzip Compression = iota + 1
+)
+
+const (
+	// compressedMagic starts each regular file written by compressedFS, and
+	// is followed by the Compression.
+	compressedMagic = "wazeroZ"
+
+	// compressedHeaderSize is the size of compressedMagic, the Compression
+	// and the little-endian uint64 uncompressed size which follows them.
+	compressedHeaderSize = len(compressedMagic) + 1 + 8
+
+	// compressedBlockSize is the size of uncompressed data compressed
+	// together. Reads only decompress the blocks they overlap.
+	compressedBlockSize = 64 * 1024
+
+	// compressedLenSize is the size of the little-endian uint32 length which
+	// precedes each compressed block.
+	compressedLenSize = 4
+)
+
+// NewCompressedFS returns an FS which delegates to `fs`, except the contents
+// of regular files are compressed with `algo`, for example, to save space in
+// a scratch directory.
+//
+// Contents are compressed in blocks of 64KiB, and their offsets are indexed
+// when a file is opened, so that File.Pread and File.Seek at any offset only
+// decompress the block they read. A header records the uncompressed size,
+// which is the Stat_t.Size seen by the guest.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.EINVAL: `algo` isn't a known Compression.
+//
+// Once created, reading a regular file which wasn't written by an FS with
+// the same Compression, or was modified on the host, returns syscall.EIO.
+//
+// # Notes
+//
+//   - Only gzip is supported, as wazero has no dependencies. Others, such as
+//     zstd, can be added as a Compression.
+//   - Written blocks are kept in memory, until they are compressed when the
+//     file is synced or closed. Until then, FS.Stat reports the previous
+//     size, and other opens of the same file don't see them.
+//   - As compressed blocks vary in size, writing a block rewrites the blocks
+//     after it, so this is best for files written sequentially.
+//   - Rewritten blocks are compressed in memory before any are written, so
+//     growing a file past platform.MaxMemoryFileSize returns syscall.EFBIG.
+//   - An empty file has no header, so files created outside this FS can be
+//     written once empty.
+//   - File.PunchHole returns syscall.ENOSYS, as offsets in the host file
+//     differ from those of the guest.
+func NewCompressedFS(fs FS, algo Compression) (FS, syscall.Errno) {
+	if algo != CompressionGzip {
+		return nil, syscall.EINVAL
+	}
+	return &compressedFS{fs: fs, algo: algo}, 0
+}
+
+type compressedFS struct {
+	UnimplementedFS
+	fs   FS
+	algo Compression
+}
+
+// compress returns the compressed `block`.
+func (c *compressedFS) compress(block []byte) ([]byte, syscall.Errno) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(block); err != nil {
+		return nil, syscall.EIO
+	} else if err = w.Close(); err != nil {
+		return nil, syscall.EIO
+	}
+	return buf.Bytes(), 0
+}
+
+// decompress decompresses `compressed` into `block`, which is
+// compressedBlockSize.
+func (c *compressedFS) decompress(compressed, block []byte) syscall.Errno {
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return syscall.EIO
+	}
+	if _, err = io.ReadFull(r, block); err != nil {
+		return syscall.EIO
+	}
+	return 0
+}
+
+// blockCount returns the count of blocks of a file with the uncompressed size.
+func blockCount(size int64) int64 {
+	return (size + compressedBlockSize - 1) / compressedBlockSize
+}
+
+// readUncompressedSize returns the uncompressed size in the header of `f`.
+func (c *compressedFS) readUncompressedSize(f platform.File) (int64, syscall.Errno) {
+	var header [compressedHeaderSize]byte
+	n, errno := f.Pread(header[:], 0)
+	if errno != 0 {
+		return 0, errno
+	} else if n == 0 {
+		return 0, 0 // empty, so not yet written.
+	} else if n != len(header) || string(header[:len(compressedMagic)]) != compressedMagic ||
+		Compression(header[len(compressedMagic)]) != c.algo {
+		return 0, syscall.EIO
+	}
+	size := int64(binary.LittleEndian.Uint64(header[len(compressedMagic)+1:]))
+	if size < 0 || size > platform.MaxMemoryFileSize {
+		return 0, syscall.EIO // not written by compressedFS.
+	}
+	return size, 0
+}
+
+// String implements fmt.Stringer
+func (c *compressedFS) String() string {
+	return c.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (c *compressedFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	return c.open(flag, func(flag int) (platform.File, syscall.Errno) {
+		return c.fs.OpenFile(path, flag, perm)
+	})
+}
+
+// OpenByID implements FS.OpenByID
+func (c *compressedFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	return c.open(flag, func(flag int) (platform.File, syscall.Errno) {
+		return c.fs.OpenByID(dev, ino, flag)
+	})
+}
+
+// open opens a file with `open`, which is called again with `flag` if the
+// file isn't regular.
+func (c *compressedFS) open(flag int, open func(flag int) (platform.File, syscall.Errno)) (platform.File, syscall.Errno) {
+	// The file is read to rewrite blocks, even if the guest can't read it.
+	// Appending and truncation are handled by compressedFile, as they are
+	// relative to the uncompressed data.
+	accessMode := flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
+	hostFlag := flag &^ (os.O_APPEND | os.O_TRUNC)
+	if accessMode == os.O_WRONLY {
+		hostFlag = hostFlag&^os.O_WRONLY | os.O_RDWR
+	}
+	f, errno := open(hostFlag)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	// Only regular files are compressed.
+	st, errno := f.Stat()
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	} else if !st.Mode.IsRegular() {
+		_ = f.Close()
+		return open(flag)
+	}
+
+	state, errno := c.index(f)
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	cf := &compressedFile{
+		File:       f,
+		fs:         c,
+		accessMode: accessMode,
+		append:     flag&os.O_APPEND != 0,
+		state:      state,
+	}
+	if flag&os.O_TRUNC != 0 && accessMode != os.O_RDONLY {
+		if errno = cf.Truncate(0); errno != 0 {
+			_ = f.Close()
+			return nil, errno
+		}
+	}
+	return cf, 0
+}
+
+// index reads the header of `f` and the offset of each compressed block.
+func (c *compressedFS) index(f platform.File) (*compressedState, syscall.Errno) {
+	size, errno := c.readUncompressedSize(f)
+	if errno != 0 {
+		return nil, errno
+	}
+	state := &compressedState{
+		size:        size,
+		flushedSize: size,
+		blocks:      make([]compressedBlock, blockCount(size)),
+		changed:     map[int64][]byte{},
+		cachedIndex: -1,
+	}
+	off := int64(compressedHeaderSize)
+	var length [compressedLenSize]byte
+	for i := range state.blocks {
+		if n, errno := f.Pread(length[:], off); errno != 0 {
+			return nil, errno
+		} else if n != len(length) {
+			return nil, syscall.EIO
+		}
+		state.blocks[i] = compressedBlock{off: off, len: int64(binary.LittleEndian.Uint32(length[:]))}
+		off = state.blocks[i].end()
+	}
+	return state, 0
+}
+
+// Lstat implements FS.Lstat
+func (c *compressedFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := c.fs.Lstat(path)
+	if errno != 0 {
+		return st, errno
+	}
+	return c.uncompressedStat(path, st)
+}
+
+// Stat implements FS.Stat
+func (c *compressedFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := c.fs.Stat(path)
+	if errno != 0 {
+		return st, errno
+	}
+	return c.uncompressedStat(path, st)
+}
+
+// uncompressedStat sets the size of a regular file to its uncompressed size.
+func (c *compressedFS) uncompressedStat(path string, st platform.Stat_t) (platform.Stat_t, syscall.Errno) {
+	if !st.Mode.IsRegular() || st.Size == 0 {
+		return st, 0
+	}
+	f, errno := c.fs.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return st, errno
+	}
+	defer f.Close()
+	st.Size, errno = c.readUncompressedSize(f)
+	return st, errno
+}
+
+// Mkdir implements FS.Mkdir
+func (c *compressedFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return c.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (c *compressedFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return c.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (c *compressedFS) Chown(path string, uid, gid int) syscall.Errno {
+	return c.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (c *compressedFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return c.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (c *compressedFS) Rename(from, to string) syscall.Errno {
+	return c.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (c *compressedFS) ExchangeDir(a, b string) syscall.Errno {
+	return c.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (c *compressedFS) Clone(src, dst string) syscall.Errno {
+	return c.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (c *compressedFS) Rmdir(path string) syscall.Errno {
+	return c.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (c *compressedFS) Unlink(path string) syscall.Errno {
+	return c.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (c *compressedFS) Link(oldPath, newPath string) syscall.Errno {
+	return c.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (c *compressedFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return c.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (c *compressedFS) Readlink(path string) (string, syscall.Errno) {
+	return c.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (c *compressedFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return c.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (c *compressedFS) Truncate(path string, size int64) syscall.Errno {
+	// The size is uncompressed, so truncate via the file.
+	f, errno := c.OpenFile(path, os.O_RDWR, 0)
+	if errno != 0 {
+		return errno
+	}
+	if errno = f.Truncate(size); errno != 0 {
+		_ = f.Close()
+		return errno
+	}
+	return f.Close()
+}
+
+// Utimens implements FS.Utimens
+func (c *compressedFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return c.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (c *compressedFS) SyncDir(path string) syscall.Errno {
+	return c.fs.SyncDir(path)
+}
+
+// compressedBlock is the location of a compressed block in the host file.
+type compressedBlock struct {
+	// off is the offset of the length which precedes the compressed block.
+	off int64
+	// len is the length of the compressed block.
+	len int64
+}
+
+// end returns the offset after the compressed block.
+func (b compressedBlock) end() int64 {
+	return b.off + compressedLenSize + b.len
+}
+
+// compressedState is the state of an open file, shared with duplicates.
+type compressedState struct {
+	mux sync.Mutex
+
+	// size is the uncompressed size, including changed blocks.
+	size int64
+	// flushedSize is the uncompressed size in the header of the host file.
+	flushedSize int64
+	// blocks are the compressed blocks in the host file, by index.
+	blocks []compressedBlock
+	// changed are the uncompressed blocks written since the last flush, by
+	// index.
+	changed map[int64][]byte
+
+	// cachedIndex is the index of cachedBlock, the last block decompressed,
+	// or -1 if none. This avoids decompressing a block again on small
+	// sequential reads.
+	cachedIndex int64
+	cachedBlock []byte
+
+	// offset is the position of Read, Write and Seek in the uncompressed
+	// data.
+	offset int64
+}
+
+// compressedFile compresses and decompresses the blocks of a regular file
+// opened by compressedFS.
+type compressedFile struct {
+	platform.File
+	fs         *compressedFS
+	accessMode int
+	append     bool
+	state      *compressedState
+}
+
+// block returns the uncompressed block `i`. The caller must hold the lock.
+func (f *compressedFile) block(i int64) ([]byte, syscall.Errno) {
+	s := f.state
+	if b, ok := s.changed[i]; ok {
+		return b, 0
+	} else if i == s.cachedIndex {
+		return s.cachedBlock, 0
+	}
+	block := make([]byte, compressedBlockSize)
+	if i >= int64(len(s.blocks)) {
+		return block, 0 // past the end of the host file, so zeros.
+	}
+	compressed, errno := f.readCompressed(s.blocks[i])
+	if errno != 0 {
+		return nil, errno
+	} else if errno = f.fs.decompress(compressed, block); errno != 0 {
+		return nil, errno
+	}
+	s.cachedIndex, s.cachedBlock = i, block
+	return block, 0
+}
+
+// setBlock records that the uncompressed block `i` changed. The caller must
+// hold the lock.
+func (f *compressedFile) setBlock(i int64, block []byte) {
+	s := f.state
+	s.changed[i] = block
+	if i == s.cachedIndex {
+		s.cachedIndex, s.cachedBlock = -1, nil
+	}
+}
+
+// readCompressed reads the compressed block `b` from the host file.
+func (f *compressedFile) readCompressed(b compressedBlock) ([]byte, syscall.Errno) {
+	compressed := make([]byte, b.len)
+	if n, errno := f.File.Pread(compressed, b.off+compressedLenSize); errno != 0 {
+		return nil, errno
+	} else if int64(n) != b.len {
+		return nil, syscall.EIO
+	}
+	return compressed, 0
+}
+
+// flush compresses the changed blocks, rewriting the host file from the first
+// block which changed. The caller must hold the lock.
+func (f *compressedFile) flush() syscall.Errno {
+	s := f.state
+	count := blockCount(s.size)
+	if len(s.changed) == 0 && s.size == s.flushedSize && int64(len(s.blocks)) == count {
+		return 0
+	}
+
+	// Find the first block which changed, including those added or removed.
+	first := int64(len(s.blocks))
+	if count < first {
+		first = count
+	}
+	for i := range s.changed {
+		if i < first {
+			first = i
+		}
+	}
+
+	// Compress or read all blocks from the first, before any are written, as
+	// writing shifts the blocks after it.
+	compressed := make([][]byte, count-first)
+	for i := first; i < count; i++ {
+		var errno syscall.Errno
+		if b, ok := s.changed[i]; ok {
+			compressed[i-first], errno = f.fs.compress(b)
+		} else if i < int64(len(s.blocks)) {
+			compressed[i-first], errno = f.readCompressed(s.blocks[i])
+		} else {
+			compressed[i-first], errno = f.fs.compress(make([]byte, compressedBlockSize))
+		}
+		if errno != 0 {
+			return errno
+		}
+	}
+
+	off := int64(compressedHeaderSize)
+	if first > 0 {
+		off = s.blocks[first-1].end()
+	}
+	blocks := s.blocks[:first]
+	var length [compressedLenSize]byte
+	for _, c := range compressed {
+		binary.LittleEndian.PutUint32(length[:], uint32(len(c)))
+		if errno := f.pwriteAll(length[:], off); errno != 0 {
+			return errno
+		} else if errno = f.pwriteAll(c, off+compressedLenSize); errno != 0 {
+			return errno
+		}
+		b := compressedBlock{off: off, len: int64(len(c))}
+		blocks = append(blocks, b)
+		off = b.end()
+	}
+	if errno := f.File.Truncate(off); errno != 0 {
+		return errno
+	}
+
+	// Update the size last, so that it never includes unwritten blocks.
+	var header [compressedHeaderSize]byte
+	copy(header[:], compressedMagic)
+	header[len(compressedMagic)] = byte(f.fs.algo)
+	binary.LittleEndian.PutUint64(header[len(compressedMagic)+1:], uint64(s.size))
+	if errno := f.pwriteAll(header[:], 0); errno != 0 {
+		return errno
+	}
+	s.blocks, s.flushedSize, s.changed = blocks, s.size, map[int64][]byte{}
+	return 0
+}
+
+// pwriteAll writes all of `data` to the host file at `off`, retrying on
+// short writes.
+func (f *compressedFile) pwriteAll(data []byte, off int64) syscall.Errno {
+	for len(data) > 0 {
+		n, errno := f.File.Pwrite(data, off)
+		if errno != 0 {
+			return errno
+		} else if n == 0 {
+			return syscall.EIO
+		}
+		data, off = data[n:], off+int64(n)
+	}
+	return 0
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *compressedFile) AccessMode() int {
+	return f.accessMode
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *compressedFile) Stat() (platform.Stat_t, syscall.Errno) {
+	st, errno := f.File.Stat()
+	if errno != 0 {
+		return st, errno
+	}
+	f.state.mux.Lock()
+	st.Size = f.state.size
+	f.state.mux.Unlock()
+	return st, 0
+}
+
+// Read implements the same method as documented on platform.File
+func (f *compressedFile) Read(buf []byte) (int, syscall.Errno) {
+	f.state.mux.Lock()
+	defer f.state.mux.Unlock()
+	n, errno := f.pread(buf, f.state.offset)
+	f.state.offset += int64(n)
+	return n, errno
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *compressedFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	f.state.mux.Lock()
+	defer f.state.mux.Unlock()
+	return f.pread(buf, off)
+}
+
+func (f *compressedFile) pread(buf []byte, off int64) (n int, errno syscall.Errno) {
+	if f.accessMode == os.O_WRONLY {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	}
+	size := f.state.size
+	if off >= size {
+		return 0, 0
+	} else if remaining := size - off; int64(len(buf)) > remaining {
+		buf = buf[:remaining]
+	}
+
+	for n < len(buf) {
+		pos := off + int64(n)
+		var block []byte
+		if block, errno = f.block(pos / compressedBlockSize); errno != 0 {
+			return
+		}
+		n += copy(buf[n:], block[pos%compressedBlockSize:])
+	}
+	return
+}
+
+// BytesAvailable implements the same method as documented on platform.File
+func (f *compressedFile) BytesAvailable() (int64, syscall.Errno) {
+	f.state.mux.Lock()
+	defer f.state.mux.Unlock()
+
+	if f.accessMode == os.O_WRONLY {
+		return 0, syscall.EBADF
+	} else if f.state.size > f.state.offset {
+		return f.state.size - f.state.offset, 0
+	}
+	return 0, 0
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *compressedFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	f.state.mux.Lock()
+	defer f.state.mux.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.state.offset
+	case io.SeekEnd:
+		offset += f.state.size
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.state.offset = offset
+	return offset, 0
+}
+
+// Write implements the same method as documented on platform.File
+func (f *compressedFile) Write(buf []byte) (int, syscall.Errno) {
+	f.state.mux.Lock()
+	defer f.state.mux.Unlock()
+	return f.write(buf)
+}
+
+func (f *compressedFile) write(buf []byte) (int, syscall.Errno) {
+	if f.append {
+		f.state.offset = f.state.size
+	}
+	n, errno := f.pwrite(buf, f.state.offset)
+	f.state.offset += int64(n)
+	return n, errno
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *compressedFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	f.state.mux.Lock()
+	defer f.state.mux.Unlock()
+
+	for _, buf := range bufs {
+		var written int
+		written, errno = f.write(buf)
+		n += written
+		if errno != 0 {
+			return
+		}
+	}
+	return
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *compressedFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	f.state.mux.Lock()
+	defer f.state.mux.Unlock()
+	return f.pwrite(buf, off)
+}
+
+func (f *compressedFile) pwrite(buf []byte, off int64) (n int, errno syscall.Errno) {
+	if f.accessMode == os.O_RDONLY {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(buf) == 0 {
+		return 0, 0
+	} else if off > platform.MaxMemoryFileSize-int64(len(buf)) {
+		return 0, syscall.EFBIG
+	}
+	for n < len(buf) {
+		pos := off + int64(n)
+		i := pos / compressedBlockSize
+		var block []byte
+		if block, errno = f.block(i); errno != 0 {
+			break
+		}
+		written := copy(block[pos%compressedBlockSize:], buf[n:])
+		f.setBlock(i, block)
+		n += written
+		if end := pos + int64(written); end > f.state.size {
+			f.state.size = end
+		}
+	}
+	return
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *compressedFile) Truncate(size int64) syscall.Errno {
+	if f.accessMode == os.O_RDONLY {
+		return syscall.EBADF
+	} else if size < 0 {
+		return syscall.EINVAL
+	} else if size > platform.MaxMemoryFileSize {
+		return syscall.EFBIG
+	}
+
+	f.state.mux.Lock()
+	defer f.state.mux.Unlock()
+
+	s := f.state
+	if size < s.size {
+		// Data after the size in the last block is always zero, so zero the
+		// end of the new last block, and forget the blocks after it.
+		count := blockCount(size)
+		if rem := size % compressedBlockSize; rem != 0 {
+			block, errno := f.block(count - 1)
+			if errno != 0 {
+				return errno
+			}
+			for j := rem; j < compressedBlockSize; j++ {
+				block[j] = 0
+			}
+			f.setBlock(count-1, block)
+		}
+		for i := range s.changed {
+			if i >= count {
+				delete(s.changed, i)
+			}
+		}
+		if int64(len(s.blocks)) > count {
+			s.blocks = s.blocks[:count] // so that growing again reads zeros.
+		}
+		if s.cachedIndex >= count {
+			s.cachedIndex, s.cachedBlock = -1, nil
+		}
+	}
+	s.size = size
+	return 0
+}
+
+// PunchHole implements the same method as documented on platform.File
+//
+// This returns syscall.ENOSYS, as offsets in the host file differ from those
+// of the guest.
+func (f *compressedFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *compressedFile) Sync() syscall.Errno {
+	f.state.mux.Lock()
+	errno := f.flush()
+	f.state.mux.Unlock()
+	if errno != 0 {
+		return errno
+	}
+	return f.File.Sync()
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *compressedFile) Datasync() syscall.Errno {
+	f.state.mux.Lock()
+	errno := f.flush()
+	f.state.mux.Unlock()
+	if errno != 0 {
+		return errno
+	}
+	return f.File.Datasync()
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *compressedFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &compressedFile{
+		File:       dup,
+		fs:         f.fs,
+		accessMode: f.accessMode,
+		append:     f.append,
+		state:      f.state,
+	}, 0
+}
+
+// Close implements the same method as documented on platform.File
+func (f *compressedFile) Close() syscall.Errno {
+	f.state.mux.Lock()
+	errno := f.flush()
+	f.state.mux.Unlock()
+	if closeErrno := f.File.Close(); errno == 0 {
+		errno = closeErrno
+	}
+	return errno
+}
@@ -0,0 +1,230 @@
+package sysfs
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewCompressedFS(t *testing.T) {
+	_, errno := NewCompressedFS(NewMemFS(), 0)
+	require.EqualErrno(t, syscall.EINVAL, errno)
+
+	hostFS := NewMemFS()
+	testFS, errno := NewCompressedFS(hostFS, CompressionGzip)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "mem:/", testFS.String())
+
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	data := bytes.Repeat([]byte("wazero"), 3*compressedBlockSize)
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "dir/file", data, 0o600))
+	require.Equal(t, []string{"file"}, readdirNames(t, hostFS, "dir"))
+
+	// The size is uncompressed, but the host file is compressed.
+	st, errno := testFS.Stat("dir/file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(len(data)), st.Size)
+	hostSt, errno := hostFS.Stat("dir/file")
+	require.EqualErrno(t, 0, errno)
+	require.True(t, hostSt.Size < st.Size/10)
+
+	f, errno := testFS.OpenFile("dir/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, data, readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	// A file written on the host can't be read.
+	require.EqualErrno(t, 0, WriteFileAtomic(hostFS, "plain", []byte("plaintext"), 0o600))
+	_, errno = testFS.OpenFile("plain", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EIO, errno)
+}
+
+func TestCompressedFile_seek(t *testing.T) {
+	testFS, errno := NewCompressedFS(NewMemFS(), CompressionGzip)
+	require.EqualErrno(t, 0, errno)
+
+	data := make([]byte, 5*compressedBlockSize+100)
+	rand.New(rand.NewSource(0)).Read(data)
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", data, 0o600))
+
+	f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	// Seeking reads from any block, including across blocks.
+	buf := make([]byte, 200)
+	for _, off := range []int64{3*compressedBlockSize - 50, 0, 5 * compressedBlockSize, compressedBlockSize} {
+		pos, errno := f.Seek(off, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, off, pos)
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, data[off:off+int64(n)], buf[:n])
+	}
+
+	pos, errno := f.Seek(-10, io.SeekEnd)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(len(data)-10), pos)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, data[len(data)-10:], buf[:n])
+
+	n, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+}
+
+func TestCompressedFile_randomAccess(t *testing.T) {
+	testFS, errno := NewCompressedFS(NewMemFS(), CompressionGzip)
+	require.EqualErrno(t, 0, errno)
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+
+	// expected is the uncompressed data, to compare against.
+	var expected []byte
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 50; i++ {
+		off := r.Int63n(4 * compressedBlockSize)
+		buf := make([]byte, r.Intn(compressedBlockSize)+1)
+		r.Read(buf)
+
+		n, errno := f.Pwrite(buf, off)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, len(buf), n)
+		if end := off + int64(len(buf)); end > int64(len(expected)) {
+			expected = append(expected, make([]byte, end-int64(len(expected)))...)
+		}
+		copy(expected[off:], buf)
+
+		// Compress some of the time, to rewrite blocks after the first.
+		if i%5 == 0 {
+			require.EqualErrno(t, 0, f.Sync())
+		}
+
+		off = r.Int63n(int64(len(expected)))
+		buf = make([]byte, r.Intn(compressedBlockSize)+1)
+		n, errno = f.Pread(buf, off)
+		require.EqualErrno(t, 0, errno)
+		end := off + int64(len(buf))
+		if end > int64(len(expected)) {
+			end = int64(len(expected))
+		}
+		require.Equal(t, expected[off:end], buf[:n])
+	}
+	require.EqualErrno(t, 0, f.Close())
+
+	// Reopening reads what was compressed on close.
+	f, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, expected, readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+}
+
+func TestCompressedFile_Truncate(t *testing.T) {
+	testFS, errno := NewCompressedFS(NewMemFS(), CompressionGzip)
+	require.EqualErrno(t, 0, errno)
+	data := bytes.Repeat([]byte{'a'}, 2*compressedBlockSize+10)
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", data, 0o600))
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+
+	// Shrinking then growing reads zeros after the truncated size, including
+	// blocks which were in the host file.
+	require.EqualErrno(t, 0, f.Truncate(5))
+	require.EqualErrno(t, 0, f.Truncate(int64(len(data))))
+	buf := make([]byte, len(data))
+	n, errno := f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, len(buf), n)
+	require.Equal(t, append(data[:5:5], make([]byte, len(buf)-5)...), buf)
+	require.EqualErrno(t, 0, f.Close())
+
+	f, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, buf, readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	require.EqualErrno(t, 0, testFS.Truncate("file", 1))
+	st, errno := testFS.Stat("file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(1), st.Size)
+
+	f, errno = testFS.OpenFile("file", os.O_RDWR|os.O_TRUNC, 0)
+	require.EqualErrno(t, 0, errno)
+	st, errno = f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, st.Size)
+	require.EqualErrno(t, syscall.ENOSYS, f.PunchHole(0, 1))
+	require.EqualErrno(t, 0, f.Close())
+}
+
+func TestCompressedFile_flags(t *testing.T) {
+	testFS, errno := NewCompressedFS(NewMemFS(), CompressionGzip)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", []byte("wa"), 0o600))
+
+	// Appending is relative to the uncompressed data.
+	f, errno := testFS.OpenFile("file", os.O_WRONLY|os.O_APPEND, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, os.O_WRONLY, f.AccessMode())
+	_, errno = f.Seek(0, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("zero"))
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Read(make([]byte, 1))
+	require.EqualErrno(t, syscall.EBADF, errno)
+
+	// A duplicate shares the offset.
+	dup, errno := f.Dup()
+	require.EqualErrno(t, 0, errno)
+	off, errno := dup.Seek(0, io.SeekCurrent)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), off)
+	require.EqualErrno(t, 0, dup.Close())
+	require.EqualErrno(t, 0, f.Close())
+
+	f, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("wazero"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	// Directories pass through.
+	f, errno = testFS.OpenFile(".", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	_, ok := f.(*compressedFile)
+	require.False(t, ok)
+	require.EqualErrno(t, 0, f.Close())
+}
+
+func TestCompressedFile_hugeFile(t *testing.T) {
+	hostFS := NewMemFS()
+	testFS, errno := NewCompressedFS(hostFS, CompressionGzip)
+	require.EqualErrno(t, 0, errno)
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+
+	require.EqualErrno(t, syscall.EFBIG, f.Truncate(1<<62))
+	_, errno = f.Pwrite([]byte{1}, 1<<62)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+	_, errno = f.Pwrite([]byte{1}, platform.MaxMemoryFileSize)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+
+	// Nothing grew, so closing doesn't need to compress huge blocks.
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, st.Size)
+	require.EqualErrno(t, 0, f.Close())
+
+	// A huge size in the header isn't indexed.
+	header := []byte(compressedMagic + string(rune(CompressionGzip)) + "\xff\xff\xff\xff\xff\xff\xff\x3f")
+	require.EqualErrno(t, 0, WriteFileAtomic(hostFS, "huge", header, 0o600))
+	_, errno = testFS.OpenFile("huge", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EIO, errno)
+}
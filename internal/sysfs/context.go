@@ -0,0 +1,25 @@
+package sysfs
+
+import (
+	"context"
+	"io/fs"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// OpenFileContext is like FS.OpenFile, except blocking reads, writes and
+// PollRead on the result return syscall.ECANCELED once the context is done.
+//
+// This allows the runtime to enforce module deadlines on I/O, not just CPU.
+// See platform.NewContextFile for details.
+func OpenFileContext(ctx context.Context, fs FS, path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if ctx.Err() != nil {
+		return nil, syscall.ECANCELED
+	}
+	f, errno := fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return platform.NewContextFile(ctx, f), 0
+}
@@ -0,0 +1,39 @@
+package sysfs
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenFileContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	testFS := NewDirFS(tmpDir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f, errno := OpenFileContext(ctx, testFS, "animals.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	buf := make([]byte, 4)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "bear", string(buf[:n]))
+
+	cancel()
+	_, errno = f.Read(buf)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+
+	_, errno = OpenFileContext(ctx, testFS, "animals.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+
+	_, errno = OpenFileContext(context.Background(), testFS, "nope", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+}
@@ -0,0 +1,252 @@
+package sysfs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// copyTreeBatch is the count of directory entries read at a time by
+// CopyTree, which bounds memory for large directories.
+const copyTreeBatch = 128
+
+// copyTreeBufSize is the size of the buffer CopyTree copies files with.
+const copyTreeBufSize = 32 * 1024
+
+// CopyTree copies `srcPath` in `src` to `dstPath` in `dst`, including the
+// contents of directories, for example, to bundle or extract a tree of files.
+//
+// Directories, regular files and symbolic links are recreated with the
+// permissions and times of the source. Other files, such as named pipes or
+// devices, can't be created through FS, so are skipped. Symbolic links are
+// also skipped when `dst` can't create them. Each skipped path in `src` is
+// returned with the reason, such as syscall.ENOTSUP or syscall.ENOSYS.
+//
+// # Errors
+//
+// A zero syscall.Errno is success, even if some files were skipped.
+// Otherwise, this returns the first error of `src` or `dst`, except:
+//   - syscall.EINVAL: `dstPath` is inside the directory `srcPath`, which
+//     would copy it into itself forever.
+//   - syscall.EEXIST: a symbolic link is at a path to copy to.
+//   - syscall.ECANCELED: the context was done before the copy completed.
+//   - syscall.EROFS: `dst` is read-only, returned by the first operation
+//     which writes it, rather than after walking `src`.
+//
+// # Notes
+//
+//   - Directories are read with File.Readdir in batches, so that large
+//     directories don't need to fit in memory.
+//   - A directory already at `dstPath` is merged into, and regular files in
+//     it are replaced. An existing symbolic link is an error, rather than
+//     followed, so that copying can't write outside `dstPath`.
+//   - This is not atomic: on failure, anything already copied remains.
+//   - Changing permissions or times isn't an error when `dst` returns
+//     syscall.ENOSYS for them.
+//   - A file with multiple hard links is copied once per link.
+func CopyTree(ctx context.Context, dst FS, dstPath string, src FS, srcPath string) (skipped map[string]syscall.Errno, errno syscall.Errno) {
+	st, errno := src.Lstat(srcPath)
+	if errno != 0 {
+		return nil, errno
+	}
+	if st.Mode.IsDir() && isInTree(dst, dstPath, src, srcPath, st) {
+		return nil, syscall.EINVAL
+	}
+	c := &treeCopier{ctx: ctx, dst: dst, src: src, skipped: map[string]syscall.Errno{}}
+	if errno = c.copy(srcPath, dstPath, st); errno != 0 {
+		return c.skipped, errno
+	}
+	return c.skipped, 0
+}
+
+// isInTree returns true if `dstPath` in `dst` is the directory `srcPath` in
+// `src`, whose status is `st`, or inside it.
+func isInTree(dst FS, dstPath string, src FS, srcPath string, st platform.Stat_t) bool {
+	dstPath, srcPath = cleanPath(dstPath), cleanPath(srcPath)
+	if sameFS(dst, src) && (srcPath == "." || srcPath == "" || dstPath == srcPath ||
+		strings.HasPrefix(dstPath, srcPath+"/")) {
+		return true
+	}
+	// Different FS can share directories, so compare the inode of each
+	// existing parent, when the device and inode are known.
+	if st.Dev == 0 || st.Ino == 0 {
+		return false
+	}
+	for p := dstPath; ; p = path.Dir(p) {
+		if dstSt, errno := dst.Stat(p); errno == 0 && dstSt.Dev == st.Dev && dstSt.Ino == st.Ino {
+			return true
+		}
+		if p == "." || p == "" {
+			return false
+		}
+	}
+}
+
+// sameFS returns true if `a` and `b` are the same FS, without panicking if
+// their type isn't comparable.
+func sameFS(a, b FS) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+type treeCopier struct {
+	ctx      context.Context
+	dst, src FS
+	skipped  map[string]syscall.Errno
+
+	// buf is reused to copy each file.
+	buf []byte
+}
+
+// copy copies the file at `srcPath`, recursing if it is a directory.
+func (c *treeCopier) copy(srcPath, dstPath string, st platform.Stat_t) syscall.Errno {
+	if c.ctx.Err() != nil {
+		return syscall.ECANCELED
+	}
+	switch st.Mode.Type() {
+	case fs.ModeDir:
+		return c.copyDir(srcPath, dstPath, st)
+	case 0:
+		return c.copyFile(srcPath, dstPath, st)
+	case fs.ModeSymlink:
+		return c.copySymlink(srcPath, dstPath)
+	default:
+		c.skipped[srcPath] = syscall.ENOTSUP
+		return 0
+	}
+}
+
+func (c *treeCopier) copyDir(srcPath, dstPath string, st platform.Stat_t) syscall.Errno {
+	// Create the directory writable, so that its entries can be copied even
+	// if the source is read-only. setMeta restores the permissions after.
+	if errno := c.dst.Mkdir(dstPath, st.Mode.Perm()|0o700); errno == syscall.EEXIST {
+		if dstSt, statErrno := c.dst.Lstat(dstPath); statErrno != 0 {
+			return statErrno
+		} else if dstSt.Mode.Type() == fs.ModeSymlink {
+			return syscall.EEXIST
+		} else if !dstSt.Mode.IsDir() {
+			return syscall.ENOTDIR
+		}
+	} else if errno != 0 {
+		return errno
+	}
+
+	dir, errno := c.src.OpenFile(srcPath, os.O_RDONLY|platform.O_DIRECTORY|platform.O_NOFOLLOW, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer dir.Close()
+
+	for {
+		if c.ctx.Err() != nil {
+			return syscall.ECANCELED
+		}
+		dirents, errno := dir.Readdir(copyTreeBatch)
+		if errno != 0 {
+			return errno
+		} else if len(dirents) == 0 {
+			break
+		}
+		for i := range dirents {
+			name := dirents[i].Name
+			child := path.Join(srcPath, name)
+			st, errno := c.src.Lstat(child)
+			if errno == syscall.ENOENT {
+				continue // removed since Readdir
+			} else if errno != 0 {
+				return errno
+			}
+			if errno = c.copy(child, path.Join(dstPath, name), st); errno != 0 {
+				return errno
+			}
+		}
+	}
+
+	// Copying entries changed the times, so set them last.
+	return c.setMeta(dstPath, st)
+}
+
+func (c *treeCopier) copyFile(srcPath, dstPath string, st platform.Stat_t) syscall.Errno {
+	in, errno := c.src.OpenFile(srcPath, os.O_RDONLY, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer in.Close()
+
+	// Don't follow a symbolic link at dstPath. Not all FS support
+	// O_NOFOLLOW, so check with Lstat first.
+	if dstSt, errno := c.dst.Lstat(dstPath); errno == 0 && dstSt.Mode.Type() == fs.ModeSymlink {
+		return syscall.EEXIST
+	}
+	out, errno := c.dst.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|platform.O_NOFOLLOW, st.Mode.Perm()|0o200)
+	if errno == syscall.ELOOP {
+		return syscall.EEXIST // replaced with a symbolic link since Lstat.
+	} else if errno != 0 {
+		return errno
+	}
+	if errno = c.copyData(out, in); errno != 0 {
+		_ = out.Close()
+		return errno
+	} else if errno = out.Close(); errno != 0 {
+		return errno
+	}
+	return c.setMeta(dstPath, st)
+}
+
+// copyData copies the contents of `in` to `out`, checking the context
+// between each buffer.
+func (c *treeCopier) copyData(out, in platform.File) syscall.Errno {
+	if c.buf == nil {
+		c.buf = make([]byte, copyTreeBufSize)
+	}
+	for {
+		if c.ctx.Err() != nil {
+			return syscall.ECANCELED
+		}
+		n, errno := in.Read(c.buf)
+		if errno != 0 {
+			return errno
+		} else if n == 0 {
+			return 0 // EOF
+		} else if errno = writeAll(out, c.buf[:n]); errno != 0 {
+			return errno
+		}
+	}
+}
+
+func (c *treeCopier) copySymlink(srcPath, dstPath string) syscall.Errno {
+	target, errno := c.src.Readlink(srcPath)
+	if errno != 0 {
+		return errno
+	}
+	switch errno = c.dst.Symlink(target, dstPath); errno {
+	case 0:
+		return 0
+	case syscall.EROFS, syscall.EEXIST:
+		return errno
+	default: // e.g. syscall.ENOSYS or syscall.EPERM
+		c.skipped[srcPath] = errno
+		return 0
+	}
+}
+
+// setMeta sets the permissions and times of `dstPath` to those of `st`.
+func (c *treeCopier) setMeta(dstPath string, st platform.Stat_t) syscall.Errno {
+	if errno := c.dst.Chmod(dstPath, st.Mode.Perm()); errno != 0 && errno != syscall.ENOSYS {
+		return errno
+	}
+	times := &[2]syscall.Timespec{
+		syscall.NsecToTimespec(st.Atim),
+		syscall.NsecToTimespec(st.Mtim),
+	}
+	if errno := c.dst.Utimens(dstPath, times, true); errno != 0 && errno != syscall.ENOSYS {
+		return errno
+	}
+	return 0
+}
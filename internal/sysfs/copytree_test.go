@@ -0,0 +1,183 @@
+package sysfs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// noSymlinkFS is an FS which can't create symbolic links.
+type noSymlinkFS struct{ FS }
+
+// Symlink implements FS.Symlink
+func (noSymlinkFS) Symlink(string, string) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+func TestCopyTree(t *testing.T) {
+	src := NewMemFS()
+	require.EqualErrno(t, 0, MkdirAll(src, "app/conf", 0o755))
+	require.EqualErrno(t, 0, writeFile(src, "app/conf/app.ini", []byte("a=1"), 0o640))
+	require.EqualErrno(t, 0, writeFile(src, "app/README", make([]byte, copyTreeBufSize+1), 0o644))
+	require.EqualErrno(t, 0, src.Symlink("conf/app.ini", "app/current"))
+	require.EqualErrno(t, 0, src.Chmod("app/conf", 0o555))
+	times := &[2]syscall.Timespec{{Sec: 1}, {Sec: 2}}
+	require.EqualErrno(t, 0, src.Utimens("app/conf/app.ini", times, true))
+	require.EqualErrno(t, 0, src.Utimens("app/conf", times, true))
+
+	t.Run("copies files, directories and symlinks", func(t *testing.T) {
+		dst := NewMemFS()
+		skipped, errno := CopyTree(context.Background(), dst, "copy", src, "app")
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, len(skipped))
+
+		b, errno := readFSFile(dst, "copy/conf/app.ini")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "a=1", string(b))
+		b, errno = readFSFile(dst, "copy/README")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, copyTreeBufSize+1, len(b))
+
+		st, errno := dst.Stat("copy/conf/app.ini")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.FileMode(0o640), st.Mode.Perm())
+		require.Equal(t, int64(2e9), st.Mtim)
+
+		st, errno = dst.Stat("copy/conf")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.ModeDir|0o555, st.Mode)
+		require.Equal(t, int64(2e9), st.Mtim)
+
+		target, errno := dst.Readlink("copy/current")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "conf/app.ini", target)
+	})
+
+	t.Run("merges into an existing directory", func(t *testing.T) {
+		dst := NewMemFS()
+		require.EqualErrno(t, 0, dst.Mkdir("conf", 0o755))
+		require.EqualErrno(t, 0, writeFile(dst, "conf/app.ini", []byte("old"), 0o600))
+		require.EqualErrno(t, 0, writeFile(dst, "other", []byte("kept"), 0o600))
+
+		_, errno := CopyTree(context.Background(), dst, ".", src, "app")
+		require.EqualErrno(t, 0, errno)
+
+		b, errno := readFSFile(dst, "conf/app.ini")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "a=1", string(b))
+		b, errno = readFSFile(dst, "other")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "kept", string(b))
+	})
+
+	t.Run("skips symlinks the destination can't create", func(t *testing.T) {
+		dst := noSymlinkFS{NewMemFS()}
+		skipped, errno := CopyTree(context.Background(), dst, "copy", src, "app")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, map[string]syscall.Errno{"app/current": syscall.ENOSYS}, skipped)
+
+		_, errno = dst.Stat("copy/conf/app.ini")
+		require.EqualErrno(t, 0, errno)
+	})
+
+	t.Run("EROFS", func(t *testing.T) {
+		_, errno := CopyTree(context.Background(), NewReadFS(NewMemFS()), "copy", src, "app")
+		require.EqualErrno(t, syscall.EROFS, errno)
+	})
+
+	t.Run("ECANCELED", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		dst := NewMemFS()
+		_, errno := CopyTree(ctx, dst, "copy", src, "app")
+		require.EqualErrno(t, syscall.ECANCELED, errno)
+		_, errno = dst.Stat("copy")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("ENOENT", func(t *testing.T) {
+		_, errno := CopyTree(context.Background(), NewMemFS(), "copy", src, "missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+}
+
+// readFSFile returns the contents of `path` in `testFS`.
+func readFSFile(testFS FS, path string) ([]byte, syscall.Errno) {
+	f, errno := testFS.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	defer f.Close()
+
+	var b []byte
+	buf := make([]byte, 4096)
+	for {
+		n, errno := f.Read(buf)
+		if errno != 0 {
+			return nil, errno
+		} else if n == 0 {
+			return b, 0
+		}
+		b = append(b, buf[:n]...)
+	}
+}
+
+func TestCopyTree_symlinkAtDestination(t *testing.T) {
+	src := NewMemFS()
+	require.EqualErrno(t, 0, src.Mkdir("dir", 0o755))
+	require.EqualErrno(t, 0, writeFile(src, "dir/file", []byte("new"), 0o644))
+
+	t.Run("file", func(t *testing.T) {
+		dst := NewMemFS()
+		require.EqualErrno(t, 0, writeFile(dst, "secret", []byte("old"), 0o600))
+		require.EqualErrno(t, 0, dst.Mkdir("copy", 0o755))
+		require.EqualErrno(t, 0, dst.Symlink("../secret", "copy/file"))
+
+		_, errno := CopyTree(context.Background(), dst, "copy", src, "dir")
+		require.EqualErrno(t, syscall.EEXIST, errno)
+		b, errno := readFSFile(dst, "secret")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "old", string(b))
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dst := NewMemFS()
+		require.EqualErrno(t, 0, dst.Mkdir("elsewhere", 0o755))
+		require.EqualErrno(t, 0, dst.Symlink("elsewhere", "copy"))
+
+		_, errno := CopyTree(context.Background(), dst, "copy", src, "dir")
+		require.EqualErrno(t, syscall.EEXIST, errno)
+		_, errno = dst.Stat("elsewhere/file")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+}
+
+func TestCopyTree_intoItself(t *testing.T) {
+	testFS := NewMemFS()
+	require.EqualErrno(t, 0, MkdirAll(testFS, "app/conf", 0o755))
+
+	for _, dstPath := range []string{"app", "app/copy", "app/conf/copy", "/app/copy"} {
+		_, errno := CopyTree(context.Background(), testFS, dstPath, testFS, "app")
+		require.EqualErrno(t, syscall.EINVAL, errno)
+	}
+	_, errno := CopyTree(context.Background(), testFS, "copy", testFS, ".")
+	require.EqualErrno(t, syscall.EINVAL, errno)
+
+	// A sibling which shares the prefix is fine.
+	_, errno = CopyTree(context.Background(), testFS, "application", testFS, "app")
+	require.EqualErrno(t, 0, errno)
+
+	// Another FS of the same directory is detected by its inode.
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(tmpDir+"/app/conf", 0o755))
+	if st, errno := NewDirFS(tmpDir).Stat("app"); errno != 0 || st.Ino == 0 {
+		t.Skip("inodes aren't known on this platform")
+	}
+	_, errno = CopyTree(context.Background(), NewDirFS(tmpDir+"/app"), "copy", NewDirFS(tmpDir), "app")
+	require.EqualErrno(t, syscall.EINVAL, errno)
+}
@@ -0,0 +1,157 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// moveBufferSize is the size of the buffer used to copy files by moveFile.
+const moveBufferSize = 32 * 1024
+
+// WithCrossDeviceRename makes Rename between different mounts, or within a
+// mount whose Rename returns syscall.EXDEV, copy the file and remove the
+// source, like `mv` does.
+//
+// # Notes
+//
+//   - The file is copied to a temporary file next to the destination, which
+//     is synced, then renamed over the destination. The source is only
+//     removed after that succeeds. On failure, the temporary file is removed
+//     and the source is left as it was.
+//   - The mode and access and modification times of the file are preserved.
+//     Symbolic links are moved as links, not followed.
+//   - Directories are not copied: syscall.EXDEV is still returned for them.
+//   - The move is not atomic: readers of the source may see it after the
+//     destination is visible.
+func WithCrossDeviceRename() MountFSOption {
+	return func(m *mountFS) {
+		m.crossDeviceRename = true
+	}
+}
+
+// moveFile moves the file at `fromPath` in `fromFS` to `toPath` in `toFS`, by
+// copying it. See WithCrossDeviceRename for details.
+func moveFile(fromFS FS, fromPath string, toFS FS, toPath string) syscall.Errno {
+	st, errno := fromFS.Lstat(fromPath)
+	if errno != 0 {
+		return errno
+	} else if st.Mode.IsDir() {
+		return syscall.EXDEV
+	}
+	if toSt, errno := toFS.Lstat(toPath); errno == 0 && toSt.Mode.IsDir() {
+		return syscall.EISDIR
+	}
+
+	var tmpPath string
+	if st.Mode&fs.ModeSymlink != 0 {
+		tmpPath, errno = copySymlink(fromFS, fromPath, toFS, toPath, st)
+	} else {
+		tmpPath, errno = copyRegular(fromFS, fromPath, toFS, toPath, st)
+	}
+	if errno != 0 {
+		return errno
+	}
+
+	if errno = toFS.Rename(tmpPath, toPath); errno != 0 {
+		_ = toFS.Unlink(tmpPath)
+		return errno
+	}
+	return fromFS.Unlink(fromPath)
+}
+
+// copyRegular copies the regular file at `fromPath` to a temporary file next
+// to `toPath`, returning its path.
+func copyRegular(fromFS FS, fromPath string, toFS FS, toPath string, st platform.Stat_t) (string, syscall.Errno) {
+	src, errno := fromFS.OpenFile(fromPath, os.O_RDONLY, 0)
+	if errno != 0 {
+		return "", errno
+	}
+	defer src.Close()
+
+	tmpPath, dst, errno := createTemp(toFS, toPath, st.Mode.Perm())
+	if errno != 0 {
+		return "", errno
+	}
+
+	errno = copyFile(dst, src)
+	if errno == 0 {
+		// Chmod undoes any umask applied on create.
+		errno = ignoreENOSYS(dst.Chmod(st.Mode.Perm()))
+	}
+	if errno == 0 {
+		errno = ignoreENOSYS(dst.Utimens(statTimes(st)))
+	}
+	if errno == 0 {
+		errno = dst.Sync()
+	}
+	if closeErrno := dst.Close(); errno == 0 {
+		errno = closeErrno
+	}
+	if errno != 0 {
+		_ = toFS.Unlink(tmpPath)
+		return "", errno
+	}
+	return tmpPath, 0
+}
+
+// copySymlink copies the symbolic link at `fromPath` to a temporary link next
+// to `toPath`, returning its path.
+func copySymlink(fromFS FS, fromPath string, toFS FS, toPath string, st platform.Stat_t) (string, syscall.Errno) {
+	target, errno := fromFS.Readlink(fromPath)
+	if errno != 0 {
+		return "", errno
+	}
+
+	dir, base := path.Split(toPath)
+	for i := 0; i < maxTempAttempts; i++ {
+		tmpPath := dir + "." + base + ".tmp" + strconv.Itoa(i)
+		switch errno = toFS.Symlink(target, tmpPath); errno {
+		case 0:
+			// Not all platforms can set the times of a link.
+			_ = toFS.Utimens(tmpPath, statTimes(st), false)
+			return tmpPath, 0
+		case syscall.EEXIST:
+			continue
+		default:
+			return "", errno
+		}
+	}
+	return "", syscall.EEXIST
+}
+
+// copyFile writes the remaining contents of `src` to `dst`.
+func copyFile(dst, src platform.File) syscall.Errno {
+	buf := make([]byte, moveBufferSize)
+	for {
+		n, errno := src.Read(buf)
+		if errno != 0 {
+			return errno
+		} else if n == 0 {
+			return 0
+		}
+		if errno = writeAll(dst, buf[:n]); errno != 0 {
+			return errno
+		}
+	}
+}
+
+// statTimes returns the access and modification times of `st`, for Utimens.
+func statTimes(st platform.Stat_t) *[2]syscall.Timespec {
+	return &[2]syscall.Timespec{
+		syscall.NsecToTimespec(st.Atim),
+		syscall.NsecToTimespec(st.Mtim),
+	}
+}
+
+// ignoreENOSYS returns zero if `errno` is syscall.ENOSYS.
+func ignoreENOSYS(errno syscall.Errno) syscall.Errno {
+	if errno == syscall.ENOSYS {
+		return 0
+	}
+	return errno
+}
@@ -0,0 +1,95 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithCrossDeviceRename(t *testing.T) {
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	mountFS, err := NewMountFS(map[string]FS{
+		"/":    NewDirFS(srcDir),
+		"/tmp": NewDirFS(dstDir),
+		"/mem": NewLimitedMemFS(100, 3),
+	}, WithCrossDeviceRename())
+	require.NoError(t, err)
+
+	t.Run("file", func(t *testing.T) {
+		file := path.Join(srcDir, "file")
+		require.NoError(t, os.WriteFile(file, []byte("wazero"), 0o640))
+		mtime := time.Unix(1234567890, 0)
+		require.NoError(t, os.Chtimes(file, mtime, mtime))
+
+		require.EqualErrno(t, 0, mountFS.Rename("file", "tmp/file"))
+
+		_, err := os.Stat(file)
+		require.ErrorIs(t, err, os.ErrNotExist)
+		b, err := os.ReadFile(path.Join(dstDir, "file"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+
+		st, errno := mountFS.Stat("tmp/file")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, mtime.UnixNano(), st.Mtim)
+		if runtime.GOOS != "windows" {
+			require.Equal(t, fs.FileMode(0o640), st.Mode.Perm())
+		}
+	})
+
+	t.Run("replaces destination", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(srcDir, "new"), []byte("new"), 0o600))
+		require.NoError(t, os.WriteFile(path.Join(dstDir, "old"), []byte("old"), 0o600))
+
+		require.EqualErrno(t, 0, mountFS.Rename("new", "tmp/old"))
+
+		b, err := os.ReadFile(path.Join(dstDir, "old"))
+		require.NoError(t, err)
+		require.Equal(t, "new", string(b))
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		require.EqualErrno(t, 0, mountFS.Symlink("target", "link"))
+
+		require.EqualErrno(t, 0, mountFS.Rename("link", "mem/link"))
+
+		target, errno := mountFS.Readlink("mem/link")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "target", target)
+		_, errno = mountFS.Lstat("link")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		require.EqualErrno(t, 0, mountFS.Mkdir("dir", 0o700))
+
+		require.EqualErrno(t, syscall.EXDEV, mountFS.Rename("dir", "tmp/dir"))
+	})
+
+	t.Run("failed copy keeps the source", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(srcDir, "big"), []byte("wazero"), 0o600))
+
+		// The memory mount only has room for 3 bytes.
+		require.EqualErrno(t, syscall.ENOSPC, mountFS.Rename("big", "mem/big"))
+
+		b, err := os.ReadFile(path.Join(srcDir, "big"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+		require.Equal(t, []string{"link"}, readdirNames(t, mountFS, "mem"))
+	})
+}
+
+func TestWithCrossDeviceRename_disabled(t *testing.T) {
+	srcDir := t.TempDir()
+	mountFS, err := NewMountFS(map[string]FS{"/": NewDirFS(srcDir), "/tmp": NewDirFS(t.TempDir())})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path.Join(srcDir, "file"), nil, 0o600))
+	require.EqualErrno(t, syscall.EXDEV, mountFS.Rename("file", "tmp/file"))
+}
@@ -0,0 +1,387 @@
+package sysfs
+
+import (
+	"context"
+	"io/fs"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// NewDeadlineFS returns an FS which delegates to `fs` until `deadline`, after
+// which every operation returns syscall.ETIMEDOUT. This caps the wall-clock
+// time a module can spend on filesystem activity, independent of CPU
+// metering.
+//
+// # Notes
+//
+//   - Operations on files opened by this FS also return syscall.ETIMEDOUT
+//     after the deadline, except Close, so that they can be released.
+//   - A Read, Write or Writev blocked at the deadline, such as on a pipe,
+//     is interrupted and returns syscall.ETIMEDOUT. This uses
+//     platform.NewContextFile, which waits for the file to be ready with
+//     select, along with a pipe closed at the deadline. Files without a
+//     file descriptor, or on platforms without select, are only checked
+//     before each call.
+//   - A blocking platform.File PollRead, such as from WASI `poll_oneoff`,
+//     returns syscall.ETIMEDOUT at the deadline, even if its own timeout is
+//     later.
+//   - Operations already in progress on the host, such as a slow Stat on a
+//     network filesystem, aren't interrupted.
+func NewDeadlineFS(fs FS, deadline time.Time) FS {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	return &deadlineFS{fs: fs, ctx: ctx, cancel: cancel}
+}
+
+type deadlineFS struct {
+	UnimplementedFS
+	fs  FS
+	ctx context.Context
+	// cancel releases the timer of ctx, which otherwise runs until the
+	// deadline.
+	cancel context.CancelFunc
+}
+
+// check returns syscall.ETIMEDOUT once the deadline passed.
+func (d *deadlineFS) check() syscall.Errno {
+	if d.ctx.Err() != nil {
+		return syscall.ETIMEDOUT
+	}
+	return 0
+}
+
+// String implements fmt.Stringer
+func (d *deadlineFS) String() string {
+	return d.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (d *deadlineFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if errno := d.check(); errno != 0 {
+		return nil, errno
+	}
+	f, errno := d.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return d.wrap(f), 0
+}
+
+// OpenByID implements FS.OpenByID
+func (d *deadlineFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	if errno := d.check(); errno != 0 {
+		return nil, errno
+	}
+	f, errno := d.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	return d.wrap(f), 0
+}
+
+// wrap returns `f` as a deadlineFile.
+func (d *deadlineFS) wrap(f platform.File) platform.File {
+	return &deadlineFile{File: platform.NewContextFile(d.ctx, f), fs: d}
+}
+
+// Lstat implements FS.Lstat
+func (d *deadlineFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := d.check(); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return d.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (d *deadlineFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := d.check(); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return d.fs.Stat(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (d *deadlineFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (d *deadlineFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (d *deadlineFS) Chown(path string, uid, gid int) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (d *deadlineFS) Lchown(path string, uid, gid int) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (d *deadlineFS) Rename(from, to string) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (d *deadlineFS) ExchangeDir(a, b string) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (d *deadlineFS) Clone(src, dst string) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (d *deadlineFS) Rmdir(path string) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (d *deadlineFS) Unlink(path string) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (d *deadlineFS) Link(oldPath, newPath string) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (d *deadlineFS) Symlink(oldPath, linkName string) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (d *deadlineFS) Readlink(path string) (string, syscall.Errno) {
+	if errno := d.check(); errno != 0 {
+		return "", errno
+	}
+	return d.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (d *deadlineFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if errno := d.check(); errno != 0 {
+		return 0, errno
+	}
+	return d.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (d *deadlineFS) Truncate(path string, size int64) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (d *deadlineFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (d *deadlineFS) SyncDir(path string) syscall.Errno {
+	if errno := d.check(); errno != 0 {
+		return errno
+	}
+	return d.fs.SyncDir(path)
+}
+
+// deadlineFile is a file opened by deadlineFS, which checks the deadline on
+// each operation except Close.
+type deadlineFile struct {
+	platform.File
+	fs *deadlineFS
+}
+
+// timedOut returns syscall.ETIMEDOUT instead of syscall.ECANCELED, which
+// platform.NewContextFile returns once the deadline passed.
+func timedOut(errno syscall.Errno) syscall.Errno {
+	if errno == syscall.ECANCELED {
+		return syscall.ETIMEDOUT
+	}
+	return errno
+}
+
+// Read implements the same method as documented on platform.File
+func (f *deadlineFile) Read(buf []byte) (int, syscall.Errno) {
+	n, errno := f.File.Read(buf)
+	return n, timedOut(errno)
+}
+
+// Write implements the same method as documented on platform.File
+func (f *deadlineFile) Write(buf []byte) (int, syscall.Errno) {
+	n, errno := f.File.Write(buf)
+	return n, timedOut(errno)
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *deadlineFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	n, errno := f.File.Writev(bufs)
+	return n, timedOut(errno)
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *deadlineFile) PollRead(timeout *time.Duration) (bool, syscall.Errno) {
+	ready, errno := f.File.PollRead(timeout)
+	return ready, timedOut(errno)
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *deadlineFile) Stat() (platform.Stat_t, syscall.Errno) {
+	if errno := f.fs.check(); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return f.File.Stat()
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *deadlineFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.fs.check(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Pread(buf, off)
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *deadlineFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.fs.check(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Pwrite(buf, off)
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *deadlineFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if errno := f.fs.check(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Seek(offset, whence)
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *deadlineFile) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	if errno := f.fs.check(); errno != 0 {
+		return nil, errno
+	}
+	return f.File.Readdir(n)
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (f *deadlineFile) RewindDir() syscall.Errno {
+	if errno := f.fs.check(); errno != 0 {
+		return errno
+	}
+	return f.File.RewindDir()
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *deadlineFile) Truncate(size int64) syscall.Errno {
+	if errno := f.fs.check(); errno != 0 {
+		return errno
+	}
+	return f.File.Truncate(size)
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *deadlineFile) PunchHole(offset, length int64) syscall.Errno {
+	if errno := f.fs.check(); errno != 0 {
+		return errno
+	}
+	return f.File.PunchHole(offset, length)
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *deadlineFile) Sync() syscall.Errno {
+	if errno := f.fs.check(); errno != 0 {
+		return errno
+	}
+	return f.File.Sync()
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *deadlineFile) Datasync() syscall.Errno {
+	if errno := f.fs.check(); errno != 0 {
+		return errno
+	}
+	return f.File.Datasync()
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *deadlineFile) Chmod(perm fs.FileMode) syscall.Errno {
+	if errno := f.fs.check(); errno != 0 {
+		return errno
+	}
+	return f.File.Chmod(perm)
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *deadlineFile) Chown(uid, gid int) syscall.Errno {
+	if errno := f.fs.check(); errno != 0 {
+		return errno
+	}
+	return f.File.Chown(uid, gid)
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *deadlineFile) Utimens(times *[2]syscall.Timespec) syscall.Errno {
+	if errno := f.fs.check(); errno != 0 {
+		return errno
+	}
+	return f.File.Utimens(times)
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *deadlineFile) Dup() (platform.File, syscall.Errno) {
+	if errno := f.fs.check(); errno != 0 {
+		return nil, errno
+	}
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	// The dup of a context file watches the same context.
+	return &deadlineFile{File: dup, fs: f.fs}, 0
+}
@@ -0,0 +1,89 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestDeadlineFS(t *testing.T) {
+	memFS := NewMemFS()
+	require.EqualErrno(t, 0, memFS.Mkdir("dir", 0o755))
+
+	t.Run("before the deadline", func(t *testing.T) {
+		testFS := NewDeadlineFS(memFS, time.Now().Add(time.Hour))
+		require.Equal(t, memFS.String(), testFS.String())
+
+		f, errno := testFS.OpenFile("dir/file", os.O_RDWR|os.O_CREATE, 0o644)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		_, errno = f.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		st, errno := testFS.Stat("dir/file")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(6), st.Size)
+	})
+
+	t.Run("after the deadline", func(t *testing.T) {
+		testFS := NewDeadlineFS(memFS, time.Now().Add(50*time.Millisecond))
+		f, errno := testFS.OpenFile("dir/file", os.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+
+		time.Sleep(100 * time.Millisecond)
+
+		_, errno = testFS.OpenFile("dir/file", os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+		_, errno = testFS.Stat("dir/file")
+		require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+		require.EqualErrno(t, syscall.ETIMEDOUT, testFS.Mkdir("other", 0o755))
+		require.EqualErrno(t, syscall.ETIMEDOUT, testFS.Unlink("dir/file"))
+
+		// An already open file also times out, but can be closed.
+		_, errno = f.Read(make([]byte, 6))
+		require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+		_, errno = f.Stat()
+		require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		// The underlying FS is unaffected.
+		_, errno = memFS.Stat("dir/file")
+		require.EqualErrno(t, 0, errno)
+	})
+}
+
+// pipeFS opens the read end of a pipe for any path.
+type pipeFS struct {
+	UnimplementedFS
+	r *os.File
+}
+
+// OpenFile implements FS.OpenFile
+func (p *pipeFS) OpenFile(string, int, fs.FileMode) (platform.File, syscall.Errno) {
+	return platform.NewFsFile("pipe", os.O_RDONLY, p.r), 0
+}
+
+func TestDeadlineFS_interruptsRead(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("select isn't supported on pipes on windows")
+	}
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer w.Close()
+
+	testFS := NewDeadlineFS(&pipeFS{r: r}, time.Now().Add(100*time.Millisecond))
+	f, errno := testFS.OpenFile("pipe", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	// Nothing is written, so this blocks until the deadline.
+	_, errno = f.Read(make([]byte, 1))
+	require.EqualErrno(t, syscall.ETIMEDOUT, errno)
+}
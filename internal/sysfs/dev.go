@@ -0,0 +1,15 @@
+package sysfs
+
+import "sync/atomic"
+
+// lastSyntheticDev is the last device ID returned by syntheticDev.
+var lastSyntheticDev uint64
+
+// syntheticDev returns a device ID for a virtual FS, such as one backed by an
+// archive. Each call returns a different ID, so that platform.SameFile doesn't
+// consider files in different instances the same, even if their inodes are.
+//
+// Note: The high bit is set to avoid colliding with device IDs of the host.
+func syntheticDev() uint64 {
+	return 1<<63 | atomic.AddUint64(&lastSyntheticDev, 1)
+}
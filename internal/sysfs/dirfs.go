@@ -3,15 +3,73 @@ package sysfs
 import (
 	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/tetratelabs/wazero/internal/platform"
 )
 
-func NewDirFS(dir string) FS {
-	return &dirFS{
+func NewDirFS(dir string, opts ...DirFSOption) FS {
+	d := &dirFS{
 		dir:        dir,
 		cleanedDir: ensureTrailingPathSeparator(dir),
+		maxPathLen: DefaultMaxPathLen,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// DirFSOption configures an FS returned by NewDirFS.
+type DirFSOption func(*dirFS)
+
+// WithSyncOnClose makes files opened for writing call Sync before Close, if
+// they were modified since the last Sync. This is for crash consistency, when
+// the guest doesn't call `fd_sync` itself.
+//
+// Note: This adds the latency of a flush to storage to each Close of a
+// modified file, which can be significant on slow disks.
+func WithSyncOnClose() DirFSOption {
+	return func(d *dirFS) {
+		d.syncOnClose = true
+	}
+}
+
+// WithUmask clears the bits in `mask` from the permissions of files and
+// directories created by the guest, like `umask` in POSIX.
+//
+// Note: On unix, the host also applies the process umask, so this can only
+// further restrict permissions. Windows has no process umask.
+func WithUmask(mask fs.FileMode) DirFSOption {
+	return func(d *dirFS) {
+		d.umask = mask.Perm()
+	}
+}
+
+// WithShortWrites makes writes which run out of space part way through return
+// the count written without an error, like `write` in POSIX. The next write
+// then returns syscall.ENOSPC.
+//
+// By default, the partial count is returned with syscall.ENOSPC, as guests
+// which ignore short counts would otherwise lose data without noticing.
+func WithShortWrites() DirFSOption {
+	return func(d *dirFS) {
+		d.shortWrites = true
+	}
+}
+
+// WithReaddirBatchSize sets the size in bytes of the buffer directories are
+// read into from the host, which is 8KiB by default. File.Readdir still
+// returns only the count of entries requested, so a larger buffer reduces
+// calls to the host when reading a large directory in pages, such as for WASI
+// `fd_readdir`.
+//
+// Note: This only has an effect on Linux. See platform.SetReaddirBufSize.
+func WithReaddirBatchSize(size int) DirFSOption {
+	return func(d *dirFS) {
+		d.readdirBufSize = size
 	}
 }
 
@@ -28,6 +86,32 @@ type dirFS struct {
 	// cleanedDir is for easier OS-specific concatenation, as it always has
 	// a trailing path separator.
 	cleanedDir string
+	// syncOnClose is set by WithSyncOnClose.
+	syncOnClose bool
+	// shortWrites is set by WithShortWrites.
+	shortWrites bool
+	// umask is set by WithUmask.
+	umask fs.FileMode
+	// createOwner is set by WithCreateOwner, to chown new files to ownerUID
+	// and ownerGID.
+	createOwner        bool
+	ownerUID, ownerGID int
+	// maxPathLen is the longest path accepted, set by WithMaxPathLen.
+	maxPathLen int
+	// atime is set by WithAtime.
+	atime AtimePolicy
+	// openFiles is set by WithOpenFileTracking.
+	openFiles *openFiles
+	// maxFileSize is set by WithMaxFileSize.
+	maxFileSize int64
+	// readdirBufSize is set by WithReaddirBatchSize.
+	readdirBufSize int
+	// openLimit is set by WithMaxOpenFiles.
+	openLimit *openLimit
+	// firstOpen is set by WithOnFirstOpen.
+	firstOpen *firstOpen
+	// sortedReaddir is set by WithSortedReaddir.
+	sortedReaddir bool
 }
 
 // String implements fmt.Stringer
@@ -36,104 +120,351 @@ func (d *dirFS) String() string {
 }
 
 // OpenFile implements FS.OpenFile
-func (d *dirFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
-	f, errno := platform.OpenFile(d.join(path), flag, perm)
+func (d *dirFS) OpenFile(path string, flag int, perm fs.FileMode) (f platform.File, errno syscall.Errno) {
+	if errno = d.validatePaths(path); errno != 0 {
+		return
+	}
+	if d.createOwner && flag&os.O_CREATE != 0 {
+		f, errno = d.createOwned(flag, func(flag int) (platform.File, syscall.Errno) {
+			return d.openFile(path, flag, perm)
+		}, func() syscall.Errno {
+			return platform.Unlink(d.join(path))
+		})
+	} else {
+		f, errno = d.openFile(path, flag, perm)
+	}
+	if errno == 0 {
+		d.firstOpen.opened(path)
+	}
+	return
+}
+
+func (d *dirFS) openFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	open := platform.OpenFile
+	if d.atime == NoAtime {
+		open = platform.OpenFileNoatime
+	}
+	if errno := d.openLimit.acquire(); errno != 0 {
+		return nil, errno
+	}
+	f, errno := open(d.join(path), flag, perm&^d.umask)
+	if errno != 0 {
+		d.openLimit.release()
+		return nil, errno
+	}
+	if flag&os.O_CREATE != 0 {
+		d.dirChanged(path)
+	}
+	return d.wrap(platform.NewFsFile(path, flag, f), flag), 0
+}
+
+// OpenByID implements FS.OpenByID
+func (d *dirFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	if flag&(os.O_CREATE|os.O_EXCL) != 0 {
+		return nil, syscall.EINVAL
+	}
+	if st, errno := d.Stat("."); errno != 0 {
+		return nil, errno
+	} else if st.Dev != dev {
+		return nil, syscall.EXDEV
+	}
+	root, err := filepath.EvalSymlinks(d.join("."))
+	if err != nil {
+		return nil, platform.UnwrapOSError(err)
+	}
+
+	// Open only a handle first, as the host can open any file on the device,
+	// and `flag` must not affect one outside this directory, e.g. O_TRUNC.
+	handle, errno := platform.OpenByID(root, ino, platform.O_PATH)
+	if errno != 0 {
+		return nil, errno
+	}
+	path, ok := relPath(root, handle.(interface{ Name() string }).Name())
+	_ = handle.Close()
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+
+	// Reopen relative to this directory with `flag`. The file may have been
+	// renamed or removed since, so check it is still the same.
+	f, errno := d.OpenFile(path, flag, 0)
 	if errno != 0 {
 		return nil, errno
 	}
-	return platform.NewFsFile(path, flag, f), 0
+	if st, errno := f.Stat(); errno != 0 || st.Ino != ino {
+		_ = f.Close()
+		return nil, syscall.ENOENT
+	}
+	return f, 0
+}
+
+// relPath returns the slash-separated path of `hostPath` relative to the
+// directory `root`, or false if it isn't inside it.
+func relPath(root, hostPath string) (string, bool) {
+	rel, err := filepath.Rel(root, hostPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// wrap applies any options which affect files opened by this FS. When
+// WithMaxOpenFiles is set, `f` must have been opened with a slot from
+// openLimit.acquire.
+func (d *dirFS) wrap(f platform.File, flag int) platform.File {
+	if d.readdirBufSize > 0 {
+		platform.SetReaddirBufSize(f, d.readdirBufSize)
+	}
+	if d.sortedReaddir {
+		if isDir, _ := f.IsDir(); isDir {
+			f = &sortedReaddirFile{File: f}
+		}
+	}
+	if d.maxFileSize > 0 && f.AccessMode() != syscall.O_RDONLY {
+		f = &maxFileSizeFile{File: f, maxFileSize: d.maxFileSize, append: flag&syscall.O_APPEND != 0}
+	}
+	if d.shortWrites {
+		f = &shortWritesFile{File: f}
+	}
+	if d.syncOnClose {
+		f = newSyncOnCloseFile(f, flag)
+	}
+	return d.openFiles.track(d.openLimit.limit(f))
+}
+
+// ResetFirstOpen implements FirstOpenFS.ResetFirstOpen
+func (d *dirFS) ResetFirstOpen() {
+	d.firstOpen.reset()
+}
+
+// OpenFiles implements OpenFilesFS.OpenFiles
+func (d *dirFS) OpenFiles() []OpenFileInfo {
+	return d.openFiles.list()
 }
 
 // Lstat implements FS.Lstat
 func (d *dirFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := d.validatePaths(path); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
 	return platform.Lstat(d.join(path))
 }
 
 // Stat implements FS.Stat
 func (d *dirFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := d.validatePaths(path); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
 	return platform.Stat(d.join(path))
 }
 
 // Mkdir implements FS.Mkdir
 func (d *dirFS) Mkdir(path string, perm fs.FileMode) (errno syscall.Errno) {
-	err := os.Mkdir(d.join(path), perm)
+	if errno = d.validatePaths(path); errno != 0 {
+		return
+	}
+	err := os.Mkdir(d.join(path), perm&^d.umask)
 	if errno = platform.UnwrapOSError(err); errno == syscall.ENOTDIR {
 		errno = syscall.ENOENT
+	} else if errno == 0 {
+		d.dirChanged(path)
+	}
+	if errno == 0 && d.createOwner {
+		errno = d.chownCreatedDir(func() (platform.File, syscall.Errno) {
+			return d.openFile(path, createdDirFlag, 0)
+		}, func() syscall.Errno {
+			return d.Rmdir(path)
+		})
 	}
 	return
 }
 
 // Chmod implements FS.Chmod
 func (d *dirFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	}
 	err := os.Chmod(d.join(path), perm)
 	return platform.UnwrapOSError(err)
 }
 
 // Chown implements FS.Chown
 func (d *dirFS) Chown(path string, uid, gid int) syscall.Errno {
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	}
 	return platform.Chown(d.join(path), uid, gid)
 }
 
 // Lchown implements FS.Lchown
 func (d *dirFS) Lchown(path string, uid, gid int) syscall.Errno {
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	}
 	return platform.Lchown(d.join(path), uid, gid)
 }
 
 // Rename implements FS.Rename
 func (d *dirFS) Rename(from, to string) syscall.Errno {
-	from, to = d.join(from), d.join(to)
-	return platform.Rename(from, to)
+	if errno := d.validatePaths(from, to); errno != 0 {
+		return errno
+	}
+	errno := platform.Rename(d.join(from), d.join(to))
+	if errno == 0 {
+		d.dirChanged(from, to)
+	}
+	return errno
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (d *dirFS) ExchangeDir(a, b string) syscall.Errno {
+	if errno := d.validatePaths(a, b); errno != 0 {
+		return errno
+	}
+	for _, path := range [...]string{a, b} {
+		if st, errno := d.Lstat(path); errno != 0 {
+			return errno
+		} else if !st.Mode.IsDir() {
+			return syscall.ENOTDIR
+		}
+	}
+	errno := platform.ExchangeDir(d.join(a), d.join(b))
+	if errno == 0 {
+		d.dirChanged(a, b)
+	}
+	return errno
+}
+
+// Clone implements FS.Clone
+func (d *dirFS) Clone(src, dst string) syscall.Errno {
+	if errno := d.validatePaths(src, dst); errno != 0 {
+		return errno
+	}
+	if st, errno := d.Stat(src); errno != 0 {
+		return errno
+	} else if st.Mode.IsDir() {
+		return syscall.EISDIR
+	} else if !st.Mode.IsRegular() {
+		return syscall.ENOTSUP
+	}
+	errno := platform.Clone(d.join(src), d.join(dst))
+	if errno == 0 {
+		d.dirChanged(dst)
+	}
+	return errno
 }
 
 // Readlink implements FS.Readlink
 func (d *dirFS) Readlink(path string) (string, syscall.Errno) {
-	// Note: do not use syscall.Readlink as that causes race on Windows.
-	// In any case, syscall.Readlink does almost the same logic as os.Readlink.
-	dst, err := os.Readlink(d.join(path))
-	if err != nil {
-		return "", platform.UnwrapOSError(err)
+	if errno := d.validatePaths(path); errno != 0 {
+		return "", errno
+	}
+	dst, errno := platform.Readlink(d.join(path))
+	if errno != 0 {
+		return "", errno
 	}
 	return platform.ToPosixPath(dst), 0
 }
 
+// ReadlinkInto implements FS.ReadlinkInto
+func (d *dirFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if errno := d.validatePaths(path); errno != 0 {
+		return 0, errno
+	}
+	return platform.ReadlinkInto(d.join(path), buf)
+}
+
 // Link implements FS.Link.
 func (d *dirFS) Link(oldName, newName string) syscall.Errno {
+	if errno := d.validatePaths(oldName, newName); errno != 0 {
+		return errno
+	}
 	err := os.Link(d.join(oldName), d.join(newName))
-	return platform.UnwrapOSError(err)
+	errno := platform.UnwrapOSError(err)
+	if errno == 0 {
+		d.dirChanged(newName)
+	}
+	return errno
 }
 
 // Rmdir implements FS.Rmdir
 func (d *dirFS) Rmdir(path string) syscall.Errno {
-	err := syscall.Rmdir(d.join(path))
-	return platform.UnwrapOSError(err)
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	}
+	errno := platform.Rmdir(d.join(path))
+	if errno == 0 {
+		d.dirChanged(path)
+	}
+	return errno
 }
 
 // Unlink implements FS.Unlink
 func (d *dirFS) Unlink(path string) (err syscall.Errno) {
-	return platform.Unlink(d.join(path))
+	if err = d.validatePaths(path); err != 0 {
+		return
+	}
+	if err = platform.Unlink(d.join(path)); err == 0 {
+		d.dirChanged(path)
+	}
+	return
 }
 
 // Symlink implements FS.Symlink
 func (d *dirFS) Symlink(oldName, link string) syscall.Errno {
+	if errno := d.validatePaths(oldName, link); errno != 0 {
+		return errno
+	}
 	// Note: do not resolve `oldName` relative to this dirFS. The link result is always resolved
 	// when dereference the `link` on its usage (e.g. readlink, read, etc).
 	// https://github.com/bytecodealliance/cap-std/blob/v1.0.4/cap-std/src/fs/dir.rs#L404-L409
-	err := os.Symlink(oldName, d.join(link))
-	return platform.UnwrapOSError(err)
+	errno := platform.Symlink(oldName, d.join(link))
+	if errno == 0 {
+		d.dirChanged(link)
+	}
+	return errno
 }
 
 // Utimens implements FS.Utimens
 func (d *dirFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	}
 	return platform.Utimens(d.join(path), times, symlinkFollow)
 }
 
+// SyncDir implements FS.SyncDir
+func (d *dirFS) SyncDir(path string) syscall.Errno {
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	}
+	return platform.SyncDir(d.join(path))
+}
+
 // Truncate implements FS.Truncate
 func (d *dirFS) Truncate(path string, size int64) syscall.Errno {
+	if errno := d.validatePaths(path); errno != 0 {
+		return errno
+	} else if d.maxFileSize > 0 && size > d.maxFileSize {
+		if st, errno := d.Stat(path); errno != 0 {
+			return errno
+		} else if errno = checkFileSize(d.maxFileSize, st.Size, size); errno != 0 {
+			return errno
+		}
+	}
 	// Use os.Truncate as syscall.Truncate doesn't exist on Windows.
 	err := os.Truncate(d.join(path), size)
 	return platform.UnwrapOSError(err)
 }
 
+// dirChanged notifies open directories that entries of the parent of each
+// path changed. See platform.DirChanged.
+func (d *dirFS) dirChanged(paths ...string) {
+	for _, p := range paths {
+		platform.DirChanged(filepath.Dir(d.join(p)))
+	}
+}
+
 func (d *dirFS) join(path string) string {
 	switch path {
 	case "", ".", "/":
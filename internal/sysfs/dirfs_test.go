@@ -2,10 +2,12 @@ package sysfs
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path"
 	"runtime"
+	"sort"
 	"syscall"
 	"testing"
 	"time"
@@ -148,6 +150,40 @@ func requireMode(t *testing.T, testFS FS, path string, mode fs.FileMode) {
 	require.Equal(t, mode, st.Mode.Perm())
 }
 
+func TestDirFS_WithUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows has no umask")
+	}
+
+	testFS := NewDirFS(t.TempDir(), WithUmask(0o022))
+
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o777))
+	requireMode(t, testFS, "dir", 0o755)
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o666)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	requireMode(t, testFS, "file", 0o644)
+
+	// The umask also applies to paths relative to a directory.
+	dir, errno := testFS.OpenFile("dir", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer dir.Close()
+
+	require.EqualErrno(t, 0, MkdirAt(testFS, dir, "sub", 0o777))
+	requireMode(t, testFS, "dir/sub", 0o755)
+
+	f, errno = OpenFileAt(testFS, dir, "file", os.O_RDWR|os.O_CREATE, 0o666)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	requireMode(t, testFS, "dir/file", 0o644)
+
+	// A stricter umask removes more bits than the process umask.
+	testFS = NewDirFS(t.TempDir(), WithUmask(0o077))
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o777))
+	requireMode(t, testFS, "dir", 0o700)
+}
+
 func TestDirFS_Rename(t *testing.T) {
 	t.Run("from doesn't exist", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -365,6 +401,169 @@ func TestDirFS_Rename(t *testing.T) {
 	})
 }
 
+func TestDirFS_ExchangeDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	for _, dir := range []string{"blue", "green"} {
+		require.NoError(t, os.Mkdir(path.Join(tmpDir, dir), 0o700))
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, dir, dir), nil, 0o600))
+	}
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), nil, 0o600))
+
+	t.Run("ENOENT", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOENT, testFS.ExchangeDir("blue", "missing"))
+		require.EqualErrno(t, syscall.ENOENT, testFS.ExchangeDir("missing", "green"))
+	})
+
+	t.Run("ENOTDIR", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOTDIR, testFS.ExchangeDir("blue", "file"))
+		require.EqualErrno(t, syscall.ENOTDIR, testFS.ExchangeDir("file", "green"))
+	})
+
+	t.Run("swaps directories", func(t *testing.T) {
+		errno := testFS.ExchangeDir("blue", "green")
+		if runtime.GOOS != "linux" {
+			require.EqualErrno(t, syscall.ENOSYS, errno)
+			return
+		} else if errno == syscall.ENOSYS {
+			t.Skip("renameat2 isn't supported by this kernel or filesystem")
+		}
+		require.EqualErrno(t, 0, errno)
+
+		require.Equal(t, []string{"green"}, readdirNames(t, testFS, "blue"))
+		require.Equal(t, []string{"blue"}, readdirNames(t, testFS, "green"))
+	})
+}
+
+func TestDirFS_SyncDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), nil, 0o600))
+
+	require.EqualErrno(t, 0, testFS.SyncDir("."))
+	require.EqualErrno(t, 0, testFS.SyncDir("dir"))
+	require.EqualErrno(t, syscall.ENOENT, testFS.SyncDir("missing"))
+	require.EqualErrno(t, syscall.ENOTDIR, testFS.SyncDir("file"))
+}
+
+func TestDirFS_Clone(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "src"), []byte("wazero"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "existing"), nil, 0o600))
+
+	t.Run("ENOENT", func(t *testing.T) {
+		require.EqualErrno(t, syscall.ENOENT, testFS.Clone("missing", "dst"))
+	})
+
+	t.Run("EISDIR", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EISDIR, testFS.Clone("dir", "dst"))
+	})
+
+	t.Run("clones file", func(t *testing.T) {
+		errno := testFS.Clone("src", "dst")
+		if errno == syscall.ENOTSUP {
+			t.Skip("reflinks aren't supported by this platform or filesystem")
+		}
+		require.EqualErrno(t, 0, errno)
+
+		b, err := os.ReadFile(path.Join(tmpDir, "dst"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("EEXIST", func(t *testing.T) {
+		errno := testFS.Clone("src", "existing")
+		if errno == syscall.ENOTSUP {
+			t.Skip("reflinks aren't supported by this platform")
+		}
+		require.EqualErrno(t, syscall.EEXIST, errno)
+	})
+
+	t.Run("ENOTSUP removes dst", func(t *testing.T) {
+		if errno := testFS.Clone("src", "dst2"); errno != syscall.ENOTSUP {
+			t.Skip("reflinks are supported")
+		}
+		_, err := os.Stat(path.Join(tmpDir, "dst2"))
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestDirFS_OpenByID(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := path.Join(tmpDir, "root")
+	require.NoError(t, os.MkdirAll(path.Join(root, "dir"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(root, "dir", "file"), []byte("wazero"), 0o600))
+	require.NoError(t, os.Link(path.Join(root, "dir", "file"), path.Join(root, "link")))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "outside"), nil, 0o600))
+	testFS := NewDirFS(root)
+
+	st, errno := testFS.Stat("link")
+	require.EqualErrno(t, 0, errno)
+
+	f, errno := testFS.OpenByID(st.Dev, st.Ino, os.O_RDONLY)
+	switch errno {
+	case syscall.ENOSYS, syscall.EPERM, syscall.ENOTSUP:
+		t.Skip("open by ID isn't supported by the platform, process or filesystem")
+	}
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	t.Run("opens file", func(t *testing.T) {
+		buf := make([]byte, 10)
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "wazero", string(buf[:n]))
+
+		// The path is one of the hard links.
+		p := f.Path()
+		require.True(t, p == "dir/file" || p == "link", p)
+	})
+
+	t.Run("enforces flags", func(t *testing.T) {
+		dirSt, errno := testFS.Stat("dir")
+		require.EqualErrno(t, 0, errno)
+		_, errno = testFS.OpenByID(dirSt.Dev, dirSt.Ino, os.O_RDWR)
+		require.EqualErrno(t, syscall.EISDIR, errno)
+		_, errno = testFS.OpenByID(st.Dev, st.Ino, os.O_RDONLY|platform.O_DIRECTORY)
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+	})
+
+	t.Run("EINVAL", func(t *testing.T) {
+		_, errno := testFS.OpenByID(st.Dev, st.Ino, os.O_RDWR|os.O_CREATE)
+		require.EqualErrno(t, syscall.EINVAL, errno)
+	})
+
+	t.Run("EXDEV", func(t *testing.T) {
+		_, errno := testFS.OpenByID(st.Dev+1, st.Ino, os.O_RDONLY)
+		require.EqualErrno(t, syscall.EXDEV, errno)
+	})
+
+	t.Run("ENOENT outside the directory", func(t *testing.T) {
+		outside, errno := platform.Stat(path.Join(tmpDir, "outside"))
+		require.EqualErrno(t, 0, errno)
+		_, errno = testFS.OpenByID(outside.Dev, outside.Ino, os.O_RDONLY)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("doesn't truncate outside the directory", func(t *testing.T) {
+		secret := path.Join(tmpDir, "secret")
+		require.NoError(t, os.WriteFile(secret, []byte("wazero"), 0o600))
+		outside, errno := platform.Stat(secret)
+		require.EqualErrno(t, 0, errno)
+		_, errno = testFS.OpenByID(outside.Dev, outside.Ino, os.O_WRONLY|os.O_TRUNC)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		b, err := os.ReadFile(secret)
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+}
+
 func TestDirFS_Rmdir(t *testing.T) {
 	t.Run("doesn't exist", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -678,6 +877,14 @@ func TestDirFS_Utimesns(t *testing.T) {
 	}
 }
 
+func TestDirFS_OpenFile_dirOrFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), nil, 0o600))
+
+	testOpen_dirOrFile(t, NewDirFS(tmpDir))
+}
+
 func TestDirFS_OpenFile(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -829,6 +1036,119 @@ func Test_fdReaddir_opened_file_written(t *testing.T) {
 	require.Equal(t, "my-file", dirents[0].Name)
 }
 
+// TestDirFS_Readdir_afterCreate ensures entries changed via the FS are
+// visible to an already-opened directory, which needs invalidation on Windows.
+func TestDirFS_Readdir_afterCreate(t *testing.T) {
+	testReaddirAfterCreate(t, NewDirFS(t.TempDir()), runtime.GOOS == "windows")
+}
+
+// testReaddirAfterCreate creates entries and lists them via a directory
+// opened before. When `reread`, this continues reading the same handle after
+// more changes, which only sees entries not yet returned.
+func testReaddirAfterCreate(t *testing.T, testFS FS, reread bool) {
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	dir, errno := testFS.OpenFile("dir", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer dir.Close()
+
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "dir/a", nil, 0o600))
+	require.EqualErrno(t, 0, testFS.Mkdir("dir/b", 0o700))
+	dirents, errno := dir.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"a", "b"}, direntNames(dirents))
+	if !reread {
+		return
+	}
+
+	f, errno := testFS.OpenFile("dir/c", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, 0, testFS.Unlink("dir/a"))
+	dirents, errno = dir.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"c"}, direntNames(dirents))
+
+	// Rewinding lists all entries again.
+	require.EqualErrno(t, 0, dir.RewindDir())
+	dirents, errno = dir.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"b", "c"}, direntNames(dirents))
+}
+
+// direntNames returns the sorted names of `dirents`.
+func direntNames(dirents []platform.Dirent) (names []string) {
+	for _, e := range dirents {
+		names = append(names, e.Name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func TestDirFS_WithReaddirBatchSize(t *testing.T) {
+	for _, size := range []int{0, platform.MinReaddirBufSize, 64 * 1024} {
+		size := size
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			testFS := NewDirFS(t.TempDir(), WithReaddirBatchSize(size))
+			var expected []string
+			for i := 0; i < 100; i++ {
+				name := fmt.Sprintf("file-%03d", i)
+				require.EqualErrno(t, 0, WriteFileAtomic(testFS, name, nil, 0o600))
+				expected = append(expected, name)
+			}
+
+			dir, errno := testFS.OpenFile(".", os.O_RDONLY|platform.O_DIRECTORY, 0)
+			require.EqualErrno(t, 0, errno)
+			defer dir.Close()
+
+			// Pages only return the count requested, regardless of the size.
+			var dirents []platform.Dirent
+			for {
+				page, errno := dir.Readdir(7)
+				require.EqualErrno(t, 0, errno)
+				require.True(t, len(page) <= 7)
+				if len(page) == 0 {
+					break
+				}
+				dirents = append(dirents, page...)
+			}
+			require.Equal(t, expected, direntNames(dirents))
+		})
+	}
+}
+
+// BenchmarkDirFS_Readdir reads a large directory in pages, like WASI
+// `fd_readdir`, comparing sizes of WithReaddirBatchSize.
+func BenchmarkDirFS_Readdir(b *testing.B) {
+	tmpDir := b.TempDir()
+	for i := 0; i < 100000; i++ {
+		if err := os.WriteFile(path.Join(tmpDir, fmt.Sprint(i)), nil, 0o600); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, size := range []int{0, 64 * 1024, 1024 * 1024} {
+		testFS := NewDirFS(tmpDir, WithReaddirBatchSize(size))
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				dir, errno := testFS.OpenFile(".", os.O_RDONLY|platform.O_DIRECTORY, 0)
+				if errno != 0 {
+					b.Fatal(errno)
+				}
+				for {
+					page, errno := dir.Readdir(64)
+					if errno != 0 {
+						b.Fatal(errno)
+					} else if len(page) == 0 {
+						break
+					}
+				}
+				dir.Close()
+			}
+		})
+	}
+}
+
 func TestDirFS_Link(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,101 @@
+package sysfs
+
+import (
+	"context"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// diskUsageBatch is the count of directory entries read at a time by
+// DiskUsage, which bounds memory for large directories.
+const diskUsageBatch = 128
+
+// DiskUsage returns the total size in bytes and count of files under `path`,
+// like `du -s --apparent-size`, without a round-trip through the guest for
+// each file.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.ENOENT: `path` doesn't exist.
+//   - syscall.ECANCELED: the context was done before the walk completed.
+//
+// # Notes
+//
+//   - Directories are read with File.Readdir in batches, so that large
+//     directories don't need to fit in memory.
+//   - Symbolic links are not followed, but counted by the size of their
+//     target path, so there are no cycles.
+//   - A file with multiple hard links is counted once, by Stat_t.Dev and
+//     Stat_t.Ino, if the FS reports them.
+//   - Directories themselves are not counted in either result.
+func DiskUsage(ctx context.Context, fs FS, path string) (bytes int64, files int64, errno syscall.Errno) {
+	st, errno := fs.Lstat(path)
+	if errno != 0 {
+		return 0, 0, errno
+	}
+	w := &diskUsageWalker{ctx: ctx, fs: fs, seen: map[[2]uint64]struct{}{}}
+	if errno = w.visit(path, st); errno != 0 {
+		return 0, 0, errno
+	}
+	return w.bytes, w.files, 0
+}
+
+type diskUsageWalker struct {
+	ctx          context.Context
+	fs           FS
+	bytes, files int64
+
+	// seen holds the device and inode of files already counted.
+	seen map[[2]uint64]struct{}
+}
+
+// visit accumulates the file at `name`, recursing if it is a directory.
+func (w *diskUsageWalker) visit(name string, st platform.Stat_t) syscall.Errno {
+	if st.Ino != 0 {
+		key := [2]uint64{st.Dev, st.Ino}
+		if _, ok := w.seen[key]; ok {
+			return 0
+		}
+		w.seen[key] = struct{}{}
+	}
+
+	if !st.Mode.IsDir() {
+		w.bytes += st.Size
+		w.files++
+		return 0
+	}
+
+	dir, errno := w.fs.OpenFile(name, os.O_RDONLY|platform.O_DIRECTORY|platform.O_NOFOLLOW, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer dir.Close()
+
+	for {
+		if w.ctx.Err() != nil {
+			return syscall.ECANCELED
+		}
+		dirents, errno := dir.Readdir(diskUsageBatch)
+		if errno != 0 {
+			return errno
+		} else if len(dirents) == 0 {
+			return 0
+		}
+		for i := range dirents {
+			child := path.Join(name, dirents[i].Name)
+			st, errno := w.fs.Lstat(child)
+			if errno == syscall.ENOENT {
+				continue // removed since Readdir
+			} else if errno != 0 {
+				return errno
+			}
+			if errno = w.visit(child, st); errno != 0 {
+				return errno
+			}
+		}
+	}
+}
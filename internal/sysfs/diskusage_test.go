@@ -0,0 +1,65 @@
+package sysfs
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestDiskUsage(t *testing.T) {
+	testFS := NewMemFS()
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	require.EqualErrno(t, 0, testFS.Mkdir("dir/sub", 0o700))
+	for name, data := range map[string]string{"a": "wazero", "dir/b": "wa", "dir/sub/c": "zero"} {
+		f, errno := testFS.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte(data))
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+	}
+
+	// A hard link is counted once, and a symbolic link isn't followed.
+	require.EqualErrno(t, 0, testFS.Link("a", "dir/hard"))
+	require.EqualErrno(t, 0, testFS.Symlink("..", "dir/sub/up"))
+
+	bytes, files, errno := DiskUsage(context.Background(), testFS, "/")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6+2+4+2), bytes)
+	require.Equal(t, int64(4), files)
+
+	bytes, files, errno = DiskUsage(context.Background(), testFS, "dir/sub")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(4+2), bytes)
+	require.Equal(t, int64(2), files)
+
+	bytes, files, errno = DiskUsage(context.Background(), testFS, "a")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), bytes)
+	require.Equal(t, int64(1), files)
+
+	_, _, errno = DiskUsage(context.Background(), testFS, "missing")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, errno = DiskUsage(ctx, testFS, "dir")
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+}
+
+func TestDiskUsage_dirFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	testFS := NewDirFS(tmpDir)
+
+	st, errno := testFS.Stat("sub/test.txt")
+	require.EqualErrno(t, 0, errno)
+
+	bytes, files, errno := DiskUsage(context.Background(), testFS, "sub")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st.Size, bytes)
+	require.Equal(t, int64(1), files)
+}
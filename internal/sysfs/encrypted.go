@@ -0,0 +1,676 @@
+package sysfs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+const (
+	// encryptedMagic starts each regular file written by encryptedFS.
+	encryptedMagic = "wazeroE2"
+
+	// encryptedIDSize is the size of the random ID of each file, which is
+	// authenticated with its size and chunks, so that they can't be swapped
+	// with those of another file.
+	encryptedIDSize = 16
+
+	// encryptedChunkSize is the size of plaintext sealed together. Reads and
+	// writes only decrypt and encrypt the chunks they overlap.
+	encryptedChunkSize = 4096
+
+	// encryptedMaxFileSize is the largest plaintext size. Growing a file
+	// writes each zero chunk, so this bounds the time and space of a Pwrite
+	// at a huge offset.
+	encryptedMaxFileSize = 1 << 36
+
+	// encryptedHeaderIndex is the chunk index in the additional data of the
+	// sealed size, which no chunk has as encryptedMaxFileSize bounds them.
+	encryptedHeaderIndex = math.MaxUint64
+)
+
+// encryptedHeader starts each regular file written by encryptedFS.
+type encryptedHeader struct {
+	// id is random per file, and zero until the file is first written.
+	id [encryptedIDSize]byte
+
+	// size is the plaintext size.
+	size int64
+}
+
+// NewEncryptedFS returns an FS which delegates to `fs`, except the contents
+// of regular files are encrypted with AES-GCM using `key`, which must be 16,
+// 24 or 32 bytes.
+//
+// Contents are sealed in chunks of 4KiB, each with a random nonce, so that
+// File.Pread and File.Pwrite at any offset only decrypt and encrypt the
+// chunks they overlap. A header records the plaintext size, which is the
+// Stat_t.Size seen by the guest. The size and each chunk are authenticated
+// with a random ID of the file, so truncating the host file, or swapping
+// chunks within or between files, fails reads with syscall.EIO.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.EINVAL: `key` isn't a valid AES key.
+//
+// Once created, opening or reading a regular file which wasn't written by an
+// FS with the same key, or was modified on the host, returns syscall.EIO.
+// Growing a file past 64GiB returns syscall.EFBIG, as growing writes each
+// zero chunk.
+//
+// # Notes
+//
+//   - Paths, directories, symbolic links and attributes such as modification
+//     times are not encrypted.
+//   - An empty file has no header, so files created outside this FS can be
+//     written once empty.
+//   - Concurrent writes to the same file via separate opens can overwrite
+//     each other's chunks, as each chunk is read, modified and written.
+//   - File.PunchHole returns syscall.ENOSYS, as offsets in the host file
+//     differ from those of the guest.
+func NewEncryptedFS(fs FS, key []byte) (FS, syscall.Errno) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, syscall.EINVAL
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, syscall.EINVAL
+	}
+	return &encryptedFS{fs: fs, aead: aead}, 0
+}
+
+type encryptedFS struct {
+	UnimplementedFS
+	fs   FS
+	aead cipher.AEAD
+}
+
+// sealedChunkSize is the size in the host file of a sealed chunk, which is
+// its nonce, the encrypted chunk and its authentication tag.
+func (e *encryptedFS) sealedChunkSize() int {
+	return e.aead.NonceSize() + encryptedChunkSize + e.aead.Overhead()
+}
+
+// headerSize is the size in the host file of the header, which is
+// encryptedMagic, the file ID, then the nonce, sealed little-endian uint64
+// plaintext size and its authentication tag.
+func (e *encryptedFS) headerSize() int {
+	return len(encryptedMagic) + encryptedIDSize + e.aead.NonceSize() + 8 + e.aead.Overhead()
+}
+
+// chunkOffset returns the offset in the host file of chunk `i`.
+func (e *encryptedFS) chunkOffset(i int64) int64 {
+	return int64(e.headerSize()) + i*int64(e.sealedChunkSize())
+}
+
+// chunkCount returns the count of chunks of a file with the plaintext size.
+func chunkCount(size int64) int64 {
+	return (size + encryptedChunkSize - 1) / encryptedChunkSize
+}
+
+// encryptedAAD returns the additional data authenticated with chunk `i` of
+// the file `id`, so that chunks can't be reordered or moved between files.
+func encryptedAAD(id *[encryptedIDSize]byte, i uint64) []byte {
+	var aad [encryptedIDSize + 8]byte
+	copy(aad[:], id[:])
+	binary.LittleEndian.PutUint64(aad[encryptedIDSize:], i)
+	return aad[:]
+}
+
+// readHeader returns the header of `f`, which is zero if `f` is empty.
+func (e *encryptedFS) readHeader(f platform.File) (h encryptedHeader, errno syscall.Errno) {
+	header := make([]byte, e.headerSize())
+	n, errno := f.Pread(header, 0)
+	if errno != 0 {
+		return h, errno
+	} else if n == 0 {
+		return h, 0 // empty, so not yet written.
+	} else if n != len(header) || string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return h, syscall.EIO
+	}
+	header = header[len(encryptedMagic):]
+	copy(h.id[:], header)
+	sealed := header[encryptedIDSize:]
+	nonceSize := e.aead.NonceSize()
+	var plain [8]byte
+	if _, err := e.aead.Open(plain[:0], sealed[:nonceSize], sealed[nonceSize:], encryptedAAD(&h.id, encryptedHeaderIndex)); err != nil {
+		return h, syscall.EIO
+	}
+	if h.size = int64(binary.LittleEndian.Uint64(plain[:])); h.size < 0 || h.size > encryptedMaxFileSize {
+		return h, syscall.EIO
+	}
+	return h, 0
+}
+
+// readPlainSize returns the plaintext size in the header of `f`.
+func (e *encryptedFS) readPlainSize(f platform.File) (int64, syscall.Errno) {
+	h, errno := e.readHeader(f)
+	return h.size, errno
+}
+
+// String implements fmt.Stringer
+func (e *encryptedFS) String() string {
+	return e.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (e *encryptedFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	return e.open(flag, func(flag int) (platform.File, syscall.Errno) {
+		return e.fs.OpenFile(path, flag, perm)
+	})
+}
+
+// OpenByID implements FS.OpenByID
+func (e *encryptedFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	return e.open(flag, func(flag int) (platform.File, syscall.Errno) {
+		return e.fs.OpenByID(dev, ino, flag)
+	})
+}
+
+// open opens a file with `open`, which is called again with `flag` if the
+// file isn't regular.
+func (e *encryptedFS) open(flag int, open func(flag int) (platform.File, syscall.Errno)) (platform.File, syscall.Errno) {
+	// The file is read to modify chunks, even if the guest can't read it.
+	// Appending and truncation are handled by encryptedFile, as they are
+	// relative to the plaintext.
+	accessMode := flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
+	hostFlag := flag &^ (os.O_APPEND | os.O_TRUNC)
+	if accessMode == os.O_WRONLY {
+		hostFlag = hostFlag&^os.O_WRONLY | os.O_RDWR
+	}
+	f, errno := open(hostFlag)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	// Only regular files are encrypted.
+	st, errno := f.Stat()
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	} else if !st.Mode.IsRegular() {
+		_ = f.Close()
+		return open(flag)
+	}
+
+	// Fail early if the file isn't encrypted.
+	if _, errno = e.readHeader(f); errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+
+	ef := &encryptedFile{
+		File:       f,
+		fs:         e,
+		accessMode: accessMode,
+		append:     flag&os.O_APPEND != 0,
+		offset:     new(int64),
+	}
+	if flag&os.O_TRUNC != 0 && accessMode != os.O_RDONLY {
+		if errno = ef.Truncate(0); errno != 0 {
+			_ = f.Close()
+			return nil, errno
+		}
+	}
+	return ef, 0
+}
+
+// Lstat implements FS.Lstat
+func (e *encryptedFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := e.fs.Lstat(path)
+	if errno != 0 {
+		return st, errno
+	}
+	return e.plainStat(path, st)
+}
+
+// Stat implements FS.Stat
+func (e *encryptedFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := e.fs.Stat(path)
+	if errno != 0 {
+		return st, errno
+	}
+	return e.plainStat(path, st)
+}
+
+// plainStat sets the size of a regular file to its plaintext size.
+func (e *encryptedFS) plainStat(path string, st platform.Stat_t) (platform.Stat_t, syscall.Errno) {
+	if !st.Mode.IsRegular() || st.Size == 0 {
+		return st, 0
+	}
+	f, errno := e.fs.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return st, errno
+	}
+	defer f.Close()
+	st.Size, errno = e.readPlainSize(f)
+	return st, errno
+}
+
+// Mkdir implements FS.Mkdir
+func (e *encryptedFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return e.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (e *encryptedFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return e.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (e *encryptedFS) Chown(path string, uid, gid int) syscall.Errno {
+	return e.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (e *encryptedFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return e.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (e *encryptedFS) Rename(from, to string) syscall.Errno {
+	return e.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (e *encryptedFS) ExchangeDir(a, b string) syscall.Errno {
+	return e.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (e *encryptedFS) Clone(src, dst string) syscall.Errno {
+	return e.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (e *encryptedFS) Rmdir(path string) syscall.Errno {
+	return e.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (e *encryptedFS) Unlink(path string) syscall.Errno {
+	return e.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (e *encryptedFS) Link(oldPath, newPath string) syscall.Errno {
+	return e.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (e *encryptedFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return e.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (e *encryptedFS) Readlink(path string) (string, syscall.Errno) {
+	return e.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (e *encryptedFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return e.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (e *encryptedFS) Truncate(path string, size int64) syscall.Errno {
+	// The size is of the plaintext, so truncate via the file.
+	f, errno := e.OpenFile(path, os.O_RDWR, 0)
+	if errno != 0 {
+		return errno
+	}
+	if errno = f.Truncate(size); errno != 0 {
+		_ = f.Close()
+		return errno
+	}
+	return f.Close()
+}
+
+// Utimens implements FS.Utimens
+func (e *encryptedFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return e.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (e *encryptedFS) SyncDir(path string) syscall.Errno {
+	return e.fs.SyncDir(path)
+}
+
+// encryptedFile encrypts and decrypts the chunks of a regular file opened by
+// encryptedFS.
+type encryptedFile struct {
+	platform.File
+	fs         *encryptedFS
+	accessMode int
+	append     bool
+
+	// offset is the position of Read, Write and Seek in the plaintext. This
+	// is shared with duplicates.
+	offset *int64
+}
+
+// readChunk decrypts chunk `i` of the file with header `h` into `plain`,
+// which is encryptedChunkSize.
+func (f *encryptedFile) readChunk(h *encryptedHeader, i int64, plain []byte) syscall.Errno {
+	sealed := make([]byte, f.fs.sealedChunkSize())
+	if n, errno := f.File.Pread(sealed, f.fs.chunkOffset(i)); errno != 0 {
+		return errno
+	} else if n != len(sealed) {
+		return syscall.EIO
+	}
+	nonceSize := f.fs.aead.NonceSize()
+	if _, err := f.fs.aead.Open(plain[:0], sealed[:nonceSize], sealed[nonceSize:], encryptedAAD(&h.id, uint64(i))); err != nil {
+		return syscall.EIO
+	}
+	return 0
+}
+
+// writeChunk encrypts `plain`, which is encryptedChunkSize, as chunk `i` of
+// the file with header `h`.
+func (f *encryptedFile) writeChunk(h *encryptedHeader, i int64, plain []byte) syscall.Errno {
+	nonceSize := f.fs.aead.NonceSize()
+	sealed := make([]byte, nonceSize, f.fs.sealedChunkSize())
+	if _, err := io.ReadFull(rand.Reader, sealed); err != nil {
+		return syscall.EIO
+	}
+	sealed = f.fs.aead.Seal(sealed, sealed[:nonceSize], plain, encryptedAAD(&h.id, uint64(i)))
+	return f.pwriteAll(sealed, f.fs.chunkOffset(i))
+}
+
+// writeHeader writes the header `h`, sealing its plaintext size.
+func (f *encryptedFile) writeHeader(h *encryptedHeader) syscall.Errno {
+	nonceSize := f.fs.aead.NonceSize()
+	header := make([]byte, len(encryptedMagic)+encryptedIDSize+nonceSize, f.fs.headerSize())
+	copy(header, encryptedMagic)
+	copy(header[len(encryptedMagic):], h.id[:])
+	nonce := header[len(encryptedMagic)+encryptedIDSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return syscall.EIO
+	}
+	var plain [8]byte
+	binary.LittleEndian.PutUint64(plain[:], uint64(h.size))
+	header = f.fs.aead.Seal(header, nonce, plain[:], encryptedAAD(&h.id, encryptedHeaderIndex))
+	return f.pwriteAll(header, 0)
+}
+
+// readWritableHeader returns the header of the file, with a new random ID if
+// the file is empty, so that chunks can be written.
+func (f *encryptedFile) readWritableHeader() (h encryptedHeader, errno syscall.Errno) {
+	if h, errno = f.fs.readHeader(f.File); errno != 0 {
+		return
+	} else if h.id == ([encryptedIDSize]byte{}) {
+		if _, err := io.ReadFull(rand.Reader, h.id[:]); err != nil {
+			return h, syscall.EIO
+		}
+	}
+	return
+}
+
+// pwriteAll writes all of `data` to the host file at `off`, retrying on
+// short writes.
+func (f *encryptedFile) pwriteAll(data []byte, off int64) syscall.Errno {
+	for len(data) > 0 {
+		n, errno := f.File.Pwrite(data, off)
+		if errno != 0 {
+			return errno
+		} else if n == 0 {
+			return syscall.EIO
+		}
+		data, off = data[n:], off+int64(n)
+	}
+	return 0
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *encryptedFile) AccessMode() int {
+	return f.accessMode
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *encryptedFile) Stat() (platform.Stat_t, syscall.Errno) {
+	st, errno := f.File.Stat()
+	if errno != 0 {
+		return st, errno
+	}
+	st.Size, errno = f.fs.readPlainSize(f.File)
+	return st, errno
+}
+
+// Read implements the same method as documented on platform.File
+func (f *encryptedFile) Read(buf []byte) (int, syscall.Errno) {
+	n, errno := f.Pread(buf, *f.offset)
+	*f.offset += int64(n)
+	return n, errno
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *encryptedFile) Pread(buf []byte, off int64) (n int, errno syscall.Errno) {
+	if f.accessMode == os.O_WRONLY {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	}
+	h, errno := f.fs.readHeader(f.File)
+	if errno != 0 {
+		return 0, errno
+	} else if off >= h.size {
+		return 0, 0
+	} else if remaining := h.size - off; int64(len(buf)) > remaining {
+		buf = buf[:remaining]
+	}
+
+	plain := make([]byte, encryptedChunkSize)
+	for n < len(buf) {
+		pos := off + int64(n)
+		if errno = f.readChunk(&h, pos/encryptedChunkSize, plain); errno != 0 {
+			return
+		}
+		n += copy(buf[n:], plain[pos%encryptedChunkSize:])
+	}
+	return
+}
+
+// BytesAvailable implements the same method as documented on platform.File
+func (f *encryptedFile) BytesAvailable() (int64, syscall.Errno) {
+	if f.accessMode == os.O_WRONLY {
+		return 0, syscall.EBADF
+	}
+	size, errno := f.fs.readPlainSize(f.File)
+	if errno != 0 {
+		return 0, errno
+	} else if size > *f.offset {
+		return size - *f.offset, 0
+	}
+	return 0, 0
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *encryptedFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += *f.offset
+	case io.SeekEnd:
+		size, errno := f.fs.readPlainSize(f.File)
+		if errno != 0 {
+			return 0, errno
+		}
+		offset += size
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	*f.offset = offset
+	return offset, 0
+}
+
+// Write implements the same method as documented on platform.File
+func (f *encryptedFile) Write(buf []byte) (int, syscall.Errno) {
+	if f.append {
+		size, errno := f.fs.readPlainSize(f.File)
+		if errno != 0 {
+			return 0, errno
+		}
+		*f.offset = size
+	}
+	n, errno := f.Pwrite(buf, *f.offset)
+	*f.offset += int64(n)
+	return n, errno
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *encryptedFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	for _, buf := range bufs {
+		var written int
+		written, errno = f.Write(buf)
+		n += written
+		if errno != 0 {
+			return
+		}
+	}
+	return
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *encryptedFile) Pwrite(buf []byte, off int64) (n int, errno syscall.Errno) {
+	if f.accessMode == os.O_RDONLY {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(buf) == 0 {
+		return 0, 0
+	} else if off > encryptedMaxFileSize-int64(len(buf)) {
+		return 0, syscall.EFBIG
+	}
+	h, errno := f.readWritableHeader()
+	if errno != 0 {
+		return 0, errno
+	} else if off > h.size {
+		// Write zero chunks up to the offset, so that the gap reads zeros.
+		if errno = f.resize(&h, off); errno != 0 {
+			return 0, errno
+		}
+	}
+	size := h.size
+
+	plain := make([]byte, encryptedChunkSize)
+	newSize := size
+	for n < len(buf) {
+		pos := off + int64(n)
+		i := pos / encryptedChunkSize
+		if i < chunkCount(size) {
+			if errno = f.readChunk(&h, i, plain); errno != 0 {
+				break
+			}
+		} else {
+			for j := range plain {
+				plain[j] = 0
+			}
+		}
+		written := copy(plain[pos%encryptedChunkSize:], buf[n:])
+		if errno = f.writeChunk(&h, i, plain); errno != 0 {
+			break
+		}
+		n += written
+		if end := pos + int64(written); end > newSize {
+			newSize = end
+		}
+	}
+
+	// Update the size last, so that it never includes unwritten chunks.
+	if newSize != size {
+		h.size = newSize
+		if sizeErrno := f.writeHeader(&h); errno == 0 {
+			errno = sizeErrno
+		}
+	}
+	return
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *encryptedFile) Truncate(size int64) syscall.Errno {
+	if f.accessMode == os.O_RDONLY {
+		return syscall.EBADF
+	} else if size < 0 {
+		return syscall.EINVAL
+	} else if size > encryptedMaxFileSize {
+		return syscall.EFBIG
+	}
+	h, errno := f.readWritableHeader()
+	if errno != 0 {
+		return errno
+	}
+	return f.resize(&h, size)
+}
+
+// resize changes the plaintext size in the header `h` to `newSize`.
+//
+// Plaintext after the size in the last chunk is always zero, so growing only
+// needs to write zero chunks, while shrinking needs to zero the end of the
+// new last chunk.
+func (f *encryptedFile) resize(h *encryptedHeader, newSize int64) syscall.Errno {
+	size := h.size
+	count, newCount := chunkCount(size), chunkCount(newSize)
+	plain := make([]byte, encryptedChunkSize)
+	if newSize < size {
+		if rem := newSize % encryptedChunkSize; rem != 0 {
+			if errno := f.readChunk(h, newCount-1, plain); errno != 0 {
+				return errno
+			}
+			for j := rem; j < encryptedChunkSize; j++ {
+				plain[j] = 0
+			}
+			if errno := f.writeChunk(h, newCount-1, plain); errno != 0 {
+				return errno
+			}
+		}
+		h.size = newSize
+		if errno := f.writeHeader(h); errno != 0 {
+			return errno
+		}
+		return f.File.Truncate(f.fs.chunkOffset(newCount))
+	}
+
+	for i := count; i < newCount; i++ {
+		if errno := f.writeChunk(h, i, plain); errno != 0 {
+			return errno
+		}
+	}
+	h.size = newSize
+	return f.writeHeader(h)
+}
+
+// PunchHole implements the same method as documented on platform.File
+//
+// This returns syscall.ENOSYS, as offsets in the host file differ from those
+// of the guest.
+func (f *encryptedFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *encryptedFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &encryptedFile{
+		File:       dup,
+		fs:         f.fs,
+		accessMode: f.accessMode,
+		append:     f.append,
+		offset:     f.offset,
+	}, 0
+}
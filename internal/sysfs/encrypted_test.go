@@ -0,0 +1,253 @@
+package sysfs
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+var testEncryptionKey = bytes.Repeat([]byte{1}, 32)
+
+func TestNewEncryptedFS(t *testing.T) {
+	_, errno := NewEncryptedFS(NewMemFS(), []byte("short"))
+	require.EqualErrno(t, syscall.EINVAL, errno)
+
+	hostFS := NewMemFS()
+	testFS, errno := NewEncryptedFS(hostFS, testEncryptionKey)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "mem:/", testFS.String())
+
+	// Directories are plaintext.
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	f, errno := testFS.OpenFile("dir/file", os.O_WRONLY|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.Equal(t, []string{"file"}, readdirNames(t, hostFS, "dir"))
+
+	// The size is of the plaintext, but the host file is encrypted.
+	st, errno := testFS.Stat("dir/file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+	host, errno := hostFS.OpenFile("dir/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	hostContent := readAll(t, host)
+	require.EqualErrno(t, 0, host.Close())
+	require.False(t, bytes.Contains(hostContent[len(encryptedMagic):], []byte("wazero")))
+
+	f, errno = testFS.OpenFile("dir/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("wazero"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	// Another key can't read the file, as the header is authenticated.
+	otherFS, errno := NewEncryptedFS(hostFS, bytes.Repeat([]byte{2}, 32))
+	require.EqualErrno(t, 0, errno)
+	_, errno = otherFS.OpenFile("dir/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EIO, errno)
+
+	// Neither can a file written on the host.
+	require.EqualErrno(t, 0, WriteFileAtomic(hostFS, "plain", []byte("plaintext"), 0o600))
+	_, errno = testFS.OpenFile("plain", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EIO, errno)
+}
+
+func TestEncryptedFile_randomAccess(t *testing.T) {
+	testFS, errno := NewEncryptedFS(NewMemFS(), testEncryptionKey)
+	require.EqualErrno(t, 0, errno)
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	// expected is the plaintext, to compare against.
+	var expected []byte
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		off := r.Int63n(4 * encryptedChunkSize)
+		buf := make([]byte, r.Intn(2*encryptedChunkSize)+1)
+		r.Read(buf)
+
+		n, errno := f.Pwrite(buf, off)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, len(buf), n)
+		if end := off + int64(len(buf)); end > int64(len(expected)) {
+			expected = append(expected, make([]byte, end-int64(len(expected)))...)
+		}
+		copy(expected[off:], buf)
+
+		// Read a random range, which may cross chunks.
+		off = r.Int63n(int64(len(expected)))
+		buf = make([]byte, r.Intn(2*encryptedChunkSize)+1)
+		n, errno = f.Pread(buf, off)
+		require.EqualErrno(t, 0, errno)
+		end := off + int64(len(buf))
+		if end > int64(len(expected)) {
+			end = int64(len(expected))
+		}
+		require.Equal(t, expected[off:end], buf[:n])
+	}
+
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(len(expected)), st.Size)
+	require.Equal(t, expected, readAll(t, f))
+}
+
+func TestEncryptedFile_Truncate(t *testing.T) {
+	testFS, errno := NewEncryptedFS(NewMemFS(), testEncryptionKey)
+	require.EqualErrno(t, 0, errno)
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	data := bytes.Repeat([]byte{'a'}, encryptedChunkSize+10)
+	_, errno = f.Write(data)
+	require.EqualErrno(t, 0, errno)
+
+	// Shrinking then growing reads zeros after the truncated size.
+	require.EqualErrno(t, 0, f.Truncate(5))
+	require.EqualErrno(t, 0, f.Truncate(2*encryptedChunkSize))
+	buf := make([]byte, 2*encryptedChunkSize)
+	n, errno := f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, len(buf), n)
+	require.Equal(t, append(data[:5:5], make([]byte, len(buf)-5)...), buf)
+
+	// Writing past the end reads zeros in the gap.
+	_, errno = f.Pwrite([]byte("wazero"), 3*encryptedChunkSize+1)
+	require.EqualErrno(t, 0, errno)
+	n, errno = f.Pread(buf[:10], 3*encryptedChunkSize-3)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "\x00\x00\x00\x00wazero", string(buf[:n]))
+
+	require.EqualErrno(t, 0, testFS.Truncate("file", 1))
+	st, errno := testFS.Stat("file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(1), st.Size)
+
+	require.EqualErrno(t, syscall.ENOSYS, f.PunchHole(0, 1))
+}
+
+func TestEncryptedFile_flags(t *testing.T) {
+	testFS, errno := NewEncryptedFS(NewMemFS(), testEncryptionKey)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", []byte("wa"), 0o600))
+
+	// Appending is relative to the plaintext.
+	f, errno := testFS.OpenFile("file", os.O_WRONLY|os.O_APPEND, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, os.O_WRONLY, f.AccessMode())
+	_, errno = f.Seek(0, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("zero"))
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Read(make([]byte, 1))
+	require.EqualErrno(t, syscall.EBADF, errno)
+
+	// A duplicate shares the offset.
+	dup, errno := f.Dup()
+	require.EqualErrno(t, 0, errno)
+	off, errno := dup.Seek(0, io.SeekCurrent)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), off)
+	require.EqualErrno(t, 0, dup.Close())
+	require.EqualErrno(t, 0, f.Close())
+
+	f, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("wazero"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	f, errno = testFS.OpenFile("file", os.O_RDWR|os.O_TRUNC, 0)
+	require.EqualErrno(t, 0, errno)
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, st.Size)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Directories pass through.
+	f, errno = testFS.OpenFile(".", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	_, ok := f.(*encryptedFile)
+	require.False(t, ok)
+	require.EqualErrno(t, 0, f.Close())
+}
+
+func TestEncryptedFile_tampered(t *testing.T) {
+	hostFS := NewMemFS()
+	testFS, errno := NewEncryptedFS(hostFS, testEncryptionKey)
+	require.EqualErrno(t, 0, errno)
+	data := bytes.Repeat([]byte{'a'}, 2*encryptedChunkSize)
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "a", data, 0o600))
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "b", data, 0o600))
+
+	e := testFS.(*encryptedFS)
+	requireReadEIO := func(path string) {
+		f, errno := testFS.OpenFile(path, os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+		_, errno = f.Pread(make([]byte, len(data)), 0)
+		require.EqualErrno(t, syscall.EIO, errno)
+	}
+
+	// Truncating the host file fails reads, as the size is authenticated.
+	require.EqualErrno(t, 0, hostFS.Truncate("a", e.chunkOffset(1)))
+	requireReadEIO("a")
+
+	// So does changing the size in the header.
+	host, errno := hostFS.OpenFile("b", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	header := make([]byte, e.headerSize())
+	_, errno = host.Pread(header, 0)
+	require.EqualErrno(t, 0, errno)
+	header[len(header)-1] ^= 1
+	_, errno = host.Pwrite(header, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, host.Close())
+	_, errno = testFS.OpenFile("b", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EIO, errno)
+
+	// Chunks can't be swapped within a file, or between files.
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "a", data, 0o600))
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "b", data, 0o600))
+	a, errno := hostFS.OpenFile("a", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	defer a.Close()
+	b, errno := hostFS.OpenFile("b", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	defer b.Close()
+	chunk := make([]byte, e.sealedChunkSize())
+	_, errno = a.Pread(chunk, e.chunkOffset(0))
+	require.EqualErrno(t, 0, errno)
+	_, errno = a.Pwrite(chunk, e.chunkOffset(1))
+	require.EqualErrno(t, 0, errno)
+	_, errno = b.Pwrite(chunk, e.chunkOffset(0))
+	require.EqualErrno(t, 0, errno)
+	requireReadEIO("a")
+	requireReadEIO("b")
+}
+
+func TestEncryptedFile_hugeFile(t *testing.T) {
+	testFS, errno := NewEncryptedFS(NewMemFS(), testEncryptionKey)
+	require.EqualErrno(t, 0, errno)
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	require.EqualErrno(t, syscall.EFBIG, f.Truncate(1<<62))
+	_, errno = f.Pwrite([]byte{1}, 1<<62)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+	_, errno = f.Pwrite([]byte{1}, encryptedMaxFileSize)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, st.Size)
+}
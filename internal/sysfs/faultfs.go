@@ -0,0 +1,418 @@
+package sysfs
+
+import (
+	"io/fs"
+	"math/rand"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// FaultPolicy configures which operations of NewFaultFS fail.
+type FaultPolicy struct {
+	// Rules are checked in order, and the first that matches an operation
+	// injects its error instead of calling the underlying filesystem.
+	Rules []FaultRule
+
+	// Seed initializes the random source of FaultRule.Probability, so that
+	// the same sequence of operations fails the same way in each run.
+	Seed int64
+}
+
+// FaultRule injects an error on an operation.
+type FaultRule struct {
+	// Op is the name of the method to fail, such as "OpenFile" for
+	// FS.OpenFile or "File.Write" for platform.File Write. Empty matches all
+	// operations.
+	Op string
+
+	// Errno is the error to inject, such as syscall.EIO or syscall.ENOSPC.
+	Errno syscall.Errno
+
+	// After is the count of matching calls which succeed before any fail.
+	After int
+
+	// Probability is the chance, from zero to one, that each matching call
+	// after After fails. Zero always fails.
+	Probability float64
+
+	// Times is the maximum count of errors to inject, or zero for no limit.
+	Times int
+}
+
+// NewFaultFS returns an FS which delegates to `fs`, except when an operation
+// matches a rule in `policy`. This is used to test how guests handle errors
+// which are otherwise hard to produce, such as syscall.EIO.
+//
+// # Notes
+//
+//   - Operations on files opened by the result are also checked.
+//   - A failed operation doesn't call `fs`, except File.Close, which closes
+//     the file before returning the error, to avoid leaking it.
+func NewFaultFS(fs FS, policy FaultPolicy) FS {
+	return &faultFS{
+		fs:    fs,
+		rules: append([]FaultRule(nil), policy.Rules...),
+		state: make([]faultState, len(policy.Rules)),
+		rand:  rand.New(rand.NewSource(policy.Seed)), //nolint:gosec
+	}
+}
+
+type faultState struct {
+	calls, faults int
+}
+
+type faultFS struct {
+	UnimplementedFS
+	fs    FS
+	rules []FaultRule
+
+	// mux guards the below fields.
+	mux   sync.Mutex
+	state []faultState // index-correlated with rules
+	rand  *rand.Rand
+}
+
+// fault returns the error to inject for the operation, or zero to call it.
+func (f *faultFS) fault(op string) syscall.Errno {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for i := range f.rules {
+		r, s := &f.rules[i], &f.state[i]
+		if r.Op != "" && r.Op != op {
+			continue
+		}
+		if s.calls++; s.calls <= r.After {
+			continue
+		} else if r.Times > 0 && s.faults >= r.Times {
+			continue
+		} else if r.Probability > 0 && f.rand.Float64() >= r.Probability {
+			continue
+		}
+		s.faults++
+		return r.Errno
+	}
+	return 0
+}
+
+// String implements fmt.Stringer
+func (f *faultFS) String() string {
+	return f.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (f *faultFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if errno := f.fault("OpenFile"); errno != 0 {
+		return nil, errno
+	}
+	file, errno := f.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &faultFile{File: file, fs: f}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (f *faultFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	if errno := f.fault("OpenByID"); errno != 0 {
+		return nil, errno
+	}
+	file, errno := f.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &faultFile{File: file, fs: f}, 0
+}
+
+// Lstat implements FS.Lstat
+func (f *faultFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := f.fault("Lstat"); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return f.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (f *faultFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := f.fault("Stat"); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return f.fs.Stat(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (f *faultFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	if errno := f.fault("Mkdir"); errno != 0 {
+		return errno
+	}
+	return f.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (f *faultFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	if errno := f.fault("Chmod"); errno != 0 {
+		return errno
+	}
+	return f.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (f *faultFS) Chown(path string, uid, gid int) syscall.Errno {
+	if errno := f.fault("Chown"); errno != 0 {
+		return errno
+	}
+	return f.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (f *faultFS) Lchown(path string, uid, gid int) syscall.Errno {
+	if errno := f.fault("Lchown"); errno != 0 {
+		return errno
+	}
+	return f.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (f *faultFS) Rename(from, to string) syscall.Errno {
+	if errno := f.fault("Rename"); errno != 0 {
+		return errno
+	}
+	return f.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (f *faultFS) ExchangeDir(a, b string) syscall.Errno {
+	if errno := f.fault("ExchangeDir"); errno != 0 {
+		return errno
+	}
+	return f.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (f *faultFS) Clone(src, dst string) syscall.Errno {
+	if errno := f.fault("Clone"); errno != 0 {
+		return errno
+	}
+	return f.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (f *faultFS) Rmdir(path string) syscall.Errno {
+	if errno := f.fault("Rmdir"); errno != 0 {
+		return errno
+	}
+	return f.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (f *faultFS) Unlink(path string) syscall.Errno {
+	if errno := f.fault("Unlink"); errno != 0 {
+		return errno
+	}
+	return f.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (f *faultFS) Link(oldPath, newPath string) syscall.Errno {
+	if errno := f.fault("Link"); errno != 0 {
+		return errno
+	}
+	return f.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (f *faultFS) Symlink(oldPath, linkName string) syscall.Errno {
+	if errno := f.fault("Symlink"); errno != 0 {
+		return errno
+	}
+	return f.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (f *faultFS) Readlink(path string) (string, syscall.Errno) {
+	if errno := f.fault("Readlink"); errno != 0 {
+		return "", errno
+	}
+	return f.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (f *faultFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if errno := f.fault("ReadlinkInto"); errno != 0 {
+		return 0, errno
+	}
+	return f.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (f *faultFS) Truncate(path string, size int64) syscall.Errno {
+	if errno := f.fault("Truncate"); errno != 0 {
+		return errno
+	}
+	return f.fs.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (f *faultFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if errno := f.fault("Utimens"); errno != 0 {
+		return errno
+	}
+	return f.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (f *faultFS) SyncDir(path string) syscall.Errno {
+	if errno := f.fault("SyncDir"); errno != 0 {
+		return errno
+	}
+	return f.fs.SyncDir(path)
+}
+
+// faultFile injects errors on a file opened by faultFS.
+type faultFile struct {
+	platform.File
+	fs *faultFS
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *faultFile) Stat() (platform.Stat_t, syscall.Errno) {
+	if errno := f.fs.fault("File.Stat"); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return f.File.Stat()
+}
+
+// Read implements the same method as documented on platform.File
+func (f *faultFile) Read(buf []byte) (int, syscall.Errno) {
+	if errno := f.fs.fault("File.Read"); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Read(buf)
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *faultFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.fs.fault("File.Pread"); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Pread(buf, off)
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *faultFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if errno := f.fs.fault("File.Seek"); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Seek(offset, whence)
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *faultFile) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	if errno := f.fs.fault("File.Readdir"); errno != 0 {
+		return nil, errno
+	}
+	return f.File.Readdir(n)
+}
+
+// Write implements the same method as documented on platform.File
+func (f *faultFile) Write(buf []byte) (int, syscall.Errno) {
+	if errno := f.fs.fault("File.Write"); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Write(buf)
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *faultFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	if errno := f.fs.fault("File.Writev"); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Writev(bufs)
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *faultFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.fs.fault("File.Pwrite"); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Pwrite(buf, off)
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *faultFile) Truncate(size int64) syscall.Errno {
+	if errno := f.fs.fault("File.Truncate"); errno != 0 {
+		return errno
+	}
+	return f.File.Truncate(size)
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *faultFile) PunchHole(offset, length int64) syscall.Errno {
+	if errno := f.fs.fault("File.PunchHole"); errno != 0 {
+		return errno
+	}
+	return f.File.PunchHole(offset, length)
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *faultFile) Sync() syscall.Errno {
+	if errno := f.fs.fault("File.Sync"); errno != 0 {
+		return errno
+	}
+	return f.File.Sync()
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *faultFile) Datasync() syscall.Errno {
+	if errno := f.fs.fault("File.Datasync"); errno != 0 {
+		return errno
+	}
+	return f.File.Datasync()
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *faultFile) Chmod(mode fs.FileMode) syscall.Errno {
+	if errno := f.fs.fault("File.Chmod"); errno != 0 {
+		return errno
+	}
+	return f.File.Chmod(mode)
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *faultFile) Chown(uid, gid int) syscall.Errno {
+	if errno := f.fs.fault("File.Chown"); errno != 0 {
+		return errno
+	}
+	return f.File.Chown(uid, gid)
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *faultFile) Utimens(times *[2]syscall.Timespec) syscall.Errno {
+	if errno := f.fs.fault("File.Utimens"); errno != 0 {
+		return errno
+	}
+	return f.File.Utimens(times)
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *faultFile) Dup() (platform.File, syscall.Errno) {
+	if errno := f.fs.fault("File.Dup"); errno != 0 {
+		return nil, errno
+	}
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &faultFile{File: dup, fs: f.fs}, 0
+}
+
+// Close implements the same method as documented on platform.File
+func (f *faultFile) Close() syscall.Errno {
+	errno := f.File.Close()
+	if fault := f.fs.fault("File.Close"); fault != 0 {
+		return fault
+	}
+	return errno
+}
@@ -0,0 +1,77 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewFaultFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	t.Run("After and Times", func(t *testing.T) {
+		testFS := NewFaultFS(NewDirFS(tmpDir), FaultPolicy{Rules: []FaultRule{
+			{Op: "Stat", Errno: syscall.EIO, After: 2, Times: 1},
+		}})
+
+		var errnos []syscall.Errno
+		for i := 0; i < 4; i++ {
+			_, errno := testFS.Stat("file")
+			errnos = append(errnos, errno)
+		}
+		require.Equal(t, []syscall.Errno{0, 0, syscall.EIO, 0}, errnos)
+
+		// Other operations aren't affected.
+		_, errno := testFS.Lstat("file")
+		require.EqualErrno(t, 0, errno)
+	})
+
+	t.Run("files", func(t *testing.T) {
+		testFS := NewFaultFS(NewDirFS(tmpDir), FaultPolicy{Rules: []FaultRule{
+			{Op: "File.Write", Errno: syscall.ENOSPC},
+			{Op: "File.Close", Errno: syscall.EINTR},
+		}})
+
+		f, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("wasm"))
+		require.EqualErrno(t, syscall.ENOSPC, errno)
+		require.EqualErrno(t, syscall.EINTR, f.Close())
+
+		// The file wasn't written.
+		b, err := os.ReadFile(path.Join(tmpDir, "file"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("Probability is deterministic", func(t *testing.T) {
+		policy := FaultPolicy{Seed: 42, Rules: []FaultRule{
+			{Errno: syscall.EIO, Probability: 0.5},
+		}}
+
+		run := func() (errnos []syscall.Errno) {
+			testFS := NewFaultFS(NewDirFS(tmpDir), policy)
+			for i := 0; i < 32; i++ {
+				_, errno := testFS.Stat("file")
+				errnos = append(errnos, errno)
+			}
+			return
+		}
+
+		first := run()
+		require.Equal(t, first, run())
+
+		// Some, but not all, calls failed.
+		var faults int
+		for _, errno := range first {
+			if errno == syscall.EIO {
+				faults++
+			}
+		}
+		require.True(t, faults > 0 && faults < len(first), "faults=%d", faults)
+	})
+}
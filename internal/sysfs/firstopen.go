@@ -0,0 +1,80 @@
+package sysfs
+
+import "sync"
+
+// FirstOpenFS is implemented by an FS returned by NewDirFS, PreopenDir or
+// NewMemFS, to reset the paths seen by WithOnFirstOpen or WithMemOnFirstOpen.
+type FirstOpenFS interface {
+	// ResetFirstOpen forgets which paths were opened, so that the callback is
+	// invoked again the first time each is opened. Call this before reusing
+	// the FS for another module.
+	ResetFirstOpen()
+}
+
+// WithOnFirstOpen makes an FS returned by NewDirFS invoke `fn` the first time
+// each distinct path is opened successfully, for example, to load assets on
+// demand or to capture the paths a module reads.
+//
+// # Notes
+//
+//   - `fn` is invoked once per path, even when opened concurrently, with the
+//     path relative to the FS, such as "dir/file" or "." for the root.
+//   - `fn` is invoked after the file is opened, and without locks held, so
+//     it may use the FS.
+//   - Paths are remembered until FirstOpenFS.ResetFirstOpen.
+func WithOnFirstOpen(fn func(path string)) DirFSOption {
+	return func(d *dirFS) {
+		d.firstOpen = &firstOpen{fn: fn, seen: map[string]struct{}{}}
+	}
+}
+
+// WithMemOnFirstOpen is like WithOnFirstOpen, except for an FS returned by
+// NewMemFS or NewLimitedMemFS.
+func WithMemOnFirstOpen(fn func(path string)) MemFSOption {
+	return func(m *memFS) {
+		m.firstOpen = &firstOpen{fn: fn, seen: map[string]struct{}{}}
+	}
+}
+
+// firstOpen records the paths opened, when a callback is set. A nil pointer
+// disables it.
+type firstOpen struct {
+	fn func(path string)
+
+	// mux guards seen.
+	mux  sync.Mutex
+	seen map[string]struct{}
+}
+
+// opened invokes the callback if `path` wasn't opened before.
+func (o *firstOpen) opened(path string) {
+	if o == nil {
+		return
+	}
+	if path = cleanPath(path); path == "" {
+		path = "."
+	}
+
+	o.mux.Lock()
+	_, ok := o.seen[path]
+	if !ok {
+		o.seen[path] = struct{}{}
+	}
+	o.mux.Unlock()
+
+	if !ok {
+		o.fn(path)
+	}
+}
+
+// reset implements FirstOpenFS.ResetFirstOpen
+func (o *firstOpen) reset() {
+	if o == nil {
+		return
+	}
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.seen = map[string]struct{}{}
+}
@@ -0,0 +1,101 @@
+package sysfs
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithOnFirstOpen(t *testing.T) {
+	tests := []struct {
+		name  string
+		newFS func(fn func(string)) FS
+		// serial is true when the FS isn't safe for concurrent use.
+		serial bool
+	}{
+		{
+			name: "DirFS",
+			newFS: func(fn func(string)) FS {
+				return NewDirFS(t.TempDir(), WithOnFirstOpen(fn))
+			},
+		},
+		{
+			name: "PreopenDir",
+			newFS: func(fn func(string)) FS {
+				testFS, errno := PreopenDir(t.TempDir(), WithOnFirstOpen(fn))
+				require.EqualErrno(t, 0, errno)
+				return testFS
+			},
+			serial: true,
+		},
+		{
+			name: "MemFS",
+			newFS: func(fn func(string)) FS {
+				return NewMemFS(WithMemOnFirstOpen(fn))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var mux sync.Mutex
+			var opened []string
+			var testFS FS
+			testFS = tc.newFS(func(path string) {
+				// The callback can use the FS.
+				if _, errno := testFS.Stat(path); errno != 0 {
+					t.Error(errno)
+				}
+				mux.Lock()
+				opened = append(opened, path)
+				mux.Unlock()
+			})
+			require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", []byte("wazero"), 0o600))
+			require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+			opened = nil // ignore the temporary file.
+
+			open := func(path string, flag int) syscall.Errno {
+				f, errno := testFS.OpenFile(path, flag, 0o600)
+				if errno == 0 {
+					errno = f.Close()
+				}
+				return errno
+			}
+
+			// Each path is seen once, however it is written.
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				if tc.serial {
+					require.EqualErrno(t, 0, open("file", os.O_RDONLY))
+					continue
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if errno := open("file", os.O_RDONLY); errno != 0 {
+						t.Error(errno)
+					}
+				}()
+			}
+			wg.Wait()
+			require.EqualErrno(t, 0, open("/dir/../file", os.O_RDONLY))
+			require.EqualErrno(t, 0, open("dir", os.O_RDONLY|platform.O_DIRECTORY))
+			require.EqualErrno(t, 0, open("dir/new", os.O_RDWR|os.O_CREATE))
+			require.EqualErrno(t, 0, open("/", os.O_RDONLY))
+
+			// Failed opens aren't seen.
+			require.EqualErrno(t, syscall.ENOENT, open("missing", os.O_RDONLY))
+			require.Equal(t, []string{"file", "dir", "dir/new", "."}, opened)
+
+			// Resetting sees paths again.
+			testFS.(FirstOpenFS).ResetFirstOpen()
+			require.EqualErrno(t, 0, open("file", os.O_RDONLY))
+			require.Equal(t, []string{"file", "dir", "dir/new", ".", "file"}, opened)
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// GeneratedFS is an FS whose files are generated on demand, returned by
+// NewGeneratedFS.
+type GeneratedFS interface {
+	FS
+
+	// Invalidate discards the cached contents of `path`, so that the next
+	// open or stat generates them again. Files already open keep reading the
+	// contents generated before.
+	Invalidate(path string)
+}
+
+// NewGeneratedFS returns a read-only FS whose files are generated by `gen`,
+// for example, to serve computed manifests or configuration to a guest
+// without writing temporary files.
+//
+// `gen` is called with the cleaned path, such as "etc/app.json", and returns
+// the contents of the file, or false when there is no file at that path.
+//
+// # Notes
+//
+//   - `gen` is called at most once per path, when the path is first opened
+//     or stat, and the result, including its absence, is cached until
+//     GeneratedFS.Invalidate. Concurrent opens of the same path wait for the
+//     same call.
+//   - `gen` must be safe for concurrent use with different paths.
+//   - The root is the only directory, and reading it returns no entries,
+//     as paths aren't known until generated. Any other path is a file.
+//   - Operations which write return syscall.EROFS.
+func NewGeneratedFS(gen func(path string) ([]byte, bool)) GeneratedFS {
+	return &generatedFS{gen: gen, dev: syntheticDev(), files: map[string]*generatedData{}}
+}
+
+type generatedFS struct {
+	readOnlyFS
+	gen func(path string) ([]byte, bool)
+	// dev is the synthetic device ID of all files.
+	dev uint64
+
+	// mux guards files.
+	mux sync.Mutex
+	// files are the cached contents by cleaned path.
+	files map[string]*generatedData
+}
+
+// generatedData is the cached result of generating a path.
+type generatedData struct {
+	mux sync.Mutex
+	ino uint64
+
+	done    bool // whether gen was called since the last invalidation
+	exists  bool
+	content []byte
+	mtim    int64
+}
+
+// generate returns the status and contents of the file at `path`, calling gen
+// when not yet generated.
+func (g *generatedFS) generate(path string) (platform.Stat_t, []byte, syscall.Errno) {
+	g.mux.Lock()
+	d, ok := g.files[path]
+	if !ok {
+		d = &generatedData{ino: uint64(len(g.files) + 2)}
+		g.files[path] = d
+	}
+	g.mux.Unlock()
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if !d.done {
+		d.content, d.exists = g.gen(path)
+		d.mtim = time.Now().UnixNano()
+		d.done = true
+	}
+	if !d.exists {
+		return platform.Stat_t{}, nil, syscall.ENOENT
+	}
+	return platform.Stat_t{
+		Dev:   g.dev,
+		Ino:   d.ino,
+		Mode:  0o444,
+		Nlink: 1,
+		Size:  int64(len(d.content)),
+		Atim:  d.mtim,
+		Mtim:  d.mtim,
+		Ctim:  d.mtim,
+	}, d.content, 0
+}
+
+// Invalidate implements GeneratedFS.Invalidate
+func (g *generatedFS) Invalidate(path string) {
+	g.mux.Lock()
+	d, ok := g.files[cleanPath(path)]
+	g.mux.Unlock()
+	if !ok {
+		return
+	}
+
+	d.mux.Lock()
+	d.done, d.exists, d.content = false, false, nil
+	d.mux.Unlock()
+}
+
+// stat returns the status of the root directory.
+func (g *generatedFS) stat() platform.Stat_t {
+	return platform.Stat_t{Dev: g.dev, Ino: 1, Mode: fs.ModeDir | 0o555, Nlink: 1}
+}
+
+// String implements fmt.Stringer
+func (g *generatedFS) String() string {
+	return "generated"
+}
+
+// OpenFile implements FS.OpenFile
+func (g *generatedFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if flag&(os.O_CREATE|os.O_WRONLY|os.O_RDWR|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, syscall.EROFS
+	}
+	path = cleanPath(path)
+	if path == "" || path == "." {
+		return &generatedDir{fs: g, path: path}, 0
+	}
+	st, content, errno := g.generate(path)
+	if errno != 0 {
+		return nil, errno
+	} else if flag&platform.O_DIRECTORY != 0 {
+		return nil, syscall.ENOTDIR
+	}
+	return &generatedFile{path: path, st: st, content: content}, 0
+}
+
+// Lstat implements FS.Lstat
+func (g *generatedFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return g.Stat(path)
+}
+
+// Stat implements FS.Stat
+func (g *generatedFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	path = cleanPath(path)
+	if path == "" || path == "." {
+		return g.stat(), 0
+	}
+	st, _, errno := g.generate(path)
+	return st, errno
+}
+
+// Readlink implements FS.Readlink
+func (g *generatedFS) Readlink(path string) (string, syscall.Errno) {
+	if _, errno := g.Stat(path); errno != 0 {
+		return "", errno
+	}
+	return "", syscall.EINVAL // not a symbolic link
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (g *generatedFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if _, errno := g.Stat(path); errno != 0 {
+		return 0, errno
+	}
+	return 0, syscall.EINVAL // not a symbolic link
+}
+
+// compile-time check to ensure generatedFile implements platform.File.
+var _ platform.File = (*generatedFile)(nil)
+
+// generatedFile is a file in a generatedFS, opened for reading. It keeps the
+// contents generated when opened, even if the path is invalidated.
+type generatedFile struct {
+	platform.UnimplementedFile
+
+	path    string
+	st      platform.Stat_t
+	content []byte
+	offset  int64
+	closed  bool
+}
+
+// Path implements the same method as documented on platform.File
+func (f *generatedFile) Path() string {
+	return f.path
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *generatedFile) AccessMode() int {
+	return syscall.O_RDONLY
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *generatedFile) Stat() (platform.Stat_t, syscall.Errno) {
+	if f.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return f.st, 0
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *generatedFile) IsDir() (bool, syscall.Errno) {
+	return false, 0
+}
+
+// Read implements the same method as documented on platform.File
+func (f *generatedFile) Read(buf []byte) (n int, errno syscall.Errno) {
+	if n, errno = f.Pread(buf, f.offset); errno == 0 {
+		f.offset += int64(n)
+	}
+	return
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *generatedFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if off >= int64(len(f.content)) {
+		return 0, 0
+	}
+	return copy(buf, f.content[off:]), 0
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *generatedFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.content))
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *generatedFile) Readdir(int) ([]platform.Dirent, syscall.Errno) {
+	return nil, syscall.ENOTDIR
+}
+
+// Write implements the same method as documented on platform.File
+func (f *generatedFile) Write([]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *generatedFile) Writev([][]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *generatedFile) Pwrite([]byte, int64) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *generatedFile) Truncate(int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *generatedFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *generatedFile) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *generatedFile) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *generatedFile) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *generatedFile) PollRead(*time.Duration) (ready bool, errno syscall.Errno) {
+	return true, 0
+}
+
+// Close implements the same method as documented on platform.File
+func (f *generatedFile) Close() syscall.Errno {
+	f.closed = true
+	return 0
+}
+
+// compile-time check to ensure generatedDir implements platform.File.
+var _ platform.File = (*generatedDir)(nil)
+
+// generatedDir is the root directory of a generatedFS, which has no entries
+// as paths aren't known until generated.
+type generatedDir struct {
+	platform.DirFile
+
+	fs     *generatedFS
+	path   string
+	closed bool
+}
+
+// Path implements the same method as documented on platform.File
+func (d *generatedDir) Path() string {
+	return d.path
+}
+
+// Stat implements the same method as documented on platform.File
+func (d *generatedDir) Stat() (platform.Stat_t, syscall.Errno) {
+	if d.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return d.fs.stat(), 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (d *generatedDir) Readdir(int) ([]platform.Dirent, syscall.Errno) {
+	if d.closed {
+		return nil, syscall.EBADF
+	}
+	return nil, 0
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (d *generatedDir) RewindDir() syscall.Errno {
+	if d.closed {
+		return syscall.EBADF
+	}
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (d *generatedDir) Sync() syscall.Errno {
+	return 0
+}
+
+// Datasync implements the same method as documented on platform.File
+func (d *generatedDir) Datasync() syscall.Errno {
+	return 0
+}
+
+// Chmod implements the same method as documented on platform.File
+func (d *generatedDir) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (d *generatedDir) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (d *generatedDir) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Close implements the same method as documented on platform.File
+func (d *generatedDir) Close() syscall.Errno {
+	d.closed = true
+	return 0
+}
@@ -0,0 +1,98 @@
+package sysfs
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewGeneratedFS(t *testing.T) {
+	var calls int32
+	content := "v1"
+	testFS := NewGeneratedFS(func(path string) ([]byte, bool) {
+		atomic.AddInt32(&calls, 1)
+		if path != "etc/app.json" {
+			return nil, false
+		}
+		return []byte(content), true
+	})
+	require.Equal(t, "generated", testFS.String())
+
+	// Stat generates the contents to report their size.
+	st, errno := testFS.Stat("/etc/app.json")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(2), st.Size)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Opening uses the cached contents.
+	f, errno := testFS.OpenFile("etc/app.json", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("v1"), readAll(t, f))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Invalidating generates again, but the open file keeps its contents.
+	content = "v22"
+	testFS.Invalidate("etc/app.json")
+	st, errno = testFS.Stat("etc/app.json")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(3), st.Size)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	buf := make([]byte, 4)
+	n, errno := f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "v1", string(buf[:n]))
+	require.EqualErrno(t, 0, f.Close())
+
+	// Missing paths are cached, too.
+	for i := 0; i < 2; i++ {
+		_, errno = testFS.OpenFile("missing", os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	}
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	_, errno = testFS.OpenFile("etc/app.json", os.O_RDWR, 0)
+	require.EqualErrno(t, syscall.EROFS, errno)
+	_, errno = testFS.OpenFile("etc/app.json", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, syscall.ENOTDIR, errno)
+	require.EqualErrno(t, syscall.EROFS, testFS.Unlink("etc/app.json"))
+
+	// The root is an empty directory.
+	dir, errno := testFS.OpenFile(".", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	dirents, errno := dir.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, len(dirents))
+	require.EqualErrno(t, 0, dir.Close())
+}
+
+func TestGeneratedFS_concurrent(t *testing.T) {
+	var calls int32
+	testFS := NewGeneratedFS(func(path string) ([]byte, bool) {
+		atomic.AddInt32(&calls, 1)
+		return []byte(path), true
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+			if errno != 0 {
+				t.Error(errno)
+				return
+			}
+			defer f.Close()
+			if st, _ := f.Stat(); st.Size != 4 {
+				t.Errorf("unexpected size %d", st.Size)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,275 @@
+package sysfs
+
+import (
+	"io/fs"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// NewGroupCommitFS returns a GroupCommitFS which coalesces File.Sync and
+// File.Datasync calls on files opened from `fs` into batches, flushed once
+// per `window`.
+//
+// This is for guests which write many small files and sync each: Instead of
+// each caller flushing on its own, concurrent callers within the window wait
+// for one flush of the batch. A caller still doesn't return until the batch,
+// including its file, was flushed, so the durability of Sync is unchanged.
+//
+// # Cost
+//
+// A batch of N files is still N calls to File.Sync or File.Datasync, as the
+// errors of each must be reported to its caller. They are made concurrently,
+// so a flush takes about as long as the slowest, and the host can merge the
+// journal commits they cause. Each caller also waits up to `window` for the
+// batch to start, so a lone Sync is slower than without this FS.
+func NewGroupCommitFS(fs FS, window time.Duration) *GroupCommitFS {
+	return &GroupCommitFS{fs: fs, window: window}
+}
+
+// GroupCommitFS is an FS which delegates to another, batching syncs of the
+// files it opens. See NewGroupCommitFS for details.
+type GroupCommitFS struct {
+	UnimplementedFS
+	fs     FS
+	window time.Duration
+
+	// mux guards pending and closed.
+	mux sync.Mutex
+	// pending is the batch waiting for the window to elapse, or nil.
+	pending *commitBatch
+	// closed is true after Close, when syncs are no longer batched.
+	closed bool
+}
+
+// commitBatch is the set of files to flush together.
+type commitBatch struct {
+	// files are the files to flush. The value is true when any caller
+	// requested File.Sync, as opposed to File.Datasync.
+	files map[platform.File]bool
+	timer *time.Timer
+
+	// done is closed after the flush, when errnos can be read.
+	done   chan struct{}
+	errnos map[platform.File]syscall.Errno
+}
+
+// sync adds `f` to the pending batch, and waits for it to be flushed.
+func (g *GroupCommitFS) sync(f platform.File, full bool) syscall.Errno {
+	g.mux.Lock()
+	if g.closed {
+		g.mux.Unlock()
+		return syncFile(f, full)
+	}
+	b := g.pending
+	if b == nil {
+		b = &commitBatch{files: map[platform.File]bool{}, done: make(chan struct{})}
+		b.timer = time.AfterFunc(g.window, func() { g.flush(b) })
+		g.pending = b
+	}
+	b.files[f] = b.files[f] || full
+	g.mux.Unlock()
+
+	<-b.done
+	return b.errnos[f]
+}
+
+// flush flushes the batch `b`, unless it was already flushed.
+func (g *GroupCommitFS) flush(b *commitBatch) {
+	g.mux.Lock()
+	if g.pending != b {
+		g.mux.Unlock()
+		return
+	}
+	g.pending = nil
+	g.mux.Unlock()
+
+	// Sync the files concurrently, so that the flush takes as long as the
+	// slowest, instead of the sum of all.
+	var wg sync.WaitGroup
+	var errnosMux sync.Mutex
+	b.errnos = make(map[platform.File]syscall.Errno, len(b.files))
+	for f, full := range b.files {
+		wg.Add(1)
+		go func(f platform.File, full bool) {
+			defer wg.Done()
+			errno := syncFile(f, full)
+			errnosMux.Lock()
+			b.errnos[f] = errno
+			errnosMux.Unlock()
+		}(f, full)
+	}
+	wg.Wait()
+	close(b.done)
+}
+
+func syncFile(f platform.File, full bool) syscall.Errno {
+	if full {
+		return f.Sync()
+	}
+	return f.Datasync()
+}
+
+// Close flushes any pending batch without waiting for its window, and
+// returns the first error flushing it. Afterwards, syncs aren't batched.
+//
+// Note: This doesn't close `fs` or the files opened from it.
+func (g *GroupCommitFS) Close() syscall.Errno {
+	g.mux.Lock()
+	g.closed = true
+	b := g.pending
+	g.mux.Unlock()
+	if b == nil {
+		return 0
+	}
+
+	b.timer.Stop()
+	g.flush(b)
+	<-b.done // in case the timer was already flushing.
+	for _, errno := range b.errnos {
+		if errno != 0 {
+			return errno
+		}
+	}
+	return 0
+}
+
+// String implements fmt.Stringer
+func (g *GroupCommitFS) String() string {
+	return g.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (g *GroupCommitFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	f, errno := g.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &groupCommitFile{File: f, fs: g}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (g *GroupCommitFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	f, errno := g.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &groupCommitFile{File: f, fs: g}, 0
+}
+
+// Lstat implements FS.Lstat
+func (g *GroupCommitFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return g.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (g *GroupCommitFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return g.fs.Stat(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (g *GroupCommitFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return g.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (g *GroupCommitFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return g.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (g *GroupCommitFS) Chown(path string, uid, gid int) syscall.Errno {
+	return g.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (g *GroupCommitFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return g.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (g *GroupCommitFS) Rename(from, to string) syscall.Errno {
+	return g.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (g *GroupCommitFS) ExchangeDir(a, b string) syscall.Errno {
+	return g.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (g *GroupCommitFS) Clone(src, dst string) syscall.Errno {
+	return g.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (g *GroupCommitFS) Rmdir(path string) syscall.Errno {
+	return g.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (g *GroupCommitFS) Unlink(path string) syscall.Errno {
+	return g.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (g *GroupCommitFS) Link(oldPath, newPath string) syscall.Errno {
+	return g.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (g *GroupCommitFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return g.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (g *GroupCommitFS) Readlink(path string) (string, syscall.Errno) {
+	return g.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (g *GroupCommitFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return g.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (g *GroupCommitFS) Truncate(path string, size int64) syscall.Errno {
+	return g.fs.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (g *GroupCommitFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return g.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (g *GroupCommitFS) SyncDir(path string) syscall.Errno {
+	return g.fs.SyncDir(path)
+}
+
+// groupCommitFile is a file opened by GroupCommitFS.
+type groupCommitFile struct {
+	platform.File
+	fs *GroupCommitFS
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *groupCommitFile) Sync() syscall.Errno {
+	return f.fs.sync(f.File, true)
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *groupCommitFile) Datasync() syscall.Errno {
+	return f.fs.sync(f.File, false)
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *groupCommitFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &groupCommitFile{File: dup, fs: f.fs}, 0
+}
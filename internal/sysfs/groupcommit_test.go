@@ -0,0 +1,124 @@
+package sysfs
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewGroupCommitFS(t *testing.T) {
+	m := &testMetrics{ops: map[string]int{}, bytes: map[string]int{}, latencies: map[string]int{}}
+	testFS := NewGroupCommitFS(NewMetricsFS(NewMemFS(), m), 50*time.Millisecond)
+	require.Equal(t, "mem:/", testFS.String())
+
+	files := make([]platform.File, 4)
+	for i := range files {
+		f, errno := testFS.OpenFile(fmt.Sprint(i), os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+		files[i] = f
+	}
+
+	// Concurrent syncs share a batch, so each file is flushed once, even if
+	// synced more than once.
+	var wg sync.WaitGroup
+	errnos := make([]syscall.Errno, 2*len(files))
+	for i, f := range append(files, files...) {
+		wg.Add(1)
+		go func(i int, f platform.File) {
+			defer wg.Done()
+			if i%2 == 0 {
+				errnos[i] = f.Sync()
+			} else {
+				errnos[i] = f.Datasync()
+			}
+		}(i, f)
+	}
+	wg.Wait()
+	for _, errno := range errnos {
+		require.EqualErrno(t, 0, errno)
+	}
+	m.mux.Lock()
+	require.Equal(t, len(files), m.ops["File.Sync"]+m.ops["File.Datasync"])
+	m.mux.Unlock()
+
+	t.Run("files are synced concurrently", func(t *testing.T) {
+		testFS := NewGroupCommitFS(NewMemFS(), 50*time.Millisecond)
+
+		// Each sync waits for all others to start, which would time out if
+		// they were synced one after another.
+		entered := &sync.WaitGroup{}
+		files := make([]platform.File, 4)
+		entered.Add(len(files))
+		for i := range files {
+			files[i] = &barrierSyncFile{entered: entered}
+		}
+		var wg sync.WaitGroup
+		errnos := make([]syscall.Errno, len(files))
+		for i, f := range files {
+			wg.Add(1)
+			go func(i int, f platform.File) {
+				defer wg.Done()
+				errnos[i] = testFS.sync(f, true)
+			}(i, f)
+		}
+		wg.Wait()
+		require.Equal(t, make([]syscall.Errno, len(files)), errnos)
+	})
+
+	t.Run("Close flushes pending", func(t *testing.T) {
+		testFS := NewGroupCommitFS(NewMemFS(), time.Hour)
+		f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		synced := make(chan syscall.Errno)
+		go func() { synced <- f.Sync() }()
+		for {
+			testFS.mux.Lock()
+			pending := testFS.pending != nil
+			testFS.mux.Unlock()
+			if pending {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		require.EqualErrno(t, 0, testFS.Close())
+		require.EqualErrno(t, 0, <-synced)
+
+		// Syncs after Close aren't batched.
+		require.EqualErrno(t, 0, f.Datasync())
+	})
+}
+
+// barrierSyncFile is a file whose Sync returns once every file sharing
+// `entered` is in Sync, or syscall.ETIMEDOUT.
+type barrierSyncFile struct {
+	platform.UnimplementedFile
+	entered *sync.WaitGroup
+}
+
+func (f *barrierSyncFile) Path() string         { return "file" }
+func (f *barrierSyncFile) AccessMode() int      { return syscall.O_RDWR }
+func (f *barrierSyncFile) Close() syscall.Errno { return 0 }
+
+func (f *barrierSyncFile) Sync() syscall.Errno {
+	f.entered.Done()
+	done := make(chan struct{})
+	go func() {
+		f.entered.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return 0
+	case <-time.After(5 * time.Second):
+		return syscall.ETIMEDOUT
+	}
+}
@@ -0,0 +1,313 @@
+package sysfs
+
+import (
+	"io/fs"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// IsDotfile returns true if `name` begins with a dot, except "." and "..".
+// This is the rule of NewNoDotfilesFS.
+func IsDotfile(name string) bool {
+	return len(name) > 0 && name[0] == '.' && name != "." && name != ".."
+}
+
+// NewNoDotfilesFS returns an FS which hides files and directories whose name
+// begins with a dot, such as ".ssh" or ".env", so that a guest can't read
+// them. This is NewHiddenFS with IsDotfile.
+func NewNoDotfilesFS(fs FS) FS {
+	return NewHiddenFS(fs, IsDotfile)
+}
+
+// NewHiddenFS returns an FS which delegates to `fs`, except it hides each
+// file or directory whose name `hide` returns true for, as if it didn't
+// exist.
+//
+// # Errors
+//
+// Operations on a path with any hidden component, such as "app/.env" or
+// ".ssh/id_rsa", return syscall.ENOENT, including those which would create
+// it. Symlink returns syscall.EPERM when its target has a hidden component,
+// so that a guest can't link to a hidden file.
+//
+// # Notes
+//
+//   - Hidden entries are omitted from platform.File Readdir, and from
+//     platform.ReaddirFrom without affecting the cookies of other entries.
+//   - Symbolic links are not resolved, so a link already in `fs` which points
+//     to a hidden file can still be followed.
+func NewHiddenFS(fs FS, hide func(name string) bool) FS {
+	return &hiddenFS{fs: fs, hide: hide}
+}
+
+type hiddenFS struct {
+	UnimplementedFS
+	fs   FS
+	hide func(name string) bool
+}
+
+// isHidden returns true if any component of `path` is hidden.
+func (h *hiddenFS) isHidden(path string) bool {
+	for _, name := range strings.Split(path, "/") {
+		if h.hide(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns syscall.ENOENT if any of `paths` is hidden.
+func (h *hiddenFS) check(paths ...string) syscall.Errno {
+	for _, p := range paths {
+		if h.isHidden(p) {
+			return syscall.ENOENT
+		}
+	}
+	return 0
+}
+
+// String implements fmt.Stringer
+func (h *hiddenFS) String() string {
+	return h.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (h *hiddenFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if errno := h.check(path); errno != 0 {
+		return nil, errno
+	}
+	f, errno := h.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	} else if isDir, _ := f.IsDir(); isDir {
+		return &hiddenDir{File: f, hide: h.hide}, 0
+	}
+	return f, 0
+}
+
+// OpenByID implements FS.OpenByID
+//
+// This returns syscall.ENOSYS on purpose: a file opened by its inode has no
+// path to check, and a hard link would make even its path unreliable, so
+// forwarding would let a guest open a hidden file.
+func (h *hiddenFS) OpenByID(uint64, uint64, int) (platform.File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
+
+// Lstat implements FS.Lstat
+func (h *hiddenFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := h.check(path); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return h.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (h *hiddenFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := h.check(path); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return h.fs.Stat(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (h *hiddenFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (h *hiddenFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (h *hiddenFS) Chown(path string, uid, gid int) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (h *hiddenFS) Lchown(path string, uid, gid int) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (h *hiddenFS) Rename(from, to string) syscall.Errno {
+	if errno := h.check(from, to); errno != 0 {
+		return errno
+	}
+	return h.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (h *hiddenFS) ExchangeDir(a, b string) syscall.Errno {
+	if errno := h.check(a, b); errno != 0 {
+		return errno
+	}
+	return h.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (h *hiddenFS) Clone(src, dst string) syscall.Errno {
+	if errno := h.check(src, dst); errno != 0 {
+		return errno
+	}
+	return h.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (h *hiddenFS) Rmdir(path string) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (h *hiddenFS) Unlink(path string) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (h *hiddenFS) Link(oldPath, newPath string) syscall.Errno {
+	if errno := h.check(oldPath, newPath); errno != 0 {
+		return errno
+	}
+	return h.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (h *hiddenFS) Symlink(oldPath, linkName string) syscall.Errno {
+	if errno := h.check(linkName); errno != 0 {
+		return errno
+	} else if h.isHidden(oldPath) {
+		return syscall.EPERM
+	}
+	return h.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (h *hiddenFS) Readlink(path string) (string, syscall.Errno) {
+	if errno := h.check(path); errno != 0 {
+		return "", errno
+	}
+	return h.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (h *hiddenFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if errno := h.check(path); errno != 0 {
+		return 0, errno
+	}
+	return h.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (h *hiddenFS) Truncate(path string, size int64) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (h *hiddenFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (h *hiddenFS) SyncDir(path string) syscall.Errno {
+	if errno := h.check(path); errno != 0 {
+		return errno
+	}
+	return h.fs.SyncDir(path)
+}
+
+// hiddenDir omits hidden entries when reading a directory opened by hiddenFS.
+type hiddenDir struct {
+	platform.File
+	hide func(name string) bool
+}
+
+// visible appends the entries of `dirents` which aren't hidden to `out`.
+func (d *hiddenDir) visible(out, dirents []platform.Dirent) []platform.Dirent {
+	for _, e := range dirents {
+		if !d.hide(e.Name) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Readdir implements the same method as documented on platform.File
+//
+// This reads until `n` entries are visible, so that a batch isn't short only
+// because some entries were hidden.
+func (d *hiddenDir) Readdir(n int) (out []platform.Dirent, errno syscall.Errno) {
+	for {
+		want := n - len(out)
+		if n <= 0 {
+			want = n
+		}
+		var dirents []platform.Dirent
+		if dirents, errno = d.File.Readdir(want); errno != 0 {
+			return nil, errno
+		}
+		out = d.visible(out, dirents)
+		if len(dirents) == 0 || n <= 0 || len(out) >= n {
+			return out, 0
+		}
+	}
+}
+
+// ReaddirFrom implements the same method as documented on
+// platform.ReaddirFrom
+//
+// The cookies are those of the underlying directory, so resuming after a
+// visible entry skips any hidden entries which follow it.
+func (d *hiddenDir) ReaddirFrom(cookie uint64, n int) (out []platform.Dirent, errno syscall.Errno) {
+	for {
+		want := n - len(out)
+		if n <= 0 {
+			want = n
+		}
+		var dirents []platform.Dirent
+		if dirents, errno = platform.ReaddirFrom(d.File, cookie, want); errno != 0 {
+			return nil, errno
+		} else if len(dirents) > 0 {
+			cookie = dirents[len(dirents)-1].Cookie
+		}
+		out = d.visible(out, dirents)
+		if len(dirents) == 0 || n <= 0 || len(out) >= n {
+			return out, 0
+		}
+	}
+}
+
+// Dup implements the same method as documented on platform.File
+func (d *hiddenDir) Dup() (platform.File, syscall.Errno) {
+	dup, errno := d.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &hiddenDir{File: dup, hide: d.hide}, 0
+}
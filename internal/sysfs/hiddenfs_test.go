@@ -0,0 +1,90 @@
+package sysfs
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestIsDotfile(t *testing.T) {
+	for _, name := range []string{".ssh", ".env", ".a"} {
+		require.True(t, IsDotfile(name), name)
+	}
+	for _, name := range []string{".", "..", "", "a.b", "env"} {
+		require.False(t, IsDotfile(name), name)
+	}
+}
+
+func TestNewNoDotfilesFS(t *testing.T) {
+	hostFS := NewMemFS()
+	require.EqualErrno(t, 0, hostFS.Mkdir(".ssh", 0o700))
+	require.EqualErrno(t, 0, WriteFileAtomic(hostFS, ".ssh/id_rsa", []byte("secret"), 0o600))
+	require.EqualErrno(t, 0, hostFS.Mkdir("app", 0o700))
+	require.EqualErrno(t, 0, WriteFileAtomic(hostFS, "app/.env", []byte("secret"), 0o600))
+	require.EqualErrno(t, 0, WriteFileAtomic(hostFS, "app/main.wasm", nil, 0o600))
+
+	testFS := NewNoDotfilesFS(hostFS)
+	require.Equal(t, "mem:/", testFS.String())
+
+	for _, p := range []string{".ssh", ".ssh/id_rsa", "app/.env", "/app/.env", "app/.env/.."} {
+		_, errno := testFS.OpenFile(p, os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.ENOENT, errno, p)
+		_, errno = testFS.Stat(p)
+		require.EqualErrno(t, syscall.ENOENT, errno, p)
+	}
+
+	// Visible paths, including "." and "..", are unaffected.
+	_, errno := testFS.Stat("app/../app/./main.wasm")
+	require.EqualErrno(t, 0, errno)
+
+	// Hidden files can't be created, renamed to or linked to.
+	_, errno = testFS.OpenFile("app/.new", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	require.EqualErrno(t, syscall.ENOENT, testFS.Mkdir(".dir", 0o700))
+	require.EqualErrno(t, syscall.ENOENT, testFS.Rename("app/main.wasm", ".main.wasm"))
+	require.EqualErrno(t, syscall.ENOENT, testFS.Unlink("app/.env"))
+	require.EqualErrno(t, syscall.EPERM, testFS.Symlink("../.ssh/id_rsa", "app/key"))
+
+	require.Equal(t, []string{"app"}, readdirNames(t, testFS, "."))
+	require.Equal(t, []string{"main.wasm"}, readdirNames(t, testFS, "app"))
+}
+
+func TestNewHiddenFS(t *testing.T) {
+	hostFS := NewMemFS()
+	for _, name := range []string{"a", "b.secret", "c", "d.secret", "e.secret", "f"} {
+		require.EqualErrno(t, 0, WriteFileAtomic(hostFS, name, nil, 0o600))
+	}
+	testFS := NewHiddenFS(hostFS, func(name string) bool {
+		return strings.HasSuffix(name, ".secret")
+	})
+
+	_, errno := testFS.Stat("b.secret")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	dir, errno := testFS.OpenFile(".", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer dir.Close()
+
+	// Batches are full, despite hidden entries.
+	dirents, errno := dir.Readdir(2)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"a", "c"}, direntNames(dirents))
+	dirents, errno = dir.Readdir(2)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"f"}, direntNames(dirents))
+
+	// Resuming at the cookie of a visible entry skips hidden ones after it.
+	dirents, errno = platform.ReaddirFrom(dir, 0, 1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"a"}, direntNames(dirents))
+	dirents, errno = platform.ReaddirFrom(dir, dirents[0].Cookie, 1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"c"}, direntNames(dirents))
+	dirents, errno = platform.ReaddirFrom(dir, dirents[0].Cookie, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"f"}, direntNames(dirents))
+}
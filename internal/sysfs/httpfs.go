@@ -0,0 +1,605 @@
+package sysfs
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+const (
+	// httpBlockSize is the size of ranges fetched by httpFS, which are cached.
+	httpBlockSize = 64 * 1024
+
+	// httpMaxFiles bounds the count of files cached by httpFS. When more are
+	// accessed, the least recently used is forgotten.
+	httpMaxFiles = 1024
+)
+
+// NewHTTPFS returns a read-only FS whose files are fetched from `baseURL`.
+// See NewHTTPFSContext for details.
+func NewHTTPFS(baseURL string, client *http.Client) FS {
+	return NewHTTPFSContext(context.Background(), baseURL, client)
+}
+
+// NewHTTPFSContext returns a read-only FS whose files are fetched from
+// `baseURL` with `client`, or http.DefaultClient if nil. For example, the
+// path "lib/app.wasm" is fetched from "https://host/base/lib/app.wasm" when
+// `baseURL` is "https://host/base".
+//
+// FS.Stat issues a HEAD request to learn the size of a file. File.Pread and
+// File.Read fetch the 64KiB blocks they overlap with Range requests, caching
+// them so each block is fetched at most once. When the server doesn't support
+// ranges, the whole file is downloaded on the first read instead.
+//
+// All requests use `ctx`, so once it is done, pending and later operations
+// return syscall.ECANCELED.
+//
+// # Errors
+//
+// Operations which write return syscall.EROFS. Otherwise, the below are
+// expected in addition to those documented on FS:
+//   - syscall.ENOENT: the server responded 404 or 410, or the path has a ".."
+//     element, which would escape `baseURL`.
+//   - syscall.EACCES: the server responded 401 or 403.
+//   - syscall.EIO: the request failed or the server responded with another
+//     error status.
+//
+// # Notes
+//
+//   - HTTP has no directories, so the root is the only directory, and
+//     reading it returns no entries. Any other path is a file.
+//   - The status and contents of up to 1024 files are cached, so changes on
+//     the server aren't seen until a file is forgotten, which also changes
+//     its inode. Failed requests aren't cached.
+//   - Fetched blocks are retained while their file is cached or open, so
+//     memory grows with the amount read.
+func NewHTTPFSContext(ctx context.Context, baseURL string, client *http.Client) FS {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpFS{
+		ctx:     ctx,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+		dev:     syntheticDev(),
+		files:   map[string]*list.Element{},
+		lru:     list.New(),
+		nextIno: 2,
+	}
+}
+
+type httpFS struct {
+	readOnlyFS
+	ctx     context.Context
+	baseURL string
+	client  *http.Client
+	// dev is the synthetic device ID of all files.
+	dev uint64
+
+	// mux guards files, lru and nextIno.
+	mux sync.Mutex
+	// files are the elements of lru by cleaned path.
+	files map[string]*list.Element
+	// lru are the cached status and contents of files, as *httpData, most
+	// recently used first.
+	lru *list.List
+	// nextIno is the inode of the next file cached. The root is 1.
+	nextIno uint64
+}
+
+// httpData is the cached status and contents of a file, shared by all opens.
+type httpData struct {
+	mux  sync.Mutex
+	path string
+	url  string
+	ino  uint64
+	head bool // whether the HEAD request succeeded
+
+	size   int64
+	mtim   int64
+	ranges bool // whether the server accepts Range requests
+
+	// full is the whole file, once downloaded because ranges aren't
+	// supported.
+	full []byte
+	// blocks are the blocks fetched with Range requests, by index.
+	blocks map[int64][]byte
+}
+
+// data returns the cached data of a file, issuing a HEAD request the first
+// time.
+func (h *httpFS) data(path string) (*httpData, syscall.Errno) {
+	for _, e := range strings.Split(path, "/") {
+		if e == ".." {
+			return nil, syscall.ENOENT
+		}
+	}
+
+	d := h.cached(path)
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if d.head {
+		return d, 0
+	}
+	resp, errno := h.do(http.MethodHead, d.url, "")
+	if errno != 0 {
+		h.forget(d)
+		return nil, errno
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		h.forget(d)
+		return nil, httpStatusErrno(resp.StatusCode)
+	}
+	d.head = true
+	d.size = resp.ContentLength // -1 when unknown.
+	d.ranges = resp.Header.Get("Accept-Ranges") == "bytes" && d.size >= 0
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		d.mtim = t.UnixNano()
+	}
+	return d, 0
+}
+
+// cached returns the cached data of `path`, adding it if not yet cached.
+func (h *httpFS) cached(path string) *httpData {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if e, ok := h.files[path]; ok {
+		h.lru.MoveToFront(e)
+		return e.Value.(*httpData)
+	}
+	if h.lru.Len() >= httpMaxFiles {
+		oldest := h.lru.Remove(h.lru.Back()).(*httpData)
+		delete(h.files, oldest.path)
+	}
+	d := &httpData{path: path, url: h.url(path), ino: h.nextIno, blocks: map[int64][]byte{}}
+	h.nextIno++
+	h.files[path] = h.lru.PushFront(d)
+	return d
+}
+
+// forget removes `d` from the cache, if still cached, so that a failed
+// request is retried.
+func (h *httpFS) forget(d *httpData) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if e, ok := h.files[d.path]; ok && e.Value == d {
+		h.lru.Remove(e)
+		delete(h.files, d.path)
+	}
+}
+
+// url returns the URL of the cleaned path, escaping each element.
+func (h *httpFS) url(path string) string {
+	elems := strings.Split(path, "/")
+	for i, e := range elems {
+		elems[i] = url.PathEscape(e)
+	}
+	return h.baseURL + "/" + strings.Join(elems, "/")
+}
+
+// do sends a request, with a Range header unless empty.
+func (h *httpFS) do(method, rawURL, byteRange string) (*http.Response, syscall.Errno) {
+	req, err := http.NewRequestWithContext(h.ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, syscall.EINVAL
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if h.ctx.Err() != nil {
+			return nil, syscall.ECANCELED
+		}
+		return nil, syscall.EIO
+	}
+	return resp, 0
+}
+
+// httpStatusErrno returns the syscall.Errno of an unexpected response status.
+func httpStatusErrno(status int) syscall.Errno {
+	switch status {
+	case http.StatusNotFound, http.StatusGone:
+		return syscall.ENOENT
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return syscall.EACCES
+	}
+	return syscall.EIO
+}
+
+// readBody reads the whole body of a response, closing it.
+func (h *httpFS) readBody(resp *http.Response) ([]byte, syscall.Errno) {
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if h.ctx.Err() != nil {
+			return nil, syscall.ECANCELED
+		}
+		return nil, syscall.EIO
+	}
+	return b, 0
+}
+
+// readAt reads the file at `off`, fetching any blocks not yet cached.
+func (h *httpFS) readAt(d *httpData, buf []byte, off int64) (n int, errno syscall.Errno) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if !d.ranges && d.full == nil {
+		if errno = h.download(d); errno != 0 {
+			return 0, errno
+		}
+	}
+	if d.full != nil {
+		if off >= int64(len(d.full)) {
+			return 0, 0
+		}
+		return copy(buf, d.full[off:]), 0
+	}
+
+	for n < len(buf) {
+		pos := off + int64(n)
+		if pos >= d.size {
+			break
+		}
+		i := pos / httpBlockSize
+		block, ok := d.blocks[i]
+		if !ok {
+			if block, errno = h.fetchBlock(d, i); errno != 0 {
+				return
+			} else if d.full != nil { // the server ignored the range.
+				if pos < int64(len(d.full)) {
+					n += copy(buf[n:], d.full[pos:])
+				}
+				return n, 0
+			}
+		}
+		if rem := pos % httpBlockSize; rem < int64(len(block)) {
+			n += copy(buf[n:], block[rem:])
+		} else {
+			break // the file is shorter than the HEAD response said.
+		}
+	}
+	return
+}
+
+// download fetches and caches the whole file.
+func (h *httpFS) download(d *httpData) syscall.Errno {
+	resp, errno := h.do(http.MethodGet, d.url, "")
+	if errno != 0 {
+		return errno
+	} else if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return httpStatusErrno(resp.StatusCode)
+	}
+	full, errno := h.readBody(resp)
+	if errno != 0 {
+		return errno
+	}
+	d.full, d.size = full, int64(len(full))
+	return 0
+}
+
+// fetchBlock fetches and caches the block `i` with a Range request. If the
+// server responds with the whole file instead, it is cached as httpData.full.
+func (h *httpFS) fetchBlock(d *httpData, i int64) ([]byte, syscall.Errno) {
+	start := i * httpBlockSize
+	end := start + httpBlockSize - 1 // inclusive
+	resp, errno := h.do(http.MethodGet, d.url, fmt.Sprintf("bytes=%d-%d", start, end))
+	if errno != 0 {
+		return nil, errno
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		full, errno := h.readBody(resp)
+		if errno != 0 {
+			return nil, errno
+		}
+		d.full, d.size, d.ranges = full, int64(len(full)), false
+		return nil, 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		_ = resp.Body.Close()
+		return nil, 0 // past the end
+	default:
+		_ = resp.Body.Close()
+		return nil, httpStatusErrno(resp.StatusCode)
+	}
+	block, errno := h.readBody(resp)
+	if errno != 0 {
+		return nil, errno
+	}
+	d.blocks[i] = block
+	return block, 0
+}
+
+// stat returns the status of a file, or the root directory if `d` is nil.
+func (h *httpFS) stat(d *httpData) platform.Stat_t {
+	if d == nil {
+		return platform.Stat_t{Dev: h.dev, Ino: 1, Mode: fs.ModeDir | 0o555, Nlink: 1}
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	st := platform.Stat_t{Dev: h.dev, Ino: d.ino, Mode: 0o444, Nlink: 1}
+	if d.size > 0 {
+		st.Size = d.size
+	}
+	st.Atim, st.Mtim, st.Ctim = d.mtim, d.mtim, d.mtim
+	return st
+}
+
+// String implements fmt.Stringer
+func (h *httpFS) String() string {
+	return h.baseURL
+}
+
+// OpenFile implements FS.OpenFile
+func (h *httpFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if flag&(os.O_CREATE|os.O_WRONLY|os.O_RDWR|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, syscall.EROFS
+	}
+	path = cleanPath(path)
+	if path == "" || path == "." {
+		return &httpDir{fs: h, path: path}, 0
+	}
+	d, errno := h.data(path)
+	if errno != 0 {
+		return nil, errno
+	} else if flag&platform.O_DIRECTORY != 0 {
+		return nil, syscall.ENOTDIR
+	}
+	return &httpFile{fs: h, data: d, path: path}, 0
+}
+
+// Lstat implements FS.Lstat
+func (h *httpFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return h.Stat(path)
+}
+
+// Stat implements FS.Stat
+func (h *httpFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	path = cleanPath(path)
+	if path == "" || path == "." {
+		return h.stat(nil), 0
+	}
+	d, errno := h.data(path)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return h.stat(d), 0
+}
+
+// Readlink implements FS.Readlink
+func (h *httpFS) Readlink(path string) (string, syscall.Errno) {
+	if _, errno := h.Stat(path); errno != 0 {
+		return "", errno
+	}
+	return "", syscall.EINVAL // not a symbolic link
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (h *httpFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if _, errno := h.Stat(path); errno != 0 {
+		return 0, errno
+	}
+	return 0, syscall.EINVAL // not a symbolic link
+}
+
+// compile-time check to ensure httpFile implements platform.File.
+var _ platform.File = (*httpFile)(nil)
+
+// httpFile is a file in an httpFS, opened for reading.
+type httpFile struct {
+	platform.UnimplementedFile
+
+	fs     *httpFS
+	data   *httpData
+	path   string
+	offset int64
+	closed bool
+}
+
+// Path implements the same method as documented on platform.File
+func (f *httpFile) Path() string {
+	return f.path
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *httpFile) AccessMode() int {
+	return syscall.O_RDONLY
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *httpFile) Stat() (platform.Stat_t, syscall.Errno) {
+	if f.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return f.fs.stat(f.data), 0
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *httpFile) IsDir() (bool, syscall.Errno) {
+	return false, 0
+}
+
+// Read implements the same method as documented on platform.File
+func (f *httpFile) Read(buf []byte) (n int, errno syscall.Errno) {
+	if n, errno = f.Pread(buf, f.offset); errno == 0 {
+		f.offset += int64(n)
+	}
+	return
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *httpFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(buf) == 0 {
+		return 0, 0
+	}
+	return f.fs.readAt(f.data, buf, off)
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *httpFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.fs.stat(f.data).Size
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *httpFile) Readdir(int) ([]platform.Dirent, syscall.Errno) {
+	return nil, syscall.ENOTDIR
+}
+
+// Write implements the same method as documented on platform.File
+func (f *httpFile) Write([]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *httpFile) Writev([][]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *httpFile) Pwrite([]byte, int64) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *httpFile) Truncate(int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *httpFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *httpFile) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *httpFile) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *httpFile) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *httpFile) PollRead(*time.Duration) (ready bool, errno syscall.Errno) {
+	return true, 0 // Reads block on the request instead.
+}
+
+// Close implements the same method as documented on platform.File
+func (f *httpFile) Close() syscall.Errno {
+	f.closed = true
+	return 0
+}
+
+// compile-time check to ensure httpDir implements platform.File.
+var _ platform.File = (*httpDir)(nil)
+
+// httpDir is the root directory of an httpFS, which has no entries as HTTP
+// can't list them.
+type httpDir struct {
+	platform.DirFile
+
+	fs     *httpFS
+	path   string
+	closed bool
+}
+
+// Path implements the same method as documented on platform.File
+func (d *httpDir) Path() string {
+	return d.path
+}
+
+// Stat implements the same method as documented on platform.File
+func (d *httpDir) Stat() (platform.Stat_t, syscall.Errno) {
+	if d.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return d.fs.stat(nil), 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (d *httpDir) Readdir(int) ([]platform.Dirent, syscall.Errno) {
+	if d.closed {
+		return nil, syscall.EBADF
+	}
+	return nil, 0
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (d *httpDir) RewindDir() syscall.Errno {
+	if d.closed {
+		return syscall.EBADF
+	}
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (d *httpDir) Sync() syscall.Errno {
+	return 0
+}
+
+// Datasync implements the same method as documented on platform.File
+func (d *httpDir) Datasync() syscall.Errno {
+	return 0
+}
+
+// Chmod implements the same method as documented on platform.File
+func (d *httpDir) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (d *httpDir) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (d *httpDir) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Close implements the same method as documented on platform.File
+func (d *httpDir) Close() syscall.Errno {
+	d.closed = true
+	return 0
+}
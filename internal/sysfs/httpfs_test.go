@@ -0,0 +1,190 @@
+package sysfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// httpTestContent spans several blocks, so reads cross them.
+var httpTestContent = bytes.Repeat([]byte("0123456789abcdef"), 3*httpBlockSize/16+1)
+
+// newHTTPTestServer returns a server of httpTestContent at "/base/dir/file",
+// which supports ranges unless `ignoreRanges`, and counts GET requests.
+func newHTTPTestServer(t *testing.T, ignoreRanges bool) (*httptest.Server, *int32) {
+	var gets int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/base/dir/file" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&gets, 1)
+		}
+		if ignoreRanges {
+			w.Header().Set("Content-Length", strconv.Itoa(len(httpTestContent)))
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(httpTestContent)
+			}
+			return
+		}
+		http.ServeContent(w, r, "file", time.Unix(1234, 0), bytes.NewReader(httpTestContent))
+	}))
+	t.Cleanup(server.Close)
+	return server, &gets
+}
+
+func TestNewHTTPFS(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		ignoreRanges bool
+		expectedGets int32
+	}{
+		{name: "ranges", expectedGets: 2},
+		{name: "no ranges", ignoreRanges: true, expectedGets: 1},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			server, gets := newHTTPTestServer(t, tc.ignoreRanges)
+			testFS := NewHTTPFS(server.URL+"/base/", server.Client())
+			require.Equal(t, server.URL+"/base", testFS.String())
+
+			st, errno := testFS.Stat("dir/file")
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, int64(len(httpTestContent)), st.Size)
+			require.Zero(t, *gets)
+
+			_, errno = testFS.Stat("missing")
+			require.EqualErrno(t, syscall.ENOENT, errno)
+
+			f, errno := testFS.OpenFile("dir/file", os.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			defer f.Close()
+
+			// Read across the first and second block.
+			buf := make([]byte, 10)
+			off := int64(httpBlockSize - 5)
+			n, errno := f.Pread(buf, off)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, httpTestContent[off:off+10], buf[:n])
+
+			// Reading the same blocks again uses the cache.
+			n, errno = f.Pread(buf, off-1)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, httpTestContent[off-1:off+9], buf[:n])
+			require.Equal(t, tc.expectedGets, atomic.LoadInt32(gets))
+
+			// Reading to the end returns the whole content.
+			_, errno = f.Seek(0, io.SeekStart)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, httpTestContent, readAll(t, f))
+		})
+	}
+}
+
+func TestHTTPFS_readOnly(t *testing.T) {
+	server, _ := newHTTPTestServer(t, false)
+	testFS := NewHTTPFS(server.URL+"/base", nil)
+
+	_, errno := testFS.OpenFile("dir/file", os.O_RDWR, 0)
+	require.EqualErrno(t, syscall.EROFS, errno)
+	_, errno = testFS.OpenFile("new", os.O_WRONLY|os.O_CREATE, 0o600)
+	require.EqualErrno(t, syscall.EROFS, errno)
+	require.EqualErrno(t, syscall.EROFS, testFS.Unlink("dir/file"))
+
+	f, errno := testFS.OpenFile("dir/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, syscall.EBADF, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// The root is an empty directory.
+	dir, errno := testFS.OpenFile(".", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	dirents, errno := dir.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, len(dirents))
+	require.EqualErrno(t, 0, dir.Close())
+}
+
+func TestHTTPFS_cancel(t *testing.T) {
+	server, _ := newHTTPTestServer(t, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	testFS := NewHTTPFSContext(ctx, server.URL+"/base", server.Client())
+
+	f, errno := testFS.OpenFile("dir/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	cancel()
+	_, errno = f.Read(make([]byte, 10))
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+	_, errno = testFS.Stat("dir/other")
+	require.EqualErrno(t, syscall.ECANCELED, errno)
+}
+
+func TestHTTPFS_dotDot(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	t.Cleanup(server.Close)
+	testFS := NewHTTPFS(server.URL+"/base/dir", server.Client())
+
+	// A ".." element would escape the base URL, so isn't requested.
+	for _, path := range []string{"..", "../secret", "/../secret", "a/../../secret"} {
+		_, errno := testFS.Stat(path)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		_, errno = testFS.OpenFile(path, os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	}
+	require.Zero(t, atomic.LoadInt32(&requests))
+
+	// Inside the base URL is fine.
+	_, errno := testFS.Stat("a/../file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestHTTPFS_cache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	testFS := NewHTTPFS(server.URL, server.Client()).(*httpFS)
+
+	// Failures aren't cached.
+	_, errno := testFS.Stat("missing")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	require.Zero(t, len(testFS.files))
+
+	// The least recently used file is forgotten past httpMaxFiles.
+	first, errno := testFS.Stat("0")
+	require.EqualErrno(t, 0, errno)
+	for i := 1; i <= httpMaxFiles; i++ {
+		_, errno = testFS.Stat(strconv.Itoa(i))
+		require.EqualErrno(t, 0, errno)
+	}
+	require.Equal(t, httpMaxFiles, len(testFS.files))
+	require.Equal(t, httpMaxFiles, testFS.lru.Len())
+	_, ok := testFS.files["0"]
+	require.False(t, ok)
+
+	// Once forgotten, a file has a new inode.
+	again, errno := testFS.Stat("0")
+	require.EqualErrno(t, 0, errno)
+	require.NotEqual(t, first.Ino, again.Ino)
+}
@@ -0,0 +1,687 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// KVFSOption configures an FS returned by NewKVFS.
+type KVFSOption func(*kvFS)
+
+// WithKVWritable makes an FS returned by NewKVFS writable: files can be
+// created, written, truncated, renamed and removed, and each change is stored
+// in the map.
+//
+// Note: The map is then written while the FS is in use, so the caller must
+// not access it concurrently.
+func WithKVWritable() KVFSOption {
+	return func(k *kvFS) {
+		k.writable = true
+	}
+}
+
+// NewKVFS returns an FS whose files are the values of `kv`, keyed by their
+// slash-separated path, such as "etc/app.json". This is simpler than an
+// fs.FS for delivering configuration a host already has in memory.
+//
+// # Notes
+//
+//   - Directories are implied by the keys: "etc/app.json" implies "etc",
+//     whose entries are read sorted by name. A key which is also the parent
+//     of others, such as "etc", is a file, which hides the others.
+//   - Keys are cleaned like paths, so "/etc/app.json" is the same file.
+//   - Values aren't copied. A value is copied before its first write, so
+//     the slice passed isn't modified.
+//   - The FS is read-only, unless WithKVWritable. Then, creating a file
+//     implies its parent directories, and renaming a directory returns
+//     syscall.ENOSYS. Other operations which write, such as Mkdir, return
+//     syscall.EROFS, as the map can't represent them. Growing a file past
+//     platform.MaxMemoryFileSize returns syscall.EFBIG.
+func NewKVFS(kv map[string][]byte, opts ...KVFSOption) FS {
+	k := &kvFS{
+		kv:      kv,
+		dev:     syntheticDev(),
+		mtim:    time.Now().UnixNano(),
+		entries: make(map[string]*kvEntry, len(kv)),
+		dirInos: map[string]uint64{},
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	// Assign inodes in key order, so that they are the same each time.
+	keys := make([]string, 0, len(kv))
+	for key := range kv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	k.lastIno = 1 // the root
+	for _, key := range keys {
+		p := cleanPath(key)
+		if p == "" || p == "." {
+			continue // the root is always a directory
+		}
+		k.lastIno++
+		k.entries[p] = &kvEntry{ino: k.lastIno, path: p, key: key, data: kv[key], mtim: k.mtim}
+	}
+	return k
+}
+
+type kvFS struct {
+	readOnlyFS
+	// dev is the synthetic device ID of all files.
+	dev uint64
+	// writable is set by WithKVWritable.
+	writable bool
+	// mtim is the time of the root, and directories implied by the keys.
+	mtim int64
+
+	// mux guards the below fields, all fields of kvEntry, and kv when
+	// writable.
+	mux sync.Mutex
+	kv  map[string][]byte
+	// entries are the files by cleaned path.
+	entries map[string]*kvEntry
+	// dirInos are the inodes of directories, by cleaned path, assigned when
+	// first seen.
+	dirInos map[string]uint64
+	lastIno uint64
+}
+
+// kvEntry is a file of a kvFS, which open files share.
+type kvEntry struct {
+	ino uint64
+	// path is the cleaned key, and key is the key in the map.
+	path, key string
+	data      []byte
+	mtim      int64
+	// owned is true once data was copied from the map, so it can be written.
+	owned bool
+}
+
+// lookup returns the file at the cleaned path `p`, or nil if it is a
+// directory. The caller holds mux.
+func (k *kvFS) lookup(p string) (*kvEntry, syscall.Errno) {
+	if p == "" || p == "." {
+		return nil, 0
+	} else if e, ok := k.entries[p]; ok {
+		return e, 0
+	}
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if _, ok := k.entries[dir]; ok {
+			return nil, syscall.ENOTDIR
+		}
+	}
+	prefix := p + "/"
+	for entryPath := range k.entries {
+		if strings.HasPrefix(entryPath, prefix) {
+			return nil, 0
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+// dirIno returns the inode of the directory at the cleaned path `p`. The
+// caller holds mux.
+func (k *kvFS) dirIno(p string) uint64 {
+	if p == "" || p == "." {
+		return 1
+	}
+	ino, ok := k.dirInos[p]
+	if !ok {
+		k.lastIno++
+		ino = k.lastIno
+		k.dirInos[p] = ino
+	}
+	return ino
+}
+
+// stat returns the status of the file `e`, or the directory at `p` if nil.
+// The caller holds mux.
+func (k *kvFS) stat(p string, e *kvEntry) platform.Stat_t {
+	if e == nil {
+		mode := fs.FileMode(0o555)
+		if k.writable {
+			mode = 0o755
+		}
+		return platform.Stat_t{Dev: k.dev, Ino: k.dirIno(p), Mode: fs.ModeDir | mode, Nlink: 1, Atim: k.mtim, Mtim: k.mtim, Ctim: k.mtim}
+	}
+	mode := fs.FileMode(0o444)
+	if k.writable {
+		mode = 0o644
+	}
+	return platform.Stat_t{Dev: k.dev, Ino: e.ino, Mode: mode, Nlink: 1, Size: int64(len(e.data)), Atim: e.mtim, Mtim: e.mtim, Ctim: e.mtim}
+}
+
+// children returns the entries of the directory at the cleaned path `p`,
+// sorted by name. The caller holds mux.
+func (k *kvFS) children(p string) []platform.Dirent {
+	prefix := ""
+	if p != "" && p != "." {
+		prefix = p + "/"
+	}
+	seen := map[string]struct{}{}
+	var dirents []platform.Dirent
+	for entryPath := range k.entries {
+		if !strings.HasPrefix(entryPath, prefix) {
+			continue
+		}
+		name := entryPath[len(prefix):]
+		if i := strings.IndexByte(name, '/'); i != -1 {
+			name = name[:i]
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		if child, ok := k.entries[prefix+name]; ok {
+			dirents = append(dirents, platform.Dirent{Name: name, Ino: child.ino})
+		} else {
+			dirents = append(dirents, platform.Dirent{Name: name, Ino: k.dirIno(prefix + name), Type: fs.ModeDir})
+		}
+	}
+	sort.Slice(dirents, func(i, j int) bool { return dirents[i].Name < dirents[j].Name })
+	return dirents
+}
+
+// resize sets the size of the file `e`, copying its data from the map if not
+// yet owned. The caller holds mux, and checks `size` isn't over
+// platform.MaxMemoryFileSize.
+func (k *kvFS) resize(e *kvEntry, size int64) {
+	if !e.owned {
+		e.data, e.owned = append([]byte(nil), e.data...), true
+	}
+	if size <= int64(len(e.data)) {
+		e.data = e.data[:size]
+	} else if size <= int64(cap(e.data)) {
+		tail := e.data[len(e.data):size]
+		for i := range tail {
+			tail[i] = 0 // in case it was truncated before
+		}
+		e.data = e.data[:size]
+	} else {
+		e.data = append(e.data, make([]byte, size-int64(len(e.data)))...)
+	}
+	k.changed(e)
+}
+
+// changed stores the data of `e` in the map, unless it was removed. The
+// caller holds mux.
+func (k *kvFS) changed(e *kvEntry) {
+	e.mtim = time.Now().UnixNano()
+	if k.entries[e.path] == e {
+		k.kv[e.key] = e.data
+	}
+}
+
+// String implements fmt.Stringer
+func (k *kvFS) String() string {
+	return "kv"
+}
+
+// OpenFile implements FS.OpenFile
+func (k *kvFS) OpenFile(p string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	accessMode := flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
+	if !k.writable && (accessMode != os.O_RDONLY || flag&(os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0) {
+		return nil, syscall.EROFS
+	}
+
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	p = cleanPath(p)
+	e, errno := k.lookup(p)
+	switch {
+	case errno == syscall.ENOENT && flag&os.O_CREATE != 0:
+		if flag&platform.O_DIRECTORY != 0 {
+			return nil, syscall.EINVAL // use Mkdir instead
+		}
+		k.lastIno++
+		e = &kvEntry{ino: k.lastIno, path: p, key: p, data: []byte{}, owned: true}
+		k.entries[p] = e
+		k.changed(e)
+	case errno != 0:
+		return nil, errno
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, syscall.EEXIST
+	case e == nil && accessMode != os.O_RDONLY:
+		return nil, syscall.EISDIR
+	case e == nil:
+		return &kvDir{fs: k, path: p}, 0
+	case flag&platform.O_DIRECTORY != 0:
+		return nil, syscall.ENOTDIR
+	case flag&os.O_TRUNC != 0 && accessMode != os.O_RDONLY:
+		k.resize(e, 0)
+	}
+	return &kvFile{fs: k, e: e, accessMode: accessMode, append: flag&os.O_APPEND != 0}, 0
+}
+
+// Lstat implements FS.Lstat
+func (k *kvFS) Lstat(p string) (platform.Stat_t, syscall.Errno) {
+	return k.Stat(p)
+}
+
+// Stat implements FS.Stat
+func (k *kvFS) Stat(p string) (platform.Stat_t, syscall.Errno) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	p = cleanPath(p)
+	e, errno := k.lookup(p)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return k.stat(p, e), 0
+}
+
+// Rename implements FS.Rename
+func (k *kvFS) Rename(from, to string) syscall.Errno {
+	if !k.writable {
+		return syscall.EROFS
+	}
+
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	from, to = cleanPath(from), cleanPath(to)
+	e, errno := k.lookup(from)
+	if errno != 0 {
+		return errno
+	} else if e == nil {
+		return syscall.ENOSYS // renaming a directory would rename each key
+	}
+	if target, errno := k.lookup(to); errno == 0 && target == nil {
+		return syscall.EISDIR
+	} else if errno != 0 && errno != syscall.ENOENT {
+		return errno
+	} else if target == e {
+		return 0
+	} else if target != nil {
+		delete(k.kv, target.key)
+	}
+
+	delete(k.entries, from)
+	delete(k.kv, e.key)
+	e.path, e.key = to, to
+	k.entries[to] = e
+	k.kv[to] = e.data
+	return 0
+}
+
+// Rmdir implements FS.Rmdir
+func (k *kvFS) Rmdir(p string) syscall.Errno {
+	if !k.writable {
+		return syscall.EROFS
+	}
+
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	p = cleanPath(p)
+	if e, errno := k.lookup(p); errno != 0 {
+		return errno
+	} else if e != nil {
+		return syscall.ENOTDIR
+	}
+	// Directories are implied by the files in them, so aren't empty.
+	return syscall.ENOTEMPTY
+}
+
+// Unlink implements FS.Unlink
+func (k *kvFS) Unlink(p string) syscall.Errno {
+	if !k.writable {
+		return syscall.EROFS
+	}
+
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	p = cleanPath(p)
+	if e, errno := k.lookup(p); errno != 0 {
+		return errno
+	} else if e == nil {
+		return syscall.EISDIR
+	} else {
+		delete(k.entries, p)
+		delete(k.kv, e.key)
+		return 0
+	}
+}
+
+// Readlink implements FS.Readlink
+func (k *kvFS) Readlink(p string) (string, syscall.Errno) {
+	if _, errno := k.Stat(p); errno != 0 {
+		return "", errno
+	}
+	return "", syscall.EINVAL // not a symbolic link
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (k *kvFS) ReadlinkInto(p string, buf []byte) (int, syscall.Errno) {
+	if _, errno := k.Stat(p); errno != 0 {
+		return 0, errno
+	}
+	return 0, syscall.EINVAL // not a symbolic link
+}
+
+// Truncate implements FS.Truncate
+func (k *kvFS) Truncate(p string, size int64) syscall.Errno {
+	if !k.writable {
+		return syscall.EROFS
+	} else if size < 0 {
+		return syscall.EINVAL
+	} else if size > platform.MaxMemoryFileSize {
+		return syscall.EFBIG
+	}
+
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	if e, errno := k.lookup(cleanPath(p)); errno != 0 {
+		return errno
+	} else if e == nil {
+		return syscall.EISDIR
+	} else {
+		k.resize(e, size)
+		return 0
+	}
+}
+
+// compile-time check to ensure kvFile implements platform.File.
+var _ platform.File = (*kvFile)(nil)
+
+// kvFile is a file of a kvFS. Open files share the entry, so a write through
+// one is visible to reads through the others.
+type kvFile struct {
+	platform.UnimplementedFile
+
+	fs         *kvFS
+	e          *kvEntry
+	accessMode int
+	append     bool
+	offset     int64
+	closed     bool
+}
+
+// Path implements the same method as documented on platform.File
+func (f *kvFile) Path() string {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	return f.e.path
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *kvFile) AccessMode() int {
+	return f.accessMode
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *kvFile) Stat() (platform.Stat_t, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return f.fs.stat(f.e.path, f.e), 0
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *kvFile) IsDir() (bool, syscall.Errno) {
+	return false, 0
+}
+
+// Read implements the same method as documented on platform.File
+func (f *kvFile) Read(buf []byte) (int, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	n, errno := f.pread(buf, f.offset)
+	f.offset += int64(n)
+	return n, errno
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *kvFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	return f.pread(buf, off)
+}
+
+func (f *kvFile) pread(buf []byte, off int64) (int, syscall.Errno) {
+	if f.closed || f.accessMode == os.O_WRONLY {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if off >= int64(len(f.e.data)) {
+		return 0, 0
+	}
+	return copy(buf, f.e.data[off:]), 0
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *kvFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.e.data))
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *kvFile) Readdir(int) ([]platform.Dirent, syscall.Errno) {
+	return nil, syscall.ENOTDIR
+}
+
+// Write implements the same method as documented on platform.File
+func (f *kvFile) Write(buf []byte) (int, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.append {
+		f.offset = int64(len(f.e.data))
+	}
+	n, errno := f.pwrite(buf, f.offset)
+	f.offset += int64(n)
+	return n, errno
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *kvFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	for _, buf := range bufs {
+		var written int
+		written, errno = f.Write(buf)
+		n += written
+		if errno != 0 {
+			return
+		}
+	}
+	return
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *kvFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	return f.pwrite(buf, off)
+}
+
+func (f *kvFile) pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	if f.closed || f.accessMode == os.O_RDONLY {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(buf) == 0 {
+		return 0, 0
+	} else if off > platform.MaxMemoryFileSize-int64(len(buf)) {
+		return 0, syscall.EFBIG
+	}
+	size := int64(len(f.e.data))
+	if end := off + int64(len(buf)); end > size {
+		size = end
+	}
+	f.fs.resize(f.e, size) // copies the data before its first write
+	return copy(f.e.data[off:], buf), 0
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *kvFile) Truncate(size int64) syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed || f.accessMode == os.O_RDONLY {
+		return syscall.EBADF
+	} else if size < 0 {
+		return syscall.EINVAL
+	} else if size > platform.MaxMemoryFileSize {
+		return syscall.EFBIG
+	}
+	f.fs.resize(f.e, size)
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *kvFile) Sync() syscall.Errno {
+	return 0 // the map is always in sync
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *kvFile) Datasync() syscall.Errno {
+	return 0
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *kvFile) PollRead(*time.Duration) (bool, syscall.Errno) {
+	return true, 0 // memory is always readable
+}
+
+// Close implements the same method as documented on platform.File
+func (f *kvFile) Close() syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	f.closed = true
+	return 0
+}
+
+// compile-time check to ensure kvDir implements platform.File.
+var _ platform.File = (*kvDir)(nil)
+
+// kvDir is a directory of a kvFS, implied by the keys in it.
+type kvDir struct {
+	platform.DirFile
+
+	fs   *kvFS
+	path string
+	// dirents are the entries, or nil if not yet read.
+	dirents []platform.Dirent
+	pos     int
+	closed  bool
+}
+
+// Path implements the same method as documented on platform.File
+func (d *kvDir) Path() string {
+	return d.path
+}
+
+// Stat implements the same method as documented on platform.File
+func (d *kvDir) Stat() (platform.Stat_t, syscall.Errno) {
+	d.fs.mux.Lock()
+	defer d.fs.mux.Unlock()
+
+	if d.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return d.fs.stat(d.path, nil), 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (d *kvDir) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	d.fs.mux.Lock()
+	defer d.fs.mux.Unlock()
+
+	if d.closed {
+		return nil, syscall.EBADF
+	} else if d.dirents == nil {
+		d.dirents = append([]platform.Dirent{}, d.fs.children(d.path)...)
+	}
+	remaining := d.dirents[d.pos:]
+	if n <= 0 || n > len(remaining) {
+		n = len(remaining)
+	}
+	d.pos += n
+	return append([]platform.Dirent(nil), remaining[:n]...), 0
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (d *kvDir) RewindDir() syscall.Errno {
+	d.fs.mux.Lock()
+	defer d.fs.mux.Unlock()
+
+	if d.closed {
+		return syscall.EBADF
+	}
+	d.dirents, d.pos = nil, 0
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (d *kvDir) Sync() syscall.Errno {
+	return 0
+}
+
+// Datasync implements the same method as documented on platform.File
+func (d *kvDir) Datasync() syscall.Errno {
+	return 0
+}
+
+// Chmod implements the same method as documented on platform.File
+func (d *kvDir) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (d *kvDir) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (d *kvDir) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Close implements the same method as documented on platform.File
+func (d *kvDir) Close() syscall.Errno {
+	d.fs.mux.Lock()
+	defer d.fs.mux.Unlock()
+
+	d.closed = true
+	return 0
+}
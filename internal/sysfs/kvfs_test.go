@@ -0,0 +1,184 @@
+package sysfs
+
+import (
+	"io/fs"
+	"math"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestKVFS(t *testing.T) {
+	kv := map[string][]byte{
+		"/etc/app.json":    []byte(`{"wazero":true}`),
+		"etc/conf.d/a.ini": []byte("a=1"),
+		"README":           []byte("wazero"),
+	}
+	testFS := NewKVFS(kv)
+	require.Equal(t, "kv", testFS.String())
+
+	t.Run("reads files", func(t *testing.T) {
+		f, errno := testFS.OpenFile("etc/app.json", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		buf := make([]byte, 32)
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, `{"wazero":true}`, string(buf[:n]))
+
+		st, errno := f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.FileMode(0o444), st.Mode)
+		require.Equal(t, int64(n), st.Size)
+	})
+
+	t.Run("directories are implied by keys", func(t *testing.T) {
+		st, errno := testFS.Stat("etc/conf.d")
+		require.EqualErrno(t, 0, errno)
+		require.True(t, st.Mode.IsDir())
+
+		requireKVNames(t, testFS, ".", "README", "etc")
+		requireKVNames(t, testFS, "etc", "app.json", "conf.d")
+		requireKVNames(t, testFS, "etc/conf.d", "a.ini")
+
+		_, errno = testFS.OpenFile("etc", os.O_RDONLY|platform.O_DIRECTORY, 0)
+		require.EqualErrno(t, 0, errno)
+		_, errno = testFS.OpenFile("README", os.O_RDONLY|platform.O_DIRECTORY, 0)
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, errno := testFS.Stat("missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		_, errno = testFS.Stat("README/file")
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+	})
+
+	t.Run("EROFS", func(t *testing.T) {
+		_, errno := testFS.OpenFile("README", os.O_RDWR, 0)
+		require.EqualErrno(t, syscall.EROFS, errno)
+		_, errno = testFS.OpenFile("new", os.O_RDONLY|os.O_CREATE, 0o644)
+		require.EqualErrno(t, syscall.EROFS, errno)
+		require.EqualErrno(t, syscall.EROFS, testFS.Unlink("README"))
+		require.EqualErrno(t, syscall.EROFS, testFS.Rename("README", "readme"))
+		require.EqualErrno(t, syscall.EROFS, testFS.Truncate("README", 0))
+		require.EqualErrno(t, syscall.EROFS, testFS.Mkdir("dir", 0o755))
+		require.Equal(t, 3, len(kv))
+	})
+}
+
+func TestKVFS_writable(t *testing.T) {
+	value := []byte("wazero")
+	kv := map[string][]byte{"etc/app.conf": value}
+	testFS := NewKVFS(kv, WithKVWritable())
+
+	t.Run("writes update the map", func(t *testing.T) {
+		f, errno := testFS.OpenFile("etc/app.conf", os.O_RDWR|os.O_APPEND, 0)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("!"))
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		require.Equal(t, "wazero!", string(kv["etc/app.conf"]))
+		require.Equal(t, "wazero", string(value)) // not modified in place
+	})
+
+	t.Run("creates files and their directories", func(t *testing.T) {
+		f, errno := testFS.OpenFile("var/log/app.log", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("started"))
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		require.Equal(t, "started", string(kv["var/log/app.log"]))
+		requireKVNames(t, testFS, ".", "etc", "var")
+
+		_, errno = testFS.OpenFile("var/log/app.log", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		require.EqualErrno(t, syscall.EEXIST, errno)
+	})
+
+	t.Run("truncates", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Truncate("var/log/app.log", 4))
+		require.Equal(t, "star", string(kv["var/log/app.log"]))
+
+		f, errno := testFS.OpenFile("var/log/app.log", os.O_WRONLY|os.O_TRUNC, 0)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+		require.Equal(t, "", string(kv["var/log/app.log"]))
+	})
+
+	t.Run("renames", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Rename("etc/app.conf", "etc/app.conf.bak"))
+		_, ok := kv["etc/app.conf"]
+		require.False(t, ok)
+		require.Equal(t, "wazero!", string(kv["etc/app.conf.bak"]))
+
+		require.EqualErrno(t, syscall.ENOSYS, testFS.Rename("etc", "config"))
+		require.EqualErrno(t, syscall.ENOENT, testFS.Rename("missing", "config"))
+	})
+
+	t.Run("unlinks", func(t *testing.T) {
+		f, errno := testFS.OpenFile("etc/app.conf.bak", os.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		require.EqualErrno(t, syscall.EISDIR, testFS.Unlink("etc"))
+		require.EqualErrno(t, syscall.ENOTEMPTY, testFS.Rmdir("etc"))
+		require.EqualErrno(t, 0, testFS.Unlink("etc/app.conf.bak"))
+		_, ok := kv["etc/app.conf.bak"]
+		require.False(t, ok)
+
+		// Writing to a removed file doesn't restore its key.
+		_, errno = f.Write([]byte("lost"))
+		require.EqualErrno(t, 0, errno)
+		_, ok = kv["etc/app.conf.bak"]
+		require.False(t, ok)
+
+		// The directory was implied by the file, so is gone.
+		_, errno = testFS.Stat("etc")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+}
+
+// requireKVNames requires the directory `dir` to have the entries `names`.
+func requireKVNames(t *testing.T, testFS FS, dir string, names ...string) {
+	d, errno := testFS.OpenFile(dir, os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer d.Close()
+
+	dirents, errno := d.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	actual := make([]string, 0, len(dirents))
+	for _, e := range dirents {
+		actual = append(actual, e.Name)
+	}
+	require.Equal(t, names, actual)
+}
+
+func TestKVFS_hugeFile(t *testing.T) {
+	value := []byte("wazero")
+	kv := map[string][]byte{"file": value}
+	testFS := NewKVFS(kv, WithKVWritable())
+
+	require.EqualErrno(t, syscall.EFBIG, testFS.Truncate("file", 1<<62))
+	f, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	require.EqualErrno(t, syscall.EFBIG, f.Truncate(platform.MaxMemoryFileSize+1))
+	_, errno = f.Pwrite([]byte{1}, 1<<62)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+	_, errno = f.Pwrite([]byte{1}, math.MaxInt64)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+
+	require.Equal(t, "wazero", string(kv["file"]))
+
+	// Shrinking then growing reads zeros, without modifying the value passed.
+	require.EqualErrno(t, 0, f.Truncate(2))
+	require.EqualErrno(t, 0, f.Truncate(8))
+	require.Equal(t, "wa\x00\x00\x00\x00\x00\x00", string(kv["file"]))
+	require.Equal(t, "wazero", string(value))
+}
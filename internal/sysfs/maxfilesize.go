@@ -0,0 +1,132 @@
+package sysfs
+
+import (
+	"io"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// WithMaxFileSize limits the size in bytes of files written through an FS
+// returned by NewDirFS. By default, file sizes are unlimited.
+//
+// A Write, Writev, Pwrite or Truncate which would grow a file past
+// `maxFileSize` fails with syscall.EFBIG, without writing anything. For
+// example, this stops a guest from creating a huge sparse file with a
+// Pwrite at a large offset.
+//
+// # Notes
+//
+//   - This is like RLIMIT_FSIZE in POSIX, except it is per FS, and it
+//     doesn't send SIGXFSZ.
+//   - Files already larger than the limit can still be written, as long as
+//     they don't grow.
+//   - Files written through other means, such as platform.File of the host,
+//     aren't limited.
+func WithMaxFileSize(maxFileSize int64) DirFSOption {
+	return func(d *dirFS) {
+		d.maxFileSize = maxFileSize
+	}
+}
+
+// checkFileSize returns syscall.EFBIG if growing a file of `size` bytes to
+// `end` exceeds `maxFileSize`. Zero means no limit.
+func checkFileSize(maxFileSize, size, end int64) syscall.Errno {
+	if maxFileSize > 0 && end > maxFileSize && end > size {
+		return syscall.EFBIG
+	}
+	return 0
+}
+
+// maxFileSizeFile is implemented by WithMaxFileSize.
+type maxFileSizeFile struct {
+	platform.File
+	maxFileSize int64
+
+	// append is true when the file was opened with syscall.O_APPEND, so
+	// writes are at the end of the file, regardless of its offset.
+	append bool
+}
+
+// checkExtent returns syscall.EFBIG if writing up to `end` would grow the
+// file past maxFileSize.
+func (f *maxFileSizeFile) checkExtent(end int64) syscall.Errno {
+	if end <= f.maxFileSize {
+		return 0 // skip the stat
+	}
+	st, errno := f.File.Stat()
+	if errno != 0 {
+		return errno
+	}
+	return checkFileSize(f.maxFileSize, st.Size, end)
+}
+
+// checkWrite is checkExtent for a write of `n` bytes at the file offset, or
+// the end of the file if appending.
+func (f *maxFileSizeFile) checkWrite(n int) syscall.Errno {
+	if n == 0 {
+		return 0
+	} else if f.append {
+		st, errno := f.File.Stat()
+		if errno != 0 {
+			return errno
+		}
+		return checkFileSize(f.maxFileSize, st.Size, st.Size+int64(n))
+	}
+
+	switch offset, errno := f.File.Seek(0, io.SeekCurrent); errno {
+	case 0:
+		return f.checkExtent(offset + int64(n))
+	case syscall.ESPIPE:
+		return 0 // a pipe or socket has no size to limit
+	default:
+		return errno
+	}
+}
+
+// Write implements the same method as documented on platform.File
+func (f *maxFileSizeFile) Write(buf []byte) (int, syscall.Errno) {
+	if errno := f.checkWrite(len(buf)); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Write(buf)
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *maxFileSizeFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	n := 0
+	for _, buf := range bufs {
+		n += len(buf)
+	}
+	if errno := f.checkWrite(n); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Writev(bufs)
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *maxFileSizeFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	if len(buf) > 0 {
+		if errno := f.checkExtent(off + int64(len(buf))); errno != 0 {
+			return 0, errno
+		}
+	}
+	return f.File.Pwrite(buf, off)
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *maxFileSizeFile) Truncate(size int64) syscall.Errno {
+	if errno := f.checkExtent(size); errno != 0 {
+		return errno
+	}
+	return f.File.Truncate(size)
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *maxFileSizeFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &maxFileSizeFile{File: dup, maxFileSize: f.maxFileSize, append: f.append}, 0
+}
@@ -0,0 +1,90 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithMaxFileSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir, WithMaxFileSize(10))
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	t.Run("within the limit", func(t *testing.T) {
+		_, errno := f.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Writev([][]byte{[]byte("wa"), []byte("ze")})
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Pwrite([]byte("ro"), 8)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Truncate(10))
+		require.EqualErrno(t, 0, testFS.Truncate("file", 10))
+	})
+
+	t.Run("beyond the limit", func(t *testing.T) {
+		n, errno := f.Write([]byte("!"))
+		require.EqualErrno(t, syscall.EFBIG, errno)
+		require.Zero(t, n)
+		_, errno = f.Writev([][]byte{[]byte("!")})
+		require.EqualErrno(t, syscall.EFBIG, errno)
+		_, errno = f.Pwrite([]byte("!"), 1<<40)
+		require.EqualErrno(t, syscall.EFBIG, errno)
+		require.EqualErrno(t, syscall.EFBIG, f.Truncate(11))
+		require.EqualErrno(t, syscall.EFBIG, testFS.Truncate("file", 11))
+
+		st, errno := f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(10), st.Size)
+	})
+
+	t.Run("overwrite at the limit", func(t *testing.T) {
+		_, errno := f.Seek(0, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write(make([]byte, 10))
+		require.EqualErrno(t, 0, errno)
+	})
+
+	t.Run("append", func(t *testing.T) {
+		f, errno := testFS.OpenFile("file", os.O_WRONLY|os.O_APPEND, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		// The offset is before the end, but appending still grows the file.
+		_, errno = f.Write([]byte("!"))
+		require.EqualErrno(t, syscall.EFBIG, errno)
+
+		dup, errno := f.Dup()
+		require.EqualErrno(t, 0, errno)
+		defer dup.Close()
+		_, errno = dup.Write([]byte("!"))
+		require.EqualErrno(t, syscall.EFBIG, errno)
+	})
+
+	t.Run("existing larger file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(joinPath(tmpDir, "large"), make([]byte, 20), 0o600))
+		f, errno := testFS.OpenFile("large", os.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		// Writes which don't grow the file are allowed.
+		_, errno = f.Pwrite([]byte("wazero"), 14)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Truncate(15))
+		_, errno = f.Pwrite([]byte("wazero"), 14)
+		require.EqualErrno(t, syscall.EFBIG, errno)
+	})
+
+	// Read-only files aren't wrapped.
+	ro, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer ro.Close()
+	_, ok := ro.(*maxFileSizeFile)
+	require.False(t, ok)
+}
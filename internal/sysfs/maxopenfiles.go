@@ -0,0 +1,91 @@
+package sysfs
+
+import (
+	"sync/atomic"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// WithMaxOpenFiles makes FS.OpenFile of an FS returned by NewDirFS return
+// syscall.EMFILE while `maxOpenFiles` files opened from it are open, like
+// RLIMIT_NOFILE scoped to a mount. Closing a file frees its slot.
+//
+// Note: Each platform.File Dup uses a slot, as each must be closed.
+func WithMaxOpenFiles(maxOpenFiles int) DirFSOption {
+	return func(d *dirFS) {
+		d.openLimit = &openLimit{max: int32(maxOpenFiles)}
+	}
+}
+
+// openLimit counts the open files of an FS, set by WithMaxOpenFiles. A nil
+// pointer has no limit.
+type openLimit struct {
+	max int32
+	// open is the count of open files, accessed atomically.
+	open int32
+}
+
+// acquire reserves a slot for a file about to be opened, or returns
+// syscall.EMFILE if there are none. When the open fails, call release.
+func (l *openLimit) acquire() syscall.Errno {
+	if l == nil {
+		return 0
+	}
+	for {
+		open := atomic.LoadInt32(&l.open)
+		if open >= l.max {
+			return syscall.EMFILE
+		} else if atomic.CompareAndSwapInt32(&l.open, open, open+1) {
+			return 0
+		}
+	}
+}
+
+// release frees a slot reserved by acquire.
+func (l *openLimit) release() {
+	if l != nil {
+		atomic.AddInt32(&l.open, -1)
+	}
+}
+
+// limit returns `f`, opened with a slot from acquire, so that its slot is
+// released on Close, or returns it as-is if there is no limit.
+func (l *openLimit) limit(f platform.File) platform.File {
+	if l == nil {
+		return f
+	}
+	return &limitedFile{File: f, openLimit: l}
+}
+
+// limitedFile is implemented by WithMaxOpenFiles.
+type limitedFile struct {
+	platform.File
+	openLimit *openLimit
+	// closed is non-zero once Close was called, accessed atomically, so
+	// that the slot is released once.
+	closed int32
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *limitedFile) Dup() (platform.File, syscall.Errno) {
+	if errno := f.openLimit.acquire(); errno != 0 {
+		return nil, errno
+	}
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		f.openLimit.release()
+		return nil, errno
+	}
+	return f.openLimit.limit(dup), 0
+}
+
+// Close implements the same method as documented on platform.File
+//
+// The slot is released even if this fails, as the file can't be used after.
+func (f *limitedFile) Close() syscall.Errno {
+	if atomic.CompareAndSwapInt32(&f.closed, 0, 1) {
+		f.openLimit.release()
+	}
+	return f.File.Close()
+}
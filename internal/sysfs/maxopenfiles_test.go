@@ -0,0 +1,92 @@
+package sysfs
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithMaxOpenFiles(t *testing.T) {
+	testFS := NewDirFS(t.TempDir(), WithMaxOpenFiles(2))
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", []byte("wazero"), 0o600))
+
+	f1, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	f2, errno := testFS.OpenFile(".", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+
+	// The next open fails, including a Dup.
+	_, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EMFILE, errno)
+	_, errno = f1.Dup()
+	require.EqualErrno(t, syscall.EMFILE, errno)
+
+	// Closing frees a slot, but only once.
+	require.EqualErrno(t, 0, f1.Close())
+	require.EqualErrno(t, syscall.EBADF, f1.Close())
+	f3, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EMFILE, errno)
+
+	// Opening relative to a directory also uses a slot.
+	_, errno = OpenFileAt(testFS, f2, "file", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EMFILE, errno)
+	require.EqualErrno(t, 0, f3.Close())
+	f3, errno = OpenFileAt(testFS, f2, "file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f3.Close())
+	require.EqualErrno(t, 0, f2.Close())
+}
+
+func TestWithMaxOpenFiles_failedOpen(t *testing.T) {
+	testFS := NewDirFS(t.TempDir(), WithMaxOpenFiles(1))
+
+	// Failed opens don't use a slot.
+	for i := 0; i < 3; i++ {
+		_, errno := testFS.OpenFile("missing", os.O_RDONLY, 0)
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		_, errno = testFS.OpenFile(".", os.O_RDWR, 0)
+		require.EqualErrno(t, syscall.EISDIR, errno)
+	}
+	f, errno := testFS.OpenFile(".", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+}
+
+func TestWithMaxOpenFiles_concurrent(t *testing.T) {
+	const max, goroutines = 4, 16
+	testFS := NewDirFS(t.TempDir(), WithMaxOpenFiles(max))
+	require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", nil, 0o600))
+
+	// Each goroutine holds its files open until all have tried, so no more
+	// than max can succeed.
+	var opened sync.WaitGroup
+	var mux sync.Mutex
+	var files []platform.File
+	opened.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer opened.Done()
+			f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+			if errno == 0 {
+				mux.Lock()
+				files = append(files, f)
+				mux.Unlock()
+			}
+		}()
+	}
+	opened.Wait()
+	require.Equal(t, max, len(files))
+
+	for _, f := range files {
+		require.EqualErrno(t, 0, f.Close())
+	}
+	f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+}
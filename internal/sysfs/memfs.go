@@ -0,0 +1,1229 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// maxSymlinks is the count of symbolic links followed before a path fails
+// with syscall.ELOOP. This is the same as Linux.
+const maxSymlinks = 40
+
+// NewMemFS returns a writable FS which keeps its files in memory, like tmpfs.
+// This is for guests which need scratch space, without access to the host.
+//
+// Open files behave like POSIX file descriptors: Each OpenFile has its own
+// offset, even for the same path, while a platform.File Dup shares the offset
+// and directory position of the file it duplicates. All share the contents,
+// so a write through one is visible to reads through the others.
+//
+// Directory entries are read sorted by name, in byte order.
+func NewMemFS(opts ...MemFSOption) FS {
+	return NewLimitedMemFS(0, 0, opts...)
+}
+
+// NewLimitedMemFS is like NewMemFS, except it limits the count of files and
+// the bytes they contain. Zero means no limit.
+//
+// # Limits
+//
+//   - `maxFiles` is the count of files, directories and symbolic links.
+//     Creating another fails with syscall.ENOSPC, even if bytes remain. Like
+//     the inode limit of tmpfs, hard links and the root directory don't
+//     count.
+//   - `maxBytes` is the total size of all files. A write which would exceed
+//     it writes what fits, then fails with syscall.ENOSPC.
+//
+// Removing a file frees its slot once it has no hard links. Like tmpfs, its
+// bytes are freed only when it is also closed, so that a file which is open
+// but removed can't grow past `maxBytes`.
+func NewLimitedMemFS(maxFiles int, maxBytes int64, opts ...MemFSOption) FS {
+	m := &memFS{maxFiles: maxFiles, maxBytes: maxBytes, dev: syntheticDev()}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.root = m.newNode(fs.ModeDir | 0o777)
+	m.root.nlink = 2
+	return m
+}
+
+// MemFSOption configures an FS returned by NewMemFS or NewLimitedMemFS.
+type MemFSOption func(*memFS)
+
+type memFS struct {
+	UnimplementedFS
+	maxFiles int
+	maxBytes int64
+	dev      uint64
+	// atime is set by WithMemAtime.
+	atime AtimePolicy
+	// openFiles is set by WithMemOpenFileTracking.
+	openFiles *openFiles
+	// firstOpen is set by WithMemOnFirstOpen.
+	firstOpen *firstOpen
+
+	// mux guards the below fields, and all fields of memNode.
+	mux     sync.Mutex
+	root    *memNode
+	files   int
+	bytes   int64
+	lastIno uint64
+	// inodes are the linked nodes by inode, for OpenByID.
+	inodes map[uint64]*memNode
+}
+
+// memNode is the equivalent of an inode.
+type memNode struct {
+	ino              uint64
+	mode             fs.FileMode
+	uid, gid         uint32
+	nlink            uint64
+	atim, mtim, ctim int64
+	// opens is the count of open files of the node, including duplicates.
+	opens int
+
+	// data is the content of a regular file.
+	data []byte
+	// target is the destination of a symbolic link.
+	target string
+	// children are the entries of a directory.
+	children map[string]*memNode
+	// gen is incremented when children change, so that open directories
+	// know to re-read them.
+	gen uint64
+}
+
+func (n *memNode) isDir() bool {
+	return n.mode.IsDir()
+}
+
+func (n *memNode) isSymlink() bool {
+	return n.mode&fs.ModeSymlink != 0
+}
+
+// stat returns the status of the node, on the device `dev` of its memFS.
+func (n *memNode) stat(dev uint64) platform.Stat_t {
+	st := platform.Stat_t{
+		Dev:   dev,
+		Ino:   n.ino,
+		Uid:   n.uid,
+		Gid:   n.gid,
+		Mode:  n.mode,
+		Nlink: n.nlink,
+		Size:  int64(len(n.data)),
+		Atim:  n.atim,
+		Mtim:  n.mtim,
+		Ctim:  n.ctim,
+	}
+	if n.isSymlink() {
+		st.Size = int64(len(n.target))
+	}
+	return st
+}
+
+// newNode returns a node with the next inode, which isn't linked yet.
+func (m *memFS) newNode(mode fs.FileMode) *memNode {
+	m.lastIno++
+	now := time.Now().UnixNano()
+	n := &memNode{ino: m.lastIno, mode: mode, nlink: 1, atim: now, mtim: now, ctim: now}
+	if mode.IsDir() {
+		n.children = map[string]*memNode{}
+	}
+	if m.inodes == nil {
+		m.inodes = map[uint64]*memNode{}
+	}
+	m.inodes[n.ino] = n
+	return n
+}
+
+// create links a new node named `name` in `dir`, or returns syscall.ENOSPC
+// if there are already maxFiles.
+func (m *memFS) create(dir *memNode, name string, mode fs.FileMode) (*memNode, syscall.Errno) {
+	if m.maxFiles > 0 && m.files >= m.maxFiles {
+		return nil, syscall.ENOSPC
+	}
+	m.files++
+	n := m.newNode(mode)
+	if n.isDir() {
+		n.nlink = 2 // "." and the entry in dir
+		dir.nlink++ // ".." of n
+	}
+	dir.children[name] = n
+	dir.gen++
+	dir.mtim = n.mtim
+	dir.ctim = n.mtim
+	return n, 0
+}
+
+// remove unlinks `name` from `dir`, freeing its slot if it has no more links,
+// and its bytes if it isn't open either.
+func (m *memFS) remove(dir *memNode, name string) {
+	n := dir.children[name]
+	delete(dir.children, name)
+	dir.gen++
+	now := time.Now().UnixNano()
+	dir.mtim, dir.ctim, n.ctim = now, now, now
+	if n.isDir() {
+		dir.nlink--
+		n.nlink = 0
+	} else {
+		n.nlink--
+	}
+	if n.nlink == 0 {
+		delete(m.inodes, n.ino)
+		m.files--
+		if n.opens == 0 {
+			m.bytes -= int64(len(n.data))
+		}
+	}
+}
+
+// closeNode releases an open file of `n`, freeing its bytes if it was the
+// last, and `n` has no more links. The caller holds mux.
+func (m *memFS) closeNode(n *memNode) {
+	n.opens--
+	if n.opens == 0 && n.nlink == 0 {
+		m.bytes -= int64(len(n.data))
+	}
+}
+
+// resize sets the size of the file `n`, or returns syscall.ENOSPC if that
+// would exceed maxBytes, after growing as much as possible. A size larger
+// than platform.MaxMemoryFileSize fails with syscall.EFBIG, without growing.
+func (m *memFS) resize(n *memNode, size int64) syscall.Errno {
+	if size > platform.MaxMemoryFileSize {
+		return syscall.EFBIG
+	}
+	grow := size - int64(len(n.data))
+	var errno syscall.Errno
+	if m.maxBytes > 0 && grow > 0 && m.bytes+grow > m.maxBytes {
+		grow = m.maxBytes - m.bytes
+		size = int64(len(n.data)) + grow
+		errno = syscall.ENOSPC
+	}
+	if size <= int64(len(n.data)) {
+		n.data = n.data[:size]
+	} else if size <= int64(cap(n.data)) {
+		tail := n.data[len(n.data):size]
+		for i := range tail {
+			tail[i] = 0 // in case it was truncated before
+		}
+		n.data = n.data[:size]
+	} else {
+		n.data = append(n.data, make([]byte, size-int64(len(n.data)))...)
+	}
+	m.bytes += grow
+	if grow != 0 {
+		n.mtim = time.Now().UnixNano()
+		n.ctim = n.mtim
+	}
+	return errno
+}
+
+// walk resolves `p`, returning its parent directory and base name, and the
+// node, which is nil if it doesn't exist. Symbolic links in the parent
+// directories are followed, and so is the last, when `follow` is true.
+//
+// The parent is nil when `p` resolves to the root, or a directory resolved
+// via "..", as those can't be replaced.
+func (m *memFS) walk(p string, follow bool) (parent *memNode, name string, n *memNode, errno syscall.Errno) {
+	if errno = validatePath(p, DefaultMaxPathLen); errno != 0 {
+		return
+	}
+	links := 0
+	names := splitPath(p)
+	dirs := []*memNode{m.root} // the directories resolved, for ".."
+	for len(names) > 0 {
+		dir := dirs[len(dirs)-1]
+		name, names = names[0], names[1:]
+		if !dir.isDir() {
+			return nil, "", nil, syscall.ENOTDIR
+		} else if name == ".." {
+			if len(dirs) > 1 {
+				dirs = dirs[:len(dirs)-1]
+			}
+			continue
+		}
+
+		n = dir.children[name]
+		last := len(names) == 0
+		if n != nil && n.isSymlink() && (!last || follow) {
+			if links++; links > maxSymlinks {
+				return nil, "", nil, syscall.ELOOP
+			} else if path.IsAbs(n.target) {
+				dirs = dirs[:1]
+			}
+			names = append(splitPath(n.target), names...)
+			continue
+		} else if last {
+			return dir, name, n, 0
+		} else if n == nil {
+			return nil, "", nil, syscall.ENOENT
+		}
+		dirs = append(dirs, n)
+	}
+	return nil, "", dirs[len(dirs)-1], 0
+}
+
+// splitPath returns the names in the cleaned path, or none for the root.
+func splitPath(p string) []string {
+	p = cleanPath(p)
+	if p == "" || p == "." || p == "/" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// lookup returns the node at `p`, or syscall.ENOENT if it doesn't exist.
+func (m *memFS) lookup(p string, follow bool) (*memNode, syscall.Errno) {
+	if _, _, n, errno := m.walk(p, follow); errno != 0 {
+		return nil, errno
+	} else if n == nil {
+		return nil, syscall.ENOENT
+	} else {
+		return n, 0
+	}
+}
+
+// String implements fmt.Stringer
+func (m *memFS) String() string {
+	return "mem:/"
+}
+
+// OpenFile implements FS.OpenFile
+func (m *memFS) OpenFile(p string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	f, errno := m.openFile(p, flag, perm)
+	if errno == 0 {
+		// Outside the lock, so that the callback can use this FS.
+		m.firstOpen.opened(p)
+	}
+	return f, errno
+}
+
+func (m *memFS) openFile(p string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	dir, name, n, errno := m.walk(p, flag&platform.O_NOFOLLOW == 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	switch {
+	case n == nil && flag&os.O_CREATE == 0:
+		return nil, syscall.ENOENT
+	case n == nil:
+		if flag&platform.O_DIRECTORY != 0 {
+			return nil, syscall.EINVAL // use Mkdir instead
+		} else if n, errno = m.create(dir, name, perm.Perm()); errno != 0 {
+			return nil, errno
+		}
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, syscall.EEXIST
+	}
+	return m.openNode(n, p, flag)
+}
+
+// openNode opens the existing node `n` as `p`. The caller holds mux.
+func (m *memFS) openNode(n *memNode, p string, flag int) (platform.File, syscall.Errno) {
+	accessMode := flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
+	switch {
+	case n.isSymlink():
+		return nil, syscall.ELOOP // O_NOFOLLOW
+	case flag&platform.O_DIRECTORY != 0 && !n.isDir():
+		return nil, syscall.ENOTDIR
+	case n.isDir() && accessMode != os.O_RDONLY:
+		return nil, syscall.EISDIR
+	case flag&os.O_TRUNC != 0 && accessMode != os.O_RDONLY:
+		_ = m.resize(n, 0) // shrinking can't fail
+	}
+
+	n.opens++
+	f := &memFile{fs: m, n: n, path: p, accessMode: accessMode, append: flag&os.O_APPEND != 0, memOpenFile: &memOpenFile{}}
+	return m.openFiles.track(f), 0
+}
+
+// OpenByID implements FS.OpenByID
+//
+// The device is zero, as in platform.Stat_t of this file system.
+func (m *memFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	if flag&(os.O_CREATE|os.O_EXCL) != 0 {
+		return nil, syscall.EINVAL
+	} else if dev != m.dev {
+		return nil, syscall.EXDEV
+	}
+	f, p, errno := m.openByID(ino, flag)
+	if errno == 0 {
+		m.firstOpen.opened(p)
+	}
+	return f, errno
+}
+
+func (m *memFS) openByID(ino uint64, flag int) (platform.File, string, syscall.Errno) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, ok := m.inodes[ino]
+	if !ok {
+		return nil, "", syscall.ENOENT
+	}
+	p, _ := m.pathOf(m.root, ".", n)
+	f, errno := m.openNode(n, p, flag)
+	return f, p, errno
+}
+
+// pathOf returns the first path of `n` under the directory `dir` at `p`, in
+// name order, or false if it isn't linked there. The caller holds mux.
+func (m *memFS) pathOf(dir *memNode, p string, n *memNode) (string, bool) {
+	if dir == n {
+		return p, true
+	}
+	names := make([]string, 0, len(dir.children))
+	for name := range dir.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := dir.children[name]
+		if child == n {
+			return path.Join(p, name), true
+		} else if child.isDir() {
+			if found, ok := m.pathOf(child, path.Join(p, name), n); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ResetFirstOpen implements FirstOpenFS.ResetFirstOpen
+func (m *memFS) ResetFirstOpen() {
+	m.firstOpen.reset()
+}
+
+// OpenFiles implements OpenFilesFS.OpenFiles
+func (m *memFS) OpenFiles() []OpenFileInfo {
+	return m.openFiles.list()
+}
+
+// Lstat implements FS.Lstat
+func (m *memFS) Lstat(p string) (platform.Stat_t, syscall.Errno) {
+	return m.statPath(p, false)
+}
+
+// Stat implements FS.Stat
+func (m *memFS) Stat(p string) (platform.Stat_t, syscall.Errno) {
+	return m.statPath(p, true)
+}
+
+func (m *memFS) statPath(p string, follow bool) (platform.Stat_t, syscall.Errno) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(p, follow)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return n.stat(m.dev), 0
+}
+
+// Mkdir implements FS.Mkdir
+func (m *memFS) Mkdir(p string, perm fs.FileMode) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	dir, name, n, errno := m.walk(p, false)
+	if errno != 0 {
+		return errno
+	} else if n != nil {
+		return syscall.EEXIST
+	}
+	_, errno = m.create(dir, name, fs.ModeDir|perm.Perm())
+	return errno
+}
+
+// Chmod implements FS.Chmod
+func (m *memFS) Chmod(p string, perm fs.FileMode) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(p, true)
+	if errno != 0 {
+		return errno
+	}
+	n.chmod(perm)
+	return 0
+}
+
+func (n *memNode) chmod(perm fs.FileMode) {
+	n.mode = n.mode.Type() | perm.Perm()
+	n.ctim = time.Now().UnixNano()
+}
+
+// Chown implements FS.Chown
+func (m *memFS) Chown(p string, uid, gid int) syscall.Errno {
+	return m.chown(p, uid, gid, true)
+}
+
+// Lchown implements FS.Lchown
+func (m *memFS) Lchown(p string, uid, gid int) syscall.Errno {
+	return m.chown(p, uid, gid, false)
+}
+
+func (m *memFS) chown(p string, uid, gid int, follow bool) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(p, follow)
+	if errno != 0 {
+		return errno
+	}
+	n.chown(uid, gid)
+	return 0
+}
+
+// chown sets the owner of the node, except for -1 values, like `chown`.
+func (n *memNode) chown(uid, gid int) {
+	if uid != -1 {
+		n.uid = uint32(uid)
+	}
+	if gid != -1 {
+		n.gid = uint32(gid)
+	}
+	n.ctim = time.Now().UnixNano()
+}
+
+// Rename implements FS.Rename
+func (m *memFS) Rename(from, to string) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	fromDir, fromName, n, errno := m.walk(from, false)
+	if errno != 0 {
+		return errno
+	} else if n == nil {
+		return syscall.ENOENT
+	} else if fromDir == nil {
+		return syscall.EBUSY // the root or a parent
+	}
+	toDir, toName, existing, errno := m.walk(to, false)
+	if errno != 0 {
+		return errno
+	} else if toDir == nil {
+		return syscall.EBUSY
+	} else if existing == n {
+		return 0 // same file, e.g. a hard link
+	} else if n.isDir() && m.isAncestor(n, toDir) {
+		return syscall.EINVAL // can't move a directory into itself
+	}
+
+	if existing != nil {
+		switch {
+		case n.isDir() && !existing.isDir():
+			return syscall.ENOTDIR
+		case !n.isDir() && existing.isDir():
+			return syscall.EISDIR
+		case existing.isDir() && len(existing.children) > 0:
+			return syscall.ENOTEMPTY
+		}
+		m.remove(toDir, toName)
+	}
+
+	delete(fromDir.children, fromName)
+	toDir.children[toName] = n
+	fromDir.gen++
+	toDir.gen++
+	if n.isDir() {
+		fromDir.nlink--
+		toDir.nlink++
+	}
+	now := time.Now().UnixNano()
+	fromDir.mtim, fromDir.ctim, toDir.mtim, toDir.ctim, n.ctim = now, now, now, now, now
+	return 0
+}
+
+// isAncestor returns true if `dir` is `n`, or is under it.
+func (m *memFS) isAncestor(n, dir *memNode) bool {
+	if n == dir {
+		return true
+	}
+	for _, child := range n.children {
+		if child.isDir() && m.isAncestor(child, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (m *memFS) ExchangeDir(a, b string) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	aDir, aName, aNode, errno := m.walk(a, false)
+	if errno != 0 {
+		return errno
+	}
+	bDir, bName, bNode, errno := m.walk(b, false)
+	switch {
+	case errno != 0:
+		return errno
+	case aNode == nil || bNode == nil:
+		return syscall.ENOENT
+	case !aNode.isDir() || !bNode.isDir():
+		return syscall.ENOTDIR
+	case aDir == nil || bDir == nil:
+		return syscall.EBUSY
+	case m.isAncestor(aNode, bDir) || m.isAncestor(bNode, aDir):
+		return syscall.EINVAL
+	}
+	aDir.children[aName], bDir.children[bName] = bNode, aNode
+	aDir.gen++
+	bDir.gen++
+	return 0
+}
+
+// Clone implements FS.Clone
+//
+// The data of `src` is copied, so counts twice towards maxBytes.
+func (m *memFS) Clone(src, dst string) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(src, true)
+	if errno != 0 {
+		return errno
+	} else if n.isDir() {
+		return syscall.EISDIR
+	}
+	dir, name, existing, errno := m.walk(dst, false)
+	switch {
+	case errno != 0:
+		return errno
+	case existing != nil:
+		return syscall.EEXIST
+	case m.maxBytes > 0 && m.bytes+int64(len(n.data)) > m.maxBytes:
+		return syscall.ENOSPC
+	}
+	clone, errno := m.create(dir, name, n.mode.Perm())
+	if errno != 0 {
+		return errno
+	}
+	clone.data = append([]byte(nil), n.data...)
+	m.bytes += int64(len(clone.data))
+	return 0
+}
+
+// Rmdir implements FS.Rmdir
+func (m *memFS) Rmdir(p string) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	dir, name, n, errno := m.walk(p, false)
+	switch {
+	case errno != 0:
+		return errno
+	case n == nil:
+		return syscall.ENOENT
+	case !n.isDir():
+		return syscall.ENOTDIR
+	case len(n.children) > 0:
+		return syscall.ENOTEMPTY
+	case dir == nil:
+		return syscall.EBUSY // the root or a parent
+	}
+	m.remove(dir, name)
+	return 0
+}
+
+// Unlink implements FS.Unlink
+func (m *memFS) Unlink(p string) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	dir, name, n, errno := m.walk(p, false)
+	switch {
+	case errno != 0:
+		return errno
+	case n == nil:
+		return syscall.ENOENT
+	case n.isDir():
+		return syscall.EISDIR
+	}
+	m.remove(dir, name)
+	return 0
+}
+
+// Link implements FS.Link
+func (m *memFS) Link(oldPath, newPath string) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(oldPath, false)
+	if errno != 0 {
+		return errno
+	} else if n.isDir() {
+		return syscall.EPERM
+	}
+	dir, name, existing, errno := m.walk(newPath, false)
+	if errno != 0 {
+		return errno
+	} else if existing != nil {
+		return syscall.EEXIST
+	}
+	dir.children[name] = n
+	dir.gen++
+	n.nlink++
+	n.ctim = time.Now().UnixNano()
+	dir.mtim, dir.ctim = n.ctim, n.ctim
+	return 0
+}
+
+// Symlink implements FS.Symlink
+func (m *memFS) Symlink(oldPath, linkName string) syscall.Errno {
+	if errno := validatePath(oldPath, DefaultMaxPathLen); errno != 0 {
+		return errno
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	dir, name, existing, errno := m.walk(linkName, false)
+	if errno != 0 {
+		return errno
+	} else if existing != nil {
+		return syscall.EEXIST
+	}
+	n, errno := m.create(dir, name, fs.ModeSymlink|0o777)
+	if errno != 0 {
+		return errno
+	}
+	n.target = oldPath
+	return 0
+}
+
+// Readlink implements FS.Readlink
+func (m *memFS) Readlink(p string) (string, syscall.Errno) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(p, false)
+	if errno != 0 {
+		return "", errno
+	} else if !n.isSymlink() {
+		return "", syscall.EINVAL
+	}
+	return n.target, 0
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (m *memFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(path, false)
+	if errno != 0 {
+		return 0, errno
+	} else if !n.isSymlink() {
+		return 0, syscall.EINVAL
+	}
+	return copy(buf, n.target), 0
+}
+
+// Truncate implements FS.Truncate
+func (m *memFS) Truncate(p string, size int64) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(p, true)
+	if errno != 0 {
+		return errno
+	}
+	return m.truncate(n, size)
+}
+
+func (m *memFS) truncate(n *memNode, size int64) syscall.Errno {
+	if n.isDir() {
+		return syscall.EISDIR
+	} else if size < 0 {
+		return syscall.EINVAL
+	}
+	return m.resize(n, size)
+}
+
+// Utimens implements FS.Utimens
+func (m *memFS) Utimens(p string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, errno := m.lookup(p, symlinkFollow)
+	if errno != 0 {
+		return errno
+	}
+	n.utimens(times)
+	return 0
+}
+
+// SyncDir implements FS.SyncDir
+//
+// This only checks `p` is a directory, as there's no storage to sync.
+func (m *memFS) SyncDir(p string) syscall.Errno {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if n, errno := m.lookup(p, true); errno != 0 {
+		return errno
+	} else if !n.isDir() {
+		return syscall.ENOTDIR
+	}
+	return 0
+}
+
+// utimens sets the access and modification times, like `utimensat`.
+func (n *memNode) utimens(times *[2]syscall.Timespec) {
+	now := time.Now().UnixNano()
+	if times == nil {
+		n.atim, n.mtim = now, now
+	} else {
+		n.atim = timespecNano(times[0], n.atim, now)
+		n.mtim = timespecNano(times[1], n.mtim, now)
+	}
+	n.ctim = now
+}
+
+// timespecNano returns the epoch nanoseconds of `ts`, handling the special
+// values platform.UTIME_OMIT and platform.UTIME_NOW.
+func timespecNano(ts syscall.Timespec, current, now int64) int64 {
+	switch ts.Nsec {
+	case platform.UTIME_OMIT:
+		return current
+	case platform.UTIME_NOW:
+		return now
+	}
+	return ts.Nano()
+}
+
+// memFile is a file opened by memFS.
+type memFile struct {
+	platform.UnimplementedFile
+	fs         *memFS
+	n          *memNode
+	path       string
+	accessMode int
+	append     bool
+
+	// memOpenFile is shared with files from Dup.
+	*memOpenFile
+	closed bool
+}
+
+// memOpenFile is the state of a memFile shared by its duplicates, like an
+// open file description in POSIX.
+type memOpenFile struct {
+	// offset is the position of Read, Write and Seek.
+	offset int64
+	// dirents are the entries for Readdir, or nil before the first.
+	dirents []platform.Dirent
+	// direntPos is the index of the next entry for Readdir.
+	direntPos int
+	// direntsGen is the memNode.gen when dirents were read.
+	direntsGen uint64
+}
+
+// Path implements the same method as documented on platform.File
+func (f *memFile) Path() string {
+	return f.path
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *memFile) AccessMode() int {
+	return f.accessMode
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *memFile) IsDir() (bool, syscall.Errno) {
+	if f.closed {
+		return false, syscall.EBADF
+	}
+	return f.n.isDir(), 0
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *memFile) Stat() (platform.Stat_t, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return f.n.stat(f.fs.dev), 0
+}
+
+// checkRead returns an error if the file can't be read.
+func (f *memFile) checkRead() syscall.Errno {
+	switch {
+	case f.closed:
+		return syscall.EBADF
+	case f.n.isDir():
+		return syscall.EISDIR
+	case f.accessMode == os.O_WRONLY:
+		return syscall.EBADF
+	}
+	return 0
+}
+
+// checkWrite returns an error if the file can't be written.
+func (f *memFile) checkWrite() syscall.Errno {
+	switch {
+	case f.closed:
+		return syscall.EBADF
+	case f.n.isDir():
+		return syscall.EISDIR
+	case f.accessMode == os.O_RDONLY:
+		return syscall.EBADF
+	}
+	return 0
+}
+
+// Read implements the same method as documented on platform.File
+func (f *memFile) Read(buf []byte) (int, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	n, errno := f.pread(buf, f.offset)
+	f.offset += int64(n)
+	return n, errno
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *memFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	return f.pread(buf, off)
+}
+
+func (f *memFile) pread(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.checkRead(); errno != 0 {
+		return 0, errno
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if off >= int64(len(f.n.data)) {
+		return 0, 0
+	}
+	if now := time.Now().UnixNano(); f.fs.atime.shouldUpdateAtime(f.n.atim, f.n.mtim, f.n.ctim, now) {
+		f.n.atim = now
+	}
+	return copy(buf, f.n.data[off:]), 0
+}
+
+// BytesAvailable implements the same method as documented on platform.File
+func (f *memFile) BytesAvailable() (int64, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if errno := f.checkRead(); errno != 0 {
+		return 0, errno
+	} else if size := int64(len(f.n.data)); size > f.offset {
+		return size - f.offset, 0
+	}
+	return 0, 0
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *memFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return 0, syscall.EBADF
+	} else if f.n.isDir() {
+		return 0, syscall.EISDIR
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.n.data))
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *memFile) PollRead(*time.Duration) (bool, syscall.Errno) {
+	return true, 0 // memory is always readable
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *memFile) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return nil, syscall.EBADF
+	} else if !f.n.isDir() {
+		return nil, syscall.ENOTDIR
+	}
+	return f.readdir(n), 0
+}
+
+// ReaddirFrom implements the same method as documented on
+// platform.ReaddirFrom. The cookie is an index.
+func (f *memFile) ReaddirFrom(cookie uint64, n int) ([]platform.Dirent, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return nil, syscall.EBADF
+	} else if !f.n.isDir() {
+		return nil, syscall.ENOTDIR
+	}
+	// The cookie is an index of the entries already read, so position on
+	// them before loadDirents re-reads any changes.
+	if f.dirents == nil {
+		f.loadDirents()
+	}
+	if cookie > uint64(len(f.dirents)) {
+		cookie = uint64(len(f.dirents))
+	}
+	f.direntPos = int(cookie)
+	f.loadDirents()
+	cookie = uint64(f.direntPos)
+	dirents := f.readdir(n)
+	for i := range dirents {
+		cookie++
+		dirents[i].Cookie = cookie
+	}
+	return dirents, 0
+}
+
+// readdir returns up to n entries at direntPos, advancing it.
+func (f *memFile) readdir(n int) []platform.Dirent {
+	f.loadDirents()
+	remaining := f.dirents[f.direntPos:]
+	if n <= 0 || n > len(remaining) {
+		n = len(remaining)
+	}
+	f.direntPos += n
+	// Copy, so that the caller can't change entries read again later.
+	return append([]platform.Dirent(nil), remaining[:n]...)
+}
+
+// loadDirents reads the children of the directory, if not yet read or they
+// changed since. When re-read, direntPos moves to after the last entry
+// returned, so entries added or removed since are seen without repeating
+// others.
+func (f *memFile) loadDirents() {
+	if f.dirents != nil && f.direntsGen == f.n.gen {
+		return
+	}
+	var last string
+	if f.direntPos > 0 {
+		last = f.dirents[f.direntPos-1].Name
+	}
+	f.dirents = make([]platform.Dirent, 0, len(f.n.children))
+	for name, child := range f.n.children {
+		f.dirents = append(f.dirents, platform.Dirent{Name: name, Ino: child.ino, Type: child.mode.Type()})
+	}
+	sort.Slice(f.dirents, func(i, j int) bool { return f.dirents[i].Name < f.dirents[j].Name })
+	f.direntsGen = f.n.gen
+	if f.direntPos > 0 {
+		f.direntPos = sort.Search(len(f.dirents), func(i int) bool { return f.dirents[i].Name > last })
+	}
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (f *memFile) RewindDir() syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return syscall.EBADF
+	} else if !f.n.isDir() {
+		return syscall.ENOTDIR
+	}
+	f.dirents, f.direntPos = nil, 0
+	return 0
+}
+
+// Write implements the same method as documented on platform.File
+func (f *memFile) Write(buf []byte) (int, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.append {
+		f.offset = int64(len(f.n.data))
+	}
+	n, errno := f.pwrite(buf, f.offset)
+	f.offset += int64(n)
+	return n, errno
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *memFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	for _, buf := range bufs {
+		var written int
+		written, errno = f.Write(buf)
+		n += written
+		if errno != 0 {
+			return
+		}
+	}
+	return
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *memFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	return f.pwrite(buf, off)
+}
+
+func (f *memFile) pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.checkWrite(); errno != 0 {
+		return 0, errno
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(buf) == 0 {
+		return 0, 0
+	} else if off > platform.MaxMemoryFileSize-int64(len(buf)) {
+		return 0, syscall.EFBIG
+	}
+	var errno syscall.Errno
+	if end := off + int64(len(buf)); end > int64(len(f.n.data)) {
+		if errno = f.fs.resize(f.n, end); errno != 0 && int64(len(f.n.data)) <= off {
+			return 0, errno // no space at all
+		}
+	}
+	n := copy(f.n.data[off:], buf)
+	f.n.mtim = time.Now().UnixNano()
+	f.n.ctim = f.n.mtim
+	return n, errno
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *memFile) Truncate(size int64) syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if errno := f.checkWrite(); errno != 0 {
+		return errno
+	}
+	return f.fs.truncate(f.n, size)
+}
+
+// PunchHole implements the same method as documented on platform.File
+//
+// Memory isn't released, but the range reads as zeros.
+func (f *memFile) PunchHole(offset, length int64) syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if errno := f.checkWrite(); errno != 0 {
+		return errno
+	} else if offset < 0 || length <= 0 {
+		return syscall.EINVAL
+	}
+	if size := int64(len(f.n.data)); offset < size {
+		end := offset + length
+		if end > size || end < 0 { // overflow
+			end = size
+		}
+		for i := offset; i < end; i++ {
+			f.n.data[i] = 0
+		}
+		f.n.mtim = time.Now().UnixNano()
+		f.n.ctim = f.n.mtim
+	}
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *memFile) Sync() syscall.Errno {
+	return 0 // memory is always in sync
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *memFile) Datasync() syscall.Errno {
+	return 0
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *memFile) Chmod(mode fs.FileMode) syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return syscall.EBADF
+	}
+	f.n.chmod(mode)
+	return 0
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *memFile) Chown(uid, gid int) syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return syscall.EBADF
+	}
+	f.n.chown(uid, gid)
+	return 0
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *memFile) Utimens(times *[2]syscall.Timespec) syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return syscall.EBADF
+	}
+	f.n.utimens(times)
+	return 0
+}
+
+// Dup implements the same method as documented on platform.File
+//
+// The result shares the content, but has its own offset, as there is no
+// open file description to share.
+func (f *memFile) Dup() (platform.File, syscall.Errno) {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if f.closed {
+		return nil, syscall.EBADF
+	}
+	f.n.opens++
+	return &memFile{fs: f.fs, n: f.n, path: f.path, accessMode: f.accessMode, append: f.append, memOpenFile: f.memOpenFile}, 0
+}
+
+// Close implements the same method as documented on platform.File
+func (f *memFile) Close() syscall.Errno {
+	f.fs.mux.Lock()
+	defer f.fs.mux.Unlock()
+
+	if !f.closed {
+		f.closed = true
+		f.fs.closeNode(f.n)
+	}
+	return 0
+}
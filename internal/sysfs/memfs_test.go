@@ -0,0 +1,407 @@
+package sysfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewMemFS(t *testing.T) {
+	testFS := NewMemFS()
+	require.Equal(t, "mem:/", testFS.String())
+
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	require.EqualErrno(t, syscall.EEXIST, testFS.Mkdir("dir", 0o700))
+
+	f, errno := testFS.OpenFile("dir/file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Seek(0, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	buf := make([]byte, 4)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "waze", string(buf[:n]))
+	require.EqualErrno(t, 0, f.Close())
+
+	_, errno = testFS.OpenFile("dir/file", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	require.EqualErrno(t, syscall.EEXIST, errno)
+
+	st, errno := testFS.Stat("dir/file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+
+	// Symbolic links and hard links resolve to the same file.
+	require.EqualErrno(t, 0, testFS.Symlink("dir/file", "link"))
+	require.EqualErrno(t, 0, testFS.Link("dir/file", "hard"))
+	target, errno := testFS.Readlink("link")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "dir/file", target)
+	n, errno = testFS.ReadlinkInto("link", buf[:3])
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "dir", string(buf[:n]))
+	for _, p := range []string{"link", "hard", "dir/../dir/file"} {
+		linkSt, errno := testFS.Stat(p)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, st.Ino, linkSt.Ino, p)
+	}
+
+	// Rename over an existing file replaces it.
+	require.EqualErrno(t, 0, testFS.Rename("hard", "dir/file2"))
+	require.Equal(t, []string{"file", "file2"}, readdirNames(t, testFS, "dir"))
+
+	require.EqualErrno(t, 0, testFS.SyncDir("dir"))
+	require.EqualErrno(t, syscall.ENOTDIR, testFS.SyncDir("dir/file"))
+	require.EqualErrno(t, syscall.ENOENT, testFS.SyncDir("missing"))
+
+	require.EqualErrno(t, syscall.ENOTEMPTY, testFS.Rmdir("dir"))
+	require.EqualErrno(t, syscall.EISDIR, testFS.Unlink("dir"))
+	require.EqualErrno(t, 0, testFS.Unlink("dir/file"))
+	require.EqualErrno(t, 0, testFS.Unlink("dir/file2"))
+	require.EqualErrno(t, 0, testFS.Rmdir("dir"))
+
+	_, errno = testFS.Stat("link")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	_, errno = testFS.Lstat("link")
+	require.EqualErrno(t, 0, errno)
+}
+
+func TestNewLimitedMemFS(t *testing.T) {
+	create := func(testFS FS, name string, size int) syscall.Errno {
+		f, errno := testFS.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o600)
+		if errno != 0 {
+			return errno
+		}
+		defer f.Close()
+		_, errno = f.Write(make([]byte, size))
+		return errno
+	}
+
+	t.Run("maxFiles", func(t *testing.T) {
+		testFS := NewLimitedMemFS(3, 1024)
+
+		require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+		require.EqualErrno(t, 0, create(testFS, "dir/a", 1))
+		require.EqualErrno(t, 0, create(testFS, "dir/b", 1))
+
+		// Bytes remain, but there are no more files.
+		require.EqualErrno(t, syscall.ENOSPC, create(testFS, "dir/c", 1))
+		require.EqualErrno(t, syscall.ENOSPC, testFS.Mkdir("dir2", 0o700))
+		require.EqualErrno(t, syscall.ENOSPC, testFS.Symlink("dir/a", "link"))
+
+		// Hard links don't use a slot.
+		require.EqualErrno(t, 0, testFS.Link("dir/a", "hard"))
+
+		// Deleting frees a slot, but only when the last link is removed.
+		require.EqualErrno(t, 0, testFS.Unlink("dir/a"))
+		require.EqualErrno(t, syscall.ENOSPC, create(testFS, "dir/c", 1))
+		require.EqualErrno(t, 0, testFS.Unlink("hard"))
+		require.EqualErrno(t, 0, create(testFS, "dir/c", 1))
+
+		// Replacing a file with rename frees its slot.
+		require.EqualErrno(t, 0, testFS.Rename("dir/b", "dir/c"))
+		require.EqualErrno(t, 0, create(testFS, "dir/d", 1))
+	})
+
+	t.Run("maxBytes", func(t *testing.T) {
+		testFS := NewLimitedMemFS(100, 10)
+
+		for i := 0; i < 5; i++ {
+			require.EqualErrno(t, 0, create(testFS, fmt.Sprint(i), 2))
+		}
+		// Files remain, but there are no more bytes.
+		require.EqualErrno(t, syscall.ENOSPC, create(testFS, "5", 1))
+
+		// Deleting frees bytes.
+		require.EqualErrno(t, 0, testFS.Unlink("0"))
+		f, errno := testFS.OpenFile("5", os.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		// A write which doesn't fit writes what it can.
+		n, errno := f.Write([]byte("wazero"))
+		require.EqualErrno(t, syscall.ENOSPC, errno)
+		require.Equal(t, 2, n)
+
+		// Truncate counts too.
+		require.EqualErrno(t, 0, f.Truncate(0))
+		require.EqualErrno(t, syscall.ENOSPC, testFS.Truncate("1", 5))
+		require.EqualErrno(t, 0, testFS.Truncate("1", 4))
+	})
+
+	t.Run("maxBytes unlinked but open", func(t *testing.T) {
+		testFS := NewLimitedMemFS(100, 10)
+
+		f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write(make([]byte, 4))
+		require.EqualErrno(t, 0, errno)
+		f2, errno := f.Dup()
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, testFS.Unlink("file"))
+
+		// The removed file still counts while open.
+		n, errno := f.Write(make([]byte, 10))
+		require.EqualErrno(t, syscall.ENOSPC, errno)
+		require.Equal(t, 6, n)
+		require.EqualErrno(t, syscall.ENOSPC, create(testFS, "other", 1))
+
+		// Its bytes are freed when the last open file closes.
+		require.EqualErrno(t, 0, f.Close())
+		require.EqualErrno(t, 0, f.Close())
+		require.EqualErrno(t, syscall.ENOSPC, create(testFS, "other", 1))
+		require.EqualErrno(t, 0, f2.Close())
+		require.EqualErrno(t, 0, create(testFS, "other", 10))
+	})
+}
+
+func TestMemFS_hugeFile(t *testing.T) {
+	testFS := NewMemFS()
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	require.EqualErrno(t, syscall.EFBIG, f.Truncate(1<<62))
+	require.EqualErrno(t, syscall.EFBIG, testFS.Truncate("file", platform.MaxMemoryFileSize+1))
+	_, errno = f.Pwrite([]byte("wazero"), 1<<62)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+	_, errno = f.Pwrite([]byte("wazero"), 1<<63-1)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+
+	// The file is unchanged, and can still grow.
+	require.EqualErrno(t, 0, f.Truncate(3))
+	require.EqualErrno(t, 0, f.Truncate(1))
+	require.EqualErrno(t, 0, f.Truncate(4096))
+	buf := make([]byte, 4096)
+	n, errno := f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, make([]byte, 4096), buf[:n])
+}
+
+func TestMemFS_Dev(t *testing.T) {
+	a, b := NewMemFS(), NewMemFS()
+	aSt, errno := a.Stat(".")
+	require.EqualErrno(t, 0, errno)
+	bSt, errno := b.Stat(".")
+	require.EqualErrno(t, 0, errno)
+
+	require.NotEqual(t, uint64(0), aSt.Dev)
+	require.NotEqual(t, aSt.Dev, bSt.Dev)
+}
+
+func TestMemFS_Clone(t *testing.T) {
+	testFS := NewLimitedMemFS(0, 10)
+
+	f, errno := testFS.OpenFile("src", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+
+	require.EqualErrno(t, syscall.ENOENT, testFS.Clone("missing", "dst"))
+	require.EqualErrno(t, syscall.EISDIR, testFS.Clone("dir", "dst"))
+	require.EqualErrno(t, syscall.EEXIST, testFS.Clone("src", "dir"))
+	// The copy would exceed maxBytes.
+	require.EqualErrno(t, syscall.ENOSPC, testFS.Clone("src", "dst"))
+
+	require.EqualErrno(t, 0, testFS.Truncate("src", 4))
+	require.EqualErrno(t, 0, testFS.Clone("src", "dst"))
+
+	// Writes to the clone don't affect the source.
+	require.EqualErrno(t, 0, testFS.Truncate("dst", 1))
+	srcSt, errno := testFS.Stat("src")
+	require.EqualErrno(t, 0, errno)
+	dstSt, errno := testFS.Stat("dst")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(4), srcSt.Size)
+	require.Equal(t, int64(1), dstSt.Size)
+	require.NotEqual(t, srcSt.Ino, dstSt.Ino)
+}
+
+func TestMemFS_OpenFile(t *testing.T) {
+	testFS := NewMemFS()
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+
+	_, errno := testFS.OpenFile("missing", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	_, errno = testFS.OpenFile("missing/file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	_, errno = testFS.OpenFile("dir", os.O_RDWR, 0)
+	require.EqualErrno(t, syscall.EISDIR, errno)
+
+	f, errno := testFS.OpenFile("file", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("wa"))
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Seek(0, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("zero"))
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Read(make([]byte, 1))
+	require.EqualErrno(t, syscall.EBADF, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	_, errno = testFS.OpenFile("file", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, syscall.ENOTDIR, errno)
+	_, errno = testFS.OpenFile("file/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ENOTDIR, errno)
+
+	f, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	buf := make([]byte, 10)
+	n, errno := f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazero", string(buf[:n]))
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, syscall.EBADF, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// A duplicate shares the offset, but a separate open doesn't.
+	f, errno = testFS.OpenFile("file", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	dup, errno := f.Dup()
+	require.EqualErrno(t, 0, errno)
+	other, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = dup.Write([]byte("WA"))
+	require.EqualErrno(t, 0, errno)
+	n, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "zero", string(buf[:n]))
+	n, errno = other.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "WAzero", string(buf[:n]))
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, 0, dup.Close())
+	require.EqualErrno(t, 0, other.Close())
+
+	// Punching a hole zeros the range, without changing the size.
+	f, errno = testFS.OpenFile("file", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.PunchHole(1, 2))
+	require.EqualErrno(t, 0, f.PunchHole(5, 10))
+	n, errno = f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "W\x00\x00er\x00", string(buf[:n]))
+	require.EqualErrno(t, syscall.EINVAL, f.PunchHole(0, 0))
+	require.EqualErrno(t, 0, f.Close())
+
+	// Cyclic symbolic links fail.
+	require.EqualErrno(t, 0, testFS.Symlink("loop", "loop"))
+	_, errno = testFS.OpenFile("loop", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ELOOP, errno)
+}
+
+func TestMemFS_OpenFile_dirOrFile(t *testing.T) {
+	testFS := NewMemFS()
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	testOpen_dirOrFile(t, testFS)
+}
+
+func TestMemFS_BytesAvailable(t *testing.T) {
+	testFS := NewMemFS()
+	require.EqualErrno(t, 0, writeFile(testFS, "file", []byte("wazero"), 0o600))
+
+	f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	_, errno = f.Read(make([]byte, 2))
+	require.EqualErrno(t, 0, errno)
+	n, errno := f.BytesAvailable()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(4), n)
+
+	d, errno := testFS.OpenFile(".", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer d.Close()
+	_, errno = d.BytesAvailable()
+	require.EqualErrno(t, syscall.EISDIR, errno)
+}
+
+func TestMemFS_OpenByID(t *testing.T) {
+	testFS := NewMemFS()
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	f, errno := testFS.OpenFile("dir/file", os.O_WRONLY|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, 0, testFS.Link("dir/file", "link"))
+
+	st, errno := testFS.Stat("link")
+	require.EqualErrno(t, 0, errno)
+
+	// Writes are visible through every path of the file.
+	f, errno = testFS.OpenByID(st.Dev, st.Ino, os.O_RDWR)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "dir/file", f.Path()) // the first path in name order
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	st, errno = testFS.Stat("link")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+
+	dirSt, errno := testFS.Stat("dir")
+	require.EqualErrno(t, 0, errno)
+	_, errno = testFS.OpenByID(dirSt.Dev, dirSt.Ino, os.O_RDWR)
+	require.EqualErrno(t, syscall.EISDIR, errno)
+	_, errno = testFS.OpenByID(st.Dev, st.Ino, os.O_RDWR|os.O_CREATE)
+	require.EqualErrno(t, syscall.EINVAL, errno)
+	_, errno = testFS.OpenByID(st.Dev+1, st.Ino, os.O_RDONLY)
+	require.EqualErrno(t, syscall.EXDEV, errno)
+
+	// The file exists until its last link is removed.
+	require.EqualErrno(t, 0, testFS.Unlink("dir/file"))
+	f, errno = testFS.OpenByID(st.Dev, st.Ino, os.O_RDONLY)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "link", f.Path())
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, 0, testFS.Unlink("link"))
+	_, errno = testFS.OpenByID(st.Dev, st.Ino, os.O_RDONLY)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+}
+
+func TestMemFS_Readdir_afterCreate(t *testing.T) {
+	testReaddirAfterCreate(t, NewMemFS(), true)
+}
+
+func TestMemFile_ReaddirFrom(t *testing.T) {
+	testFS := NewMemFS()
+	for _, name := range []string{"a", "b", "c"} {
+		require.EqualErrno(t, 0, testFS.Mkdir(name, 0o700))
+	}
+	dir, errno := testFS.OpenFile(".", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer dir.Close()
+
+	dirents, errno := platform.ReaddirFrom(dir, 0, 2)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []platform.Dirent{
+		{Name: "a", Ino: dirents[0].Ino, Type: fs.ModeDir, Cookie: 1},
+		{Name: "b", Ino: dirents[1].Ino, Type: fs.ModeDir, Cookie: 2},
+	}, dirents)
+
+	// Resuming at an earlier cookie re-reads entries.
+	dirents, errno = platform.ReaddirFrom(dir, 1, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 2, len(dirents))
+	require.Equal(t, "b", dirents[0].Name)
+	require.Equal(t, uint64(3), dirents[1].Cookie)
+
+	// A cookie past the end reads nothing.
+	dirents, errno = platform.ReaddirFrom(dir, 10, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, len(dirents))
+}
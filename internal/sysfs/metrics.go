@@ -0,0 +1,266 @@
+package sysfs
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// Metrics receives measurements of filesystem operations from NewMetricsFS.
+//
+// This is small, so that it can be backed by a metrics library, such as
+// Prometheus or OpenTelemetry. Implementations must be safe for concurrent
+// use.
+//
+// The `op` is the name of the method, such as "OpenFile" for FS.OpenFile or
+// "File.Read" for platform.File Read.
+type Metrics interface {
+	// IncOp increments the count of the operation, regardless of whether it
+	// succeeded.
+	IncOp(op string)
+
+	// AddBytes adds the count of bytes read or written by the operation. This
+	// is only called when `n` is positive.
+	AddBytes(op string, n int)
+
+	// ObserveLatency records how long the operation took.
+	ObserveLatency(op string, d time.Duration)
+}
+
+// NewMetricsFS returns an FS which reports each operation on `fs` to `m`,
+// including read, write, readdir and sync operations on files it opens.
+//
+// Note: Files are only wrapped on these operations. Others, such as
+// File.Stat, are not measured, to keep overhead low.
+func NewMetricsFS(fs FS, m Metrics) FS {
+	return &metricsFS{fs: fs, m: m}
+}
+
+type metricsFS struct {
+	UnimplementedFS
+	fs FS
+	m  Metrics
+}
+
+// observe reports an operation which began at `start`. Use with defer.
+func (m *metricsFS) observe(op string, start time.Time) {
+	m.m.IncOp(op)
+	m.m.ObserveLatency(op, time.Since(start))
+}
+
+// observeN is like observe, except it also reports the bytes transferred.
+func (m *metricsFS) observeN(op string, start time.Time, n int) {
+	m.observe(op, start)
+	if n > 0 {
+		m.m.AddBytes(op, n)
+	}
+}
+
+// String implements fmt.Stringer
+func (m *metricsFS) String() string {
+	return m.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (m *metricsFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	defer m.observe("OpenFile", time.Now())
+	f, errno := m.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &metricsFile{File: f, m: m}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (m *metricsFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	defer m.observe("OpenByID", time.Now())
+	f, errno := m.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &metricsFile{File: f, m: m}, 0
+}
+
+// Lstat implements FS.Lstat
+func (m *metricsFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	defer m.observe("Lstat", time.Now())
+	return m.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (m *metricsFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	defer m.observe("Stat", time.Now())
+	return m.fs.Stat(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (m *metricsFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	defer m.observe("Mkdir", time.Now())
+	return m.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (m *metricsFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	defer m.observe("Chmod", time.Now())
+	return m.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (m *metricsFS) Chown(path string, uid, gid int) syscall.Errno {
+	defer m.observe("Chown", time.Now())
+	return m.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (m *metricsFS) Lchown(path string, uid, gid int) syscall.Errno {
+	defer m.observe("Lchown", time.Now())
+	return m.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (m *metricsFS) Rename(from, to string) syscall.Errno {
+	defer m.observe("Rename", time.Now())
+	return m.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (m *metricsFS) ExchangeDir(a, b string) syscall.Errno {
+	defer m.observe("ExchangeDir", time.Now())
+	return m.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (m *metricsFS) Clone(src, dst string) syscall.Errno {
+	defer m.observe("Clone", time.Now())
+	return m.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (m *metricsFS) Rmdir(path string) syscall.Errno {
+	defer m.observe("Rmdir", time.Now())
+	return m.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (m *metricsFS) Unlink(path string) syscall.Errno {
+	defer m.observe("Unlink", time.Now())
+	return m.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (m *metricsFS) Link(oldPath, newPath string) syscall.Errno {
+	defer m.observe("Link", time.Now())
+	return m.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (m *metricsFS) Symlink(oldPath, linkName string) syscall.Errno {
+	defer m.observe("Symlink", time.Now())
+	return m.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (m *metricsFS) Readlink(path string) (string, syscall.Errno) {
+	defer m.observe("Readlink", time.Now())
+	return m.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (m *metricsFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	defer m.observe("ReadlinkInto", time.Now())
+	return m.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (m *metricsFS) Truncate(path string, size int64) syscall.Errno {
+	defer m.observe("Truncate", time.Now())
+	return m.fs.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (m *metricsFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	defer m.observe("Utimens", time.Now())
+	return m.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (m *metricsFS) SyncDir(path string) syscall.Errno {
+	defer m.observe("SyncDir", time.Now())
+	return m.fs.SyncDir(path)
+}
+
+// metricsFile reports I/O on a file opened by metricsFS.
+type metricsFile struct {
+	platform.File
+	m *metricsFS
+}
+
+// Read implements the same method as documented on platform.File
+func (f *metricsFile) Read(buf []byte) (n int, errno syscall.Errno) {
+	start := time.Now()
+	n, errno = f.File.Read(buf)
+	f.m.observeN("File.Read", start, n)
+	return
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *metricsFile) Pread(buf []byte, off int64) (n int, errno syscall.Errno) {
+	start := time.Now()
+	n, errno = f.File.Pread(buf, off)
+	f.m.observeN("File.Pread", start, n)
+	return
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *metricsFile) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	defer f.m.observe("File.Readdir", time.Now())
+	return f.File.Readdir(n)
+}
+
+// Write implements the same method as documented on platform.File
+func (f *metricsFile) Write(buf []byte) (n int, errno syscall.Errno) {
+	start := time.Now()
+	n, errno = f.File.Write(buf)
+	f.m.observeN("File.Write", start, n)
+	return
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *metricsFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	start := time.Now()
+	n, errno = f.File.Writev(bufs)
+	f.m.observeN("File.Writev", start, n)
+	return
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *metricsFile) Pwrite(buf []byte, off int64) (n int, errno syscall.Errno) {
+	start := time.Now()
+	n, errno = f.File.Pwrite(buf, off)
+	f.m.observeN("File.Pwrite", start, n)
+	return
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *metricsFile) Sync() syscall.Errno {
+	defer f.m.observe("File.Sync", time.Now())
+	return f.File.Sync()
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *metricsFile) Datasync() syscall.Errno {
+	defer f.m.observe("File.Datasync", time.Now())
+	return f.File.Datasync()
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *metricsFile) Dup() (platform.File, syscall.Errno) {
+	defer f.m.observe("File.Dup", time.Now())
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &metricsFile{File: dup, m: f.m}, 0
+}
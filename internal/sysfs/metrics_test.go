@@ -0,0 +1,76 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewMetricsFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	m := &testMetrics{ops: map[string]int{}, bytes: map[string]int{}, latencies: map[string]int{}}
+	testFS := NewMetricsFS(NewDirFS(tmpDir), m)
+	require.Equal(t, tmpDir, testFS.String())
+
+	_, errno := testFS.Stat("missing")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	buf := make([]byte, 4)
+	_, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Pwrite([]byte("WA"), 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Writev([][]byte{{}, {}})
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	require.Equal(t, map[string]int{
+		"Stat":        1,
+		"OpenFile":    1,
+		"File.Read":   2,
+		"File.Pwrite": 1,
+		"File.Writev": 1,
+	}, m.ops)
+	require.Equal(t, m.ops, m.latencies)
+	// Zero-length transfers don't add bytes.
+	require.Equal(t, map[string]int{
+		"File.Read":   6,
+		"File.Pwrite": 2,
+	}, m.bytes)
+}
+
+type testMetrics struct {
+	mux                   sync.Mutex
+	ops, bytes, latencies map[string]int
+}
+
+func (m *testMetrics) IncOp(op string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.ops[op]++
+}
+
+func (m *testMetrics) AddBytes(op string, n int) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.bytes[op] += n
+}
+
+func (m *testMetrics) ObserveLatency(op string, d time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if d >= 0 {
+		m.latencies[op]++
+	}
+}
@@ -0,0 +1,52 @@
+package sysfs
+
+import (
+	"io/fs"
+	"path"
+	"syscall"
+)
+
+// MkdirAll creates the directory `dir` in `fs`, along with any parents that
+// don't exist, each with `perm`.
+//
+// # Errors
+//
+// A zero syscall.Errno is success, including when `dir` is already a
+// directory. Otherwise, this returns the first error of FS.Mkdir, except:
+//   - syscall.ENOTDIR: `dir` or one of its parents exists, but isn't a
+//     directory.
+//
+// # Notes
+//
+//   - This is like os.MkdirAll. It is not atomic: on failure, any parents
+//     already created remain.
+//   - A directory created concurrently by another caller isn't an error.
+func MkdirAll(fs FS, dir string, perm fs.FileMode) syscall.Errno {
+	dir = cleanPath(dir)
+	if dir == "" || dir == "." {
+		return 0 // the root always exists.
+	}
+
+	if st, errno := fs.Stat(dir); errno == 0 {
+		if st.Mode.IsDir() {
+			return 0
+		}
+		return syscall.ENOTDIR
+	}
+
+	if parent := path.Dir(dir); parent != "." {
+		if errno := MkdirAll(fs, parent, perm); errno != 0 {
+			return errno
+		}
+	}
+
+	errno := fs.Mkdir(dir, perm)
+	if errno == syscall.EEXIST {
+		// Either created concurrently, or it is a file.
+		if st, statErrno := fs.Stat(dir); statErrno == 0 && st.Mode.IsDir() {
+			return 0
+		}
+		return syscall.ENOTDIR
+	}
+	return errno
+}
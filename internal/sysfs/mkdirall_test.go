@@ -0,0 +1,54 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMkdirAll(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		newFS func(t *testing.T) FS
+	}{
+		{name: "dirFS", newFS: func(t *testing.T) FS { return NewDirFS(t.TempDir()) }},
+		{name: "memFS", newFS: func(*testing.T) FS { return NewMemFS() }},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			testFS := tc.newFS(t)
+
+			require.EqualErrno(t, 0, MkdirAll(testFS, "a/b/c", 0o700))
+			st, errno := testFS.Stat("a/b/c")
+			require.EqualErrno(t, 0, errno)
+			require.True(t, st.Mode.IsDir())
+
+			// Existing directories aren't an error.
+			require.EqualErrno(t, 0, MkdirAll(testFS, "a/b/c", 0o700))
+			require.EqualErrno(t, 0, MkdirAll(testFS, "/a/b/", 0o700))
+			require.EqualErrno(t, 0, MkdirAll(testFS, ".", 0o700))
+
+			// Only missing parts of a partially existing tree are created.
+			require.EqualErrno(t, 0, MkdirAll(testFS, "a/b/d/e", 0o700))
+			require.Equal(t, []string{"c", "d"}, readdirNames(t, testFS, "a/b"))
+			require.Equal(t, []string{"e"}, readdirNames(t, testFS, "a/b/d"))
+
+			// A file in the path isn't a directory.
+			require.EqualErrno(t, 0, WriteFileAtomic(testFS, "a/file", nil, 0o600))
+			require.EqualErrno(t, syscall.ENOTDIR, MkdirAll(testFS, "a/file", 0o700))
+			require.EqualErrno(t, syscall.ENOTDIR, MkdirAll(testFS, "a/file/sub", 0o700))
+		})
+	}
+}
+
+func TestMkdirAll_readFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(tmpDir, "a", "b"), 0o700))
+	testFS := NewReadFS(NewDirFS(tmpDir))
+
+	require.EqualErrno(t, 0, MkdirAll(testFS, "a/b", 0o700))
+	require.EqualErrno(t, syscall.EROFS, MkdirAll(testFS, "a/b/c", 0o700))
+}
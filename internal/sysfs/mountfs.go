@@ -0,0 +1,396 @@
+package sysfs
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// NewMountFS returns an FS which routes each path to the filesystem in
+// `mounts` whose guest path prefix matches it longest. The prefix is removed
+// from the path before it is passed to that filesystem.
+//
+// For example, given mounts for "" and "tmp/cache", the path "tmp/cache/a"
+// is "a" in the latter, and "tmp/b" is "tmp/b" in the former.
+//
+// # Notes
+//
+//   - Unlike NewRootFS, prefixes can have multiple levels, e.g. "a/b".
+//   - Directories listings include the mounts inside them. Parents of a
+//     mount which aren't in any filesystem, e.g. "a" given only a mount at
+//     "a/b", are synthesized as read-only directories.
+//   - Paths that don't match any mount result in syscall.ENOENT.
+//   - Operations on two paths, such as Rename, fail with syscall.EXDEV when
+//     the paths are in different mounts, unless WithCrossDeviceRename is set.
+func NewMountFS(mounts map[string]FS, opts ...MountFSOption) (FS, error) {
+	ret := &mountFS{mounts: make([]mount, 0, len(mounts))}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	seen := make(map[string]string, len(mounts))
+	for guestPath, f := range mounts {
+		prefix := StripPrefixesAndTrailingSlash(guestPath)
+		if other, ok := seen[prefix]; ok {
+			return nil, fmt.Errorf("duplicate mounts %q and %q", other, guestPath)
+		} else if prefix != cleanPath(prefix) || strings.HasPrefix(prefix, "..") {
+			return nil, fmt.Errorf("invalid mount %q", guestPath)
+		}
+		seen[prefix] = guestPath
+		ret.mounts = append(ret.mounts, mount{guestPath: guestPath, prefix: prefix, fs: f})
+	}
+
+	// Sort longest first, so that the first match is the longest.
+	sort.Slice(ret.mounts, func(i, j int) bool {
+		if li, lj := len(ret.mounts[i].prefix), len(ret.mounts[j].prefix); li != lj {
+			return li > lj
+		}
+		return ret.mounts[i].prefix < ret.mounts[j].prefix
+	})
+
+	if len(ret.mounts) == 0 {
+		ret.string = "[]"
+	} else {
+		fsList := make([]FS, len(ret.mounts))
+		guestPaths := make([]string, len(ret.mounts))
+		for i, m := range ret.mounts {
+			fsList[i], guestPaths[i] = m.fs, m.guestPath
+		}
+		ret.string = stringFS(fsList, guestPaths)
+	}
+	return ret, nil
+}
+
+// MountFSOption configures NewMountFS.
+type MountFSOption func(*mountFS)
+
+type mount struct {
+	// guestPath is the original path supplied by the caller.
+	guestPath string
+	// prefix is the cleaned guestPath, e.g. "" for the root.
+	prefix string
+	fs     FS
+}
+
+type mountFS struct {
+	UnimplementedFS
+	// string is cached for convenience.
+	string string
+	// mounts are in descending length of prefix.
+	mounts []mount
+
+	// crossDeviceRename is set by WithCrossDeviceRename.
+	crossDeviceRename bool
+}
+
+// String implements fmt.Stringer
+func (m *mountFS) String() string {
+	return m.string
+}
+
+// route returns the filesystem for the path and the path relative to it, or
+// false if there is no matching mount.
+func (m *mountFS) route(path string) (FS, string, bool) {
+	if i, relativePath := m.routeIndex(path); i != -1 {
+		return m.mounts[i].fs, relativePath, true
+	}
+	return nil, "", false
+}
+
+// routeIndex returns the index of the mount for the path and the path
+// relative to it, or -1 if there is no matching mount.
+func (m *mountFS) routeIndex(path string) (int, string) {
+	path = StripPrefixesAndTrailingSlash(path)
+	for i, mnt := range m.mounts {
+		switch {
+		case mnt.prefix == "":
+			return i, path
+		case path == mnt.prefix:
+			return i, ""
+		case strings.HasPrefix(path, mnt.prefix) && path[len(mnt.prefix)] == '/':
+			return i, path[len(mnt.prefix)+1:]
+		}
+	}
+	return -1, ""
+}
+
+// children returns the filesystems mounted directly under the path, keyed by
+// name. Parents of deeper mounts are a fakeRootFS.
+func (m *mountFS) children(path string) map[string]FS {
+	path = StripPrefixesAndTrailingSlash(path)
+	var ret map[string]FS
+	for _, mnt := range m.mounts {
+		rest := mnt.prefix
+		if rest == "" || rest == path {
+			continue
+		} else if path != "" {
+			if !strings.HasPrefix(rest, path+"/") {
+				continue
+			}
+			rest = rest[len(path)+1:]
+		}
+
+		name, child := rest, mnt.fs
+		if i := strings.IndexByte(rest, '/'); i != -1 {
+			name, child = rest[:i], &fakeRootFS{}
+		}
+		if ret == nil {
+			ret = map[string]FS{}
+		}
+		// As mounts are longest first, a shorter mount at the same name
+		// replaces any synthetic parent.
+		ret[name] = child
+	}
+	return ret
+}
+
+// OpenFile implements FS.OpenFile
+func (m *mountFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	children := m.children(path)
+	var file platform.File
+	errno := syscall.ENOENT
+	if f, relativePath, ok := m.route(path); ok {
+		file, errno = f.OpenFile(relativePath, flag, perm)
+	}
+	if errno == syscall.ENOENT && children != nil {
+		// This is a synthetic parent of a mount.
+		if flag&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+			return nil, syscall.EISDIR
+		}
+		file, errno = (&fakeRootFS{}).OpenFile("", flag, perm)
+	}
+	if errno != 0 {
+		return nil, errno
+	} else if children == nil {
+		return file, 0
+	}
+
+	// Ensure the directory listing includes any mounts inside it.
+	if isDir, errno := file.IsDir(); errno != 0 {
+		_ = file.Close()
+		return nil, errno
+	} else if !isDir {
+		return file, 0
+	}
+	return &openRootDir{path: path, mounts: children, f: file}, 0
+}
+
+// OpenByID implements FS.OpenByID
+//
+// The file is opened by the first mount which has it.
+func (m *mountFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	fss := make([]FS, len(m.mounts))
+	for i := range m.mounts {
+		fss[i] = m.mounts[i].fs
+	}
+	return openByIDFirst(fss, dev, ino, flag)
+}
+
+// Lstat implements FS.Lstat
+func (m *mountFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	if f, relativePath, ok := m.route(path); ok {
+		if st, errno := f.Lstat(relativePath); errno != syscall.ENOENT {
+			return st, errno
+		}
+	}
+	return m.syntheticStat(path)
+}
+
+// Stat implements FS.Stat
+func (m *mountFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	if f, relativePath, ok := m.route(path); ok {
+		if st, errno := f.Stat(relativePath); errno != syscall.ENOENT {
+			return st, errno
+		}
+	}
+	return m.syntheticStat(path)
+}
+
+// syntheticStat returns the status of a path which isn't in any mount.
+func (m *mountFS) syntheticStat(path string) (platform.Stat_t, syscall.Errno) {
+	if m.children(path) == nil {
+		return platform.Stat_t{}, syscall.ENOENT
+	}
+	return (&fakeRootFS{}).Stat("")
+}
+
+// routeErrno returns the errno of a path which doesn't match any mount.
+func (m *mountFS) routeErrno(path string) syscall.Errno {
+	if m.children(path) == nil {
+		return syscall.ENOENT
+	}
+	return syscall.EROFS // a synthetic parent of a mount.
+}
+
+// Mkdir implements FS.Mkdir
+func (m *mountFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	if m.children(path) != nil {
+		return syscall.EEXIST // a parent of a mount.
+	} else if f, relativePath, ok := m.route(path); ok {
+		return f.Mkdir(relativePath, perm)
+	}
+	return syscall.ENOENT
+}
+
+// Chmod implements FS.Chmod
+func (m *mountFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.Chmod(relativePath, perm)
+	}
+	return m.routeErrno(path)
+}
+
+// Chown implements FS.Chown
+func (m *mountFS) Chown(path string, uid, gid int) syscall.Errno {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.Chown(relativePath, uid, gid)
+	}
+	return m.routeErrno(path)
+}
+
+// Lchown implements FS.Lchown
+func (m *mountFS) Lchown(path string, uid, gid int) syscall.Errno {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.Lchown(relativePath, uid, gid)
+	}
+	return m.routeErrno(path)
+}
+
+// Rename implements FS.Rename
+func (m *mountFS) Rename(from, to string) syscall.Errno {
+	fromI, fromPath := m.routeIndex(from)
+	if fromI == -1 {
+		return m.routeErrno(from)
+	}
+	toI, toPath := m.routeIndex(to)
+	if toI == -1 {
+		return m.routeErrno(to)
+	}
+	errno := syscall.EXDEV
+	if fromI == toI {
+		errno = m.mounts[fromI].fs.Rename(fromPath, toPath)
+	}
+	if errno == syscall.EXDEV && m.crossDeviceRename {
+		errno = moveFile(m.mounts[fromI].fs, fromPath, m.mounts[toI].fs, toPath)
+	}
+	return errno
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (m *mountFS) ExchangeDir(a, b string) syscall.Errno {
+	aI, aPath := m.routeIndex(a)
+	if aI == -1 {
+		return m.routeErrno(a)
+	}
+	bI, bPath := m.routeIndex(b)
+	if bI == -1 {
+		return m.routeErrno(b)
+	} else if aI != bI {
+		return syscall.EXDEV
+	}
+	return m.mounts[aI].fs.ExchangeDir(aPath, bPath)
+}
+
+// Clone implements FS.Clone
+func (m *mountFS) Clone(src, dst string) syscall.Errno {
+	srcI, srcPath := m.routeIndex(src)
+	if srcI == -1 {
+		return m.routeErrno(src)
+	}
+	dstI, dstPath := m.routeIndex(dst)
+	if dstI == -1 {
+		return m.routeErrno(dst)
+	} else if srcI != dstI {
+		return syscall.EXDEV
+	}
+	return m.mounts[srcI].fs.Clone(srcPath, dstPath)
+}
+
+// Rmdir implements FS.Rmdir
+func (m *mountFS) Rmdir(path string) syscall.Errno {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.Rmdir(relativePath)
+	}
+	return m.routeErrno(path)
+}
+
+// Unlink implements FS.Unlink
+func (m *mountFS) Unlink(path string) syscall.Errno {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.Unlink(relativePath)
+	}
+	return m.routeErrno(path)
+}
+
+// Link implements FS.Link
+func (m *mountFS) Link(oldPath, newPath string) syscall.Errno {
+	oldI, oldRelativePath := m.routeIndex(oldPath)
+	if oldI == -1 {
+		return m.routeErrno(oldPath)
+	}
+	newI, newRelativePath := m.routeIndex(newPath)
+	if newI == -1 {
+		return m.routeErrno(newPath)
+	} else if oldI != newI {
+		return syscall.EXDEV
+	}
+	return m.mounts[oldI].fs.Link(oldRelativePath, newRelativePath)
+}
+
+// Symlink implements FS.Symlink
+func (m *mountFS) Symlink(oldPath, linkName string) syscall.Errno {
+	// Note: `oldPath` is the content of the link, so it isn't routed.
+	if f, relativePath, ok := m.route(linkName); ok {
+		return f.Symlink(oldPath, relativePath)
+	}
+	return m.routeErrno(linkName)
+}
+
+// Readlink implements FS.Readlink
+func (m *mountFS) Readlink(path string) (string, syscall.Errno) {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.Readlink(relativePath)
+	} else if m.children(path) != nil {
+		return "", syscall.EINVAL // not a symbolic link
+	}
+	return "", syscall.ENOENT
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (m *mountFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.ReadlinkInto(relativePath, buf)
+	} else if m.children(path) != nil {
+		return 0, syscall.EINVAL // not a symbolic link
+	}
+	return 0, syscall.ENOENT
+}
+
+// Truncate implements FS.Truncate
+func (m *mountFS) Truncate(path string, size int64) syscall.Errno {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.Truncate(relativePath, size)
+	} else if m.children(path) != nil {
+		return syscall.EISDIR
+	}
+	return syscall.ENOENT
+}
+
+// Utimens implements FS.Utimens
+func (m *mountFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.Utimens(relativePath, times, symlinkFollow)
+	}
+	return m.routeErrno(path)
+}
+
+// SyncDir implements FS.SyncDir
+func (m *mountFS) SyncDir(path string) syscall.Errno {
+	if f, relativePath, ok := m.route(path); ok {
+		return f.SyncDir(relativePath)
+	}
+	return m.routeErrno(path)
+}
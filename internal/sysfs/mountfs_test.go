@@ -0,0 +1,106 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewMountFS(t *testing.T) {
+	rootDir, cacheDir, tmpDir := t.TempDir(), t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "root.txt"), []byte("root"), 0o600))
+	require.NoError(t, os.Mkdir(path.Join(rootDir, "tmp"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "tmp", "shadowed.txt"), []byte("root"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "tmp.txt"), []byte("tmp"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(cacheDir, "cache.txt"), []byte("cache"), 0o600))
+
+	rootFS, tmpFS, cacheFS := NewDirFS(rootDir), NewDirFS(tmpDir), NewDirFS(cacheDir)
+	mountFS, err := NewMountFS(map[string]FS{
+		"/":              rootFS,
+		"/tmp":           tmpFS,
+		"/var/cache/app": cacheFS,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "["+cacheDir+":/var/cache/app "+tmpDir+":/tmp "+rootDir+":/]", mountFS.String())
+
+	t.Run("longest match", func(t *testing.T) {
+		requireFileContent(t, mountFS, "root.txt", "root")
+		requireFileContent(t, mountFS, "/tmp/tmp.txt", "tmp")
+		requireFileContent(t, mountFS, "var/cache/app/cache.txt", "cache")
+
+		// The mount at tmp shadows the directory of the same name in the root.
+		_, errno := mountFS.Stat("tmp/shadowed.txt")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("Readdir includes mounts", func(t *testing.T) {
+		require.Equal(t, []string{"root.txt", "tmp", "var"}, readdirNames(t, mountFS, "/"))
+		require.Equal(t, []string{"cache"}, readdirNames(t, mountFS, "var"))
+		require.Equal(t, []string{"app"}, readdirNames(t, mountFS, "var/cache"))
+		require.Equal(t, []string{"cache.txt"}, readdirNames(t, mountFS, "var/cache/app"))
+	})
+
+	t.Run("synthetic parent", func(t *testing.T) {
+		st, errno := mountFS.Stat("var/cache")
+		require.EqualErrno(t, 0, errno)
+		require.True(t, st.Mode.IsDir())
+
+		_, errno = mountFS.OpenFile("var", os.O_RDWR, 0)
+		require.EqualErrno(t, syscall.EISDIR, errno)
+		require.EqualErrno(t, syscall.EEXIST, mountFS.Mkdir("var", 0o700))
+	})
+
+	t.Run("cross-mount", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EXDEV, mountFS.Rename("root.txt", "tmp/root.txt"))
+		require.EqualErrno(t, syscall.EXDEV, mountFS.Link("root.txt", "tmp/root.txt"))
+	})
+
+	t.Run("translates paths", func(t *testing.T) {
+		require.EqualErrno(t, 0, mountFS.Rename("tmp/tmp.txt", "/tmp/renamed.txt"))
+		_, err := os.Stat(path.Join(tmpDir, "renamed.txt"))
+		require.NoError(t, err)
+	})
+}
+
+func TestNewMountFS_noRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	mountFS, err := NewMountFS(map[string]FS{"a/b": NewDirFS(tmpDir)})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"a"}, readdirNames(t, mountFS, "."))
+	require.Equal(t, []string{"b"}, readdirNames(t, mountFS, "a"))
+
+	_, errno := mountFS.OpenFile("missing", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	_, errno = mountFS.Stat("a/missing")
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	require.EqualErrno(t, syscall.ENOENT, mountFS.Unlink("missing"))
+	require.EqualErrno(t, syscall.EROFS, mountFS.Rmdir("a"))
+}
+
+func TestNewMountFS_errors(t *testing.T) {
+	testFS := NewDirFS(".")
+
+	_, err := NewMountFS(map[string]FS{"tmp": testFS, "/tmp/": testFS})
+	require.Error(t, err)
+
+	_, err = NewMountFS(map[string]FS{"../tmp": testFS})
+	require.EqualError(t, err, `invalid mount "../tmp"`)
+
+	_, err = NewMountFS(map[string]FS{"a/../b": testFS})
+	require.EqualError(t, err, `invalid mount "a/../b"`)
+}
+
+func requireFileContent(t *testing.T, testFS FS, path, expected string) {
+	f, errno := testFS.OpenFile(path, os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	buf := make([]byte, len(expected)+1)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, expected, string(buf[:n]))
+}
@@ -0,0 +1,53 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// OpenFileOffset is like FS.OpenFile, except the file offset starts at
+// `offset`, so the first Read or Write starts there. This is for resuming a
+// large file, such as a log a guest tails.
+//
+// The file is positioned before it is returned, so no read can start at zero
+// meanwhile. When `flag` includes os.O_APPEND, the file is positioned at its
+// end instead, as writes would be.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. In addition to the errors of FS.OpenFile,
+// the below are expected:
+//   - syscall.EINVAL: `offset` is negative.
+//   - syscall.EISDIR: `offset` isn't zero, and the file is a directory.
+//   - syscall.ESPIPE: `offset` isn't zero, and the file is a pipe.
+//
+// # Notes
+//
+//   - WASI `path_open` always opens files at offset zero, so this isn't used
+//     for files a guest opens. To resume files a guest opens, wrap the FS,
+//     calling this from its OpenFile.
+//   - Like `lseek` in POSIX, an offset past the end of the file is allowed.
+func OpenFileOffset(fs FS, path string, flag int, perm fs.FileMode, offset int64) (platform.File, syscall.Errno) {
+	if offset < 0 {
+		return nil, syscall.EINVAL
+	}
+	f, errno := fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	if flag&os.O_APPEND != 0 {
+		_, errno = f.Seek(0, io.SeekEnd)
+	} else if offset != 0 {
+		_, errno = f.Seek(offset, io.SeekStart)
+	}
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	return f, 0
+}
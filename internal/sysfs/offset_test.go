@@ -0,0 +1,55 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenFileOffset(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "log"), []byte("line1\nline2\n"), 0o600))
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+
+	for _, testFS := range []FS{NewDirFS(tmpDir), NewMemFS()} {
+		testFS := testFS
+		t.Run(testFS.String(), func(t *testing.T) {
+			if _, errno := testFS.Stat("log"); errno != 0 {
+				require.EqualErrno(t, 0, WriteFileAtomic(testFS, "log", []byte("line1\nline2\n"), 0o600))
+				require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+			}
+
+			// The first read starts at the offset.
+			f, errno := OpenFileOffset(testFS, "log", os.O_RDONLY, 0, 6)
+			require.EqualErrno(t, 0, errno)
+			buf := make([]byte, 12)
+			n, errno := f.Read(buf)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, "line2\n", string(buf[:n]))
+			require.EqualErrno(t, 0, f.Close())
+
+			// Append mode starts at the end.
+			f, errno = OpenFileOffset(testFS, "log", os.O_RDWR|os.O_APPEND, 0, 6)
+			require.EqualErrno(t, 0, errno)
+			n, errno = f.Read(buf)
+			require.EqualErrno(t, 0, errno)
+			require.Zero(t, n)
+			require.EqualErrno(t, 0, f.Close())
+
+			_, errno = OpenFileOffset(testFS, "log", os.O_RDONLY, 0, -1)
+			require.EqualErrno(t, syscall.EINVAL, errno)
+			_, errno = OpenFileOffset(testFS, "missing", os.O_RDONLY, 0, 1)
+			require.EqualErrno(t, syscall.ENOENT, errno)
+
+			// Directories can only be opened at zero.
+			d, errno := OpenFileOffset(testFS, "dir", os.O_RDONLY, 0, 0)
+			require.EqualErrno(t, 0, errno)
+			require.EqualErrno(t, 0, d.Close())
+			_, errno = OpenFileOffset(testFS, "dir", os.O_RDONLY, 0, 1)
+			require.EqualErrno(t, syscall.EISDIR, errno)
+		})
+	}
+}
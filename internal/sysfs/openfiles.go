@@ -0,0 +1,142 @@
+package sysfs
+
+import (
+	"runtime/debug"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// OpenFileInfo describes a file which was opened, but not yet closed.
+type OpenFileInfo struct {
+	// Path is the path the file was opened with, relative to the FS.
+	Path string
+
+	// Opened is when the file was opened.
+	Opened time.Time
+
+	// Stack is the stack trace of the goroutine which opened the file, or
+	// empty if stacks aren't captured.
+	Stack string
+}
+
+// OpenFilesFS is implemented by an FS which can track its open files, such
+// as those returned by NewDirFS and NewMemFS. This is for hosts to detect
+// files a guest never closed, for example, after a module finishes.
+//
+// Note: Wrappers, such as NewReadFS, don't implement this, so keep the FS
+// which tracks files to call OpenFiles.
+type OpenFilesFS interface {
+	// OpenFiles returns the files opened, but not yet closed, in the order
+	// they were opened. This is empty unless tracking was enabled with
+	// WithOpenFileTracking or WithMemOpenFileTracking.
+	//
+	// Each platform.File Dup is tracked separately from the file it
+	// duplicates, as each must be closed.
+	OpenFiles() []OpenFileInfo
+}
+
+// WithOpenFileTracking makes an FS returned by NewDirFS record each file it
+// opens until it is closed, and return them from OpenFilesFS.OpenFiles.
+//
+// When `stacks` is true, each record includes the stack trace of where the
+// file was opened. This is expensive, so is best left to debugging leaks.
+func WithOpenFileTracking(stacks bool) DirFSOption {
+	return func(d *dirFS) {
+		d.openFiles = &openFiles{stacks: stacks, files: map[uint64]OpenFileInfo{}}
+	}
+}
+
+// WithMemOpenFileTracking is like WithOpenFileTracking, except for an FS
+// returned by NewMemFS or NewLimitedMemFS.
+func WithMemOpenFileTracking(stacks bool) MemFSOption {
+	return func(m *memFS) {
+		m.openFiles = &openFiles{stacks: stacks, files: map[uint64]OpenFileInfo{}}
+	}
+}
+
+// openFiles records open files, when tracking is enabled. A nil pointer
+// disables tracking.
+type openFiles struct {
+	stacks bool
+
+	// mux guards the below fields.
+	mux    sync.Mutex
+	lastID uint64
+	files  map[uint64]OpenFileInfo
+}
+
+// track records `f` until it is closed, or returns it as-is if tracking is
+// disabled.
+func (o *openFiles) track(f platform.File) platform.File {
+	if o == nil {
+		return f
+	}
+	info := OpenFileInfo{Path: f.Path(), Opened: time.Now()}
+	if o.stacks {
+		info.Stack = string(debug.Stack())
+	}
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.lastID++
+	o.files[o.lastID] = info
+	return &trackedFile{File: f, openFiles: o, id: o.lastID}
+}
+
+// untrack removes the record of the file with the ID `id`, if present.
+func (o *openFiles) untrack(id uint64) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	delete(o.files, id)
+}
+
+// list implements OpenFilesFS.OpenFiles
+func (o *openFiles) list() []OpenFileInfo {
+	if o == nil {
+		return nil
+	}
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	ids := make([]uint64, 0, len(o.files))
+	for id := range o.files {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	ret := make([]OpenFileInfo, 0, len(ids))
+	for _, id := range ids {
+		ret = append(ret, o.files[id])
+	}
+	return ret
+}
+
+// trackedFile is implemented by WithOpenFileTracking.
+type trackedFile struct {
+	platform.File
+	openFiles *openFiles
+	id        uint64
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *trackedFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return f.openFiles.track(dup), 0
+}
+
+// Close implements the same method as documented on platform.File
+//
+// The record is removed even if this fails, as the file can't be used after.
+func (f *trackedFile) Close() syscall.Errno {
+	f.openFiles.untrack(f.id)
+	return f.File.Close()
+}
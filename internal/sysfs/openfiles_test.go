@@ -0,0 +1,76 @@
+package sysfs
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenFilesFS(t *testing.T) {
+	tests := []struct {
+		name  string
+		newFS func(stacks bool) FS
+	}{
+		{name: "dirFS", newFS: func(stacks bool) FS {
+			return NewDirFS(t.TempDir(), WithOpenFileTracking(stacks))
+		}},
+		{name: "memFS", newFS: func(stacks bool) FS {
+			return NewMemFS(WithMemOpenFileTracking(stacks))
+		}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			testFS := tc.newFS(false)
+			require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+			require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", []byte("wazero"), 0o600))
+			require.Zero(t, len(testFS.(OpenFilesFS).OpenFiles()))
+
+			f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			d, errno := testFS.OpenFile("dir", os.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			dup, errno := f.Dup()
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, []string{"file", "dir", "file"}, openFilePaths(t, testFS))
+
+			// Closing removes each file from the set.
+			require.EqualErrno(t, 0, f.Close())
+			require.Equal(t, []string{"dir", "file"}, openFilePaths(t, testFS))
+			require.EqualErrno(t, 0, dup.Close())
+			require.EqualErrno(t, 0, d.Close())
+			require.Zero(t, len(testFS.(OpenFilesFS).OpenFiles()))
+		})
+
+		t.Run(tc.name+" stacks", func(t *testing.T) {
+			testFS := tc.newFS(true)
+			require.EqualErrno(t, 0, WriteFileAtomic(testFS, "file", []byte("wazero"), 0o600))
+			f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			defer f.Close()
+
+			files := testFS.(OpenFilesFS).OpenFiles()
+			require.Equal(t, 1, len(files))
+			require.False(t, files[0].Opened.IsZero())
+			require.True(t, strings.Contains(files[0].Stack, "TestOpenFilesFS"), files[0].Stack)
+		})
+	}
+
+	// Tracking is disabled by default.
+	testFS := NewMemFS()
+	f, errno := testFS.OpenFile(".", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	require.Nil(t, testFS.(OpenFilesFS).OpenFiles())
+}
+
+func openFilePaths(t *testing.T, testFS FS) (paths []string) {
+	for _, f := range testFS.(OpenFilesFS).OpenFiles() {
+		require.Equal(t, "", f.Stack)
+		paths = append(paths, f.Path)
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// WithCreateOwner makes files and directories created by the guest owned by
+// `uid` and `gid`, instead of the user running the host. This is for hosts
+// which run as root to set up a sandbox for another user.
+//
+// # Notes
+//
+//   - The owner is changed with `fchown` on the new file, before the guest
+//     can use it. If that fails, the new file is removed, so that it isn't
+//     left with the wrong owner, and the error is returned. For example,
+//     this returns syscall.EPERM when the host isn't root.
+//   - Opening an existing file with syscall.O_CREAT doesn't change its owner.
+//   - Windows doesn't support ownership, so creating files returns
+//     syscall.ENOSYS.
+func WithCreateOwner(uid, gid int) DirFSOption {
+	return func(d *dirFS) {
+		d.createOwner = true
+		d.ownerUID, d.ownerGID = uid, gid
+	}
+}
+
+// createdDirFlag opens a directory just created, to change its owner.
+const createdDirFlag = os.O_RDONLY | platform.O_DIRECTORY | platform.O_NOFOLLOW
+
+// createOwned opens a file with syscall.O_CREAT in `flag`, changing its owner
+// if it was created. `open` opens the file with the given flag, and `unlink`
+// removes it.
+func (d *dirFS) createOwned(flag int, open func(flag int) (platform.File, syscall.Errno), unlink func() syscall.Errno) (platform.File, syscall.Errno) {
+	for {
+		// Add O_EXCL to know if the file was created by this call.
+		f, errno := open(flag | os.O_EXCL)
+		if errno == syscall.EEXIST && flag&os.O_EXCL == 0 {
+			// The file exists, so open it without changing its owner, unless
+			// it was removed in the meantime.
+			if f, errno = open(flag &^ os.O_CREATE); errno == syscall.ENOENT {
+				continue
+			}
+			return f, errno
+		} else if errno != 0 {
+			return nil, errno
+		}
+
+		if errno = f.Chown(d.ownerUID, d.ownerGID); errno != 0 {
+			_ = f.Close()
+			_ = unlink()
+			return nil, errno
+		}
+		return f, 0
+	}
+}
+
+// chownCreatedDir changes the owner of a directory just created. `open` opens
+// the directory, and `rmdir` removes it, if changing the owner fails.
+func (d *dirFS) chownCreatedDir(open func() (platform.File, syscall.Errno), rmdir func() syscall.Errno) syscall.Errno {
+	f, errno := open()
+	if errno == 0 {
+		errno = f.Chown(d.ownerUID, d.ownerGID)
+		_ = f.Close()
+	}
+	if errno != 0 {
+		_ = rmdir()
+	}
+	return errno
+}
@@ -0,0 +1,87 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithCreateOwner(t *testing.T) {
+	// Only root can give a file to another user.
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid == 0 {
+		uid, gid = 12345, 12345
+	}
+
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir, WithCreateOwner(uid, gid))
+
+	if runtime.GOOS == "windows" {
+		_, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+		require.EqualErrno(t, syscall.ENOSYS, testFS.Mkdir("dir", 0o700))
+
+		// Nothing is left with the wrong owner.
+		_, err := os.Stat(path.Join(tmpDir, "file"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(path.Join(tmpDir, "dir"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+		return
+	}
+
+	t.Run("OpenFile", func(t *testing.T) {
+		f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		st, errno := testFS.Stat("file")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, uint32(uid), st.Uid)
+		require.Equal(t, uint32(gid), st.Gid)
+
+		// O_EXCL is still honored.
+		_, errno = testFS.OpenFile("file", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		require.EqualErrno(t, syscall.EEXIST, errno)
+	})
+
+	t.Run("Mkdir", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+
+		st, errno := testFS.Stat("dir")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, uint32(uid), st.Uid)
+		require.Equal(t, uint32(gid), st.Gid)
+	})
+
+	t.Run("existing file keeps its owner", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "existing"), nil, 0o600))
+
+		f, errno := testFS.OpenFile("existing", os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		st, errno := testFS.Stat("existing")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, uint32(os.Getuid()), st.Uid)
+	})
+
+	t.Run("chown failure removes the file", func(t *testing.T) {
+		if os.Getuid() == 0 {
+			t.Skip("root can chown to any user")
+		}
+		failFS := NewDirFS(tmpDir, WithCreateOwner(12345, 12345))
+
+		_, errno := failFS.OpenFile("denied", os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, syscall.EPERM, errno)
+		_, err := os.Stat(path.Join(tmpDir, "denied"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		require.EqualErrno(t, syscall.EPERM, failFS.Mkdir("denied", 0o700))
+		_, err = os.Stat(path.Join(tmpDir, "denied"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
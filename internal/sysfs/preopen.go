@@ -0,0 +1,288 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// PreopenDir opens the host directory `dir` once, returning an FS which
+// resolves paths against the open directory, instead of its path.
+//
+// This is for applications which instantiate many modules with the same
+// mount: Unlike NewDirFS, which re-resolves `dir` on each operation, the
+// result can be shared by all instances, concurrently.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.ENOENT: `dir` doesn't exist.
+//   - syscall.ENOTDIR: `dir` isn't a directory.
+//
+// Once open, operations return syscall.EBADF if `dir` was removed.
+//
+// # Notes
+//
+//   - OpenFile, Stat, Lstat, Mkdir and Unlink use the open directory when
+//     the platform supports it, so they are unaffected by a rename of `dir`.
+//     Other operations, and all operations on other platforms, join paths
+//     to `dir`, like NewDirFS.
+//   - The directory is closed when the result is garbage collected.
+func PreopenDir(dir string, opts ...DirFSOption) (FS, syscall.Errno) {
+	d := NewDirFS(dir, opts...).(*dirFS)
+	// Open the root directly, so that it isn't seen by WithOnFirstOpen.
+	root, errno := d.openFile(".", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &preopenFS{dirFS: d, root: root}, 0
+}
+
+type preopenFS struct {
+	UnimplementedFS
+	dirFS *dirFS
+
+	// root is the directory opened by PreopenDir. Its file descriptor is
+	// safe to share between goroutines, but platform.File caches state on
+	// Stat, so rootMux serializes that.
+	root    platform.File
+	rootMux sync.Mutex
+}
+
+// checkRoot returns syscall.EBADF if the root directory was removed.
+func (p *preopenFS) checkRoot() syscall.Errno {
+	p.rootMux.Lock()
+	st, errno := p.root.Stat()
+	p.rootMux.Unlock()
+	if errno != 0 || st.Nlink == 0 {
+		return syscall.EBADF
+	}
+	return 0
+}
+
+// rel returns `path` relative to the root directory.
+func (p *preopenFS) rel(path string) string {
+	if path = cleanPath(path); path == "" {
+		return "."
+	}
+	return path
+}
+
+// String implements fmt.Stringer
+func (p *preopenFS) String() string {
+	return p.dirFS.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (p *preopenFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	return p.OpenFileAt(p.root, p.rel(path), flag, perm)
+}
+
+// OpenByID implements FS.OpenByID
+func (p *preopenFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	if errno := p.checkRoot(); errno != 0 {
+		return nil, errno
+	}
+	return p.dirFS.OpenByID(dev, ino, flag)
+}
+
+// Lstat implements FS.Lstat
+func (p *preopenFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	if errno := p.checkRoot(); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	if path = p.rel(path); isAbsOrParent(path) {
+		return p.dirFS.Lstat(path)
+	}
+	st, errno := platform.Statat(p.root, path, false)
+	if errno == syscall.ENOSYS {
+		return p.dirFS.Lstat(path)
+	}
+	return st, errno
+}
+
+// Stat implements FS.Stat
+func (p *preopenFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return p.StatAt(p.root, p.rel(path))
+}
+
+// Mkdir implements FS.Mkdir
+func (p *preopenFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return p.MkdirAt(p.root, p.rel(path), perm)
+}
+
+// Chmod implements FS.Chmod
+func (p *preopenFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (p *preopenFS) Chown(path string, uid, gid int) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (p *preopenFS) Lchown(path string, uid, gid int) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (p *preopenFS) Rename(from, to string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (p *preopenFS) ExchangeDir(a, b string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (p *preopenFS) Clone(src, dst string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (p *preopenFS) Rmdir(path string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (p *preopenFS) Unlink(path string) syscall.Errno {
+	return p.UnlinkAt(p.root, p.rel(path))
+}
+
+// Link implements FS.Link
+func (p *preopenFS) Link(oldPath, newPath string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (p *preopenFS) Symlink(oldPath, linkName string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (p *preopenFS) Readlink(path string) (string, syscall.Errno) {
+	if errno := p.checkRoot(); errno != 0 {
+		return "", errno
+	}
+	return p.dirFS.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (p *preopenFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if errno := p.checkRoot(); errno != 0 {
+		return 0, errno
+	}
+	return p.dirFS.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (p *preopenFS) Truncate(path string, size int64) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (p *preopenFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (p *preopenFS) SyncDir(path string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.SyncDir(path)
+}
+
+// ResetFirstOpen implements FirstOpenFS.ResetFirstOpen
+func (p *preopenFS) ResetFirstOpen() {
+	p.dirFS.ResetFirstOpen()
+}
+
+// compile-time check to ensure preopenFS implements AtFS.
+var _ AtFS = (*preopenFS)(nil)
+
+// OpenFileAt implements AtFS.OpenFileAt
+func (p *preopenFS) OpenFileAt(dir platform.File, path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if errno := p.checkRoot(); errno != 0 {
+		return nil, errno
+	}
+	return p.dirFS.OpenFileAt(dir, path, flag, perm)
+}
+
+// StatAt implements AtFS.StatAt
+func (p *preopenFS) StatAt(dir platform.File, path string) (platform.Stat_t, syscall.Errno) {
+	if errno := p.checkRoot(); errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return p.dirFS.StatAt(dir, path)
+}
+
+// MkdirAt implements AtFS.MkdirAt
+func (p *preopenFS) MkdirAt(dir platform.File, path string, perm fs.FileMode) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.MkdirAt(dir, path, perm)
+}
+
+// UnlinkAt implements AtFS.UnlinkAt
+func (p *preopenFS) UnlinkAt(dir platform.File, path string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.UnlinkAt(dir, path)
+}
+
+// SymlinkAt implements AtFS.SymlinkAt
+func (p *preopenFS) SymlinkAt(target string, dir platform.File, link string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.SymlinkAt(target, dir, link)
+}
+
+// LinkAt implements AtFS.LinkAt
+func (p *preopenFS) LinkAt(oldDir platform.File, oldPath string, newDir platform.File, newPath string) syscall.Errno {
+	if errno := p.checkRoot(); errno != 0 {
+		return errno
+	}
+	return p.dirFS.LinkAt(oldDir, oldPath, newDir, newPath)
+}
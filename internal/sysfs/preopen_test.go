@@ -0,0 +1,111 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestPreopenDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	t.Run("not a directory", func(t *testing.T) {
+		_, errno := PreopenDir(path.Join(tmpDir, "empty.txt"))
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+		_, errno = PreopenDir(path.Join(tmpDir, "missing"))
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		testFS, errno := PreopenDir(tmpDir)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, tmpDir, testFS.String())
+
+		var wg sync.WaitGroup
+		errnos := make([]syscall.Errno, 8)
+		for i := range errnos {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				// Each checks the shared root, so run with -race.
+				f, errno := testFS.OpenFile("/sub/test.txt", os.O_RDONLY, 0)
+				if errno == 0 {
+					errno = f.Close()
+				}
+				if errno == 0 {
+					_, errno = testFS.Stat("sub")
+				}
+				if errno == 0 {
+					_, errno = testFS.Lstat("sub/test.txt")
+				}
+				errnos[i] = errno
+			}(i)
+		}
+		wg.Wait()
+		require.Equal(t, make([]syscall.Errno, len(errnos)), errnos)
+	})
+
+	t.Run("operations", func(t *testing.T) {
+		testFS, errno := PreopenDir(tmpDir)
+		require.EqualErrno(t, 0, errno)
+
+		st, errno := testFS.Stat("sub/test.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(14), st.Size)
+		st, errno = testFS.Lstat("")
+		require.EqualErrno(t, 0, errno)
+		require.True(t, st.Mode.IsDir())
+
+		require.EqualErrno(t, 0, testFS.Mkdir("preopen", 0o700))
+		st, errno = testFS.Stat("/preopen")
+		require.EqualErrno(t, 0, errno)
+		require.True(t, st.Mode.IsDir())
+		require.EqualErrno(t, 0, testFS.Rmdir("preopen"))
+	})
+
+	t.Run("renamed", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("TODO: *at functions on " + runtime.GOOS)
+		}
+
+		root := path.Join(t.TempDir(), "root")
+		require.NoError(t, os.Mkdir(root, 0o700))
+		require.NoError(t, os.WriteFile(path.Join(root, "file"), []byte("wazero"), 0o600))
+		testFS, errno := PreopenDir(root)
+		require.EqualErrno(t, 0, errno)
+
+		moved := root + "-moved"
+		require.NoError(t, os.Rename(root, moved))
+
+		f, errno := testFS.OpenFile("file", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []byte("wazero"), readAll(t, f))
+		require.EqualErrno(t, 0, f.Close())
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("windows can't remove an open directory")
+		}
+
+		root := path.Join(t.TempDir(), "root")
+		require.NoError(t, os.Mkdir(root, 0o700))
+		testFS, errno := PreopenDir(root)
+		require.EqualErrno(t, 0, errno)
+
+		require.NoError(t, os.Remove(root))
+
+		_, errno = testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, syscall.EBADF, errno)
+		_, errno = testFS.Stat(".")
+		require.EqualErrno(t, syscall.EBADF, errno)
+		require.EqualErrno(t, syscall.EBADF, testFS.Mkdir("dir", 0o700))
+	})
+}
@@ -0,0 +1,413 @@
+package sysfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// ProcInfo is the information exposed by NewProcFS.
+type ProcInfo struct {
+	// Args are the arguments of the module, read from "self/cmdline".
+	Args []string
+
+	// Environ are the "key=value" environment variables of the module, read
+	// from "self/environ".
+	Environ []string
+
+	// Memory returns the total and free memory in bytes, read from
+	// "meminfo". When nil, this uses the memory the Go runtime obtained from
+	// the host, and how much of it isn't in use.
+	Memory func() (total, free uint64)
+}
+
+// NewProcFS returns a read-only FS like `/proc` on Linux, for programs which
+// read their environment from it. It should be mounted at "/proc", and has
+// the below files, generated from `info`:
+//   - "self/cmdline": ProcInfo.Args, each terminated by a NUL byte.
+//   - "self/environ": ProcInfo.Environ, each terminated by a NUL byte.
+//   - "meminfo": "MemTotal", "MemFree" and "MemAvailable" in kB.
+//
+// # Notes
+//
+//   - Contents are generated when a file is opened, and again on each read
+//     at offset zero, so that reads return current values.
+//   - Like Linux, the size of each file is zero, so they must be read until
+//     the end instead of by size.
+//   - Operations which write return syscall.EROFS.
+func NewProcFS(info ProcInfo) FS {
+	return &procFS{info: info, dev: syntheticDev()}
+}
+
+type procFS struct {
+	readOnlyFS
+	info ProcInfo
+	// dev is the synthetic device ID of all files.
+	dev uint64
+}
+
+// procEntry is a file or directory in a procFS.
+type procEntry struct {
+	ino uint64
+	// entries are the names in a directory, or nil for a file.
+	entries []string
+	// content generates the contents of a file.
+	content func(info *ProcInfo) []byte
+}
+
+// procEntries are the files and directories in a procFS, by cleaned path.
+var procEntries = map[string]*procEntry{
+	".":            {ino: 1, entries: []string{"meminfo", "self"}},
+	"self":         {ino: 2, entries: []string{"cmdline", "environ"}},
+	"meminfo":      {ino: 3, content: procMeminfo},
+	"self/cmdline": {ino: 4, content: procCmdline},
+	"self/environ": {ino: 5, content: procEnviron},
+}
+
+// procCmdline returns the args, each terminated by a NUL byte.
+func procCmdline(info *ProcInfo) []byte {
+	return nulTerminated(info.Args)
+}
+
+// procEnviron returns the environment, each terminated by a NUL byte.
+func procEnviron(info *ProcInfo) []byte {
+	return nulTerminated(info.Environ)
+}
+
+func nulTerminated(values []string) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		buf.WriteString(v)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// procMeminfo returns the memory in the format of Linux, in kB.
+func procMeminfo(info *ProcInfo) []byte {
+	var total, free uint64
+	if info.Memory != nil {
+		total, free = info.Memory()
+	} else {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		total, free = stats.Sys, stats.Sys-stats.HeapInuse-stats.StackInuse
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "MemTotal:       %8d kB\n", total/1024)
+	fmt.Fprintf(&buf, "MemFree:        %8d kB\n", free/1024)
+	fmt.Fprintf(&buf, "MemAvailable:   %8d kB\n", free/1024)
+	return buf.Bytes()
+}
+
+// String implements fmt.Stringer
+func (p *procFS) String() string {
+	return "proc"
+}
+
+// lookup returns the entry at `path`, or syscall.ENOENT.
+func (p *procFS) lookup(path string) (string, *procEntry, syscall.Errno) {
+	path = cleanPath(path)
+	if path == "" {
+		path = "."
+	}
+	if e, ok := procEntries[path]; ok {
+		return path, e, 0
+	}
+	return path, nil, syscall.ENOENT
+}
+
+// stat returns the status of an entry.
+func (p *procFS) stat(e *procEntry) platform.Stat_t {
+	st := platform.Stat_t{Dev: p.dev, Ino: e.ino, Mode: 0o444, Nlink: 1}
+	if e.content == nil {
+		st.Mode = fs.ModeDir | 0o555
+	}
+	return st
+}
+
+// OpenFile implements FS.OpenFile
+func (p *procFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	path, e, errno := p.lookup(path)
+	if errno != 0 {
+		if flag&os.O_CREATE != 0 {
+			return nil, syscall.EROFS
+		}
+		return nil, errno
+	} else if flag&(os.O_WRONLY|os.O_RDWR|os.O_TRUNC|os.O_APPEND) != 0 {
+		if e.content != nil {
+			return nil, syscall.EROFS
+		}
+		return nil, syscall.EISDIR
+	}
+
+	if e.content == nil {
+		return &procDir{path: path, fs: p, entry: e}, 0
+	} else if flag&platform.O_DIRECTORY != 0 {
+		return nil, syscall.ENOTDIR
+	}
+	return &procFile{path: path, fs: p, entry: e, content: e.content(&p.info)}, 0
+}
+
+// Lstat implements FS.Lstat
+func (p *procFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return p.Stat(path)
+}
+
+// Stat implements FS.Stat
+func (p *procFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	_, e, errno := p.lookup(path)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return p.stat(e), 0
+}
+
+// Readlink implements FS.Readlink
+func (p *procFS) Readlink(path string) (string, syscall.Errno) {
+	if _, _, errno := p.lookup(path); errno != 0 {
+		return "", errno
+	}
+	return "", syscall.EINVAL // not a symbolic link
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (p *procFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if _, _, errno := p.lookup(path); errno != 0 {
+		return 0, errno
+	}
+	return 0, syscall.EINVAL // not a symbolic link
+}
+
+// compile-time check to ensure procFile implements platform.File.
+var _ platform.File = (*procFile)(nil)
+
+// procFile is a file in a procFS, opened for reading.
+type procFile struct {
+	platform.UnimplementedFile
+
+	path    string
+	fs      *procFS
+	entry   *procEntry
+	content []byte
+	offset  int64
+	closed  bool
+}
+
+// Path implements the same method as documented on platform.File
+func (f *procFile) Path() string {
+	return f.path
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *procFile) AccessMode() int {
+	return syscall.O_RDONLY
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *procFile) Stat() (platform.Stat_t, syscall.Errno) {
+	if f.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return f.fs.stat(f.entry), 0
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *procFile) IsDir() (bool, syscall.Errno) {
+	return false, 0
+}
+
+// Read implements the same method as documented on platform.File
+func (f *procFile) Read(buf []byte) (n int, errno syscall.Errno) {
+	if n, errno = f.Pread(buf, f.offset); errno == 0 {
+		f.offset += int64(n)
+	}
+	return
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *procFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if off == 0 {
+		f.content = f.entry.content(&f.fs.info) // re-read current values.
+	}
+	if off >= int64(len(f.content)) {
+		return 0, 0
+	}
+	return copy(buf, f.content[off:]), 0
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *procFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.content))
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *procFile) Readdir(int) ([]platform.Dirent, syscall.Errno) {
+	return nil, syscall.ENOTDIR
+}
+
+// Write implements the same method as documented on platform.File
+func (f *procFile) Write([]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *procFile) Writev([][]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *procFile) Pwrite([]byte, int64) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *procFile) Truncate(int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *procFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *procFile) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *procFile) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *procFile) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *procFile) PollRead(*time.Duration) (ready bool, errno syscall.Errno) {
+	return true, 0
+}
+
+// Close implements the same method as documented on platform.File
+func (f *procFile) Close() syscall.Errno {
+	f.closed = true
+	return 0
+}
+
+// compile-time check to ensure procDir implements platform.File.
+var _ platform.File = (*procDir)(nil)
+
+// procDir is a directory in a procFS, opened for reading.
+type procDir struct {
+	platform.DirFile
+
+	path   string
+	fs     *procFS
+	entry  *procEntry
+	pos    int // the index of the next entry for Readdir
+	closed bool
+}
+
+// Path implements the same method as documented on platform.File
+func (d *procDir) Path() string {
+	return d.path
+}
+
+// Stat implements the same method as documented on platform.File
+func (d *procDir) Stat() (platform.Stat_t, syscall.Errno) {
+	if d.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return d.fs.stat(d.entry), 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (d *procDir) Readdir(n int) (dirents []platform.Dirent, errno syscall.Errno) {
+	if d.closed {
+		return nil, syscall.EBADF
+	}
+	names := d.entry.entries[d.pos:]
+	if n <= 0 || n > len(names) {
+		n = len(names)
+	}
+	for _, name := range names[:n] {
+		p := name
+		if d.path != "." {
+			p = d.path + "/" + name
+		}
+		st := d.fs.stat(procEntries[p])
+		dirents = append(dirents, platform.Dirent{Name: name, Ino: st.Ino, Type: st.Mode.Type()})
+	}
+	d.pos += n
+	return
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (d *procDir) RewindDir() syscall.Errno {
+	if d.closed {
+		return syscall.EBADF
+	}
+	d.pos = 0
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (d *procDir) Sync() syscall.Errno {
+	return 0
+}
+
+// Datasync implements the same method as documented on platform.File
+func (d *procDir) Datasync() syscall.Errno {
+	return 0
+}
+
+// Chmod implements the same method as documented on platform.File
+func (d *procDir) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (d *procDir) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (d *procDir) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Close implements the same method as documented on platform.File
+func (d *procDir) Close() syscall.Errno {
+	d.closed = true
+	return 0
+}
@@ -0,0 +1,118 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewProcFS(t *testing.T) {
+	var free uint64 = 1024 * 1024
+	testFS := NewProcFS(ProcInfo{
+		Args:    []string{"app.wasm", "-v"},
+		Environ: []string{"HOME=/", "TERM=dumb"},
+		Memory:  func() (uint64, uint64) { return 4 * 1024 * 1024, free },
+	})
+	require.Equal(t, "proc", testFS.String())
+
+	t.Run("Readdir", func(t *testing.T) {
+		require.Equal(t, []string{"meminfo", "self"}, readdirNames(t, testFS, "."))
+		require.Equal(t, []string{"cmdline", "environ"}, readdirNames(t, testFS, "/self"))
+
+		d, errno := testFS.OpenFile("self", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer d.Close()
+		dirents, errno := d.Readdir(1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []platform.Dirent{{Name: "cmdline", Ino: 4}}, dirents)
+		require.EqualErrno(t, 0, d.RewindDir())
+		dirents, errno = d.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 2, len(dirents))
+	})
+
+	t.Run("self", func(t *testing.T) {
+		require.Equal(t, "app.wasm\x00-v\x00", string(readProcFile(t, testFS, "self/cmdline")))
+		require.Equal(t, "HOME=/\x00TERM=dumb\x00", string(readProcFile(t, testFS, "/self/environ")))
+	})
+
+	t.Run("meminfo", func(t *testing.T) {
+		f, errno := testFS.OpenFile("meminfo", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+		require.Equal(t, `MemTotal:           4096 kB
+MemFree:            1024 kB
+MemAvailable:       1024 kB
+`, string(readUntilEOF(t, f)))
+
+		// Reading from the start again returns current values.
+		free = 2048 * 1024
+		buf := make([]byte, 100)
+		n, errno := f.Pread(buf, 0)
+		require.EqualErrno(t, 0, errno)
+		require.True(t, strings.Contains(string(buf[:n]), "MemFree:            2048 kB"))
+	})
+
+	t.Run("meminfo default", func(t *testing.T) {
+		content := string(readProcFile(t, NewProcFS(ProcInfo{}), "meminfo"))
+		require.True(t, strings.HasPrefix(content, "MemTotal:"), content)
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		st, errno := testFS.Stat("self")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.ModeDir|0o555, st.Mode)
+		st, errno = testFS.Lstat("self/cmdline")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.FileMode(0o444), st.Mode)
+		require.Zero(t, st.Size)
+		_, errno = testFS.Stat("self/missing")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("EROFS", func(t *testing.T) {
+		_, errno := testFS.OpenFile("self/cmdline", os.O_RDWR, 0)
+		require.EqualErrno(t, syscall.EROFS, errno)
+		_, errno = testFS.OpenFile("new", os.O_WRONLY|os.O_CREATE, 0o600)
+		require.EqualErrno(t, syscall.EROFS, errno)
+		_, errno = testFS.OpenFile("self", os.O_RDWR, 0)
+		require.EqualErrno(t, syscall.EISDIR, errno)
+		_, errno = testFS.OpenFile("meminfo", os.O_RDONLY|platform.O_DIRECTORY, 0)
+		require.EqualErrno(t, syscall.ENOTDIR, errno)
+		require.EqualErrno(t, syscall.EROFS, testFS.Unlink("meminfo"))
+		require.EqualErrno(t, syscall.EROFS, testFS.Mkdir("dir", 0o700))
+
+		f, errno := testFS.OpenFile("meminfo", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+		_, errno = f.Write([]byte("wazero"))
+		require.EqualErrno(t, syscall.EBADF, errno)
+	})
+}
+
+// readProcFile reads the whole file at `path`.
+func readProcFile(t *testing.T, testFS FS, path string) []byte {
+	f, errno := testFS.OpenFile(path, os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	return readUntilEOF(t, f)
+}
+
+// readUntilEOF reads `f` until a read returns no bytes, as the size of files
+// in a procFS is zero.
+func readUntilEOF(t *testing.T, f platform.File) (content []byte) {
+	buf := make([]byte, 8)
+	for {
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		if n == 0 {
+			return
+		}
+		content = append(content, buf[:n]...)
+	}
+}
@@ -23,6 +23,7 @@ func NewReadFS(fs FS) FS {
 }
 
 type readFS struct {
+	readOnlyFS
 	fs FS
 }
 
@@ -65,6 +66,20 @@ func (r *readFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.Fil
 	return &readFile{f: f}, 0
 }
 
+// OpenByID implements FS.OpenByID
+func (r *readFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_WRONLY, os.O_RDWR:
+		return nil, syscall.ENOSYS // same as OpenFile
+	}
+
+	f, errno := r.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &readFile{f: f}, 0
+}
+
 // compile-time check to ensure readFile implements platform.File.
 var _ platform.File = (*readFile)(nil)
 
@@ -92,6 +107,16 @@ func (r *readFile) SetNonblock(enabled bool) syscall.Errno {
 	return r.f.SetNonblock(enabled)
 }
 
+// SetCloexec implements the same method as documented on platform.File.
+func (r *readFile) SetCloexec(enabled bool) syscall.Errno {
+	return r.f.SetCloexec(enabled)
+}
+
+// SetTerminalRaw implements the same method as documented on platform.File.
+func (r *readFile) SetTerminalRaw(enable bool) syscall.Errno {
+	return r.f.SetTerminalRaw(enable)
+}
+
 // Stat implements the same method as documented on platform.File.
 func (r *readFile) Stat() (platform.Stat_t, syscall.Errno) {
 	return r.f.Stat()
@@ -122,11 +147,21 @@ func (r *readFile) Readdir(n int) (dirents []platform.Dirent, errno syscall.Errn
 	return r.f.Readdir(n)
 }
 
+// RewindDir implements the same method as documented on platform.File.
+func (r *readFile) RewindDir() syscall.Errno {
+	return r.f.RewindDir()
+}
+
 // Write implements the same method as documented on platform.File.
 func (r *readFile) Write([]byte) (int, syscall.Errno) {
 	return 0, r.writeErr()
 }
 
+// Writev implements the same method as documented on platform.File.
+func (r *readFile) Writev([][]byte) (int, syscall.Errno) {
+	return 0, r.writeErr()
+}
+
 // Pwrite implements the same method as documented on platform.File.
 func (r *readFile) Pwrite([]byte, int64) (n int, errno syscall.Errno) {
 	return 0, r.writeErr()
@@ -137,6 +172,11 @@ func (r *readFile) Truncate(int64) syscall.Errno {
 	return r.writeErr()
 }
 
+// PunchHole implements the same method as documented on platform.File.
+func (r *readFile) PunchHole(int64, int64) syscall.Errno {
+	return r.writeErr()
+}
+
 // Sync implements the same method as documented on platform.File.
 func (r *readFile) Sync() syscall.Errno {
 	return syscall.EBADF
@@ -171,6 +211,15 @@ func (r *readFile) writeErr() syscall.Errno {
 	return syscall.EBADF
 }
 
+// Dup implements the same method as documented on platform.File.
+func (r *readFile) Dup() (platform.File, syscall.Errno) {
+	f, errno := r.f.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &readFile{f: f}, 0 // the result is read-only, too.
+}
+
 // Close implements the same method as documented on platform.File.
 func (r *readFile) Close() syscall.Errno {
 	return r.f.Close()
@@ -181,6 +230,35 @@ func (r *readFile) PollRead(timeout *time.Duration) (ready bool, errno syscall.E
 	return r.f.PollRead(timeout)
 }
 
+// BytesAvailable implements File.BytesAvailable
+func (r *readFile) BytesAvailable() (int64, syscall.Errno) {
+	return r.f.BytesAvailable()
+}
+
+// Rmdir implements FS.Rmdir
+//
+// This returns the same errors as a writeable FS for a missing path, a file
+// or a non-empty directory, and syscall.EROFS otherwise.
+func (r *readFS) Rmdir(path string) syscall.Errno {
+	st, errno := r.fs.Lstat(path)
+	if errno != 0 {
+		return errno
+	} else if !st.Mode.IsDir() {
+		return syscall.ENOTDIR
+	}
+	dir, errno := r.fs.OpenFile(path, os.O_RDONLY|platform.O_DIRECTORY, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer dir.Close()
+	if dirents, errno := dir.Readdir(1); errno != 0 {
+		return errno
+	} else if len(dirents) > 0 {
+		return syscall.ENOTEMPTY
+	}
+	return syscall.EROFS
+}
+
 // Lstat implements FS.Lstat
 func (r *readFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
 	return r.fs.Lstat(path)
@@ -196,57 +274,83 @@ func (r *readFS) Readlink(path string) (dst string, err syscall.Errno) {
 	return r.fs.Readlink(path)
 }
 
+// ReadlinkInto implements FS.ReadlinkInto
+func (r *readFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return r.fs.ReadlinkInto(path, buf)
+}
+
+// readOnlyFS is embeddable to return syscall.EROFS from all functions that
+// would modify the filesystem.
+type readOnlyFS struct {
+	UnimplementedFS
+}
+
 // Mkdir implements FS.Mkdir
-func (r *readFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+func (readOnlyFS) Mkdir(string, fs.FileMode) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Chmod implements FS.Chmod
-func (r *readFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+func (readOnlyFS) Chmod(string, fs.FileMode) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Chown implements FS.Chown
-func (r *readFS) Chown(path string, uid, gid int) syscall.Errno {
+func (readOnlyFS) Chown(string, int, int) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Lchown implements FS.Lchown
-func (r *readFS) Lchown(path string, uid, gid int) syscall.Errno {
+func (readOnlyFS) Lchown(string, int, int) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Rename implements FS.Rename
-func (r *readFS) Rename(from, to string) syscall.Errno {
+func (readOnlyFS) Rename(string, string) syscall.Errno {
+	return syscall.EROFS
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (readOnlyFS) ExchangeDir(string, string) syscall.Errno {
+	return syscall.EROFS
+}
+
+// Clone implements FS.Clone
+func (readOnlyFS) Clone(string, string) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Rmdir implements FS.Rmdir
-func (r *readFS) Rmdir(path string) syscall.Errno {
+func (readOnlyFS) Rmdir(string) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Link implements FS.Link
-func (r *readFS) Link(_, _ string) syscall.Errno {
+func (readOnlyFS) Link(string, string) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Symlink implements FS.Symlink
-func (r *readFS) Symlink(_, _ string) syscall.Errno {
+func (readOnlyFS) Symlink(string, string) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Unlink implements FS.Unlink
-func (r *readFS) Unlink(path string) syscall.Errno {
+func (readOnlyFS) Unlink(string) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Utimens implements FS.Utimens
-func (r *readFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+func (readOnlyFS) Utimens(string, *[2]syscall.Timespec, bool) syscall.Errno {
+	return syscall.EROFS
+}
+
+// SyncDir implements FS.SyncDir
+func (readOnlyFS) SyncDir(string) syscall.Errno {
 	return syscall.EROFS
 }
 
 // Truncate implements FS.Truncate
-func (r *readFS) Truncate(string, int64) syscall.Errno {
+func (readOnlyFS) Truncate(string, int64) syscall.Errno {
 	return syscall.EROFS
 }
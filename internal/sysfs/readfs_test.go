@@ -86,6 +86,32 @@ func TestReadFS_Rename(t *testing.T) {
 	require.EqualErrno(t, syscall.EROFS, err)
 }
 
+func TestReadFS_ExchangeDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(joinPath(tmpDir, "a"), 0o700))
+	require.NoError(t, os.Mkdir(joinPath(tmpDir, "b"), 0o700))
+	testFS := NewReadFS(NewDirFS(tmpDir))
+
+	err := testFS.ExchangeDir("a", "b")
+	require.EqualErrno(t, syscall.EROFS, err)
+}
+
+func TestReadFS_Clone(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, "a"), nil, 0o600))
+	testFS := NewReadFS(NewDirFS(tmpDir))
+
+	err := testFS.Clone("a", "b")
+	require.EqualErrno(t, syscall.EROFS, err)
+}
+
+func TestReadFS_SyncDir(t *testing.T) {
+	testFS := NewReadFS(NewDirFS(t.TempDir()))
+
+	err := testFS.SyncDir(".")
+	require.EqualErrno(t, syscall.EROFS, err)
+}
+
 func TestReadFS_Rmdir(t *testing.T) {
 	tmpDir := t.TempDir()
 	writeable := NewDirFS(tmpDir)
@@ -174,3 +200,63 @@ func TestReadFS_Readlink(t *testing.T) {
 	testFS := NewReadFS(writeable)
 	testReadlink(t, testFS, writeable)
 }
+
+func TestReadFS_Dup(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := NewReadFS(NewDirFS(tmpDir))
+	f, errno := testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	dup, errno := f.Dup()
+	if errno == syscall.ENOSYS {
+		t.Skip("dup isn't supported on this platform")
+	}
+	require.EqualErrno(t, 0, errno)
+	defer dup.Close()
+
+	// The result is read-only, too.
+	_, errno = dup.Write([]byte("wazero"))
+	require.EqualErrno(t, syscall.EBADF, errno)
+	require.EqualErrno(t, syscall.EBADF, dup.Truncate(0))
+}
+
+func TestReadFS_Writev(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := NewReadFS(NewDirFS(tmpDir))
+	bufs := [][]byte{[]byte("wazero")}
+
+	// Writev fails the same way as Write.
+	f, errno := testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	_, errno = f.Writev(bufs)
+	require.EqualErrno(t, syscall.EBADF, errno)
+
+	d, errno := testFS.OpenFile("sub", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer d.Close()
+	_, errno = d.Writev(bufs)
+	require.EqualErrno(t, syscall.EISDIR, errno)
+}
+
+func TestReadFS_PunchHole(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	testFS := NewReadFS(NewDirFS(tmpDir))
+
+	f, errno := testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	require.EqualErrno(t, syscall.EBADF, f.PunchHole(0, 1))
+
+	d, errno := testFS.OpenFile("sub", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer d.Close()
+	require.EqualErrno(t, syscall.EISDIR, d.PunchHole(0, 1))
+}
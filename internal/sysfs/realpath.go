@@ -0,0 +1,86 @@
+package sysfs
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// RealPathFS is an optional interface implemented by an FS backed by a host
+// directory, which can resolve a guest path to the path on the host.
+//
+// This is for debugging and for host integrations that need to pass a real
+// path to another process. Use RealPath, which returns syscall.ENOSYS when
+// the FS doesn't implement this interface.
+type RealPathFS interface {
+	// RealPath returns the absolute host path of `path`, with symbolic links
+	// evaluated.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOENT: `path` doesn't exist.
+	//   - syscall.EPERM: `path` resolves outside the FS, e.g. via ".." or a
+	//     symbolic link.
+	//
+	// # Notes
+	//
+	//   - The result may be stale, e.g. after `path` or one of its parents is
+	//     renamed. Don't use it to make access decisions.
+	RealPath(path string) (string, syscall.Errno)
+}
+
+// RealPath calls RealPathFS.RealPath if implemented by `fs`, or returns
+// syscall.ENOSYS otherwise. For example, an archive or fs.FS has no host path.
+func RealPath(fs FS, path string) (string, syscall.Errno) {
+	if rpFS, ok := fs.(RealPathFS); ok {
+		return rpFS.RealPath(path)
+	}
+	return "", syscall.ENOSYS
+}
+
+// RealPath implements RealPathFS.RealPath
+func (d *dirFS) RealPath(path string) (string, syscall.Errno) {
+	// Reject paths that escape the FS before touching the host.
+	p := cleanPath(path)
+	if p == "" {
+		p = "."
+	}
+	if !fs.ValidPath(p) {
+		return "", syscall.EPERM
+	}
+
+	root, err := filepath.Abs(d.join(""))
+	if err != nil {
+		return "", platform.UnwrapOSError(err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", platform.UnwrapOSError(err)
+	}
+
+	// Symbolic links under root can still point outside it.
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(p)))
+	if err != nil {
+		return "", platform.UnwrapOSError(err)
+	}
+	if resolved != root && !strings.HasPrefix(resolved, ensureTrailingPathSeparator(root)) {
+		return "", syscall.EPERM
+	}
+	return resolved, 0
+}
+
+// RealPath implements RealPathFS.RealPath
+func (c *CompositeFS) RealPath(path string) (string, syscall.Errno) {
+	matchIndex, relativePath := c.chooseFS(path)
+	return RealPath(c.fs[matchIndex], relativePath)
+}
+
+// compile-time checks to ensure the host-backed FS types implement
+// RealPathFS.
+var (
+	_ RealPathFS = (*dirFS)(nil)
+	_ RealPathFS = (*CompositeFS)(nil)
+)
@@ -0,0 +1,92 @@
+package sysfs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestDirFS_RealPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	// The host path is absolute and has symbolic links evaluated, e.g. on
+	// macOS /var is a link to /private/var.
+	root, err := filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+
+	testFS := NewDirFS(tmpDir)
+
+	tests := []struct {
+		path, expected string
+		expectedErrno  syscall.Errno
+	}{
+		{path: "", expected: root},
+		{path: ".", expected: root},
+		{path: "/", expected: root},
+		{path: "animals.txt", expected: filepath.Join(root, "animals.txt")},
+		{path: "/sub/test.txt", expected: filepath.Join(root, "sub", "test.txt")},
+		{path: "sub/../animals.txt", expected: filepath.Join(root, "animals.txt")},
+		{path: "missing", expectedErrno: syscall.ENOENT},
+		{path: "..", expectedErrno: syscall.EPERM},
+		{path: "sub/../../animals.txt", expectedErrno: syscall.EPERM},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.path, func(t *testing.T) {
+			p, errno := RealPath(testFS, tc.path)
+			require.EqualErrno(t, tc.expectedErrno, errno)
+			require.Equal(t, tc.expected, p)
+		})
+	}
+
+	if runtime.GOOS == "windows" {
+		return // symlinks may require privileges
+	}
+
+	t.Run("symlink inside", func(t *testing.T) {
+		require.NoError(t, os.Symlink("sub", filepath.Join(tmpDir, "sub-link")))
+
+		p, errno := RealPath(testFS, "sub-link/test.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, filepath.Join(root, "sub", "test.txt"), p)
+	})
+
+	t.Run("symlink escapes", func(t *testing.T) {
+		outside := t.TempDir()
+		require.NoError(t, os.Symlink(outside, filepath.Join(tmpDir, "escape")))
+
+		_, errno := RealPath(testFS, "escape")
+		require.EqualErrno(t, syscall.EPERM, errno)
+	})
+}
+
+func TestRealPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	root, err := filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+
+	t.Run("ENOSYS without a host path", func(t *testing.T) {
+		_, errno := RealPath(Adapt(fstest.FS), "animals.txt")
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+	})
+
+	t.Run("CompositeFS", func(t *testing.T) {
+		rootFS, err := NewRootFS([]FS{Adapt(fstest.FS), NewDirFS(tmpDir)}, []string{"/", "/tmp"})
+		require.NoError(t, err)
+
+		p, errno := RealPath(rootFS, "/tmp/animals.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, filepath.Join(root, "animals.txt"), p)
+
+		_, errno = RealPath(rootFS, "/animals.txt")
+		require.EqualErrno(t, syscall.ENOSYS, errno)
+	})
+}
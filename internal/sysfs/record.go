@@ -0,0 +1,807 @@
+package sysfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// recordEvent is one operation in a recording, written as a line of JSON.
+type recordEvent struct {
+	// Op is the name of the method, e.g. "OpenFile" or "File.Read".
+	Op string `json:"op"`
+	// File identifies the file of a File method, numbered from one in the
+	// order files were opened. This is zero for FS methods.
+	File uint64 `json:"file,omitempty"`
+	// Args are the formatted arguments, which must match on replay.
+	Args string `json:"args,omitempty"`
+
+	// The below are results, depending on the operation.
+
+	Errno   syscall.Errno     `json:"errno,omitempty"`
+	N       int64             `json:"n,omitempty"`    // count, offset or the ID of an opened file
+	Data    []byte            `json:"data,omitempty"` // bytes read
+	Str     string            `json:"str,omitempty"`  // e.g. the result of Readlink
+	Bool    bool              `json:"bool,omitempty"`
+	Stat    *platform.Stat_t  `json:"stat,omitempty"`
+	Dirents []platform.Dirent `json:"dirents,omitempty"`
+}
+
+func (e *recordEvent) String() string {
+	if e.File != 0 {
+		return fmt.Sprintf("%s(%s) on file %d", e.Op, e.Args, e.File)
+	}
+	return fmt.Sprintf("%s(%s)", e.Op, e.Args)
+}
+
+// NewRecordFS returns an FS which delegates to `fs`, and writes each operation
+// and its results to `w`, in order. Use NewReplayFS to replay the recording.
+//
+// This is for golden tests of modules which depend on the filesystem, so
+// that they behave the same regardless of the host.
+//
+// # Notes
+//
+//   - The recording includes all data read, so can be large.
+//   - Errors writing to `w` are ignored, so use a writer which doesn't fail,
+//     such as a bytes.Buffer or an os.File.
+func NewRecordFS(fs FS, w io.Writer) FS {
+	return &recordFS{fs: fs, enc: json.NewEncoder(w)}
+}
+
+type recordFS struct {
+	UnimplementedFS
+	fs FS
+
+	// mux guards the below fields.
+	mux    sync.Mutex
+	enc    *json.Encoder
+	lastID uint64
+}
+
+// log writes the event to the recording.
+func (r *recordFS) log(e *recordEvent) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	_ = r.enc.Encode(e)
+}
+
+// String implements fmt.Stringer
+func (r *recordFS) String() string {
+	return r.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (r *recordFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	f, errno := r.fs.OpenFile(path, flag, perm)
+	e := &recordEvent{Op: "OpenFile", Args: openArgs(path, flag, perm), Errno: errno}
+	if errno != 0 {
+		r.log(e)
+		return nil, errno
+	}
+
+	r.mux.Lock()
+	r.lastID++
+	id := r.lastID
+	r.mux.Unlock()
+
+	e.N = int64(id)
+	r.log(e)
+	return &recordFile{File: f, r: r, id: id}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (r *recordFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	f, errno := r.fs.OpenByID(dev, ino, flag)
+	e := &recordEvent{Op: "OpenByID", Args: openByIDArgs(dev, ino, flag), Errno: errno}
+	if errno != 0 {
+		r.log(e)
+		return nil, errno
+	}
+
+	r.mux.Lock()
+	r.lastID++
+	id := r.lastID
+	r.mux.Unlock()
+
+	// The path is recorded, as replay can't know it from the arguments.
+	e.N, e.Str = int64(id), f.Path()
+	r.log(e)
+	return &recordFile{File: f, r: r, id: id}, 0
+}
+
+// Lstat implements FS.Lstat
+func (r *recordFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := r.fs.Lstat(path)
+	r.log(&recordEvent{Op: "Lstat", Args: fmt.Sprintf("%q", path), Errno: errno, Stat: &st})
+	return st, errno
+}
+
+// Stat implements FS.Stat
+func (r *recordFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := r.fs.Stat(path)
+	r.log(&recordEvent{Op: "Stat", Args: fmt.Sprintf("%q", path), Errno: errno, Stat: &st})
+	return st, errno
+}
+
+// Mkdir implements FS.Mkdir
+func (r *recordFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return r.logErrno("Mkdir", fmt.Sprintf("%q, %o", path, perm), r.fs.Mkdir(path, perm))
+}
+
+// Chmod implements FS.Chmod
+func (r *recordFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return r.logErrno("Chmod", fmt.Sprintf("%q, %o", path, perm), r.fs.Chmod(path, perm))
+}
+
+// Chown implements FS.Chown
+func (r *recordFS) Chown(path string, uid, gid int) syscall.Errno {
+	return r.logErrno("Chown", fmt.Sprintf("%q, %d, %d", path, uid, gid), r.fs.Chown(path, uid, gid))
+}
+
+// Lchown implements FS.Lchown
+func (r *recordFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return r.logErrno("Lchown", fmt.Sprintf("%q, %d, %d", path, uid, gid), r.fs.Lchown(path, uid, gid))
+}
+
+// Rename implements FS.Rename
+func (r *recordFS) Rename(from, to string) syscall.Errno {
+	return r.logErrno("Rename", fmt.Sprintf("%q, %q", from, to), r.fs.Rename(from, to))
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (r *recordFS) ExchangeDir(a, b string) syscall.Errno {
+	return r.logErrno("ExchangeDir", fmt.Sprintf("%q, %q", a, b), r.fs.ExchangeDir(a, b))
+}
+
+// Clone implements FS.Clone
+func (r *recordFS) Clone(src, dst string) syscall.Errno {
+	return r.logErrno("Clone", fmt.Sprintf("%q, %q", src, dst), r.fs.Clone(src, dst))
+}
+
+// Rmdir implements FS.Rmdir
+func (r *recordFS) Rmdir(path string) syscall.Errno {
+	return r.logErrno("Rmdir", fmt.Sprintf("%q", path), r.fs.Rmdir(path))
+}
+
+// Unlink implements FS.Unlink
+func (r *recordFS) Unlink(path string) syscall.Errno {
+	return r.logErrno("Unlink", fmt.Sprintf("%q", path), r.fs.Unlink(path))
+}
+
+// Link implements FS.Link
+func (r *recordFS) Link(oldPath, newPath string) syscall.Errno {
+	return r.logErrno("Link", fmt.Sprintf("%q, %q", oldPath, newPath), r.fs.Link(oldPath, newPath))
+}
+
+// Symlink implements FS.Symlink
+func (r *recordFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return r.logErrno("Symlink", fmt.Sprintf("%q, %q", oldPath, linkName), r.fs.Symlink(oldPath, linkName))
+}
+
+// Readlink implements FS.Readlink
+func (r *recordFS) Readlink(path string) (string, syscall.Errno) {
+	dst, errno := r.fs.Readlink(path)
+	r.log(&recordEvent{Op: "Readlink", Args: fmt.Sprintf("%q", path), Errno: errno, Str: dst})
+	return dst, errno
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (r *recordFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	n, errno := r.fs.ReadlinkInto(path, buf)
+	r.log(&recordEvent{Op: "ReadlinkInto", Args: fmt.Sprintf("%q, %d", path, len(buf)), Errno: errno, Str: string(buf[:n])})
+	return n, errno
+}
+
+// Truncate implements FS.Truncate
+func (r *recordFS) Truncate(path string, size int64) syscall.Errno {
+	return r.logErrno("Truncate", fmt.Sprintf("%q, %d", path, size), r.fs.Truncate(path, size))
+}
+
+// Utimens implements FS.Utimens
+func (r *recordFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	args := fmt.Sprintf("%q, %s, %v", path, timesArg(times), symlinkFollow)
+	return r.logErrno("Utimens", args, r.fs.Utimens(path, times, symlinkFollow))
+}
+
+// SyncDir implements FS.SyncDir
+func (r *recordFS) SyncDir(path string) syscall.Errno {
+	return r.logErrno("SyncDir", fmt.Sprintf("%q", path), r.fs.SyncDir(path))
+}
+
+func (r *recordFS) logErrno(op, args string, errno syscall.Errno) syscall.Errno {
+	r.log(&recordEvent{Op: op, Args: args, Errno: errno})
+	return errno
+}
+
+// recordFile records each operation on a file opened by recordFS.
+type recordFile struct {
+	platform.File
+	r  *recordFS
+	id uint64
+}
+
+func (f *recordFile) logErrno(op, args string, errno syscall.Errno) syscall.Errno {
+	f.r.log(&recordEvent{Op: op, File: f.id, Args: args, Errno: errno})
+	return errno
+}
+
+func (f *recordFile) logN(op, args string, n int, errno syscall.Errno) (int, syscall.Errno) {
+	f.r.log(&recordEvent{Op: op, File: f.id, Args: args, Errno: errno, N: int64(n)})
+	return n, errno
+}
+
+// SetNonblock implements the same method as documented on platform.File
+func (f *recordFile) SetNonblock(enable bool) syscall.Errno {
+	return f.logErrno("File.SetNonblock", fmt.Sprint(enable), f.File.SetNonblock(enable))
+}
+
+// SetCloexec implements the same method as documented on platform.File
+func (f *recordFile) SetCloexec(enable bool) syscall.Errno {
+	return f.logErrno("File.SetCloexec", fmt.Sprint(enable), f.File.SetCloexec(enable))
+}
+
+// SetTerminalRaw implements the same method as documented on platform.File
+func (f *recordFile) SetTerminalRaw(enable bool) syscall.Errno {
+	return f.logErrno("File.SetTerminalRaw", fmt.Sprint(enable), f.File.SetTerminalRaw(enable))
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *recordFile) Stat() (platform.Stat_t, syscall.Errno) {
+	st, errno := f.File.Stat()
+	f.r.log(&recordEvent{Op: "File.Stat", File: f.id, Errno: errno, Stat: &st})
+	return st, errno
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *recordFile) IsDir() (bool, syscall.Errno) {
+	isDir, errno := f.File.IsDir()
+	f.r.log(&recordEvent{Op: "File.IsDir", File: f.id, Errno: errno, Bool: isDir})
+	return isDir, errno
+}
+
+// Read implements the same method as documented on platform.File
+func (f *recordFile) Read(buf []byte) (int, syscall.Errno) {
+	n, errno := f.File.Read(buf)
+	f.r.log(&recordEvent{Op: "File.Read", File: f.id, Args: fmt.Sprint(len(buf)), Errno: errno, N: int64(n), Data: buf[:n]})
+	return n, errno
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *recordFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	n, errno := f.File.Pread(buf, off)
+	f.r.log(&recordEvent{Op: "File.Pread", File: f.id, Args: fmt.Sprintf("%d, %d", len(buf), off), Errno: errno, N: int64(n), Data: buf[:n]})
+	return n, errno
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *recordFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	newOffset, errno := f.File.Seek(offset, whence)
+	f.r.log(&recordEvent{Op: "File.Seek", File: f.id, Args: fmt.Sprintf("%d, %d", offset, whence), Errno: errno, N: newOffset})
+	return newOffset, errno
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *recordFile) PollRead(timeout *time.Duration) (bool, syscall.Errno) {
+	ready, errno := f.File.PollRead(timeout)
+	f.r.log(&recordEvent{Op: "File.PollRead", File: f.id, Args: timeoutArg(timeout), Errno: errno, Bool: ready})
+	return ready, errno
+}
+
+// BytesAvailable implements the same method as documented on platform.File
+func (f *recordFile) BytesAvailable() (int64, syscall.Errno) {
+	n, errno := f.File.BytesAvailable()
+	f.r.log(&recordEvent{Op: "File.BytesAvailable", File: f.id, Errno: errno, N: n})
+	return n, errno
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *recordFile) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	dirents, errno := f.File.Readdir(n)
+	f.r.log(&recordEvent{Op: "File.Readdir", File: f.id, Args: fmt.Sprint(n), Errno: errno, Dirents: dirents})
+	return dirents, errno
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (f *recordFile) RewindDir() syscall.Errno {
+	return f.logErrno("File.RewindDir", "", f.File.RewindDir())
+}
+
+// Write implements the same method as documented on platform.File
+func (f *recordFile) Write(buf []byte) (int, syscall.Errno) {
+	n, errno := f.File.Write(buf)
+	return f.logN("File.Write", fmt.Sprintf("%q", buf), n, errno)
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *recordFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	n, errno := f.File.Writev(bufs)
+	return f.logN("File.Writev", fmt.Sprintf("%q", bufs), n, errno)
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *recordFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	n, errno := f.File.Pwrite(buf, off)
+	return f.logN("File.Pwrite", fmt.Sprintf("%q, %d", buf, off), n, errno)
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *recordFile) Truncate(size int64) syscall.Errno {
+	return f.logErrno("File.Truncate", fmt.Sprint(size), f.File.Truncate(size))
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *recordFile) PunchHole(offset, length int64) syscall.Errno {
+	return f.logErrno("File.PunchHole", fmt.Sprintf("%d, %d", offset, length), f.File.PunchHole(offset, length))
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *recordFile) Sync() syscall.Errno {
+	return f.logErrno("File.Sync", "", f.File.Sync())
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *recordFile) Datasync() syscall.Errno {
+	return f.logErrno("File.Datasync", "", f.File.Datasync())
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *recordFile) Chmod(mode fs.FileMode) syscall.Errno {
+	return f.logErrno("File.Chmod", fmt.Sprintf("%o", mode), f.File.Chmod(mode))
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *recordFile) Chown(uid, gid int) syscall.Errno {
+	return f.logErrno("File.Chown", fmt.Sprintf("%d, %d", uid, gid), f.File.Chown(uid, gid))
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *recordFile) Utimens(times *[2]syscall.Timespec) syscall.Errno {
+	return f.logErrno("File.Utimens", timesArg(times), f.File.Utimens(times))
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *recordFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	e := &recordEvent{Op: "File.Dup", File: f.id, Errno: errno}
+	if errno != 0 {
+		f.r.log(e)
+		return nil, errno
+	}
+
+	f.r.mux.Lock()
+	f.r.lastID++
+	id := f.r.lastID
+	f.r.mux.Unlock()
+
+	e.N = int64(id)
+	f.r.log(e)
+	return &recordFile{File: dup, r: f.r, id: id}, 0
+}
+
+// Close implements the same method as documented on platform.File
+func (f *recordFile) Close() syscall.Errno {
+	return f.logErrno("File.Close", "", f.File.Close())
+}
+
+// ReplayFS is an FS which replays a recording from NewRecordFS.
+//
+// Each operation must match the next one in the recording, including its
+// arguments, and returns the recorded results. Otherwise, the operation fails
+// with syscall.EIO, as do all later ones, and Err describes the divergence.
+type ReplayFS struct {
+	UnimplementedFS
+
+	// mux guards the below fields.
+	mux   sync.Mutex
+	dec   *json.Decoder
+	count int   // the count of operations replayed.
+	err   error // the first divergence from the recording.
+}
+
+// NewReplayFS returns a ReplayFS which reads the recording from `r`.
+func NewReplayFS(r io.Reader) *ReplayFS {
+	return &ReplayFS{dec: json.NewDecoder(r)}
+}
+
+// Err returns nil, or an error describing the first operation which didn't
+// match the recording.
+func (p *ReplayFS) Err() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.err
+}
+
+// next returns the next event in the recording, or nil if it doesn't match
+// the operation.
+func (p *ReplayFS) next(op string, file uint64, args string) *recordEvent {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.err != nil {
+		return nil
+	}
+
+	p.count++
+	actual := &recordEvent{Op: op, File: file, Args: args}
+	var e recordEvent
+	if err := p.dec.Decode(&e); err == io.EOF {
+		p.err = fmt.Errorf("replay: operation %d %s is after the end of the recording", p.count, actual)
+		return nil
+	} else if err != nil {
+		p.err = fmt.Errorf("replay: invalid recording at operation %d: %w", p.count, err)
+		return nil
+	} else if e.Op != op || e.File != file || e.Args != args {
+		p.err = fmt.Errorf("replay: operation %d %s doesn't match the recording %s", p.count, actual, &e)
+		return nil
+	}
+	return &e
+}
+
+func (p *ReplayFS) nextErrno(op, args string) syscall.Errno {
+	if e := p.next(op, 0, args); e != nil {
+		return e.Errno
+	}
+	return syscall.EIO
+}
+
+func (p *ReplayFS) nextStat(op, args string) (platform.Stat_t, syscall.Errno) {
+	if e := p.next(op, 0, args); e == nil {
+		return platform.Stat_t{}, syscall.EIO
+	} else if e.Errno != 0 || e.Stat == nil {
+		return platform.Stat_t{}, e.Errno
+	} else {
+		return *e.Stat, 0
+	}
+}
+
+// String implements fmt.Stringer
+func (p *ReplayFS) String() string {
+	return "replay"
+}
+
+// OpenFile implements FS.OpenFile
+func (p *ReplayFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	e := p.next("OpenFile", 0, openArgs(path, flag, perm))
+	if e == nil {
+		return nil, syscall.EIO
+	} else if e.Errno != 0 {
+		return nil, e.Errno
+	}
+	accessMode := flag & (syscall.O_RDONLY | syscall.O_WRONLY | syscall.O_RDWR)
+	return &replayFile{p: p, id: uint64(e.N), path: path, accessMode: accessMode}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (p *ReplayFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	e := p.next("OpenByID", 0, openByIDArgs(dev, ino, flag))
+	if e == nil {
+		return nil, syscall.EIO
+	} else if e.Errno != 0 {
+		return nil, e.Errno
+	}
+	accessMode := flag & (syscall.O_RDONLY | syscall.O_WRONLY | syscall.O_RDWR)
+	return &replayFile{p: p, id: uint64(e.N), path: e.Str, accessMode: accessMode}, 0
+}
+
+// Lstat implements FS.Lstat
+func (p *ReplayFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return p.nextStat("Lstat", fmt.Sprintf("%q", path))
+}
+
+// Stat implements FS.Stat
+func (p *ReplayFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return p.nextStat("Stat", fmt.Sprintf("%q", path))
+}
+
+// Mkdir implements FS.Mkdir
+func (p *ReplayFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return p.nextErrno("Mkdir", fmt.Sprintf("%q, %o", path, perm))
+}
+
+// Chmod implements FS.Chmod
+func (p *ReplayFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return p.nextErrno("Chmod", fmt.Sprintf("%q, %o", path, perm))
+}
+
+// Chown implements FS.Chown
+func (p *ReplayFS) Chown(path string, uid, gid int) syscall.Errno {
+	return p.nextErrno("Chown", fmt.Sprintf("%q, %d, %d", path, uid, gid))
+}
+
+// Lchown implements FS.Lchown
+func (p *ReplayFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return p.nextErrno("Lchown", fmt.Sprintf("%q, %d, %d", path, uid, gid))
+}
+
+// Rename implements FS.Rename
+func (p *ReplayFS) Rename(from, to string) syscall.Errno {
+	return p.nextErrno("Rename", fmt.Sprintf("%q, %q", from, to))
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (p *ReplayFS) ExchangeDir(a, b string) syscall.Errno {
+	return p.nextErrno("ExchangeDir", fmt.Sprintf("%q, %q", a, b))
+}
+
+// Clone implements FS.Clone
+func (p *ReplayFS) Clone(src, dst string) syscall.Errno {
+	return p.nextErrno("Clone", fmt.Sprintf("%q, %q", src, dst))
+}
+
+// Rmdir implements FS.Rmdir
+func (p *ReplayFS) Rmdir(path string) syscall.Errno {
+	return p.nextErrno("Rmdir", fmt.Sprintf("%q", path))
+}
+
+// Unlink implements FS.Unlink
+func (p *ReplayFS) Unlink(path string) syscall.Errno {
+	return p.nextErrno("Unlink", fmt.Sprintf("%q", path))
+}
+
+// Link implements FS.Link
+func (p *ReplayFS) Link(oldPath, newPath string) syscall.Errno {
+	return p.nextErrno("Link", fmt.Sprintf("%q, %q", oldPath, newPath))
+}
+
+// Symlink implements FS.Symlink
+func (p *ReplayFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return p.nextErrno("Symlink", fmt.Sprintf("%q, %q", oldPath, linkName))
+}
+
+// Readlink implements FS.Readlink
+func (p *ReplayFS) Readlink(path string) (string, syscall.Errno) {
+	if e := p.next("Readlink", 0, fmt.Sprintf("%q", path)); e != nil {
+		return e.Str, e.Errno
+	}
+	return "", syscall.EIO
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (p *ReplayFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if e := p.next("ReadlinkInto", 0, fmt.Sprintf("%q, %d", path, len(buf))); e != nil {
+		return copy(buf, e.Str), e.Errno
+	}
+	return 0, syscall.EIO
+}
+
+// Truncate implements FS.Truncate
+func (p *ReplayFS) Truncate(path string, size int64) syscall.Errno {
+	return p.nextErrno("Truncate", fmt.Sprintf("%q, %d", path, size))
+}
+
+// Utimens implements FS.Utimens
+func (p *ReplayFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return p.nextErrno("Utimens", fmt.Sprintf("%q, %s, %v", path, timesArg(times), symlinkFollow))
+}
+
+// SyncDir implements FS.SyncDir
+func (p *ReplayFS) SyncDir(path string) syscall.Errno {
+	return p.nextErrno("SyncDir", fmt.Sprintf("%q", path))
+}
+
+// replayFile is a file opened by ReplayFS.
+type replayFile struct {
+	p          *ReplayFS
+	id         uint64
+	path       string
+	accessMode int
+	nonblock   bool
+}
+
+func (f *replayFile) next(op, args string) *recordEvent {
+	return f.p.next(op, f.id, args)
+}
+
+func (f *replayFile) nextErrno(op, args string) syscall.Errno {
+	if e := f.next(op, args); e != nil {
+		return e.Errno
+	}
+	return syscall.EIO
+}
+
+func (f *replayFile) nextN(op, args string) (int, syscall.Errno) {
+	if e := f.next(op, args); e != nil {
+		return int(e.N), e.Errno
+	}
+	return 0, syscall.EIO
+}
+
+// Path implements the same method as documented on platform.File
+func (f *replayFile) Path() string {
+	return f.path
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *replayFile) AccessMode() int {
+	return f.accessMode
+}
+
+// IsNonblock implements the same method as documented on platform.File
+func (f *replayFile) IsNonblock() bool {
+	return f.nonblock
+}
+
+// SetNonblock implements the same method as documented on platform.File
+func (f *replayFile) SetNonblock(enable bool) syscall.Errno {
+	errno := f.nextErrno("File.SetNonblock", fmt.Sprint(enable))
+	if errno == 0 {
+		f.nonblock = enable
+	}
+	return errno
+}
+
+// SetCloexec implements the same method as documented on platform.File
+func (f *replayFile) SetCloexec(enable bool) syscall.Errno {
+	return f.nextErrno("File.SetCloexec", fmt.Sprint(enable))
+}
+
+// SetTerminalRaw implements the same method as documented on platform.File
+func (f *replayFile) SetTerminalRaw(enable bool) syscall.Errno {
+	return f.nextErrno("File.SetTerminalRaw", fmt.Sprint(enable))
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *replayFile) Stat() (platform.Stat_t, syscall.Errno) {
+	if e := f.next("File.Stat", ""); e == nil {
+		return platform.Stat_t{}, syscall.EIO
+	} else if e.Errno != 0 || e.Stat == nil {
+		return platform.Stat_t{}, e.Errno
+	} else {
+		return *e.Stat, 0
+	}
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *replayFile) IsDir() (bool, syscall.Errno) {
+	if e := f.next("File.IsDir", ""); e != nil {
+		return e.Bool, e.Errno
+	}
+	return false, syscall.EIO
+}
+
+// Read implements the same method as documented on platform.File
+func (f *replayFile) Read(buf []byte) (int, syscall.Errno) {
+	if e := f.next("File.Read", fmt.Sprint(len(buf))); e != nil {
+		return copy(buf, e.Data), e.Errno
+	}
+	return 0, syscall.EIO
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *replayFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if e := f.next("File.Pread", fmt.Sprintf("%d, %d", len(buf), off)); e != nil {
+		return copy(buf, e.Data), e.Errno
+	}
+	return 0, syscall.EIO
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *replayFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if e := f.next("File.Seek", fmt.Sprintf("%d, %d", offset, whence)); e != nil {
+		return e.N, e.Errno
+	}
+	return 0, syscall.EIO
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *replayFile) PollRead(timeout *time.Duration) (bool, syscall.Errno) {
+	if e := f.next("File.PollRead", timeoutArg(timeout)); e != nil {
+		return e.Bool, e.Errno
+	}
+	return false, syscall.EIO
+}
+
+// BytesAvailable implements the same method as documented on platform.File
+func (f *replayFile) BytesAvailable() (int64, syscall.Errno) {
+	if e := f.next("File.BytesAvailable", ""); e != nil {
+		return e.N, e.Errno
+	}
+	return 0, syscall.EIO
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *replayFile) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	if e := f.next("File.Readdir", fmt.Sprint(n)); e != nil {
+		return e.Dirents, e.Errno
+	}
+	return nil, syscall.EIO
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (f *replayFile) RewindDir() syscall.Errno {
+	return f.nextErrno("File.RewindDir", "")
+}
+
+// Write implements the same method as documented on platform.File
+func (f *replayFile) Write(buf []byte) (int, syscall.Errno) {
+	return f.nextN("File.Write", fmt.Sprintf("%q", buf))
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *replayFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	return f.nextN("File.Writev", fmt.Sprintf("%q", bufs))
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *replayFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	return f.nextN("File.Pwrite", fmt.Sprintf("%q, %d", buf, off))
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *replayFile) Truncate(size int64) syscall.Errno {
+	return f.nextErrno("File.Truncate", fmt.Sprint(size))
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *replayFile) PunchHole(offset, length int64) syscall.Errno {
+	return f.nextErrno("File.PunchHole", fmt.Sprintf("%d, %d", offset, length))
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *replayFile) Sync() syscall.Errno {
+	return f.nextErrno("File.Sync", "")
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *replayFile) Datasync() syscall.Errno {
+	return f.nextErrno("File.Datasync", "")
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *replayFile) Chmod(mode fs.FileMode) syscall.Errno {
+	return f.nextErrno("File.Chmod", fmt.Sprintf("%o", mode))
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *replayFile) Chown(uid, gid int) syscall.Errno {
+	return f.nextErrno("File.Chown", fmt.Sprintf("%d, %d", uid, gid))
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *replayFile) Utimens(times *[2]syscall.Timespec) syscall.Errno {
+	return f.nextErrno("File.Utimens", timesArg(times))
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *replayFile) Dup() (platform.File, syscall.Errno) {
+	e := f.next("File.Dup", "")
+	if e == nil {
+		return nil, syscall.EIO
+	} else if e.Errno != 0 {
+		return nil, e.Errno
+	}
+	return &replayFile{p: f.p, id: uint64(e.N), path: f.path, accessMode: f.accessMode, nonblock: f.nonblock}, 0
+}
+
+// Close implements the same method as documented on platform.File
+func (f *replayFile) Close() syscall.Errno {
+	return f.nextErrno("File.Close", "")
+}
+
+func openArgs(path string, flag int, perm fs.FileMode) string {
+	return fmt.Sprintf("%q, %#x, %o", path, flag, perm)
+}
+
+func openByIDArgs(dev, ino uint64, flag int) string {
+	return fmt.Sprintf("%d, %d, %#x", dev, ino, flag)
+}
+
+func timesArg(times *[2]syscall.Timespec) string {
+	if times == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*times)
+}
+
+func timeoutArg(timeout *time.Duration) string {
+	if timeout == nil {
+		return "nil"
+	}
+	return timeout.String()
+}
@@ -0,0 +1,120 @@
+package sysfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// recordedOps performs operations whose results are compared between the
+// recording and its replay.
+func recordedOps(t *testing.T, testFS FS) (results []interface{}) {
+	st, errno := testFS.Stat("animals.txt")
+	results = append(results, st, errno)
+
+	_, errno = testFS.OpenFile("missing.txt", os.O_RDONLY, 0)
+	results = append(results, errno)
+
+	f, errno := testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	buf := make([]byte, 5)
+	n, errno := f.Read(buf)
+	results = append(results, string(buf[:n]), errno)
+	n, errno = f.Pread(buf, 5)
+	results = append(results, string(buf[:n]), errno)
+	off, errno := f.Seek(0, io.SeekEnd)
+	results = append(results, off, errno)
+	_, errno = f.Write([]byte("cat"))
+	results = append(results, errno)
+	results = append(results, f.Close())
+
+	w, errno := testFS.OpenFile("new.txt", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	n, errno = w.Writev([][]byte{[]byte("wa"), []byte("zero")})
+	results = append(results, n, errno)
+	results = append(results, w.Close())
+
+	d, errno := testFS.OpenFile("sub", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	dirents, errno := d.Readdir(-1)
+	results = append(results, dirents, errno)
+	results = append(results, d.Close())
+
+	results = append(results, testFS.Unlink("new.txt"))
+	return
+}
+
+func TestRecordFS_Replay(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	var recording bytes.Buffer
+	recorded := recordedOps(t, NewRecordFS(NewDirFS(tmpDir), &recording))
+
+	// Remove the files, so that the replay can't read them.
+	require.NoError(t, os.RemoveAll(tmpDir))
+
+	replayFS := NewReplayFS(bytes.NewReader(recording.Bytes()))
+	replayed := recordedOps(t, replayFS)
+	require.NoError(t, replayFS.Err())
+	require.Equal(t, recorded, replayed)
+
+	// The recording is complete, so any further operation diverges.
+	_, errno := replayFS.Stat("animals.txt")
+	require.EqualErrno(t, syscall.EIO, errno)
+	require.EqualError(t, replayFS.Err(),
+		`replay: operation 16 Stat("animals.txt") is after the end of the recording`)
+}
+
+func TestReplayFS_Divergence(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	var recording bytes.Buffer
+	recordFS := NewRecordFS(NewDirFS(tmpDir), &recording)
+	f, errno := recordFS.OpenFile("file", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("wasm"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	replayFS := NewReplayFS(&recording)
+	f, errno = replayFS.OpenFile("file", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+
+	// Writing different data is a divergence, as is everything after it.
+	_, errno = f.Write([]byte("wasi"))
+	require.EqualErrno(t, syscall.EIO, errno)
+	require.EqualErrno(t, syscall.EIO, f.Close())
+	require.EqualError(t, replayFS.Err(),
+		`replay: operation 2 File.Write("wasi") on file 1 doesn't match the recording File.Write("wasm") on file 1`)
+}
+
+func TestRecordFS_OpenByID(t *testing.T) {
+	memFS := NewMemFS()
+	require.EqualErrno(t, 0, WriteFileAtomic(memFS, "file", []byte("wazero"), 0o600))
+	st, errno := memFS.Stat("file")
+	require.EqualErrno(t, 0, errno)
+
+	var recording bytes.Buffer
+	recordFS := NewRecordFS(memFS, &recording)
+	f, errno := recordFS.OpenByID(st.Dev, st.Ino, os.O_RDONLY)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []byte("wazero"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+
+	// The path is replayed, as it isn't in the arguments.
+	replayFS := NewReplayFS(&recording)
+	f, errno = replayFS.OpenByID(st.Dev, st.Ino, os.O_RDONLY)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "file", f.Path())
+	require.Equal(t, []byte("wazero"), readAll(t, f))
+	require.EqualErrno(t, 0, f.Close())
+	require.NoError(t, replayFS.Err())
+}
@@ -0,0 +1,267 @@
+package sysfs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// RewriteRule replaces the guest path prefix From with To. For example, a
+// rule from "/usr/lib" to "/opt/compat/lib" makes "/usr/lib/libc.so" open
+// "/opt/compat/lib/libc.so" in the underlying FS.
+type RewriteRule struct {
+	// From is the guest path prefix, matching whole path components.
+	From string
+	// To replaces From in the path passed to the underlying FS.
+	To string
+}
+
+// NewRewriteFS returns an FS which rewrites paths with `rules` before passing
+// them to `fs`. This is for compatibility shims, where the guest expects
+// files at a different path than they are.
+//
+// # Notes
+//
+//   - The rule with the longest From matching a path wins. Rules don't
+//     chain: the result of a rewrite isn't matched again.
+//   - Guest paths are cleaned before matching, and ".." can't go above the
+//     root, so neither the guest nor a rule can escape `fs`.
+//   - Paths returned are rewritten back: absolute Readlink targets and
+//     platform.File Path. Likewise, absolute Symlink targets are rewritten.
+func NewRewriteFS(fs FS, rules []RewriteRule) FS {
+	ret := &rewriteFS{fs: fs, rules: make([]rewriteRule, len(rules))}
+	for i, r := range rules {
+		ret.rules[i] = rewriteRule{from: cleanRewritePath(r.From), to: cleanRewritePath(r.To)}
+	}
+	sort.SliceStable(ret.rules, func(i, j int) bool {
+		return len(ret.rules[i].from) > len(ret.rules[j].from)
+	})
+
+	// Reverse rules are ordered longest To first, for paths returned.
+	ret.reverse = append([]rewriteRule(nil), ret.rules...)
+	sort.SliceStable(ret.reverse, func(i, j int) bool {
+		return len(ret.reverse[i].to) > len(ret.reverse[j].to)
+	})
+	return ret
+}
+
+type rewriteRule struct {
+	// from and to are cleaned, with no leading slash, so "" is the root.
+	from, to string
+}
+
+type rewriteFS struct {
+	UnimplementedFS
+	fs FS
+
+	// rules are ordered longest from first, so the first match wins.
+	rules []rewriteRule
+	// reverse are rules ordered longest to first, for paths returned.
+	reverse []rewriteRule
+}
+
+// cleanRewritePath cleans `p` relative to the root, so that ".." can't go
+// above it. The result has no leading slash, so the root is "".
+func cleanRewritePath(p string) string {
+	return path.Clean("/" + p)[1:]
+}
+
+// replacePrefix replaces the prefix `from` of `p` with `to`, or returns false
+// if `p` doesn't start with the whole path components of `from`.
+func replacePrefix(p, from, to string) (string, bool) {
+	var rest string
+	switch {
+	case from == "":
+		rest = p
+	case p == from:
+		rest = ""
+	case strings.HasPrefix(p, from) && p[len(from)] == '/':
+		rest = p[len(from)+1:]
+	default:
+		return "", false
+	}
+	switch {
+	case to == "":
+		return rest, true
+	case rest == "":
+		return to, true
+	}
+	return to + "/" + rest, true
+}
+
+// rewrite returns the path in the underlying FS of the guest path `p`.
+func (r *rewriteFS) rewrite(p string) string {
+	p = cleanRewritePath(p)
+	for _, rule := range r.rules {
+		if rewritten, ok := replacePrefix(p, rule.from, rule.to); ok {
+			return rewritten
+		}
+	}
+	return p
+}
+
+// unrewrite returns the guest path of the path `p` in the underlying FS.
+func (r *rewriteFS) unrewrite(p string) string {
+	p = cleanRewritePath(p)
+	for _, rule := range r.reverse {
+		if guestPath, ok := replacePrefix(p, rule.to, rule.from); ok {
+			return guestPath
+		}
+	}
+	return p
+}
+
+// String implements fmt.Stringer
+func (r *rewriteFS) String() string {
+	return fmt.Sprintf("rewrite(%v)", r.fs)
+}
+
+// OpenFile implements FS.OpenFile
+func (r *rewriteFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	f, errno := r.fs.OpenFile(r.rewrite(path), flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &rewriteFile{File: f, path: path}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (r *rewriteFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	f, errno := r.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &rewriteFile{File: f, path: r.unrewrite(f.Path())}, 0
+}
+
+// Lstat implements FS.Lstat
+func (r *rewriteFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return r.fs.Lstat(r.rewrite(path))
+}
+
+// Stat implements FS.Stat
+func (r *rewriteFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return r.fs.Stat(r.rewrite(path))
+}
+
+// Mkdir implements FS.Mkdir
+func (r *rewriteFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return r.fs.Mkdir(r.rewrite(path), perm)
+}
+
+// Chmod implements FS.Chmod
+func (r *rewriteFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return r.fs.Chmod(r.rewrite(path), perm)
+}
+
+// Chown implements FS.Chown
+func (r *rewriteFS) Chown(path string, uid, gid int) syscall.Errno {
+	return r.fs.Chown(r.rewrite(path), uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (r *rewriteFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return r.fs.Lchown(r.rewrite(path), uid, gid)
+}
+
+// Rename implements FS.Rename
+func (r *rewriteFS) Rename(from, to string) syscall.Errno {
+	return r.fs.Rename(r.rewrite(from), r.rewrite(to))
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (r *rewriteFS) ExchangeDir(a, b string) syscall.Errno {
+	return r.fs.ExchangeDir(r.rewrite(a), r.rewrite(b))
+}
+
+// Clone implements FS.Clone
+func (r *rewriteFS) Clone(src, dst string) syscall.Errno {
+	return r.fs.Clone(r.rewrite(src), r.rewrite(dst))
+}
+
+// Rmdir implements FS.Rmdir
+func (r *rewriteFS) Rmdir(path string) syscall.Errno {
+	return r.fs.Rmdir(r.rewrite(path))
+}
+
+// Unlink implements FS.Unlink
+func (r *rewriteFS) Unlink(path string) syscall.Errno {
+	return r.fs.Unlink(r.rewrite(path))
+}
+
+// Link implements FS.Link
+func (r *rewriteFS) Link(oldPath, newPath string) syscall.Errno {
+	return r.fs.Link(r.rewrite(oldPath), r.rewrite(newPath))
+}
+
+// Symlink implements FS.Symlink
+func (r *rewriteFS) Symlink(oldPath, linkName string) syscall.Errno {
+	// Relative targets resolve against the directory of the link, so only
+	// absolute targets are rewritten.
+	if path.IsAbs(oldPath) {
+		oldPath = "/" + r.rewrite(oldPath)
+	}
+	return r.fs.Symlink(oldPath, r.rewrite(linkName))
+}
+
+// Readlink implements FS.Readlink
+func (r *rewriteFS) Readlink(p string) (string, syscall.Errno) {
+	dst, errno := r.fs.Readlink(r.rewrite(p))
+	if errno != 0 {
+		return "", errno
+	}
+	if path.IsAbs(dst) {
+		dst = "/" + r.unrewrite(dst)
+	}
+	return dst, 0
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (r *rewriteFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	// The whole destination is needed to reverse the rewrite.
+	dst, errno := r.Readlink(path)
+	if errno != 0 {
+		return 0, errno
+	}
+	return copy(buf, dst), 0
+}
+
+// Truncate implements FS.Truncate
+func (r *rewriteFS) Truncate(path string, size int64) syscall.Errno {
+	return r.fs.Truncate(r.rewrite(path), size)
+}
+
+// Utimens implements FS.Utimens
+func (r *rewriteFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return r.fs.Utimens(r.rewrite(path), times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (r *rewriteFS) SyncDir(path string) syscall.Errno {
+	return r.fs.SyncDir(r.rewrite(path))
+}
+
+// rewriteFile returns the guest path from Path, instead of the rewritten one.
+type rewriteFile struct {
+	platform.File
+	path string
+}
+
+// Path implements the same method as documented on platform.File
+func (f *rewriteFile) Path() string {
+	return f.path
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *rewriteFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &rewriteFile{File: dup, path: f.path}, 0
+}
@@ -0,0 +1,102 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestRewriteFS(t *testing.T) {
+	memFS := NewMemFS()
+	for _, dir := range []string{"opt", "opt/compat", "opt/compat/lib", "opt/python", "usr", "usr/lib", "etc"} {
+		require.EqualErrno(t, 0, memFS.Mkdir(dir, 0o700))
+	}
+	for _, file := range []string{"opt/compat/lib/libc.so", "opt/python/os.py", "usr/lib/native.so", "etc/passwd"} {
+		f, errno := memFS.OpenFile(file, os.O_RDWR|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+	}
+
+	testFS := NewRewriteFS(memFS, []RewriteRule{
+		{From: "/usr/lib", To: "/opt/compat/lib"},
+		// Nested in the rule above, so it wins for paths under it.
+		{From: "/usr/lib/python", To: "/opt/python"},
+		// Overlaps the rules above, which win for paths under them.
+		{From: "/usr", To: "/u"},
+		// Tries to escape the root, so is cleaned to "/".
+		{From: "/escape", To: "../../.."},
+	})
+
+	t.Run("longest prefix wins", func(t *testing.T) {
+		for guestPath, expected := range map[string]string{
+			"/usr/lib/libc.so":      "opt/compat/lib/libc.so",
+			"usr/lib/libc.so":       "opt/compat/lib/libc.so",
+			"/usr/lib":              "opt/compat/lib",
+			"/usr/lib/python/os.py": "opt/python/os.py",
+			"/usr/lib/python":       "opt/python",
+			"/usr/libfoo":           "u/libfoo", // not a whole component
+			"/usr/bin/sh":           "u/bin/sh",
+			"/usr":                  "u",
+			"/escape/etc/passwd":    "etc/passwd",
+		} {
+			require.Equal(t, expected, testFS.(*rewriteFS).rewrite(guestPath), guestPath)
+		}
+
+		_, errno := testFS.Stat("/usr/lib/libc.so")
+		require.EqualErrno(t, 0, errno)
+		_, errno = testFS.Stat("/usr/lib/python/os.py")
+		require.EqualErrno(t, 0, errno)
+		// The rewritten directory hides the original.
+		_, errno = testFS.Stat("/usr/lib/native.so")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("can't escape", func(t *testing.T) {
+		for guestPath, expected := range map[string]string{
+			"/usr/lib/../../etc":       "etc",
+			"/usr/lib/../../../../etc": "etc",
+			"../usr/lib/libc.so":       "opt/compat/lib/libc.so",
+			"/escape/..":               "",
+		} {
+			require.Equal(t, expected, testFS.(*rewriteFS).rewrite(guestPath), guestPath)
+		}
+	})
+
+	t.Run("paths returned are rewritten back", func(t *testing.T) {
+		f, errno := testFS.OpenFile("/usr/lib/libc.so", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "/usr/lib/libc.so", f.Path())
+		dup, errno := f.Dup()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "/usr/lib/libc.so", dup.Path())
+		require.EqualErrno(t, 0, dup.Close())
+		require.EqualErrno(t, 0, f.Close())
+
+		// Absolute targets are rewritten both ways.
+		require.EqualErrno(t, 0, testFS.Symlink("/usr/lib/python/os.py", "/etc/abs"))
+		target, errno := memFS.Readlink("etc/abs")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "/opt/python/os.py", target)
+		target, errno = testFS.Readlink("/etc/abs")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "/usr/lib/python/os.py", target)
+
+		// Relative targets aren't.
+		require.EqualErrno(t, 0, testFS.Symlink("libc.so", "/usr/lib/rel"))
+		target, errno = testFS.Readlink("/usr/lib/rel")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "libc.so", target)
+	})
+
+	t.Run("two paths", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Rename("/usr/lib/libc.so", "/usr/lib/python/libc.so"))
+		_, errno := memFS.Stat("opt/python/libc.so")
+		require.EqualErrno(t, 0, errno)
+
+		require.EqualErrno(t, 0, testFS.Link("/usr/lib/python/libc.so", "/etc/libc.so"))
+		_, errno = memFS.Stat("etc/libc.so")
+		require.EqualErrno(t, 0, errno)
+	})
+}
@@ -130,20 +130,54 @@ func (c *CompositeFS) OpenFile(path string, flag int, perm fs.FileMode) (f platf
 		switch path {
 		case ".", "/", "":
 			if len(c.rootGuestPaths) > 0 {
-				f = &openRootDir{path: path, c: c, f: f}
+				mounts := make(map[string]FS, len(c.rootGuestPaths))
+				for name, fsI := range c.rootGuestPaths {
+					mounts[name] = c.fs[fsI]
+				}
+				f = &openRootDir{path: path, mounts: mounts, f: f}
 			}
 		}
 	}
 	return
 }
 
+// OpenByID implements FS.OpenByID
+//
+// The file is opened by the first filesystem which has it.
+func (c *CompositeFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	return openByIDFirst(c.fs, dev, ino, flag)
+}
+
+// openByIDFirst returns the file opened by FS.OpenByID of the first of `fss`
+// which has it. syscall.ENOSYS is returned only if all return it.
+func openByIDFirst(fss []FS, dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	errno := syscall.ENOSYS
+	for _, sub := range fss {
+		f, e := sub.OpenByID(dev, ino, flag)
+		switch e {
+		case 0:
+			return f, 0
+		case syscall.ENOSYS:
+		case syscall.ENOENT, syscall.EXDEV:
+			if errno == syscall.ENOSYS || e == syscall.ENOENT {
+				errno = e
+			}
+		default:
+			return nil, e
+		}
+	}
+	return nil, errno
+}
+
 // An openRootDir is a root directory open for reading, which has mounts inside
 // of it.
 type openRootDir struct {
 	platform.DirFile
 
-	path     string
-	c        *CompositeFS
+	path string
+	// mounts are the filesystems mounted directly under this directory, keyed
+	// by name.
+	mounts   map[string]FS
 	f        platform.File     // the directory file itself
 	dirents  []platform.Dirent // the directory contents
 	direntsI int               // the read offset, an index into the files slice
@@ -183,6 +217,16 @@ func (d *openRootDir) Readdir(count int) (dirents []platform.Dirent, errno sysca
 	return
 }
 
+// RewindDir implements the same method as documented on platform.File
+func (d *openRootDir) RewindDir() syscall.Errno {
+	if errno := d.f.RewindDir(); errno != 0 {
+		return errno
+	}
+	// Read the directory again, in case it changed.
+	d.dirents, d.direntsI = nil, 0
+	return 0
+}
+
 func (d *openRootDir) readdir() (errno syscall.Errno) {
 	// readDir reads the directory fully into d.dirents, replacing any entries that
 	// correspond to prefix matches or appending them to the end.
@@ -190,15 +234,15 @@ func (d *openRootDir) readdir() (errno syscall.Errno) {
 		return
 	}
 
-	remaining := make(map[string]int, len(d.c.rootGuestPaths))
-	for k, v := range d.c.rootGuestPaths {
+	remaining := make(map[string]FS, len(d.mounts))
+	for k, v := range d.mounts {
 		remaining[k] = v
 	}
 
 	for i := range d.dirents {
 		e := d.dirents[i]
-		if fsI, ok := remaining[e.Name]; ok {
-			if d.dirents[i], errno = d.rootEntry(e.Name, fsI); errno != 0 {
+		if mount, ok := remaining[e.Name]; ok {
+			if d.dirents[i], errno = d.rootEntry(e.Name, mount); errno != 0 {
 				return
 			}
 			delete(remaining, e.Name)
@@ -206,8 +250,8 @@ func (d *openRootDir) readdir() (errno syscall.Errno) {
 	}
 
 	var di platform.Dirent
-	for n, fsI := range remaining {
-		if di, errno = d.rootEntry(n, fsI); errno != 0 {
+	for n, mount := range remaining {
+		if di, errno = d.rootEntry(n, mount); errno != 0 {
 			return
 		}
 		d.dirents = append(d.dirents, di)
@@ -245,8 +289,8 @@ func (d *openRootDir) Close() syscall.Errno {
 	return d.f.Close()
 }
 
-func (d *openRootDir) rootEntry(name string, fsI int) (platform.Dirent, syscall.Errno) {
-	if st, errno := d.c.fs[fsI].Stat("."); errno != 0 {
+func (d *openRootDir) rootEntry(name string, mount FS) (platform.Dirent, syscall.Errno) {
+	if st, errno := mount.Stat("."); errno != 0 {
 		return platform.Dirent{}, errno
 	} else {
 		return platform.Dirent{Name: name, Ino: st.Ino, Type: st.Mode.Type()}, 0
@@ -299,12 +343,38 @@ func (c *CompositeFS) Rename(from, to string) syscall.Errno {
 	return c.fs[fromFS].Rename(fromPath, toPath)
 }
 
+// ExchangeDir implements FS.ExchangeDir
+func (c *CompositeFS) ExchangeDir(a, b string) syscall.Errno {
+	aFS, aPath := c.chooseFS(a)
+	bFS, bPath := c.chooseFS(b)
+	if aFS != bFS {
+		return syscall.ENOSYS // not yet anyway
+	}
+	return c.fs[aFS].ExchangeDir(aPath, bPath)
+}
+
+// Clone implements FS.Clone
+func (c *CompositeFS) Clone(src, dst string) syscall.Errno {
+	srcFS, srcPath := c.chooseFS(src)
+	dstFS, dstPath := c.chooseFS(dst)
+	if srcFS != dstFS {
+		return syscall.ENOSYS // not yet anyway
+	}
+	return c.fs[srcFS].Clone(srcPath, dstPath)
+}
+
 // Readlink implements FS.Readlink
 func (c *CompositeFS) Readlink(path string) (string, syscall.Errno) {
 	matchIndex, relativePath := c.chooseFS(path)
 	return c.fs[matchIndex].Readlink(relativePath)
 }
 
+// ReadlinkInto implements FS.ReadlinkInto
+func (c *CompositeFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	matchIndex, relativePath := c.chooseFS(path)
+	return c.fs[matchIndex].ReadlinkInto(relativePath, buf)
+}
+
 // Link implements FS.Link.
 func (c *CompositeFS) Link(oldName, newName string) syscall.Errno {
 	fromFS, oldNamePath := c.chooseFS(oldName)
@@ -321,6 +391,12 @@ func (c *CompositeFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFo
 	return c.fs[matchIndex].Utimens(relativePath, times, symlinkFollow)
 }
 
+// SyncDir implements FS.SyncDir
+func (c *CompositeFS) SyncDir(path string) syscall.Errno {
+	matchIndex, relativePath := c.chooseFS(path)
+	return c.fs[matchIndex].SyncDir(relativePath)
+}
+
 // Symlink implements FS.Symlink
 func (c *CompositeFS) Symlink(oldName, link string) (err syscall.Errno) {
 	fromFS, oldNamePath := c.chooseFS(oldName)
@@ -495,6 +571,15 @@ func (*fakeRootFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.F
 	return nil, syscall.ENOENT
 }
 
+// Stat implements FS.Stat
+func (*fakeRootFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	switch path {
+	case ".", "/", "":
+		return platform.Stat_t{Mode: fakeRootDirInfo{}.Mode(), Nlink: 1}, 0
+	}
+	return platform.Stat_t{}, syscall.ENOENT
+}
+
 type fakeRootDir struct{}
 
 func (fakeRootDir) Close() (err error) { return }
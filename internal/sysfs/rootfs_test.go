@@ -156,6 +156,27 @@ func TestRootFS_Open(t *testing.T) {
 	})
 }
 
+func TestRootFS_OpenByID(t *testing.T) {
+	rootFS, tmpFS := NewMemFS(), NewMemFS()
+	f, errno := tmpFS.OpenFile("file", os.O_WRONLY|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	testFS, err := NewRootFS([]FS{rootFS, tmpFS}, []string{"/", "/tmp"})
+	require.NoError(t, err)
+
+	st, errno := testFS.Stat("/tmp/file")
+	require.EqualErrno(t, 0, errno)
+
+	// The root has no such file, so it is opened by the next filesystem.
+	f, errno = testFS.OpenByID(st.Dev, st.Ino, os.O_RDONLY)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "file", f.Path())
+	require.EqualErrno(t, 0, f.Close())
+
+	_, errno = testFS.OpenByID(st.Dev, st.Ino+1, os.O_RDONLY)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+}
+
 func TestRootFS_Stat(t *testing.T) {
 	tmpDir := t.TempDir()
 	require.NoError(t, fstest.WriteTestFiles(tmpDir))
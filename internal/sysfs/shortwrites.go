@@ -0,0 +1,45 @@
+package sysfs
+
+import (
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// shortWritesFile is implemented by WithShortWrites.
+type shortWritesFile struct {
+	platform.File
+}
+
+// Write implements the same method as documented on platform.File
+func (f *shortWritesFile) Write(buf []byte) (int, syscall.Errno) {
+	return shortWrite(f.File.Write(buf))
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *shortWritesFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	return shortWrite(f.File.Writev(bufs))
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *shortWritesFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	return shortWrite(f.File.Pwrite(buf, off))
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *shortWritesFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &shortWritesFile{File: dup}, 0
+}
+
+// shortWrite returns a partial count without syscall.ENOSPC, which the next
+// write will return instead.
+func shortWrite(n int, errno syscall.Errno) (int, syscall.Errno) {
+	if errno == syscall.ENOSPC && n > 0 {
+		return n, 0
+	}
+	return n, errno
+}
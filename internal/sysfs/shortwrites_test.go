@@ -0,0 +1,78 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithShortWrites(t *testing.T) {
+	testFS := NewDirFS(t.TempDir(), WithShortWrites(), WithSyncOnClose())
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	_, ok := f.(*syncOnCloseFile).File.(*shortWritesFile)
+	require.True(t, ok)
+
+	n, errno := f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+}
+
+func TestShortWritesFile(t *testing.T) {
+	// Simulate a disk which fills after 4 bytes.
+	f := &shortWritesFile{File: &quotaFile{quota: 4}}
+
+	n, errno := f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 4, n)
+
+	n, errno = f.Write([]byte("ro"))
+	require.EqualErrno(t, syscall.ENOSPC, errno)
+	require.Zero(t, n)
+
+	n, errno = f.Pwrite([]byte("wazero"), 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 4, n)
+
+	n, errno = f.Writev([][]byte{[]byte("ro")})
+	require.EqualErrno(t, syscall.ENOSPC, errno)
+	require.Zero(t, n)
+}
+
+// quotaFile accepts quota bytes in total, then returns syscall.ENOSPC, like
+// platform.File does when a disk fills part way through a write.
+type quotaFile struct {
+	platform.File  // only the write methods are implemented
+	quota, written int
+}
+
+func (f *quotaFile) write(n int) (int, syscall.Errno) {
+	if avail := f.quota - f.written; n > avail {
+		f.written = f.quota
+		return avail, syscall.ENOSPC
+	}
+	f.written += n
+	return n, 0
+}
+
+func (f *quotaFile) Write(buf []byte) (int, syscall.Errno) {
+	return f.write(len(buf))
+}
+
+func (f *quotaFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	var n int
+	for _, buf := range bufs {
+		n += len(buf)
+	}
+	return f.write(n)
+}
+
+func (f *quotaFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	f.written = int(off)
+	return f.write(len(buf))
+}
@@ -0,0 +1,391 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// NewSingleFileFS returns a read-only FS that exposes exactly one file at
+// `name`, whose contents are streamed from `r`. For example, this can present
+// stdin-like data at a named guest path.
+//
+// # Notes
+//
+//   - Bytes read from `r` are retained, so that Pread, Seek and later opens
+//     of the same file see the same data. This means memory grows with the
+//     amount read.
+//   - The size of the file is reported as zero until `r` is read to the end.
+//   - Any directories in `name` are implicitly created.
+func NewSingleFileFS(name string, r io.Reader) FS {
+	name = cleanPath(name)
+	return &singleFileFS{name: name, data: &streamData{r: r}, dev: syntheticDev()}
+}
+
+type singleFileFS struct {
+	readOnlyFS
+	name string
+	data *streamData
+	// dev is the synthetic device ID of the file and its parents.
+	dev uint64
+}
+
+// streamChunkSize is the most bytes read from the stream at a time.
+const streamChunkSize = 32 * 1024
+
+// streamData is the shared state of all opens of the file.
+type streamData struct {
+	mux sync.Mutex
+	r   io.Reader
+	buf []byte // bytes already read from r
+	eof bool
+}
+
+// readAt reads from buf at `off`, reading more from r as needed. Like a pipe,
+// this returns as soon as any data is available at `off`, so the count may be
+// less than len(p) even before the end of the stream.
+func (s *streamData) readAt(p []byte, off int64) (int, syscall.Errno) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for !s.eof && int64(len(s.buf)) <= off {
+		// Read no more than needed, up to a fixed amount at a time, as `off`
+		// is controlled by the guest.
+		size := int64(streamChunkSize)
+		if remaining := off - int64(len(s.buf)); remaining < size-int64(len(p)) {
+			size = remaining + int64(len(p))
+		}
+		chunk := make([]byte, size)
+		n, err := s.r.Read(chunk)
+		s.buf = append(s.buf, chunk[:n]...)
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			if n > 0 {
+				break // return the data now, and the error on the next read.
+			}
+			return 0, platform.UnwrapOSError(err)
+		} else if n == 0 {
+			break // avoid spinning on a reader that returns nothing.
+		}
+	}
+
+	if off >= int64(len(s.buf)) {
+		return 0, 0
+	}
+	return copy(p, s.buf[off:]), 0
+}
+
+// size returns the length of the stream, or zero if it isn't yet known.
+func (s *streamData) size() int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.eof {
+		return int64(len(s.buf))
+	}
+	return 0
+}
+
+// String implements fmt.Stringer
+func (s *singleFileFS) String() string {
+	return s.name
+}
+
+// lookup returns the depth of the path from the root, and whether it is the
+// file. Any other path is an implicit directory.
+func (s *singleFileFS) lookup(path string) (depth int, isFile bool, errno syscall.Errno) {
+	path = cleanPath(path)
+	switch {
+	case path == "" || path == ".":
+		return 0, false, 0
+	case path == s.name:
+		return strings.Count(path, "/") + 1, true, 0
+	case strings.HasPrefix(s.name, path+"/"):
+		return strings.Count(path, "/") + 1, false, 0
+	}
+	return 0, false, syscall.ENOENT
+}
+
+// stat returns the status of the file or one of its parent directories.
+func (s *singleFileFS) stat(depth int, isFile bool) platform.Stat_t {
+	st := platform.Stat_t{Dev: s.dev, Ino: uint64(depth + 1), Nlink: 1}
+	if isFile {
+		st.Mode = 0o444
+		st.Size = s.data.size()
+	} else {
+		st.Mode = fs.ModeDir | 0o555
+	}
+	return st
+}
+
+// OpenFile implements FS.OpenFile
+func (s *singleFileFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	depth, isFile, errno := s.lookup(path)
+	if errno != 0 {
+		if flag&os.O_CREATE != 0 {
+			return nil, syscall.EROFS
+		}
+		return nil, errno
+	} else if flag&(os.O_WRONLY|os.O_RDWR|os.O_TRUNC|os.O_APPEND) != 0 {
+		if isFile {
+			return nil, syscall.EROFS
+		}
+		return nil, syscall.EISDIR
+	}
+
+	if !isFile {
+		return &singleFileDir{path: path, fs: s, depth: depth}, 0
+	} else if flag&platform.O_DIRECTORY != 0 {
+		return nil, syscall.ENOTDIR
+	}
+	return &singleFile{path: path, fs: s, depth: depth}, 0
+}
+
+// Lstat implements FS.Lstat
+func (s *singleFileFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return s.Stat(path)
+}
+
+// Stat implements FS.Stat
+func (s *singleFileFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	depth, isFile, errno := s.lookup(path)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return s.stat(depth, isFile), 0
+}
+
+// Readlink implements FS.Readlink
+func (s *singleFileFS) Readlink(path string) (string, syscall.Errno) {
+	if _, _, errno := s.lookup(path); errno != 0 {
+		return "", errno
+	}
+	return "", syscall.EINVAL // not a symbolic link
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (s *singleFileFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	if _, _, errno := s.lookup(path); errno != 0 {
+		return 0, errno
+	}
+	return 0, syscall.EINVAL // not a symbolic link
+}
+
+// compile-time check to ensure singleFile implements platform.File.
+var _ platform.File = (*singleFile)(nil)
+
+// singleFile is the file in a singleFileFS, opened for reading.
+type singleFile struct {
+	platform.UnimplementedFile
+
+	path   string
+	fs     *singleFileFS
+	depth  int
+	offset int64
+	closed bool
+}
+
+// Path implements the same method as documented on platform.File
+func (f *singleFile) Path() string {
+	return f.path
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *singleFile) AccessMode() int {
+	return syscall.O_RDONLY
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *singleFile) Stat() (platform.Stat_t, syscall.Errno) {
+	if f.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return f.fs.stat(f.depth, true), 0
+}
+
+// IsDir implements the same method as documented on platform.File
+func (f *singleFile) IsDir() (bool, syscall.Errno) {
+	return false, 0
+}
+
+// Read implements the same method as documented on platform.File
+func (f *singleFile) Read(buf []byte) (n int, errno syscall.Errno) {
+	if n, errno = f.Pread(buf, f.offset); errno == 0 {
+		f.offset += int64(n)
+	}
+	return
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *singleFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(buf) == 0 {
+		return 0, 0
+	}
+	return f.fs.data.readAt(buf, off)
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *singleFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	default: // io.SeekEnd isn't supported as the size may be unknown.
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *singleFile) Readdir(int) ([]platform.Dirent, syscall.Errno) {
+	return nil, syscall.ENOTDIR
+}
+
+// Write implements the same method as documented on platform.File
+func (f *singleFile) Write([]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *singleFile) Writev([][]byte) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *singleFile) Pwrite([]byte, int64) (int, syscall.Errno) {
+	return 0, syscall.EBADF
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *singleFile) Truncate(int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *singleFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *singleFile) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *singleFile) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *singleFile) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// PollRead implements the same method as documented on platform.File
+func (f *singleFile) PollRead(*time.Duration) (ready bool, errno syscall.Errno) {
+	return true, 0 // Reads block on the underlying reader instead.
+}
+
+// Close implements the same method as documented on platform.File
+func (f *singleFile) Close() syscall.Errno {
+	f.closed = true
+	return 0
+}
+
+// compile-time check to ensure singleFileDir implements platform.File.
+var _ platform.File = (*singleFileDir)(nil)
+
+// singleFileDir is a directory in a singleFileFS, opened for reading.
+type singleFileDir struct {
+	platform.DirFile
+
+	path   string
+	fs     *singleFileFS
+	depth  int
+	read   bool // whether the only entry was returned by Readdir
+	closed bool
+}
+
+// Path implements the same method as documented on platform.File
+func (d *singleFileDir) Path() string {
+	return d.path
+}
+
+// Stat implements the same method as documented on platform.File
+func (d *singleFileDir) Stat() (platform.Stat_t, syscall.Errno) {
+	if d.closed {
+		return platform.Stat_t{}, syscall.EBADF
+	}
+	return d.fs.stat(d.depth, false), 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (d *singleFileDir) Readdir(int) (dirents []platform.Dirent, errno syscall.Errno) {
+	if d.closed || d.read {
+		return
+	}
+	d.read = true
+
+	// The only entry is the next element of the file's path.
+	name := strings.Split(d.fs.name, "/")[d.depth]
+	isFile := d.depth == strings.Count(d.fs.name, "/")
+	st := d.fs.stat(d.depth+1, isFile)
+	return []platform.Dirent{{Name: name, Ino: st.Ino, Type: st.Mode.Type()}}, 0
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (d *singleFileDir) RewindDir() syscall.Errno {
+	if d.closed {
+		return syscall.EBADF
+	}
+	d.read = false
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (d *singleFileDir) Sync() syscall.Errno {
+	return 0
+}
+
+// Datasync implements the same method as documented on platform.File
+func (d *singleFileDir) Datasync() syscall.Errno {
+	return 0
+}
+
+// Chmod implements the same method as documented on platform.File
+func (d *singleFileDir) Chmod(fs.FileMode) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Chown implements the same method as documented on platform.File
+func (d *singleFileDir) Chown(int, int) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Utimens implements the same method as documented on platform.File
+func (d *singleFileDir) Utimens(*[2]syscall.Timespec) syscall.Errno {
+	return syscall.EBADF
+}
+
+// Close implements the same method as documented on platform.File
+func (d *singleFileDir) Close() syscall.Errno {
+	d.closed = true
+	return 0
+}
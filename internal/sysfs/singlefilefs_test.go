@@ -0,0 +1,193 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/iotest"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewSingleFileFS(t *testing.T) {
+	testFS := NewSingleFileFS("/in/data.txt", strings.NewReader("wazero"))
+	require.Equal(t, "in/data.txt", testFS.String())
+
+	t.Run("Readdir", func(t *testing.T) {
+		d, errno := testFS.OpenFile(".", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer d.Close()
+
+		dirents, errno := d.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []platform.Dirent{{Name: "in", Ino: 2, Type: fs.ModeDir}}, dirents)
+
+		dirents, errno = d.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, len(dirents))
+
+		d, errno = testFS.OpenFile("in", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer d.Close()
+
+		dirents, errno = d.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []platform.Dirent{{Name: "data.txt", Ino: 3}}, dirents)
+	})
+
+	f, errno := testFS.OpenFile("in/data.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	// Size is unknown until the reader is exhausted.
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.FileMode(0o444), st.Mode)
+	require.Zero(t, st.Size)
+
+	buf := make([]byte, 3)
+	requireRead(t, f, buf)
+	require.Equal(t, "waz", string(buf))
+
+	// Pread can read buffered bytes, and returns early at the end of them.
+	n, errno := f.Pread(buf, 1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "az", string(buf[:n]))
+
+	requireRead(t, f, buf)
+	require.Equal(t, "ero", string(buf))
+
+	n, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+
+	st, errno = testFS.Stat("in/data.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+
+	// Another open sees the same data.
+	f2, errno := testFS.OpenFile("/in/data.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f2.Close()
+	b, err := io.ReadAll(io.NewSectionReader(platform.AsReadWriterAt(f2), 0, 100))
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+}
+
+func TestNewSingleFileFS_shortRead(t *testing.T) {
+	testFS := NewSingleFileFS("data.txt", iotest.OneByteReader(strings.NewReader("wazero")))
+
+	f, errno := testFS.OpenFile("data.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	// Like a pipe, Read returns what's available instead of blocking to fill
+	// the buffer.
+	buf := make([]byte, 3)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "w", string(buf[:n]))
+
+	n, errno = f.Pread(buf, 3)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "e", string(buf[:n]))
+}
+
+func TestNewSingleFileFS_hugeOffset(t *testing.T) {
+	testFS := NewSingleFileFS("data.txt", strings.NewReader("wazero"))
+
+	f, errno := testFS.OpenFile("data.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	// Reading past the end doesn't allocate up to the offset, nor overflow.
+	buf := make([]byte, 3)
+	for _, off := range []int64{1 << 40, math.MaxInt64 - 1} {
+		n, errno := f.Pread(buf, off)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+	}
+
+	n, errno := f.Pread(buf, 3)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "ero", string(buf[:n]))
+}
+
+func TestNewSingleFileFS_EOF(t *testing.T) {
+	for _, r := range []io.Reader{
+		iotest.DataErrReader(strings.NewReader("wazero")),
+		iotest.HalfReader(strings.NewReader("wazero")),
+	} {
+		f, errno := NewSingleFileFS("data.txt", r).OpenFile("data.txt", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+
+		var b []byte
+		buf := make([]byte, 4)
+		for {
+			n, errno := f.Read(buf)
+			require.EqualErrno(t, 0, errno)
+			if n == 0 {
+				break
+			}
+			b = append(b, buf[:n]...)
+		}
+		require.Equal(t, "wazero", string(b))
+
+		// Subsequent reads consistently return zero.
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+		require.EqualErrno(t, 0, f.Close())
+	}
+
+	// An error returned with data is deferred to the next read.
+	f, errno := NewSingleFileFS("data.txt", iotest.TimeoutReader(strings.NewReader("wazero"))).
+		OpenFile("data.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	buf := make([]byte, 6)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazero", string(buf[:n]))
+	_, errno = f.Read(buf)
+	require.EqualErrno(t, syscall.EIO, errno)
+}
+
+func TestNewSingleFileFS_Errors(t *testing.T) {
+	testFS := NewSingleFileFS("data.txt", strings.NewReader("wazero"))
+
+	_, errno := testFS.OpenFile("nope", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+	_, errno = testFS.OpenFile("nope", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, syscall.EROFS, errno)
+	_, errno = testFS.OpenFile("data.txt", os.O_WRONLY, 0)
+	require.EqualErrno(t, syscall.EROFS, errno)
+	_, errno = testFS.OpenFile("data.txt", os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, syscall.ENOTDIR, errno)
+	_, errno = testFS.Readlink("data.txt")
+	require.EqualErrno(t, syscall.EINVAL, errno)
+	require.EqualErrno(t, syscall.EROFS, testFS.Unlink("data.txt"))
+
+	f, errno := testFS.OpenFile("data.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	_, errno = f.Write([]byte{1})
+	require.EqualErrno(t, syscall.EBADF, errno)
+	_, errno = f.Seek(0, io.SeekEnd)
+	require.EqualErrno(t, syscall.EINVAL, errno)
+	_, errno = f.Readdir(-1)
+	require.EqualErrno(t, syscall.ENOTDIR, errno)
+}
+
+func requireRead(t *testing.T, f platform.File, buf []byte) {
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, len(buf), n)
+}
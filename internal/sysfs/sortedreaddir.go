@@ -0,0 +1,101 @@
+package sysfs
+
+import (
+	"sort"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// WithSortedReaddir makes directories opened by an FS returned by NewDirFS
+// return entries sorted by name, in byte order, instead of the order of the
+// host. This makes listings the same on all platforms, for example, for
+// golden tests.
+//
+// # Notes
+//
+//   - The whole directory is read and sorted on the first read, so this
+//     defeats streaming of huge directories. It is intended for reproducible
+//     test runs.
+//   - Like a host directory, entries added or removed after the first read
+//     are seen after platform.File RewindDir.
+//   - NewMemFS always returns entries sorted by name.
+func WithSortedReaddir() DirFSOption {
+	return func(d *dirFS) {
+		d.sortedReaddir = true
+	}
+}
+
+// sortedReaddirFile is implemented by WithSortedReaddir.
+type sortedReaddirFile struct {
+	platform.File
+
+	// dirents are all entries sorted by name, or nil if not yet read.
+	dirents []platform.Dirent
+	// pos is the index in dirents of the next entry to read.
+	pos int
+}
+
+// load reads and sorts all entries, if not yet read.
+func (f *sortedReaddirFile) load() syscall.Errno {
+	if f.dirents != nil {
+		return 0
+	}
+	dirents, errno := f.File.Readdir(-1)
+	if errno != 0 {
+		return errno
+	}
+	sort.Slice(dirents, func(i, j int) bool { return dirents[i].Name < dirents[j].Name })
+	f.dirents = append(make([]platform.Dirent, 0, len(dirents)), dirents...)
+	return 0
+}
+
+// Readdir implements the same method as documented on platform.File
+func (f *sortedReaddirFile) Readdir(n int) ([]platform.Dirent, syscall.Errno) {
+	if errno := f.load(); errno != 0 {
+		return nil, errno
+	}
+	remaining := f.dirents[f.pos:]
+	if n <= 0 || n > len(remaining) {
+		n = len(remaining)
+	}
+	f.pos += n
+	return append([]platform.Dirent(nil), remaining[:n]...), 0
+}
+
+// ReaddirFrom implements the same method as documented on
+// platform.ReaddirFrom
+//
+// The cookie is the index of the entry in sorted order.
+func (f *sortedReaddirFile) ReaddirFrom(cookie uint64, n int) ([]platform.Dirent, syscall.Errno) {
+	if errno := f.load(); errno != 0 {
+		return nil, errno
+	}
+	if cookie > uint64(len(f.dirents)) {
+		cookie = uint64(len(f.dirents))
+	}
+	f.pos = int(cookie)
+	dirents, errno := f.Readdir(n)
+	for i := range dirents {
+		dirents[i].Cookie = cookie + uint64(i) + 1
+	}
+	return dirents, errno
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (f *sortedReaddirFile) RewindDir() syscall.Errno {
+	if errno := f.File.RewindDir(); errno != 0 {
+		return errno
+	}
+	f.dirents, f.pos = nil, 0
+	return 0
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *sortedReaddirFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &sortedReaddirFile{File: dup, dirents: f.dirents, pos: f.pos}, 0
+}
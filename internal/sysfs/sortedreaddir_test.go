@@ -0,0 +1,79 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithSortedReaddir(t *testing.T) {
+	tmpDir := t.TempDir()
+	// Created out of order, and with names whose order differs between byte
+	// order and collation, such as upper and lower case.
+	for _, name := range []string{"b", "Z", "a10", "a2", "_", "é", "A", "a"} {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, name), nil, 0o600))
+	}
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+	expected := []string{"A", "Z", "_", "a", "a10", "a2", "b", "dir", "é"}
+
+	for _, testFS := range []FS{NewDirFS(tmpDir, WithSortedReaddir()), newMemFSFromDir(t, tmpDir)} {
+		dir, errno := testFS.OpenFile(".", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+
+		// Reading in batches continues in order.
+		var names []string
+		for {
+			dirents, errno := dir.Readdir(2)
+			require.EqualErrno(t, 0, errno)
+			if len(dirents) == 0 {
+				break
+			}
+			for _, d := range dirents {
+				names = append(names, d.Name)
+			}
+		}
+		require.Equal(t, expected, names, testFS.String())
+
+		// Resuming at a cookie continues in the same order.
+		dirents, errno := platform.ReaddirFrom(dir, 3, 2)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 2, len(dirents))
+		require.Equal(t, expected[3], dirents[0].Name)
+		require.Equal(t, expected[4], dirents[1].Name)
+		dirents, errno = platform.ReaddirFrom(dir, dirents[1].Cookie, 1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, expected[5], dirents[0].Name)
+
+		require.EqualErrno(t, 0, dir.RewindDir())
+		dirents, errno = dir.Readdir(1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, expected[0], dirents[0].Name)
+		require.EqualErrno(t, 0, dir.Close())
+	}
+
+	// Regular files aren't wrapped.
+	f, errno := NewDirFS(tmpDir, WithSortedReaddir()).OpenFile("a", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	_, ok := f.(*sortedReaddirFile)
+	require.False(t, ok)
+	require.EqualErrno(t, 0, f.Close())
+}
+
+// newMemFSFromDir returns a MemFS with the same entries as the top level of
+// `dir`.
+func newMemFSFromDir(t *testing.T, dir string) FS {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	testFS := NewMemFS()
+	for _, e := range entries {
+		if e.IsDir() {
+			require.EqualErrno(t, 0, testFS.Mkdir(e.Name(), 0o700))
+		} else {
+			require.EqualErrno(t, 0, WriteFileAtomic(testFS, e.Name(), nil, 0o600))
+		}
+	}
+	return testFS
+}
@@ -0,0 +1,99 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// newSyncOnCloseFile returns a file which calls Sync before Close if modified,
+// or the input if it wasn't opened for writing.
+func newSyncOnCloseFile(f platform.File, flag int) platform.File {
+	if f.AccessMode() == syscall.O_RDONLY {
+		return f
+	}
+	// Creating or truncating a file modifies it before any write.
+	dirty := flag&(os.O_CREATE|os.O_TRUNC) != 0
+	return &syncOnCloseFile{File: f, dirty: dirty}
+}
+
+// syncOnCloseFile is implemented by WithSyncOnClose.
+type syncOnCloseFile struct {
+	platform.File
+
+	// dirty is true when the file was modified since the last Sync.
+	dirty bool
+}
+
+// Write implements the same method as documented on platform.File
+func (f *syncOnCloseFile) Write(buf []byte) (n int, errno syscall.Errno) {
+	if n, errno = f.File.Write(buf); n > 0 {
+		f.dirty = true
+	}
+	return
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *syncOnCloseFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	if n, errno = f.File.Writev(bufs); n > 0 {
+		f.dirty = true
+	}
+	return
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *syncOnCloseFile) Pwrite(buf []byte, off int64) (n int, errno syscall.Errno) {
+	if n, errno = f.File.Pwrite(buf, off); n > 0 {
+		f.dirty = true
+	}
+	return
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *syncOnCloseFile) Truncate(size int64) (errno syscall.Errno) {
+	if errno = f.File.Truncate(size); errno == 0 {
+		f.dirty = true
+	}
+	return
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *syncOnCloseFile) PunchHole(offset, length int64) (errno syscall.Errno) {
+	if errno = f.File.PunchHole(offset, length); errno == 0 {
+		f.dirty = true
+	}
+	return
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *syncOnCloseFile) Sync() (errno syscall.Errno) {
+	if errno = f.File.Sync(); errno == 0 {
+		f.dirty = false
+	}
+	return
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *syncOnCloseFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &syncOnCloseFile{File: dup, dirty: f.dirty}, 0
+}
+
+// Close implements the same method as documented on platform.File
+func (f *syncOnCloseFile) Close() syscall.Errno {
+	var errno syscall.Errno
+	if f.dirty {
+		if errno = f.Sync(); errno == syscall.ENOSYS {
+			errno = 0 // best efforts
+		}
+	}
+	// Close even if Sync failed, returning the first error.
+	if closeErrno := f.File.Close(); errno == 0 {
+		errno = closeErrno
+	}
+	return errno
+}
@@ -0,0 +1,137 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithSyncOnClose(t *testing.T) {
+	testFS := NewDirFS(t.TempDir(), WithSyncOnClose())
+
+	f, errno := testFS.OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, ok := f.(*syncOnCloseFile)
+	require.True(t, ok)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Read-only files aren't wrapped.
+	f, errno = testFS.OpenFile("file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	_, ok = f.(*syncOnCloseFile)
+	require.False(t, ok)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Nor are files when the option isn't set.
+	f, errno = NewDirFS(t.TempDir()).OpenFile("file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, ok = f.(*syncOnCloseFile)
+	require.False(t, ok)
+	require.EqualErrno(t, 0, f.Close())
+}
+
+func TestSyncOnCloseFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		flag          int
+		op            func(f platform.File) syscall.Errno
+		expectedSyncs int
+	}{
+		{
+			name:          "unmodified",
+			flag:          os.O_RDWR,
+			op:            func(platform.File) syscall.Errno { return 0 },
+			expectedSyncs: 0,
+		},
+		{
+			name:          "created",
+			flag:          os.O_RDWR | os.O_CREATE,
+			op:            func(platform.File) syscall.Errno { return 0 },
+			expectedSyncs: 1,
+		},
+		{
+			name: "Write",
+			flag: os.O_RDWR,
+			op: func(f platform.File) syscall.Errno {
+				_, errno := f.Write([]byte("wazero"))
+				return errno
+			},
+			expectedSyncs: 1,
+		},
+		{
+			name: "Pwrite",
+			flag: os.O_RDWR,
+			op: func(f platform.File) syscall.Errno {
+				_, errno := f.Pwrite([]byte("wazero"), 1)
+				return errno
+			},
+			expectedSyncs: 1,
+		},
+		{
+			name:          "Truncate",
+			flag:          os.O_RDWR,
+			op:            func(f platform.File) syscall.Errno { return f.Truncate(1) },
+			expectedSyncs: 1,
+		},
+		{
+			name: "synced by guest",
+			flag: os.O_RDWR,
+			op: func(f platform.File) syscall.Errno {
+				if _, errno := f.Write([]byte("wazero")); errno != 0 {
+					return errno
+				}
+				return f.Sync()
+			},
+			expectedSyncs: 1, // not again on close
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			counter := &syncCountFile{}
+			f := newSyncOnCloseFile(counter, tc.flag)
+			require.EqualErrno(t, 0, tc.op(f))
+			require.EqualErrno(t, 0, f.Close())
+			require.Equal(t, tc.expectedSyncs, counter.syncs)
+			require.True(t, counter.closed)
+		})
+	}
+
+	t.Run("Sync error", func(t *testing.T) {
+		counter := &syncCountFile{syncErrno: syscall.EIO}
+		f := newSyncOnCloseFile(counter, os.O_RDWR|os.O_CREATE)
+		require.EqualErrno(t, syscall.EIO, f.Close())
+		require.True(t, counter.closed)
+	})
+
+	t.Run("Sync ENOSYS", func(t *testing.T) {
+		counter := &syncCountFile{syncErrno: syscall.ENOSYS}
+		f := newSyncOnCloseFile(counter, os.O_RDWR|os.O_CREATE)
+		require.EqualErrno(t, 0, f.Close())
+		require.True(t, counter.closed)
+	})
+}
+
+// syncCountFile is a writable file which counts calls to Sync.
+type syncCountFile struct {
+	platform.UnimplementedFile
+	syncs     int
+	syncErrno syscall.Errno
+	closed    bool
+}
+
+func (f *syncCountFile) Path() string                                    { return "file" }
+func (f *syncCountFile) AccessMode() int                                 { return syscall.O_RDWR }
+func (f *syncCountFile) Write(buf []byte) (int, syscall.Errno)           { return len(buf), 0 }
+func (f *syncCountFile) Pwrite(buf []byte, _ int64) (int, syscall.Errno) { return len(buf), 0 }
+func (f *syncCountFile) Truncate(int64) syscall.Errno                    { return 0 }
+func (f *syncCountFile) Close() syscall.Errno                            { f.closed = true; return 0 }
+
+func (f *syncCountFile) Sync() syscall.Errno {
+	f.syncs++
+	return f.syncErrno
+}
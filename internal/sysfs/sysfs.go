@@ -77,6 +77,32 @@ type FS interface {
 	// ^^ TODO: Consider syscall.Open, though this implies defining and
 	// coercing flags and perms similar to what is done in os.OpenFile.
 
+	// OpenByID opens the existing file whose platform.Stat_t has the device
+	// `dev` and inode `ino`, without resolving a path. This allows tools
+	// which follow hard links, such as backups, to re-open a file seen while
+	// walking the directory tree. It should be closed via Close on
+	// platform.File.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation or host does not support this
+	//     function.
+	//   - syscall.EINVAL: `flag` contains os.O_CREATE or os.O_EXCL.
+	//   - syscall.ENOENT: there's no such file in this file system.
+	//   - syscall.EXDEV: `dev` isn't the device of this file system.
+	//   - syscall.EPERM: the host requires privileges to open by ID.
+	//   - syscall.ENOTSUP: the host filesystem doesn't support this.
+	//
+	// # Notes
+	//
+	//   - flag are the same as OpenFile, and are enforced the same way.
+	//   - The path of the returned file is one of its paths in this file
+	//     system, as a file with hard links has several.
+	//   - This is like `open_by_handle_at` in Linux. See
+	//     https://man7.org/linux/man-pages/man2/open_by_handle_at.2.html
+	OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno)
+
 	// Lstat gets file status without following symbolic links.
 	//
 	// # Errors
@@ -216,6 +242,52 @@ type FS interface {
 	//   -  Windows doesn't let you overwrite an existing directory.
 	Rename(from, to string) syscall.Errno
 
+	// ExchangeDir atomically swaps two directories, so that each path refers
+	// to the directory at the other. For example, this allows a build tool to
+	// replace a directory tree without a window where it is missing.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation or host does not support this
+	//     function.
+	//   - syscall.EINVAL: `a` or `b` is invalid.
+	//   - syscall.ENOENT: `a` or `b` don't exist.
+	//   - syscall.ENOTDIR: `a` or `b` exist, but aren't directories.
+	//   - syscall.EXDEV: `a` and `b` are on different file systems.
+	//
+	// # Notes
+	//
+	//   - This is like `renameat2` with `RENAME_EXCHANGE` in Linux. See
+	//     https://man7.org/linux/man-pages/man2/rename.2.html
+	//   - Only Linux supports this, so other platforms return syscall.ENOSYS.
+	ExchangeDir(a, b string) syscall.Errno
+
+	// Clone creates `dst` as a copy of the regular file `src`, which shares
+	// its data until either is written. On filesystems which support this,
+	// such as btrfs, XFS and APFS, it is instant regardless of the file size.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation does not support this function.
+	//   - syscall.ENOTSUP: the filesystem can't clone `src`, e.g. ext4.
+	//     Callers should fall back to copying the data.
+	//   - syscall.EINVAL: `src` or `dst` is invalid.
+	//   - syscall.ENOENT: `src` doesn't exist or `dst` is in a directory that
+	//     doesn't exist.
+	//   - syscall.EEXIST: `dst` already exists.
+	//   - syscall.EISDIR: `src` is a directory.
+	//   - syscall.EXDEV: `src` and `dst` are on different file systems.
+	//   - syscall.EROFS: the file system is read-only.
+	//
+	// # Notes
+	//
+	//   - This is like the `FICLONE` ioctl in Linux or `clonefile` in Darwin.
+	//     See https://man7.org/linux/man-pages/man2/ioctl_ficlone.2.html
+	//   - Other platforms return syscall.ENOTSUP.
+	Clone(src, dst string) syscall.Errno
+
 	// Rmdir removes a directory.
 	//
 	// # Errors
@@ -225,7 +297,9 @@ type FS interface {
 	//   - syscall.EINVAL: `path` is invalid.
 	//   - syscall.ENOENT: `path` doesn't exist.
 	//   - syscall.ENOTDIR: `path` exists, but isn't a directory.
-	//   - syscall.ENOTEMPTY: `path` exists, but isn't empty.
+	//   - syscall.ENOTEMPTY: `path` exists, but isn't empty. This is
+	//     returned even where the host returns syscall.EEXIST, which POSIX
+	//     also allows.
 	//
 	// # Notes
 	//
@@ -321,6 +395,20 @@ type FS interface {
 	//     separator.
 	Readlink(path string) (string, syscall.Errno)
 
+	// ReadlinkInto is like Readlink, except it writes the contents of the
+	// symbolic link into `buf`, returning the count of bytes written.
+	//
+	// When the contents are longer than `buf`, they are truncated, like
+	// `readlink` in POSIX. This avoids allocating a string in callers with a
+	// fixed size buffer, such as WASI path_readlink.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation does not support this function.
+	//   - syscall.EINVAL: `path` is invalid or not a symbolic link.
+	ReadlinkInto(path string, buf []byte) (int, syscall.Errno)
+
 	// Truncate truncates a file to a specified length.
 	//
 	// # Errors
@@ -367,4 +455,25 @@ type FS interface {
 	//   - This is like syscall.UtimesNano and `utimensat` with `AT_FDCWD` in
 	//     POSIX. See https://pubs.opengroup.org/onlinepubs/9699919799/functions/futimens.html
 	Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno
+
+	// SyncDir flushes the entries of the directory `path` to storage, so
+	// that a crash doesn't lose a file created, renamed or removed in it.
+	//
+	// # Errors
+	//
+	// A zero syscall.Errno is success. The below are expected otherwise:
+	//   - syscall.ENOSYS: the implementation does not support this function.
+	//   - syscall.ENOENT: `path` doesn't exist.
+	//   - syscall.ENOTDIR: `path` exists, but isn't a directory.
+	//   - syscall.EROFS: the file system is read-only.
+	//
+	// # Notes
+	//
+	//   - This is like `fsync` in POSIX, on a directory opened for read. See
+	//     https://pubs.opengroup.org/onlinepubs/9699919799/functions/fsync.html
+	//   - platform.File Sync of a file doesn't include its directory entry,
+	//     so a rename isn't durable until its directory is synced.
+	//   - Windows doesn't support syncing a directory, so this only checks
+	//     `path` is a directory.
+	SyncDir(path string) syscall.Errno
 }
@@ -1,6 +1,7 @@
 package sysfs
 
 import (
+	"crypto/sha256"
 	_ "embed"
 	"io"
 	"io/fs"
@@ -10,6 +11,8 @@ import (
 	"sort"
 	"syscall"
 	"testing"
+	gofstest "testing/fstest"
+	"time"
 
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -68,6 +71,34 @@ func testOpen_O_RDWR(t *testing.T, tmpDir string, testFS FS) {
 	}
 }
 
+// testOpen_dirOrFile requires that opening "dir", a directory, or "file", a
+// regular file, fails early when the flags don't match its type.
+func testOpen_dirOrFile(t *testing.T, testFS FS) {
+	tests := []struct {
+		name     string
+		path     string
+		flag     int
+		expected syscall.Errno
+	}{
+		{name: "dir O_WRONLY", path: "dir", flag: os.O_WRONLY, expected: syscall.EISDIR},
+		{name: "dir O_RDWR", path: "dir", flag: os.O_RDWR, expected: syscall.EISDIR},
+		{name: "dir O_DIRECTORY", path: "dir", flag: os.O_RDONLY | platform.O_DIRECTORY},
+		{name: "file O_RDWR", path: "file", flag: os.O_RDWR},
+		{name: "file O_DIRECTORY", path: "file", flag: os.O_RDONLY | platform.O_DIRECTORY, expected: syscall.ENOTDIR},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			f, errno := testFS.OpenFile(tc.path, tc.flag, 0)
+			require.EqualErrno(t, tc.expected, errno)
+			if errno == 0 {
+				require.EqualErrno(t, 0, f.Close())
+			}
+		})
+	}
+}
+
 func testOpen_Read(t *testing.T, testFS FS, expectIno bool) {
 	t.Run("doesn't exist", func(t *testing.T) {
 		_, errno := testFS.OpenFile("nope", os.O_RDONLY, 0)
@@ -339,6 +370,16 @@ func testReadlink(t *testing.T, readFS, writeFS FS) {
 		dst, errno := readFS.Readlink(tl.dst)
 		require.EqualErrno(t, 0, errno)
 		require.Equal(t, tl.old, dst)
+
+		buf := make([]byte, 64)
+		n, errno := readFS.ReadlinkInto(tl.dst, buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, tl.old, string(buf[:n]))
+
+		// A smaller buffer truncates, like readlink(2).
+		n, errno = readFS.ReadlinkInto(tl.dst, buf[:3])
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, tl.old[:3], string(buf[:n]))
 	}
 
 	t.Run("errors", func(t *testing.T) {
@@ -348,6 +389,8 @@ func testReadlink(t *testing.T, readFS, writeFS FS) {
 		require.Error(t, err)
 		_, err = readFS.Readlink("animals.txt")
 		require.Error(t, err)
+		_, err = readFS.ReadlinkInto("animals.txt", make([]byte, 64))
+		require.Error(t, err)
 	})
 }
 
@@ -368,3 +411,140 @@ func requireIno(t *testing.T, dirents []platform.Dirent, expectIno bool) {
 func joinPath(dirName, baseName string) string {
 	return path.Join(dirName, baseName)
 }
+
+// TestRmdir_errno ensures implementations return the same errors when a
+// directory can't be removed, regardless of the host.
+func TestRmdir_errno(t *testing.T) {
+	// setup creates a non-empty directory, a file and an empty directory.
+	setup := func(t *testing.T, testFS FS) {
+		require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+		require.EqualErrno(t, 0, WriteFileAtomic(testFS, "dir/file", nil, 0o600))
+		require.EqualErrno(t, 0, testFS.Mkdir("empty", 0o700))
+	}
+
+	tests := []struct {
+		name   string
+		testFS func(t *testing.T) FS
+		// expectEmpty is the result of removing an empty directory.
+		expectEmpty syscall.Errno
+	}{
+		{
+			name: "DirFS",
+			testFS: func(t *testing.T) FS {
+				testFS := NewDirFS(t.TempDir())
+				setup(t, testFS)
+				return testFS
+			},
+		},
+		{
+			name: "MemFS",
+			testFS: func(t *testing.T) FS {
+				testFS := NewMemFS()
+				setup(t, testFS)
+				return testFS
+			},
+		},
+		{
+			name: "ReadFS",
+			testFS: func(t *testing.T) FS {
+				testFS := NewDirFS(t.TempDir())
+				setup(t, testFS)
+				return NewReadFS(testFS)
+			},
+			expectEmpty: syscall.EROFS,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			testFS := tc.testFS(t)
+			for _, c := range []struct {
+				path     string
+				expected syscall.Errno
+			}{
+				{path: "dir", expected: syscall.ENOTEMPTY},
+				{path: "dir/file", expected: syscall.ENOTDIR},
+				{path: "missing", expected: syscall.ENOENT},
+				{path: "dir/missing", expected: syscall.ENOENT},
+				{path: "empty", expected: tc.expectEmpty},
+			} {
+				require.EqualErrno(t, c.expected, testFS.Rmdir(c.path), c.path)
+			}
+		})
+	}
+}
+
+// TestOpenByID_wrappers ensures FS wrappers forward OpenByID, and wrap the
+// file the same as OpenFile.
+func TestOpenByID_wrappers(t *testing.T) {
+	isText := func(string) bool { return true }
+	for _, tc := range []struct {
+		name string
+		wrap func(FS) FS
+		// path is the guest path of the file, when rewritten.
+		path string
+	}{
+		{name: "AccessStatsFS", wrap: func(fs FS) FS { return NewAccessStatsFS(fs) }},
+		{name: "ChecksumFS", wrap: func(fs FS) FS { return NewChecksumFS(fs, sha256.New) }},
+		{name: "compressedFS", wrap: func(fs FS) FS {
+			ret, _ := NewCompressedFS(fs, CompressionGzip)
+			return ret
+		}},
+		{name: "encryptedFS", wrap: func(fs FS) FS {
+			ret, _ := NewEncryptedFS(fs, testEncryptionKey)
+			return ret
+		}},
+		{name: "faultFS", wrap: func(fs FS) FS { return NewFaultFS(fs, FaultPolicy{}) }},
+		{name: "GroupCommitFS", wrap: func(fs FS) FS { return NewGroupCommitFS(fs, time.Millisecond) }},
+		{name: "metricsFS", wrap: func(fs FS) FS {
+			return NewMetricsFS(fs, &testMetrics{ops: map[string]int{}, bytes: map[string]int{}, latencies: map[string]int{}})
+		}},
+		{name: "recordFS", wrap: func(fs FS) FS { return NewRecordFS(fs, io.Discard) }},
+		{name: "rewriteFS", path: "guest/file", wrap: func(fs FS) FS {
+			_ = fs.Mkdir("host", 0o700)
+			_ = fs.Mkdir("guest", 0o700)
+			return NewRewriteFS(fs, []RewriteRule{{From: "guest", To: "host"}})
+		}},
+		{name: "textFS", wrap: func(fs FS) FS { return NewTextFS(fs, isText, EolLF) }},
+		{name: "timedReadFS", wrap: func(fs FS) FS { return NewTimedReadFS(fs, time.Now().Add(time.Hour)) }},
+		{name: "writableAdapter", wrap: func(fs FS) FS { return AdaptWithWritable(gofstest.MapFS{}, fs) }},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			testFS := tc.wrap(NewMemFS())
+			p := tc.path
+			if p == "" {
+				p = "file"
+			}
+			f, errno := testFS.OpenFile(p, os.O_WRONLY|os.O_CREATE, 0o600)
+			require.EqualErrno(t, 0, errno)
+			_, errno = f.Write([]byte("wazero"))
+			require.EqualErrno(t, 0, errno)
+			require.EqualErrno(t, 0, f.Close())
+			st, errno := testFS.Stat(p)
+			require.EqualErrno(t, 0, errno)
+
+			f, errno = testFS.OpenByID(st.Dev, st.Ino, os.O_RDONLY)
+			require.EqualErrno(t, 0, errno)
+			defer f.Close()
+			require.Equal(t, p, f.Path())
+			require.Equal(t, []byte("wazero"), readAll(t, f))
+
+			f, errno = testFS.OpenByID(st.Dev, st.Ino, os.O_WRONLY|os.O_TRUNC)
+			require.EqualErrno(t, 0, errno)
+			_, errno = f.Write([]byte("zero"))
+			require.EqualErrno(t, 0, errno)
+			require.EqualErrno(t, 0, f.Close())
+			f, errno = testFS.OpenFile(p, os.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			defer f.Close()
+			require.Equal(t, []byte("zero"), readAll(t, f))
+		})
+	}
+
+	// hiddenFS doesn't forward on purpose, as it couldn't hide the file.
+	testFS := NewNoDotfilesFS(NewMemFS())
+	_, errno := testFS.OpenByID(0, 1, os.O_RDONLY)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+}
@@ -0,0 +1,486 @@
+package sysfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// EolMode is the line ending the guest sees in files matched by NewTextFS.
+type EolMode int
+
+const (
+	// EolLF shows the guest LF line endings, for files with CRLF line
+	// endings on the host, such as text files checked out on Windows.
+	EolLF EolMode = iota
+	// EolCRLF shows the guest CRLF line endings, for files with LF line
+	// endings on the host.
+	EolCRLF
+)
+
+// NewTextFS returns an FS which delegates to `fs`, except files whose path
+// `match` returns true for have their line endings converted to `mode` when
+// read, and back when written. Other files pass through untouched.
+//
+// # Notes
+//
+//   - A matching file is buffered in memory while open, so that offsets of
+//     Pread, Pwrite and Seek are in the converted content. Writes are
+//     converted back when the file is synced or closed. Growing it past
+//     platform.MaxMemoryFileSize fails with syscall.EFBIG.
+//   - Stat_t.Size of a matching file is the size of the converted content.
+//     This reads the file on FS.Stat and FS.Lstat.
+//   - Converting back normalizes all line endings of a written file, even
+//     unmodified lines with the other line ending on the host.
+func NewTextFS(fs FS, match func(path string) bool, mode EolMode) FS {
+	return &textFS{fs: fs, match: match, mode: mode}
+}
+
+type textFS struct {
+	UnimplementedFS
+	fs    FS
+	match func(path string) bool
+	mode  EolMode
+}
+
+// toGuest converts host content to the line endings the guest sees.
+func (t *textFS) toGuest(b []byte) []byte {
+	if t.mode == EolLF {
+		return toLF(b)
+	}
+	return toCRLF(b)
+}
+
+// toHost converts guest content to the line endings of the host.
+func (t *textFS) toHost(b []byte) []byte {
+	if t.mode == EolLF {
+		return toCRLF(b)
+	}
+	return toLF(b)
+}
+
+// toLF converts CRLF to LF.
+func toLF(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+}
+
+// toCRLF converts LF to CRLF, except when it is already preceded by CR.
+func toCRLF(b []byte) []byte {
+	converted := make([]byte, 0, len(b)+bytes.Count(b, []byte("\n")))
+	for i, c := range b {
+		if c == '\n' && (i == 0 || b[i-1] != '\r') {
+			converted = append(converted, '\r')
+		}
+		converted = append(converted, c)
+	}
+	return converted
+}
+
+// String implements fmt.Stringer
+func (t *textFS) String() string {
+	return t.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (t *textFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	if !t.match(path) {
+		return t.fs.OpenFile(path, flag, perm)
+	}
+	return t.open(flag, func(flag int) (platform.File, syscall.Errno) {
+		return t.fs.OpenFile(path, flag, perm)
+	})
+}
+
+// OpenByID implements FS.OpenByID
+func (t *textFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	// The path is only known once open, so check it with `flag` first.
+	f, errno := t.fs.OpenByID(dev, ino, flag)
+	if errno != 0 || !t.match(cleanPath(f.Path())) {
+		return f, errno
+	}
+	_ = f.Close()
+	return t.open(flag, func(flag int) (platform.File, syscall.Errno) {
+		return t.fs.OpenByID(dev, ino, flag)
+	})
+}
+
+// open opens a file matched for conversion with `open`, which is called
+// again with `flag` if the file isn't regular.
+func (t *textFS) open(flag int, open func(flag int) (platform.File, syscall.Errno)) (platform.File, syscall.Errno) {
+	// The file is read to convert it, even if the guest can't read it.
+	// Appending is handled by textFile, as it is relative to converted content.
+	accessMode := flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
+	hostFlag := flag &^ os.O_APPEND
+	if accessMode == os.O_WRONLY {
+		hostFlag = hostFlag&^os.O_WRONLY | os.O_RDWR
+	}
+	f, errno := open(hostFlag)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	// Only regular files are converted.
+	st, errno := f.Stat()
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	} else if !st.Mode.IsRegular() {
+		_ = f.Close()
+		return open(flag)
+	}
+
+	host, errno := readAllFile(f)
+	if errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	return &textFile{
+		File:       f,
+		fs:         t,
+		accessMode: accessMode,
+		append:     flag&os.O_APPEND != 0,
+		buf:        t.toGuest(host),
+	}, 0
+}
+
+// readAllFile reads the file from its start.
+func readAllFile(f platform.File) ([]byte, syscall.Errno) {
+	var b []byte
+	buf := make([]byte, 4096)
+	for {
+		n, errno := f.Pread(buf, int64(len(b)))
+		if errno != 0 {
+			return nil, errno
+		} else if n == 0 {
+			return b, 0
+		}
+		b = append(b, buf[:n]...)
+	}
+}
+
+// Lstat implements FS.Lstat
+func (t *textFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := t.fs.Lstat(path)
+	if errno != 0 {
+		return st, errno
+	}
+	return t.convertSize(path, st)
+}
+
+// Stat implements FS.Stat
+func (t *textFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	st, errno := t.fs.Stat(path)
+	if errno != 0 {
+		return st, errno
+	}
+	return t.convertSize(path, st)
+}
+
+// convertSize sets the size of a matching regular file to the size of its
+// converted content.
+func (t *textFS) convertSize(path string, st platform.Stat_t) (platform.Stat_t, syscall.Errno) {
+	if !st.Mode.IsRegular() || !t.match(path) {
+		return st, 0
+	}
+	f, errno := t.fs.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return st, errno
+	}
+	defer f.Close()
+	host, errno := readAllFile(f)
+	if errno != 0 {
+		return st, errno
+	}
+	st.Size = int64(len(t.toGuest(host)))
+	return st, 0
+}
+
+// Mkdir implements FS.Mkdir
+func (t *textFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	return t.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (t *textFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	return t.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (t *textFS) Chown(path string, uid, gid int) syscall.Errno {
+	return t.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (t *textFS) Lchown(path string, uid, gid int) syscall.Errno {
+	return t.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (t *textFS) Rename(from, to string) syscall.Errno {
+	return t.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (t *textFS) ExchangeDir(a, b string) syscall.Errno {
+	return t.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (t *textFS) Clone(src, dst string) syscall.Errno {
+	return t.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (t *textFS) Rmdir(path string) syscall.Errno {
+	return t.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (t *textFS) Unlink(path string) syscall.Errno {
+	return t.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (t *textFS) Link(oldPath, newPath string) syscall.Errno {
+	return t.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (t *textFS) Symlink(oldPath, linkName string) syscall.Errno {
+	return t.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (t *textFS) Readlink(path string) (string, syscall.Errno) {
+	return t.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (t *textFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return t.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (t *textFS) Truncate(path string, size int64) syscall.Errno {
+	if !t.match(path) {
+		return t.fs.Truncate(path, size)
+	}
+	// The size is of the converted content, so convert it.
+	f, errno := t.OpenFile(path, os.O_RDWR, 0)
+	if errno != 0 {
+		return errno
+	}
+	if errno = f.Truncate(size); errno != 0 {
+		_ = f.Close()
+		return errno
+	}
+	return f.Close()
+}
+
+// Utimens implements FS.Utimens
+func (t *textFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	return t.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (t *textFS) SyncDir(path string) syscall.Errno {
+	return t.fs.SyncDir(path)
+}
+
+// textFile buffers the converted content of a file matched by textFS.
+type textFile struct {
+	platform.File
+	fs         *textFS
+	accessMode int
+	append     bool
+
+	// buf is the content the guest sees.
+	buf []byte
+	// offset is the position of Read, Write and Seek in buf.
+	offset int64
+	// dirty is true when buf was written, but not yet converted back.
+	dirty bool
+}
+
+// AccessMode implements the same method as documented on platform.File
+func (f *textFile) AccessMode() int {
+	return f.accessMode
+}
+
+// Stat implements the same method as documented on platform.File
+func (f *textFile) Stat() (platform.Stat_t, syscall.Errno) {
+	st, errno := f.File.Stat()
+	st.Size = int64(len(f.buf))
+	return st, errno
+}
+
+// Read implements the same method as documented on platform.File
+func (f *textFile) Read(buf []byte) (int, syscall.Errno) {
+	n, errno := f.Pread(buf, f.offset)
+	f.offset += int64(n)
+	return n, errno
+}
+
+// Pread implements the same method as documented on platform.File
+func (f *textFile) Pread(buf []byte, off int64) (int, syscall.Errno) {
+	if f.accessMode == os.O_WRONLY {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if off >= int64(len(f.buf)) {
+		return 0, 0
+	}
+	return copy(buf, f.buf[off:]), 0
+}
+
+// BytesAvailable implements the same method as documented on platform.File
+func (f *textFile) BytesAvailable() (int64, syscall.Errno) {
+	if f.accessMode == os.O_WRONLY {
+		return 0, syscall.EBADF
+	} else if size := int64(len(f.buf)); size > f.offset {
+		return size - f.offset, 0
+	}
+	return 0, 0
+}
+
+// Seek implements the same method as documented on platform.File
+func (f *textFile) Seek(offset int64, whence int) (int64, syscall.Errno) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.buf))
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// Write implements the same method as documented on platform.File
+func (f *textFile) Write(buf []byte) (int, syscall.Errno) {
+	if f.append {
+		f.offset = int64(len(f.buf))
+	}
+	n, errno := f.Pwrite(buf, f.offset)
+	f.offset += int64(n)
+	return n, errno
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *textFile) Writev(bufs [][]byte) (n int, errno syscall.Errno) {
+	for _, buf := range bufs {
+		var written int
+		written, errno = f.Write(buf)
+		n += written
+		if errno != 0 {
+			return
+		}
+	}
+	return
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *textFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	if f.accessMode == os.O_RDONLY {
+		return 0, syscall.EBADF
+	} else if off < 0 {
+		return 0, syscall.EINVAL
+	} else if len(buf) == 0 {
+		return 0, 0
+	} else if off > platform.MaxMemoryFileSize-int64(len(buf)) {
+		return 0, syscall.EFBIG // the content is buffered in memory.
+	}
+	if end := off + int64(len(buf)); end > int64(len(f.buf)) {
+		f.resize(end)
+	}
+	f.dirty = true
+	return copy(f.buf[off:], buf), 0
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *textFile) Truncate(size int64) syscall.Errno {
+	if f.accessMode == os.O_RDONLY {
+		return syscall.EBADF
+	} else if size < 0 {
+		return syscall.EINVAL
+	} else if size > platform.MaxMemoryFileSize {
+		return syscall.EFBIG // the content is buffered in memory.
+	}
+	f.resize(size)
+	f.dirty = true
+	return 0
+}
+
+// PunchHole implements the same method as documented on platform.File
+//
+// This returns syscall.ENOSYS, as offsets in the host file differ from those
+// of the guest.
+func (f *textFile) PunchHole(int64, int64) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+// resize grows or shrinks buf, zero-filling any growth.
+func (f *textFile) resize(size int64) {
+	if size <= int64(len(f.buf)) {
+		f.buf = f.buf[:size]
+		return
+	}
+	f.buf = append(f.buf, make([]byte, size-int64(len(f.buf)))...)
+}
+
+// flush converts the content back and writes it to the host file, if it
+// was written.
+func (f *textFile) flush() syscall.Errno {
+	if !f.dirty {
+		return 0
+	}
+	host := f.fs.toHost(f.buf)
+	if errno := f.File.Truncate(int64(len(host))); errno != 0 {
+		return errno
+	} else if _, errno = f.File.Pwrite(host, 0); errno != 0 {
+		return errno
+	}
+	f.dirty = false
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (f *textFile) Sync() syscall.Errno {
+	if errno := f.flush(); errno != 0 {
+		return errno
+	}
+	return f.File.Sync()
+}
+
+// Datasync implements the same method as documented on platform.File
+func (f *textFile) Datasync() syscall.Errno {
+	if errno := f.flush(); errno != 0 {
+		return errno
+	}
+	return f.File.Datasync()
+}
+
+// Dup implements the same method as documented on platform.File
+//
+// This returns syscall.ENOSYS, as the converted content is buffered per file.
+func (f *textFile) Dup() (platform.File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
+
+// Close implements the same method as documented on platform.File
+func (f *textFile) Close() syscall.Errno {
+	errno := f.flush()
+	if closeErrno := f.File.Close(); errno == 0 {
+		errno = closeErrno
+	}
+	return errno
+}
@@ -0,0 +1,109 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewTextFS(t *testing.T) {
+	isText := func(p string) bool { return strings.HasSuffix(p, ".txt") }
+
+	t.Run("EolLF", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "a.txt"), []byte("wa\r\nze\r\nro"), 0o600))
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "a.bin"), []byte("wa\r\nze\r\nro"), 0o600))
+		testFS := NewTextFS(NewDirFS(tmpDir), isText, EolLF)
+
+		// Size is of the converted content.
+		st, errno := testFS.Stat("a.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(8), st.Size)
+		st, errno = testFS.Stat("a.bin")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(10), st.Size)
+
+		f, errno := testFS.OpenFile("a.txt", os.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []byte("wa\nze\nro"), readAll(t, f))
+
+		// Offsets are in the converted content.
+		buf := make([]byte, 2)
+		n, errno := f.Pread(buf, 3)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "ze", string(buf[:n]))
+
+		_, errno = f.Pwrite([]byte("ZE\n"), 3)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Seek(0, io.SeekEnd)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("\n"))
+		require.EqualErrno(t, 0, errno)
+		st, errno = f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(9), st.Size)
+		require.EqualErrno(t, 0, f.Close())
+
+		b, err := os.ReadFile(path.Join(tmpDir, "a.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "wa\r\nZE\r\nro\r\n", string(b))
+
+		// Non-matching files pass through.
+		f, errno = testFS.OpenFile("a.bin", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []byte("wa\r\nze\r\nro"), readAll(t, f))
+		require.EqualErrno(t, 0, f.Close())
+	})
+
+	t.Run("EolCRLF", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "a.txt"), []byte("wa\nzero\n"), 0o600))
+		testFS := NewTextFS(NewDirFS(tmpDir), isText, EolCRLF)
+
+		f, errno := testFS.OpenFile("a.txt", os.O_WRONLY|os.O_APPEND, 0)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Read(make([]byte, 1))
+		require.EqualErrno(t, syscall.EBADF, errno)
+		_, errno = f.Write([]byte("wasm\r\n"))
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		b, err := os.ReadFile(path.Join(tmpDir, "a.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "wa\nzero\nwasm\n", string(b))
+
+		// Truncate is relative to the converted content.
+		require.EqualErrno(t, 0, testFS.Truncate("a.txt", 4))
+		b, err = os.ReadFile(path.Join(tmpDir, "a.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "wa\n", string(b))
+	})
+}
+
+func TestTextFile_hugeFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "a.txt"), []byte("wazero\n"), 0o600))
+	testFS := NewTextFS(NewDirFS(tmpDir), func(string) bool { return true }, EolLF)
+
+	f, errno := testFS.OpenFile("a.txt", os.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+
+	require.EqualErrno(t, syscall.EFBIG, f.Truncate(1<<62))
+	_, errno = f.Pwrite([]byte("wazero"), 1<<62)
+	require.EqualErrno(t, syscall.EFBIG, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	b, err := os.ReadFile(path.Join(tmpDir, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "wazero\n", string(b))
+}
+
+func Test_toCRLF(t *testing.T) {
+	require.Equal(t, "\r\na\r\nb\r\n", string(toCRLF([]byte("\na\r\nb\n"))))
+	require.Equal(t, "\na\nb\n", string(toLF([]byte("\r\na\r\nb\n"))))
+}
@@ -0,0 +1,286 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// NewTimedReadFS returns an FS which delegates to `fs` until
+// `writableUntil`, and then becomes read-only. This allows a guest to set up
+// data, which is immutable for the rest of the run.
+//
+// After the deadline, operations which would modify the filesystem return
+// syscall.EROFS. This includes writes to files opened before the deadline,
+// as each write checks the time.
+func NewTimedReadFS(fs FS, writableUntil time.Time) FS {
+	return &timedReadFS{fs: fs, writableUntil: writableUntil}
+}
+
+type timedReadFS struct {
+	UnimplementedFS
+	fs            FS
+	writableUntil time.Time
+}
+
+// checkWritable returns syscall.EROFS once the deadline passed.
+func (t *timedReadFS) checkWritable() syscall.Errno {
+	if time.Now().Before(t.writableUntil) {
+		return 0
+	}
+	return syscall.EROFS
+}
+
+// String implements fmt.Stringer
+func (t *timedReadFS) String() string {
+	return t.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (t *timedReadFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	readOnly := flag&(os.O_WRONLY|os.O_RDWR) == 0 && flag&(os.O_CREATE|os.O_TRUNC) == 0
+	if !readOnly {
+		if errno := t.checkWritable(); errno != 0 {
+			return nil, errno
+		}
+	}
+	f, errno := t.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	} else if readOnly {
+		return f, 0
+	}
+	return &timedReadFile{File: f, fs: t}, 0
+}
+
+// OpenByID implements FS.OpenByID
+func (t *timedReadFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	readOnly := flag&(os.O_WRONLY|os.O_RDWR) == 0 && flag&(os.O_CREATE|os.O_TRUNC) == 0
+	if !readOnly {
+		if errno := t.checkWritable(); errno != 0 {
+			return nil, errno
+		}
+	}
+	f, errno := t.fs.OpenByID(dev, ino, flag)
+	if errno != 0 {
+		return nil, errno
+	} else if readOnly {
+		return f, 0
+	}
+	return &timedReadFile{File: f, fs: t}, 0
+}
+
+// Lstat implements FS.Lstat
+func (t *timedReadFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return t.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (t *timedReadFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return t.fs.Stat(path)
+}
+
+// Mkdir implements FS.Mkdir
+func (t *timedReadFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Mkdir(path, perm)
+}
+
+// Chmod implements FS.Chmod
+func (t *timedReadFS) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (t *timedReadFS) Chown(path string, uid, gid int) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (t *timedReadFS) Lchown(path string, uid, gid int) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Lchown(path, uid, gid)
+}
+
+// Rename implements FS.Rename
+func (t *timedReadFS) Rename(from, to string) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Rename(from, to)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+func (t *timedReadFS) ExchangeDir(a, b string) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+func (t *timedReadFS) Clone(src, dst string) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Clone(src, dst)
+}
+
+// Rmdir implements FS.Rmdir
+func (t *timedReadFS) Rmdir(path string) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Rmdir(path)
+}
+
+// Unlink implements FS.Unlink
+func (t *timedReadFS) Unlink(path string) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Unlink(path)
+}
+
+// Link implements FS.Link
+func (t *timedReadFS) Link(oldPath, newPath string) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Link(oldPath, newPath)
+}
+
+// Symlink implements FS.Symlink
+func (t *timedReadFS) Symlink(oldPath, linkName string) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Symlink(oldPath, linkName)
+}
+
+// Readlink implements FS.Readlink
+func (t *timedReadFS) Readlink(path string) (string, syscall.Errno) {
+	return t.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (t *timedReadFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return t.fs.ReadlinkInto(path, buf)
+}
+
+// Truncate implements FS.Truncate
+func (t *timedReadFS) Truncate(path string, size int64) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Truncate(path, size)
+}
+
+// Utimens implements FS.Utimens
+func (t *timedReadFS) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+func (t *timedReadFS) SyncDir(path string) syscall.Errno {
+	if errno := t.checkWritable(); errno != 0 {
+		return errno
+	}
+	return t.fs.SyncDir(path)
+}
+
+// timedReadFile is a file opened for writing by timedReadFS, which checks
+// the deadline on each modification.
+type timedReadFile struct {
+	platform.File
+	fs *timedReadFS
+}
+
+// Write implements the same method as documented on platform.File
+func (f *timedReadFile) Write(buf []byte) (int, syscall.Errno) {
+	if errno := f.fs.checkWritable(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Write(buf)
+}
+
+// Writev implements the same method as documented on platform.File
+func (f *timedReadFile) Writev(bufs [][]byte) (int, syscall.Errno) {
+	if errno := f.fs.checkWritable(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Writev(bufs)
+}
+
+// Pwrite implements the same method as documented on platform.File
+func (f *timedReadFile) Pwrite(buf []byte, off int64) (int, syscall.Errno) {
+	if errno := f.fs.checkWritable(); errno != 0 {
+		return 0, errno
+	}
+	return f.File.Pwrite(buf, off)
+}
+
+// Truncate implements the same method as documented on platform.File
+func (f *timedReadFile) Truncate(size int64) syscall.Errno {
+	if errno := f.fs.checkWritable(); errno != 0 {
+		return errno
+	}
+	return f.File.Truncate(size)
+}
+
+// PunchHole implements the same method as documented on platform.File
+func (f *timedReadFile) PunchHole(offset, length int64) syscall.Errno {
+	if errno := f.fs.checkWritable(); errno != 0 {
+		return errno
+	}
+	return f.File.PunchHole(offset, length)
+}
+
+// Chmod implements the same method as documented on platform.File
+func (f *timedReadFile) Chmod(perm fs.FileMode) syscall.Errno {
+	if errno := f.fs.checkWritable(); errno != 0 {
+		return errno
+	}
+	return f.File.Chmod(perm)
+}
+
+// Chown implements the same method as documented on platform.File
+func (f *timedReadFile) Chown(uid, gid int) syscall.Errno {
+	if errno := f.fs.checkWritable(); errno != 0 {
+		return errno
+	}
+	return f.File.Chown(uid, gid)
+}
+
+// Utimens implements the same method as documented on platform.File
+func (f *timedReadFile) Utimens(times *[2]syscall.Timespec) syscall.Errno {
+	if errno := f.fs.checkWritable(); errno != 0 {
+		return errno
+	}
+	return f.File.Utimens(times)
+}
+
+// Dup implements the same method as documented on platform.File
+func (f *timedReadFile) Dup() (platform.File, syscall.Errno) {
+	dup, errno := f.File.Dup()
+	if errno != 0 {
+		return nil, errno
+	}
+	return &timedReadFile{File: dup, fs: f.fs}, 0
+}
@@ -0,0 +1,60 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewTimedReadFS(t *testing.T) {
+	testFS := NewTimedReadFS(NewMemFS(), time.Now().Add(time.Hour))
+	require.Equal(t, "mem:/", testFS.String())
+
+	// Before the deadline, writes are delegated.
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	f, errno := testFS.OpenFile("dir/file", os.O_RDWR|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+
+	// Expire the deadline.
+	testFS.(*timedReadFS).writableUntil = time.Now()
+
+	// Files opened before the deadline can no longer be written.
+	_, errno = f.Write([]byte("!"))
+	require.EqualErrno(t, syscall.EROFS, errno)
+	_, errno = f.Pwrite([]byte("!"), 0)
+	require.EqualErrno(t, syscall.EROFS, errno)
+	require.EqualErrno(t, syscall.EROFS, f.Truncate(0))
+	dup, errno := f.Dup()
+	require.EqualErrno(t, 0, errno)
+	defer dup.Close()
+	_, errno = dup.Write([]byte("!"))
+	require.EqualErrno(t, syscall.EROFS, errno)
+
+	// Reads still work.
+	buf := make([]byte, 10)
+	n, errno := f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazero", string(buf[:n]))
+
+	_, errno = testFS.OpenFile("dir/file", os.O_RDWR, 0)
+	require.EqualErrno(t, syscall.EROFS, errno)
+	_, errno = testFS.OpenFile("dir/new", os.O_RDONLY|os.O_CREATE, 0o600)
+	require.EqualErrno(t, syscall.EROFS, errno)
+	r, errno := testFS.OpenFile("dir/file", os.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, r.Close())
+
+	require.EqualErrno(t, syscall.EROFS, testFS.Mkdir("dir2", 0o700))
+	require.EqualErrno(t, syscall.EROFS, testFS.Unlink("dir/file"))
+	require.EqualErrno(t, syscall.EROFS, testFS.Rename("dir/file", "file"))
+	require.EqualErrno(t, syscall.EROFS, testFS.Truncate("dir/file", 0))
+	st, errno := testFS.Stat("dir/file")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+}
@@ -26,6 +26,11 @@ func (UnimplementedFS) OpenFile(path string, flag int, perm fs.FileMode) (platfo
 	return nil, syscall.ENOSYS
 }
 
+// OpenByID implements FS.OpenByID
+func (UnimplementedFS) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	return nil, syscall.ENOSYS
+}
+
 // Lstat implements FS.Lstat
 func (UnimplementedFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
 	return platform.Stat_t{}, syscall.ENOSYS
@@ -41,6 +46,11 @@ func (UnimplementedFS) Readlink(path string) (string, syscall.Errno) {
 	return "", syscall.ENOSYS
 }
 
+// ReadlinkInto implements FS.ReadlinkInto
+func (UnimplementedFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return 0, syscall.ENOSYS
+}
+
 // Mkdir implements FS.Mkdir
 func (UnimplementedFS) Mkdir(path string, perm fs.FileMode) syscall.Errno {
 	return syscall.ENOSYS
@@ -66,6 +76,16 @@ func (UnimplementedFS) Rename(from, to string) syscall.Errno {
 	return syscall.ENOSYS
 }
 
+// ExchangeDir implements FS.ExchangeDir
+func (UnimplementedFS) ExchangeDir(a, b string) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+// Clone implements FS.Clone
+func (UnimplementedFS) Clone(src, dst string) syscall.Errno {
+	return syscall.ENOSYS
+}
+
 // Rmdir implements FS.Rmdir
 func (UnimplementedFS) Rmdir(path string) syscall.Errno {
 	return syscall.ENOSYS
@@ -91,6 +111,11 @@ func (UnimplementedFS) Utimens(path string, times *[2]syscall.Timespec, symlinkF
 	return syscall.ENOSYS
 }
 
+// SyncDir implements FS.SyncDir
+func (UnimplementedFS) SyncDir(path string) syscall.Errno {
+	return syscall.ENOSYS
+}
+
 // Truncate implements FS.Truncate
 func (UnimplementedFS) Truncate(string, int64) syscall.Errno {
 	return syscall.ENOSYS
@@ -0,0 +1,64 @@
+package sysfs
+
+import (
+	"strings"
+	"syscall"
+)
+
+const (
+	// MaxNameLen is the maximum length in bytes of each component of a path,
+	// like NAME_MAX in POSIX.
+	MaxNameLen = 255
+
+	// DefaultMaxPathLen is the default maximum length in bytes of a path,
+	// like PATH_MAX on Linux.
+	DefaultMaxPathLen = 4096
+)
+
+// WithMaxPathLen changes the maximum length in bytes of paths passed to the
+// FS from DefaultMaxPathLen. Longer paths fail with syscall.ENAMETOOLONG.
+//
+// Note: This is checked before the host sees the path, so a host with a
+// lower limit, such as Windows without long path support, can still return
+// syscall.ENAMETOOLONG for shorter paths.
+func WithMaxPathLen(maxPathLen int) DirFSOption {
+	return func(d *dirFS) {
+		d.maxPathLen = maxPathLen
+	}
+}
+
+// validatePath returns an error if `path` can't be passed to the host, so
+// that all FS implementations return the same error, regardless of the
+// platform.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. The below are expected otherwise:
+//   - syscall.EINVAL: `path` includes a NUL byte.
+//   - syscall.ENAMETOOLONG: a component of `path` is longer than MaxNameLen,
+//     or `path` is longer than `maxPathLen`.
+func validatePath(path string, maxPathLen int) syscall.Errno {
+	if strings.IndexByte(path, 0) != -1 {
+		return syscall.EINVAL
+	} else if len(path) > maxPathLen {
+		return syscall.ENAMETOOLONG
+	}
+	for len(path) > MaxNameLen {
+		i := strings.IndexByte(path, '/')
+		if i == -1 || i > MaxNameLen {
+			return syscall.ENAMETOOLONG
+		}
+		path = path[i+1:]
+	}
+	return 0
+}
+
+// validatePaths is like validatePath for each path given to dirFS.
+func (d *dirFS) validatePaths(paths ...string) syscall.Errno {
+	for _, p := range paths {
+		if errno := validatePath(p, d.maxPathLen); errno != 0 {
+			return errno
+		}
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package sysfs
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestValidatePath(t *testing.T) {
+	longName := strings.Repeat("a", MaxNameLen)
+	tests := []struct {
+		name     string
+		path     string
+		expected syscall.Errno
+	}{
+		{name: "empty", path: ""},
+		{name: "max name", path: longName},
+		{name: "max name in path", path: "dir/" + longName + "/file"},
+		{name: "name too long", path: longName + "a", expected: syscall.ENAMETOOLONG},
+		{name: "name too long in path", path: "dir/" + longName + "a/file", expected: syscall.ENAMETOOLONG},
+		{name: "max path", path: strings.Repeat("a/", DefaultMaxPathLen/2)},
+		{name: "path too long", path: strings.Repeat("a/", DefaultMaxPathLen/2) + "a", expected: syscall.ENAMETOOLONG},
+		{name: "NUL", path: "a\x00b", expected: syscall.EINVAL},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualErrno(t, tc.expected, validatePath(tc.path, DefaultMaxPathLen))
+		})
+	}
+}
+
+func TestValidatePath_FS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	longName := strings.Repeat("a", MaxNameLen+1)
+
+	tests := []struct {
+		name string
+		fs   FS
+	}{
+		{name: "dirFS", fs: NewDirFS(tmpDir)},
+		{name: "adapter", fs: Adapt(os.DirFS(tmpDir))},
+		{name: "memFS", fs: NewMemFS()},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, errno := tc.fs.OpenFile("a\x00b", os.O_RDONLY, 0)
+			require.EqualErrno(t, syscall.EINVAL, errno)
+			_, errno = tc.fs.Stat("a\x00b")
+			require.EqualErrno(t, syscall.EINVAL, errno)
+			_, errno = tc.fs.OpenFile(longName, os.O_RDONLY, 0)
+			require.EqualErrno(t, syscall.ENAMETOOLONG, errno)
+			_, errno = tc.fs.Lstat("sub/" + longName)
+			require.EqualErrno(t, syscall.ENAMETOOLONG, errno)
+		})
+	}
+}
+
+func TestWithMaxPathLen(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	testFS := NewDirFS(tmpDir, WithMaxPathLen(len("sub/test.txt")))
+
+	_, errno := testFS.Stat("sub/test.txt")
+	require.EqualErrno(t, 0, errno)
+	_, errno = testFS.Stat("sub/./test.txt")
+	require.EqualErrno(t, syscall.ENAMETOOLONG, errno)
+	require.EqualErrno(t, syscall.ENAMETOOLONG, testFS.Rename("sub/test.txt", "sub/renamed.txt"))
+	require.EqualErrno(t, syscall.ENAMETOOLONG, testFS.Mkdir("sub/longerdir", 0o700))
+}
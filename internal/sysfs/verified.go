@@ -0,0 +1,170 @@
+package sysfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// NewVerifiedFS returns a read-only FS which only opens regular files in
+// `fs` whose SHA-256 checksum matches `manifest`. The manifest maps the path
+// of each regular file to its checksum, and can be made with BuildManifest.
+//
+// This lets guests trust mounted assets without verifying them: Each file is
+// hashed completely when opened, so the guest never reads content which
+// doesn't match.
+//
+// # Errors
+//
+// OpenFile returns the below, in addition to the errors of `fs`:
+//   - syscall.EIO: the checksum of the file doesn't match the manifest.
+//   - syscall.EACCES: the file isn't a directory, and isn't in the manifest.
+//
+// Note: Files shouldn't change while mounted, as changes after a file is
+// opened aren't detected.
+func NewVerifiedFS(fs FS, manifest map[string][]byte) FS {
+	cleaned := make(map[string][]byte, len(manifest))
+	for p, sum := range manifest {
+		cleaned[cleanPath(p)] = sum
+	}
+	return &verifiedFS{fs: NewReadFS(fs), manifest: cleaned}
+}
+
+type verifiedFS struct {
+	readOnlyFS
+	fs FS
+
+	// manifest are checksums by cleaned path.
+	manifest map[string][]byte
+}
+
+// String implements fmt.Stringer
+func (v *verifiedFS) String() string {
+	return v.fs.String()
+}
+
+// OpenFile implements FS.OpenFile
+func (v *verifiedFS) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	f, errno := v.fs.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	if errno = v.verify(cleanPath(path), f); errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	return f, 0
+}
+
+// verify checks the checksum of `f`, unless it is a directory.
+func (v *verifiedFS) verify(path string, f platform.File) syscall.Errno {
+	if isDir, errno := f.IsDir(); errno != 0 {
+		return errno
+	} else if isDir {
+		return 0
+	}
+	want, ok := v.manifest[path]
+	if !ok {
+		return syscall.EACCES
+	}
+	sum, errno := hashFile(f, sha256.New())
+	if errno != 0 {
+		return errno
+	} else if !bytes.Equal(sum, want) {
+		return syscall.EIO
+	}
+	return 0
+}
+
+// Lstat implements FS.Lstat
+func (v *verifiedFS) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	return v.fs.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (v *verifiedFS) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	return v.fs.Stat(path)
+}
+
+// Readlink implements FS.Readlink
+func (v *verifiedFS) Readlink(path string) (string, syscall.Errno) {
+	return v.fs.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (v *verifiedFS) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	return v.fs.ReadlinkInto(path, buf)
+}
+
+// hashFile returns the checksum of the contents of `f`. This uses Pread, so
+// it doesn't change the file offset.
+func hashFile(f platform.File, h hash.Hash) ([]byte, syscall.Errno) {
+	buf := make([]byte, 32*1024)
+	for off := int64(0); ; {
+		n, errno := f.Pread(buf, off)
+		if errno != 0 {
+			return nil, errno
+		} else if n == 0 {
+			return h.Sum(nil), 0
+		}
+		h.Write(buf[:n])
+		off += int64(n)
+	}
+}
+
+// BuildManifest returns the SHA-256 checksum of each regular file in `fs`,
+// for NewVerifiedFS. Symbolic links to regular files are included with the
+// checksum of their target.
+func BuildManifest(fs FS) (map[string][]byte, syscall.Errno) {
+	manifest := map[string][]byte{}
+	if errno := buildManifest(fs, ".", manifest); errno != 0 {
+		return nil, errno
+	}
+	return manifest, 0
+}
+
+func buildManifest(fs FS, dir string, manifest map[string][]byte) syscall.Errno {
+	d, errno := fs.OpenFile(dir, os.O_RDONLY|platform.O_DIRECTORY, 0)
+	if errno != 0 {
+		return errno
+	}
+	dirents, errno := d.Readdir(-1)
+	_ = d.Close()
+	if errno != 0 {
+		return errno
+	}
+
+	for _, e := range dirents {
+		name := path.Join(dir, e.Name)
+		switch {
+		case e.Type.IsDir():
+			if errno = buildManifest(fs, name, manifest); errno != 0 {
+				return errno
+			}
+			continue
+		case e.Type == os.ModeSymlink:
+			if st, errno := fs.Stat(name); errno != 0 || !st.Mode.IsRegular() {
+				continue // e.g. a broken link or a link to a directory.
+			}
+		case !e.Type.IsRegular():
+			continue
+		}
+		f, errno := fs.OpenFile(name, os.O_RDONLY, 0)
+		if errno != 0 {
+			return errno
+		}
+		sum, errno := hashFile(f, sha256.New())
+		_ = f.Close()
+		if errno != 0 {
+			return errno
+		}
+		manifest[name] = sum
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package sysfs
+
+import (
+	"crypto/sha256"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewVerifiedFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+	require.NoError(t, os.Symlink("animals.txt", path.Join(tmpDir, "link")))
+
+	manifest, errno := BuildManifest(NewDirFS(tmpDir))
+	require.EqualErrno(t, 0, errno)
+	data, err := os.ReadFile(path.Join(tmpDir, "animals.txt"))
+	require.NoError(t, err)
+	animals := sha256.Sum256(data)
+	require.Equal(t, animals[:], manifest["animals.txt"])
+	require.Equal(t, animals[:], manifest["link"])
+	require.NotNil(t, manifest["sub/test.txt"])
+
+	testFS := NewVerifiedFS(NewDirFS(tmpDir), manifest)
+	require.Equal(t, tmpDir, testFS.String())
+
+	// Files which match the manifest can be read, as can directories.
+	for _, p := range []string{"animals.txt", "./sub/test.txt", "link", "sub"} {
+		f, errno := testFS.OpenFile(p, os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno, p)
+		require.EqualErrno(t, 0, f.Close())
+	}
+
+	// A file which changed fails verification.
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "animals.txt"), []byte("tampered"), 0o600))
+	_, errno = testFS.OpenFile("animals.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EIO, errno)
+
+	// A file not in the manifest can't be opened.
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "new.txt"), []byte("new"), 0o600))
+	_, errno = testFS.OpenFile("new.txt", os.O_RDONLY, 0)
+	require.EqualErrno(t, syscall.EACCES, errno)
+
+	// The result is read-only.
+	_, errno = testFS.OpenFile("sub/test.txt", os.O_RDWR, 0)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+	require.EqualErrno(t, syscall.EROFS, testFS.Unlink("sub/test.txt"))
+}
@@ -0,0 +1,671 @@
+package sysfs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	pathutil "path"
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// AdaptWithWritable returns an FS which reads from `ro`, but redirects any
+// changes to `rw`. For example, this allows a guest to modify files embedded
+// with embed.FS, writing changes to a temporary directory.
+//
+// # Notes
+//
+//   - Opening a file in `ro` for writing first copies it, and any parent
+//     directories, to `rw`. Later opens read the copy.
+//   - Copies are made writable by their owner, as fs.FS implementations
+//     typically report read-only permissions.
+//   - Paths in `rw` take precedence over the same paths in `ro`. Directory
+//     listings include entries from both.
+//   - Removals of paths in `ro` are only recorded in memory.
+//   - Renaming or exchanging a directory in `ro` returns syscall.EXDEV, like
+//     overlay filesystems without directory redirection.
+//   - This is simpler than an overlay filesystem. For example, the inode of a
+//     file changes when it is copied.
+func AdaptWithWritable(ro fs.FS, rw FS) FS {
+	return &writableAdapter{ro: Adapt(ro), rw: rw, removed: map[string]struct{}{}}
+}
+
+type writableAdapter struct {
+	UnimplementedFS
+	ro, rw FS
+
+	mux sync.Mutex
+	// removed are paths in ro which were removed, and not since created in rw.
+	removed map[string]struct{}
+}
+
+// String implements fmt.Stringer
+func (w *writableAdapter) String() string {
+	return fmt.Sprintf("%v+%v", w.ro, w.rw)
+}
+
+func (w *writableAdapter) isRemoved(path string) bool {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	_, ok := w.removed[path]
+	return ok
+}
+
+func (w *writableAdapter) setRemoved(path string, removed bool) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if removed {
+		w.removed[path] = struct{}{}
+	} else {
+		delete(w.removed, path)
+	}
+}
+
+// lookup returns rw if the path exists there, or ro otherwise.
+func (w *writableAdapter) lookup(path string) (FS, syscall.Errno) {
+	if _, errno := w.rw.Lstat(path); errno == 0 {
+		return w.rw, 0
+	} else if errno != syscall.ENOENT {
+		return nil, errno
+	} else if w.isRemoved(path) {
+		return nil, syscall.ENOENT
+	} else if _, errno = w.ro.Lstat(path); errno != 0 {
+		return nil, errno
+	}
+	return w.ro, 0
+}
+
+// inRO returns true if the path exists in ro, regardless of whether it was
+// removed.
+func (w *writableAdapter) inRO(path string) bool {
+	_, errno := w.ro.Lstat(path)
+	return errno == 0
+}
+
+// copyUp ensures the path exists in rw, copying it and any parent directories
+// from ro as needed. The contents of a regular file aren't copied when
+// `trunc` is true.
+func (w *writableAdapter) copyUp(path string, trunc bool) syscall.Errno {
+	if path == "" || path == "." {
+		return 0 // the root directory of rw
+	}
+
+	from, errno := w.lookup(path)
+	if errno != 0 || from == w.rw {
+		return errno
+	}
+	if errno = w.copyUp(pathutil.Dir(path), false); errno != 0 {
+		return errno
+	}
+
+	st, errno := w.ro.Lstat(path)
+	if errno != 0 {
+		return errno
+	}
+	perm := st.Mode.Perm() | 0o200
+	switch st.Mode.Type() {
+	case fs.ModeDir:
+		return w.rw.Mkdir(path, perm)
+	case 0:
+		return w.copyFile(path, path, perm, trunc)
+	default:
+		return syscall.ENOSYS // fs.FS doesn't define other file types.
+	}
+}
+
+// copyFile copies the regular file `src` in ro to `dst` in rw.
+func (w *writableAdapter) copyFile(src, dst string, perm fs.FileMode, trunc bool) syscall.Errno {
+	f, errno := w.rw.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if errno != 0 {
+		return errno
+	}
+	if !trunc {
+		errno = w.copyContents(src, f)
+	}
+	if closeErrno := f.Close(); errno == 0 {
+		errno = closeErrno
+	}
+	if errno != 0 {
+		_ = w.rw.Unlink(dst) // don't leave a partial copy.
+	}
+	return errno
+}
+
+func (w *writableAdapter) copyContents(path string, dst platform.File) syscall.Errno {
+	src, errno := w.ro.OpenFile(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer src.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, errno := src.Read(buf)
+		if errno != 0 {
+			return errno
+		} else if n == 0 {
+			return 0
+		} else if errno = writeAll(dst, buf[:n]); errno != 0 {
+			return errno
+		}
+	}
+}
+
+// copyUpParent copies the parent directory of the path, as it will be
+// created in rw.
+func (w *writableAdapter) copyUpParent(path string) syscall.Errno {
+	return w.copyUp(pathutil.Dir(path), false)
+}
+
+// OpenFile implements FS.OpenFile
+func (w *writableAdapter) OpenFile(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	path = cleanPath(path)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		return w.openRead(path, flag, perm)
+	}
+
+	switch errno := w.copyUp(path, flag&os.O_TRUNC != 0); errno {
+	case 0:
+	case syscall.ENOENT:
+		if flag&os.O_CREATE == 0 {
+			return nil, errno
+		} else if errno = w.copyUpParent(path); errno != 0 {
+			return nil, errno
+		}
+	default:
+		return nil, errno
+	}
+
+	f, errno := w.rw.OpenFile(path, flag, perm)
+	if errno == 0 {
+		w.setRemoved(path, false)
+	}
+	return f, errno
+}
+
+// OpenByID implements FS.OpenByID
+//
+// The file is reopened by its path, so that a directory lists entries from
+// both `ro` and `rw`, and a file in `ro` is copied to `rw` when opened for
+// writing.
+func (w *writableAdapter) OpenByID(dev, ino uint64, flag int) (platform.File, syscall.Errno) {
+	if flag&(os.O_CREATE|os.O_EXCL) != 0 {
+		return nil, syscall.EINVAL
+	}
+	path, errno := pathByID(w.rw, dev, ino)
+	switch errno {
+	case syscall.EXDEV, syscall.ENOENT, syscall.ENOSYS:
+		// Try ro, but report the error of rw if ro can't open by ID either.
+		roPath, roErrno := pathByID(w.ro, dev, ino)
+		switch roErrno {
+		case 0:
+			// The path in ro is hidden if removed or replaced in rw.
+			if from, lookupErrno := w.lookup(roPath); lookupErrno != 0 || from != w.ro {
+				return nil, syscall.ENOENT
+			}
+			path, errno = roPath, 0
+		case syscall.EXDEV, syscall.ENOSYS:
+		default:
+			errno = roErrno
+		}
+	}
+	if errno != 0 {
+		return nil, errno
+	}
+	return w.OpenFile(path, flag, 0)
+}
+
+// pathByID returns the path of the file with the inode `ino` in `fsys`.
+func pathByID(fsys FS, dev, ino uint64) (string, syscall.Errno) {
+	f, errno := fsys.OpenByID(dev, ino, os.O_RDONLY)
+	if errno != 0 {
+		return "", errno
+	}
+	defer f.Close()
+	return cleanPath(f.Path()), 0
+}
+
+func (w *writableAdapter) openRead(path string, flag int, perm fs.FileMode) (platform.File, syscall.Errno) {
+	from, errno := w.lookup(path)
+	if errno != 0 {
+		return nil, errno
+	}
+	f, errno := from.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	// Ensure the directory listing includes entries from both ro and rw.
+	if isDir, _ := f.IsDir(); isDir {
+		return &writableDir{path: path, w: w, f: f}, 0
+	}
+	return f, 0
+}
+
+// Lstat implements FS.Lstat
+func (w *writableAdapter) Lstat(path string) (platform.Stat_t, syscall.Errno) {
+	path = cleanPath(path)
+	from, errno := w.lookup(path)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return from.Lstat(path)
+}
+
+// Stat implements FS.Stat
+func (w *writableAdapter) Stat(path string) (platform.Stat_t, syscall.Errno) {
+	path = cleanPath(path)
+	from, errno := w.lookup(path)
+	if errno != 0 {
+		return platform.Stat_t{}, errno
+	}
+	return from.Stat(path)
+}
+
+// Readlink implements FS.Readlink
+func (w *writableAdapter) Readlink(path string) (string, syscall.Errno) {
+	path = cleanPath(path)
+	from, errno := w.lookup(path)
+	if errno != 0 {
+		return "", errno
+	}
+	return from.Readlink(path)
+}
+
+// ReadlinkInto implements FS.ReadlinkInto
+func (w *writableAdapter) ReadlinkInto(path string, buf []byte) (int, syscall.Errno) {
+	path = cleanPath(path)
+	from, errno := w.lookup(path)
+	if errno != 0 {
+		return 0, errno
+	}
+	return from.ReadlinkInto(path, buf)
+}
+
+// Mkdir implements FS.Mkdir
+func (w *writableAdapter) Mkdir(path string, perm fs.FileMode) syscall.Errno {
+	path = cleanPath(path)
+	if _, errno := w.lookup(path); errno == 0 {
+		return syscall.EEXIST
+	} else if errno = w.copyUpParent(path); errno != 0 {
+		return errno
+	} else if errno = w.rw.Mkdir(path, perm); errno != 0 {
+		return errno
+	}
+	w.setRemoved(path, false)
+	return 0
+}
+
+// Rmdir implements FS.Rmdir
+func (w *writableAdapter) Rmdir(path string) syscall.Errno {
+	path = cleanPath(path)
+	f, errno := w.openRead(path, os.O_RDONLY, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer f.Close()
+
+	if errno = emptyDir(f); errno != 0 {
+		return errno
+	}
+	return w.remove(path, w.rw.Rmdir)
+}
+
+// emptyDir returns syscall.ENOTDIR if `f` isn't a directory, or
+// syscall.ENOTEMPTY if it has entries.
+func emptyDir(f platform.File) syscall.Errno {
+	if isDir, _ := f.IsDir(); !isDir {
+		return syscall.ENOTDIR
+	} else if dirents, errno := platform.ReaddirNoIno(f, 1); errno != 0 {
+		return errno
+	} else if len(dirents) > 0 {
+		return syscall.ENOTEMPTY
+	}
+	return 0
+}
+
+// Unlink implements FS.Unlink
+func (w *writableAdapter) Unlink(path string) syscall.Errno {
+	path = cleanPath(path)
+	if st, errno := w.Lstat(path); errno != 0 {
+		return errno
+	} else if st.Mode.IsDir() {
+		return syscall.EISDIR
+	}
+	return w.remove(path, w.rw.Unlink)
+}
+
+// remove removes the path from rw, if it exists there, and hides it in ro.
+func (w *writableAdapter) remove(path string, removeRW func(string) syscall.Errno) syscall.Errno {
+	if from, errno := w.lookup(path); errno != 0 {
+		return errno
+	} else if from == w.rw {
+		if errno = removeRW(path); errno != 0 {
+			return errno
+		}
+	}
+	if w.inRO(path) {
+		w.setRemoved(path, true)
+	}
+	return 0
+}
+
+// Rename implements FS.Rename
+func (w *writableAdapter) Rename(from, to string) syscall.Errno {
+	from, to = cleanPath(from), cleanPath(to)
+	fromSt, errno := w.Lstat(from)
+	if errno != 0 {
+		return errno
+	} else if fromSt.Mode.IsDir() && w.inRO(from) {
+		return syscall.EXDEV
+	} else if errno = w.checkRenameTo(fromSt, to); errno != 0 {
+		return errno
+	} else if errno = w.copyUp(from, false); errno != 0 {
+		return errno
+	} else if errno = w.copyUpParent(to); errno != 0 {
+		return errno
+	}
+
+	if errno := w.rw.Rename(from, to); errno != 0 {
+		return errno
+	}
+	if w.inRO(from) {
+		w.setRemoved(from, true)
+	}
+	w.setRemoved(to, false)
+	return 0
+}
+
+// checkRenameTo returns the error of renaming a file with the status `fromSt`
+// onto `to`, if `to` is only in ro. This isn't left to rw, as it would need a
+// copy of `to`, which would remain if the rename failed.
+func (w *writableAdapter) checkRenameTo(fromSt platform.Stat_t, to string) syscall.Errno {
+	if toFS, errno := w.lookup(to); errno == syscall.ENOENT || toFS == w.rw {
+		return 0
+	} else if errno != 0 {
+		return errno
+	}
+
+	toSt, errno := w.ro.Lstat(to)
+	switch {
+	case errno != 0:
+		return errno
+	case !fromSt.Mode.IsDir() && toSt.Mode.IsDir():
+		return syscall.EISDIR
+	case fromSt.Mode.IsDir() && !toSt.Mode.IsDir():
+		return syscall.ENOTDIR
+	case !toSt.Mode.IsDir():
+		return 0
+	}
+
+	f, errno := w.openRead(to, os.O_RDONLY, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer f.Close()
+	return emptyDir(f)
+}
+
+// ExchangeDir implements FS.ExchangeDir
+//
+// Like Rename, a directory in ro can't be moved, so returns syscall.EXDEV.
+func (w *writableAdapter) ExchangeDir(a, b string) syscall.Errno {
+	a, b = cleanPath(a), cleanPath(b)
+	for _, path := range [...]string{a, b} {
+		if st, errno := w.Lstat(path); errno != 0 {
+			return errno
+		} else if !st.Mode.IsDir() {
+			return syscall.ENOTDIR
+		}
+	}
+	if w.inRO(a) || w.inRO(b) {
+		return syscall.EXDEV
+	}
+	return w.rw.ExchangeDir(a, b)
+}
+
+// Clone implements FS.Clone
+//
+// A file only in ro is copied to `dst` in rw, as they can't share data.
+func (w *writableAdapter) Clone(src, dst string) syscall.Errno {
+	src, dst = cleanPath(src), cleanPath(dst)
+	st, errno := w.Stat(src)
+	if errno != 0 {
+		return errno
+	} else if st.Mode.IsDir() {
+		return syscall.EISDIR
+	} else if !st.Mode.IsRegular() {
+		return syscall.ENOTSUP
+	}
+	if _, errno = w.lookup(dst); errno == 0 {
+		return syscall.EEXIST
+	} else if errno != syscall.ENOENT {
+		return errno
+	} else if errno = w.copyUpParent(dst); errno != 0 {
+		return errno
+	}
+
+	from, errno := w.lookup(src)
+	if errno != 0 {
+		return errno
+	} else if from == w.rw {
+		errno = w.rw.Clone(src, dst)
+	} else {
+		errno = w.copyFile(src, dst, st.Mode.Perm()|0o200, false)
+	}
+	if errno != 0 {
+		return errno
+	}
+	w.setRemoved(dst, false)
+	return 0
+}
+
+// Link implements FS.Link
+func (w *writableAdapter) Link(oldName, newName string) syscall.Errno {
+	oldName, newName = cleanPath(oldName), cleanPath(newName)
+	if _, errno := w.lookup(newName); errno == 0 {
+		return syscall.EEXIST
+	} else if errno = w.copyUp(oldName, false); errno != 0 {
+		return errno
+	} else if errno = w.copyUpParent(newName); errno != 0 {
+		return errno
+	} else if errno = w.rw.Link(oldName, newName); errno != 0 {
+		return errno
+	}
+	w.setRemoved(newName, false)
+	return 0
+}
+
+// Symlink implements FS.Symlink
+func (w *writableAdapter) Symlink(oldName, link string) syscall.Errno {
+	link = cleanPath(link)
+	if _, errno := w.lookup(link); errno == 0 {
+		return syscall.EEXIST
+	} else if errno = w.copyUpParent(link); errno != 0 {
+		return errno
+	} else if errno = w.rw.Symlink(oldName, link); errno != 0 {
+		return errno
+	}
+	w.setRemoved(link, false)
+	return 0
+}
+
+// Chmod implements FS.Chmod
+func (w *writableAdapter) Chmod(path string, perm fs.FileMode) syscall.Errno {
+	path = cleanPath(path)
+	if errno := w.copyUp(path, false); errno != 0 {
+		return errno
+	}
+	return w.rw.Chmod(path, perm)
+}
+
+// Chown implements FS.Chown
+func (w *writableAdapter) Chown(path string, uid, gid int) syscall.Errno {
+	path = cleanPath(path)
+	if errno := w.copyUp(path, false); errno != 0 {
+		return errno
+	}
+	return w.rw.Chown(path, uid, gid)
+}
+
+// Lchown implements FS.Lchown
+func (w *writableAdapter) Lchown(path string, uid, gid int) syscall.Errno {
+	path = cleanPath(path)
+	if errno := w.copyUp(path, false); errno != 0 {
+		return errno
+	}
+	return w.rw.Lchown(path, uid, gid)
+}
+
+// Utimens implements FS.Utimens
+func (w *writableAdapter) Utimens(path string, times *[2]syscall.Timespec, symlinkFollow bool) syscall.Errno {
+	path = cleanPath(path)
+	if errno := w.copyUp(path, false); errno != 0 {
+		return errno
+	}
+	return w.rw.Utimens(path, times, symlinkFollow)
+}
+
+// SyncDir implements FS.SyncDir
+//
+// A directory only in the read-only FS has nothing to sync.
+func (w *writableAdapter) SyncDir(path string) syscall.Errno {
+	path = cleanPath(path)
+	from, errno := w.lookup(path)
+	if errno != 0 {
+		return errno
+	} else if from == w.rw {
+		return w.rw.SyncDir(path)
+	} else if st, errno := w.ro.Stat(path); errno != 0 {
+		return errno
+	} else if !st.Mode.IsDir() {
+		return syscall.ENOTDIR
+	}
+	return 0
+}
+
+// Truncate implements FS.Truncate
+func (w *writableAdapter) Truncate(path string, size int64) syscall.Errno {
+	path = cleanPath(path)
+	if errno := w.copyUp(path, size == 0); errno != 0 {
+		return errno
+	}
+	return w.rw.Truncate(path, size)
+}
+
+// writableDir is a directory open for reading, whose entries are the union of
+// the same directory in ro and rw.
+type writableDir struct {
+	platform.DirFile
+
+	path     string
+	w        *writableAdapter
+	f        platform.File     // the directory file itself
+	dirents  []platform.Dirent // the directory contents
+	direntsI int               // the read offset, an index into the files slice
+	read     bool              // whether dirents were read
+}
+
+// Path implements the same method as documented on platform.File
+func (d *writableDir) Path() string {
+	return d.path
+}
+
+// Stat implements the same method as documented on platform.File
+func (d *writableDir) Stat() (platform.Stat_t, syscall.Errno) {
+	return d.f.Stat()
+}
+
+// Readdir implements the same method as documented on platform.File
+func (d *writableDir) Readdir(count int) (dirents []platform.Dirent, errno syscall.Errno) {
+	if !d.read {
+		if errno = d.readdir(); errno != 0 {
+			return
+		}
+		d.read = true
+	}
+
+	n := len(d.dirents) - d.direntsI
+	if n == 0 {
+		return
+	}
+	if count > 0 && n > count {
+		n = count
+	}
+	dirents = make([]platform.Dirent, n)
+	copy(dirents, d.dirents[d.direntsI:])
+	d.direntsI += n
+	return
+}
+
+// RewindDir implements the same method as documented on platform.File
+func (d *writableDir) RewindDir() syscall.Errno {
+	// Read the directory again, in case it changed.
+	d.dirents, d.direntsI, d.read = nil, 0, false
+	return 0
+}
+
+// readdir reads the directory from both rw and ro into d.dirents, skipping
+// entries in ro which are shadowed or were removed.
+func (d *writableDir) readdir() syscall.Errno {
+	seen := map[string]struct{}{}
+	for _, from := range []FS{d.w.rw, d.w.ro} {
+		f, errno := from.OpenFile(d.path, os.O_RDONLY|platform.O_DIRECTORY, 0)
+		switch errno {
+		case 0:
+		case syscall.ENOENT, syscall.ENOTDIR:
+			continue // only in the other FS.
+		default:
+			return errno
+		}
+		dirents, errno := f.Readdir(-1)
+		_ = f.Close()
+		if errno == syscall.ENOTDIR {
+			continue // fs.FS doesn't check platform.O_DIRECTORY
+		} else if errno != 0 {
+			return errno
+		}
+
+		for _, e := range dirents {
+			if _, ok := seen[e.Name]; ok {
+				continue
+			} else if from == d.w.ro && d.w.isRemoved(pathutil.Join(d.path, e.Name)) {
+				continue
+			}
+			seen[e.Name] = struct{}{}
+			d.dirents = append(d.dirents, e)
+		}
+	}
+	return 0
+}
+
+// Sync implements the same method as documented on platform.File
+func (d *writableDir) Sync() syscall.Errno {
+	return d.f.Sync()
+}
+
+// Datasync implements the same method as documented on platform.File
+func (d *writableDir) Datasync() syscall.Errno {
+	return d.f.Datasync()
+}
+
+// Chmod implements the same method as documented on platform.File
+func (d *writableDir) Chmod(perm fs.FileMode) syscall.Errno {
+	return d.w.Chmod(d.path, perm)
+}
+
+// Chown implements the same method as documented on platform.File
+func (d *writableDir) Chown(uid, gid int) syscall.Errno {
+	return d.w.Chown(d.path, uid, gid)
+}
+
+// Utimens implements the same method as documented on platform.File
+func (d *writableDir) Utimens(times *[2]syscall.Timespec) syscall.Errno {
+	return d.w.Utimens(d.path, times, true)
+}
+
+// Close implements the same method as documented on platform.File
+func (d *writableDir) Close() syscall.Errno {
+	return d.f.Close()
+}
@@ -0,0 +1,189 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"sort"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestAdaptWithWritable(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := AdaptWithWritable(fstest.FS, NewDirFS(tmpDir))
+
+	// Reads come from the fs.FS until written.
+	testOpen_Read(t, testFS, false)
+
+	t.Run("copy up on write", func(t *testing.T) {
+		f, errno := testFS.OpenFile("sub/test.txt", os.O_RDWR|os.O_APPEND, 0)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("bye\n"))
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		b, err := os.ReadFile(path.Join(tmpDir, "sub", "test.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "greet sub dir\nbye\n", string(b))
+
+		f, errno = testFS.OpenFile("sub/test.txt", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, b, readAll(t, f))
+		require.EqualErrno(t, 0, f.Close())
+	})
+
+	t.Run("O_TRUNC doesn't copy", func(t *testing.T) {
+		f, errno := testFS.OpenFile("empty.txt", os.O_WRONLY|os.O_TRUNC, 0)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		st, errno := testFS.Stat("empty.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, st.Size)
+	})
+
+	t.Run("O_EXCL on existing", func(t *testing.T) {
+		_, errno := testFS.OpenFile("animals.txt", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		require.EqualErrno(t, syscall.EEXIST, errno)
+	})
+
+	t.Run("create and list", func(t *testing.T) {
+		f, errno := testFS.OpenFile("dir/new.txt", os.O_WRONLY|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		require.Equal(t, []string{"-", "a-", "ab-", "new.txt"}, readdirNames(t, testFS, "dir"))
+	})
+
+	t.Run("unlink", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Unlink("animals.txt"))
+		_, errno := testFS.Stat("animals.txt")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		require.EqualErrno(t, syscall.ENOENT, testFS.Unlink("animals.txt"))
+		require.EqualErrno(t, syscall.EISDIR, testFS.Unlink("sub"))
+
+		for _, name := range readdirNames(t, testFS, ".") {
+			require.NotEqual(t, "animals.txt", name)
+		}
+
+		// The file can be created again.
+		f, errno := testFS.OpenFile("animals.txt", os.O_WRONLY|os.O_CREATE, 0o600)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+		st, errno := testFS.Stat("animals.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, st.Size)
+	})
+
+	t.Run("mkdir and rmdir", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EEXIST, testFS.Mkdir("sub", 0o700))
+		require.EqualErrno(t, syscall.ENOENT, testFS.Mkdir("nope/dir", 0o700))
+		require.EqualErrno(t, 0, testFS.Mkdir("newdir", 0o700))
+		require.EqualErrno(t, 0, testFS.Rmdir("newdir"))
+
+		require.EqualErrno(t, syscall.ENOTEMPTY, testFS.Rmdir("sub"))
+		require.EqualErrno(t, 0, testFS.Unlink("sub/test.txt"))
+		require.EqualErrno(t, 0, testFS.Rmdir("sub"))
+		_, errno := testFS.Stat("sub")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Rename("dir/ab-", "renamed"))
+		_, errno := testFS.Stat("dir/ab-")
+		require.EqualErrno(t, syscall.ENOENT, errno)
+		_, errno = testFS.Stat("renamed")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []string{"-", "a-", "new.txt"}, readdirNames(t, testFS, "dir"))
+
+		require.EqualErrno(t, syscall.EXDEV, testFS.Rename("dir", "dir2"))
+	})
+
+	t.Run("EROFS not returned", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Chmod("empty.txt", 0o600))
+		require.EqualErrno(t, 0, testFS.Truncate("renamed", 1))
+	})
+}
+
+func TestAdaptWithWritable_Rename(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := AdaptWithWritable(fstest.FS, NewDirFS(tmpDir))
+
+	require.EqualErrno(t, 0, testFS.Mkdir("newdir", 0o700))
+	f, errno := testFS.OpenFile("new.txt", os.O_WRONLY|os.O_CREATE, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// A failed rename onto a path in ro leaves it unchanged.
+	require.EqualErrno(t, syscall.ENOTDIR, testFS.Rename("newdir", "animals.txt"))
+	require.EqualErrno(t, syscall.EISDIR, testFS.Rename("new.txt", "emptydir"))
+	require.EqualErrno(t, syscall.ENOTEMPTY, testFS.Rename("newdir", "dir"))
+	for _, name := range []string{"animals.txt", "emptydir", "dir"} {
+		_, err := os.Lstat(path.Join(tmpDir, name))
+		require.True(t, os.IsNotExist(err))
+	}
+	st, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.NotEqual(t, int64(0), st.Size)
+
+	// A rename which succeeds replaces the path in ro.
+	require.EqualErrno(t, 0, testFS.Rename("newdir", "emptydir"))
+	require.EqualErrno(t, 0, testFS.Rename("new.txt", "animals.txt"))
+	st, errno = testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, st.Size)
+	require.Equal(t, []string{"animals.txt", "dir", "empty.txt", "emptydir", "sub"}, readdirNames(t, testFS, "."))
+}
+
+func TestAdaptWithWritable_ExchangeDirClone(t *testing.T) {
+	testFS := AdaptWithWritable(fstest.FS, NewMemFS())
+
+	t.Run("clone from ro", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Clone("sub/test.txt", "sub/clone.txt"))
+		f, errno := testFS.OpenFile("sub/clone.txt", os.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "greet sub dir\n", string(readAll(t, f)))
+		require.EqualErrno(t, 0, f.Close())
+
+		require.EqualErrno(t, syscall.EEXIST, testFS.Clone("sub/test.txt", "animals.txt"))
+		require.EqualErrno(t, syscall.EISDIR, testFS.Clone("sub", "sub2"))
+		require.EqualErrno(t, syscall.ENOENT, testFS.Clone("missing", "dst"))
+	})
+
+	t.Run("clone from rw", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Clone("sub/clone.txt", "clone2.txt"))
+		st, errno := testFS.Stat("clone2.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(len("greet sub dir\n")), st.Size)
+	})
+
+	t.Run("exchange dir", func(t *testing.T) {
+		require.EqualErrno(t, syscall.EXDEV, testFS.ExchangeDir("sub", "dir"))
+		require.EqualErrno(t, syscall.ENOTDIR, testFS.ExchangeDir("sub", "animals.txt"))
+
+		require.EqualErrno(t, 0, testFS.Mkdir("a", 0o700))
+		require.EqualErrno(t, 0, testFS.Mkdir("b", 0o700))
+		require.EqualErrno(t, 0, testFS.Clone("animals.txt", "a/file"))
+		require.EqualErrno(t, 0, testFS.ExchangeDir("a", "b"))
+		require.Equal(t, []string{"file"}, readdirNames(t, testFS, "b"))
+		require.Equal(t, 0, len(readdirNames(t, testFS, "a")))
+	})
+}
+
+func readdirNames(t *testing.T, testFS FS, path string) (names []string) {
+	f, errno := testFS.OpenFile(path, os.O_RDONLY|platform.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	dirents, errno := f.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	for _, e := range dirents {
+		names = append(names, e.Name)
+	}
+	sort.Strings(names)
+	return
+}
@@ -0,0 +1,118 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// maxTempAttempts bounds how many temporary names WriteFileAtomic tries
+// before giving up with syscall.EEXIST.
+const maxTempAttempts = 100
+
+// WriteFileAtomic replaces the contents of `path` with `data`, such that
+// readers either see the old or the new contents, but never a partial write.
+//
+// This creates a temporary file in the same directory as `path`, writes and
+// syncs `data` to it, then renames it over `path`. On any failure, the
+// temporary file is removed. Finally, the directory is synced, so that the
+// rename survives a crash.
+//
+// # Errors
+//
+// A zero syscall.Errno is success. Otherwise, this returns the first error
+// encountered by the underlying FS or platform.File.
+//
+// # Notes
+//
+//   - This is like os.WriteFile, except the result is atomic when the FS
+//     supports Rename.
+//   - When Rename returns syscall.ENOSYS, this falls back to writing `path`
+//     directly with os.O_TRUNC, which is not atomic.
+//   - When FS.SyncDir returns syscall.ENOSYS, the rename is atomic, but may
+//     be lost on a crash.
+func WriteFileAtomic(fs FS, path string, data []byte, perm fs.FileMode) syscall.Errno {
+	tmpPath, f, errno := createTemp(fs, path, perm)
+	if errno != 0 {
+		return errno
+	}
+
+	if errno = writeAll(f, data); errno == 0 {
+		errno = f.Sync()
+	}
+	if closeErrno := f.Close(); errno == 0 {
+		errno = closeErrno
+	}
+	if errno != 0 {
+		_ = fs.Unlink(tmpPath)
+		return errno
+	}
+
+	switch errno = fs.Rename(tmpPath, path); errno {
+	case 0:
+		return syncParent(fs, path)
+	case syscall.ENOSYS:
+		_ = fs.Unlink(tmpPath)
+		return writeFile(fs, path, data, perm)
+	default:
+		_ = fs.Unlink(tmpPath)
+		return errno
+	}
+}
+
+// syncParent syncs the directory containing `target`, ignoring
+// syscall.ENOSYS.
+func syncParent(fs FS, target string) syscall.Errno {
+	if errno := fs.SyncDir(path.Dir(target)); errno != syscall.ENOSYS {
+		return errno
+	}
+	return 0
+}
+
+// createTemp exclusively creates a temporary file next to `target`, so that a
+// later rename stays on the same filesystem.
+func createTemp(fs FS, target string, perm fs.FileMode) (string, platform.File, syscall.Errno) {
+	dir, base := path.Split(target)
+	for i := 0; i < maxTempAttempts; i++ {
+		tmpPath := dir + "." + base + ".tmp" + strconv.Itoa(i)
+		f, errno := fs.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if errno == syscall.EEXIST {
+			continue
+		}
+		return tmpPath, f, errno
+	}
+	return "", nil, syscall.EEXIST
+}
+
+// writeFile is the non-atomic fallback of WriteFileAtomic.
+func writeFile(fs FS, path string, data []byte, perm fs.FileMode) syscall.Errno {
+	f, errno := fs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if errno != 0 {
+		return errno
+	}
+	if errno = writeAll(f, data); errno == 0 {
+		errno = f.Sync()
+	}
+	if closeErrno := f.Close(); errno == 0 {
+		errno = closeErrno
+	}
+	return errno
+}
+
+// writeAll writes all of `data` to `f`, retrying on short writes.
+func writeAll(f platform.File, data []byte) syscall.Errno {
+	for len(data) > 0 {
+		n, errno := f.Write(data)
+		if errno != 0 {
+			return errno
+		} else if n == 0 {
+			return syscall.EIO
+		}
+		data = data[n:]
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package sysfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	realPath := joinPath(tmpDir, "config")
+	require.NoError(t, os.WriteFile(realPath, []byte("old"), 0o600))
+
+	errno := WriteFileAtomic(testFS, "config", []byte("new"), 0o600)
+	require.EqualErrno(t, 0, errno)
+
+	b, err := os.ReadFile(realPath)
+	require.NoError(t, err)
+	require.Equal(t, "new", string(b))
+
+	// The temporary file should not be left behind.
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+}
+
+func TestWriteFileAtomic_renameENOSYS(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := &noRenameFS{NewDirFS(tmpDir)}
+
+	realPath := joinPath(tmpDir, "config")
+	require.NoError(t, os.WriteFile(realPath, []byte("old contents"), 0o600))
+
+	errno := WriteFileAtomic(testFS, "config", []byte("new"), 0o600)
+	require.EqualErrno(t, 0, errno)
+
+	b, err := os.ReadFile(realPath)
+	require.NoError(t, err)
+	require.Equal(t, "new", string(b))
+
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+}
+
+func TestWriteFileAtomic_SyncDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(joinPath(tmpDir, "dir"), 0o700))
+	testFS := &syncDirFS{FS: NewDirFS(tmpDir)}
+
+	// The directory is synced, so that the rename is durable.
+	errno := WriteFileAtomic(testFS, "dir/config", []byte("new"), 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"dir"}, testFS.synced)
+
+	// An FS which can't sync directories still succeeds.
+	testFS.errno = syscall.ENOSYS
+	errno = WriteFileAtomic(testFS, "config", []byte("new"), 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []string{"dir", "."}, testFS.synced)
+
+	b, err := os.ReadFile(joinPath(tmpDir, "config"))
+	require.NoError(t, err)
+	require.Equal(t, "new", string(b))
+
+	// Other errors are returned.
+	testFS.errno = syscall.EIO
+	errno = WriteFileAtomic(testFS, "config", []byte("new"), 0o600)
+	require.EqualErrno(t, syscall.EIO, errno)
+}
+
+func TestWriteFileAtomic_errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	errno := WriteFileAtomic(testFS, "missing/config", []byte("new"), 0o600)
+	require.EqualErrno(t, syscall.ENOENT, errno)
+
+	errno = WriteFileAtomic(NewReadFS(testFS), "config", []byte("new"), 0o600)
+	require.EqualErrno(t, syscall.ENOSYS, errno)
+}
+
+// noRenameFS is an FS that doesn't support rename.
+type noRenameFS struct{ FS }
+
+// Rename implements FS.Rename
+func (*noRenameFS) Rename(string, string) syscall.Errno {
+	return syscall.ENOSYS
+}
+
+// syncDirFS records calls to SyncDir, returning errno instead of syncing
+// when it is set.
+type syncDirFS struct {
+	FS
+	errno  syscall.Errno
+	synced []string
+}
+
+// SyncDir implements FS.SyncDir
+func (s *syncDirFS) SyncDir(path string) syscall.Errno {
+	s.synced = append(s.synced, path)
+	if s.errno != 0 {
+		return s.errno
+	}
+	return s.FS.SyncDir(path)
+}
@@ -299,10 +299,14 @@ func ToErrno(errno syscall.Errno) Errno {
 		return ErrnoNotempty
 	case syscall.ENOTSUP:
 		return ErrnoNotsup
+	case syscall.ENXIO:
+		return ErrnoNxio
 	case syscall.EPERM:
 		return ErrnoPerm
 	case syscall.EROFS:
 		return ErrnoRofs
+	case syscall.ESPIPE:
+		return ErrnoSpipe
 	default:
 		return ErrnoIo
 	}
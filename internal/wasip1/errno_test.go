@@ -95,6 +95,11 @@ func TestToErrno(t *testing.T) {
 			input:    syscall.ENOTSUP,
 			expected: ErrnoNotsup,
 		},
+		{
+			name:     "syscall.ENXIO",
+			input:    syscall.ENXIO,
+			expected: ErrnoNxio,
+		},
 		{
 			name:     "syscall.EPERM",
 			input:    syscall.EPERM,
@@ -105,6 +110,11 @@ func TestToErrno(t *testing.T) {
 			input:    syscall.EROFS,
 			expected: ErrnoRofs,
 		},
+		{
+			name:     "syscall.ESPIPE",
+			input:    syscall.ESPIPE,
+			expected: ErrnoSpipe,
+		},
 		{
 			name:     "syscall.EqualErrno unexpected == ErrnoIo",
 			input:    syscall.Errno(0xfe),